@@ -2,196 +2,936 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
-	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/buddyserver"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/codetodos"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/handlers"
-	"github.com/omar-haris/cursor-buddy-mcp/internal/monitor"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/logging"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/metrics"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/testresults"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/websocket"
 )
 
-// runServer contains the main server logic that can be tested
+// Transports the server can be reached over
+const (
+	transportStdio = "stdio"
+	transportHTTP  = "http" // streamable HTTP, with SSE streaming, at /mcp
+	transportSSE   = "sse"  // legacy HTTP+SSE at /sse and /message
+	transportWS    = "ws"   // WebSocket at /ws
+)
+
+// Name and version the server reports to clients
+const (
+	serverName    = buddyserver.Name
+	serverVersion = buddyserver.Version
+)
+
+// defaultListen is the address HTTP transports listen on by default
+const defaultListen = ":8787"
+
+// healthPath is where HTTP listeners serve the health report, without
+// requiring the auth token
+const healthPath = "/healthz"
+
+// shutdownTimeout bounds how long shutdown waits for in-flight HTTP
+// requests, and then for running tool calls
+const shutdownTimeout = 5 * time.Second
+
+// serverOptions selects how MCP clients reach the server
+type serverOptions struct {
+	Transport string
+	Listen    string
+	Projects  []projectRoot // served alongside the main buddy directory
+	// MetricsListen is the address of the Prometheus /metrics listener;
+	// empty disables it
+	MetricsListen string
+	// AuthToken is required with tool calls over HTTP transports,
+	// overriding auth.token in config.yaml
+	AuthToken string
+	// AlsoListen is an address to serve streamable HTTP on beside stdio,
+	// for scripts and dashboards sharing the process with the editor;
+	// empty disables it
+	AlsoListen string
+	// IdleTimeout shuts a networked server down once no request has
+	// arrived for this long, for orchestrators that start instances on
+	// demand; zero disables it
+	IdleTimeout time.Duration
+	// Heartbeat is how often uptime and activity are logged; zero
+	// disables the heartbeat
+	Heartbeat time.Duration
+}
+
+// projectRoot is an extra buddy directory served by the same process
+type projectRoot = buddyserver.Project
+
+// runServer contains the main server logic that can be tested. It serves
+// over stdio.
 func runServer(ctx context.Context, buddyPath string) error {
-	// Initialize the buddy handlers
-	buddyHandlers, err := handlers.NewBuddyHandlers(buddyPath)
+	return serve(ctx, buddyPath, serverOptions{Transport: transportStdio})
+}
+
+// serve initializes the buddy handlers of every project and serves them
+// over the selected transport until the client disconnects (stdio) or ctx
+// is cancelled. On the way out, new tool calls are refused, running ones
+// get up to shutdownTimeout to finish, and file monitoring stops before
+// the search indexes are closed.
+func serve(ctx context.Context, buddyPath string, opts serverOptions) error {
+	switch opts.Transport {
+	case "":
+		opts.Transport = transportStdio
+	case transportStdio, transportHTTP, transportSSE, transportWS:
+	default:
+		return fmt.Errorf("unknown transport %q (expected stdio, http, sse or ws)", opts.Transport)
+	}
+	if opts.Listen == "" {
+		opts.Listen = defaultListen
+	}
+	if opts.AlsoListen != "" && opts.Transport != transportStdio {
+		return fmt.Errorf("--also-listen only applies to the stdio transport; %s already listens on %s", opts.Transport, opts.Listen)
+	}
+	if opts.IdleTimeout < 0 || opts.Heartbeat < 0 {
+		return fmt.Errorf("--idle-timeout and --heartbeat can't be negative")
+	}
+	if opts.IdleTimeout > 0 && opts.Transport == transportStdio {
+		return fmt.Errorf("--idle-timeout only applies to the http, sse and ws transports; stdio stops when the client closes it")
+	}
+
+	// Initialize the buddy handlers of every project and the MCP server
+	roots := append([]projectRoot{{Name: projectName(buddyPath), Path: buddyPath}}, opts.Projects...)
+	buddyServer, err := buddyserver.New(roots, buddyserver.Options{
+		Transport:  opts.Transport,
+		AlsoListen: opts.AlsoListen,
+		Networked:  opts.Transport != transportStdio || opts.AlsoListen != "",
+		AuthToken:  opts.AuthToken,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to initialize buddy handlers: %w", err)
+		return err
 	}
+	defer buddyServer.Close()
+	mcpServer, projects, health, drainer := buddyServer.MCP, buddyServer.Projects, buddyServer.Health, buddyServer.Drainer
 
-	// Start file monitoring
-	fileMonitor := monitor.NewFileMonitor(buddyPath, buddyHandlers)
-	go fileMonitor.Start(ctx)
+	if opts.MetricsListen != "" {
+		stopMetrics, err := serveMetrics(opts.MetricsListen, projects, health)
+		if err != nil {
+			return err
+		}
+		defer stopMetrics()
+	}
 
-	// Create MCP server
-	mcpServer := server.NewMCPServer(
-		"Cursor Buddy MCP",
-		"1.0.0",
-	)
+	// Shutdown begins when ctx is cancelled. New tool calls are refused
+	// before the transport stops, so calls already running aren't cut off.
+	transportCtx, stopTransport := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopTransport()
+	go func() {
+		select {
+		case <-ctx.Done():
+			drainer.Close()
+			stopTransport()
+		case <-transportCtx.Done():
+		}
+	}()
 
-	// Register tool handlers
-	// Rules tool
-	rulesTool := mcp.NewTool("buddy_get_rules",
-		mcp.WithDescription("Get coding rules and guidelines from the project's buddy system"),
-		mcp.WithString("category",
-			mcp.Description("Filter rules by category (optional)"),
-		),
-		mcp.WithString("priority",
-			mcp.Description("Filter rules by priority: critical, recommended, optional (optional)"),
-			mcp.Enum("critical", "recommended", "optional"),
-		),
-	)
-	mcpServer.AddTool(rulesTool, buddyHandlers.GetRulesToolHandler())
-
-	// Knowledge search tool
-	knowledgeTool := mcp.NewTool("buddy_search_knowledge",
-		mcp.WithDescription("Search the project knowledge base for context and documentation"),
-		mcp.WithString("query",
-			mcp.Required(),
-			mcp.Description("Search query to find relevant knowledge"),
-		),
-		mcp.WithString("category",
-			mcp.Description("Filter by category (optional)"),
-		),
-	)
-	mcpServer.AddTool(knowledgeTool, buddyHandlers.GetKnowledgeToolHandler())
-
-	// Database info tool
-	databaseTool := mcp.NewTool("buddy_get_database_info",
-		mcp.WithDescription("Get database schema and connection information"),
-		mcp.WithString("table_name",
-			mcp.Description("Get info for specific table (optional)"),
-		),
-		mcp.WithString("validate_query",
-			mcp.Description("SQL query to validate against schema (optional)"),
-		),
-	)
-	mcpServer.AddTool(databaseTool, buddyHandlers.GetDatabaseToolHandler())
-
-	// Todo management tool
-	todoTool := mcp.NewTool("buddy_manage_todos",
-		mcp.WithDescription("Manage project todos and track feature implementation progress"),
-		mcp.WithString("action",
-			mcp.Required(),
-			mcp.Description("Action to perform: list, update, progress"),
-			mcp.Enum("list", "update", "progress"),
-		),
-		mcp.WithString("feature",
-			mcp.Description("Filter by feature name (optional for list)"),
-		),
-		mcp.WithString("todo_id",
-			mcp.Description("Todo ID (required for update)"),
-		),
-		mcp.WithBoolean("completed",
-			mcp.Description("New completion status (required for update)"),
-		),
-		mcp.WithBoolean("only_incomplete",
-			mcp.Description("Show only incomplete todos (optional for list)"),
-		),
-	)
-	mcpServer.AddTool(todoTool, buddyHandlers.GetTodoToolHandler())
-
-	// History tool
-	historyTool := mcp.NewTool("buddy_history",
-		mcp.WithDescription("Track and search implementation history"),
-		mcp.WithString("action",
-			mcp.Required(),
-			mcp.Description("Action to perform: list, add, search"),
-			mcp.Enum("list", "add", "search"),
-		),
-		mcp.WithString("feature",
-			mcp.Description("Feature name (for filtering or adding)"),
-		),
-		mcp.WithString("description",
-			mcp.Description("Description of changes (required for add)"),
-		),
-		mcp.WithString("reasoning",
-			mcp.Description("Reasoning behind changes (required for add)"),
-		),
-		mcp.WithArray("changes",
-			mcp.Description("List of file changes (required for add)"),
-		),
-		mcp.WithString("query",
-			mcp.Description("Search query (required for search)"),
-		),
-		mcp.WithNumber("limit",
-			mcp.Description("Limit results (default: 10)"),
-		),
-	)
-	mcpServer.AddTool(historyTool, buddyHandlers.GetHistoryToolHandler())
-
-	// Backup tool
-	backupTool := mcp.NewTool("buddy_backup",
-		mcp.WithDescription("Manage file backups for safe code changes"),
-		mcp.WithString("action",
-			mcp.Required(),
-			mcp.Description("Action to perform: list, create, restore, clean"),
-			mcp.Enum("list", "create", "restore", "clean"),
-		),
-		mcp.WithString("file_path",
-			mcp.Description("Original file path (for create or list by file)"),
-		),
-		mcp.WithString("backup_id",
-			mcp.Description("Backup ID (required for restore)"),
-		),
-		mcp.WithString("context",
-			mcp.Description("Context of the change (required for create)"),
-		),
-		mcp.WithString("reasoning",
-			mcp.Description("Reasoning for the backup (required for create)"),
-		),
-		mcp.WithNumber("max_age_days",
-			mcp.Description("Maximum age in days for cleanup (required for clean)"),
-		),
-	)
-	mcpServer.AddTool(backupTool, buddyHandlers.GetBackupToolHandler())
-
-	// Add project context resource
-	projectResource := mcp.NewResource(
-		"buddy://project-context",
-		"Project Context",
-		mcp.WithResourceDescription("Complete project context including rules, knowledge, database schema, and todos"),
-		mcp.WithMIMEType("application/json"),
-	)
-	mcpServer.AddResource(projectResource, buddyHandlers.GetProjectContextResourceHandler())
+	go watchActivity(transportCtx, buddyServer.Activity, opts.Heartbeat, opts.IdleTimeout, func() {
+		drainer.Close()
+		stopTransport()
+	})
+
+	// Start file monitoring of every project. It stops before
+	// buddyServer.Close, which closes the search indexes.
+	defer shutdown(drainer, buddyServer.StartMonitor())
 
 	// Start server with context-aware serving
-	fmt.Println("Starting Cursor Buddy MCP server...")
+	slog.Info("starting Cursor Buddy MCP server", "buddy_path", buddyPath, "transport", opts.Transport, "projects", len(projects.List()))
+
+	switch opts.Transport {
+	case transportHTTP:
+		httpServer := newHTTPTransport(mcpServer, health)
+		slog.Info("listening for streamable HTTP clients at /mcp", "address", opts.Listen)
+		return serveHTTP(transportCtx, opts.Listen, httpServer.Start, httpServer.Shutdown)
+
+	case transportSSE:
+		mux := healthMux(health)
+		sseServer := server.NewSSEServer(mcpServer, server.WithSSEContextFunc(handlers.WithAuthorization), server.WithHTTPServer(&http.Server{Handler: mux}))
+		mux.Handle("/", sseServer)
+		slog.Info("listening for SSE clients at /sse, messages at /message", "address", opts.Listen)
+		return serveHTTP(transportCtx, opts.Listen, sseServer.Start, sseServer.Shutdown)
+
+	case transportWS:
+		wsServer := websocket.NewServer(mcpServer,
+			websocket.WithContextFunc(handlers.WithAuthorization),
+			websocket.WithAllowedOrigins(projects.Default().Config().Auth.AllowedOrigins...),
+			websocket.WithRoute(healthPath, health))
+		slog.Info("listening for WebSocket clients at "+websocket.Path, "address", opts.Listen)
+		return serveHTTP(transportCtx, opts.Listen, wsServer.Start, wsServer.Shutdown)
+	}
+
+	// Local tools can share the process with the editor over HTTP. If the
+	// listener fails, stdio stops too rather than running on without it.
+	alsoDone := make(chan error, 1)
+	if opts.AlsoListen != "" {
+		httpServer := newHTTPTransport(mcpServer, health)
+		slog.Info("also listening for streamable HTTP clients at /mcp", "address", opts.AlsoListen)
+		go func() {
+			err := serveHTTP(transportCtx, opts.AlsoListen, httpServer.Start, httpServer.Shutdown)
+			stopTransport()
+			alsoDone <- err
+		}()
+	} else {
+		alsoDone <- nil
+	}
 
-	log.Println("Cursor Buddy MCP server started")
+	slog.Info("serving over stdio")
 
-	// Serve stdio directly - this will block until stdin is closed or context is cancelled
-	if err := server.ServeStdio(mcpServer); err != nil {
-		log.Printf("MCP server error: %v", err)
+	// Serve stdio until stdin is closed or shutdown begins
+	stdioServer := server.NewStdioServer(mcpServer)
+	err = stdioServer.Listen(transportCtx, os.Stdin, os.Stdout)
+	stopTransport()
+	if alsoErr := <-alsoDone; alsoErr != nil {
+		return alsoErr
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
 		return fmt.Errorf("MCP server error: %w", err)
 	}
 
-	log.Println("Server completed successfully")
+	slog.Info("server stopped")
+	return nil
+}
+
+// watchActivity logs a heartbeat every heartbeat interval and calls stop
+// once the server has gone idleTimeout without a request, until ctx ends.
+// A zero interval or timeout disables that part.
+func watchActivity(ctx context.Context, activity *handlers.ActivityTracker, heartbeat, idleTimeout time.Duration, stop func()) {
+	var beats, idle <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		beats = ticker.C
+	}
+	var idleTimer *time.Timer
+	if idleTimeout > 0 {
+		idleTimer = time.NewTimer(idleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+	if beats == nil && idle == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-beats:
+			slog.Info("heartbeat",
+				"uptime", activity.Uptime().Round(time.Second),
+				"last_activity", activity.LastActivity().UTC().Format(time.RFC3339),
+				"idle", activity.Idle().Round(time.Second),
+				"calls_running", activity.Running())
+		case <-idle:
+			// Sleep until the timeout would end counting from the latest
+			// request, or stop if that's already past
+			remaining := idleTimeout - activity.Idle()
+			if remaining > 0 {
+				idleTimer.Reset(remaining)
+				continue
+			}
+			slog.Info("shutting down after idle timeout", "idle_timeout", idleTimeout, "uptime", activity.Uptime().Round(time.Second))
+			stop()
+			return
+		}
+	}
+}
+
+// newHTTPTransport creates the streamable HTTP transport, serving MCP at
+// /mcp and the health report at healthPath
+func newHTTPTransport(mcpServer *server.MCPServer, health *handlers.HealthChecker) *server.StreamableHTTPServer {
+	mux := healthMux(health)
+	httpServer := server.NewStreamableHTTPServer(mcpServer, server.WithHTTPContextFunc(handlers.WithAuthorization), server.WithStreamableHTTPServer(&http.Server{Handler: mux}))
+	mux.Handle("/mcp", httpServer)
+	return httpServer
+}
+
+// shutdown waits up to shutdownTimeout for running tool calls, cancelling
+// any that outlast it, then stops background work
+func shutdown(drainer *handlers.CallDrainer, stopBackground func()) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if cancelled, err := drainer.Wait(ctx); err != nil {
+		slog.Warn("cancelled tool calls still running at shutdown", "calls", cancelled, "error", err)
+	}
+	stopBackground()
+}
+
+// serveHTTP runs an HTTP transport until ctx is cancelled, then shuts it
+// down, giving in-flight requests up to shutdownTimeout to finish
+func serveHTTP(ctx context.Context, listen string, start func(addr string) error, shutdown func(ctx context.Context) error) error {
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- start(listen)
+	}()
+
+	select {
+	case err := <-errChan:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("MCP server error: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down MCP server: %w", err)
+	}
+
+	slog.Info("server stopped")
+	return nil
+}
+
+// healthMux routes healthPath to the health report, for the transport to
+// add its own paths to
+func healthMux(health *handlers.HealthChecker) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle(healthPath, health)
+	return mux
+}
+
+// serveMetrics serves Prometheus metrics at /metrics, and the health report
+// at healthPath, on listen until the returned stop function is called.
+// Index document counts and sizes are read from every project at scrape
+// time.
+func serveMetrics(listen string, projects *handlers.Projects, health *handlers.HealthChecker) (func(), error) {
+	metrics.Default.NewGaugeFunc("buddy_index_documents", "Documents in each search index.", []string{"project", "index"}, func() []metrics.Sample {
+		var samples []metrics.Sample
+		for _, project := range projects.List() {
+			for index, count := range project.Handlers.IndexDocumentCounts() {
+				samples = append(samples, metrics.Sample{LabelValues: []string{project.Name, index}, Value: float64(count)})
+			}
+		}
+		return samples
+	})
+	metrics.Default.NewGaugeFunc("buddy_index_bytes", "Disk space of each search index in bytes.", []string{"project", "index"}, func() []metrics.Sample {
+		var samples []metrics.Sample
+		for _, project := range projects.List() {
+			for index, size := range project.Handlers.IndexUsage().Bytes {
+				samples = append(samples, metrics.Sample{LabelValues: []string{project.Name, index}, Value: float64(size)})
+			}
+		}
+		return samples
+	})
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		metrics.Default.Unregister("buddy_index_documents")
+		metrics.Default.Unregister("buddy_index_bytes")
+		return nil, fmt.Errorf("failed to start metrics listener: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler())
+	mux.Handle(healthPath, health)
+	metricsServer := &http.Server{Handler: mux}
+	go func() {
+		if err := metricsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics listener failed", "error", err)
+		}
+	}()
+	slog.Info("serving Prometheus metrics at /metrics", "address", listener.Addr().String())
+
+	return func() {
+		metricsServer.Close()
+		metrics.Default.Unregister("buddy_index_documents")
+		metrics.Default.Unregister("buddy_index_bytes")
+	}, nil
+}
+
+// importCodeTodos syncs TODO/FIXME comments from the project's source into
+// todos/code-todos.md. It works on the files directly rather than through
+// the handlers, so it can run while a server holds the search indexes.
+func importCodeTodos(buddyPath string) (codetodos.SyncResult, error) {
+	cfg, err := config.Load(buddyPath)
+	if err != nil {
+		return codetodos.SyncResult{}, err
+	}
+
+	absBuddyPath, err := filepath.Abs(buddyPath)
+	if err != nil {
+		return codetodos.SyncResult{}, err
+	}
+
+	scanner := codetodos.Scanner{
+		Root:    filepath.Dir(absBuddyPath),
+		Sources: cfg.CodeTodos.Sources,
+		Markers: cfg.CodeTodos.Markers,
+		Allow:   cfg.Paths.Allows,
+		Skip:    []string{absBuddyPath},
+	}
+	items, err := scanner.Scan(context.Background())
+	if err != nil {
+		return codetodos.SyncResult{}, err
+	}
+
+	path := filepath.Join(absBuddyPath, "todos", codetodos.FileName)
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return codetodos.SyncResult{}, err
+	}
+
+	content, result := codetodos.Sync(string(existing), items)
+	if content == string(existing) {
+		return result, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return codetodos.SyncResult{}, err
+	}
+	return result, os.WriteFile(path, []byte(content), 0644)
+}
+
+// attachTestResults parses a CI test report and attaches it to a history
+// entry. Like importCodeTodos it edits the files directly; a running server
+// picks up the change through its file watcher.
+func attachTestResults(buddyPath, reportPath string, target testresults.Target, source string) (models.HistoryEntry, models.TestRun, error) {
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		return models.HistoryEntry{}, models.TestRun{}, fmt.Errorf("failed to read test report: %w", err)
+	}
+
+	run, err := testresults.Parse(content)
+	if err != nil {
+		return models.HistoryEntry{}, models.TestRun{}, err
+	}
+	run.Source = source
+
+	entry, err := testresults.Attach(storage.NewLocal(), filepath.Join(buddyPath, "history"), target, run)
+	return entry, run, err
+}
+
+// initBuddy implements the init subcommand: it scaffolds a .buddy folder
+// with starter rules, knowledge, todos and an example schema, printing the
+// files it wrote to out
+func initBuddy(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	flags.SetOutput(out)
+	buddyPath := flags.String("buddy-path", envOr("BUDDY_PATH", ".buddy"), "Where to create the .buddy directory; a path argument overrides it")
+	name := flags.String("name", "", "Project name for the overview (default: the directory holding the .buddy folder)")
+	language := flags.String("language", "other", "Primary language for starter rules: go, typescript, python, java, rust or other")
+	database := flags.String("database", "none", "Main database: postgresql, mysql, sqlite, mongodb or none")
+	flags.Usage = func() {
+		fmt.Fprintf(out, "Usage: %s init [options] [path]\n\nCreates a .buddy directory with example files in the formats the server reads.\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("init takes at most one path, got %d", flags.NArg())
+	}
+	if flags.NArg() == 1 {
+		*buddyPath = flags.Arg(0)
+	}
+
+	written, err := handlers.InitBuddyFolder(*buddyPath, handlers.SetupAnswers{
+		ProjectName: *name,
+		Language:    *language,
+		Database:    *database,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(written) == 0 {
+		fmt.Fprintf(out, "%s already has every starter file; nothing was written\n", *buddyPath)
+		return nil
+	}
+	fmt.Fprintf(out, "Initialized %s with %d files:\n", *buddyPath, len(written))
+	for _, path := range written {
+		fmt.Fprintf(out, "  %s\n", path)
+	}
+	fmt.Fprintf(out, "\nEdit the examples to describe your project, and uncomment any settings you need in %s, then point your MCP client at this folder with --buddy-path=%s\n",
+		filepath.Join(*buddyPath, config.FileName), *buddyPath)
+	return nil
+}
+
+// doctorBuddy implements the doctor subcommand: it checks every file in a
+// .buddy folder and prints the problems found with suggested fixes. It
+// fails when any problem is an error.
+func doctorBuddy(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.SetOutput(out)
+	buddyPath := flags.String("buddy-path", envOr("BUDDY_PATH", ".buddy"), "The .buddy directory to check; a path argument overrides it")
+	flags.Usage = func() {
+		fmt.Fprintf(out, "Usage: %s doctor [options] [path]\n\nChecks every file in a .buddy directory and reports content the server would reject or misread.\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("doctor takes at most one path, got %d", flags.NArg())
+	}
+	if flags.NArg() == 1 {
+		*buddyPath = flags.Arg(0)
+	}
+
+	report, err := handlers.Diagnose(*buddyPath)
+	if err != nil {
+		return err
+	}
+
+	errorCount := report.Errors()
+	fmt.Fprintf(out, "Checked %d files in %s: %d errors, %d warnings\n", report.Checked, *buddyPath, errorCount, len(report.Issues)-errorCount)
+	for _, issue := range report.Issues {
+		fmt.Fprintf(out, "\n%-7s %s\n        %s\n        Fix: %s\n", strings.ToUpper(issue.Severity), issue.Path, issue.Problem, issue.Fix)
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("%d errors found", errorCount)
+	}
+	return nil
+}
+
+// testRules implements the test-rules subcommand: it runs the good and bad
+// snippets in each rule's frontmatter through the rule's checks and fails
+// when any snippet is misjudged
+func testRules(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("test-rules", flag.ContinueOnError)
+	flags.SetOutput(out)
+	buddyPath := flags.String("buddy-path", envOr("BUDDY_PATH", ".buddy"), "The .buddy directory whose rules to test; a path argument overrides it")
+	category := flags.String("category", "", "Only test rules in this category")
+	flags.Usage = func() {
+		fmt.Fprintf(out, "Usage: %s test-rules [options] [path]\n\nChecks each rule's test snippets against its forbid patterns and glossary.\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("test-rules takes at most one path, got %d", flags.NArg())
+	}
+	if flags.NArg() == 1 {
+		*buddyPath = flags.Arg(0)
+	}
+
+	rulesHandler := handlers.NewRulesHandler(filepath.Join(*buddyPath, "rules"), nil)
+	if err := rulesHandler.Load(); err != nil {
+		return err
+	}
+	rules := rulesHandler.GetRules()
+	if *category != "" {
+		rules = rulesHandler.GetRulesByCategory(*category)
+	}
+
+	results := handlers.RunRuleTests(rules)
+	fmt.Fprint(out, handlers.FormatRuleTestResults(results))
+
+	failed := 0
+	for _, result := range results {
+		if !result.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d rule tests failed", failed)
+	}
+	return nil
+}
+
+// migratePaths implements the migrate-paths subcommand: it rewrites the
+// absolute paths stored by older versions as workspace-relative paths
+func migratePaths(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("migrate-paths", flag.ContinueOnError)
+	flags.SetOutput(out)
+	buddyPath := flags.String("buddy-path", envOr("BUDDY_PATH", ".buddy"), "The .buddy directory to migrate; a path argument overrides it")
+	oldRoot := flags.String("old-root", "", "Where the workspace used to be, if it moved; paths under it are rebased onto the current root")
+	flags.Usage = func() {
+		fmt.Fprintf(out, "Usage: %s migrate-paths [options] [path]\n\nRewrites absolute paths in backups and history as paths relative to the workspace root.\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("migrate-paths takes at most one path, got %d", flags.NArg())
+	}
+	if flags.NArg() == 1 {
+		*buddyPath = flags.Arg(0)
+	}
+
+	result, err := handlers.MigratePaths(*buddyPath, *oldRoot)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Made %d paths relative to %s in %d files\n", result.Paths, result.Root, len(result.Files))
+	for _, path := range result.Files {
+		fmt.Fprintf(out, "  %s\n", path)
+	}
+	if len(result.Outside) > 0 {
+		fmt.Fprintf(out, "\n%d paths are outside the workspace and stay absolute", len(result.Outside))
+		if *oldRoot == "" {
+			fmt.Fprint(out, "; pass -old-root if the workspace moved")
+		}
+		fmt.Fprintln(out, ":")
+		for _, path := range result.Outside {
+			fmt.Fprintf(out, "  %s\n", path)
+		}
+	}
+	return nil
+}
+
+// exportBuddy implements the export subcommand: it renders rules,
+// knowledge, todos, the database schema and recent history into one
+// markdown or JSON document, written to a file or out
+func exportBuddy(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(out)
+	buddyPath := flags.String("buddy-path", envOr("BUDDY_PATH", ".buddy"), "The .buddy directory to export; a path argument overrides it")
+	format := flags.String("format", "markdown", "Output format: markdown or json")
+	output := flags.String("output", "", "File to write the bundle to (default: standard output)")
+	history := flags.Int("history", 10, "How many of the newest history entries to include")
+	flags.Usage = func() {
+		fmt.Fprintf(out, "Usage: %s export [options] [path]\n\nRenders rules, knowledge, todos, the database schema and recent history into one file for sharing or pasting into other AI tools.\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("export takes at most one path, got %d", flags.NArg())
+	}
+	if flags.NArg() == 1 {
+		*buddyPath = flags.Arg(0)
+	}
+	if *history < 1 {
+		return fmt.Errorf("--history must be at least 1")
+	}
+
+	bundle, err := handlers.Export(*buddyPath, projectName(*buddyPath), *history)
+	if err != nil {
+		return err
+	}
+
+	var content []byte
+	switch *format {
+	case "markdown", "md":
+		content = []byte(handlers.FormatExportMarkdown(bundle))
+	case "json":
+		content, err = json.MarshalIndent(bundle, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal export: %w", err)
+		}
+		content = append(content, '\n')
+	default:
+		return fmt.Errorf("unknown format %q (expected markdown or json)", *format)
+	}
+
+	if *output == "" {
+		_, err := out.Write(content)
+		return err
+	}
+	if err := os.WriteFile(*output, content, 0644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	fmt.Fprintf(out, "Exported %d rules, %d knowledge documents, %d todos and %d history entries from %s to %s\n",
+		len(bundle.Rules), len(bundle.Knowledge), len(bundle.Todos), len(bundle.History), *buddyPath, *output)
+	return nil
+}
+
+// exportCursor implements the export-cursor subcommand: it writes the
+// rules as Cursor's own .cursor/rules/*.mdc or .cursorrules files
+func exportCursor(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("export-cursor", flag.ContinueOnError)
+	flags.SetOutput(out)
+	buddyPath := flags.String("buddy-path", envOr("BUDDY_PATH", ".buddy"), "The .buddy directory whose rules are exported; a path argument overrides it")
+	format := flags.String("format", "mdc", "Output format: mdc for one .cursor/rules file per rule, or cursorrules for a single .cursorrules file")
+	force := flags.Bool("force", false, "Overwrite Cursor rules files that weren't generated by buddy")
+	dryRun := flags.Bool("dry-run", false, "List the files that would be written and removed without changing anything")
+	flags.Usage = func() {
+		fmt.Fprintf(out, "Usage: %s export-cursor [options] [path]\n\nWrites the buddy rules as Cursor's native rules files in the workspace root, so Cursor applies them without the MCP server. Run it again after changing the rules.\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("export-cursor takes at most one path, got %d", flags.NArg())
+	}
+	if flags.NArg() == 1 {
+		*buddyPath = flags.Arg(0)
+	}
+
+	export, err := handlers.ExportCursorRules(*buddyPath, *format, *force, *dryRun)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, handlers.FormatCursorExport(export, *dryRun))
+	return nil
+}
+
+// importBuddy implements the import subcommand: it merges a JSON bundle
+// written by export into a .buddy directory
+func importBuddy(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(out)
+	buddyPath := flags.String("buddy-path", envOr("BUDDY_PATH", ".buddy"), "The .buddy directory to merge the bundle into")
+	dryRun := flags.Bool("dry-run", false, "List what would be written without changing anything")
+	flags.Usage = func() {
+		fmt.Fprintf(out, "Usage: %s import [options] <bundle.json>\n\nMerges a bundle written by \"export --format json\" into a .buddy directory. Content already present is skipped, and files or history entries whose name or ID is taken get a new one.\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("import takes one bundle file (or - for standard input), got %d", flags.NArg())
+	}
+
+	var (
+		content []byte
+		err     error
+	)
+	if flags.Arg(0) == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(flags.Arg(0))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+	var bundle handlers.ExportBundle
+	if err := json.Unmarshal(content, &bundle); err != nil {
+		return fmt.Errorf("%s is not a JSON bundle (write one with export --format json): %w", flags.Arg(0), err)
+	}
+	if err := os.MkdirAll(*buddyPath, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", *buddyPath, err)
+	}
+
+	result, err := handlers.Import(*buddyPath, &bundle, *dryRun)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int)
+	for _, change := range result.Changes {
+		counts[change.Action]++
+		line := fmt.Sprintf("%-8s %s", change.Action, change.Path)
+		if change.Note != "" {
+			line += " (" + change.Note + ")"
+		}
+		fmt.Fprintln(out, line)
+	}
+	verb, source := "Imported", "the bundle"
+	if *dryRun {
+		verb = "Would import"
+	}
+	if bundle.Project != "" {
+		source = bundle.Project
+	}
+	fmt.Fprintf(out, "%s %s into %s: %d created, %d renamed, %d merged, %d skipped\n", verb, source, *buddyPath,
+		counts[handlers.ImportCreated], counts[handlers.ImportRenamed], counts[handlers.ImportMerged], counts[handlers.ImportSkipped])
+	if result.SnapshotID != "" {
+		fmt.Fprintf(out, "Undo with: %s restore --buddy-path=%s %s\n", filepath.Base(os.Args[0]), *buddyPath, result.SnapshotID)
+	}
 	return nil
 }
 
+// restoreBuddy implements the restore subcommand: it restores a safety
+// snapshot, such as the restore point of an import or reorganization, or
+// lists them when no ID is given
+func restoreBuddy(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.SetOutput(out)
+	buddyPath := flags.String("buddy-path", envOr("BUDDY_PATH", ".buddy"), "The .buddy directory whose snapshots to use")
+	flags.Usage = func() {
+		fmt.Fprintf(out, "Usage: %s restore [options] [snapshot-id]\n\nUndoes a bulk edit by restoring its restore point: files it changed get their old content back and files it created are removed. Without an ID, lists the snapshots kept in the .buddy directory.\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("restore takes at most one snapshot ID, got %d", flags.NArg())
+	}
+
+	store := handlers.BuddySafetyStore(*buddyPath, storage.NewLocal())
+	if flags.NArg() == 0 {
+		snapshots, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			fmt.Fprintf(out, "No snapshots in %s\n", *buddyPath)
+		}
+		for _, snapshot := range snapshots {
+			line := fmt.Sprintf("%s  %s", snapshot.ID, snapshot.Timestamp.Local().Format("2006-01-02 15:04"))
+			if snapshot.Label != "" {
+				line += "  " + snapshot.Label
+			}
+			fmt.Fprintln(out, line)
+		}
+		return nil
+	}
+
+	snapshot, err := store.Restore(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Restored %s: %d files put back, %d removed\n", snapshot.ID, len(snapshot.Files), len(snapshot.Created))
+	return nil
+}
+
+// statsBuddy implements the stats subcommand: it prints counts, sizes,
+// staleness and search index health for each section of a .buddy folder
+func statsBuddy(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	flags.SetOutput(out)
+	buddyPath := flags.String("buddy-path", envOr("BUDDY_PATH", ".buddy"), "The .buddy directory to measure; a path argument overrides it")
+	staleDays := flags.Int("stale-days", 90, "Count files unchanged for longer than this many days as stale")
+	format := flags.String("format", "text", "Output format: text or json")
+	flags.Usage = func() {
+		fmt.Fprintf(out, "Usage: %s stats [options] [path]\n\nPrints file and document counts, sizes, staleness and search index health for rules, knowledge, todos, history and backups, without starting the server.\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("stats takes at most one path, got %d", flags.NArg())
+	}
+	if flags.NArg() == 1 {
+		*buddyPath = flags.Arg(0)
+	}
+	if *staleDays < 1 {
+		return fmt.Errorf("--stale-days must be at least 1")
+	}
+
+	report, err := handlers.Stats(*buddyPath, *staleDays)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "text":
+		fmt.Fprintf(out, "Stats for %s\n\n%s", *buddyPath, handlers.FormatStats(report))
+	case "json":
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal stats: %w", err)
+		}
+		fmt.Fprintf(out, "%s\n", content)
+	default:
+		return fmt.Errorf("unknown format %q (expected text or json)", *format)
+	}
+	return nil
+}
+
+// subcommands run instead of the server when named as the first argument
+var subcommands = map[string]func(args []string, out io.Writer) error{
+	"init":          initBuddy,
+	"doctor":        doctorBuddy,
+	"test-rules":    testRules,
+	"migrate-paths": migratePaths,
+	"export":        exportBuddy,
+	"export-cursor": exportCursor,
+	"import":        importBuddy,
+	"stats":         statsBuddy,
+	"restore":       restoreBuddy,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:], os.Stdout); err != nil && !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintf(os.Stderr, "%s failed: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	}
+
 	var (
-		buddyPath = flag.String("buddy-path", os.Getenv("BUDDY_PATH"), "Path to the .buddy directory")
-		version   = flag.Bool("version", false, "Show version information")
-		help      = flag.Bool("help", false, "Show help information")
+		buddyPath   = flag.String("buddy-path", os.Getenv("BUDDY_PATH"), "Path to the .buddy directory")
+		version     = flag.Bool("version", false, "Show version information")
+		help        = flag.Bool("help", false, "Show help information")
+		importTodos = flag.Bool("import-todos", false, "Sync TODO/FIXME comments from source into todos/code-todos.md and exit")
+		attachTests = flag.String("attach-tests", "", "Attach a JUnit XML or go test -json report to a history entry and exit")
+		entryID     = flag.String("history-entry", "", "History entry for --attach-tests (default: newest entry)")
+		feature     = flag.String("history-feature", "", "Attach to the newest history entry for this feature")
+		testSource  = flag.String("test-source", "", "Where the test report came from, e.g. a CI run URL")
+		transport   = flag.String("transport", envOr("BUDDY_TRANSPORT", transportStdio), "How clients connect: stdio, http (streamable HTTP), sse or ws (WebSocket)")
+		listen      = flag.String("listen", envOr("BUDDY_LISTEN", defaultListen), "Address the http, sse and ws transports listen on")
+		projectList = flag.String("projects", os.Getenv("BUDDY_PROJECTS"), "Extra .buddy directories to serve, as comma-separated [name=]path entries")
+		alsoListen  = flag.String("also-listen", os.Getenv("BUDDY_ALSO_LISTEN"), "With the stdio transport, also serve streamable HTTP at /mcp on this address, e.g. 127.0.0.1:8788, for local scripts sharing the process (default: disabled)")
+		idleTimeout = flag.String("idle-timeout", os.Getenv("BUDDY_IDLE_TIMEOUT"), "With the http, sse and ws transports, shut down once no request has arrived for this long, e.g. 30m (default: disabled)")
+		heartbeat   = flag.String("heartbeat", os.Getenv("BUDDY_HEARTBEAT"), "Log uptime and last activity at this interval, e.g. 1m (default: disabled)")
+		metricsAddr = flag.String("metrics-listen", os.Getenv("BUDDY_METRICS_LISTEN"), "Serve Prometheus metrics at /metrics on this address, e.g. :9090 (default: disabled)")
+		logLevel    = flag.String("log-level", envOr("BUDDY_LOG_LEVEL", "info"), "Minimum level to log: debug, info, warn or error")
+		logFormat   = flag.String("log-format", envOr("BUDDY_LOG_FORMAT", "text"), "Log format: text or json")
+		logFile     = flag.Bool("log-file", os.Getenv("BUDDY_LOG_FILE") == "true", "Also write logs to logs/"+logging.FileName+" in the .buddy directory")
 	)
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Cursor Buddy MCP Server\n")
 		fmt.Fprintf(os.Stderr, "A Model Context Protocol server for development workflow management\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s init [options] [path]         Create a .buddy directory with example files\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s doctor [options] [path]       Check every file in a .buddy directory\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s test-rules [options] [path]   Check rules against their test snippets\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s migrate-paths [options] [path] Store backup and history paths relative to the workspace\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s export [options] [path]       Render the whole buddy state into one markdown or JSON file\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s export-cursor [options] [path] Write the rules as .cursor/rules/*.mdc or .cursorrules files\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s import [options] <bundle>     Merge a JSON export into a .buddy directory\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s stats [options] [path]        Print counts, sizes, staleness and index health per section\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s restore [options] [id]        Undo an import or reorganization from its restore point\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nEnvironment Variables:\n")
-		fmt.Fprintf(os.Stderr, "  BUDDY_PATH    Path to the .buddy directory (default: .buddy)\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_PATH           Path to the .buddy directory (default: .buddy)\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_TRANSPORT      Default for --transport\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_LISTEN         Default for --listen\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_PROJECTS       Default for --projects\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_ALSO_LISTEN    Default for --also-listen\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_METRICS_LISTEN Default for --metrics-listen\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_IDLE_TIMEOUT   Default for --idle-timeout\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_HEARTBEAT      Default for --heartbeat\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_AUTH_TOKEN     Bearer token required for tool calls over http, sse and ws\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_LOG_LEVEL      Default for --log-level\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_LOG_FORMAT     Default for --log-format\n")
+		fmt.Fprintf(os.Stderr, "  BUDDY_LOG_FILE       Set to true to enable --log-file\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
+		fmt.Fprintf(os.Stderr, "  %s init --language=go --database=postgresql\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s doctor .buddy\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s export --format=json --output=context.json .buddy\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s export-cursor --format=mdc .buddy\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import --buddy-path=.buddy --dry-run context.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s stats --stale-days=30 .buddy\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s restore --buddy-path=.buddy 20250101_120000.000000000-import\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --buddy-path=/home/user/project/.buddy\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  BUDDY_PATH=/home/user/project/.buddy %s\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --buddy-path=.buddy --transport=http --listen=:8787\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --buddy-path=.buddy --transport=http --idle-timeout=30m --heartbeat=1m\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --buddy-path=.buddy --also-listen=127.0.0.1:8788\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --buddy-path=.buddy --projects=api=services/api/.buddy,web=services/web/.buddy\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --buddy-path=.buddy --import-todos\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --buddy-path=.buddy --attach-tests=report.xml --history-feature=auth --test-source=$CI_JOB_URL\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -202,7 +942,7 @@ func main() {
 	}
 
 	if *version {
-		fmt.Println("Cursor Buddy MCP Server v1.0.0")
+		fmt.Printf("%s Server v%s\n", serverName, serverVersion)
 		os.Exit(0)
 	}
 
@@ -211,6 +951,39 @@ func main() {
 		*buddyPath = ".buddy"
 	}
 
+	// Logs go to stderr since stdout carries the stdio transport
+	logOptions := logging.Options{Level: *logLevel, Format: *logFormat}
+	if *logFile {
+		logOptions.File = logging.FilePath(*buddyPath)
+	}
+	logCloser, err := logging.Setup(logOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging options: %v\n", err)
+		os.Exit(2)
+	}
+	defer logCloser.Close()
+
+	if *importTodos {
+		result, err := importCodeTodos(*buddyPath)
+		if err != nil {
+			fatal("failed to import code todos", err)
+		}
+		fmt.Printf("Synced code comments into todos/%s: %d open, %d new, %d resolved, %d reopened\n",
+			codetodos.FileName, result.Open, result.Added, result.Resolved, result.Reopened)
+		os.Exit(0)
+	}
+
+	if *attachTests != "" {
+		target := testresults.Target{EntryID: *entryID, Feature: *feature}
+		entry, run, err := attachTestResults(*buddyPath, *attachTests, target, *testSource)
+		if err != nil {
+			fatal("failed to attach test results", err)
+		}
+		fmt.Printf("Attached %s test run (%d of %d failed) to history entry %s: %s\n",
+			run.Status, run.Failed, run.Total, entry.ID, entry.Description)
+		os.Exit(0)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -218,13 +991,90 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Run the server
-	if err := runServer(ctx, *buddyPath); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	projects, err := parseProjects(*projectList)
+	if err != nil {
+		fatal("invalid --projects", err)
 	}
+	opts := serverOptions{Transport: *transport, Listen: *listen, Projects: projects, MetricsListen: *metricsAddr, AuthToken: os.Getenv("BUDDY_AUTH_TOKEN"), AlsoListen: *alsoListen}
+	for _, setting := range []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{
+		{"idle-timeout", *idleTimeout, &opts.IdleTimeout},
+		{"heartbeat", *heartbeat, &opts.Heartbeat},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(setting.value)
+		if err != nil {
+			fatal("invalid --"+setting.name, err)
+		}
+		*setting.into = duration
+	}
+
+	// Every transport shuts down gracefully on a signal
+	go func() {
+		<-sigChan
+		slog.Info("shutting down")
+		cancel()
+	}()
 
-	// Wait for shutdown signal
-	<-sigChan
-	log.Println("Shutting down...")
-	cancel()
+	if err := serve(ctx, *buddyPath, opts); err != nil {
+		fatal("failed to start server", err)
+	}
+}
+
+// parseProjects parses comma-separated [name=]path project entries. An
+// entry without a name is named after the directory holding its .buddy
+// folder.
+func parseProjects(spec string) ([]projectRoot, error) {
+	var roots []projectRoot
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		root := projectRoot{Path: entry}
+		if name, path, ok := strings.Cut(entry, "="); ok {
+			root = projectRoot{Name: strings.TrimSpace(name), Path: strings.TrimSpace(path)}
+		}
+		if root.Path == "" {
+			return nil, fmt.Errorf("project %q has no path", entry)
+		}
+		if root.Name == "" {
+			root.Name = projectName(root.Path)
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+// projectName derives a project name from its buddy directory: the name of
+// the directory holding a .buddy folder, or the folder's own name
+func projectName(buddyPath string) string {
+	abs, err := filepath.Abs(buddyPath)
+	if err != nil {
+		abs = filepath.Clean(buddyPath)
+	}
+	if filepath.Base(abs) == ".buddy" {
+		abs = filepath.Dir(abs)
+	}
+	return filepath.Base(abs)
+}
+
+// fatal logs an error that stops the process and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// envOr returns the environment variable key, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
@@ -0,0 +1,555 @@
+// Package buddyserver assembles the buddy MCP server: the handlers of
+// every project, the tool call middleware, and the tools, prompts and
+// resources clients see. Serving it over a transport is up to the caller.
+package buddyserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/handlers"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/logging"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/monitor"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/schema"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// Name and version the server reports to clients
+const (
+	Name    = "Cursor Buddy MCP"
+	Version = "1.0.0"
+)
+
+// Project is a buddy directory served by the server
+type Project struct {
+	Name string
+	Path string
+}
+
+// Options configures how the server describes and protects itself
+type Options struct {
+	// Transport and AlsoListen are reported by buddy_server_info
+	Transport  string
+	AlsoListen string
+	// Networked is set when clients other than the editor can reach the
+	// server; tool calls then need the auth token, if one is configured
+	Networked bool
+	// AuthToken overrides auth.token in the default project's config.yaml
+	AuthToken string
+}
+
+// Server is an assembled MCP server with the parts its transports and
+// shutdown need
+type Server struct {
+	MCP      *server.MCPServer
+	Projects *handlers.Projects
+	Health   *handlers.HealthChecker
+	// Drainer lets shutdown wait for running tool calls
+	Drainer *handlers.CallDrainer
+	// Activity records requests, for the heartbeat log and idle shutdown
+	Activity *handlers.ActivityTracker
+	// ResourceURIs are the resources that change with the default
+	// project's files
+	ResourceURIs []string
+}
+
+// New initializes the buddy handlers of every project, the first being the
+// default, and registers the tools, prompts and resources that serve them.
+// Close the server when done.
+func New(roots []Project, opts Options) (*Server, error) {
+	startedAt := time.Now().UTC()
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no buddy directory to serve")
+	}
+	buddyPath := roots[0].Path
+
+	// Initialize the buddy handlers, one set per project
+	projects := handlers.NewProjects()
+	for _, root := range roots {
+		buddyHandlers, err := handlers.NewBuddyHandlers(root.Path)
+		if err != nil {
+			projects.Close()
+			return nil, fmt.Errorf("failed to initialize buddy handlers for project %s: %w", root.Name, err)
+		}
+		if err := projects.Add(root.Name, root.Path, buddyHandlers); err != nil {
+			buddyHandlers.Close()
+			projects.Close()
+			return nil, err
+		}
+	}
+	defaultHandlers := projects.Default()
+	// Orchestrators probe buddy_health's report over HTTP
+	health := handlers.NewHealthChecker(projects)
+
+	// Create MCP server
+	// Summaries are written by the client's model when it can sample
+	sampler := handlers.NewSampler()
+	hooks := &server.Hooks{}
+	sampler.RegisterHooks(hooks)
+	// Cancelled requests end their handler's context so searches and walks stop
+	canceller := handlers.NewRequestCanceller()
+	canceller.RegisterHooks(hooks)
+	// Shutdown waits for running tool calls
+	drainer := handlers.NewCallDrainer()
+	// Idle instances can shut themselves down
+	activity := handlers.NewActivityTracker(startedAt)
+	activity.RegisterHooks(hooks)
+	// Buddy files changed by tool calls can be reverted with buddy_undo
+	undoLog := handlers.NewUndoLog()
+	undoLog.RegisterHooks(hooks)
+	// Every call is kept for buddy_audit
+	auditLog := handlers.NewAuditLog(filepath.Join(buddyPath, logging.DirName, "audit.jsonl"))
+	// Clients sharing the server take turns changing buddy files
+	sessions := handlers.NewSessionGuard(defaultHandlers.Config)
+	sessions.RegisterHooks(hooks)
+	// Scratchpad notes last as long as the session that wrote them
+	projects.RegisterHooks(hooks)
+
+	serverOpts := []server.ServerOption{
+		server.WithToolHandlerMiddleware(handlers.TraceRequests),
+		server.WithToolHandlerMiddleware(handlers.TagSessions),
+		server.WithToolHandlerMiddleware(handlers.LogToolCalls),
+		server.WithToolHandlerMiddleware(handlers.RecordToolMetrics),
+		server.WithToolHandlerMiddleware(auditLog.Middleware),
+	}
+	// Over the network any process could reach the tools, so they can be
+	// locked behind a bearer token
+	var authToken string
+	if opts.Networked {
+		authToken = opts.AuthToken
+		if authToken == "" {
+			authToken = defaultHandlers.Config().Auth.Token
+		}
+		if authToken != "" {
+			serverOpts = append(serverOpts, server.WithToolHandlerMiddleware(handlers.RequireBearerToken(authToken)))
+		} else {
+			slog.Warn("no auth token configured; any client that can reach the listener can call tools", "transport", opts.Transport)
+		}
+	}
+	serverOpts = append(serverOpts,
+		// Expensive tools like searches can be capped in config.yaml
+		server.WithToolHandlerMiddleware(handlers.NewRateLimiter(defaultHandlers.Config).Middleware),
+		server.WithToolHandlerMiddleware(drainer.Middleware),
+		server.WithToolHandlerMiddleware(activity.Middleware),
+		server.WithToolHandlerMiddleware(sessions.Middleware),
+		server.WithToolHandlerMiddleware(canceller.Middleware),
+		server.WithToolHandlerMiddleware(undoLog.Middleware),
+		// Backups and restores outside the sandbox fail with a JSON error
+		server.WithToolHandlerMiddleware(handlers.ReportSandboxViolations),
+		// A panicking handler fails its call, seen by the middleware above,
+		// instead of taking the server down
+		server.WithRecovery(),
+		server.WithHooks(hooks),
+	)
+	mcpServer := server.NewMCPServer(Name, Version, serverOpts...)
+	sampler.Attach(mcpServer)
+	canceller.Attach(mcpServer)
+	for _, project := range projects.List() {
+		project.Handlers.SetSampler(sampler)
+		project.Handlers.SetActivity(activity)
+	}
+
+	// Register tool handlers through the registry so buddy_help can describe them
+	tools := handlers.NewToolRegistry(mcpServer, defaultHandlers.Config().Tools)
+	tools.SetProjects(projects.Names())
+
+	// Rules tool
+	rulesTool := toolargs.NewTool("buddy_get_rules",
+		"Get coding rules and guidelines from the project's buddy system, or create, update and delete rule files",
+		handlers.RulesArgs{})
+	tools.AddTool(rulesTool, handlers.WithTokenBudget(projects.Tool((*handlers.BuddyHandlers).GetRulesToolHandler)))
+
+	// Naming check tool
+	namingTool := toolargs.NewTool("buddy_check_names",
+		"Check proposed identifier names against the project glossary and naming conventions",
+		handlers.NamingArgs{})
+	tools.AddTool(namingTool, projects.Tool((*handlers.BuddyHandlers).GetNamingToolHandler))
+
+	// Knowledge search tool
+	knowledgeTool := toolargs.NewTool("buddy_search_knowledge",
+		"Search the project knowledge base for context and documentation",
+		handlers.KnowledgeArgs{})
+	tools.AddTool(knowledgeTool, handlers.WithTokenBudget(projects.Tool((*handlers.BuddyHandlers).GetKnowledgeToolHandler)))
+
+	// Database info tool
+	databaseTool := toolargs.NewTool("buddy_get_database_info",
+		"Get database schema and connection information",
+		handlers.DatabaseArgs{})
+	tools.AddTool(databaseTool, handlers.WithTokenBudget(projects.Tool((*handlers.BuddyHandlers).GetDatabaseToolHandler)))
+
+	// Fixture generation tool
+	fixtureTool := toolargs.NewTool("buddy_generate_fixtures",
+		"Generate test fixture templates for a table from the parsed database schema",
+		handlers.FixtureArgs{})
+	tools.AddTool(fixtureTool, projects.Tool((*handlers.BuddyHandlers).GetFixtureToolHandler))
+
+	// Mock API response tool
+	mockTool := toolargs.NewTool("buddy_mock",
+		"Generate deterministic mock JSON responses for a table (REST style) or for an endpoint of the OpenAPI specs in the buddy api folder, so frontend work can proceed without a running backend",
+		handlers.MockArgs{})
+	tools.AddTool(mockTool, projects.Tool((*handlers.BuddyHandlers).GetMockToolHandler))
+
+	// Todo management tool
+	todoTool := toolargs.NewTool("buddy_manage_todos",
+		"Manage project todos and track feature implementation progress",
+		handlers.TodoArgs{})
+	tools.AddTool(todoTool, handlers.WithTokenBudget(projects.Tool((*handlers.BuddyHandlers).GetTodoToolHandler)))
+
+	// History tool
+	historyTool := toolargs.NewTool("buddy_history",
+		"Track and search implementation history",
+		handlers.HistoryArgs{})
+	tools.AddTool(historyTool, handlers.WithTokenBudget(projects.Tool((*handlers.BuddyHandlers).GetHistoryToolHandler)))
+
+	// Backup tool
+	backupTool := toolargs.NewTool("buddy_backup",
+		"Manage file backups for safe code changes",
+		handlers.BackupArgs{})
+	tools.AddTool(backupTool, projects.Tool((*handlers.BuddyHandlers).GetBackupToolHandler))
+
+	// Changeset tool
+	changesetTool := toolargs.NewTool("buddy_apply_changeset",
+		"Write a group of files, backing up existing ones as one backup set first and verifying each write against the submitted content",
+		handlers.ChangesetArgs{})
+	tools.AddTool(changesetTool, projects.Tool((*handlers.BuddyHandlers).GetChangesetToolHandler))
+
+	// Reorganize tool
+	reorganizeTool := toolargs.NewTool("buddy_reorganize",
+		"Move or rename a group of knowledge or rules files between categories in one step: rewrites their category headers, moves knowledge out of the old category's folder, updates relative links to and from them, snapshots the files first and reindexes after",
+		handlers.ReorganizeArgs{})
+	tools.AddTool(reorganizeTool, projects.Tool((*handlers.BuddyHandlers).GetReorganizeToolHandler))
+
+	// Draft tool
+	draftTool := toolargs.NewTool("buddy_draft",
+		"Turn a free-form convention into a structured rule or knowledge draft for human review",
+		handlers.DraftArgs{})
+	tools.AddTool(draftTool, projects.Tool((*handlers.BuddyHandlers).GetDraftToolHandler))
+
+	// Datasets tool
+	datasetsTool := toolargs.NewTool("buddy_get_datasets",
+		"Look up canonical reference datasets (CSV/TSV lookup tables such as country or error codes) with their schema and rows",
+		handlers.DatasetsArgs{})
+	tools.AddTool(datasetsTool, handlers.WithTokenBudget(projects.Tool((*handlers.BuddyHandlers).GetDatasetsToolHandler)))
+
+	// Compliance tool
+	complianceTool := toolargs.NewTool("buddy_check_compliance",
+		"Check a dependency and its license against the project's license and compliance policies, or list the policies",
+		handlers.ComplianceArgs{})
+	tools.AddTool(complianceTool, projects.Tool((*handlers.BuddyHandlers).GetComplianceToolHandler))
+
+	// Advisory file lock tool
+	lockTool := toolargs.NewTool("buddy_lock",
+		"Advisory file locks so agents sharing this server don't edit the same files at once: acquire, release or list locks",
+		handlers.LockArgs{})
+	tools.AddTool(lockTool, projects.Tool((*handlers.BuddyHandlers).GetLockToolHandler))
+
+	// Performance budgets tool
+	budgetsTool := toolargs.NewTool("buddy_budgets",
+		"Report performance budgets (latency, binary size, bundle size) for a component, or record a measured value and warn when it exceeds its budget",
+		handlers.BudgetsArgs{})
+	tools.AddTool(budgetsTool, projects.Tool((*handlers.BuddyHandlers).GetBudgetsToolHandler))
+
+	// Security check tool
+	securityTool := toolargs.NewTool("buddy_security_check",
+		"Check a proposed change for hard-coded secrets, SQL injection-prone query building and disabled TLS verification",
+		handlers.SecurityCheckArgs{})
+	tools.AddTool(securityTool, projects.Tool((*handlers.BuddyHandlers).GetSecurityCheckToolHandler))
+
+	// Session capture tool
+	captureTool := toolargs.NewTool("buddy_capture_session",
+		"Turn a session's history entries, diffs and reasoning into a 'how we implemented X' knowledge draft. The overview is written by the client's model when it supports sampling",
+		handlers.CaptureSessionArgs{})
+	tools.AddTool(captureTool, projects.Tool((*handlers.BuddyHandlers).GetCaptureSessionToolHandler))
+
+	// Summarize tool
+	summarizeTool := toolargs.NewTool("buddy_summarize",
+		"Summarize knowledge on a topic or write a digest of recent activity. Uses the client's model through MCP sampling when available, otherwise an extractive summary",
+		handlers.SummarizeArgs{})
+	tools.AddTool(summarizeTool, projects.Tool((*handlers.BuddyHandlers).GetSummarizeToolHandler))
+
+	// Time travel tool
+	timeTravelTool := toolargs.NewTool("buddy_time_travel",
+		"Reconstruct the rules, knowledge, todo progress and history the buddy folder held at a past time, e.g. to audit what guidance the agent had when a change was made",
+		handlers.TimeTravelArgs{})
+	tools.AddTool(timeTravelTool, projects.Tool((*handlers.BuddyHandlers).GetTimeTravelToolHandler))
+
+	// Handoff tool
+	handoffTool := toolargs.NewTool("buddy_handoff",
+		"Package everything about one feature - its todos and history, the knowledge and rules about it or the files it touched, and the tables involved - into one bundle for a teammate taking it over or for another repository",
+		handlers.HandoffArgs{})
+	tools.AddTool(handoffTool, projects.Tool((*handlers.BuddyHandlers).GetHandoffToolHandler))
+
+	// Views tool
+	viewsTool := toolargs.NewTool("buddy_views",
+		"Run one of the project's saved views - queries over rules, knowledge, todos or history defined in .buddy/views, such as \"critical rules for files changed this week\" - or list them",
+		handlers.ViewsArgs{})
+	tools.AddTool(viewsTool, handlers.WithTokenBudget(projects.Tool((*handlers.BuddyHandlers).GetViewsToolHandler)))
+
+	// Focus tool
+	focusTool := toolargs.NewTool("buddy_focus",
+		"Show or set the features currently being worked on. While a focus is set, todo and history lists show only those features and searches rank them first.",
+		handlers.FocusArgs{})
+	tools.AddTool(focusTool, projects.Tool((*handlers.BuddyHandlers).GetFocusToolHandler))
+
+	// Scratchpad tool
+	scratchpadTool := toolargs.NewTool("buddy_scratchpad",
+		"Keep small named notes, such as intermediate findings or a plan, between tool calls of this session. Notes are removed when the session ends unless marked to be saved as a history entry or knowledge draft.",
+		handlers.ScratchpadArgs{})
+	tools.AddTool(scratchpadTool, projects.Tool((*handlers.BuddyHandlers).GetScratchpadToolHandler))
+
+	// Status tool
+	statusTool := toolargs.NewTool("buddy_status",
+		"Get an overview of loaded buddy content, search index disk usage and any active warnings",
+		handlers.StatusArgs{})
+	tools.AddTool(statusTool, projects.Tool((*handlers.BuddyHandlers).GetStatusToolHandler))
+
+	// Quality tool
+	qualityTool := toolargs.NewTool("buddy_quality",
+		"Score the health of buddy content and list actionable findings for curation",
+		handlers.QualityArgs{})
+	tools.AddTool(qualityTool, projects.Tool((*handlers.BuddyHandlers).GetQualityToolHandler))
+
+	// Setup wizard tool
+	setupTool := toolargs.NewTool("buddy_setup",
+		"Onboarding wizard for an empty .buddy folder: call without arguments for the questions to ask the user, then again with the answers to generate tailored starter rules, knowledge and todos. The questions come back as text for you to relay, since the MCP library in use (mcp-go v0.33) can't send elicitation requests",
+		handlers.SetupArgs{})
+	tools.AddTool(setupTool, projects.Tool((*handlers.BuddyHandlers).GetSetupToolHandler))
+
+	// Tools of custom handlers registered with handlers.RegisterHandler
+	for _, name := range handlers.RegisteredHandlers() {
+		tools.AddTool(defaultHandlers.CustomHandler(name).Tool(), projects.Tool(func(bh *handlers.BuddyHandlers) server.ToolHandlerFunc {
+			return bh.CustomHandler(name).GetToolHandler()
+		}))
+	}
+
+	// Undo tool
+	undoTool := toolargs.NewTool("buddy_undo",
+		"Revert the buddy files changed by this session's last tool calls, such as todo updates, history entries and drafts",
+		handlers.UndoArgs{})
+	tools.AddTool(undoTool, undoLog.GetToolHandler(func() error {
+		for _, project := range projects.List() {
+			if err := project.Handlers.ReloadData(); err != nil {
+				return fmt.Errorf("project %s: %w", project.Name, err)
+			}
+		}
+		return nil
+	}))
+
+	// Audit tool
+	auditTool := toolargs.NewTool("buddy_audit",
+		"List logged tool calls, newest first, with their arguments (secrets redacted), duration, result size and error, to see what an agent actually did",
+		handlers.AuditArgs{})
+	tools.AddTool(auditTool, auditLog.GetToolHandler())
+
+	// Health tool
+	healthTool := mcp.NewTool("buddy_health",
+		mcp.WithDescription("Check that the search indexes are open, the buddy folders readable and file monitoring running; reports ok or degraded with the failing checks"),
+	)
+	tools.AddTool(healthTool, health.GetToolHandler())
+
+	// Server info tool
+	serverInfoTool := mcp.NewTool("buddy_server_info",
+		mcp.WithDescription("Report the server version, registered tools, document counts per index, buddy paths, last reload time and configuration summary as JSON"),
+	)
+	tools.AddTool(serverInfoTool, tools.GetServerInfoToolHandler(handlers.ServerInfo{
+		Name:         Name,
+		Version:      Version,
+		Transport:    opts.Transport,
+		AlsoListen:   opts.AlsoListen,
+		StartedAt:    startedAt,
+		AuthRequired: authToken != "",
+	}, projects, sessions))
+
+	// Help tool
+	helpTool := toolargs.NewTool("buddy_help",
+		"Describe every available buddy tool with its actions, arguments and example calls as JSON",
+		handlers.HelpArgs{})
+	tools.AddTool(helpTool, tools.GetHelpToolHandler())
+
+	// Resources are served from the default project
+	// Register prompts that template buddy content
+	prompts := handlers.NewPromptRegistry(mcpServer)
+	prompts.SetProjects(projects.Names())
+
+	prompts.AddPrompt(mcp.NewPrompt("apply-critical-rules",
+		mcp.WithPromptDescription("Carry out a task while following the project's critical rules"),
+		mcp.WithArgument("task",
+			mcp.ArgumentDescription("What you are about to do (optional)"),
+		),
+		mcp.WithArgument("category",
+			mcp.ArgumentDescription("Only include rules in this category (optional)"),
+		),
+		mcp.WithArgument("include_recommended",
+			mcp.ArgumentDescription("Set to true to include recommended rules too"),
+		),
+	), projects.Prompt((*handlers.BuddyHandlers).GetApplyCriticalRulesPromptHandler))
+
+	prompts.AddPrompt(mcp.NewPrompt("summarize-todos",
+		mcp.WithPromptDescription("Report progress on the todo lists and suggest what to do next"),
+		mcp.WithArgument("feature",
+			mcp.ArgumentDescription("Only summarize this feature's todos (optional)"),
+		),
+	), projects.Prompt((*handlers.BuddyHandlers).GetSummarizeTodosPromptHandler))
+
+	prompts.AddPrompt(mcp.NewPrompt("write-history-entry",
+		mcp.WithPromptDescription("Record the work just done as a history entry"),
+		mcp.WithArgument("feature",
+			mcp.ArgumentDescription("Feature the work belongs to"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("summary",
+			mcp.ArgumentDescription("Short description of the work (optional)"),
+		),
+	), projects.Prompt((*handlers.BuddyHandlers).GetWriteHistoryEntryPromptHandler))
+
+	// Add project context resource
+	projectResource := mcp.NewResource(
+		"buddy://project-context",
+		"Project Context",
+		mcp.WithResourceDescription("Complete project context including rules, knowledge, database schema, and todos"),
+		mcp.WithMIMEType("application/json"),
+	)
+	mcpServer.AddResource(projectResource, defaultHandlers.GetProjectContextResourceHandler())
+
+	// Add one resource per project context section, for clients that
+	// don't need all of it
+	sectionResources := map[string]struct{ name, description string }{
+		"rules":     {"Project Rules", "Coding rules and standards"},
+		"knowledge": {"Project Knowledge", "Project knowledge documents"},
+		"todos":     {"Project Todos", "Todo lists and their tasks"},
+		"database":  {"Database Schema", "Parsed database schema: tables, columns, indexes and constraints"},
+		"history":   {"Recent History", "The 10 most recent implementation history entries"},
+	}
+	sectionURIs := make([]string, 0, len(handlers.ContextSections))
+	for _, section := range handlers.ContextSections {
+		uri := "buddy://" + section
+		sectionURIs = append(sectionURIs, uri)
+		mcpServer.AddResource(mcp.NewResource(
+			uri,
+			sectionResources[section].name,
+			mcp.WithResourceDescription(sectionResources[section].description),
+			mcp.WithMIMEType("application/json"),
+		), defaultHandlers.GetSectionResourceHandler(section))
+	}
+
+	// Add priority inbox resource
+	inboxResource := mcp.NewResource(
+		"buddy://inbox",
+		"Priority Inbox",
+		mcp.WithResourceDescription("Urgent items across buddy content to check at session start: reload errors, overdue todos, recently changed critical rules and drafts awaiting review"),
+		mcp.WithMIMEType("application/json"),
+	)
+	mcpServer.AddResource(inboxResource, defaultHandlers.GetInboxResourceHandler())
+
+	// Add content schemas, so tools and people can author compatible files
+	schemasResource := mcp.NewResource(
+		"buddy://schemas",
+		"Buddy Content Schemas",
+		mcp.WithResourceDescription("JSON Schemas that history entries, backup metadata, config.yaml and dataset frontmatter are validated against when loaded"),
+		mcp.WithMIMEType("application/json"),
+	)
+	mcpServer.AddResource(schemasResource, handlers.GetSchemasResourceHandler())
+	for _, info := range schema.List() {
+		mcpServer.AddResource(mcp.NewResource(
+			info.URI,
+			info.Title+" Schema",
+			mcp.WithResourceDescription(info.Description),
+			mcp.WithMIMEType("application/schema+json"),
+		), handlers.GetSchemaResourceHandler(info.Name))
+	}
+
+	// Add incremental changes resource
+	changesTemplate := mcp.NewResourceTemplate(
+		"buddy://changes{?since}",
+		"Buddy Changes",
+		mcp.WithTemplateDescription("Buddy documents added, modified or removed since a time (RFC3339, YYYY-MM-DD, 'last 3 days', 'PT1H')"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+	mcpServer.AddResourceTemplate(changesTemplate, defaultHandlers.GetChangesResourceHandler())
+
+	// Add saved views resource; the query parameters are the view's params
+	viewsTemplate := mcp.NewResourceTemplate(
+		"buddy://views/{name}{?params*}",
+		"Buddy Views",
+		mcp.WithTemplateDescription("The result of a saved view defined in .buddy/views, with its params as query parameters, e.g. buddy://views/feature-rules?feature=checkout"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+	mcpServer.AddResourceTemplate(viewsTemplate, defaultHandlers.GetViewResourceHandler())
+
+	return &Server{
+		MCP:          mcpServer,
+		Projects:     projects,
+		Health:       health,
+		Drainer:      drainer,
+		Activity:     activity,
+		ResourceURIs: append([]string{"buddy://project-context", "buddy://inbox", "buddy://changes"}, sectionURIs...),
+	}, nil
+}
+
+// knowledgeDebounce is how long knowledge documents must go unchanged
+// before they are reloaded; editors often save long documents in bursts
+const knowledgeDebounce = 2 * time.Second
+
+// StartMonitor watches every project's files, reloading a project's
+// handlers when they change and telling clients when the default
+// project's resources did. Critical rules are reloaded and announced at
+// once; knowledge waits for a quiet spell. It returns a function that
+// stops monitoring and waits for it to end.
+func (s *Server) StartMonitor() func() {
+	projects := s.Projects.List()
+	buddyPath := projects[0].Path
+	notifier := handlers.NewResourceNotifier(s.MCP, s.ResourceURIs...)
+	store := projects[0].Handlers.Storage()
+	fileMonitor := monitor.NewFileMonitor(buddyPath, projects[0].Handlers)
+	fileMonitor.SetStorage(store)
+	for _, project := range projects[1:] {
+		fileMonitor.AddRoot(project.Path, project.Handlers)
+	}
+	fileMonitor.OnReload(func(root string) {
+		if root == buddyPath {
+			notifier.Notify()
+		}
+	})
+	for _, name := range handlers.RegisteredHandlers() {
+		fileMonitor.WatchDir(name)
+	}
+	fileMonitor.SetPolicy("rules", monitor.EventPolicy{Urgent: func(path string) bool {
+		return handlers.CriticalRuleFile(store, path)
+	}})
+	fileMonitor.SetPolicy("knowledge", monitor.EventPolicy{Debounce: knowledgeDebounce})
+	fileMonitor.OnUrgent(func(root, path string) {
+		if root == buddyPath {
+			notifier.NotifyNow()
+		}
+		for _, project := range projects {
+			if project.Path == root {
+				project.Handlers.AnnounceCriticalRule(project.Name, path)
+			}
+		}
+	})
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	if err := fileMonitor.Start(monitorCtx); err != nil {
+		slog.Warn("failed to start file monitoring", "error", err)
+	}
+	s.Health.AddCheck("file_monitor", func() error {
+		if !fileMonitor.Running() {
+			return fmt.Errorf("file monitoring has stopped; changed buddy files aren't reloaded")
+		}
+		return nil
+	})
+	return func() {
+		stopMonitor()
+		fileMonitor.Wait()
+	}
+}
+
+// Close closes every project's handlers and search indexes. Stop file
+// monitoring first.
+func (s *Server) Close() error {
+	return s.Projects.Close()
+}
@@ -0,0 +1,822 @@
+package buddyserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/handlers"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const petsSpec = `openapi: 3.0.3
+info:
+  title: Pets
+  version: "1"
+paths:
+  /pets:
+    get:
+      responses:
+        200:
+          description: Pets
+          content:
+            application/json:
+              schema:
+                type: array
+                minItems: 2
+                items:
+                  $ref: '#/components/schemas/Pet'
+  /pets/{petId}:
+    get:
+      responses:
+        200:
+          description: A pet
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+        404:
+          description: Not found
+          content:
+            application/json:
+              example:
+                error: pet not found
+  /pets/mine:
+    get:
+      responses:
+        204:
+          description: No pets
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        id:
+          type: integer
+        owner_email:
+          type: string
+          format: email
+        kind:
+          type: string
+          enum: [cat, dog]
+`
+
+func TestMock(t *testing.T) {
+	client := testutil.Start(t, testutil.BuddyDir(t, map[string]string{
+		"database/schema.sql": "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL, active BOOLEAN);\n",
+		"api/pets.yaml":       petsSpec,
+	}))
+
+	t.Run("table list", func(t *testing.T) {
+		text := client.CallText(t, "buddy_mock", map[string]any{"table_name": "users", "count": 2})
+		assert.Contains(t, text, "Mock response for GET /users")
+		assert.Contains(t, text, `"email": "user2@example.com"`)
+		assert.NotContains(t, text, "user3@example.com")
+	})
+
+	t.Run("table row", func(t *testing.T) {
+		text := client.CallText(t, "buddy_mock", map[string]any{"table_name": "users", "id": 42})
+		assert.Contains(t, text, "Mock response for GET /users/42")
+		assert.Contains(t, text, `"id": 42`)
+		assert.Contains(t, text, `"email": "user42@example.com"`)
+		assert.Equal(t, text, client.CallText(t, "buddy_mock", map[string]any{"table_name": "users", "id": 42}))
+	})
+
+	t.Run("endpoint", func(t *testing.T) {
+		text := client.CallText(t, "buddy_mock", map[string]any{"path": "/pets/7"})
+		assert.Contains(t, text, "pets.yaml /pets/{petId}, status 200, application/json")
+		assert.Contains(t, text, `"id": 7`)
+		assert.Contains(t, text, `"owner_email": "user7@example.com"`)
+		assert.Contains(t, text, `"kind": "cat"`)
+
+		list := client.CallText(t, "buddy_mock", map[string]any{"path": "/pets"})
+		assert.Contains(t, list, `"id": 2`)
+		assert.NotContains(t, list, `"id": 3`)
+	})
+
+	t.Run("status and literal paths", func(t *testing.T) {
+		assert.Contains(t, client.CallText(t, "buddy_mock", map[string]any{"path": "/pets/7", "status": "404"}), `"error": "pet not found"`)
+		assert.Contains(t, client.CallText(t, "buddy_mock", map[string]any{"path": "/pets/mine"}), "(no body)")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := client.Call("buddy_mock", map[string]any{"path": "/owners"})
+		assert.ErrorContains(t, err, "no operation for GET /owners")
+		_, err = client.Call("buddy_mock", map[string]any{})
+		assert.ErrorContains(t, err, "table_name or path is required")
+	})
+}
+
+// incidentsIndex is the search index of the custom handler below
+const incidentsIndex search.IndexType = "incidents"
+
+// incidentsHandler is a minimal custom handler: it indexes the titles of
+// the markdown files in incidents/ and lists or searches them
+type incidentsHandler struct {
+	dir    string
+	store  storage.Storage
+	search *search.SearchManager
+	mu     sync.RWMutex
+	titles []string
+}
+
+func init() {
+	handlers.RegisterHandler("incidents", func(dir string, store storage.Storage, searchManager *search.SearchManager) (handlers.Handler, error) {
+		return &incidentsHandler{dir: dir, store: store, search: searchManager}, nil
+	})
+}
+
+func (ih *incidentsHandler) Load(ctx context.Context) error {
+	files, err := ih.store.List(ih.dir, false)
+	if err != nil && !storage.IsNotExist(err) {
+		return err
+	}
+	if err := ih.search.ReindexAll(incidentsIndex); err != nil {
+		return err
+	}
+	var titles []string
+	for _, file := range files {
+		content, err := ih.store.Read(file.Path)
+		if err != nil {
+			return err
+		}
+		title, _, _ := strings.Cut(strings.TrimPrefix(string(content), "# "), "\n")
+		titles = append(titles, title)
+		if err := ih.search.IndexDocument(incidentsIndex, filepath.Base(file.Path), map[string]interface{}{"title": title}); err != nil {
+			return err
+		}
+	}
+	ih.mu.Lock()
+	ih.titles = titles
+	ih.mu.Unlock()
+	return nil
+}
+
+func (ih *incidentsHandler) IndexType() search.IndexType {
+	return incidentsIndex
+}
+
+func (ih *incidentsHandler) Tool() mcp.Tool {
+	return mcp.NewTool("buddy_incidents",
+		mcp.WithDescription("List or search past incidents"),
+		mcp.WithString("query", mcp.Description("Words to search incident titles for")),
+	)
+}
+
+func (ih *incidentsHandler) GetToolHandler() server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		query := request.GetString("query", "")
+		if query == "" {
+			ih.mu.RLock()
+			defer ih.mu.RUnlock()
+			return mcp.NewToolResultText(strings.Join(ih.titles, "\n")), nil
+		}
+		results, err := ih.search.Search(incidentsIndex, query, 10)
+		if err != nil {
+			return nil, err
+		}
+		var ids []string
+		for _, hit := range results.Hits {
+			ids = append(ids, hit.ID)
+		}
+		return mcp.NewToolResultText(strings.Join(ids, "\n")), nil
+	}
+}
+
+func TestCustomHandler(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"incidents/outage.md": "# Database outage\n\nThe primary failed over.\n",
+	})
+	client := testutil.Start(t, buddyPath, testutil.WithFileMonitor())
+
+	assert.Equal(t, "Database outage", client.CallText(t, "buddy_incidents", map[string]any{}))
+	assert.Equal(t, "outage.md", client.CallText(t, "buddy_incidents", map[string]any{"query": "database"}))
+
+	// New files are picked up by the file monitor
+	require.NoError(t, os.WriteFile(filepath.Join(buddyPath, "incidents", "certs.md"), []byte("# Expired certificate\n"), 0644))
+	assert.Eventually(t, func() bool {
+		return client.CallText(t, "buddy_incidents", map[string]any{"query": "certificate"}) == "certs.md"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestReloadCircuitBreaker(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"rules/style.md": "# Style\nCategory: style\nPriority: critical\n\n- Use gofmt\n",
+	})
+	client := testutil.Start(t, buddyPath, testutil.WithFileMonitor())
+	badPath := filepath.Join(buddyPath, "rules", "bad.md")
+
+	// Each save of the broken file is retried until the budget is used up
+	saves := 0
+	require.Eventually(t, func() bool {
+		saves++
+		require.NoError(t, os.WriteFile(badPath, []byte(fmt.Sprintf("---\nforbid: [\"(\"]\n---\n# Bad %d\n", saves)), 0644))
+		time.Sleep(100 * time.Millisecond)
+		return strings.Contains(client.CallText(t, "buddy_status", map[string]any{}), "times in a row on rules/bad.md")
+	}, 10*time.Second, 50*time.Millisecond)
+
+	// The last good rules are still served and the server reports degraded
+	assert.Contains(t, client.CallText(t, "buddy_get_rules", map[string]any{}), "Style")
+	assert.Contains(t, client.CallText(t, "buddy_health", map[string]any{}), "failed reloads on rules/bad.md")
+
+	// Changes to other files wait for the backoff
+	require.NoError(t, os.WriteFile(filepath.Join(buddyPath, "rules", "naming.md"), []byte("# Naming\nCategory: naming\nPriority: optional\n\n- Use camelCase\n"), 0644))
+	time.Sleep(500 * time.Millisecond)
+	assert.NotContains(t, client.CallText(t, "buddy_get_rules", map[string]any{}), "Naming")
+
+	// Fixing the failing file closes the breaker at once
+	require.NoError(t, os.WriteFile(badPath, []byte("# Bad\nCategory: misc\nPriority: optional\n\n- Fixed\n"), 0644))
+	assert.Eventually(t, func() bool {
+		return strings.Contains(client.CallText(t, "buddy_status", map[string]any{}), "✅ No warnings")
+	}, 5*time.Second, 50*time.Millisecond)
+	rules := client.CallText(t, "buddy_get_rules", map[string]any{})
+	assert.Contains(t, rules, "Naming")
+	assert.Contains(t, rules, "Bad")
+	assert.NotContains(t, client.CallText(t, "buddy_health", map[string]any{}), "failed reloads")
+}
+
+func TestFocus(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"todos/login.md":     "# Feature: Login\n\n- [ ] Add login form\n",
+		"todos/billing.md":   "# Feature: Billing\n\n- [ ] Send invoices\n",
+		"knowledge/login.md": "# Sessions\nCategory: security\n\nLogin sessions are stored in Redis.\n",
+		"knowledge/cache.md": "# Caching\nCategory: ops\n\nRedis caches rendered pages.\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	assert.Contains(t, client.CallText(t, "buddy_focus", map[string]any{}), "No focus set")
+	set := client.CallText(t, "buddy_focus", map[string]any{"action": "set", "features": []any{"billing"}, "note": "invoice run"})
+	assert.Contains(t, set, "Current focus: billing")
+	assert.FileExists(t, filepath.Join(buddyPath, "focus.json"))
+
+	todos := client.CallText(t, "buddy_manage_todos", map[string]any{"action": "list"})
+	assert.Contains(t, todos, "Focus: billing")
+	assert.Contains(t, todos, "Send invoices")
+	assert.NotContains(t, todos, "Add login form")
+	assert.Contains(t, client.CallText(t, "buddy_manage_todos", map[string]any{"action": "list", "ignore_focus": true}), "Add login form")
+	assert.Contains(t, client.CallText(t, "buddy_manage_todos", map[string]any{"action": "list", "feature": "Login"}), "Add login form")
+
+	client.CallText(t, "buddy_focus", map[string]any{"action": "add", "features": []any{"login", "Billing"}})
+	assert.Contains(t, client.CallText(t, "buddy_focus", map[string]any{"action": "get"}), "Current focus: billing, login\nNote: invoice run")
+	knowledge := client.CallText(t, "buddy_search_knowledge", map[string]any{"query": "redis"})
+	assert.Less(t, strings.Index(knowledge, "Sessions"), strings.Index(knowledge, "Caching"))
+
+	assert.Contains(t, client.CallText(t, "buddy_focus", map[string]any{"action": "clear"}), "No focus set")
+	assert.NoFileExists(t, filepath.Join(buddyPath, "focus.json"))
+	assert.NotContains(t, client.CallText(t, "buddy_manage_todos", map[string]any{"action": "list"}), "Focus:")
+
+	// Focus changes are undone like other tool calls
+	client.CallText(t, "buddy_undo", map[string]any{})
+	assert.FileExists(t, filepath.Join(buddyPath, "focus.json"))
+	assert.Contains(t, client.CallText(t, "buddy_focus", map[string]any{"action": "get"}), "Current focus: billing, login")
+
+	_, err := client.Call("buddy_focus", map[string]any{"action": "set"})
+	assert.ErrorContains(t, err, "features is required")
+}
+
+func TestRuleEdits(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"rules/style.md": "---\nforbid:\n  - 'fmt\\.Println'\n---\n# Style\nCategory: style\nPriority: optional\n\n- Use gofmt\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	created := client.CallText(t, "buddy_get_rules", map[string]any{
+		"action": "create", "title": "Wrap Errors", "category": "errors", "priority": "critical",
+		"content": "Wrap returned errors with %w.", "applies_to": []any{"**/*.go"},
+	})
+	assert.Contains(t, created, "Created rule \"Wrap Errors\"\n\nFile: rules/wrap-errors.md")
+	content, err := os.ReadFile(filepath.Join(buddyPath, "rules", "wrap-errors.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "# Wrap Errors\nCategory: errors\nPriority: critical\n\nWrap returned errors with %w.\n")
+	assert.Contains(t, client.CallText(t, "buddy_get_rules", map[string]any{"priority": "critical"}), "Wrap Errors")
+
+	_, err = client.Call("buddy_get_rules", map[string]any{"action": "create", "title": "Style", "content": "x", "file": "style"})
+	assert.ErrorContains(t, err, "rules/style.md already exists")
+
+	// Updates keep the frontmatter and the headers they don't change
+	updated := client.CallText(t, "buddy_get_rules", map[string]any{"action": "update", "rule": "style", "priority": "recommended", "content": "- Run gofmt before committing"})
+	assert.Contains(t, updated, "Priority: recommended")
+	content, err = os.ReadFile(filepath.Join(buddyPath, "rules", "style.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "---\nforbid:\n  - 'fmt\\.Println'\n---\n# Style\nCategory: style\nPriority: recommended\n\n- Run gofmt before committing\n", string(content))
+
+	assert.Contains(t, client.CallText(t, "buddy_get_rules", map[string]any{"action": "delete", "rule": "wrap errors"}), "Deleted rule \"Wrap Errors\"")
+	assert.NoFileExists(t, filepath.Join(buddyPath, "rules", "wrap-errors.md"))
+	assert.NotContains(t, client.CallText(t, "buddy_get_rules", map[string]any{}), "Wrap Errors")
+
+	scoped := client.CallText(t, "buddy_get_rules", map[string]any{"file_path": "internal/handlers/rules.go"})
+	assert.Contains(t, scoped, "Found 1 rules for file: internal/handlers/rules.go")
+	assert.Contains(t, scoped, "Style")
+	client.CallText(t, "buddy_get_rules", map[string]any{"action": "create", "title": "Handler Docs", "content": "Document every handler.", "applies_to": []any{"internal/handlers/**"}})
+	scoped = client.CallText(t, "buddy_get_rules", map[string]any{"file_path": "internal/handlers/rules.go"})
+	assert.Contains(t, scoped, "Handler Docs")
+	assert.Contains(t, scoped, "Applies to: internal/handlers/**")
+	assert.NotContains(t, client.CallText(t, "buddy_get_rules", map[string]any{"file_path": "cmd/main.go"}), "Handler Docs")
+
+	_, err = client.Call("buddy_get_rules", map[string]any{"action": "update", "rule": "style", "priority": "urgent"})
+	assert.Error(t, err)
+	_, err = client.Call("buddy_get_rules", map[string]any{"action": "delete", "rule": "missing"})
+	assert.ErrorContains(t, err, "no rule matches")
+}
+
+func TestRuleDisable(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"rules/style.md":  "# Style\nCategory: style\nPriority: critical\n\n- Use gofmt\n",
+		"rules/legacy.md": "# Legacy Logging\nCategory: logging\nPriority: optional\nDisabled: true\n\n- Use log.Printf\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	list := client.CallText(t, "buddy_get_rules", map[string]any{})
+	assert.Contains(t, list, "Found 1 rules")
+	assert.Contains(t, list, "⏸️ 1 disabled rules not shown: Legacy Logging")
+	assert.NotContains(t, client.CallText(t, "buddy_get_rules", map[string]any{"search": "log.Printf"}), "Legacy Logging")
+
+	disabled := client.CallText(t, "buddy_get_rules", map[string]any{"action": "disable", "rule": "style"})
+	assert.Contains(t, disabled, "Disabled rule \"Style\"")
+	content, err := os.ReadFile(filepath.Join(buddyPath, "rules", "style.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Style\nCategory: style\nPriority: critical\nDisabled: true\n\n- Use gofmt\n", string(content))
+	assert.Contains(t, client.CallText(t, "buddy_get_rules", map[string]any{}), "No rules found")
+
+	_, err = client.Call("buddy_get_rules", map[string]any{"action": "disable", "rule": "Style"})
+	assert.ErrorContains(t, err, "rule \"Style\" is already disabled")
+
+	client.CallText(t, "buddy_get_rules", map[string]any{"action": "enable", "rule": "Legacy Logging"})
+	content, err = os.ReadFile(filepath.Join(buddyPath, "rules", "legacy.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Legacy Logging\nCategory: logging\nPriority: optional\n\n- Use log.Printf\n", string(content))
+	assert.Contains(t, client.CallText(t, "buddy_get_rules", map[string]any{"search": "log.Printf"}), "Legacy Logging")
+}
+
+func TestHandoff(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"todos/billing.md":    "# Feature: Billing\n\n- [x] Store invoices in the invoices table\n- [ ] Email invoices\n",
+		"todos/login.md":      "# Feature: Login\n\n- [ ] Add login form\n",
+		"history/h1.json":     `{"id":"h1","timestamp":"2024-03-01T00:00:00Z","feature":"billing","description":"Added invoices","changes":[{"file_path":"internal/billing/invoice.go","change_type":"created"}]}`,
+		"knowledge/pdf.md":    "# Invoice PDFs\nCategory: docs\n\nRendering lives in `internal/billing/invoice.go`.\n",
+		"knowledge/cache.md":  "# Caching\nCategory: ops\n\nRedis caches rendered pages.\n",
+		"rules/money.md":      "---\napplies_to:\n  - 'internal/billing/**'\n---\n# Money\nCategory: style\nPriority: critical\n\nStore amounts in cents.\n",
+		"rules/style.md":      "# Style\nCategory: style\nPriority: optional\n\nUse gofmt.\n",
+		"database/schema.sql": "CREATE TABLE invoices (id INTEGER PRIMARY KEY, total INTEGER);\nCREATE TABLE users (id INTEGER PRIMARY KEY);\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	text := client.CallText(t, "buddy_handoff", map[string]any{"feature": "Billing"})
+	assert.Contains(t, text, ": Billing handoff")
+	for _, want := range []string{"Email invoices", "Added invoices", "Invoice PDFs", "Money", "### invoices"} {
+		assert.Contains(t, text, want)
+	}
+	for _, unwanted := range []string{"Add login form", "Caching", "Style", "### users"} {
+		assert.NotContains(t, text, unwanted)
+	}
+
+	var bundle handlers.ExportBundle
+	require.NoError(t, json.Unmarshal([]byte(client.CallText(t, "buddy_handoff", map[string]any{"feature": "billing", "format": "json"})), &bundle))
+	assert.Equal(t, "billing", bundle.Feature)
+	assert.Len(t, bundle.Todos, 2)
+	assert.Len(t, bundle.Rules, 1)
+
+	_, err := client.Call("buddy_handoff", map[string]any{"feature": "search"})
+	assert.ErrorContains(t, err, "known features: Billing, Login")
+}
+
+func TestKnowledgeLanguage(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"knowledge/migrationen.md": "# Migrationen\nCategory: database\n\nDie Datenbank wird bei jedem Start migriert, und es ist nicht nötig, das von Hand zu tun.\n",
+		"knowledge/migrations.md":  "# Migrations\nCategory: database\n\nThe database is migrated on every start, so there is no need to run them by hand.\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	german := client.CallText(t, "buddy_search_knowledge", map[string]any{"query": "Datenbanken", "language": "German"})
+	assert.Contains(t, german, "Migrationen")
+	assert.Contains(t, german, "Language: German")
+	assert.NotContains(t, german, "Migrations\n")
+
+	english := client.CallText(t, "buddy_search_knowledge", map[string]any{"query": "migrated", "language": "en"})
+	assert.Contains(t, english, "Migrations")
+	assert.NotContains(t, english, "Migrationen")
+
+	_, err := client.Call("buddy_search_knowledge", map[string]any{"query": "start", "language": "klingon"})
+	assert.ErrorContains(t, err, "unknown language")
+}
+
+func TestRuleTemplates(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"rules/style.md": "# Style\nCategory: style\nPriority: optional\n\n- Use gofmt\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	templates := client.CallText(t, "buddy_get_rules", map[string]any{"action": "templates"})
+	assert.Contains(t, templates, "testing-policy")
+	assert.Contains(t, templates, "test_command: Command running the test suite, e.g. go test ./... (required)")
+
+	_, err := client.Call("buddy_get_rules", map[string]any{"action": "create_from_template", "template": "testing-policy"})
+	assert.ErrorContains(t, err, "needs a value for test_command")
+	_, err = client.Call("buddy_get_rules", map[string]any{"action": "create_from_template", "template": "naming-conventions", "values": map[string]any{"type_case": "Title Case"}})
+	assert.ErrorContains(t, err, "invalid type_case")
+
+	created := client.CallText(t, "buddy_get_rules", map[string]any{
+		"action": "create_from_template", "template": "testing-policy",
+		"values": map[string]any{"test_command": "go test ./...", "coverage": 90},
+	})
+	assert.Contains(t, created, "Created rule \"Testing Policy\"\n\nFile: rules/testing-policy.md")
+	content, err := os.ReadFile(filepath.Join(buddyPath, "rules", "testing-policy.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "# Testing Policy\nCategory: testing\nPriority: critical\n\n")
+	assert.Contains(t, string(content), "- Run `go test ./...` before committing; it must pass.\n- Keep the coverage of changed code at 90% or more.")
+
+	// The naming template's header feeds buddy_check_names
+	client.CallText(t, "buddy_get_rules", map[string]any{"action": "create_from_template", "template": "naming-conventions", "values": map[string]any{"function_case": "snake_case"}})
+	names := client.CallText(t, "buddy_check_names", map[string]any{"names": []any{"loadUser"}, "kind": "function"})
+	assert.Contains(t, names, "function names should be snake_case")
+}
+
+func TestRuleLint(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"rules/style.md":   "# Style\nCategory: style\nPriority: critical\n\n- Use gofmt\n",
+		"rules/style-2.md": "# style\nPriority: urgent\n\n- Keep lines short\n",
+		"rules/empty.md":   "# Empty\nCategory: misc\nPriority: optional\n\n",
+		"rules/long.md":    "# Long\nCategory: misc\nPriority: optional\n\n" + strings.Repeat("Explain every decision. ", 20) + "\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	lint := client.CallText(t, "buddy_get_rules", map[string]any{"action": "lint", "max_length": 200})
+	assert.Contains(t, lint, "Rule lint: 4 rules checked, 4 errors, 2 warnings")
+	assert.Contains(t, lint, "Empty (rules/empty.md)\n   ❌ empty_description")
+	assert.Contains(t, lint, "   ⚠️ too_long: 479 characters, over the 200 limit")
+	assert.Contains(t, lint, "   ❌ unknown_priority: priority \"urgent\"")
+	assert.Contains(t, lint, "   ⚠️ missing_category")
+	assert.Contains(t, lint, "duplicate_title: same title as rules/style.md")
+	assert.Contains(t, lint, "duplicate_title: same title as rules/style-2.md")
+
+	clean := client.CallText(t, "buddy_get_rules", map[string]any{"action": "lint", "category": "style"})
+	assert.Equal(t, "✅ Rule lint: 1 rules checked, no issues\n", clean)
+}
+
+func TestRuleHistory(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"rules/errors.md": "# Wrap Errors\nCategory: errors\nPriority: recommended\n\n- Wrap returned errors with %w\n",
+	})
+	client := testutil.Start(t, buddyPath, testutil.WithFileMonitor())
+	rulePath := filepath.Join(buddyPath, "rules", "errors.md")
+	history := func() string {
+		return client.CallText(t, "buddy_get_rules", map[string]any{"action": "history", "rule": "Wrap Errors"})
+	}
+	assert.Contains(t, history(), "📜 History of \"Wrap Errors\" (rules/errors.md): 1 versions")
+
+	require.NoError(t, os.WriteFile(rulePath, []byte("# Wrap Errors\nCategory: errors\nPriority: critical\n\n- Wrap returned errors with %w\n- Never discard an error\n"), 0644))
+	require.Eventually(t, func() bool {
+		return strings.Contains(history(), ": 2 versions")
+	}, 5*time.Second, 50*time.Millisecond)
+	text := history()
+	assert.Contains(t, text, "v1 · ")
+	assert.Contains(t, text, " — created\n   Priority: recommended · Category: errors\n")
+	assert.Contains(t, text, " — current\n   Priority: critical (was recommended)\n")
+	assert.Contains(t, text, "   + - Never discard an error\n")
+	assert.NotContains(t, text, "   - - Wrap returned errors")
+
+	// Deleted rules keep their history, found by file name
+	require.NoError(t, os.Remove(rulePath))
+	require.Eventually(t, func() bool {
+		return strings.Contains(client.CallText(t, "buddy_get_rules", map[string]any{"action": "history", "rule": "errors.md"}), " — deleted")
+	}, 5*time.Second, 50*time.Millisecond)
+
+	_, err := client.Call("buddy_get_rules", map[string]any{"action": "history", "rule": "missing"})
+	assert.ErrorContains(t, err, "no rule matches")
+}
+
+func TestRuleExtends(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"rules/org-errors.md": "---\nforbid:\n  - 'errors\\.New'\n---\n# Org Errors\nCategory: errors\nPriority: recommended\nDisabled: true\n\nWrap errors with %w.\n",
+		"rules/service.md":    "# Service Errors\nExtends: Org Errors\nPriority: critical\n\nLog the request ID.\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	var page struct {
+		Items []models.Rule `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(client.CallText(t, "buddy_get_rules", map[string]any{"output": "json"})), &page))
+	require.Len(t, page.Items, 1)
+	service := page.Items[0]
+	assert.Equal(t, "Service Errors", service.Title)
+	assert.Equal(t, "errors", service.Category)
+	assert.Equal(t, "critical", service.Priority)
+	assert.Equal(t, "Wrap errors with %w.\n\nLog the request ID.", strings.TrimSpace(service.Description))
+	assert.Equal(t, []string{`errors\.New`}, service.Forbid)
+
+	created := client.CallText(t, "buddy_get_rules", map[string]any{"action": "create", "title": "Handler Errors", "extends": "service.md", "content": "Name the tool.", "file": "handlers"})
+	assert.Contains(t, created, "Category: errors\nPriority: critical\nExtends: service.md")
+	assert.Contains(t, client.CallText(t, "buddy_get_rules", map[string]any{"category": "errors"}), "Wrap errors with %w.\n   Log the request ID.\n   Name the tool.")
+
+	_, err := client.Call("buddy_get_rules", map[string]any{"action": "update", "rule": "service.md", "extends": "handlers.md"})
+	assert.ErrorContains(t, err, "loop (service.md → handlers.md → service.md)")
+	_, err = client.Call("buddy_get_rules", map[string]any{"action": "delete", "rule": "service.md"})
+	assert.ErrorContains(t, err, "other rules build on it")
+	_, err = client.Call("buddy_get_rules", map[string]any{"action": "create", "title": "Orphan", "extends": "missing.md", "content": "Text."})
+	assert.ErrorContains(t, err, `no rule matches "missing.md"`)
+	assert.NoFileExists(t, filepath.Join(buddyPath, "rules", "orphan.md"))
+}
+
+func TestRuleTags(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"rules/sql.md":    "# Parameterized Queries\nCategory: database\nPriority: critical\nTags: security, performance\n\nNever build SQL from strings.\n",
+		"rules/tokens.md": "# Token Storage\nCategory: auth\nPriority: critical\nTags: Security\n\nKeep tokens out of logs.\n",
+		"rules/style.md":  "# Style\nCategory: style\nPriority: optional\n\nUse gofmt.\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	security := client.CallText(t, "buddy_get_rules", map[string]any{"tags": []any{"security"}})
+	assert.Contains(t, security, "Found 2 rules tagged: security")
+	assert.Contains(t, security, "   Tags: security, performance")
+	assert.NotContains(t, security, "Style")
+	both := client.CallText(t, "buddy_get_rules", map[string]any{"tags": []any{"SECURITY", "performance"}})
+	assert.Contains(t, both, "Found 1 rules")
+	assert.Contains(t, both, "Parameterized Queries")
+	assert.Contains(t, client.CallText(t, "buddy_get_rules", map[string]any{"search": "performance"}), "Parameterized Queries")
+	none := client.CallText(t, "buddy_get_rules", map[string]any{"tags": []any{"testing"}})
+	assert.Contains(t, none, "No rules found tagged: testing")
+	assert.Contains(t, none, "Available tags: performance, security")
+
+	updated := client.CallText(t, "buddy_get_rules", map[string]any{"action": "update", "rule": "style.md", "tags": []any{"readability"}})
+	assert.Contains(t, updated, "Tags: readability")
+	content, err := os.ReadFile(filepath.Join(buddyPath, "rules", "style.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Style\nCategory: style\nPriority: optional\nTags: readability\n\nUse gofmt.\n", string(content))
+	client.CallText(t, "buddy_get_rules", map[string]any{"action": "update", "rule": "style.md", "tags": []any{}})
+	content, err = os.ReadFile(filepath.Join(buddyPath, "rules", "style.md"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "Tags:")
+
+	_, err = client.Call("buddy_get_rules", map[string]any{"action": "update", "rule": "style.md", "tags": []any{"a, b"}})
+	assert.ErrorContains(t, err, "without commas")
+}
+
+func TestBackupSandbox(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	shared := filepath.Join(outside, "shared.txt")
+	for _, path := range []string{secret, shared} {
+		require.NoError(t, os.WriteFile(path, []byte("outside the project\n"), 0644))
+	}
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"config.json": `{"sandbox": {"enabled": true, "allow": [` + strconv.Quote(shared) + `]}}`,
+	})
+	project := filepath.Dir(buddyPath)
+	require.NoError(t, os.WriteFile(filepath.Join(project, "main.go"), []byte("package main\n"), 0644))
+	client := testutil.Start(t, buddyPath)
+
+	backup := func(path string) (*mcp.CallToolResult, error) {
+		return client.Call("buddy_backup", map[string]any{"action": "create", "file_path": path, "context": "edit", "reasoning": "test"})
+	}
+	result, err := backup("main.go")
+	require.NoError(t, err)
+	assert.False(t, result.IsError, testutil.Text(result))
+	result, err = backup(shared)
+	require.NoError(t, err)
+	assert.False(t, result.IsError, testutil.Text(result))
+
+	result, err = backup(secret)
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	var violation struct {
+		Error string   `json:"error"`
+		Op    string   `json:"op"`
+		Path  string   `json:"path"`
+		Roots []string `json:"roots"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(testutil.Text(result)), &violation))
+	assert.Equal(t, "sandbox_violation", violation.Error)
+	assert.Equal(t, "backup", violation.Op)
+	assert.Equal(t, secret, violation.Path)
+	assert.Len(t, violation.Roots, 1)
+
+	result, err = client.Call("buddy_backup", map[string]any{"action": "create_tree", "dir_path": outside, "context": "edit", "reasoning": "test"})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, testutil.Text(result), `"error":"sandbox_violation"`)
+}
+
+func TestSandboxWrites(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("outside the project\n"), 0644))
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"config.json":  `{"sandbox": {"enabled": true, "roots": ["src"]}}`,
+		"rules/fmt.md": "# Format\nCategory: style\nPriority: required\n\nUse gofmt.\n",
+		".safety/20240101_000000.000000000-tamper/manifest.json":  `{"id":"20240101_000000.000000000-tamper","action":"tamper","timestamp":"2024-01-01T00:00:00Z","files":{` + strconv.Quote(secret) + `:"000_secret.txt"}}`,
+		".safety/20240101_000000.000000000-tamper/000_secret.txt": "overwritten\n",
+	})
+	project := filepath.Dir(buddyPath)
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "src"), 0755))
+	client := testutil.Start(t, buddyPath)
+
+	violation := func(result *mcp.CallToolResult, err error) string {
+		t.Helper()
+		require.NoError(t, err)
+		require.True(t, result.IsError, testutil.Text(result))
+		var report struct {
+			Error string `json:"error"`
+			Op    string `json:"op"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(testutil.Text(result)), &report))
+		assert.Equal(t, "sandbox_violation", report.Error)
+		return report.Op
+	}
+
+	// A changeset reaching outside the roots writes none of its files
+	inside := filepath.Join(project, "src", "main.go")
+	result, err := client.Call("buddy_apply_changeset", map[string]any{
+		"changes": []any{
+			map[string]any{"path": "src/main.go", "content": "package main\n"},
+			map[string]any{"path": secret, "content": "overwritten\n"},
+		},
+		"context":   "edit",
+		"reasoning": "test",
+	})
+	assert.Equal(t, "write", violation(result, err))
+	assert.NoFileExists(t, inside)
+	content, err := os.ReadFile(secret)
+	require.NoError(t, err)
+	assert.Equal(t, "outside the project\n", string(content))
+
+	// A safety snapshot naming a file outside the roots isn't restored
+	result, err = client.Call("buddy_backup", map[string]any{"action": "restore_safety", "snapshot_id": "20240101_000000.000000000-tamper"})
+	assert.Equal(t, "restore", violation(result, err))
+	content, err = os.ReadFile(secret)
+	require.NoError(t, err)
+	assert.Equal(t, "outside the project\n", string(content))
+
+	// Cursor rules go in the project root, outside src
+	result, err = client.Call("buddy_get_rules", map[string]any{"action": "export_cursor"})
+	assert.Equal(t, "write", violation(result, err))
+	assert.NoDirExists(t, filepath.Join(project, ".cursor"))
+
+	// Files inside the roots are still written
+	client.CallText(t, "buddy_apply_changeset", map[string]any{
+		"changes":   []any{map[string]any{"path": "src/main.go", "content": "package main\n"}},
+		"context":   "edit",
+		"reasoning": "test",
+	})
+	assert.FileExists(t, inside)
+}
+
+func TestViews(t *testing.T) {
+	recent := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"rules/money.md":              "---\napplies_to:\n  - 'internal/billing/**'\n---\n# Money\nCategory: billing\nPriority: critical\n\nStore amounts in cents.\n",
+		"rules/auth.md":               "---\napplies_to:\n  - 'internal/auth/**'\n---\n# Sessions\nCategory: auth\nPriority: critical\n\nExpire sessions after an hour.\n",
+		"rules/style.md":              "---\napplies_to:\n  - 'internal/billing/**'\n---\n# Style\nCategory: style\nPriority: optional\n\nUse gofmt.\n",
+		"history/new.json":            `{"id":"new","timestamp":"` + recent + `","feature":"billing","description":"Added invoices","changes":[{"file_path":"internal/billing/invoice.go","change_type":"created"}]}`,
+		"history/old.json":            `{"id":"old","timestamp":"2020-01-01T00:00:00Z","feature":"auth","description":"Added login","changes":[{"file_path":"internal/auth/login.go","change_type":"created"}]}`,
+		"views/critical-changes.yaml": "description: Critical rules for files changed this week\nfrom: rules\nwhere:\n  priority: critical\nfiles:\n  changed_since: last 7 days\n",
+		"views/feature-history.yaml":  "from: history\nwhere:\n  feature: \"{{feature}}\"\nparams:\n  feature: \"\"\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	list := client.CallText(t, "buddy_views", map[string]any{})
+	assert.Contains(t, list, "🔭 2 views")
+	assert.Contains(t, list, "critical-changes (from rules): Critical rules for files changed this week")
+	assert.Contains(t, list, "Params: feature (required)")
+
+	text := client.CallText(t, "buddy_views", map[string]any{"name": "critical-changes"})
+	assert.Contains(t, text, "Changed files: 1")
+	assert.Contains(t, text, "Found 1 rules")
+	assert.Contains(t, text, "1. Money (critical · billing)\n   rules/money.md · ")
+	assert.NotContains(t, text, "Sessions")
+	assert.NotContains(t, text, "Style")
+
+	var result handlers.ViewResult
+	require.NoError(t, json.Unmarshal([]byte(client.CallText(t, "buddy_views", map[string]any{"name": "feature-history", "params": map[string]any{"feature": "AUTH"}, "output": "json"})), &result))
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "old", result.Items[0].ID)
+	assert.Equal(t, map[string]string{"feature": "AUTH"}, result.Params)
+
+	_, err := client.Call("buddy_views", map[string]any{"name": "feature-history"})
+	assert.ErrorContains(t, err, "needs a value for param \"feature\"")
+	_, err = client.Call("buddy_views", map[string]any{"name": "missing"})
+	assert.ErrorContains(t, err, "available: critical-changes, feature-history")
+
+	resource := client.ReadText(t, "buddy://views/feature-history?feature=billing")
+	assert.Contains(t, resource, `"view":"feature-history"`)
+	assert.Contains(t, resource, `"Added invoices"`)
+	assert.NotContains(t, resource, `"Added login"`)
+}
+
+func TestScratchpad(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, nil)
+	client := testutil.Start(t, buddyPath)
+
+	assert.Contains(t, client.CallText(t, "buddy_scratchpad", map[string]any{}), "The scratchpad is empty")
+	client.CallText(t, "buddy_scratchpad", map[string]any{"action": "set", "name": "plan", "content": "1. migrate\n2. deploy"})
+	saved := client.CallText(t, "buddy_scratchpad", map[string]any{"action": "set", "name": "pool cause", "content": "Timeouts come from the pool size", "promote": "history", "feature": "payments"})
+	assert.Contains(t, saved, "Saved to history when the session ends")
+	client.CallText(t, "buddy_scratchpad", map[string]any{"action": "set", "name": "scrap", "content": "temporary"})
+
+	list := client.CallText(t, "buddy_scratchpad", map[string]any{"action": "list"})
+	assert.Contains(t, list, "3 scratchpad notes")
+	assert.Less(t, strings.Index(list, "- plan"), strings.Index(list, "- pool cause"))
+	assert.Contains(t, list, "→ history at session end")
+	assert.Equal(t, "1. migrate\n2. deploy", client.CallText(t, "buddy_scratchpad", map[string]any{"action": "get", "name": "plan"}))
+
+	promoted := client.CallText(t, "buddy_scratchpad", map[string]any{"action": "promote", "name": "plan", "promote": "knowledge", "feature": "architecture"})
+	assert.Contains(t, promoted, "Promoted note \"plan\" to drafts/knowledge/")
+	drafts, err := filepath.Glob(filepath.Join(buddyPath, "drafts", "knowledge", "*.md"))
+	require.NoError(t, err)
+	require.Len(t, drafts, 1)
+	content, err := os.ReadFile(drafts[0])
+	require.NoError(t, err)
+	assert.Equal(t, "# plan\nCategory: architecture\n\n1. migrate\n2. deploy\n", string(content))
+
+	_, err = client.Call("buddy_scratchpad", map[string]any{"action": "get", "name": "plan"})
+	assert.ErrorContains(t, err, "no scratchpad note named \"plan\"")
+	_, err = client.Call("buddy_scratchpad", map[string]any{"action": "set", "name": "big", "content": strings.Repeat("x", 20<<10)})
+	assert.ErrorContains(t, err, "over the 16384 byte limit")
+	_, err = client.Call("buddy_scratchpad", map[string]any{"action": "set", "name": "x", "content": "y", "promote": "todos"})
+	assert.ErrorContains(t, err, "invalid promote: todos (expected one of history, knowledge)")
+
+	// Ending the session saves the notes marked for promotion and drops the rest
+	require.NoError(t, client.Server.Close())
+	entries, err := filepath.Glob(filepath.Join(buddyPath, "history", "*.json"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entry, err := os.ReadFile(entries[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(entry), `"feature": "payments"`)
+	assert.Contains(t, string(entry), "Timeouts come from the pool size")
+	left, err := os.ReadDir(filepath.Join(buddyPath, "scratchpad"))
+	require.NoError(t, err)
+	assert.Empty(t, left)
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	files := map[string]string{
+		"rules/logging.md":    "# Logging\nCategory: ops\nPriority: recommended\n\nLog with slog.\n",
+		"rules/errors.md":     "# Errors\nCategory: go\nPriority: critical\n\nWrap errors.\n",
+		"todos/zeta.md":       "# Feature: zeta\n\n- [ ] Ship zeta\n",
+		"todos/alpha.md":      "# Feature: alpha\n\n- [x] Ship alpha\n- [ ] Test alpha\n",
+		"knowledge/deploy.md": "# Deploys\nCategory: ops\n\nDeploy on Tuesdays.\n",
+		"knowledge/auth.md":   "# Tokens\nCategory: api\n\nTokens expire hourly.\n",
+	}
+	first := testutil.Start(t, testutil.BuddyDir(t, files))
+	// The same files checked out elsewhere
+	second := testutil.Start(t, testutil.BuddyDir(t, files))
+
+	todos := first.CallText(t, "buddy_manage_todos", map[string]any{"action": "list"})
+	assert.Less(t, strings.Index(todos, "=== ALPHA ==="), strings.Index(todos, "=== ZETA ==="))
+	assert.Equal(t, todos, second.CallText(t, "buddy_manage_todos", map[string]any{"action": "list"}))
+	for range 5 {
+		assert.Equal(t, todos, first.CallText(t, "buddy_manage_todos", map[string]any{"action": "list"}))
+	}
+
+	ruleIDs := func(client *testutil.Client) []string {
+		var page struct {
+			Items []models.Rule `json:"items"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(client.CallText(t, "buddy_get_rules", map[string]any{"output": "json"})), &page))
+		var ids []string
+		for _, rule := range page.Items {
+			ids = append(ids, rule.ID)
+		}
+		return ids
+	}
+	assert.Len(t, ruleIDs(first), 2)
+	assert.Equal(t, ruleIDs(first), ruleIDs(second))
+
+	missing := first.CallText(t, "buddy_search_knowledge", map[string]any{"query": "kubernetes"})
+	assert.Contains(t, missing, "Available categories:\n- api\n- ops")
+}
+
+func TestKnowledgeAnswer(t *testing.T) {
+	buddyPath := testutil.BuddyDir(t, map[string]string{
+		"knowledge/deploys.md": "# Deploys\nCategory: ops\n\nDeploys run from the main branch.\n\n## Rollback\n\nTo roll back a deploy, redeploy the previous image tag. It takes about a minute.\n\n## Schedule\n\nNo deploys on Fridays.\n",
+		"knowledge/cache.md":   "# Caching\nCategory: ops\n\nRedis caches rendered pages for five minutes.\n",
+	})
+	client := testutil.Start(t, buddyPath)
+
+	text := client.CallText(t, "buddy_search_knowledge", map[string]any{"query": "roll back deploy", "answer": true})
+	assert.Contains(t, text, "💬 Answer: roll back deploy")
+	assert.Contains(t, text, "To roll back a deploy, redeploy the previous image tag. [1]")
+	assert.Contains(t, text, "[1] Deploys › Rollback (knowledge/deploys.md, id ")
+	assert.Contains(t, text, "Answer: extractive")
+	assert.NotContains(t, text, "Caching")
+
+	var answer handlers.KnowledgeAnswer
+	require.NoError(t, json.Unmarshal([]byte(client.CallText(t, "buddy_search_knowledge", map[string]any{"query": "roll back deploy", "answer": true, "top_k": 1, "output": "json"})), &answer))
+	require.Len(t, answer.Citations, 1)
+	assert.Equal(t, "Rollback", answer.Citations[0].Heading)
+	assert.NotEmpty(t, answer.Citations[0].DocumentID)
+}
@@ -0,0 +1,274 @@
+// Package codetodos imports TODO and FIXME comments from source code into
+// a buddy todo file, so work noted in code is tracked alongside planned
+// work.
+package codetodos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FileName is the todo file comments are synced into, inside todos/
+const FileName = "code-todos.md"
+
+// Feature is the todo feature imported comments are listed under
+const Feature = "Code TODOs"
+
+// DefaultMarkers are the comment markers imported when none are configured
+var DefaultMarkers = []string{"TODO", "FIXME"}
+
+// maxFileSize skips files too large to be hand-written source
+const maxFileSize = 1 << 20
+
+// Item is a marker comment found in a source file
+type Item struct {
+	Marker string // e.g. TODO, FIXME
+	Text   string
+	File   string // slash-separated, relative to the project root
+	Line   int
+}
+
+// key identifies a comment across scans; line numbers are left out because
+// they shift as surrounding code changes
+func (i Item) key() string {
+	return i.Marker + "\x00" + i.Text + "\x00" + i.File
+}
+
+// task renders the item as todo task text
+func (i Item) task() string {
+	return fmt.Sprintf("%s: %s (%s:%d)", i.Marker, i.Text, i.File, i.Line)
+}
+
+// Scanner walks source directories for marker comments
+type Scanner struct {
+	Root    string   // project root
+	Sources []string // directories relative to Root; empty scans all of Root
+	Markers []string // empty uses DefaultMarkers
+	// Allow filters files by their slash-separated path relative to Root
+	Allow func(path string) bool
+	// Skip lists absolute directories never scanned, e.g. the buddy folder
+	Skip []string
+}
+
+// commentPattern builds the expression matching a marker after a comment
+// token, with an optional "(owner)" and separator before the text
+func commentPattern(markers []string) *regexp.Regexp {
+	quoted := make([]string, len(markers))
+	for i, marker := range markers {
+		quoted[i] = regexp.QuoteMeta(marker)
+	}
+	return regexp.MustCompile(`(?://|#|/\*|^\s*\*|--|<!--|;|%)\s*(` + strings.Join(quoted, "|") + `)\b(?:\([^)]*\))?[:\s-]*(.*)$`)
+}
+
+// Scan returns the marker comments in all source files, ordered by file
+// and line. It stops with ctx's error when ctx is cancelled.
+func (s Scanner) Scan(ctx context.Context) ([]Item, error) {
+	markers := s.Markers
+	if len(markers) == 0 {
+		markers = DefaultMarkers
+	}
+	pattern := commentPattern(markers)
+
+	sources := s.Sources
+	if len(sources) == 0 {
+		sources = []string{"."}
+	}
+
+	seen := make(map[string]bool)
+	var items []Item
+	for _, source := range sources {
+		dir := filepath.Join(s.Root, source)
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == dir {
+					return err
+				}
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				if path != dir && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				for _, skip := range s.Skip {
+					if path == skip {
+						return filepath.SkipDir
+					}
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() || seen[path] {
+				return nil
+			}
+			seen[path] = true
+
+			rel, err := filepath.Rel(s.Root, path)
+			if err != nil {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+			if s.Allow != nil && !s.Allow(rel) {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil || info.Size() > maxFileSize {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+
+			items = append(items, ScanContent(rel, content, pattern)...)
+			return nil
+		})
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, fmt.Errorf("failed to scan %s: %w", source, err)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].File != items[j].File {
+			return items[i].File < items[j].File
+		}
+		return items[i].Line < items[j].Line
+	})
+	return items, nil
+}
+
+// ScanContent returns the marker comments in one file. Binary files yield
+// nothing.
+func ScanContent(file string, content []byte, pattern *regexp.Regexp) []Item {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+
+	var items []Item
+	for i, line := range strings.Split(string(content), "\n") {
+		match := pattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		text := strings.TrimSpace(match[2])
+		text = strings.TrimSpace(strings.TrimSuffix(text, "*/"))
+		text = strings.TrimSpace(strings.TrimSuffix(text, "-->"))
+		if text == "" {
+			continue
+		}
+
+		items = append(items, Item{Marker: match[1], Text: text, File: file, Line: i + 1})
+	}
+	return items
+}
+
+// SyncResult counts what a sync changed
+type SyncResult struct {
+	Added    int // comments not tracked before
+	Resolved int // tracked comments no longer in the source
+	Reopened int // resolved comments that reappeared
+	Open     int // comments currently in the source
+}
+
+// taskPattern parses a task line written by Sync
+var taskPattern = regexp.MustCompile(`^- \[( |x)\] (\S+): (.*) \(([^()]+):(\d+)\)$`)
+
+// Sync merges scanned items into the content of the code todo file and
+// returns the new content. Comments still in the source are open tasks
+// with their current line; tracked comments that disappeared are marked
+// done, so resolved work stays visible until the file is pruned by hand.
+func Sync(existing string, items []Item) (string, SyncResult) {
+	var result SyncResult
+
+	type entry struct {
+		item Item
+		done bool
+	}
+
+	var tracked []entry
+	index := make(map[string]int)
+	for _, line := range strings.Split(existing, "\n") {
+		match := taskPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+		lineNumber, _ := strconv.Atoi(match[5])
+		item := Item{Marker: match[2], Text: match[3], File: match[4], Line: lineNumber}
+		if _, dup := index[item.key()]; dup {
+			continue
+		}
+		index[item.key()] = len(tracked)
+		tracked = append(tracked, entry{item: item, done: match[1] == "x"})
+	}
+
+	found := make(map[string]bool)
+	for _, item := range items {
+		if found[item.key()] {
+			continue
+		}
+		found[item.key()] = true
+		result.Open++
+
+		if i, ok := index[item.key()]; ok {
+			if tracked[i].done {
+				result.Reopened++
+			}
+			tracked[i] = entry{item: item}
+			continue
+		}
+
+		index[item.key()] = len(tracked)
+		tracked = append(tracked, entry{item: item})
+		result.Added++
+	}
+
+	for i := range tracked {
+		if !tracked[i].done && !found[tracked[i].item.key()] {
+			tracked[i].done = true
+			result.Resolved++
+		}
+	}
+
+	// Open tasks first, each group in source order
+	sort.SliceStable(tracked, func(i, j int) bool {
+		if tracked[i].done != tracked[j].done {
+			return !tracked[i].done
+		}
+		if tracked[i].item.File != tracked[j].item.File {
+			return tracked[i].item.File < tracked[j].item.File
+		}
+		return tracked[i].item.Line < tracked[j].item.Line
+	})
+
+	var sb strings.Builder
+	sb.WriteString("# Feature: " + Feature + "\n\n")
+	sb.WriteString("Imported from TODO/FIXME comments in source. Edit the comments, not this file; resolved comments are checked off on the next import.\n\n")
+	for _, e := range tracked {
+		check := " "
+		if e.done {
+			check = "x"
+		}
+		sb.WriteString(fmt.Sprintf("- [%s] %s\n", check, e.item.task()))
+	}
+
+	return sb.String(), result
+}
@@ -0,0 +1,122 @@
+package codetodos
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanContent(t *testing.T) {
+	content := strings.Join([]string{
+		"package main",
+		"// TODO: handle errors",
+		"x := 1 // FIXME(alice): off by one",
+		"# TODO - python style",
+		"/* TODO: block comment */",
+		" * FIXME multi-line body",
+		"<!-- TODO: docs -->",
+		"todoList := nil",
+		"// TODO",
+		`s := "TODO: not a comment"`,
+	}, "\n")
+
+	items := ScanContent("main.go", []byte(content), commentPattern(DefaultMarkers))
+	require.Len(t, items, 6)
+
+	assert.Equal(t, Item{Marker: "TODO", Text: "handle errors", File: "main.go", Line: 2}, items[0])
+	assert.Equal(t, Item{Marker: "FIXME", Text: "off by one", File: "main.go", Line: 3}, items[1])
+	assert.Equal(t, "python style", items[2].Text)
+	assert.Equal(t, "block comment", items[3].Text)
+	assert.Equal(t, "multi-line body", items[4].Text)
+	assert.Equal(t, "docs", items[5].Text)
+}
+
+func TestScanContent_SkipsBinary(t *testing.T) {
+	assert.Empty(t, ScanContent("a.bin", []byte("\x00\x01// TODO: nope"), commentPattern(DefaultMarkers)))
+}
+
+func TestScanner_Scan(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	write("src/a.go", "// TODO: first\n")
+	write("src/b.go", "\n// HACK: custom marker\n")
+	write("vendor/lib.go", "// TODO: vendored\n")
+	write(".git/hooks/x", "# TODO: hidden\n")
+	write(".buddy/todos/x.md", "<!-- TODO: buddy -->\n")
+	write("docs/readme.md", "<!-- TODO: outside sources -->\n")
+
+	scanner := Scanner{
+		Root:    root,
+		Sources: []string{"src", "vendor"},
+		Markers: []string{"TODO", "HACK"},
+		Allow:   func(path string) bool { return !strings.HasPrefix(path, "vendor/") },
+	}
+	items, err := scanner.Scan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "src/a.go", items[0].File)
+	assert.Equal(t, "HACK", items[1].Marker)
+	assert.Equal(t, 2, items[1].Line)
+
+	// The whole project, minus skipped and hidden directories
+	items, err = Scanner{Root: root, Skip: []string{filepath.Join(root, ".buddy")}}.Scan(context.Background())
+	require.NoError(t, err)
+	var files []string
+	for _, item := range items {
+		files = append(files, item.File)
+	}
+	assert.Equal(t, []string{"docs/readme.md", "src/a.go", "vendor/lib.go"}, files)
+
+	// A cancelled scan stops with the context's error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = scanner.Scan(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSync(t *testing.T) {
+	first := []Item{
+		{Marker: "TODO", Text: "handle errors", File: "main.go", Line: 2},
+		{Marker: "FIXME", Text: "off by one", File: "util.go", Line: 10},
+	}
+
+	content, result := Sync("", first)
+	assert.Equal(t, SyncResult{Added: 2, Open: 2}, result)
+	assert.Contains(t, content, "# Feature: Code TODOs")
+	assert.Contains(t, content, "- [ ] TODO: handle errors (main.go:2)\n")
+	assert.Contains(t, content, "- [ ] FIXME: off by one (util.go:10)\n")
+
+	// The TODO moved down a few lines and the FIXME was fixed
+	second := []Item{
+		{Marker: "TODO", Text: "handle errors", File: "main.go", Line: 5},
+		{Marker: "TODO", Text: "add tests", File: "main.go", Line: 9},
+	}
+	content, result = Sync(content, second)
+	assert.Equal(t, SyncResult{Added: 1, Resolved: 1, Open: 2}, result)
+	assert.Contains(t, content, "- [ ] TODO: handle errors (main.go:5)\n")
+	assert.NotContains(t, content, "(main.go:2)")
+	assert.Contains(t, content, "- [x] FIXME: off by one (util.go:10)\n")
+
+	// Open tasks are listed before resolved ones
+	assert.Less(t, strings.Index(content, "add tests"), strings.Index(content, "off by one"))
+
+	// The FIXME came back
+	content, result = Sync(content, append(second, first[1]))
+	assert.Equal(t, SyncResult{Reopened: 1, Open: 3}, result)
+	assert.Contains(t, content, "- [ ] FIXME: off by one (util.go:10)\n")
+
+	// Syncing unchanged source is stable
+	again, result := Sync(content, append(second, first[1]))
+	assert.Equal(t, content, again)
+	assert.Equal(t, SyncResult{Open: 3}, result)
+}
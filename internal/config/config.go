@@ -0,0 +1,391 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/schema"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FileName is the name of the optional configuration file inside the buddy directory
+	FileName = "config.yaml"
+	// LegacyFileName is the JSON configuration file read when there is no config.yaml
+	LegacyFileName = "config.json"
+)
+
+// defaultExcludes are paths that never belong in backups or change checks
+var defaultExcludes = []string{
+	".git/**",
+	"node_modules/**",
+	"vendor/**",
+}
+
+// Config holds user-tunable settings read from .buddy/config.yaml, or
+// .buddy/config.json in folders set up before YAML was supported
+type Config struct {
+	Paths     PathFilter `json:"paths"`
+	Display   Display    `json:"display"`
+	Churn     Churn      `json:"churn"`
+	Tools     Tools      `json:"tools"`
+	CodeTodos CodeTodos  `json:"code_todos"`
+	Redaction Redaction  `json:"redaction"`
+	Files     Files      `json:"files"`
+	Limits    Limits     `json:"limits"`
+	Todos     Todos      `json:"todos"`
+	Knowledge Knowledge  `json:"knowledge"`
+	Auth      Auth       `json:"auth"`
+	Search    Search     `json:"search"`
+	Sandbox   Sandbox    `json:"sandbox"`
+	// Notifications announces changes to services outside MCP
+	Notifications Notifications `json:"notifications"`
+	// RateLimits caps calls per tool, keyed by the tool's unprefixed name;
+	// "*" applies to tools without their own entry
+	RateLimits map[string]RateLimit `json:"rate_limits"`
+}
+
+// RateLimit bounds how hard clients can drive one tool, across all sessions
+type RateLimit struct {
+	MaxConcurrent int `json:"max_concurrent"` // calls running at once; 0 means no limit
+	PerMinute     int `json:"per_minute"`     // calls started in any minute; 0 means no limit
+}
+
+// RateLimitFor returns the limit of a tool by its unprefixed name
+func (c *Config) RateLimitFor(name string) RateLimit {
+	if limit, ok := c.RateLimits[name]; ok {
+		return limit
+	}
+	return c.RateLimits["*"]
+}
+
+// Notifications configures webhooks told about important changes
+type Notifications struct {
+	// CriticalRuleWebhook receives a JSON POST whenever a rule file with
+	// priority critical changes on disk. $VARIABLES are expanded.
+	CriticalRuleWebhook string `json:"critical_rule_webhook"`
+}
+
+// Search configures where search indexes live and how they are kept small
+type Search struct {
+	// IndexDir moves the indexes out of the buddy folder, e.g. to a cache
+	// directory; each project gets its own subdirectory. Relative paths
+	// are resolved from the buddy folder and $VARIABLES are expanded.
+	IndexDir string `json:"index_dir"`
+	// CompactMinutes is how often indexes are compacted; 0 turns scheduled
+	// compaction off
+	CompactMinutes int `json:"compact_minutes"`
+	// MaxIndexMB caps the disk space of a project's indexes. Over it they
+	// are compacted, then rebuilt if that isn't enough. 0 means no cap.
+	MaxIndexMB int `json:"max_index_mb"`
+}
+
+// Sandbox confines the project files backups read and restores write, so
+// a misbehaving agent can't reach outside the project
+type Sandbox struct {
+	// Enabled refuses paths outside Roots and Allow
+	Enabled bool `json:"enabled"`
+	// Roots are the directories files may be backed up from and restored
+	// to. Relative roots are resolved from the workspace root, which is
+	// the only root by default.
+	Roots []string `json:"roots"`
+	// Allow lists files and directories outside the roots that may still
+	// be backed up and restored
+	Allow []string `json:"allow"`
+}
+
+// Auth protects tools served over the network transports
+type Auth struct {
+	// Token must be sent as "Authorization: Bearer <token>" with tool
+	// calls over HTTP or SSE. The BUDDY_AUTH_TOKEN environment variable
+	// takes precedence.
+	Token string `json:"token"`
+	// AllowedOrigins lists the browser origins, like
+	// "https://app.example.com", that may open WebSocket connections.
+	// Pages served from loopback hosts are always allowed.
+	AllowedOrigins []string `json:"allowed_origins"`
+}
+
+// Todos configures todo file housekeeping
+type Todos struct {
+	// AutoArchive moves a todo file to todos/archive/ once all its tasks
+	// are complete
+	AutoArchive bool `json:"auto_archive"`
+}
+
+// Knowledge configures how knowledge files are read
+type Knowledge struct {
+	// Fields are extra header fields, such as "service" or "tier", kept as
+	// metadata on each document and usable as search filters
+	Fields []string `json:"fields"`
+}
+
+// Limits guards against buddy files large enough to slow loading and search
+type Limits struct {
+	// MaxFileKB is the size limit of one file; larger text files are cut
+	// to it when loaded. Default 1024; a negative value turns it off.
+	MaxFileKB int `json:"max_file_kb"`
+}
+
+// Files configures the names of files tools create from titles, such as
+// drafts
+type Files struct {
+	// NameTemplate names files using {slug}, {kind} and {date}; default "{slug}"
+	NameTemplate string `json:"name_template"`
+	// MaxSlugLength bounds the slug derived from a title; default 60
+	MaxSlugLength int `json:"max_slug_length"`
+}
+
+// Redaction configures masking of personal data in sample rows
+type Redaction struct {
+	// Columns are column name patterns with "*" wildcards; default common
+	// personal data names, and an empty list turns them off
+	Columns []string `json:"columns"`
+}
+
+// CodeTodos configures importing TODO/FIXME comments from source code
+type CodeTodos struct {
+	Sources []string `json:"sources"` // directories relative to the project root; default the whole project
+	Markers []string `json:"markers"` // comment markers; default TODO and FIXME
+}
+
+// Tools controls how MCP tools are named and which are registered, to
+// avoid collisions when several MCP servers are installed side by side
+type Tools struct {
+	Prefix  string   `json:"prefix"`  // prepended to every tool name
+	Suffix  string   `json:"suffix"`  // appended to every tool name
+	Enable  []string `json:"enable"`  // if set, only these tools are registered
+	Disable []string `json:"disable"` // tools never registered
+}
+
+// Name returns the registered name for a tool
+func (t Tools) Name(name string) string {
+	return t.Prefix + name + t.Suffix
+}
+
+// BaseName returns a tool's unprefixed name from its registered one
+func (t Tools) BaseName(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, t.Prefix), t.Suffix)
+}
+
+// Enabled reports whether a tool should be registered. Tools are matched by
+// their unprefixed name, e.g. "buddy_history".
+func (t Tools) Enabled(name string) bool {
+	for _, disabled := range t.Disable {
+		if disabled == name {
+			return false
+		}
+	}
+
+	if len(t.Enable) == 0 {
+		return true
+	}
+
+	for _, enabled := range t.Enable {
+		if enabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Churn tunes warnings about rule files that are edited repeatedly
+type Churn struct {
+	WindowMinutes int `json:"window_minutes"` // default 10
+	Threshold     int `json:"threshold"`      // edits within the window; default 3
+}
+
+// Display controls how stored UTC timestamps are shown to users
+type Display struct {
+	TimeZone   string `json:"time_zone"`   // IANA name, e.g. "Europe/Berlin"; empty means UTC
+	Locale     string `json:"locale"`      // e.g. "en-US", "de-DE"; selects a date layout
+	TimeFormat string `json:"time_format"` // explicit Go layout, overrides locale
+}
+
+// PathFilter decides which project paths buddy tools should touch.
+// Patterns use forward slashes; "*" matches within a path segment and
+// "**" matches across segments. Patterns without a leading "/" may match
+// starting at any directory boundary.
+type PathFilter struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+	// Root is the workspace directory stored paths are relative to and
+	// patterns are matched from. A relative root is resolved from the
+	// directory containing the buddy folder, which is also the default.
+	Root string `json:"root"`
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	return &Config{
+		Paths: PathFilter{
+			Exclude: append([]string{}, defaultExcludes...),
+		},
+	}
+}
+
+// Path returns the configuration file Load reads in buddyPath:
+// config.yaml, or config.json when only that exists
+func Path(buddyPath string) string {
+	if _, err := os.Stat(filepath.Join(buddyPath, FileName)); os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(buddyPath, LegacyFileName)); err == nil {
+			return filepath.Join(buddyPath, LegacyFileName)
+		}
+	}
+	return filepath.Join(buddyPath, FileName)
+}
+
+// IsFile reports whether name, relative to the buddy directory, is one of
+// the configuration files
+func IsFile(name string) bool {
+	return name == FileName || name == LegacyFileName
+}
+
+// Load reads the configuration from buddyPath, falling back to defaults
+// when the file is absent. config.yaml wins over config.json when a folder
+// has both.
+func Load(buddyPath string) (*Config, error) {
+	cfg := Default()
+
+	path := Path(buddyPath)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if filepath.Base(path) == FileName {
+		if content, err = yamlToJSON(content); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if err := schema.Validate(schema.Config, content); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := json.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// yamlToJSON converts a YAML document to JSON, so YAML and JSON files are
+// validated against the same schema and decoded by the same struct tags.
+// A document holding nothing but comments is an empty configuration.
+func yamlToJSON(content []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(doc)
+}
+
+// Allows reports whether a path passes the include and exclude patterns.
+// An empty include list allows everything not excluded.
+func (pf PathFilter) Allows(path string) bool {
+	path = filepath.ToSlash(path)
+
+	for _, pattern := range pf.Exclude {
+		if MatchGlob(pattern, path) {
+			return false
+		}
+	}
+
+	if len(pf.Include) == 0 {
+		return true
+	}
+
+	for _, pattern := range pf.Include {
+		if MatchGlob(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob reports whether path matches the glob pattern
+func MatchGlob(pattern, path string) bool {
+	glob, err := CompileGlob(pattern)
+	if err != nil {
+		return false
+	}
+	return glob.Match(path)
+}
+
+// Glob is a compiled glob pattern, safe for concurrent use
+type Glob struct {
+	re *regexp.Regexp
+}
+
+// compiledGlobs caches CompileGlob's results by pattern, since the same
+// applies_to and path filter globs are matched over and over
+var compiledGlobs sync.Map // string to *Glob
+
+// CompileGlob compiles a glob pattern once so it can be matched against
+// many paths. Patterns are cached, so compiling one again is cheap.
+func CompileGlob(pattern string) (*Glob, error) {
+	if glob, ok := compiledGlobs.Load(pattern); ok {
+		return glob.(*Glob), nil
+	}
+	re, err := globToRegexp(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	glob, _ := compiledGlobs.LoadOrStore(pattern, &Glob{re: re})
+	return glob.(*Glob), nil
+}
+
+// Match reports whether path matches the glob
+func (g *Glob) Match(path string) bool {
+	return g.re.MatchString(filepath.ToSlash(path))
+}
+
+// globToRegexp converts a glob pattern to an equivalent regular expression
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	pattern = filepath.ToSlash(pattern)
+
+	var sb strings.Builder
+	if strings.HasPrefix(pattern, "/") {
+		sb.WriteString("^")
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		sb.WriteString("(^|/)")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			// "**/" matches whole directories, including none
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				sb.WriteString("(.*/)?")
+				i++
+			} else {
+				sb.WriteString(".*")
+			}
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	// A pattern naming a directory also covers everything below it
+	sb.WriteString("(/.*)?$")
+
+	return regexp.Compile(sb.String())
+}
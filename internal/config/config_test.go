@@ -0,0 +1,232 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_DefaultsWhenMissing(t *testing.T) {
+	cfg, err := Load(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, defaultExcludes, cfg.Paths.Exclude)
+	assert.Empty(t, cfg.Paths.Include)
+}
+
+func TestLoad_FromFile(t *testing.T) {
+	tempDir := t.TempDir()
+	content := `{"paths": {"include": ["src/**"], "exclude": ["**/*.pb.go"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, LegacyFileName), []byte(content), 0644))
+
+	cfg, err := Load(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/**"}, cfg.Paths.Include)
+	assert.Equal(t, []string{"**/*.pb.go"}, cfg.Paths.Exclude)
+}
+
+func TestLoad_FromYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		include []string
+		prefix  string
+		wantErr string
+	}{
+		{
+			name:    "yaml",
+			files:   map[string]string{FileName: "paths:\n  include: [\"src/**\"]\ntools:\n  prefix: acme_\n"},
+			include: []string{"src/**"},
+			prefix:  "acme_",
+		},
+		{
+			name: "yaml wins over json",
+			files: map[string]string{
+				FileName:       "tools:\n  prefix: acme_\n",
+				LegacyFileName: `{"tools": {"prefix": "old_"}}`,
+			},
+			prefix: "acme_",
+		},
+		{
+			name:  "only comments",
+			files: map[string]string{FileName: "# tools:\n#   prefix: acme_\n"},
+		},
+		{
+			name:    "unknown setting",
+			files:   map[string]string{FileName: "tools:\n  prefx: acme_\n"},
+			wantErr: `unknown property "prefx"`,
+		},
+		{
+			name:    "invalid yaml",
+			files:   map[string]string{FileName: "tools: [\n"},
+			wantErr: "failed to parse config",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			for name, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+			}
+
+			cfg, err := Load(tempDir)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.include, cfg.Paths.Include)
+			assert.Equal(t, tt.prefix, cfg.Tools.Prefix)
+			assert.Equal(t, filepath.Join(tempDir, FileName), Path(tempDir))
+		})
+	}
+}
+
+func TestPath_FallsBackToJSON(t *testing.T) {
+	tempDir := t.TempDir()
+	assert.Equal(t, filepath.Join(tempDir, FileName), Path(tempDir))
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, LegacyFileName), []byte(`{}`), 0644))
+	assert.Equal(t, filepath.Join(tempDir, LegacyFileName), Path(tempDir))
+}
+
+func TestLoad_RedactionColumns(t *testing.T) {
+	// Unset columns keep the defaults, an empty list turns them off
+	cfg, err := Load(t.TempDir())
+	require.NoError(t, err)
+	assert.Nil(t, cfg.Redaction.Columns)
+
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, LegacyFileName), []byte(`{"redaction": {"columns": []}}`), 0644))
+	cfg, err = Load(tempDir)
+	require.NoError(t, err)
+	assert.NotNil(t, cfg.Redaction.Columns)
+	assert.Empty(t, cfg.Redaction.Columns)
+}
+
+func TestLoad_Files(t *testing.T) {
+	tempDir := t.TempDir()
+	content := `{"files": {"name_template": "{date}-{slug}", "max_slug_length": 40}}`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, LegacyFileName), []byte(content), 0644))
+
+	cfg, err := Load(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, "{date}-{slug}", cfg.Files.NameTemplate)
+	assert.Equal(t, 40, cfg.Files.MaxSlugLength)
+}
+
+func TestLoad_InvalidJSON(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, LegacyFileName), []byte("{"), 0644))
+
+	_, err := Load(tempDir)
+	assert.Error(t, err)
+}
+
+func TestLoad_RejectsUnknownSettings(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, LegacyFileName), []byte(`{"paths": {"includes": ["src/**"]}}`), 0644))
+
+	_, err := Load(tempDir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `$.paths: unknown property "includes"`)
+}
+
+func TestSchemaCoversConfig(t *testing.T) {
+	// Every setting the server reads must be allowed by the published schema
+	content, err := json.Marshal(Default())
+	require.NoError(t, err)
+	assert.NoError(t, schema.Validate(schema.Config, content))
+}
+
+func TestRateLimitFor(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, RateLimit{}, cfg.RateLimitFor("buddy_search_knowledge"))
+
+	cfg.RateLimits = map[string]RateLimit{
+		"*":                      {PerMinute: 120},
+		"buddy_search_knowledge": {MaxConcurrent: 2, PerMinute: 30},
+	}
+	assert.Equal(t, RateLimit{MaxConcurrent: 2, PerMinute: 30}, cfg.RateLimitFor("buddy_search_knowledge"))
+	assert.Equal(t, RateLimit{PerMinute: 120}, cfg.RateLimitFor("buddy_history"))
+
+	content, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	assert.NoError(t, schema.Validate(schema.Config, content))
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"vendor/**", "vendor/github.com/pkg/a.go", true},
+		{"vendor/**", "/home/user/project/vendor/a.go", true},
+		{"vendor", "/home/user/project/vendor/a.go", true},
+		{"vendor/**", "/home/user/project/myvendor/a.go", false},
+		{"*.go", "cmd/main.go", true},
+		{"*.go", "cmd/main.go.orig", false},
+		{"**/*.pb.go", "api/v1/user.pb.go", true},
+		{"**/*.pb.go", "user.pb.go", true},
+		{"src/**/gen", "src/gen", true},
+		{"src/**/gen", "src/a/b/gen/x.go", true},
+		{"src/**/gen", "src/regen", false},
+		{"src/**/gen", "src/a/oxygen", false},
+		{"/build/*", "build/out.js", true},
+		{"/build/*", "web/build/out.js", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MatchGlob(tt.pattern, tt.path), "%s vs %s", tt.pattern, tt.path)
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	glob, err := CompileGlob("src/**/*.go")
+	require.NoError(t, err)
+	assert.True(t, glob.Match("src/a/b.go"))
+	assert.True(t, glob.Match(filepath.Join("src", "b.go")))
+	assert.False(t, glob.Match("src/a/b.js"))
+
+	again, err := CompileGlob("src/**/*.go")
+	require.NoError(t, err)
+	assert.Same(t, glob, again, "compiled globs are cached")
+}
+
+func TestPathFilter_Allows(t *testing.T) {
+	pf := PathFilter{
+		Include: []string{"src/**"},
+		Exclude: []string{"src/generated/**"},
+	}
+
+	assert.True(t, pf.Allows("src/app/main.go"))
+	assert.False(t, pf.Allows("src/generated/api.go"))
+	assert.False(t, pf.Allows("docs/readme.md"))
+
+	assert.True(t, Default().Paths.Allows("internal/app.go"))
+	assert.False(t, Default().Paths.Allows("node_modules/react/index.js"))
+}
+
+func TestTools_NameAndEnabled(t *testing.T) {
+	tools := Tools{Prefix: "proj_", Suffix: "_v2"}
+	assert.Equal(t, "proj_buddy_history_v2", tools.Name("buddy_history"))
+	assert.Equal(t, "buddy_history", tools.BaseName("proj_buddy_history_v2"))
+	assert.True(t, tools.Enabled("buddy_history"))
+
+	tools = Tools{Disable: []string{"buddy_backup"}}
+	assert.False(t, tools.Enabled("buddy_backup"))
+	assert.True(t, tools.Enabled("buddy_history"))
+
+	tools = Tools{Enable: []string{"buddy_get_rules", "buddy_backup"}, Disable: []string{"buddy_backup"}}
+	assert.True(t, tools.Enabled("buddy_get_rules"))
+	assert.False(t, tools.Enabled("buddy_history"))
+	assert.False(t, tools.Enabled("buddy_backup"))
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ActivityTracker records when the server last handled a request, for the
+// heartbeat log, idle shutdown and buddy_status. Every MCP request counts,
+// pings included; health probes over HTTP don't, since an orchestrator
+// polling them would otherwise keep an unused instance alive.
+type ActivityTracker struct {
+	startedAt time.Time
+	last      atomic.Int64 // unix nanoseconds of the latest request
+	running   atomic.Int64 // tool calls in progress
+}
+
+// NewActivityTracker creates a tracker for a server started at startedAt,
+// which counts as its first activity
+func NewActivityTracker(startedAt time.Time) *ActivityTracker {
+	at := &ActivityTracker{startedAt: startedAt}
+	at.last.Store(startedAt.UnixNano())
+	return at
+}
+
+// RegisterHooks records every request the server receives
+func (at *ActivityTracker) RegisterHooks(hooks *server.Hooks) {
+	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+		at.touch()
+	})
+}
+
+// Middleware counts tool calls while they run, so a long call doesn't
+// look idle, and records their end as activity
+func (at *ActivityTracker) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		at.running.Add(1)
+		at.touch()
+		defer func() {
+			at.touch()
+			at.running.Add(-1)
+		}()
+		return next(ctx, request)
+	}
+}
+
+// touch records activity now
+func (at *ActivityTracker) touch() {
+	at.last.Store(time.Now().UnixNano())
+}
+
+// StartedAt returns when the server started
+func (at *ActivityTracker) StartedAt() time.Time {
+	return at.startedAt
+}
+
+// Uptime returns how long the server has been running
+func (at *ActivityTracker) Uptime() time.Duration {
+	return time.Since(at.startedAt)
+}
+
+// LastActivity returns when the server last handled a request, or its
+// start time if it hasn't yet
+func (at *ActivityTracker) LastActivity() time.Time {
+	return time.Unix(0, at.last.Load())
+}
+
+// Running returns the number of tool calls in progress
+func (at *ActivityTracker) Running() int {
+	return int(at.running.Load())
+}
+
+// Idle returns how long the server has gone without a request; it is
+// never idle while a tool call runs
+func (at *ActivityTracker) Idle() time.Duration {
+	if at.Running() > 0 {
+		return 0
+	}
+	return time.Since(at.LastActivity())
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/summary"
+)
+
+const (
+	// defaultAnswerChunks is how many passages an answer draws on
+	defaultAnswerChunks = 5
+	// answerSearchLimit is how many documents are split into passages
+	answerSearchLimit = 10
+	// maxChunkPrompt caps each passage in the sampling prompt, in bytes
+	maxChunkPrompt = 1500
+	// extractiveAnswerSentences is how many passages the fallback answer quotes
+	extractiveAnswerSentences = 3
+)
+
+// markdownHeading is an ATX heading line, capturing its text
+var markdownHeading = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*$`)
+
+// knowledgeChunk is the part of a knowledge document under one heading
+type knowledgeChunk struct {
+	Document models.Knowledge
+	Heading  string // empty for the text before the first heading
+	Text     string
+}
+
+// Citation names a passage an answer is based on
+type Citation struct {
+	Number     int    `json:"number"`
+	DocumentID string `json:"document_id"`
+	Title      string `json:"title"`
+	Heading    string `json:"heading,omitempty"`
+	Path       string `json:"path"`
+}
+
+// KnowledgeAnswer is a short answer to a question from the knowledge base
+type KnowledgeAnswer struct {
+	Question  string     `json:"question"`
+	Answer    string     `json:"answer"`
+	Model     string     `json:"model,omitempty"` // empty for extractive answers
+	Citations []Citation `json:"citations"`
+}
+
+// chunkKnowledge splits a document at its markdown headings, leaving
+// headings inside code blocks alone and dropping empty parts
+func chunkKnowledge(doc models.Knowledge) []knowledgeChunk {
+	var chunks []knowledgeChunk
+	heading := ""
+	var lines []string
+	flush := func() {
+		if text := strings.TrimSpace(strings.Join(lines, "\n")); text != "" {
+			chunks = append(chunks, knowledgeChunk{Document: doc, Heading: heading, Text: text})
+		}
+		lines = nil
+	}
+
+	inCode := false
+	for _, line := range strings.Split(doc.Content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+		}
+		if match := markdownHeading.FindStringSubmatch(trimmed); match != nil && !inCode {
+			flush()
+			heading = match[1]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return chunks
+}
+
+// topChunks returns up to k passages of the documents most relevant to
+// question. Documents are in search order, which breaks ties; when no
+// passage shares a word with the question, the best document's first
+// passage stands in.
+func topChunks(docs []models.Knowledge, question string, k int) []knowledgeChunk {
+	type scored struct {
+		chunk knowledgeChunk
+		score int
+	}
+	var ranked []scored
+	for _, doc := range docs {
+		for _, chunk := range chunkKnowledge(doc) {
+			// Words in a heading say more about a passage than words in its text
+			score := 2*summary.Relevance(chunk.Heading, question) + summary.Relevance(chunk.Text, question)
+			ranked = append(ranked, scored{chunk, score})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	var chunks []knowledgeChunk
+	for _, item := range ranked {
+		if len(chunks) == k || (item.score == 0 && len(chunks) > 0) {
+			break
+		}
+		chunks = append(chunks, item.chunk)
+	}
+	return chunks
+}
+
+// answerKnowledge answers a question from the passages of the matching
+// knowledge documents, through the client's model when it can sample and
+// by quoting the most relevant sentences otherwise
+func (bh *BuddyHandlers) answerKnowledge(ctx context.Context, args KnowledgeArgs) (*mcp.CallToolResult, error) {
+	question := args.Query
+	if strings.TrimSpace(question) == "" {
+		return nil, fmt.Errorf("query is required for answer mode")
+	}
+	filters, err := searchFilters(args)
+	if err != nil {
+		return nil, err
+	}
+	k := defaultAnswerChunks
+	if args.TopK > 0 {
+		k = args.TopK
+	}
+
+	docs, err := bh.knowledgeHandler.SearchDocuments(ctx, question, filters, answerSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	chunks := topChunks(docs, question, k)
+	if len(chunks) == 0 {
+		result := fmt.Sprintf("No knowledge found to answer: %s\n", question)
+		return mcp.NewToolResultText(result + formatSuggestions(ctx, bh.searchManager, search.IndexTypeKnowledge, question, "category", "categories")), nil
+	}
+
+	answer := KnowledgeAnswer{Question: question}
+	for i, chunk := range chunks {
+		answer.Citations = append(answer.Citations, Citation{
+			Number:     i + 1,
+			DocumentID: chunk.Document.ID,
+			Title:      chunk.Document.Title,
+			Heading:    chunk.Heading,
+			Path:       "knowledge/" + relativeTo(bh.knowledgeHandler.path, chunk.Document.FilePath),
+		})
+	}
+	answer.Answer, answer.Model = bh.synthesizeAnswer(ctx, question, chunks)
+
+	if args.Output == "json" {
+		content, err := json.MarshalIndent(answer, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal answer: %w", err)
+		}
+		return mcp.NewToolResultText(string(content)), nil
+	}
+	return mcp.NewToolResultText(formatKnowledgeAnswer(answer)), nil
+}
+
+// synthesizeAnswer writes the answer text and names the model that wrote
+// it, or returns no model for an extractive answer
+func (bh *BuddyHandlers) synthesizeAnswer(ctx context.Context, question string, chunks []knowledgeChunk) (string, string) {
+	if bh.sampler.Available(ctx) {
+		var prompt strings.Builder
+		fmt.Fprintf(&prompt, "Question: %s\n\nAnswer in at most four sentences, citing the passages you use by number, like [2].\n", question)
+		for i, chunk := range chunks {
+			text := chunk.Text
+			if len(text) > maxChunkPrompt {
+				text = string(cutAtLine([]byte(text), maxChunkPrompt)) + "..."
+			}
+			fmt.Fprintf(&prompt, "\n---\n\n[%d] %s\n\n%s\n", i+1, chunkLabel(chunk), text)
+		}
+		reply, model, err := bh.sampler.Complete(ctx,
+			"You answer questions about a software project from its documentation. Use only the passages given and cite them. If they don't answer the question, say so.",
+			prompt.String(), 400)
+		if err == nil {
+			if model == "" {
+				model = "unknown"
+			}
+			return reply, model
+		}
+		slog.WarnContext(ctx, "sampling failed, using extractive answer", "error", err)
+	}
+
+	var sentences []string
+	for i, chunk := range chunks {
+		if len(sentences) == extractiveAnswerSentences {
+			break
+		}
+		if sentence := summary.BestSentence(chunk.Text, question); sentence != "" {
+			sentences = append(sentences, fmt.Sprintf("%s [%d]", sentence, i+1))
+		}
+	}
+	if len(sentences) == 0 {
+		return fmt.Sprintf("%s [1]", summary.Extract(chunks[0].Text, 2)), ""
+	}
+	return strings.Join(sentences, " "), ""
+}
+
+// chunkLabel names a passage by its document title and heading
+func chunkLabel(chunk knowledgeChunk) string {
+	if chunk.Heading == "" {
+		return chunk.Document.Title
+	}
+	return chunk.Document.Title + " › " + chunk.Heading
+}
+
+// formatKnowledgeAnswer writes an answer with its numbered sources
+func formatKnowledgeAnswer(answer KnowledgeAnswer) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "💬 Answer: %s\n\n%s\n\nSources:\n", answer.Question, answer.Answer)
+	for _, citation := range answer.Citations {
+		label := citation.Title
+		if citation.Heading != "" {
+			label += " › " + citation.Heading
+		}
+		fmt.Fprintf(&sb, "[%d] %s (%s, id %s)\n", citation.Number, label, citation.Path, citation.DocumentID)
+	}
+	fmt.Fprintf(&sb, "\nAnswer: %s", Summary{Text: answer.Answer, Model: answer.Model}.Source())
+	return sb.String()
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/logging"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/security"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+const (
+	// maxAuditBytes is the size at which the audit log is rotated to
+	// audit.jsonl.1, replacing the previous rotation
+	maxAuditBytes = 10 * 1024 * 1024
+	// maxAuditValue is how much of a string argument is logged
+	maxAuditValue = 256
+	// defaultAuditLimit is how many calls buddy_audit lists by default
+	defaultAuditLimit = 20
+)
+
+// secretArgumentNames mark arguments whose values are never logged
+var secretArgumentNames = []string{"token", "password", "passwd", "secret", "api_key", "apikey", "authorization", "credential", "private_key"}
+
+// AuditEntry records one tool call
+type AuditEntry struct {
+	Time        time.Time              `json:"time"`
+	Tool        string                 `json:"tool"`
+	Session     string                 `json:"session,omitempty"`
+	RequestID   string                 `json:"request_id,omitempty"`
+	Arguments   map[string]interface{} `json:"arguments,omitempty"` // secrets redacted, long values cut
+	DurationMS  float64                `json:"duration_ms"`
+	ResultBytes int                    `json:"result_bytes"`
+	Error       string                 `json:"error,omitempty"` // the error, or the text of an error result
+}
+
+// AuditLog appends every tool call to a JSON lines file so what an agent
+// actually did can be looked up afterwards
+type AuditLog struct {
+	path    string
+	secrets *security.Engine
+	mu      sync.Mutex
+}
+
+// NewAuditLog creates an audit log writing to path. Register its middleware
+// with the MCP server before use.
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{
+		path:    path,
+		secrets: security.NewEngine(),
+	}
+}
+
+// Middleware records each tool call once it returns. A failure to write
+// the log is logged and doesn't fail the call.
+func (al *AuditLog) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		entry := AuditEntry{
+			Time:       start.UTC(),
+			Tool:       request.Params.Name,
+			Session:    undoSession(ctx),
+			RequestID:  logging.RequestID(ctx),
+			Arguments:  al.redactArguments(request.GetArguments()),
+			DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if result != nil {
+			if data, marshalErr := json.Marshal(result); marshalErr == nil {
+				entry.ResultBytes = len(data)
+			}
+		}
+		switch {
+		case err != nil:
+			entry.Error = err.Error()
+		case result != nil && result.IsError:
+			entry.Error = resultText(result)
+		}
+
+		if writeErr := al.append(entry); writeErr != nil {
+			slog.WarnContext(ctx, "failed to write audit log", "path", al.path, "error", writeErr)
+		}
+		return result, err
+	}
+}
+
+// resultText joins the text content of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// redactArguments copies arguments with secrets masked and long strings cut
+func (al *AuditLog) redactArguments(args map[string]interface{}) map[string]interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		redacted[name] = al.redactValue(name, value)
+	}
+	return redacted
+}
+
+// redactValue masks one argument value, looking into objects and arrays
+func (al *AuditLog) redactValue(name string, value interface{}) interface{} {
+	lower := strings.ToLower(name)
+	for _, secret := range secretArgumentNames {
+		if strings.Contains(lower, secret) {
+			return "[redacted]"
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		v = al.secrets.RedactSecrets(v)
+		if len(v) > maxAuditValue {
+			v = fmt.Sprintf("%s… (%d bytes)", strings.ToValidUTF8(v[:maxAuditValue], ""), len(v))
+		}
+		return v
+	case map[string]interface{}:
+		return al.redactArguments(v)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = al.redactValue(name, item)
+		}
+		return items
+	default:
+		return v
+	}
+}
+
+// append writes an entry as one line, rotating the file once it is too big
+func (al *AuditLog) append(entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(al.path), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Stat(al.path); err == nil && info.Size()+int64(len(data)) > maxAuditBytes {
+		if err := os.Rename(al.path, al.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(al.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// AuditFilter selects audit entries; zero fields match everything
+type AuditFilter struct {
+	Tool       string // part of the tool name
+	RequestID  string
+	ErrorsOnly bool
+	Since      time.Time
+	Until      time.Time
+}
+
+// matches reports whether an entry passes the filter
+func (f AuditFilter) matches(entry AuditEntry) bool {
+	if f.Tool != "" && !strings.Contains(entry.Tool, f.Tool) {
+		return false
+	}
+	if f.RequestID != "" && entry.RequestID != f.RequestID {
+		return false
+	}
+	if f.ErrorsOnly && entry.Error == "" {
+		return false
+	}
+	return inTimeRange(entry.Time, f.Since, f.Until)
+}
+
+// Entries returns the logged calls matching filter, newest first, including
+// the rotated file. Lines that can't be parsed are skipped.
+func (al *AuditLog) Entries(filter AuditFilter) ([]AuditEntry, error) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	var entries []AuditEntry
+	for _, path := range []string{al.path + ".1", al.path} {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), maxAuditBytes)
+		for scanner.Scan() {
+			var entry AuditEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if filter.matches(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// AuditArgs are the arguments of buddy_audit
+type AuditArgs struct {
+	Tool       string `arg:"tool" desc:"Only calls of tools whose name contains this (optional)"`
+	RequestID  string `arg:"request_id" desc:"Only the call with this request ID (optional)"`
+	ErrorsOnly bool   `arg:"errors_only" desc:"Only calls that failed (default: false)"`
+	Since      string `arg:"since" desc:"Only calls from this time on: RFC3339, YYYY-MM-DD, 'last 3 days', 'P1W' (optional)"`
+	Until      string `arg:"until" desc:"Only calls up to this time, same formats as since (optional)"`
+	Limit      int    `arg:"limit" desc:"Number of calls to list (default: 20)"`
+}
+
+// GetToolHandler returns the buddy_audit tool handler, which lists logged
+// tool calls
+func (al *AuditLog) GetToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args AuditArgs) (*mcp.CallToolResult, error) {
+		since, until, err := parseTimeRange(args.Since, args.Until, time.UTC)
+		if err != nil {
+			return nil, err
+		}
+		filter := AuditFilter{Since: since, Until: until, Tool: args.Tool, RequestID: args.RequestID, ErrorsOnly: args.ErrorsOnly}
+		limit := defaultAuditLimit
+		if args.Limit > 0 {
+			limit = args.Limit
+		}
+
+		entries, err := al.Entries(filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			return mcp.NewToolResultText("🧾 No logged tool calls match."), nil
+		}
+
+		shown := entries
+		if len(shown) > limit {
+			shown = shown[:limit]
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "🧾 Tool calls (%d of %d, newest first):\n\n", len(shown), len(entries))
+		for _, entry := range shown {
+			status := "✅"
+			if entry.Error != "" {
+				status = "❌"
+			}
+			fmt.Fprintf(&sb, "%s %s %s (%.1f ms, %s)\n", status, entry.Time.Format(time.RFC3339), entry.Tool,
+				entry.DurationMS, formatFileSize(int64(entry.ResultBytes)))
+			if entry.RequestID != "" {
+				fmt.Fprintf(&sb, "   Request: %s\n", entry.RequestID)
+			}
+			if len(entry.Arguments) > 0 {
+				data, _ := json.Marshal(entry.Arguments)
+				fmt.Fprintf(&sb, "   Arguments: %s\n", data)
+			}
+			if entry.Error != "" {
+				message, _, _ := strings.Cut(entry.Error, "\n")
+				fmt.Fprintf(&sb, "   Error: %s\n", message)
+			}
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	})
+}
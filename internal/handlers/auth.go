@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ErrUnauthorized is returned for tool calls without the configured token
+var ErrUnauthorized = errors.New("unauthorized: missing or invalid bearer token")
+
+// authorizationContextKey holds the Authorization header of the HTTP
+// request a tool call arrived in
+type authorizationContextKey struct{}
+
+// WithAuthorization stores the request's Authorization header in ctx for
+// RequireBearerToken. Register it as the HTTP transport's context function.
+func WithAuthorization(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, authorizationContextKey{}, r.Header.Get("Authorization"))
+}
+
+// RequireBearerToken returns a middleware that refuses tool calls unless
+// their HTTP request carried "Authorization: Bearer <token>". Calls that
+// didn't arrive over HTTP, from the stdio client that started the process
+// alongside an HTTP listener, pass.
+func RequireBearerToken(token string) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			header, overHTTP := ctx.Value(authorizationContextKey{}).(string)
+			if !overHTTP {
+				return next(ctx, request)
+			}
+			scheme, presented, ok := strings.Cut(header, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") ||
+				subtle.ConstantTimeCompare([]byte(strings.TrimSpace(presented)), []byte(token)) != 1 {
+				return nil, ErrUnauthorized
+			}
+			return next(ctx, request)
+		}
+	}
+}
@@ -3,20 +3,29 @@ package handlers
 import (
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
-	"io/ioutil"
+	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/schema"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/timeutil"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/workspace"
 )
 
 // BackupHandler manages file backups
@@ -24,6 +33,12 @@ type BackupHandler struct {
 	path          string
 	backups       []models.Backup
 	searchManager *search.SearchManager
+	store         storage.Storage
+	safety        *SafetyStore
+	pathFilter    setting[config.PathFilter]
+	sandbox       setting[*workspace.Sandbox] // nil when paths aren't confined
+	root          workspace.Root              // stored paths are relative to it
+	timeFormat    *timeutil.Formatter
 	mu            sync.RWMutex
 }
 
@@ -33,6 +48,7 @@ func NewBackupHandler(path string, searchManager *search.SearchManager) *BackupH
 		path:          path,
 		backups:       []models.Backup{},
 		searchManager: searchManager,
+		store:         storage.NewLocal(),
 	}
 }
 
@@ -50,21 +66,28 @@ func (bh *BackupHandler) Load() error {
 
 	// Load backup metadata
 	metadataPath := filepath.Join(bh.path, "metadata.json")
-	if _, err := os.Stat(metadataPath); err == nil {
-		content, err := ioutil.ReadFile(metadataPath)
+	if _, err := bh.store.Stat(metadataPath); err == nil {
+		content, err := bh.store.Read(metadataPath)
 		if err != nil {
 			return err
 		}
 
+		if err := schema.Validate(schema.BackupMetadata, content); err != nil {
+			return fmt.Errorf("invalid backup metadata: %w", err)
+		}
 		if err := json.Unmarshal(content, &bh.backups); err != nil {
 			return err
 		}
+		for i := range bh.backups {
+			bh.backups[i].OriginalPath = bh.root.Abs(bh.backups[i].OriginalPath)
+			bh.backups[i].BackupPath = bh.root.Abs(bh.backups[i].BackupPath)
+		}
 
 		// Index all backups
 		for _, backup := range bh.backups {
 			doc := search.FromBackup(backup)
 			if err := bh.searchManager.IndexDocument(search.IndexTypeBackups, backup.ID, doc); err != nil {
-				fmt.Printf("failed to index backup %s: %v\n", backup.ID, err)
+				slog.Warn("failed to index backup", "id", backup.ID, "error", err)
 			}
 		}
 	}
@@ -72,15 +95,21 @@ func (bh *BackupHandler) Load() error {
 	return nil
 }
 
-// save saves backup metadata
+// save saves backup metadata, with paths relative to the workspace root
 func (bh *BackupHandler) save() error {
 	metadataPath := filepath.Join(bh.path, "metadata.json")
-	data, err := json.MarshalIndent(bh.backups, "", "  ")
+	stored := make([]models.Backup, len(bh.backups))
+	for i, backup := range bh.backups {
+		backup.OriginalPath = bh.root.Rel(backup.OriginalPath)
+		backup.BackupPath = bh.root.Rel(backup.BackupPath)
+		stored[i] = backup
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(metadataPath, data, 0644)
+	return bh.store.Write(metadataPath, data)
 }
 
 // CreateBackup creates a backup of a file
@@ -88,104 +117,307 @@ func (bh *BackupHandler) CreateBackup(originalPath, context, reasoning string) (
 	bh.mu.Lock()
 	defer bh.mu.Unlock()
 
+	backup, err := bh.backupFile(originalPath, context, reasoning, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Add to list and save
+	bh.backups = append(bh.backups, *backup)
+	if err := bh.save(); err != nil {
+		return nil, fmt.Errorf("failed to save metadata: %w", err)
+	}
+
+	bh.indexBackup(*backup)
+
+	return backup, nil
+}
+
+// CreateBackupSet backs up several files under one shared set ID so they
+// can later be restored together
+func (bh *BackupHandler) CreateBackupSet(originalPaths []string, context, reasoning string) (string, []models.Backup, error) {
+	if len(originalPaths) == 0 {
+		return "", nil, fmt.Errorf("at least one file path is required")
+	}
+
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+
+	// Check all files up front so a missing file doesn't leave a partial set
+	for _, originalPath := range originalPaths {
+		if err := bh.checkSandbox("backup", bh.root.Abs(originalPath)); err != nil {
+			return "", nil, err
+		}
+		if _, err := bh.store.Stat(bh.root.Abs(originalPath)); err != nil {
+			return "", nil, fmt.Errorf("file not found: %w", err)
+		}
+	}
+
+	setID := fmt.Sprintf("set-%x", md5.Sum([]byte(fmt.Sprintf("%s-%d", strings.Join(originalPaths, ","), time.Now().UnixNano()))))
+
+	var created []models.Backup
+	for _, originalPath := range originalPaths {
+		backup, err := bh.backupFile(originalPath, context, reasoning, setID)
+		if err != nil {
+			// Drop whatever was copied so far
+			for _, b := range created {
+				bh.store.Remove(filepath.Dir(b.BackupPath))
+			}
+			return "", nil, err
+		}
+		created = append(created, *backup)
+	}
+
+	bh.backups = append(bh.backups, created...)
+	if err := bh.save(); err != nil {
+		return "", nil, fmt.Errorf("failed to save metadata: %w", err)
+	}
+
+	for _, backup := range created {
+		bh.indexBackup(backup)
+	}
+
+	return setID, created, nil
+}
+
+// backupFile copies a single file into the backup area and returns its
+// record. The caller must hold the lock and persist the record.
+func (bh *BackupHandler) backupFile(originalPath, context, reasoning, setID string) (*models.Backup, error) {
+	originalPath = bh.root.Abs(originalPath)
+	if err := bh.checkSandbox("backup", originalPath); err != nil {
+		return nil, err
+	}
+	if !bh.allows(originalPath) {
+		return nil, fmt.Errorf("file is excluded from backups by path configuration: %s", originalPath)
+	}
+
 	// Check if file exists
-	fileInfo, err := os.Stat(originalPath)
+	fileInfo, err := bh.store.Stat(originalPath)
 	if err != nil {
 		return nil, fmt.Errorf("file not found: %w", err)
 	}
 
 	// Generate backup ID and path
 	id := fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%d", originalPath, time.Now().UnixNano()))))
-	timestamp := time.Now()
+	timestamp := time.Now().UTC()
 	backupFileName := fmt.Sprintf("%s_%s%s",
 		strings.TrimSuffix(filepath.Base(originalPath), filepath.Ext(originalPath)),
 		timestamp.Format("20060102_150405"),
 		filepath.Ext(originalPath))
 	backupPath := filepath.Join(bh.path, id, backupFileName)
 
-	// Create backup directory
-	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
-		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	// Copy file into the backup store
+	content, err := bh.store.Read(originalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
-
-	// Copy file
-	if err := bh.copyFile(originalPath, backupPath); err != nil {
+	if err := bh.store.Write(backupPath, content); err != nil {
 		return nil, fmt.Errorf("failed to copy file: %w", err)
 	}
 
-	// Create backup record
-	backup := models.Backup{
+	return &models.Backup{
 		ID:            id,
 		OriginalPath:  originalPath,
 		BackupPath:    backupPath,
 		Timestamp:     timestamp,
 		ChangeContext: context,
 		Reasoning:     reasoning,
-		FileSize:      fileInfo.Size(),
-	}
+		FileSize:      fileInfo.Size,
+		SetID:         setID,
+	}, nil
+}
 
-	// Add to list and save
-	bh.backups = append(bh.backups, backup)
-	if err := bh.save(); err != nil {
-		return nil, fmt.Errorf("failed to save metadata: %w", err)
-	}
+// allows reports whether the path configuration lets path be backed up.
+// Patterns are matched against the workspace-relative path.
+func (bh *BackupHandler) allows(path string) bool {
+	return bh.pathFilter.Load().Allows(bh.root.Rel(bh.root.Abs(path)))
+}
 
-	// Index the backup
+// indexBackup adds a backup record to the search index
+func (bh *BackupHandler) indexBackup(backup models.Backup) {
 	doc := search.FromBackup(backup)
 	if err := bh.searchManager.IndexDocument(search.IndexTypeBackups, backup.ID, doc); err != nil {
-		fmt.Printf("failed to index backup %s: %v\n", backup.ID, err)
+		slog.Warn("failed to index backup", "id", backup.ID, "error", err)
 	}
-
-	return &backup, nil
 }
 
-// copyFile copies a file from src to dst
-func (bh *BackupHandler) copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
+// restoreFile copies a stored backup back to its original location
+func (bh *BackupHandler) restoreFile(backupPath, originalPath string) error {
+	content, err := bh.store.Read(backupPath)
 	if err != nil {
 		return err
 	}
-	defer sourceFile.Close()
 
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return err
+	_, err = bh.writeVerified(originalPath, content, 0644)
+	return err
+}
+
+// writeVerified writes content to path through the storage and reads it
+// back, failing if the stored file doesn't hash to what was written. A
+// new file is created with mode where the storage keeps permissions. It
+// returns the hex SHA-256 of the content.
+func (bh *BackupHandler) writeVerified(path string, content []byte, mode os.FileMode) (string, error) {
+	if err := storage.WriteMode(bh.store, path, content, mode); err != nil {
+		return "", err
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
-	return err
+	want := sha256.Sum256(content)
+	written, err := bh.store.Read(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify %s: %w", path, err)
+	}
+	if sha256.Sum256(written) != want {
+		return "", fmt.Errorf("verification failed for %s: content on disk doesn't match what was written", path)
+	}
+	return hex.EncodeToString(want[:]), nil
 }
 
-// RestoreBackup restores a backup
-func (bh *BackupHandler) RestoreBackup(backupID string) error {
+// GetBackup returns the backup with the given ID
+func (bh *BackupHandler) GetBackup(backupID string) (models.Backup, bool) {
 	bh.mu.RLock()
-	var backup *models.Backup
-	for _, b := range bh.backups {
-		if b.ID == backupID {
-			backup = &b
-			break
+	defer bh.mu.RUnlock()
+
+	for _, backup := range bh.backups {
+		if backup.ID == backupID {
+			return backup, true
 		}
 	}
-	bh.mu.RUnlock()
+	return models.Backup{}, false
+}
 
-	if backup == nil {
+// RestoreBackup restores a backup; directory backups are restored whole.
+// Unless force is set, it refuses to overwrite a file with uncommitted git
+// changes.
+func (bh *BackupHandler) RestoreBackup(ctx context.Context, backupID string, force bool) error {
+	backup, ok := bh.GetBackup(backupID)
+	if !ok {
 		return fmt.Errorf("backup not found: %s", backupID)
 	}
+	if backup.Type == models.BackupTypeTree {
+		_, err := bh.RestoreTreeBackup(ctx, backupID, nil, force)
+		return err
+	}
+
+	if err := bh.checkSandbox("restore", backup.OriginalPath); err != nil {
+		return err
+	}
 
 	// Check if backup file exists
-	if _, err := os.Stat(backup.BackupPath); err != nil {
+	if _, err := bh.store.Stat(backup.BackupPath); err != nil {
 		return fmt.Errorf("backup file missing: %w", err)
 	}
 
+	if !force {
+		if err := checkUncommitted([]string{backup.OriginalPath}); err != nil {
+			return err
+		}
+	}
+
 	// Copy backup to original location
-	if err := bh.copyFile(backup.BackupPath, backup.OriginalPath); err != nil {
+	if err := bh.restoreFile(backup.BackupPath, backup.OriginalPath); err != nil {
 		return fmt.Errorf("failed to restore file: %w", err)
 	}
 
 	return nil
 }
 
+// GetBackupSet returns the backups belonging to a set
+func (bh *BackupHandler) GetBackupSet(setID string) []models.Backup {
+	bh.mu.RLock()
+	defer bh.mu.RUnlock()
+
+	var members []models.Backup
+	for _, backup := range bh.backups {
+		if backup.SetID == setID {
+			members = append(members, backup)
+		}
+	}
+	return members
+}
+
+// RestoreBackupSet restores every file in a backup set. If any file fails
+// to restore, files already written are rolled back to their prior content.
+// Unless force is set, it refuses to overwrite files with uncommitted git changes.
+func (bh *BackupHandler) RestoreBackupSet(ctx context.Context, setID string, force bool) ([]models.Backup, error) {
+	members := bh.GetBackupSet(setID)
+	if len(members) == 0 {
+		return nil, fmt.Errorf("backup set not found: %s", setID)
+	}
+
+	// Make sure every backup file is present before touching anything
+	for _, backup := range members {
+		if _, err := bh.store.Stat(backup.BackupPath); err != nil {
+			return nil, fmt.Errorf("backup file missing for %s: %w", backup.OriginalPath, err)
+		}
+	}
+
+	var originals []string
+	for _, backup := range members {
+		originals = append(originals, backup.OriginalPath)
+	}
+	if err := bh.checkSandbox("restore", originals...); err != nil {
+		return nil, err
+	}
+
+	if !force {
+		if err := checkUncommitted(originals); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := bh.safety.Snapshot(ctx, "restore_set", originals); err != nil {
+		return nil, err
+	}
+
+	err := bh.writeWithRollback("set", originals, func(i int) error {
+		return bh.restoreFile(members[i].BackupPath, members[i].OriginalPath)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// writeWithRollback writes each target in turn with write. If one fails,
+// targets already written are rolled back to their prior content through
+// the storage, and targets that didn't exist are removed. what names the
+// group in the error, e.g. "set".
+func (bh *BackupHandler) writeWithRollback(what string, targets []string, write func(i int) error) error {
+	// Remember current contents so a failed restore can be undone
+	type priorState struct {
+		path    string
+		content []byte
+		existed bool
+	}
+	var priors []priorState
+	for _, target := range targets {
+		content, existed, err := readExisting(bh.store, target)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", target, err)
+		}
+		priors = append(priors, priorState{path: target, content: content, existed: existed})
+	}
+
+	for i, target := range targets {
+		if err := write(i); err != nil {
+			for _, prior := range priors[:i+1] {
+				var rollbackErr error
+				if prior.existed {
+					rollbackErr = bh.store.Write(prior.path, prior.content)
+				} else {
+					rollbackErr = bh.store.Remove(prior.path)
+				}
+				if rollbackErr != nil && !storage.IsNotExist(rollbackErr) {
+					slog.Error("failed to roll back file", "path", prior.path, "error", rollbackErr)
+				}
+			}
+			return fmt.Errorf("failed to write %s, %s rolled back: %w", target, what, err)
+		}
+	}
+	return nil
+}
+
 // ListBackups returns all backups or filtered by file path
 func (bh *BackupHandler) ListBackups(filePath string) []models.Backup {
 	bh.mu.RLock()
@@ -194,6 +426,7 @@ func (bh *BackupHandler) ListBackups(filePath string) []models.Backup {
 	if filePath == "" {
 		return bh.backups
 	}
+	filePath = bh.root.Abs(filePath)
 
 	var filtered []models.Backup
 	for _, backup := range bh.backups {
@@ -205,24 +438,47 @@ func (bh *BackupHandler) ListBackups(filePath string) []models.Backup {
 }
 
 // CleanOldBackups removes backups older than specified days
-func (bh *BackupHandler) CleanOldBackups(maxAgeDays int) (int, error) {
+func (bh *BackupHandler) CleanOldBackups(ctx context.Context, maxAgeDays int) (int, error) {
 	bh.mu.Lock()
 	defer bh.mu.Unlock()
 
 	cutoffTime := time.Now().AddDate(0, 0, -maxAgeDays)
+
+	var expired, kept []models.Backup
+	affected := []string{filepath.Join(bh.path, "metadata.json")}
+	for _, backup := range bh.backups {
+		if backup.Timestamp.Before(cutoffTime) {
+			expired = append(expired, backup)
+			affected = append(affected, backup.BackupPath)
+		} else {
+			kept = append(kept, backup)
+		}
+	}
+
+	// Directory backup content no remaining backup shares goes too; it is
+	// snapshotted with the rest so the cleanup can be undone
+	orphans := bh.orphanedObjects(expired, kept)
+	affected = append(affected, orphans...)
+
+	if len(affected) > 1 {
+		if _, err := bh.safety.Snapshot(ctx, "clean", affected); err != nil {
+			return 0, err
+		}
+	}
+
 	var retained []models.Backup
 	removedCount := 0
 
 	for _, backup := range bh.backups {
 		if backup.Timestamp.Before(cutoffTime) {
 			// Remove backup files
-			if err := os.RemoveAll(filepath.Dir(backup.BackupPath)); err != nil {
-				fmt.Printf("failed to remove backup %s: %v\n", backup.ID, err)
+			if err := bh.store.Remove(filepath.Dir(backup.BackupPath)); err != nil {
+				slog.WarnContext(ctx, "failed to remove backup", "id", backup.ID, "error", err)
 			}
 
 			// Remove from index
 			if err := bh.searchManager.DeleteDocument(search.IndexTypeBackups, backup.ID); err != nil {
-				fmt.Printf("failed to remove backup from index %s: %v\n", backup.ID, err)
+				slog.WarnContext(ctx, "failed to remove backup from index", "id", backup.ID, "error", err)
 			}
 
 			removedCount++
@@ -231,6 +487,12 @@ func (bh *BackupHandler) CleanOldBackups(maxAgeDays int) (int, error) {
 		}
 	}
 
+	for _, object := range orphans {
+		if err := bh.store.Remove(object); err != nil {
+			slog.WarnContext(ctx, "failed to remove backup object", "object", object, "error", err)
+		}
+	}
+
 	bh.backups = retained
 	if err := bh.save(); err != nil {
 		return removedCount, fmt.Errorf("failed to save metadata: %w", err)
@@ -239,25 +501,51 @@ func (bh *BackupHandler) CleanOldBackups(maxAgeDays int) (int, error) {
 	return removedCount, nil
 }
 
+// BackupArgs are the arguments of buddy_backup
+type BackupArgs struct {
+	Action     string   `arg:"action,required" enum:"list,create,create_set,create_tree,restore,restore_set,clean,list_safety,restore_safety" desc:"Action to perform: list, create, create_set, create_tree, restore, restore_set, clean, list_safety, restore_safety"`
+	FilePath   string   `arg:"file_path" desc:"Original file path (for create or list by file), or a file or subdirectory to restore from a directory backup"`
+	Query      string   `arg:"query" desc:"Search backups by path, context or reasoning (optional for list)"`
+	DirPath    string   `arg:"dir_path" desc:"Directory to back up with all its files (required for create_tree)"`
+	FilePaths  []string `arg:"file_paths" desc:"List of file paths to back up together (required for create_set)"`
+	BackupID   string   `arg:"backup_id" desc:"Backup ID (required for restore; for list, shows the files of a directory backup)"`
+	SetID      string   `arg:"set_id" desc:"Backup set ID (required for restore_set)"`
+	SnapshotID string   `arg:"snapshot_id" desc:"Automatic safety snapshot ID (required for restore_safety)"`
+	Context    string   `arg:"context" desc:"Context of the change (required for create, create_set and create_tree)"`
+	Reasoning  string   `arg:"reasoning" desc:"Reasoning for the backup (required for create, create_set and create_tree)"`
+	MaxAgeDays *int     `arg:"max_age_days" desc:"Maximum age in days for cleanup (required for clean)"`
+	Since      string   `arg:"since" desc:"List only backups at or after this time: RFC3339, YYYY-MM-DD, 'last 3 days', 'P1W' (optional)"`
+	Until      string   `arg:"until" desc:"List only backups at or before this time, same formats as since (optional)"`
+	Force      bool     `arg:"force" desc:"Restore even if the target files have uncommitted git changes (optional for restore and restore_set)"`
+}
+
 // GetToolHandler returns the tool handler function for backups
 func (bh *BackupHandler) GetToolHandler() server.ToolHandlerFunc {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		args := request.GetArguments()
-		action, ok := args["action"].(string)
-		if !ok {
-			return nil, fmt.Errorf("action is required")
-		}
-
-		switch action {
+	return toolargs.Handler(func(ctx context.Context, args BackupArgs) (*mcp.CallToolResult, error) {
+		switch args.Action {
 		case "list":
-			filePath, _ := args["file_path"].(string)
-			query, _ := args["query"].(string)
+			filePath, query := args.FilePath, args.Query
+
+			// A directory backup ID lists the files it holds
+			if args.BackupID != "" {
+				backup, manifest, err := bh.getTreeBackup(args.BackupID)
+				if err != nil {
+					return nil, err
+				}
+				return mcp.NewToolResultText(bh.formatTreeContents(backup, manifest)), nil
+			}
+
+			since, until, err := parseTimeRange(args.Since, args.Until, bh.timeFormat.Location())
+			if err != nil {
+				return nil, err
+			}
 
 			var backups []models.Backup
 
 			if query != "" {
 				// Use Bleve search
-				searchResults, err := bh.searchManager.Search(
+				searchResults, err := bh.searchManager.SearchContext(
+					ctx,
 					search.IndexTypeBackups,
 					query,
 					50, // Limit to 50 results
@@ -280,26 +568,31 @@ func (bh *BackupHandler) GetToolHandler() server.ToolHandlerFunc {
 				backups = bh.ListBackups(filePath)
 			}
 
+			if !since.IsZero() || !until.IsZero() {
+				var filtered []models.Backup
+				for _, backup := range backups {
+					if inTimeRange(backup.Timestamp, since, until) {
+						filtered = append(filtered, backup)
+					}
+				}
+				backups = filtered
+			}
+
 			result := bh.formatBackupList(backups, query)
 			return mcp.NewToolResultText(result), nil
 
 		case "create":
-			filePath, ok := args["file_path"].(string)
-			if !ok {
+			if args.FilePath == "" {
 				return nil, fmt.Errorf("file_path is required for create action")
 			}
-
-			context, ok := args["context"].(string)
-			if !ok {
+			if args.Context == "" {
 				return nil, fmt.Errorf("context is required for create action")
 			}
-
-			reasoning, ok := args["reasoning"].(string)
-			if !ok {
+			if args.Reasoning == "" {
 				return nil, fmt.Errorf("reasoning is required for create action")
 			}
 
-			backup, err := bh.CreateBackup(filePath, context, reasoning)
+			backup, err := bh.CreateBackup(args.FilePath, args.Context, args.Reasoning)
 			if err != nil {
 				return nil, err
 			}
@@ -309,30 +602,185 @@ func (bh *BackupHandler) GetToolHandler() server.ToolHandlerFunc {
 			result += fmt.Sprintf("Original: %s\n", backup.OriginalPath)
 			result += fmt.Sprintf("Backup: %s\n", backup.BackupPath)
 			result += fmt.Sprintf("Size: %d bytes\n", backup.FileSize)
-			result += fmt.Sprintf("Time: %s\n", backup.Timestamp.Format("2006-01-02 15:04:05"))
+			result += fmt.Sprintf("Time: %s\n", bh.timeFormat.Format(backup.Timestamp))
+
+			return mcp.NewToolResultText(result), nil
+
+		case "create_set":
+			if len(args.FilePaths) == 0 {
+				return nil, fmt.Errorf("file_paths is required for create_set action")
+			}
+
+			var filePaths, skipped []string
+			for _, path := range args.FilePaths {
+				if path != "" {
+					if !bh.allows(path) {
+						skipped = append(skipped, path)
+						continue
+					}
+					filePaths = append(filePaths, path)
+				}
+			}
+			if len(filePaths) == 0 {
+				return nil, fmt.Errorf("all file_paths are excluded by path configuration")
+			}
+
+			if args.Context == "" {
+				return nil, fmt.Errorf("context is required for create_set action")
+			}
+			if args.Reasoning == "" {
+				return nil, fmt.Errorf("reasoning is required for create_set action")
+			}
+
+			setID, backups, err := bh.CreateBackupSet(filePaths, args.Context, args.Reasoning)
+			if err != nil {
+				return nil, err
+			}
+
+			result := fmt.Sprintf("✅ Backup set created successfully\n\n")
+			result += fmt.Sprintf("Set ID: %s\n", setID)
+			result += fmt.Sprintf("Files: %d\n", len(backups))
+			for _, backup := range backups {
+				result += fmt.Sprintf("- %s (%s, ID: %s)\n", backup.OriginalPath, formatFileSize(backup.FileSize), backup.ID)
+			}
+			if len(skipped) > 0 {
+				result += fmt.Sprintf("\nSkipped %d excluded files:\n", len(skipped))
+				for _, path := range skipped {
+					result += fmt.Sprintf("- %s\n", path)
+				}
+			}
+			result += "\n💡 To restore all files at once, use action 'restore_set' with the set ID"
+
+			return mcp.NewToolResultText(result), nil
+
+		case "create_tree":
+			if args.DirPath == "" {
+				return nil, fmt.Errorf("dir_path is required for create_tree action")
+			}
+			if args.Context == "" {
+				return nil, fmt.Errorf("context is required for create_tree action")
+			}
+			if args.Reasoning == "" {
+				return nil, fmt.Errorf("reasoning is required for create_tree action")
+			}
+
+			backup, stats, err := bh.CreateTreeBackup(ctx, args.DirPath, args.Context, args.Reasoning)
+			if err != nil {
+				return nil, err
+			}
+
+			result := fmt.Sprintf("✅ Directory backup created successfully\n\n")
+			result += fmt.Sprintf("ID: %s\n", backup.ID)
+			result += fmt.Sprintf("Directory: %s\n", backup.OriginalPath)
+			result += fmt.Sprintf("Files: %d (%s)\n", backup.FileCount, formatFileSize(backup.FileSize))
+			result += fmt.Sprintf("Stored: %d new objects (%s compressed), %d files deduplicated\n",
+				stats.Stored, formatFileSize(stats.StoredBytes), stats.Deduplicated)
+			if len(stats.Skipped) > 0 {
+				result += fmt.Sprintf("⚠️ Skipped %d files over %s: %s\n",
+					len(stats.Skipped), formatFileSize(maxTreeBackupFileBytes), strings.Join(stats.Skipped, ", "))
+			}
+			result += fmt.Sprintf("Time: %s\n", bh.timeFormat.Format(backup.Timestamp))
+			result += "\n💡 Use action 'restore' with the backup ID to restore the whole tree, or add file_path for one file or subdirectory"
 
 			return mcp.NewToolResultText(result), nil
 
 		case "restore":
-			backupID, ok := args["backup_id"].(string)
-			if !ok {
+			backupID, force := args.BackupID, args.Force
+			if backupID == "" {
 				return nil, fmt.Errorf("backup_id is required for restore action")
 			}
 
-			if err := bh.RestoreBackup(backupID); err != nil {
+			if backup, ok := bh.GetBackup(backupID); ok && backup.Type == models.BackupTypeTree {
+				var paths []string
+				if args.FilePath != "" {
+					paths = append(paths, args.FilePath)
+				}
+
+				restored, err := bh.RestoreTreeBackup(ctx, backupID, paths, force)
+				if err != nil {
+					return nil, err
+				}
+				if len(restored) == 0 {
+					return mcp.NewToolResultText(fmt.Sprintf("✅ Nothing to restore: files already match backup %s", backupID)), nil
+				}
+
+				result := fmt.Sprintf("✅ Restored %d files from directory backup %s\n\n", len(restored), backupID)
+				for _, path := range restored {
+					result += fmt.Sprintf("- %s\n", path)
+				}
+				return mcp.NewToolResultText(result), nil
+			}
+
+			if err := bh.RestoreBackup(ctx, backupID, force); err != nil {
 				return nil, err
 			}
 
 			return mcp.NewToolResultText(fmt.Sprintf("✅ Backup %s restored successfully", backupID)), nil
 
+		case "restore_set":
+			setID := args.SetID
+			if setID == "" {
+				return nil, fmt.Errorf("set_id is required for restore_set action")
+			}
+
+			restored, err := bh.RestoreBackupSet(ctx, setID, args.Force)
+			if err != nil {
+				return nil, err
+			}
+
+			result := fmt.Sprintf("✅ Backup set %s restored successfully\n\n", setID)
+			for _, backup := range restored {
+				result += fmt.Sprintf("- %s\n", backup.OriginalPath)
+			}
+
+			return mcp.NewToolResultText(result), nil
+
+		case "list_safety":
+			snapshots, err := bh.safety.List()
+			if err != nil {
+				return nil, err
+			}
+
+			return mcp.NewToolResultText(bh.formatSafetySnapshots(snapshots)), nil
+
+		case "restore_safety":
+			snapshotID := args.SnapshotID
+			if snapshotID == "" {
+				return nil, fmt.Errorf("snapshot_id is required for restore_safety action")
+			}
+
+			// A snapshot's manifest names the files it writes, so they are
+			// checked against the sandbox before any is touched
+			snapshot, err := bh.safety.Get(snapshotID)
+			if err != nil {
+				return nil, err
+			}
+			if err := bh.checkSandbox("restore", append(slices.Sorted(maps.Keys(snapshot.Files)), snapshot.Created...)...); err != nil {
+				return nil, err
+			}
+
+			snapshot, err = bh.safety.Restore(snapshotID)
+			if err != nil {
+				return nil, err
+			}
+
+			result := fmt.Sprintf("✅ Safety snapshot %s restored successfully\n\n", snapshot.ID)
+			for _, original := range slices.Sorted(maps.Keys(snapshot.Files)) {
+				result += fmt.Sprintf("- %s\n", original)
+			}
+			for _, created := range snapshot.Created {
+				result += fmt.Sprintf("- %s (removed)\n", created)
+			}
+
+			return mcp.NewToolResultText(result), nil
+
 		case "clean":
-			maxAgeDaysFloat, ok := args["max_age_days"].(float64)
-			if !ok {
+			if args.MaxAgeDays == nil {
 				return nil, fmt.Errorf("max_age_days is required for clean action")
 			}
-			maxAgeDays := int(maxAgeDaysFloat)
+			maxAgeDays := *args.MaxAgeDays
 
-			removedCount, err := bh.CleanOldBackups(maxAgeDays)
+			removedCount, err := bh.CleanOldBackups(ctx, maxAgeDays)
 			if err != nil {
 				return nil, err
 			}
@@ -344,9 +792,9 @@ func (bh *BackupHandler) GetToolHandler() server.ToolHandlerFunc {
 			return mcp.NewToolResultText(result), nil
 
 		default:
-			return nil, fmt.Errorf("invalid action: %s", action)
+			return nil, fmt.Errorf("invalid action: %s", args.Action)
 		}
-	}
+	})
 }
 
 // formatBackupList formats backup list for display
@@ -416,19 +864,63 @@ func (bh *BackupHandler) formatBackupList(backups []models.Backup, query string)
 	}
 
 	// Add restore instructions
-	result += "\n💡 To restore a backup, use action 'restore' with the backup ID"
+	result += "\n💡 To restore a backup, use action 'restore' with the backup ID, or 'restore_set' with a set ID"
+
+	return result
+}
+
+// formatSafetySnapshots formats automatic safety snapshots for display
+func (bh *BackupHandler) formatSafetySnapshots(snapshots []SafetySnapshot) string {
+	if len(snapshots) == 0 {
+		return "No safety snapshots found"
+	}
+
+	result := fmt.Sprintf("Found %d safety snapshots\n", len(snapshots))
+	for _, snapshot := range snapshots {
+		result += fmt.Sprintf("\n🛟 ID: %s\n", snapshot.ID)
+		result += fmt.Sprintf("   Action: %s\n", snapshot.Action)
+		if snapshot.Label != "" {
+			result += fmt.Sprintf("   Label: %s\n", snapshot.Label)
+		}
+		result += fmt.Sprintf("   Time: %s (%s)\n",
+			bh.timeFormat.Format(snapshot.Timestamp),
+			bh.formatTimeAgo(snapshot.Timestamp))
+		result += fmt.Sprintf("   Files: %d\n", len(snapshot.Files))
+		if len(snapshot.Created) > 0 {
+			result += fmt.Sprintf("   Created: %d (removed on restore)\n", len(snapshot.Created))
+		}
+	}
+
+	result += "\n💡 To undo a destructive action, use action 'restore_safety' with the snapshot ID"
 
 	return result
 }
 
 // formatBackupEntry formats a single backup entry
 func (bh *BackupHandler) formatBackupEntry(backup models.Backup) string {
+	if backup.Type == models.BackupTypeTree {
+		result := fmt.Sprintf("\n🗂️ ID: %s\n", backup.ID)
+		result += fmt.Sprintf("   Directory: %s\n", backup.OriginalPath)
+		result += fmt.Sprintf("   Time: %s (%s)\n",
+			bh.timeFormat.Format(backup.Timestamp),
+			bh.formatTimeAgo(backup.Timestamp))
+		result += fmt.Sprintf("   Tree: %d files, %s\n", backup.FileCount, formatFileSize(backup.FileSize))
+		result += fmt.Sprintf("   Context: %s\n", backup.ChangeContext)
+		if backup.Reasoning != "" {
+			result += fmt.Sprintf("   Reasoning: %s\n", backup.Reasoning)
+		}
+		return result
+	}
+
 	result := fmt.Sprintf("\n📦 ID: %s\n", backup.ID)
 	result += fmt.Sprintf("   File: %s\n", backup.OriginalPath)
+	if backup.SetID != "" {
+		result += fmt.Sprintf("   Set: %s\n", backup.SetID)
+	}
 	result += fmt.Sprintf("   Time: %s (%s)\n",
-		backup.Timestamp.Format("2006-01-02 15:04:05"),
+		bh.timeFormat.Format(backup.Timestamp),
 		bh.formatTimeAgo(backup.Timestamp))
-	result += fmt.Sprintf("   Size: %s\n", bh.formatFileSize(backup.FileSize))
+	result += fmt.Sprintf("   Size: %s\n", formatFileSize(backup.FileSize))
 	result += fmt.Sprintf("   Context: %s\n", backup.ChangeContext)
 	if backup.Reasoning != "" {
 		result += fmt.Sprintf("   Reasoning: %s\n", backup.Reasoning)
@@ -466,7 +958,7 @@ func (bh *BackupHandler) formatTimeAgo(t time.Time) string {
 }
 
 // formatFileSize formats file size in human-readable format
-func (bh *BackupHandler) formatFileSize(size int64) string {
+func formatFileSize(size int64) string {
 	const unit = 1024
 	if size < unit {
 		return fmt.Sprintf("%d B", size)
@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/workspace"
+)
+
+// maxTreeBackupFiles bounds directory backups so a mistaken root such as
+// the home directory fails fast instead of copying everything
+const maxTreeBackupFiles = 10000
+
+// maxTreeBackupFileBytes is the largest file a directory backup copies;
+// bigger files, usually build output or media, are skipped and reported
+const maxTreeBackupFileBytes = 10 << 20
+
+// treeManifest lists the files of a directory backup and the stored
+// objects holding their content
+type treeManifest struct {
+	Root  string     `json:"root"`
+	Files []treeFile `json:"files"`
+}
+
+// treeFile is one file of a directory backup
+type treeFile struct {
+	Path string      `json:"path"` // slash-separated, relative to the root
+	Hash string      `json:"hash"` // SHA-256 of the content
+	Size int64       `json:"size"`
+	Mode fs.FileMode `json:"mode"`
+}
+
+// TreeBackupStats reports how much new content a directory backup stored
+type TreeBackupStats struct {
+	Files        int
+	Stored       int // objects written by this backup
+	StoredBytes  int64
+	Deduplicated int      // files whose content was already stored
+	Skipped      []string // files over maxTreeBackupFileBytes, relative to the root
+}
+
+// objectPath returns where content with the given hash is stored. Objects
+// are shared by all directory backups, so each distinct content is stored
+// once. hash must be a SHA-256 hex digest; manifests are checked with
+// validObjectHash when read.
+func (bh *BackupHandler) objectPath(hash string) string {
+	return filepath.Join(bh.path, "objects", hash[:2], hash+".gz")
+}
+
+// validObjectHash reports whether hash is a SHA-256 hex digest, so it
+// names an object inside the object store
+func validObjectHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// CreateTreeBackup backs up every file under dirPath that passes the path
+// configuration, storing each distinct content once. A cancelled ctx stops
+// the walk before the manifest is written, so no backup is recorded.
+func (bh *BackupHandler) CreateTreeBackup(ctx context.Context, dirPath, changeContext, reasoning string) (*models.Backup, TreeBackupStats, error) {
+	var stats TreeBackupStats
+	dirPath = bh.root.Abs(dirPath)
+
+	if err := bh.checkSandbox("backup", dirPath); err != nil {
+		return nil, stats, err
+	}
+	if !bh.allows(dirPath) {
+		return nil, stats, fmt.Errorf("directory is excluded from backups by path configuration: %s", dirPath)
+	}
+	info, err := os.Stat(dirPath)
+	if err != nil {
+		return nil, stats, fmt.Errorf("directory not found: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, stats, fmt.Errorf("not a directory: %s (use create for single files)", dirPath)
+	}
+
+	// Never back up the buddy folder itself, backups included
+	buddyRoot, _ := filepath.Abs(filepath.Dir(bh.path))
+
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+
+	manifest := treeManifest{Root: bh.root.Rel(dirPath)}
+	var totalSize int64
+	seen := make(map[string]bool)
+
+	err = filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == dirPath {
+				return nil
+			}
+			if absPath, _ := filepath.Abs(path); absPath == buddyRoot || !bh.allows(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !bh.allows(path) {
+			return nil
+		}
+		if len(manifest.Files) >= maxTreeBackupFiles {
+			return fmt.Errorf("directory has more than %d files; back up a narrower directory", maxTreeBackupFiles)
+		}
+
+		relPath, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+		fileInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if fileInfo.Size() > maxTreeBackupFileBytes {
+			stats.Skipped = append(stats.Skipped, filepath.ToSlash(relPath))
+			return nil
+		}
+
+		content, err := bh.store.Read(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		if err := bh.storeObject(hash, content, seen, &stats); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, treeFile{
+			Path: filepath.ToSlash(relPath),
+			Hash: hash,
+			Size: int64(len(content)),
+			Mode: fileInfo.Mode().Perm(),
+		})
+		totalSize += int64(len(content))
+		return nil
+	})
+	if err != nil {
+		return nil, stats, err
+	}
+	stats.Files = len(manifest.Files)
+
+	id := fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%d", dirPath, time.Now().UnixNano()))))
+	manifestPath := filepath.Join(bh.path, id, "manifest.json")
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, stats, err
+	}
+	if err := bh.store.Write(manifestPath, data); err != nil {
+		return nil, stats, fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+
+	backup := models.Backup{
+		ID:            id,
+		OriginalPath:  dirPath,
+		BackupPath:    manifestPath,
+		Timestamp:     time.Now().UTC(),
+		ChangeContext: changeContext,
+		Reasoning:     reasoning,
+		FileSize:      totalSize,
+		Type:          models.BackupTypeTree,
+		FileCount:     len(manifest.Files),
+	}
+
+	bh.backups = append(bh.backups, backup)
+	if err := bh.save(); err != nil {
+		return nil, stats, fmt.Errorf("failed to save metadata: %w", err)
+	}
+	bh.indexBackup(backup)
+
+	return &backup, stats, nil
+}
+
+// storeObject writes content to the object store unless it is already
+// there. The caller must hold the lock.
+func (bh *BackupHandler) storeObject(hash string, content []byte, seen map[string]bool, stats *TreeBackupStats) error {
+	if seen[hash] {
+		stats.Deduplicated++
+		return nil
+	}
+	seen[hash] = true
+
+	path := bh.objectPath(hash)
+	if _, err := bh.store.Stat(path); err == nil {
+		stats.Deduplicated++
+		return nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := bh.store.Write(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to store backup object: %w", err)
+	}
+
+	stats.Stored++
+	stats.StoredBytes += int64(buf.Len())
+	return nil
+}
+
+// readObject returns stored content, checking it against its hash
+func (bh *BackupHandler) readObject(hash string) ([]byte, error) {
+	data, err := bh.store.Read(bh.objectPath(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("backup object %s is corrupt", hash)
+	}
+	return content, nil
+}
+
+// readManifest loads the file list of a directory backup
+func (bh *BackupHandler) readManifest(backup models.Backup) (treeManifest, error) {
+	var manifest treeManifest
+	data, err := bh.store.Read(backup.BackupPath)
+	if err != nil {
+		return manifest, fmt.Errorf("backup manifest missing: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	for _, file := range manifest.Files {
+		if !validObjectHash(file.Hash) {
+			return manifest, fmt.Errorf("invalid backup manifest: %s has malformed hash %q", file.Path, file.Hash)
+		}
+	}
+	manifest.Root = bh.root.Abs(manifest.Root)
+	return manifest, nil
+}
+
+// getTreeBackup returns a directory backup and its manifest
+func (bh *BackupHandler) getTreeBackup(backupID string) (models.Backup, treeManifest, error) {
+	backup, ok := bh.GetBackup(backupID)
+	if !ok {
+		return backup, treeManifest{}, fmt.Errorf("backup not found: %s", backupID)
+	}
+	if backup.Type != models.BackupTypeTree {
+		return backup, treeManifest{}, fmt.Errorf("backup %s is a single file, not a directory", backupID)
+	}
+
+	manifest, err := bh.readManifest(backup)
+	return backup, manifest, err
+}
+
+// selectTreeFiles picks the manifest files matching paths, each of which
+// may name a file or directory relative to the backup's root or to the
+// project root. No paths selects everything.
+func selectTreeFiles(manifest treeManifest, root workspace.Root, paths []string) ([]treeFile, error) {
+	if len(paths) == 0 {
+		return manifest.Files, nil
+	}
+
+	var selected []treeFile
+	picked := make(map[string]bool)
+	for _, path := range paths {
+		// Accept paths relative to the tree root as well as paths naming
+		// the files where they live, e.g. "a.go" or "src/a.go" for root "src"
+		candidates := []string{path}
+		if rel, err := filepath.Rel(manifest.Root, root.Abs(path)); err == nil && !strings.HasPrefix(rel, "..") {
+			candidates = append(candidates, rel)
+		}
+
+		matched := false
+		for _, candidate := range candidates {
+			candidate = filepath.ToSlash(filepath.Clean(candidate))
+			for _, file := range manifest.Files {
+				if candidate == "." || file.Path == candidate || strings.HasPrefix(file.Path, candidate+"/") {
+					matched = true
+					if !picked[file.Path] {
+						picked[file.Path] = true
+						selected = append(selected, file)
+					}
+				}
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("%s is not in the backup of %s", path, manifest.Root)
+		}
+	}
+	return selected, nil
+}
+
+// RestoreTreeBackup restores files from a directory backup: all of them,
+// or those under paths. Files already matching the backup are left alone,
+// and files added since the backup are kept. Unless force is set, it
+// refuses to overwrite files with uncommitted git changes.
+func (bh *BackupHandler) RestoreTreeBackup(ctx context.Context, backupID string, paths []string, force bool) ([]string, error) {
+	_, manifest, err := bh.getTreeBackup(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := selectTreeFiles(manifest, bh.root, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only touch files whose content differs from the backup
+	var (
+		changed []treeFile
+		targets []string
+	)
+	for _, file := range files {
+		target := filepath.Join(manifest.Root, filepath.FromSlash(file.Path))
+		if current, err := bh.store.Read(target); err == nil {
+			sum := sha256.Sum256(current)
+			if hex.EncodeToString(sum[:]) == file.Hash {
+				continue
+			}
+		}
+		if _, err := bh.store.Stat(bh.objectPath(file.Hash)); err != nil {
+			return nil, fmt.Errorf("backup content missing for %s: %w", file.Path, err)
+		}
+		changed = append(changed, file)
+		targets = append(targets, target)
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	if err := bh.checkSandbox("restore", targets...); err != nil {
+		return nil, err
+	}
+
+	if !force {
+		if err := checkUncommitted(targets); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := bh.safety.Snapshot(ctx, "restore_tree", targets); err != nil {
+		return nil, err
+	}
+
+	err = bh.writeWithRollback("tree", targets, func(i int) error {
+		content, err := bh.readObject(changed[i].Hash)
+		if err != nil {
+			return err
+		}
+		mode := changed[i].Mode
+		if mode == 0 {
+			mode = 0644
+		}
+		_, err = bh.writeVerified(targets[i], content, mode)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	restored := make([]string, len(changed))
+	for i, file := range changed {
+		restored[i] = file.Path
+	}
+	return restored, nil
+}
+
+// orphanedObjects returns the stored objects only referenced by the
+// removed backups. When a manifest can't be read, nothing is reported, so
+// content that may still be needed is kept.
+func (bh *BackupHandler) orphanedObjects(removed, retained []models.Backup) []string {
+	candidates := make(map[string]bool)
+	for _, backup := range removed {
+		if backup.Type != models.BackupTypeTree {
+			continue
+		}
+		manifest, err := bh.readManifest(backup)
+		if err != nil {
+			return nil
+		}
+		for _, file := range manifest.Files {
+			candidates[file.Hash] = true
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	for _, backup := range retained {
+		if backup.Type != models.BackupTypeTree {
+			continue
+		}
+		manifest, err := bh.readManifest(backup)
+		if err != nil {
+			return nil
+		}
+		for _, file := range manifest.Files {
+			delete(candidates, file.Hash)
+		}
+	}
+
+	var paths []string
+	for hash := range candidates {
+		paths = append(paths, bh.objectPath(hash))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// formatTreeContents lists the files of a directory backup
+func (bh *BackupHandler) formatTreeContents(backup models.Backup, manifest treeManifest) string {
+	result := fmt.Sprintf("🗂️ Directory backup %s\n", backup.ID)
+	result += fmt.Sprintf("Root: %s\n", manifest.Root)
+	result += fmt.Sprintf("Time: %s (%s)\n", bh.timeFormat.Format(backup.Timestamp), bh.formatTimeAgo(backup.Timestamp))
+	result += fmt.Sprintf("Files: %d, %s\n\n", len(manifest.Files), formatFileSize(backup.FileSize))
+
+	for _, file := range manifest.Files {
+		result += fmt.Sprintf("- %s (%s)\n", file.Path, formatFileSize(file.Size))
+	}
+
+	result += "\n💡 Use action 'restore' with this backup ID to restore the whole tree, or add file_path to restore one file or subdirectory"
+	return result
+}
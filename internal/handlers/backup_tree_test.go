@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTreeBackup_Deduplicates(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	writeTestFile(t, filepath.Join(project, "src", "a.go"), "package src\n")
+	writeTestFile(t, filepath.Join(project, "src", "b.go"), "package src\n")
+	writeTestFile(t, filepath.Join(project, "src", "c.go"), "package src // c\n")
+
+	backup, stats, err := bh.CreateTreeBackup(context.Background(), "src", "before refactor", "test")
+	require.NoError(t, err)
+	assert.Equal(t, 3, backup.FileCount)
+	assert.Equal(t, 3, stats.Files)
+	assert.Equal(t, 2, stats.Stored)
+	assert.Equal(t, 1, stats.Deduplicated)
+
+	// Nothing changed: every file is already stored
+	_, stats, err = bh.CreateTreeBackup(context.Background(), "src", "again", "test")
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Stored)
+	assert.Equal(t, 3, stats.Deduplicated)
+}
+
+func TestCreateTreeBackup_SkipsOversizedFiles(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	writeTestFile(t, filepath.Join(project, "src", "a.go"), "package src\n")
+	large, err := os.Create(filepath.Join(project, "src", "video.mp4"))
+	require.NoError(t, err)
+	require.NoError(t, large.Truncate(maxTreeBackupFileBytes+1))
+	require.NoError(t, large.Close())
+
+	backup, stats, err := bh.CreateTreeBackup(context.Background(), "src", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, backup.FileCount)
+	assert.Equal(t, []string{"video.mp4"}, stats.Skipped)
+}
+
+func TestRestoreTreeBackup_SelectedPaths(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	writeTestFile(t, filepath.Join(project, "src", "a.go"), "package src\n")
+	writeTestFile(t, filepath.Join(project, "src", "sub", "b.go"), "package sub\n")
+	backup, _, err := bh.CreateTreeBackup(context.Background(), "src", "", "")
+	require.NoError(t, err)
+
+	writeTestFile(t, filepath.Join(project, "src", "a.go"), "package src // changed\n")
+	writeTestFile(t, filepath.Join(project, "src", "sub", "b.go"), "package sub // changed\n")
+	writeTestFile(t, filepath.Join(project, "src", "sub", "new.go"), "package sub\n")
+
+	restored, err := bh.RestoreTreeBackup(context.Background(), backup.ID, []string{"sub"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub/b.go"}, restored)
+	assertFileContent(t, filepath.Join(project, "src", "sub", "b.go"), "package sub\n")
+	assertFileContent(t, filepath.Join(project, "src", "a.go"), "package src // changed\n")
+	assertFileContent(t, filepath.Join(project, "src", "sub", "new.go"), "package sub\n")
+
+	// Unchanged files are left alone
+	restored, err = bh.RestoreTreeBackup(context.Background(), backup.ID, []string{"src/sub"}, true)
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+
+	_, err = bh.RestoreTreeBackup(context.Background(), backup.ID, []string{"missing"}, true)
+	assert.Error(t, err)
+}
+
+func TestRestoreTreeBackup_RollsBackWhenAWriteFails(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	writeTestFile(t, filepath.Join(project, "src", "a.go"), "package src // a\n")
+	writeTestFile(t, filepath.Join(project, "src", "b.go"), "package src // b\n")
+	backup, _, err := bh.CreateTreeBackup(context.Background(), "src", "", "")
+	require.NoError(t, err)
+
+	writeTestFile(t, filepath.Join(project, "src", "a.go"), "package src // a changed\n")
+	writeTestFile(t, filepath.Join(project, "src", "b.go"), "package src // b changed\n")
+	bh.store = &faultyStore{Storage: bh.store, failWrite: filepath.Join(project, "src", "b.go")}
+
+	_, err = bh.RestoreTreeBackup(context.Background(), backup.ID, nil, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tree rolled back")
+	assertFileContent(t, filepath.Join(project, "src", "a.go"), "package src // a changed\n")
+	assertFileContent(t, filepath.Join(project, "src", "b.go"), "package src // b changed\n")
+}
+
+func TestOrphanedObjects(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	writeTestFile(t, filepath.Join(project, "src", "shared.go"), "package src\n")
+	writeTestFile(t, filepath.Join(project, "src", "old.go"), "package src // old\n")
+	old, _, err := bh.CreateTreeBackup(context.Background(), "src", "", "")
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(filepath.Join(project, "src", "old.go")))
+	writeTestFile(t, filepath.Join(project, "src", "new.go"), "package src // new\n")
+	current, _, err := bh.CreateTreeBackup(context.Background(), "src", "", "")
+	require.NoError(t, err)
+
+	manifest, err := bh.readManifest(*old)
+	require.NoError(t, err)
+	var oldHash string
+	for _, file := range manifest.Files {
+		if file.Path == "old.go" {
+			oldHash = file.Hash
+		}
+	}
+
+	orphaned := bh.orphanedObjects([]models.Backup{*old}, []models.Backup{*current})
+	assert.Equal(t, []string{bh.objectPath(oldHash)}, orphaned)
+	assert.Empty(t, bh.orphanedObjects([]models.Backup{*current}, []models.Backup{*old, *current}))
+}
+
+func TestReadManifest_RejectsMalformedHashes(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	writeTestFile(t, filepath.Join(project, "src", "a.go"), "package src\n")
+	backup, _, err := bh.CreateTreeBackup(context.Background(), "src", "", "")
+	require.NoError(t, err)
+
+	for _, hash := range []string{"", "a", "../../../etc/passwd", strings.Repeat("z", 64)} {
+		writeTestFile(t, backup.BackupPath, `{"root": "src", "files": [{"path": "a.go", "hash": "`+hash+`"}]}`)
+		_, err := bh.readManifest(*backup)
+		assert.ErrorContains(t, err, "malformed hash", hash)
+	}
+}
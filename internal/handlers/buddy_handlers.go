@@ -4,48 +4,168 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/timeutil"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/workspace"
 )
 
 // marshalFunc is a test hook for json.Marshal
 var marshalFunc = json.Marshal
 
+// parseTimeRange parses the optional "since" and "until" tool arguments.
+// Zero times mean the bound is open; an until naming a day runs to the
+// end of that day.
+func parseTimeRange(sinceArg, untilArg string, loc *time.Location) (time.Time, time.Time, error) {
+	var since, until time.Time
+	now := time.Now()
+
+	if sinceArg != "" {
+		t, err := timeutil.ParseTime(sinceArg, now, loc)
+		if err != nil {
+			return since, until, fmt.Errorf("invalid since: %w", err)
+		}
+		since = t
+	}
+
+	if untilArg != "" {
+		t, err := timeutil.ParseUntil(untilArg, now, loc)
+		if err != nil {
+			return since, until, fmt.Errorf("invalid until: %w", err)
+		}
+		until = t
+	}
+
+	return since, until, nil
+}
+
+// inTimeRange reports whether t falls within the optional since/until bounds
+func inTimeRange(t, since, until time.Time) bool {
+	if !since.IsZero() && t.Before(since) {
+		return false
+	}
+	if !until.IsZero() && t.After(until) {
+		return false
+	}
+	return true
+}
+
 // BuddyHandlers manages all buddy system handlers
 type BuddyHandlers struct {
-	buddyPath        string
-	searchManager    *search.SearchManager
-	rulesHandler     *RulesHandler
-	knowledgeHandler *KnowledgeHandler
-	databaseHandler  *DatabaseHandler
-	todoHandler      *TodoHandler
-	historyHandler   *HistoryHandler
-	backupHandler    *BackupHandler
-	mu               sync.RWMutex
-}
-
-// NewBuddyHandlers creates a new instance of BuddyHandlers
+	buddyPath         string
+	config            atomic.Pointer[config.Config] // replaced by ReloadConfig
+	timeFormat        *timeutil.Formatter
+	searchManager     *search.SearchManager
+	store             storage.Storage
+	rulesHandler      *RulesHandler
+	knowledgeHandler  *KnowledgeHandler
+	databaseHandler   *DatabaseHandler
+	todoHandler       *TodoHandler
+	historyHandler    *HistoryHandler
+	backupHandler     *BackupHandler
+	draftHandler      *DraftHandler
+	datasetsHandler   *DatasetsHandler
+	complianceHandler *ComplianceHandler
+	budgetsHandler    *BudgetsHandler
+	viewsHandler      *ViewsHandler
+	custom            []customHandler // registered with RegisterHandler
+	changeLog         *ChangeLog
+	timeline          *Timeline
+	claims            *ClaimRegistry
+	focus             *FocusStore
+	resources         *ResourceCache
+	scratchpad        *Scratchpad
+	sampler           *Sampler
+	activity          *ActivityTracker // nil outside a server
+	reloaders         map[string]*sectionReloader
+	loadCtx           context.Context
+	stopLoads         context.CancelFunc
+	reloadOrder       []string
+	reloadsInFlight   int
+	reloadsIdle       *sync.Cond // signalled when reloadsInFlight drops to zero
+	snapshot          atomic.Pointer[ContextSnapshot]
+	indexes           indexMaintenance
+	mu                sync.RWMutex
+}
+
+// workspaceRoot returns the directory stored paths are relative to: the
+// configured root, resolved from the directory containing the buddy folder,
+// or that directory itself
+func workspaceRoot(buddyPath string, cfg *config.Config) (workspace.Root, error) {
+	dir := filepath.Dir(filepath.Clean(buddyPath))
+	if cfg.Paths.Root != "" {
+		dir = filepath.Join(dir, cfg.Paths.Root)
+		if filepath.IsAbs(cfg.Paths.Root) {
+			dir = cfg.Paths.Root
+		}
+	}
+	return workspace.NewRoot(dir)
+}
+
+// NewBuddyHandlers creates a new instance of BuddyHandlers backed by the local filesystem
 func NewBuddyHandlers(buddyPath string) (*BuddyHandlers, error) {
+	return NewBuddyHandlersWithStorage(buddyPath, storage.NewLocal())
+}
+
+// NewBuddyHandlersWithStorage creates a new instance of BuddyHandlers that
+// reads and writes buddy content through the given storage backend
+func NewBuddyHandlersWithStorage(buddyPath string, store storage.Storage) (*BuddyHandlers, error) {
 	// Create buddy directory structure if it doesn't exist
 	if err := createBuddyStructure(buddyPath); err != nil {
 		return nil, fmt.Errorf("failed to create buddy structure: %w", err)
 	}
 
+	// Load optional configuration
+	cfg, err := config.Load(buddyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	timeFormat, err := timeutil.NewFormatter(cfg.Display.TimeZone, cfg.Display.Locale, cfg.Display.TimeFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid display configuration: %w", err)
+	}
+
 	// Initialize search manager
-	searchManager, err := search.NewSearchManager(buddyPath)
+	indexesPath, err := indexDir(buddyPath, cfg.Search)
+	if err != nil {
+		return nil, err
+	}
+	searchManager, err := search.NewSearchManagerIn(indexesPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create search manager: %w", err)
 	}
 
 	bh := &BuddyHandlers{
 		buddyPath:     buddyPath,
+		timeFormat:    timeFormat,
 		searchManager: searchManager,
+		store:         store,
+		changeLog:     NewChangeLog(),
+		timeline:      NewTimeline(filepath.Join(buddyPath, timelineDir), buddyPath, store),
+		claims:        NewClaimRegistry(),
+		focus:         NewFocusStore(filepath.Join(buddyPath, focusFile), store),
+		resources:     NewResourceCache(),
 	}
+	// A failed start closes what it opened, so the indexes aren't left
+	// locked for the next attempt
+	started := false
+	defer func() {
+		if !started {
+			bh.Close()
+		}
+	}()
 
 	// Initialize all handlers with search manager
 	bh.rulesHandler = NewRulesHandler(filepath.Join(buddyPath, "rules"), searchManager)
@@ -54,85 +174,143 @@ func NewBuddyHandlers(buddyPath string) (*BuddyHandlers, error) {
 	bh.todoHandler = NewTodoHandler(filepath.Join(buddyPath, "todos"), searchManager)
 	bh.historyHandler = NewHistoryHandler(filepath.Join(buddyPath, "history"), searchManager)
 	bh.backupHandler = NewBackupHandler(filepath.Join(buddyPath, "backups"), searchManager)
+	bh.draftHandler = NewDraftHandler(filepath.Join(buddyPath, "drafts"), store)
+	bh.datasetsHandler = NewDatasetsHandler(filepath.Join(buddyPath, "datasets"), searchManager)
+	bh.complianceHandler = NewComplianceHandler(filepath.Join(buddyPath, "compliance"), searchManager)
+	bh.budgetsHandler = NewBudgetsHandler(buddyPath, filepath.Join(buddyPath, "budgets"), store)
+	bh.viewsHandler = NewViewsHandler(filepath.Join(buddyPath, "views"))
 
-	// Load initial data
-	if err := bh.loadAllData(); err != nil {
-		return nil, fmt.Errorf("failed to load initial data: %w", err)
-	}
+	// Route all content I/O through the configured storage backend
+	bh.rulesHandler.store = store
+	bh.knowledgeHandler.store = store
+	bh.databaseHandler.store = store
+	bh.todoHandler.store = store
+	bh.historyHandler.store = store
+	bh.backupHandler.store = store
+	bh.datasetsHandler.store = store
+	bh.complianceHandler.store = store
+	bh.viewsHandler.store = store
 
-	return bh, nil
-}
+	// Scratchpad notes marked for promotion become history or knowledge
+	// drafts when their session ends
+	bh.scratchpad = NewScratchpad(filepath.Join(buddyPath, scratchpadDir), store, bh.promoteScratchNote)
 
-// createBuddyStructure creates the necessary directory structure
-func createBuddyStructure(buddyPath string) error {
-	dirs := []string{
-		"rules",
-		"knowledge",
-		"todos",
-		"database",
-		"history",
-		"backups",
-		"indexes", // For Bleve indexes
-	}
+	// Destructive actions snapshot affected files here first
+	safety := BuddySafetyStore(buddyPath, store)
+	bh.backupHandler.safety = safety
+	bh.todoHandler.safety = safety
+	bh.rulesHandler.safety = safety
+	bh.todoHandler.claims = bh.claims
 
-	for _, dir := range dirs {
-		path := filepath.Join(buddyPath, dir)
-		if err := os.MkdirAll(path, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", path, err)
-		}
+	// Read tools narrow and rank their results by the current focus
+	if err := bh.focus.Load(); err != nil {
+		slog.Warn("ignoring focus", "error", err)
 	}
+	bh.todoHandler.focus = bh.focus
+	bh.historyHandler.focus = bh.focus
+	bh.knowledgeHandler.focus = bh.focus
 
-	return nil
-}
-
-// loadAllData loads all data from disk
-func (bh *BuddyHandlers) loadAllData() error {
-	// Load rules
-	if err := bh.rulesHandler.Load(); err != nil {
-		return fmt.Errorf("failed to load rules: %w", err)
+	// Stored paths are relative to the workspace, by default the project
+	// that contains the buddy folder
+	absBuddyPath, err := filepath.Abs(buddyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve buddy path: %w", err)
 	}
-
-	// Load knowledge
-	if err := bh.knowledgeHandler.Load(); err != nil {
-		return fmt.Errorf("failed to load knowledge: %w", err)
+	root, err := workspaceRoot(absBuddyPath, cfg)
+	if err != nil {
+		return nil, err
 	}
+	bh.backupHandler.root = root
+	bh.rulesHandler.root = root
+	bh.historyHandler.root = root
+	bh.backupHandler.timeFormat = timeFormat
+	bh.historyHandler.timeFormat = timeFormat
+	bh.databaseHandler.timeFormat = timeFormat
+	bh.budgetsHandler.timeFormat = timeFormat
 
-	// Load database
-	if err := bh.databaseHandler.Load(); err != nil {
-		return fmt.Errorf("failed to load database: %w", err)
+	if err := bh.initCustomHandlers(buddyPath, store); err != nil {
+		return nil, err
 	}
 
-	// Load todos
-	if err := bh.todoHandler.Load(); err != nil {
-		return fmt.Errorf("failed to load todos: %w", err)
-	}
+	// Apply the settings that can change while the server runs
+	bh.applyConfig(cfg, absBuddyPath)
+
+	bh.initReloaders()
 
-	// Load history
-	if err := bh.historyHandler.Load(); err != nil {
-		return fmt.Errorf("failed to load history: %w", err)
+	// Load initial data
+	if err := bh.loadSections(); err != nil {
+		return nil, fmt.Errorf("failed to load initial data: %w", err)
 	}
+	bh.startIndexMaintenance(bh.loadCtx)
+
+	started = true
+	return bh, nil
+}
 
-	// Load backups
-	if err := bh.backupHandler.Load(); err != nil {
-		return fmt.Errorf("failed to load backups: %w", err)
+// buddyDirs are the subdirectories every buddy folder has
+var buddyDirs = []string{
+	"rules",
+	"knowledge",
+	"todos",
+	"database",
+	"history",
+	"backups",
+	"drafts",
+	"datasets",
+	"compliance",
+	"budgets",
+	"views",
+	"indexes", // For Bleve indexes
+}
+
+// createBuddyStructure creates the necessary directory structure, along
+// with a directory per registered custom handler
+func createBuddyStructure(buddyPath string) error {
+	for _, dir := range append(buddyDirs[:len(buddyDirs):len(buddyDirs)], RegisteredHandlers()...) {
+		path := filepath.Join(buddyPath, dir)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", path, err)
+		}
 	}
 
 	return nil
 }
 
-// ReloadData reloads data when files change
+// ReloadData reloads data when files change. The focus is re-read too,
+// e.g. after buddy_undo put back an earlier focus.json.
 func (bh *BuddyHandlers) ReloadData() error {
-	return bh.loadAllData()
+	if err := bh.focus.Load(); err != nil {
+		slog.Warn("ignoring focus", "error", err)
+	}
+	return bh.loadSections()
 }
 
 // GetRulesToolHandler returns the tool handler for rules management
 func (bh *BuddyHandlers) GetRulesToolHandler() server.ToolHandlerFunc {
-	return bh.rulesHandler.GetToolHandler()
+	return toolargs.Handler(func(ctx context.Context, args RulesArgs) (*mcp.CallToolResult, error) {
+		// History comes from the timeline, which the rules handler doesn't see
+		if args.Action == "history" {
+			return bh.ruleHistory(ctx, args.Rule)
+		}
+		return bh.rulesHandler.handle(ctx, args)
+	})
 }
 
-// GetKnowledgeToolHandler returns the tool handler for knowledge base
+// GetNamingToolHandler returns the tool handler for identifier naming checks
+func (bh *BuddyHandlers) GetNamingToolHandler() server.ToolHandlerFunc {
+	return bh.rulesHandler.GetNamingToolHandler()
+}
+
+// GetKnowledgeToolHandler returns the tool handler for knowledge base.
+// With answer set, it answers the query from the best passages instead of
+// listing the matching documents.
 func (bh *BuddyHandlers) GetKnowledgeToolHandler() server.ToolHandlerFunc {
-	return bh.knowledgeHandler.GetToolHandler()
+	return toolargs.Handler(func(ctx context.Context, args KnowledgeArgs) (*mcp.CallToolResult, error) {
+		if args.Answer {
+			return bh.answerKnowledge(ctx, args)
+		}
+		return bh.knowledgeHandler.search(ctx, args)
+	})
 }
 
 // GetDatabaseToolHandler returns the tool handler for database management
@@ -140,6 +318,11 @@ func (bh *BuddyHandlers) GetDatabaseToolHandler() server.ToolHandlerFunc {
 	return bh.databaseHandler.GetToolHandler()
 }
 
+// GetFixtureToolHandler returns the tool handler for schema-based test fixtures
+func (bh *BuddyHandlers) GetFixtureToolHandler() server.ToolHandlerFunc {
+	return bh.databaseHandler.GetFixtureToolHandler()
+}
+
 // GetTodoToolHandler returns the tool handler for todo management
 func (bh *BuddyHandlers) GetTodoToolHandler() server.ToolHandlerFunc {
 	return bh.todoHandler.GetToolHandler()
@@ -155,36 +338,103 @@ func (bh *BuddyHandlers) GetBackupToolHandler() server.ToolHandlerFunc {
 	return bh.backupHandler.GetToolHandler()
 }
 
+// GetChangesetToolHandler returns the tool handler that writes files with
+// automatic backups
+func (bh *BuddyHandlers) GetChangesetToolHandler() server.ToolHandlerFunc {
+	return bh.backupHandler.GetChangesetToolHandler()
+}
+
+// GetDraftToolHandler returns the tool handler for rule and knowledge drafts
+func (bh *BuddyHandlers) GetDraftToolHandler() server.ToolHandlerFunc {
+	return bh.draftHandler.GetToolHandler()
+}
+
+// GetDatasetsToolHandler returns the tool handler for reference datasets
+func (bh *BuddyHandlers) GetDatasetsToolHandler() server.ToolHandlerFunc {
+	return bh.datasetsHandler.GetToolHandler()
+}
+
+// GetComplianceToolHandler returns the tool handler for license and policy checks
+func (bh *BuddyHandlers) GetComplianceToolHandler() server.ToolHandlerFunc {
+	return bh.complianceHandler.GetToolHandler()
+}
+
+// GetBudgetsToolHandler returns the tool handler for performance budgets
+func (bh *BuddyHandlers) GetBudgetsToolHandler() server.ToolHandlerFunc {
+	return bh.budgetsHandler.GetToolHandler()
+}
+
 // GetProjectContextResourceHandler returns the resource handler for project context
 func (bh *BuddyHandlers) GetProjectContextResourceHandler() server.ResourceHandlerFunc {
 	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-		// Gather all project context
-		projectContext := map[string]interface{}{
-			"rules":     bh.rulesHandler.GetRules(),
-			"knowledge": bh.knowledgeHandler.GetKnowledge(),
-			"todos":     bh.todoHandler.GetTodos(),
-			"database":  bh.databaseHandler.GetDatabaseInfo(),
-			"history":   bh.historyHandler.GetRecentHistory(10),
-		}
+		// Gather all project context from one consistent snapshot
+		return bh.readCachedResource(request, 0, func(snap *ContextSnapshot, now time.Time) (map[string]interface{}, error) {
+			payload := make(map[string]interface{}, len(ContextSections))
+			for _, name := range ContextSections {
+				value, err := snap.section(name)
+				if err != nil {
+					return nil, err
+				}
+				payload[name] = value
+			}
+			return payload, nil
+		})
+	}
+}
 
-		// Marshal to JSON
-		data, err := marshalFunc(projectContext)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal context: %w", err)
-		}
+// Storage returns the storage the handlers read and write through
+func (bh *BuddyHandlers) Storage() storage.Storage {
+	return bh.store
+}
 
-		return []mcp.ResourceContents{
-			mcp.TextResourceContents{
-				URI:      "buddy://project-context",
-				MIMEType: "application/json",
-				Text:     string(data),
-			},
-		}, nil
-	}
+// SetSampler lets the handlers have the client's model write summaries
+func (bh *BuddyHandlers) SetSampler(sampler *Sampler) {
+	bh.sampler = sampler
+}
+
+// SetActivity lets buddy_status report the server's uptime and last activity
+func (bh *BuddyHandlers) SetActivity(activity *ActivityTracker) {
+	bh.activity = activity
+}
+
+// Config returns the loaded buddy configuration
+func (bh *BuddyHandlers) Config() *config.Config {
+	return bh.config.Load()
+}
+
+// OversizedFiles lists the buddy files over the size limit at their last load
+func (bh *BuddyHandlers) OversizedFiles() []OversizedFile {
+	var files []OversizedFile
+	files = append(files, bh.rulesHandler.Oversized()...)
+	files = append(files, bh.knowledgeHandler.Oversized()...)
+	files = append(files, bh.todoHandler.Oversized()...)
+	files = append(files, bh.historyHandler.Oversized()...)
+	files = append(files, bh.datasetsHandler.Oversized()...)
+	files = append(files, bh.complianceHandler.Oversized()...)
+	return files
 }
 
-// Close closes all resources including the search manager
+// Close closes all resources including the search manager. It ends the
+// scratchpad sessions still open, so their promoted notes are saved, then
+// stops reloads in progress and waits for them to return so no index is
+// closed mid-write
 func (bh *BuddyHandlers) Close() error {
+	if bh.scratchpad != nil {
+		bh.scratchpad.EndAll(context.Background())
+	}
+	if bh.stopLoads != nil {
+		bh.stopLoads()
+	}
+	if bh.reloadsIdle != nil {
+		bh.mu.Lock()
+		for bh.reloadsInFlight > 0 {
+			bh.reloadsIdle.Wait()
+		}
+		bh.mu.Unlock()
+	}
+	if bh.indexes.done != nil {
+		<-bh.indexes.done
+	}
 	if bh.searchManager != nil {
 		return bh.searchManager.Close()
 	}
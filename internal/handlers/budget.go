@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/tokens"
+)
+
+// TokenBudgetArgs declares the max_tokens argument WithTokenBudget reads,
+// for embedding in the arguments of tools it wraps
+type TokenBudgetArgs struct {
+	MaxTokens int `arg:"max_tokens" desc:"Truncate the response to about this many tokens, by the server's estimate rather than an exact tokenizer count (optional)"`
+}
+
+// WithTokenBudget wraps a read tool so its text response is cut to the
+// caller's "max_tokens" argument, letting agents fit results into their
+// remaining context window. Responses without the argument are unchanged.
+func WithTokenBudget(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := handler(ctx, request)
+		if err != nil || result == nil {
+			return result, err
+		}
+
+		maxFloat, ok := request.GetArguments()["max_tokens"].(float64)
+		if !ok || maxFloat <= 0 {
+			return result, nil
+		}
+
+		applyTokenBudget(result, int(maxFloat))
+		return result, nil
+	}
+}
+
+// applyTokenBudget truncates the text content of a result to maxTokens,
+// reserving room for a note telling the caller how to see the rest
+func applyTokenBudget(result *mcp.CallToolResult, maxTokens int) {
+	remaining := maxTokens
+	for i, content := range result.Content {
+		text, ok := content.(mcp.TextContent)
+		if !ok {
+			continue
+		}
+
+		total := tokens.Count(text.Text)
+		if total <= remaining {
+			remaining -= total
+			continue
+		}
+
+		note := fmt.Sprintf("\n\n✂️ Truncated to %d of ~%d tokens; raise max_tokens or narrow the query to see more", maxTokens, total)
+		budget := remaining - tokens.Count(note)
+		if budget < 0 {
+			budget = 0
+		}
+
+		truncated, _ := tokens.Truncate(text.Text, budget)
+		text.Text = strings.TrimRight(truncated, "\n") + note
+		result.Content[i] = text
+		remaining = 0
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// requestKeyField is the _meta field a tool call's in-flight key is stored
+// under between the before-call hook and the middleware
+const requestKeyField = "buddy/requestKey"
+
+// RequestCanceller cancels the context of a running tool call when the
+// client sends notifications/cancelled for it, so handlers checking their
+// context stop early. The MCP library does not do this itself.
+type RequestCanceller struct {
+	inflight map[string]context.CancelFunc
+	mu       sync.Mutex
+}
+
+// NewRequestCanceller creates a canceller. Register its hooks, middleware
+// and notification handler with the MCP server before use.
+func NewRequestCanceller() *RequestCanceller {
+	return &RequestCanceller{inflight: make(map[string]context.CancelFunc)}
+}
+
+// requestKey identifies a request within the session behind ctx. Hooks
+// pass IDs as mcp.RequestId, notifications as decoded JSON values.
+func requestKey(ctx context.Context, id any) string {
+	requestID, ok := id.(mcp.RequestId)
+	if !ok {
+		requestID = mcp.NewRequestId(id)
+	}
+	key := requestID.String()
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		key = session.SessionID() + "/" + key
+	}
+	return key
+}
+
+// RegisterHooks records each tool call's request ID where the middleware
+// can find it, since handlers are not given the ID
+func (rc *RequestCanceller) RegisterHooks(hooks *server.Hooks) {
+	hooks.AddBeforeCallTool(func(ctx context.Context, id any, message *mcp.CallToolRequest) {
+		if message.Params.Meta == nil {
+			message.Params.Meta = &mcp.Meta{}
+		}
+		if message.Params.Meta.AdditionalFields == nil {
+			message.Params.Meta.AdditionalFields = make(map[string]any)
+		}
+		message.Params.Meta.AdditionalFields[requestKeyField] = requestKey(ctx, id)
+	})
+}
+
+// Attach handles cancellation notifications sent to mcpServer
+func (rc *RequestCanceller) Attach(mcpServer *server.MCPServer) {
+	mcpServer.AddNotificationHandler("notifications/cancelled", func(ctx context.Context, notification mcp.JSONRPCNotification) {
+		id, ok := notification.Params.AdditionalFields["requestId"]
+		if !ok {
+			return
+		}
+		rc.Cancel(requestKey(ctx, id))
+	})
+}
+
+// Middleware gives each tool call a context that Cancel can end
+func (rc *RequestCanceller) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if request.Params.Meta == nil {
+			return next(ctx, request)
+		}
+		key, _ := request.Params.Meta.AdditionalFields[requestKeyField].(string)
+		if key == "" {
+			return next(ctx, request)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		rc.mu.Lock()
+		rc.inflight[key] = cancel
+		rc.mu.Unlock()
+
+		defer func() {
+			rc.mu.Lock()
+			delete(rc.inflight, key)
+			rc.mu.Unlock()
+			cancel()
+		}()
+
+		return next(ctx, request)
+	}
+}
+
+// Cancel cancels the in-flight request with the given key, reporting
+// whether one was running
+func (rc *RequestCanceller) Cancel(key string) bool {
+	rc.mu.Lock()
+	cancel, ok := rc.inflight[key]
+	rc.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+	return ok
+}
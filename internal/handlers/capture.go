@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/slug"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// maxCaptureDiffLines caps how many diff lines are included per change
+const maxCaptureDiffLines = 40
+
+// CaptureSessionArgs are the arguments of buddy_capture_session
+type CaptureSessionArgs struct {
+	Feature  string   `arg:"feature" desc:"Capture history entries for this feature (optional)"`
+	EntryIDs []string `arg:"entry_ids" desc:"Capture exactly these history entry IDs (optional)"`
+	Since    string   `arg:"since" desc:"Capture entries at or after this time: RFC3339, YYYY-MM-DD, 'last 3 days', 'P1W' (optional)"`
+	Until    string   `arg:"until" desc:"Capture entries at or before this time, same formats as since (optional)"`
+	Title    string   `arg:"title" desc:"Draft title (default: 'How we implemented <feature>')"`
+	Category string   `arg:"category" desc:"Knowledge category (default: worked-examples)"`
+}
+
+// GetCaptureSessionToolHandler returns the tool handler that turns a
+// session's history entries into a "how we implemented X" knowledge draft
+func (bh *BuddyHandlers) GetCaptureSessionToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args CaptureSessionArgs) (*mcp.CallToolResult, error) {
+		feature := args.Feature
+
+		var entryIDs []string
+		for _, id := range args.EntryIDs {
+			if id != "" {
+				entryIDs = append(entryIDs, id)
+			}
+		}
+
+		since, until, err := parseTimeRange(args.Since, args.Until, bh.timeFormat.Location())
+		if err != nil {
+			return nil, err
+		}
+
+		if feature == "" && len(entryIDs) == 0 && since.IsZero() {
+			return nil, fmt.Errorf("feature, entry_ids or since is required to select the session")
+		}
+
+		entries := selectSessionEntries(bh.historyHandler.GetHistory(), feature, entryIDs)
+		entries = filterHistoryByTime(entries, since, until)
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("no history entries match the session selection")
+		}
+
+		title := args.Title
+		if title == "" {
+			subject := feature
+			if subject == "" {
+				subject = entries[0].Feature
+			}
+			title = fmt.Sprintf("How we implemented %s", subject)
+		}
+		category := args.Category
+		if category == "" {
+			category = "worked-examples"
+		}
+
+		overview := bh.summarize(ctx,
+			"Write a short overview paragraph of this coding session for a knowledge base article: what was implemented and the key decisions.",
+			sessionNarrative(entries), 3)
+
+		content := bh.renderSessionKnowledge(title, category, overview.Text, entries)
+		unlock := lockWrites(ctx)
+		filePath, err := bh.draftHandler.WriteDraft(ctx, "knowledge", title, content)
+		unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		result := fmt.Sprintf("📝 Worked example drafted from %d history entries\n\n", len(entries))
+		result += fmt.Sprintf("Title: %s\n", title)
+		result += fmt.Sprintf("Category: %s\n", category)
+		result += fmt.Sprintf("File: %s\n", filePath)
+		result += fmt.Sprintf("Overview: %s\n\n", overview.Source())
+		result += strings.Repeat("-", 40) + "\n"
+		result += content
+		result += strings.Repeat("-", 40) + "\n"
+		result += "\n💡 Review the draft, fill in the TODOs, then move it into .buddy/knowledge to activate it"
+
+		return mcp.NewToolResultText(result), nil
+	})
+}
+
+// selectSessionEntries picks history entries by ID or feature, oldest first
+// so the draft reads in the order the work happened
+func selectSessionEntries(history []models.HistoryEntry, feature string, entryIDs []string) []models.HistoryEntry {
+	wanted := make(map[string]bool, len(entryIDs))
+	for _, id := range entryIDs {
+		wanted[id] = true
+	}
+
+	var entries []models.HistoryEntry
+	for _, entry := range history {
+		if len(wanted) > 0 && !wanted[entry.ID] {
+			continue
+		}
+		if feature != "" && !strings.EqualFold(entry.Feature, feature) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries
+}
+
+// sessionNarrative describes a session's entries as prose to summarize
+func sessionNarrative(entries []models.HistoryEntry) string {
+	var sb strings.Builder
+	for _, entry := range entries {
+		sb.WriteString(strings.TrimSuffix(entry.Description, ".") + ".")
+		if entry.Reasoning != "" {
+			sb.WriteString(" " + entry.Reasoning)
+		}
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
+
+// renderSessionKnowledge produces a knowledge file describing the session's
+// steps, opening with the overview summary
+func (bh *BuddyHandlers) renderSessionKnowledge(title, category, overview string, entries []models.HistoryEntry) string {
+	features := make(map[string]bool)
+	var tags []string
+	for _, entry := range entries {
+		if entry.Feature != "" && !features[entry.Feature] {
+			features[entry.Feature] = true
+			if tag := slug.Make(entry.Feature, 0, ""); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	tags = append(tags, "worked-example")
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s\n", title))
+	sb.WriteString(fmt.Sprintf("Category: %s\n", category))
+	sb.WriteString(fmt.Sprintf("Tags: %s\n", strings.Join(tags, ", ")))
+	sb.WriteString("\n")
+
+	sb.WriteString("## Overview\n\n")
+	if overview != "" {
+		sb.WriteString(overview + "\n\n")
+	}
+	for _, entry := range entries {
+		sb.WriteString(fmt.Sprintf("- %s\n", entry.Description))
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString("## Steps\n")
+	files := make(map[string]string)
+	var fileOrder []string
+	for i, entry := range entries {
+		sb.WriteString(fmt.Sprintf("\n### %d. %s\n\n", i+1, entry.Description))
+		sb.WriteString(fmt.Sprintf("_%s_\n\n", bh.timeFormat.Format(entry.Timestamp)))
+		if entry.Reasoning != "" {
+			sb.WriteString(fmt.Sprintf("**Why:** %s\n\n", entry.Reasoning))
+		}
+
+		for _, change := range entry.Changes {
+			if _, seen := files[change.FilePath]; !seen {
+				fileOrder = append(fileOrder, change.FilePath)
+			}
+			files[change.FilePath] = change.ChangeType
+
+			sb.WriteString(fmt.Sprintf("- `%s` (%s)\n", change.FilePath, change.ChangeType))
+			if diff := renderChangeDiff(change); diff != "" {
+				sb.WriteString("\n```diff\n")
+				sb.WriteString(diff)
+				sb.WriteString("```\n")
+			}
+		}
+	}
+
+	if len(fileOrder) > 0 {
+		sb.WriteString("\n## Files Touched\n\n")
+		for _, filePath := range fileOrder {
+			sb.WriteString(fmt.Sprintf("- `%s` (%s)\n", filePath, files[filePath]))
+		}
+	}
+
+	sb.WriteString("\n## Lessons Learned\n\n")
+	sb.WriteString("TODO: note pitfalls, alternatives considered and follow-ups.\n")
+	return sb.String()
+}
+
+// renderChangeDiff shows a change's before/after content as -/+ lines,
+// capped so large rewrites don't swamp the draft
+func renderChangeDiff(change models.Change) string {
+	var lines []string
+	for _, side := range []struct {
+		prefix string
+		body   string
+	}{{"-", change.Before}, {"+", change.After}} {
+		if strings.TrimSpace(side.body) == "" {
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimRight(side.body, "\n"), "\n") {
+			lines = append(lines, side.prefix+" "+line)
+		}
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	omitted := 0
+	if len(lines) > maxCaptureDiffLines {
+		omitted = len(lines) - maxCaptureDiffLines
+		lines = lines[:maxCaptureDiffLines]
+	}
+
+	result := strings.Join(lines, "\n") + "\n"
+	if omitted > 0 {
+		result += fmt.Sprintf("... %d more lines\n", omitted)
+	}
+	return result
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/timeutil"
+)
+
+// maxChangeEvents caps the in-memory change log
+const maxChangeEvents = 5000
+
+// ChangeEvent records a buddy document being added, modified or removed
+type ChangeEvent struct {
+	Time     time.Time `json:"time"`
+	Change   string    `json:"change"` // added, modified, removed
+	Type     string    `json:"type"`   // rule, knowledge, todo, history, table, backup
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	FilePath string    `json:"file_path,omitempty"`
+}
+
+// trackedDocument is the last known state of a document
+type trackedDocument struct {
+	docType     string
+	id          string
+	title       string
+	filePath    string
+	fingerprint string
+}
+
+// ChangeLog diffs successive reloads to record which documents changed
+type ChangeLog struct {
+	documents map[string]trackedDocument
+	events    []ChangeEvent
+	mu        sync.RWMutex
+}
+
+// NewChangeLog creates an empty change log
+func NewChangeLog() *ChangeLog {
+	return &ChangeLog{
+		documents: make(map[string]trackedDocument),
+	}
+}
+
+// fingerprint hashes a document's JSON encoding
+func fingerprint(doc interface{}) string {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", md5.Sum(data))
+}
+
+// Record compares the current documents with the previous state and logs
+// the differences. On the first call every document is logged as added at
+// its own modification time, so clients asking for changes since before the
+// server started still see recently edited content.
+func (cl *ChangeLog) Record(current []trackedDocument, modTimes map[string]time.Time, now time.Time) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	initial := len(cl.documents) == 0 && len(cl.events) == 0
+	next := make(map[string]trackedDocument, len(current))
+
+	for _, doc := range current {
+		key := doc.docType + ":" + doc.id
+		next[key] = doc
+
+		previous, existed := cl.documents[key]
+		switch {
+		case !existed:
+			at := now
+			if initial {
+				at = modTimes[key]
+			}
+			cl.append(at, "added", doc)
+		case previous.fingerprint != doc.fingerprint:
+			cl.append(now, "modified", doc)
+		}
+	}
+
+	for key, doc := range cl.documents {
+		if _, ok := next[key]; !ok {
+			cl.append(now, "removed", doc)
+		}
+	}
+
+	cl.documents = next
+
+	if len(cl.events) > maxChangeEvents {
+		cl.events = cl.events[len(cl.events)-maxChangeEvents:]
+	}
+}
+
+// append adds an event; the caller must hold the lock
+func (cl *ChangeLog) append(at time.Time, change string, doc trackedDocument) {
+	cl.events = append(cl.events, ChangeEvent{
+		Time:     at.UTC(),
+		Change:   change,
+		Type:     doc.docType,
+		ID:       doc.id,
+		Title:    doc.title,
+		FilePath: doc.filePath,
+	})
+}
+
+// Since returns events after the given time, oldest first
+func (cl *ChangeLog) Since(since time.Time) []ChangeEvent {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	var events []ChangeEvent
+	for _, event := range cl.events {
+		if event.Time.After(since) {
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	return events
+}
+
+// recordChanges diffs a content snapshot into the change log
+func (bh *BuddyHandlers) recordChanges(snap *ContextSnapshot) {
+	var docs []trackedDocument
+	modTimes := make(map[string]time.Time)
+
+	add := func(docType, id, title, filePath string, modTime time.Time, doc interface{}) {
+		docs = append(docs, trackedDocument{
+			docType:     docType,
+			id:          id,
+			title:       title,
+			filePath:    filePath,
+			fingerprint: fingerprint(doc),
+		})
+		modTimes[docType+":"+id] = modTime
+	}
+
+	for _, rule := range snap.Rules {
+		add("rule", rule.ID, rule.Title, rule.FilePath, rule.UpdatedAt, rule)
+	}
+	for _, kb := range snap.Knowledge {
+		add("knowledge", kb.ID, kb.Title, kb.FilePath, kb.UpdatedAt, kb)
+	}
+	for _, todo := range snap.Todos {
+		// Todos are re-stamped on every load, so leave the time out of the fingerprint
+		todo.UpdatedAt = time.Time{}
+		add("todo", todo.ID, todo.Task, todo.FilePath, time.Now(), todo)
+	}
+	for _, entry := range snap.History {
+		add("history", entry.ID, entry.Description, entry.FilePath, entry.Timestamp, entry)
+	}
+	if dbInfo := snap.Database; dbInfo != nil {
+		for _, table := range dbInfo.Tables {
+			add("table", table.Name, table.Name, dbInfo.SchemaPath, dbInfo.UpdatedAt, table)
+		}
+	}
+	for _, backup := range snap.Backups {
+		add("backup", backup.ID, backup.OriginalPath, backup.BackupPath, backup.Timestamp, backup)
+	}
+
+	bh.changeLog.Record(docs, modTimes, time.Now())
+}
+
+// GetChangesResourceHandler returns the resource template handler for
+// buddy://changes?since=<time>
+func (bh *BuddyHandlers) GetChangesResourceHandler() server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		uri, err := url.Parse(request.Params.URI)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resource URI: %w", err)
+		}
+
+		var since time.Time
+		if value := uri.Query().Get("since"); value != "" {
+			since, err = timeutil.ParseTime(value, time.Now(), bh.timeFormat.Location())
+			if err != nil {
+				return nil, fmt.Errorf("invalid since: %w", err)
+			}
+		}
+
+		// The change log only grows when a snapshot is published, so the
+		// body stays valid until the next reload. Relative times like "1h"
+		// move with the clock, hence the age limit.
+		return bh.readCachedResource(request, time.Minute, func(snap *ContextSnapshot, now time.Time) (map[string]interface{}, error) {
+			events := bh.changeLog.Since(since)
+			if events == nil {
+				events = []ChangeEvent{}
+			}
+			return map[string]interface{}{
+				"since":   since.UTC(),
+				"now":     now.UTC(),
+				"changes": events,
+			}, nil
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeLog_Record(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(minute int) time.Time { return start.Add(time.Duration(minute) * time.Minute) }
+	doc := func(id, version string) trackedDocument {
+		return trackedDocument{docType: "rule", id: id, title: id, filePath: id + ".md", fingerprint: version}
+	}
+
+	// reload is one Record call: the documents loaded, at minute now
+	type reload struct {
+		docs []trackedDocument
+		now  int
+	}
+	tests := []struct {
+		name    string
+		reloads []reload
+		since   int
+		want    []string // change:id, oldest first
+	}{
+		{
+			name:    "first load is added at the files' own times",
+			reloads: []reload{{docs: []trackedDocument{doc("a", "1"), doc("b", "1")}, now: 60}},
+			since:   -1,
+			want:    []string{"added:b", "added:a"},
+		},
+		{
+			name: "later loads log additions, edits and removals",
+			reloads: []reload{
+				{docs: []trackedDocument{doc("a", "1"), doc("b", "1")}, now: 60},
+				{docs: []trackedDocument{doc("a", "2"), doc("c", "1")}, now: 61},
+			},
+			since: 30,
+			want:  []string{"modified:a", "added:c", "removed:b"},
+		},
+		{
+			name: "unchanged documents log nothing",
+			reloads: []reload{
+				{docs: []trackedDocument{doc("a", "1")}, now: 60},
+				{docs: []trackedDocument{doc("a", "1")}, now: 61},
+			},
+			since: -1,
+			want:  []string{"added:a"},
+		},
+		{
+			name: "since is exclusive",
+			reloads: []reload{
+				{docs: []trackedDocument{doc("a", "1")}, now: 60},
+				{docs: []trackedDocument{doc("a", "2")}, now: 61},
+				{docs: []trackedDocument{doc("a", "3")}, now: 62},
+			},
+			since: 61,
+			want:  []string{"modified:a"},
+		},
+		{
+			name:    "nothing after since",
+			reloads: []reload{{docs: []trackedDocument{doc("a", "1")}, now: 60}},
+			since:   60,
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := NewChangeLog()
+			modTimes := map[string]time.Time{"rule:a": at(1), "rule:b": at(0), "rule:c": at(2)}
+			for _, reload := range tt.reloads {
+				cl.Record(reload.docs, modTimes, at(reload.now))
+			}
+
+			var got []string
+			for _, event := range cl.Since(at(tt.since)) {
+				got = append(got, event.Change+":"+event.ID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChangeLog_KeepsTheNewestEvents(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	cl := NewChangeLog()
+	cl.Record([]trackedDocument{{docType: "rule", id: "a", fingerprint: "0"}}, nil, start)
+
+	// Every reload edits the rule, one event each
+	for i := 1; i <= maxChangeEvents+10; i++ {
+		cl.Record([]trackedDocument{{docType: "rule", id: "a", fingerprint: fmt.Sprint(i)}}, nil, start.Add(time.Duration(i)*time.Second))
+	}
+
+	events := cl.Since(time.Time{})
+	require.Len(t, events, maxChangeEvents)
+	assert.Equal(t, start.Add(11*time.Second), events[0].Time, "the oldest events are dropped")
+	assert.Equal(t, start.Add(time.Duration(maxChangeEvents+10)*time.Second), events[len(events)-1].Time)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// FileChange is the new content of one file in a changeset
+type FileChange struct {
+	Path    string `arg:"path,required"`
+	Content string `arg:"content,required"`
+}
+
+// ChangeResult reports how one file of a changeset was written
+type ChangeResult struct {
+	Path     string
+	Created  bool   // the file didn't exist before
+	BackupID string // backup of the previous content, empty for new files
+	Hash     string // SHA-256 of the content, verified on disk
+}
+
+// ChangesetResult reports an applied changeset
+type ChangesetResult struct {
+	SetID string // backup set holding the previous content of changed files
+	Files []ChangeResult
+}
+
+// ApplyChangeset writes a group of files. Existing files are first backed
+// up together as one backup set, so the changeset can be undone with
+// restore_set. Every write is read back and verified against the submitted
+// content; if any write fails, or ctx is cancelled midway, files already
+// written are rolled back.
+func (bh *BackupHandler) ApplyChangeset(ctx context.Context, changes []FileChange, changeContext, reasoning string) (*ChangesetResult, error) {
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("at least one change is required")
+	}
+
+	seen := make(map[string]bool)
+	var targets, existing []string
+	results := make([]ChangeResult, len(changes))
+	for i, change := range changes {
+		if change.Path == "" {
+			return nil, fmt.Errorf("change %d has no path", i+1)
+		}
+		path := bh.root.Abs(change.Path)
+		clean := filepath.Clean(path)
+		if seen[clean] {
+			return nil, fmt.Errorf("duplicate path in changeset: %s", change.Path)
+		}
+		seen[clean] = true
+
+		if !bh.allows(path) {
+			return nil, fmt.Errorf("file is excluded from backups by path configuration: %s", change.Path)
+		}
+		if err := bh.checkSandbox("write", path); err != nil {
+			return nil, err
+		}
+
+		info, err := bh.store.Stat(path)
+		switch {
+		case err == nil && info.IsDir:
+			return nil, fmt.Errorf("%s is a directory", change.Path)
+		case err == nil:
+			existing = append(existing, path)
+		case storage.IsNotExist(err):
+			results[i].Created = true
+		default:
+			return nil, fmt.Errorf("failed to check %s: %w", change.Path, err)
+		}
+
+		targets = append(targets, path)
+		results[i].Path = change.Path
+	}
+
+	result := &ChangesetResult{}
+	if len(existing) > 0 {
+		setID, backups, err := bh.CreateBackupSet(existing, changeContext, reasoning)
+		if err != nil {
+			return nil, fmt.Errorf("failed to back up files before applying changeset: %w", err)
+		}
+		result.SetID = setID
+
+		backupIDs := make(map[string]string, len(backups))
+		for _, backup := range backups {
+			backupIDs[backup.OriginalPath] = backup.ID
+		}
+		for i := range results {
+			results[i].BackupID = backupIDs[targets[i]]
+		}
+	}
+
+	err := bh.writeWithRollback("changeset", targets, func(i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hash, err := bh.writeVerified(targets[i], []byte(changes[i].Content), 0644)
+		if err != nil {
+			return err
+		}
+		results[i].Hash = hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Files = results
+	return result, nil
+}
+
+// ChangesetArgs are the arguments of buddy_apply_changeset
+type ChangesetArgs struct {
+	Changes   []FileChange `arg:"changes,required" desc:"Files to write, each an object with path and the full new content"`
+	Context   string       `arg:"context,required" desc:"Context of the change, stored with the backups"`
+	Reasoning string       `arg:"reasoning,required" desc:"Reasoning for the change, stored with the backups"`
+}
+
+// GetChangesetToolHandler returns the tool handler that applies changesets
+func (bh *BackupHandler) GetChangesetToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args ChangesetArgs) (*mcp.CallToolResult, error) {
+		if len(args.Changes) == 0 {
+			return nil, fmt.Errorf("changes is required")
+		}
+
+		applied, err := bh.ApplyChangeset(ctx, args.Changes, args.Context, args.Reasoning)
+		if err != nil {
+			return nil, err
+		}
+
+		result := fmt.Sprintf("✅ Changeset applied: %d files written and verified\n\n", len(applied.Files))
+		for _, file := range applied.Files {
+			status := "modified, backup " + file.BackupID
+			if file.Created {
+				status = "created"
+			}
+			result += fmt.Sprintf("- %s (%s)\n  sha256: %s ✔ verified\n", file.Path, status, file.Hash)
+		}
+
+		if applied.SetID != "" {
+			result += fmt.Sprintf("\nBackup set: %s\n", applied.SetID)
+			result += "💡 To undo the changeset, use buddy_backup action 'restore_set' with the set ID"
+			if created := countCreated(applied.Files); created > 0 {
+				result += fmt.Sprintf(" and delete the %d created files", created)
+			}
+		} else {
+			result += "\nNo existing files were changed, so no backup was needed"
+		}
+
+		return mcp.NewToolResultText(strings.TrimRight(result, "\n")), nil
+	})
+}
+
+// countCreated counts the files a changeset created
+func countCreated(files []ChangeResult) int {
+	count := 0
+	for _, file := range files {
+		if file.Created {
+			count++
+		}
+	}
+	return count
+}
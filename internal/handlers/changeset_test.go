@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/workspace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// faultyStore is local storage that fails writes to one path and returns
+// altered content when another is read
+type faultyStore struct {
+	storage.Storage
+	failWrite string
+	corrupt   string
+}
+
+func (f *faultyStore) Write(path string, data []byte) error {
+	if path == f.failWrite {
+		return errors.New("disk full")
+	}
+	return f.Storage.Write(path, data)
+}
+
+func (f *faultyStore) Read(path string) ([]byte, error) {
+	content, err := f.Storage.Read(path)
+	if err == nil && path == f.corrupt {
+		content = append(content, '!')
+	}
+	return content, err
+}
+
+// newTestBackupHandler returns a backup handler for a fresh project
+// directory, which it returns too
+func newTestBackupHandler(t *testing.T) (*BackupHandler, string) {
+	t.Helper()
+	project := t.TempDir()
+	searchManager, err := search.NewSearchManagerIn(filepath.Join(t.TempDir(), "indexes"))
+	require.NoError(t, err)
+	t.Cleanup(func() { searchManager.Close() })
+
+	bh := NewBackupHandler(filepath.Join(project, ".buddy", "backups"), searchManager)
+	bh.root, err = workspace.NewRoot(project)
+	require.NoError(t, err)
+	bh.safety = BuddySafetyStore(filepath.Join(project, ".buddy"), bh.store)
+	return bh, project
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, string(content))
+}
+
+func TestApplyChangeset_CreatedAndModified(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	writeTestFile(t, filepath.Join(project, "main.go"), "package main\n")
+
+	result, err := bh.ApplyChangeset(context.Background(), []FileChange{
+		{Path: "main.go", Content: "package main\n\nfunc main() {}\n"},
+		{Path: "internal/app/app.go", Content: "package app\n"},
+	}, "feature", "test")
+	require.NoError(t, err)
+	require.Len(t, result.Files, 2)
+
+	modified, created := result.Files[0], result.Files[1]
+	assert.Equal(t, "main.go", modified.Path)
+	assert.False(t, modified.Created)
+	assert.NotEmpty(t, modified.BackupID)
+	assert.Equal(t, "internal/app/app.go", created.Path)
+	assert.True(t, created.Created)
+	assert.Empty(t, created.BackupID)
+
+	sum := sha256.Sum256([]byte("package app\n"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), created.Hash)
+	assertFileContent(t, filepath.Join(project, "main.go"), "package main\n\nfunc main() {}\n")
+	assertFileContent(t, filepath.Join(project, "internal", "app", "app.go"), "package app\n")
+
+	// Only new files: nothing to back up
+	result, err = bh.ApplyChangeset(context.Background(), []FileChange{{Path: "README.md", Content: "# App\n"}}, "docs", "test")
+	require.NoError(t, err)
+	assert.Empty(t, result.SetID)
+}
+
+func TestApplyChangeset_BackupSetPerFile(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	writeTestFile(t, filepath.Join(project, "a.go"), "package a\n")
+	writeTestFile(t, filepath.Join(project, "b.go"), "package b\n")
+
+	result, err := bh.ApplyChangeset(context.Background(), []FileChange{
+		{Path: "a.go", Content: "package a // changed\n"},
+		{Path: "b.go", Content: "package b // changed\n"},
+	}, "rename", "test")
+	require.NoError(t, err)
+	require.NotEmpty(t, result.SetID)
+	assert.NotEqual(t, result.Files[0].BackupID, result.Files[1].BackupID)
+
+	for i, original := range []string{"package a\n", "package b\n"} {
+		backup, ok := bh.GetBackup(result.Files[i].BackupID)
+		require.True(t, ok, result.Files[i].Path)
+		assert.Equal(t, result.SetID, backup.SetID)
+		assert.Equal(t, filepath.Join(project, result.Files[i].Path), backup.OriginalPath)
+		assertFileContent(t, backup.BackupPath, original)
+	}
+}
+
+func TestApplyChangeset_RollsBackWhenAWriteFails(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	writeTestFile(t, filepath.Join(project, "a.go"), "package a\n")
+	bh.store = &faultyStore{Storage: bh.store, failWrite: filepath.Join(project, "c.go")}
+
+	_, err := bh.ApplyChangeset(context.Background(), []FileChange{
+		{Path: "a.go", Content: "package a // changed\n"},
+		{Path: "b.go", Content: "package b\n"},
+		{Path: "c.go", Content: "package c\n"},
+	}, "feature", "test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "changeset rolled back")
+
+	assertFileContent(t, filepath.Join(project, "a.go"), "package a\n")
+	assert.NoFileExists(t, filepath.Join(project, "b.go"))
+	assert.NoFileExists(t, filepath.Join(project, "c.go"))
+}
+
+func TestApplyChangeset_StopsWhenCancelled(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	writeTestFile(t, filepath.Join(project, "a.go"), "package a\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := bh.ApplyChangeset(ctx, []FileChange{
+		{Path: "a.go", Content: "package a // changed\n"},
+		{Path: "b.go", Content: "package b\n"},
+	}, "feature", "test")
+	require.ErrorIs(t, err, context.Canceled)
+
+	assertFileContent(t, filepath.Join(project, "a.go"), "package a\n")
+	assert.NoFileExists(t, filepath.Join(project, "b.go"))
+}
+
+func TestWriteVerified_DetectsMismatch(t *testing.T) {
+	bh, project := newTestBackupHandler(t)
+	path := filepath.Join(project, "a.go")
+
+	hash, err := bh.writeVerified(path, []byte("package a\n"), 0644)
+	require.NoError(t, err)
+	sum := sha256.Sum256([]byte("package a\n"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), hash)
+
+	bh.store = &faultyStore{Storage: bh.store, corrupt: path}
+	_, err = bh.writeVerified(path, []byte("package a\n"), 0644)
+	assert.ErrorContains(t, err, "verification failed")
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultChurnWindow is the window in which repeated rule edits are counted
+	defaultChurnWindow = 10 * time.Minute
+	// defaultChurnThreshold is the number of edits within the window that raises a warning
+	defaultChurnThreshold = 3
+)
+
+// ChurnWarning reports a rule file that changed too often within the window
+type ChurnWarning struct {
+	FilePath string
+	Changes  int
+	Window   time.Duration
+	Last     time.Time
+}
+
+// ChurnTracker records how often files are modified so that rapid
+// back-and-forth edits (often an agent and a human fighting over a rule)
+// can be surfaced
+type ChurnTracker struct {
+	window    time.Duration
+	threshold int
+	lastSeen  map[string]time.Time
+	changes   map[string][]time.Time
+	mu        sync.Mutex
+}
+
+// NewChurnTracker creates a tracker with the given window and threshold.
+// Non-positive values fall back to the defaults.
+func NewChurnTracker(window time.Duration, threshold int) *ChurnTracker {
+	if window <= 0 {
+		window = defaultChurnWindow
+	}
+	if threshold <= 0 {
+		threshold = defaultChurnThreshold
+	}
+
+	return &ChurnTracker{
+		window:    window,
+		threshold: threshold,
+		lastSeen:  make(map[string]time.Time),
+		changes:   make(map[string][]time.Time),
+	}
+}
+
+// Configure changes the window and threshold, keeping the changes seen so
+// far. Non-positive values fall back to the defaults.
+func (ct *ChurnTracker) Configure(window time.Duration, threshold int) {
+	if window <= 0 {
+		window = defaultChurnWindow
+	}
+	if threshold <= 0 {
+		threshold = defaultChurnThreshold
+	}
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.window = window
+	ct.threshold = threshold
+}
+
+// Limits returns the window and threshold
+func (ct *ChurnTracker) Limits() (time.Duration, int) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return ct.window, ct.threshold
+}
+
+// Observe records the current modification time of a file. It returns true
+// when this observation pushes the file over the churn threshold.
+func (ct *ChurnTracker) Observe(filePath string, modTime time.Time) bool {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	previous, seen := ct.lastSeen[filePath]
+	ct.lastSeen[filePath] = modTime
+
+	// The first sighting is the initial load, not a change
+	if !seen || !modTime.After(previous) {
+		return false
+	}
+
+	recent := ct.prune(append(ct.changes[filePath], modTime), modTime)
+	ct.changes[filePath] = recent
+
+	return len(recent) == ct.threshold
+}
+
+// Warnings returns files whose change count within the window, measured
+// back from now, meets the threshold
+func (ct *ChurnTracker) Warnings(now time.Time) []ChurnWarning {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	var warnings []ChurnWarning
+	for filePath, times := range ct.changes {
+		recent := ct.prune(times, now)
+		ct.changes[filePath] = recent
+		if len(recent) >= ct.threshold {
+			warnings = append(warnings, ChurnWarning{
+				FilePath: filePath,
+				Changes:  len(recent),
+				Window:   ct.window,
+				Last:     recent[len(recent)-1],
+			})
+		}
+	}
+
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Changes > warnings[j].Changes
+	})
+
+	return warnings
+}
+
+// prune drops change times that fall outside the window ending at now
+func (ct *ChurnTracker) prune(times []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-ct.window)
+	var recent []time.Time
+	for _, t := range times {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChurnTracker_Observe(t *testing.T) {
+	tests := []struct {
+		name    string
+		minutes []int // modification times, in minutes from the start
+		want    []bool
+	}{
+		{"first sighting is the initial load", []int{0}, []bool{false}},
+		{"third change reaches the threshold", []int{0, 1, 2, 3}, []bool{false, false, false, true}},
+		{"only the crossing is reported", []int{0, 1, 2, 3, 4}, []bool{false, false, false, true, false}},
+		{"unchanged or older times aren't changes", []int{0, 1, 1, 0, 2}, []bool{false, false, false, false, false}},
+		{"changes roll out of the window", []int{0, 1, 2, 12, 13}, []bool{false, false, false, false, false}},
+		{"the count restarts after a quiet spell", []int{0, 1, 2, 12, 13, 14}, []bool{false, false, false, false, false, true}},
+	}
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct := NewChurnTracker(10*time.Minute, 3)
+			var got []bool
+			for _, minute := range tt.minutes {
+				got = append(got, ct.Observe("rules/style.md", start.Add(time.Duration(minute)*time.Minute)))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChurnTracker_Warnings(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(minute int) time.Time { return start.Add(time.Duration(minute) * time.Minute) }
+
+	ct := NewChurnTracker(10*time.Minute, 2)
+	for _, minute := range []int{0, 1, 2, 3} {
+		ct.Observe("rules/style.md", at(minute))
+	}
+	for _, minute := range []int{0, 4, 5} {
+		ct.Observe("rules/api.md", at(minute))
+	}
+	ct.Observe("rules/calm.md", at(0))
+	ct.Observe("rules/calm.md", at(6))
+
+	tests := []struct {
+		name string
+		now  int
+		want []ChurnWarning
+	}{
+		{"most changes first", 6, []ChurnWarning{
+			{FilePath: "rules/style.md", Changes: 3, Window: 10 * time.Minute, Last: at(3)},
+			{FilePath: "rules/api.md", Changes: 2, Window: 10 * time.Minute, Last: at(5)},
+		}},
+		{"older changes roll out", 12, []ChurnWarning{
+			{FilePath: "rules/api.md", Changes: 2, Window: 10 * time.Minute, Last: at(5)},
+		}},
+		{"all quiet", 30, nil},
+	}
+	// Each call prunes what it no longer counts, so the cases run in order
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ct.Warnings(at(tt.now)), tt.name)
+	}
+}
+
+func TestChurnTracker_Configure(t *testing.T) {
+	ct := NewChurnTracker(0, -1)
+	window, threshold := ct.Limits()
+	assert.Equal(t, defaultChurnWindow, window)
+	assert.Equal(t, defaultChurnThreshold, threshold)
+
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	ct.Observe("rules/style.md", start)
+	ct.Observe("rules/style.md", start.Add(time.Minute))
+
+	// A lower threshold counts the changes already seen
+	ct.Configure(time.Hour, 1)
+	window, threshold = ct.Limits()
+	assert.Equal(t, time.Hour, window)
+	assert.Equal(t, 1, threshold)
+	assert.Len(t, ct.Warnings(start.Add(time.Minute)), 1)
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+const (
+	// defaultClaimTTL is how long a claim lasts unless renewed
+	defaultClaimTTL = 30 * time.Minute
+	// maxClaimTTL bounds claims so a crashed agent can't hold work forever
+	maxClaimTTL = 24 * time.Hour
+)
+
+// Claim kinds
+const (
+	ClaimTodo = "todo"
+	ClaimFile = "file"
+)
+
+// Claim marks a todo or file as being worked on by one agent until it is
+// released or expires. Claims are advisory: they tell cooperating agents
+// sharing a server what is taken, and nothing enforces them.
+type Claim struct {
+	Kind      string    `json:"kind"`   // todo, file
+	Target    string    `json:"target"` // todo ID or slash-separated file path
+	Owner     string    `json:"owner"`
+	Note      string    `json:"note,omitempty"`
+	ClaimedAt time.Time `json:"claimed_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ClaimConflictError is returned when another agent holds a claim
+type ClaimConflictError struct {
+	Claim Claim
+}
+
+func (e *ClaimConflictError) Error() string {
+	return fmt.Sprintf("%s %s is claimed by %s until %s", e.Claim.Kind, e.Claim.Target, e.Claim.Owner, e.Claim.ExpiresAt.Format(time.RFC3339))
+}
+
+// ClaimRegistry holds the claims of all agents connected to this server.
+// Claims live in memory only and end with the server.
+type ClaimRegistry struct {
+	claims map[string]Claim // kind:target -> claim
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+// NewClaimRegistry creates an empty claim registry
+func NewClaimRegistry() *ClaimRegistry {
+	return &ClaimRegistry{
+		claims: make(map[string]Claim),
+		now:    time.Now,
+	}
+}
+
+// claimKey normalizes a claim target so equivalent file paths collide
+func claimKey(kind, target string) string {
+	if kind == ClaimFile {
+		target = filepath.ToSlash(filepath.Clean(target))
+	}
+	return kind + ":" + target
+}
+
+// prune drops expired claims; callers hold mu
+func (cr *ClaimRegistry) prune() {
+	now := cr.now()
+	for key, claim := range cr.claims {
+		if !now.Before(claim.ExpiresAt) {
+			delete(cr.claims, key)
+		}
+	}
+}
+
+// Acquire claims targets for owner, all or none. Claims the owner already
+// holds are renewed.
+func (cr *ClaimRegistry) Acquire(kind string, targets []string, owner string, ttl time.Duration, note string) ([]Claim, error) {
+	if ttl <= 0 {
+		ttl = defaultClaimTTL
+	}
+	if ttl > maxClaimTTL {
+		ttl = maxClaimTTL
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.prune()
+
+	for _, target := range targets {
+		if existing, ok := cr.claims[claimKey(kind, target)]; ok && existing.Owner != owner {
+			return nil, &ClaimConflictError{Claim: existing}
+		}
+	}
+
+	now := cr.now().UTC()
+	var claims []Claim
+	for _, target := range targets {
+		key := claimKey(kind, target)
+		claim := Claim{
+			Kind:      kind,
+			Target:    strings.TrimPrefix(key, kind+":"),
+			Owner:     owner,
+			Note:      note,
+			ClaimedAt: now,
+			ExpiresAt: now.Add(ttl),
+		}
+		// Renewals keep their original claim time
+		if existing, ok := cr.claims[key]; ok {
+			claim.ClaimedAt = existing.ClaimedAt
+			if note == "" {
+				claim.Note = existing.Note
+			}
+		}
+		cr.claims[key] = claim
+		claims = append(claims, claim)
+	}
+
+	return claims, nil
+}
+
+// Release drops owner's claims on targets. With force, claims held by
+// other agents are released too, e.g. to clean up after a crashed agent.
+// It returns the targets that were released.
+func (cr *ClaimRegistry) Release(kind string, targets []string, owner string, force bool) ([]string, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.prune()
+
+	for _, target := range targets {
+		if existing, ok := cr.claims[claimKey(kind, target)]; ok && existing.Owner != owner && !force {
+			return nil, &ClaimConflictError{Claim: existing}
+		}
+	}
+
+	var released []string
+	for _, target := range targets {
+		key := claimKey(kind, target)
+		if _, ok := cr.claims[key]; ok {
+			delete(cr.claims, key)
+			released = append(released, strings.TrimPrefix(key, kind+":"))
+		}
+	}
+	return released, nil
+}
+
+// Get returns the live claim on a target, if any
+func (cr *ClaimRegistry) Get(kind, target string) (Claim, bool) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.prune()
+
+	claim, ok := cr.claims[claimKey(kind, target)]
+	return claim, ok
+}
+
+// List returns the live claims of a kind, or of all kinds when kind is
+// empty, ordered by target
+func (cr *ClaimRegistry) List(kind string) []Claim {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.prune()
+
+	var claims []Claim
+	for _, claim := range cr.claims {
+		if kind == "" || claim.Kind == kind {
+			claims = append(claims, claim)
+		}
+	}
+	sort.Slice(claims, func(i, j int) bool {
+		if claims[i].Kind != claims[j].Kind {
+			return claims[i].Kind < claims[j].Kind
+		}
+		return claims[i].Target < claims[j].Target
+	})
+	return claims
+}
+
+// claimOwner identifies the calling agent: the agent argument if given,
+// otherwise the MCP session, so each connection is its own claimer
+func claimOwner(ctx context.Context, agent string) (string, error) {
+	if agent = strings.TrimSpace(agent); agent != "" {
+		return agent, nil
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil && session.SessionID() != "" {
+		return "session-" + session.SessionID(), nil
+	}
+	return "", fmt.Errorf("agent is required to identify who holds the claim")
+}
+
+// claimTTL converts the ttl_minutes argument, defaulting when it is left out
+func claimTTL(minutes float64) time.Duration {
+	if minutes > 0 {
+		return time.Duration(minutes * float64(time.Minute))
+	}
+	return defaultClaimTTL
+}
+
+// describeClaim renders who holds a claim and for how much longer
+func describeClaim(claim Claim, now time.Time) string {
+	result := fmt.Sprintf("claimed by %s, expires in %s", claim.Owner, claim.ExpiresAt.Sub(now).Round(time.Minute))
+	if claim.Note != "" {
+		result += fmt.Sprintf(" (%s)", claim.Note)
+	}
+	return result
+}
+
+// LockArgs are the arguments of buddy_lock
+type LockArgs struct {
+	Action     string   `arg:"action" enum:"list,acquire,release" desc:"Action to perform: list (default), acquire or release"`
+	Paths      []string `arg:"paths" desc:"File paths to lock or unlock; acquire takes all or none (required for acquire and release)"`
+	Agent      string   `arg:"agent" desc:"Who holds the lock; defaults to the MCP session (optional)"`
+	TTLMinutes float64  `arg:"ttl_minutes" desc:"How long the lock lasts before it expires (default: 30, max: 1440)"`
+	Note       string   `arg:"note" desc:"What the lock holder is doing (optional for acquire)"`
+	Force      bool     `arg:"force" desc:"Release locks held by another agent (optional for release)"`
+}
+
+// GetLockToolHandler returns the tool handler for advisory file locks
+func (bh *BuddyHandlers) GetLockToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args LockArgs) (*mcp.CallToolResult, error) {
+		action := args.Action
+
+		var paths []string
+		for _, p := range args.Paths {
+			if p != "" {
+				paths = append(paths, p)
+			}
+		}
+
+		switch action {
+		case "", "list":
+			return mcp.NewToolResultText(formatClaims(bh.claims.List(""), time.Now())), nil
+
+		case "acquire", "release":
+			if len(paths) == 0 {
+				return nil, fmt.Errorf("paths is required for %s", action)
+			}
+			owner, err := claimOwner(ctx, args.Agent)
+			if err != nil {
+				return nil, err
+			}
+
+			if action == "acquire" {
+				claims, err := bh.claims.Acquire(ClaimFile, paths, owner, claimTTL(args.TTLMinutes), args.Note)
+				if err != nil {
+					return nil, err
+				}
+				result := fmt.Sprintf("🔒 Locked %d files for %s until %s\n", len(claims), owner, claims[0].ExpiresAt.Format(time.RFC3339))
+				for _, claim := range claims {
+					result += fmt.Sprintf("- %s\n", claim.Target)
+				}
+				return mcp.NewToolResultText(result), nil
+			}
+
+			released, err := bh.claims.Release(ClaimFile, paths, owner, args.Force)
+			if err != nil {
+				return nil, err
+			}
+			if len(released) == 0 {
+				return mcp.NewToolResultText("No locks were held on these files"), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("🔓 Released %d locks: %s", len(released), strings.Join(released, ", "))), nil
+
+		default:
+			return nil, fmt.Errorf("unknown action: %s", action)
+		}
+	})
+}
+
+// formatClaims lists live claims grouped by kind
+func formatClaims(claims []Claim, now time.Time) string {
+	if len(claims) == 0 {
+		return "No active claims or locks"
+	}
+
+	result := fmt.Sprintf("🔒 Active claims: %d\n", len(claims))
+	kind := ""
+	for _, claim := range claims {
+		if claim.Kind != kind {
+			kind = claim.Kind
+			result += fmt.Sprintf("\n%s:\n", strings.ToUpper(kind))
+		}
+		result += fmt.Sprintf("- %s: %s\n", claim.Target, describeClaim(claim, now))
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClaims returns a claim registry whose clock the returned function advances
+func testClaims() (*ClaimRegistry, func(time.Duration)) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cr := NewClaimRegistry()
+	cr.now = func() time.Time { return now }
+	return cr, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestClaimRegistry_Expiry(t *testing.T) {
+	cr, advance := testClaims()
+	_, err := cr.Acquire(ClaimTodo, []string{"todo-1"}, "alice", 10*time.Minute, "")
+	require.NoError(t, err)
+
+	advance(9 * time.Minute)
+	_, ok := cr.Get(ClaimTodo, "todo-1")
+	assert.True(t, ok)
+
+	// Expired claims are gone and free for others
+	advance(time.Minute)
+	_, ok = cr.Get(ClaimTodo, "todo-1")
+	assert.False(t, ok)
+	_, err = cr.Acquire(ClaimTodo, []string{"todo-1"}, "bob", 0, "")
+	assert.NoError(t, err)
+}
+
+func TestClaimRegistry_TTLBounds(t *testing.T) {
+	cr, _ := testClaims()
+	claims, err := cr.Acquire(ClaimTodo, []string{"a"}, "alice", 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, defaultClaimTTL, claims[0].ExpiresAt.Sub(claims[0].ClaimedAt))
+
+	claims, err = cr.Acquire(ClaimTodo, []string{"b"}, "alice", 48*time.Hour, "")
+	require.NoError(t, err)
+	assert.Equal(t, maxClaimTTL, claims[0].ExpiresAt.Sub(claims[0].ClaimedAt))
+}
+
+func TestClaimRegistry_RenewalKeepsClaimedAt(t *testing.T) {
+	cr, advance := testClaims()
+	first, err := cr.Acquire(ClaimFile, []string{"src/app.go"}, "alice", 10*time.Minute, "refactor")
+	require.NoError(t, err)
+
+	advance(5 * time.Minute)
+	renewed, err := cr.Acquire(ClaimFile, []string{"./src/app.go"}, "alice", 10*time.Minute, "")
+	require.NoError(t, err)
+	require.Len(t, renewed, 1)
+	assert.Equal(t, first[0].ClaimedAt, renewed[0].ClaimedAt)
+	assert.Equal(t, first[0].ExpiresAt.Add(5*time.Minute), renewed[0].ExpiresAt)
+	assert.Equal(t, "refactor", renewed[0].Note)
+	assert.Equal(t, "src/app.go", renewed[0].Target)
+}
+
+func TestClaimRegistry_AcquireIsAllOrNothing(t *testing.T) {
+	cr, _ := testClaims()
+	_, err := cr.Acquire(ClaimTodo, []string{"b"}, "bob", 0, "")
+	require.NoError(t, err)
+
+	_, err = cr.Acquire(ClaimTodo, []string{"a", "b", "c"}, "alice", 0, "")
+	var conflict *ClaimConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "bob", conflict.Claim.Owner)
+	assert.Equal(t, "b", conflict.Claim.Target)
+
+	// Neither a nor c was claimed
+	claims := cr.List(ClaimTodo)
+	require.Len(t, claims, 1)
+	assert.Equal(t, "b", claims[0].Target)
+}
+
+func TestClaimRegistry_ReleaseConflicts(t *testing.T) {
+	cr, _ := testClaims()
+	_, err := cr.Acquire(ClaimTodo, []string{"a"}, "alice", 0, "")
+	require.NoError(t, err)
+	_, err = cr.Acquire(ClaimTodo, []string{"b"}, "bob", 0, "")
+	require.NoError(t, err)
+
+	// Another agent's claim blocks the whole release
+	_, err = cr.Release(ClaimTodo, []string{"a", "b"}, "alice", false)
+	var conflict *ClaimConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "bob", conflict.Claim.Owner)
+	assert.Len(t, cr.List(""), 2)
+
+	released, err := cr.Release(ClaimTodo, []string{"a", "unclaimed"}, "alice", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, released)
+
+	released, err = cr.Release(ClaimTodo, []string{"b"}, "alice", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, released)
+	assert.Empty(t, cr.List(""))
+}
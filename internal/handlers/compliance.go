@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// Compliance verdicts, from most to least permissive
+const (
+	VerdictAllowed = "allowed"
+	VerdictUnknown = "unknown"
+	VerdictReview  = "review"
+	VerdictDenied  = "denied"
+)
+
+// verdictRank orders verdicts for combining license expressions: "AND"
+// takes the strictest part and "OR" the most permissive alternative
+var verdictRank = map[string]int{
+	VerdictAllowed: 0,
+	VerdictUnknown: 1,
+	VerdictReview:  2,
+	VerdictDenied:  3,
+}
+
+// ComplianceHandler manages license and data-handling policies
+type ComplianceHandler struct {
+	*DocumentHandler[models.CompliancePolicy]
+}
+
+// NewComplianceHandler creates a new compliance handler
+func NewComplianceHandler(path string, searchManager *search.SearchManager) *ComplianceHandler {
+	ch := &ComplianceHandler{}
+	ch.DocumentHandler = NewDocumentHandler(path, searchManager, DocumentSpec[models.CompliancePolicy]{
+		IndexType:  search.IndexTypeCompliance,
+		Extensions: []string{".md"},
+		Truncate:   truncateText,
+		Recursive:  true,
+		Parse:      ch.parsePolicyFile,
+		ID:         func(policy models.CompliancePolicy) string { return policy.ID },
+		Index:      func(policy models.CompliancePolicy) interface{} { return search.FromCompliancePolicy(policy) },
+	})
+	return ch
+}
+
+// parsePolicyFile parses a single policy file. Metadata lines follow the
+// title until the first blank line:
+//
+//	# License Policy
+//	Category: licenses
+//	Allowed: MIT, Apache-2.0
+//	Review: MPL-2.0
+//	Denied: GPL-3.0, AGPL-3.0
+//	Denied Packages: left-pad, github.com/acme/*
+//	Default: review
+func (ch *ComplianceHandler) parsePolicyFile(file storage.FileInfo, content []byte) ([]models.CompliancePolicy, error) {
+	policy := models.CompliancePolicy{
+		ID:        documentID(ch.path, file.Path),
+		FilePath:  file.Path,
+		UpdatedAt: file.ModTime,
+	}
+
+	lines := strings.Split(string(content), "\n")
+	contentStart := len(lines)
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "# ") {
+			policy.Title = strings.TrimPrefix(line, "# ")
+			continue
+		}
+		if line == "" && i > 0 {
+			contentStart = i + 1
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "category":
+			policy.Category = value
+		case "allowed":
+			policy.Allowed = splitTags(value)
+		case "review":
+			policy.Review = splitTags(value)
+		case "denied":
+			policy.Denied = splitTags(value)
+		case "denied packages":
+			policy.DeniedPackages = splitTags(value)
+		case "default":
+			policy.Default = strings.ToLower(value)
+		}
+	}
+
+	switch policy.Default {
+	case "", VerdictAllowed, VerdictReview, VerdictDenied:
+	case "allow":
+		policy.Default = VerdictAllowed
+	case "deny":
+		policy.Default = VerdictDenied
+	default:
+		return nil, fmt.Errorf("invalid default verdict %q (expected allow, review or deny)", policy.Default)
+	}
+
+	if policy.Category == "" {
+		policy.Category = "data"
+		if governsLicenses(policy) {
+			policy.Category = "licenses"
+		}
+	}
+
+	if contentStart < len(lines) {
+		policy.Content = strings.Join(lines[contentStart:], "\n")
+	}
+
+	return []models.CompliancePolicy{policy}, nil
+}
+
+// ComplianceResult is the outcome of checking a dependency against the policies
+type ComplianceResult struct {
+	Verdict string
+	Reasons []string
+}
+
+// Check evaluates a dependency name and SPDX license expression against
+// every policy. Denied packages always win; otherwise the license verdict
+// applies, with "AND" requiring every license and "OR" needing any one.
+func (ch *ComplianceHandler) Check(dependency, license string) ComplianceResult {
+	policies := ch.Documents()
+	result := ComplianceResult{Verdict: VerdictUnknown}
+
+	if dependency != "" {
+		for _, policy := range policies {
+			for _, pattern := range policy.DeniedPackages {
+				if matchPackage(pattern, dependency) {
+					result.Verdict = VerdictDenied
+					result.Reasons = append(result.Reasons, fmt.Sprintf("%s is denied by %q (matches %s)", dependency, policy.Title, pattern))
+				}
+			}
+		}
+		if result.Verdict == VerdictDenied {
+			return result
+		}
+	}
+
+	if license == "" {
+		result.Reasons = append(result.Reasons, "No license given; pass the dependency's SPDX license to check it")
+		return result
+	}
+
+	hasLicensePolicy := false
+	for _, policy := range policies {
+		if governsLicenses(policy) {
+			hasLicensePolicy = true
+			break
+		}
+	}
+	if !hasLicensePolicy {
+		result.Reasons = append(result.Reasons, "No license policy defined in .buddy/compliance/")
+		return result
+	}
+
+	// Evaluate "A OR B" as the most permissive alternative, and "A AND B"
+	// within an alternative as its strictest part
+	bestRank := -1
+	var bestReasons []string
+	for _, alternative := range splitLicenseExpression(license, " or ") {
+		altVerdict := VerdictAllowed
+		var altReasons []string
+		for _, part := range splitLicenseExpression(alternative, " and ") {
+			verdict, reasons := licenseVerdict(policies, part)
+			altReasons = append(altReasons, reasons...)
+			if verdictRank[verdict] > verdictRank[altVerdict] {
+				altVerdict = verdict
+			}
+		}
+		if bestRank < 0 || verdictRank[altVerdict] < bestRank {
+			bestRank = verdictRank[altVerdict]
+			result.Verdict = altVerdict
+			bestReasons = altReasons
+		}
+	}
+	result.Reasons = append(result.Reasons, bestReasons...)
+
+	return result
+}
+
+// governsLicenses reports whether a policy says anything about licenses
+func governsLicenses(policy models.CompliancePolicy) bool {
+	return len(policy.Allowed) > 0 || len(policy.Review) > 0 || len(policy.Denied) > 0 || policy.Default != ""
+}
+
+// licenseVerdict checks a single license against every policy; the
+// strictest listing wins, and policy defaults apply only when no policy lists it
+func licenseVerdict(policies []models.CompliancePolicy, license string) (string, []string) {
+	normalized := normalizeLicense(license)
+
+	listed := ""
+	var reasons []string
+	defaultVerdict := ""
+	var defaultReasons []string
+
+	for _, policy := range policies {
+		verdict := ""
+		switch {
+		case containsLicense(policy.Denied, normalized):
+			verdict = VerdictDenied
+		case containsLicense(policy.Review, normalized):
+			verdict = VerdictReview
+		case containsLicense(policy.Allowed, normalized):
+			verdict = VerdictAllowed
+		}
+
+		if verdict != "" {
+			reasons = append(reasons, fmt.Sprintf("%s is %s by %q", license, verdictLabel(verdict), policy.Title))
+			if listed == "" || verdictRank[verdict] > verdictRank[listed] {
+				listed = verdict
+			}
+			continue
+		}
+
+		if policy.Default != "" {
+			defaultReasons = append(defaultReasons, fmt.Sprintf("%s is not listed; %q defaults to %s", license, policy.Title, policy.Default))
+			if defaultVerdict == "" || verdictRank[policy.Default] > verdictRank[defaultVerdict] {
+				defaultVerdict = policy.Default
+			}
+		}
+	}
+
+	if listed != "" {
+		return listed, reasons
+	}
+	if defaultVerdict != "" {
+		return defaultVerdict, defaultReasons
+	}
+	return VerdictUnknown, []string{fmt.Sprintf("%s is not covered by any policy", license)}
+}
+
+// verdictLabel phrases a verdict for reasons
+func verdictLabel(verdict string) string {
+	switch verdict {
+	case VerdictAllowed:
+		return "allowed"
+	case VerdictReview:
+		return "flagged for review"
+	case VerdictDenied:
+		return "denied"
+	default:
+		return "not covered"
+	}
+}
+
+// splitLicenseExpression splits an SPDX expression on a case-insensitive
+// operator, ignoring parentheses
+func splitLicenseExpression(expression, operator string) []string {
+	expression = strings.NewReplacer("(", " ", ")", " ").Replace(expression)
+	lower := strings.ToLower(expression)
+
+	var parts []string
+	for {
+		idx := strings.Index(lower, operator)
+		if idx < 0 {
+			break
+		}
+		parts = append(parts, strings.TrimSpace(expression[:idx]))
+		expression = expression[idx+len(operator):]
+		lower = lower[idx+len(operator):]
+	}
+	parts = append(parts, strings.TrimSpace(expression))
+	return parts
+}
+
+// normalizeLicense makes license identifiers comparable, so "Apache 2.0",
+// "apache-2.0" and "Apache-2.0 License" match
+func normalizeLicense(license string) string {
+	license = strings.ToLower(strings.TrimSpace(license))
+	license = strings.TrimSuffix(license, " license")
+	return strings.Join(strings.Fields(license), "-")
+}
+
+// containsLicense reports whether a normalized license appears in a list
+func containsLicense(licenses []string, normalized string) bool {
+	for _, license := range licenses {
+		if normalizeLicense(license) == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPackage reports whether a dependency name matches a denied package
+// name or glob pattern, case-insensitively
+func matchPackage(pattern, dependency string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	dependency = strings.ToLower(strings.TrimSpace(dependency))
+	if pattern == dependency {
+		return true
+	}
+	matched, err := path.Match(pattern, dependency)
+	return err == nil && matched
+}
+
+// ComplianceArgs are the arguments of buddy_check_compliance
+type ComplianceArgs struct {
+	Dependency string `arg:"dependency" desc:"Dependency name, e.g. github.com/foo/bar or left-pad (optional)"`
+	License    string `arg:"license" desc:"SPDX license expression, e.g. MIT or 'MIT OR Apache-2.0' (optional)"`
+	Category   string `arg:"category" desc:"When listing policies, only show this category, e.g. licenses or data (optional)"`
+}
+
+// GetToolHandler returns the tool handler function for compliance checks
+func (ch *ComplianceHandler) GetToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args ComplianceArgs) (*mcp.CallToolResult, error) {
+		dependency, license, category := args.Dependency, args.License, args.Category
+
+		if dependency == "" && license == "" {
+			policies := ch.Documents()
+			if category != "" {
+				policies = ch.Filter(func(policy models.CompliancePolicy) bool {
+					return strings.EqualFold(policy.Category, category)
+				})
+			}
+			return mcp.NewToolResultText(ch.formatPolicies(policies)), nil
+		}
+
+		result := ch.Check(dependency, license)
+		return mcp.NewToolResultText(formatComplianceResult(dependency, license, result)), nil
+	})
+}
+
+// formatComplianceResult formats the verdict for a dependency
+func formatComplianceResult(dependency, license string, result ComplianceResult) string {
+	subject := dependency
+	if subject == "" {
+		subject = "License"
+	}
+	if license != "" {
+		subject += fmt.Sprintf(" (%s)", license)
+	}
+
+	var verdict string
+	switch result.Verdict {
+	case VerdictAllowed:
+		verdict = "✅ ALLOWED"
+	case VerdictReview:
+		verdict = "⚠️ NEEDS LEGAL REVIEW"
+	case VerdictDenied:
+		verdict = "❌ DENIED"
+	default:
+		verdict = "❓ UNKNOWN"
+	}
+
+	output := fmt.Sprintf("%s: %s\n", verdict, subject)
+	for _, reason := range result.Reasons {
+		output += fmt.Sprintf("- %s\n", reason)
+	}
+
+	switch result.Verdict {
+	case VerdictDenied:
+		output += "\n💡 Do not add this dependency; look for an alternative with an allowed license"
+	case VerdictReview, VerdictUnknown:
+		output += "\n💡 Ask a human to confirm with legal before adding this dependency"
+	}
+
+	return output
+}
+
+// formatPolicies lists policies grouped by category
+func (ch *ComplianceHandler) formatPolicies(policies []models.CompliancePolicy) string {
+	if len(policies) == 0 {
+		return "No compliance policies found. Add license and data-handling policies to .buddy/compliance/"
+	}
+
+	result := fmt.Sprintf("Found %d compliance policies\n", len(policies))
+	for i, policy := range policies {
+		result += fmt.Sprintf("\n%d. [%s] %s\n", i+1, policy.Category, policy.Title)
+		if len(policy.Allowed) > 0 {
+			result += fmt.Sprintf("   ✅ Allowed: %s\n", strings.Join(policy.Allowed, ", "))
+		}
+		if len(policy.Review) > 0 {
+			result += fmt.Sprintf("   ⚠️ Review: %s\n", strings.Join(policy.Review, ", "))
+		}
+		if len(policy.Denied) > 0 {
+			result += fmt.Sprintf("   ❌ Denied: %s\n", strings.Join(policy.Denied, ", "))
+		}
+		if len(policy.DeniedPackages) > 0 {
+			result += fmt.Sprintf("   🚫 Denied packages: %s\n", strings.Join(policy.DeniedPackages, ", "))
+		}
+		if policy.Default != "" {
+			result += fmt.Sprintf("   Default for unlisted licenses: %s\n", policy.Default)
+		}
+
+		content := strings.TrimSpace(policy.Content)
+		if len(content) > 300 {
+			content = content[:300] + "..."
+		}
+		if content != "" {
+			result += fmt.Sprintf("   %s\n", strings.ReplaceAll(content, "\n", "\n   "))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/codetodos"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/redact"
+)
+
+// setting holds a configured value that a config reload may replace while
+// handlers read it. The zero setting holds the zero value.
+type setting[T any] struct {
+	value atomic.Pointer[T]
+}
+
+// Load returns the current value
+func (s *setting[T]) Load() T {
+	if value := s.value.Load(); value != nil {
+		return *value
+	}
+	var zero T
+	return zero
+}
+
+// Store replaces the value
+func (s *setting[T]) Store(value T) {
+	s.value.Store(&value)
+}
+
+// applyConfig hands the settings that can change while the server runs to
+// the handlers: path filters, the sandbox, size limits, churn, redaction, draft and
+// rule naming, knowledge metadata fields, todo archiving and code todo
+// scanning. Rate limits and index compaction read Config directly. Size
+// limits, redaction and knowledge fields take effect from the next load.
+func (bh *BuddyHandlers) applyConfig(cfg *config.Config, absBuddyPath string) {
+	bh.backupHandler.pathFilter.Store(cfg.Paths)
+	sandbox := newSandbox(cfg.Sandbox, bh.backupHandler.root, absBuddyPath)
+	bh.backupHandler.sandbox.Store(sandbox)
+	bh.rulesHandler.sandbox.Store(sandbox)
+	bh.rulesHandler.churn.Configure(time.Duration(cfg.Churn.WindowMinutes)*time.Minute, cfg.Churn.Threshold)
+	policy := redact.NewPolicy(cfg.Redaction.Columns)
+	bh.databaseHandler.redaction.Store(policy)
+	bh.datasetsHandler.redaction.Store(policy)
+	bh.draftHandler.files.Store(cfg.Files)
+	bh.rulesHandler.files.Store(cfg.Files)
+	bh.knowledgeHandler.fields.Store(cfg.Knowledge.Fields)
+	bh.todoHandler.autoArchive.Store(cfg.Todos.AutoArchive)
+	bh.todoHandler.codeScanner.Store(codetodos.Scanner{
+		Root:    bh.backupHandler.root.Dir(),
+		Sources: cfg.CodeTodos.Sources,
+		Markers: cfg.CodeTodos.Markers,
+		Allow:   cfg.Paths.Allows,
+		Skip:    []string{absBuddyPath},
+	})
+
+	maxFileSize := maxFileBytes(cfg.Limits.MaxFileKB)
+	bh.rulesHandler.SetMaxFileSize(maxFileSize)
+	bh.knowledgeHandler.SetMaxFileSize(maxFileSize)
+	bh.todoHandler.SetMaxFileSize(maxFileSize)
+	bh.historyHandler.SetMaxFileSize(maxFileSize)
+	bh.datasetsHandler.SetMaxFileSize(maxFileSize)
+	bh.complianceHandler.SetMaxFileSize(maxFileSize)
+
+	bh.config.Store(cfg)
+}
+
+// ReloadConfig re-reads config.yaml and applies it without restarting the
+// server, then reloads the buddy content so new size limits and redaction
+// rules cover it. An invalid file is reported and the previous settings
+// stay in effect.
+func (bh *BuddyHandlers) ReloadConfig() error {
+	cfg, err := config.Load(bh.buddyPath)
+	if err != nil {
+		return fmt.Errorf("keeping the previous configuration: %w", err)
+	}
+	absBuddyPath, err := filepath.Abs(bh.buddyPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve buddy path: %w", err)
+	}
+
+	previous := bh.Config()
+	bh.applyConfig(cfg, absBuddyPath)
+	if pending := restartSettings(previous, cfg); len(pending) > 0 {
+		slog.Warn("configuration changes that take effect after a restart", "settings", pending)
+	}
+	slog.Info("reloaded configuration", "path", config.Path(bh.buddyPath))
+
+	return bh.loadSections()
+}
+
+// restartSettings lists the settings changed between two configurations
+// that are only read when the server starts
+func restartSettings(previous, cfg *config.Config) []string {
+	var changed []string
+	if !reflect.DeepEqual(previous.Display, cfg.Display) {
+		changed = append(changed, "display")
+	}
+	if !reflect.DeepEqual(previous.Tools, cfg.Tools) {
+		changed = append(changed, "tools")
+	}
+	if !reflect.DeepEqual(previous.Auth, cfg.Auth) {
+		changed = append(changed, "auth")
+	}
+	if previous.Paths.Root != cfg.Paths.Root {
+		changed = append(changed, "paths.root")
+	}
+	if previous.Search.IndexDir != cfg.Search.IndexDir {
+		changed = append(changed, "search.index_dir")
+	}
+	return changed
+}
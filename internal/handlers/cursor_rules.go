@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+)
+
+const (
+	// cursorRulesDir holds Cursor's project rules, one .mdc file each
+	cursorRulesDir = ".cursor/rules"
+	// legacyCursorRules is the single rules file older Cursor versions read
+	legacyCursorRules = ".cursorrules"
+	// cursorFilePrefix starts the names of the .mdc files buddy writes, so
+	// they don't collide with hand-written ones
+	cursorFilePrefix = "buddy-"
+	// generatedMarker starts the comment in every generated file; files
+	// without it are never overwritten or removed unless forced
+	generatedMarker = "<!-- Generated by buddy-mcp"
+)
+
+// CursorFile is one generated Cursor rules file
+type CursorFile struct {
+	Path    string // relative to the workspace root
+	Content string
+}
+
+// CursorExport reports the files an export of Cursor rules wrote and the
+// stale generated files it removed, relative to the workspace root
+type CursorExport struct {
+	Format  string
+	Written []string
+	Removed []string
+}
+
+// priorityOrder sorts rules by priority, unknown priorities last
+var priorityOrder = map[string]int{"critical": 0, "recommended": 1, "optional": 2}
+
+// CursorRules converts rules to Cursor's native format: "mdc" for one
+// .cursor/rules file per rule, or "cursorrules" for a single .cursorrules
+// file. source names a rule's file in the generated comment.
+func CursorRules(rules []models.Rule, format string, source func(models.Rule) string) ([]CursorFile, error) {
+	rules = append([]models.Rule(nil), rules...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		pi, pj := rulePriorityRank(rules[i]), rulePriorityRank(rules[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return strings.ToLower(rules[i].Title) < strings.ToLower(rules[j].Title)
+	})
+
+	switch format {
+	case "", "mdc":
+		files := make([]CursorFile, 0, len(rules))
+		for _, rule := range rules {
+			files = append(files, CursorFile{Path: mdcPath(source(rule)), Content: formatMDC(rule, source(rule))})
+		}
+		return files, nil
+	case "cursorrules":
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s from the buddy rules; edit those instead of this file -->\n\n# Project Rules\n", generatedMarker)
+		group := ""
+		for _, rule := range rules {
+			if heading := priorityHeading(rule.Priority); heading != group {
+				group = heading
+				fmt.Fprintf(&sb, "\n## %s\n", group)
+			}
+			fmt.Fprintf(&sb, "\n### %s\n\n%s\n\n%s\n", rule.Title, ruleScopeLine(rule), strings.TrimSpace(rule.Description))
+		}
+		return []CursorFile{{Path: legacyCursorRules, Content: sb.String()}}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected mdc or cursorrules)", format)
+	}
+}
+
+// rulePriorityRank is the position of a rule's priority in priorityOrder
+func rulePriorityRank(rule models.Rule) int {
+	if rank, ok := priorityOrder[rule.Priority]; ok {
+		return rank
+	}
+	return len(priorityOrder)
+}
+
+// priorityHeading is the .cursorrules section a priority is listed under
+func priorityHeading(priority string) string {
+	if _, ok := priorityOrder[priority]; !ok {
+		return "Other"
+	}
+	return strings.ToUpper(priority[:1]) + priority[1:]
+}
+
+// ruleScopeLine describes a rule's priority, category and the files it covers
+func ruleScopeLine(rule models.Rule) string {
+	var parts []string
+	if rule.Priority != "" {
+		parts = append(parts, "Priority: "+rule.Priority)
+	}
+	if rule.Category != "" {
+		parts = append(parts, "Category: "+rule.Category)
+	}
+	if len(rule.AppliesTo) > 0 {
+		parts = append(parts, "Applies to: "+strings.Join(rule.AppliesTo, ", "))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// mdcPath is the .cursor/rules file a rule file is exported to
+func mdcPath(source string) string {
+	name := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	return cursorRulesDir + "/" + cursorFilePrefix + name + ".mdc"
+}
+
+// formatMDC writes a rule as a Cursor .mdc file. Rules with applies_to are
+// attached to the files their globs match; the rest are always applied
+// when critical or recommended, and left for the agent to request by
+// description when optional.
+func formatMDC(rule models.Rule, source string) string {
+	var globs []string
+	for _, pattern := range rule.AppliesTo {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		// A buddy glob without a slash matches at any depth, a Cursor one
+		// only at the root
+		if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") && !strings.HasPrefix(pattern, "**") {
+			pattern = "**/" + pattern
+		}
+		globs = append(globs, pattern)
+	}
+	alwaysApply := len(globs) == 0 && rule.Priority != "optional"
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "---\ndescription: %s\nglobs:", mdcValue(rule.Title))
+	if len(globs) > 0 {
+		fmt.Fprintf(&sb, " %s", strings.Join(globs, ","))
+	}
+	fmt.Fprintf(&sb, "\nalwaysApply: %t\n---\n", alwaysApply)
+	fmt.Fprintf(&sb, "%s from %s; edit that rule instead of this file -->\n\n", generatedMarker, source)
+	fmt.Fprintf(&sb, "# %s\n\n%s\n\n%s\n", rule.Title, ruleScopeLine(rule), strings.TrimSpace(rule.Description))
+	return sb.String()
+}
+
+// mdcValue quotes a frontmatter value YAML would otherwise misread
+func mdcValue(value string) string {
+	if strings.ContainsAny(value, ":#'\"[]{}&*!|>%@`") {
+		return fmt.Sprintf("%q", value)
+	}
+	return value
+}
+
+// ExportCursorRules writes the rules as Cursor rules files in the
+// workspace root. Files there that buddy didn't generate are left alone
+// unless force is set; .mdc files generated for rules that no longer exist
+// are removed. With dryRun nothing is changed.
+func (rh *RulesHandler) ExportCursorRules(ctx context.Context, format string, force, dryRun bool) (*CursorExport, error) {
+	files, err := CursorRules(rh.GetRules(), format, func(rule models.Rule) string {
+		return rh.root.Rel(rule.FilePath)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = "mdc"
+	}
+
+	export := &CursorExport{Format: format}
+	wanted := make(map[string]bool, len(files))
+	for _, file := range files {
+		wanted[file.Path] = true
+		generated, err := rh.generatedFile(rh.root.Abs(file.Path))
+		if err != nil {
+			return nil, err
+		}
+		if !generated && !force {
+			return nil, fmt.Errorf("%s exists and wasn't generated by buddy; move it aside or set force to overwrite it", file.Path)
+		}
+	}
+
+	var stale []string
+	if format == "mdc" {
+		existing, err := rh.store.List(rh.root.Abs(cursorRulesDir), false)
+		if err != nil && !storage.IsNotExist(err) {
+			return nil, err
+		}
+		for _, file := range existing {
+			path := rh.root.Rel(file.Path)
+			name := filepath.Base(path)
+			if wanted[path] || !strings.HasPrefix(name, cursorFilePrefix) || filepath.Ext(name) != ".mdc" {
+				continue
+			}
+			// A hand-written file that happens to share the prefix is kept
+			if content, err := rh.store.Read(file.Path); err == nil && strings.Contains(string(content), generatedMarker) {
+				stale = append(stale, path)
+			}
+		}
+	}
+
+	// Nothing is written unless every file may be
+	targets := make([]string, 0, len(files)+len(stale))
+	for _, file := range files {
+		targets = append(targets, rh.root.Abs(file.Path))
+	}
+	for _, path := range stale {
+		targets = append(targets, rh.root.Abs(path))
+	}
+	if err := checkSandbox(rh.sandbox.Load(), "write", targets...); err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if !dryRun {
+			if err := writeFile(ctx, rh.store, rh.root.Abs(file.Path), []byte(file.Content)); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", file.Path, err)
+			}
+		}
+		export.Written = append(export.Written, file.Path)
+	}
+	for _, path := range stale {
+		if !dryRun {
+			if err := removeFile(ctx, rh.store, rh.root.Abs(path)); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		}
+		export.Removed = append(export.Removed, path)
+	}
+	return export, nil
+}
+
+// generatedFile reports whether path is missing or a file buddy wrote
+func (rh *RulesHandler) generatedFile(path string) (bool, error) {
+	content, err := rh.store.Read(path)
+	if storage.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(content), generatedMarker), nil
+}
+
+// ExportCursorRules reads the rules of a buddy folder and writes them as
+// Cursor rules files in its workspace root, like the tool's export_cursor
+// action. It reads the files directly, so it can run alongside a server.
+func ExportCursorRules(buddyPath, format string, force, dryRun bool) (*CursorExport, error) {
+	if info, err := os.Stat(buddyPath); err != nil {
+		return nil, fmt.Errorf("buddy folder not found: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", buddyPath)
+	}
+	cfg, err := config.Load(buddyPath)
+	if err != nil {
+		return nil, err
+	}
+	root, err := workspaceRoot(buddyPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+	absBuddyPath, err := filepath.Abs(buddyPath)
+	if err != nil {
+		return nil, err
+	}
+	rulesHandler := NewRulesHandler(filepath.Join(buddyPath, "rules"), nil)
+	rulesHandler.root = root
+	rulesHandler.sandbox.Store(newSandbox(cfg.Sandbox, root, absBuddyPath))
+	rulesHandler.SetMaxFileSize(maxFileBytes(cfg.Limits.MaxFileKB))
+	if err := rulesHandler.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+	return rulesHandler.ExportCursorRules(context.Background(), format, force, dryRun)
+}
+
+// FormatCursorExport lists what an export of Cursor rules changed
+func FormatCursorExport(export *CursorExport, dryRun bool) string {
+	var sb strings.Builder
+	verb, removed := "Wrote", "Removed"
+	if dryRun {
+		verb, removed = "Would write", "Would remove"
+	}
+	fmt.Fprintf(&sb, "📤 %s %d Cursor rules file(s) (%s)\n", verb, len(export.Written), export.Format)
+	for _, path := range export.Written {
+		fmt.Fprintf(&sb, "   %s\n", path)
+	}
+	if len(export.Removed) > 0 {
+		fmt.Fprintf(&sb, "\n%s %d stale generated file(s)\n", removed, len(export.Removed))
+		for _, path := range export.Removed {
+			fmt.Fprintf(&sb, "   %s\n", path)
+		}
+	}
+	sb.WriteString("\n💡 Edit the buddy rules and export again; the generated files are overwritten")
+	return sb.String()
+}
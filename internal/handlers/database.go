@@ -3,8 +3,7 @@ package handlers
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
-	"os"
+	"log/slog"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -14,7 +13,11 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/redact"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/timeutil"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
 )
 
 // DatabaseHandler manages database schema information
@@ -22,36 +25,42 @@ type DatabaseHandler struct {
 	path          string
 	dbInfo        *models.DatabaseInfo
 	searchManager *search.SearchManager
+	store         storage.Storage
+	timeFormat    *timeutil.Formatter
+	redaction     setting[redact.Policy] // marks personal data columns by name
 	mu            sync.RWMutex
 }
 
 // NewDatabaseHandler creates a new database handler
 func NewDatabaseHandler(path string, searchManager *search.SearchManager) *DatabaseHandler {
-	return &DatabaseHandler{
+	dh := &DatabaseHandler{
 		path:          path,
 		dbInfo:        nil,
 		searchManager: searchManager,
+		store:         storage.NewLocal(),
 	}
+	dh.redaction.Store(redact.NewPolicy(nil))
+	return dh
 }
 
 // Load loads database schema information
 func (dh *DatabaseHandler) Load() error {
-	dh.mu.Lock()
-	defer dh.mu.Unlock()
-
-	// First, reindex all database tables
-	if err := dh.searchManager.ReindexAll(search.IndexTypeDatabase); err != nil {
-		return fmt.Errorf("failed to reindex database: %w", err)
+	// First, reindex all database tables. Handlers without a search
+	// manager, as used by CLI subcommands, skip indexing.
+	if dh.searchManager != nil {
+		if err := dh.searchManager.ReindexAll(search.IndexTypeDatabase); err != nil {
+			return fmt.Errorf("failed to reindex database: %w", err)
+		}
 	}
 
 	dbInfo := &models.DatabaseInfo{
 		Tables:    []models.Table{},
-		UpdatedAt: time.Now(),
+		UpdatedAt: time.Now().UTC(),
 	}
 
 	// Check for schema.sql
 	schemaPath := filepath.Join(dh.path, "schema.sql")
-	if _, err := os.Stat(schemaPath); err == nil {
+	if _, err := dh.store.Stat(schemaPath); err == nil {
 		dbInfo.SchemaPath = schemaPath
 
 		// Parse schema file
@@ -60,10 +69,13 @@ func (dh *DatabaseHandler) Load() error {
 
 			// Index all tables
 			for _, table := range tables {
+				if dh.searchManager == nil {
+					continue
+				}
 				doc := search.FromTable(table)
 				if err := dh.searchManager.IndexDocument(search.IndexTypeDatabase, table.Name, doc); err != nil {
 					// Log error but continue
-					fmt.Printf("failed to index table %s: %v\n", table.Name, err)
+					slog.Warn("failed to index table", "table", table.Name, "error", err)
 				}
 			}
 		}
@@ -73,7 +85,7 @@ func (dh *DatabaseHandler) Load() error {
 	erdFiles := []string{"erd.png", "erd.jpg", "erd.svg", "erd.pdf"}
 	for _, erd := range erdFiles {
 		erdPath := filepath.Join(dh.path, erd)
-		if _, err := os.Stat(erdPath); err == nil {
+		if _, err := dh.store.Stat(erdPath); err == nil {
 			dbInfo.ERDPath = erdPath
 			break
 		}
@@ -81,7 +93,7 @@ func (dh *DatabaseHandler) Load() error {
 
 	// Load connection info
 	connPath := filepath.Join(dh.path, "connection.md")
-	if content, err := ioutil.ReadFile(connPath); err == nil {
+	if content, err := dh.store.Read(connPath); err == nil {
 		dbInfo.ConnectionInfo = string(content)
 
 		// Try to determine database type
@@ -97,13 +109,17 @@ func (dh *DatabaseHandler) Load() error {
 		}
 	}
 
+	// Only hold the lock for the swap so queries aren't blocked while parsing
+	dh.mu.Lock()
 	dh.dbInfo = dbInfo
+	dh.mu.Unlock()
+
 	return nil
 }
 
 // parseSchema parses a SQL schema file
 func (dh *DatabaseHandler) parseSchema(filePath string) ([]models.Table, error) {
-	content, err := ioutil.ReadFile(filePath)
+	content, err := dh.store.Read(filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +128,7 @@ func (dh *DatabaseHandler) parseSchema(filePath string) ([]models.Table, error)
 	sql := string(content)
 
 	// Find CREATE TABLE statements
-	createTableRegex := regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\w+)\s*\((.*?)\);`)
+	createTableRegex := regexp.MustCompile(`(?is)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\w+)\s*\((.*?)\);`)
 	matches := createTableRegex.FindAllStringSubmatch(sql, -1)
 
 	for _, match := range matches {
@@ -121,9 +137,16 @@ func (dh *DatabaseHandler) parseSchema(filePath string) ([]models.Table, error)
 			tableDefinition := match[2]
 
 			table := models.Table{
-				Name:    tableName,
-				Columns: dh.parseColumns(tableDefinition),
-				Indexes: dh.parseIndexes(sql, tableName),
+				Name:        tableName,
+				Columns:     dh.parseColumns(tableDefinition),
+				Indexes:     dh.parseIndexes(sql, tableName),
+				ForeignKeys: dh.parseForeignKeys(tableDefinition),
+			}
+			applyKeyConstraints(&table, tableDefinition)
+
+			flagged := piiCommentColumns(tableDefinition)
+			for i, column := range table.Columns {
+				table.Columns[i].PII = flagged[strings.ToLower(column.Name)] || dh.redaction.Load().Sensitive(column.Name)
 			}
 
 			tables = append(tables, table)
@@ -138,7 +161,7 @@ func (dh *DatabaseHandler) parseColumns(definition string) []models.Column {
 	var columns []models.Column
 
 	// Split by commas, but be careful about nested parentheses
-	lines := strings.Split(definition, ",")
+	lines := splitDefinitions(definition)
 
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
@@ -160,6 +183,13 @@ func (dh *DatabaseHandler) parseColumns(definition string) []models.Column {
 				Nullable: !strings.Contains(strings.ToUpper(line), "NOT NULL"),
 			}
 
+			// Keys declared on the column itself; key columns can't be null
+			if columnPrimaryKeyRegex.MatchString(line) {
+				column.PrimaryKey = true
+				column.Nullable = false
+			}
+			column.Unique = columnUniqueRegex.MatchString(line)
+
 			// Check for DEFAULT value
 			if strings.Contains(strings.ToUpper(line), "DEFAULT") {
 				defaultRegex := regexp.MustCompile(`(?i)DEFAULT\s+([^,\s]+)`)
@@ -175,6 +205,121 @@ func (dh *DatabaseHandler) parseColumns(definition string) []models.Column {
 	return columns
 }
 
+// columnPrimaryKeyRegex and columnUniqueRegex match keys declared in a
+// column definition
+var (
+	columnPrimaryKeyRegex = regexp.MustCompile(`(?i)\bPRIMARY\s+KEY\b`)
+	columnUniqueRegex     = regexp.MustCompile(`(?i)\bUNIQUE\b`)
+)
+
+// keyConstraintRegex matches table-level PRIMARY KEY and UNIQUE
+// constraints, named or not, including MySQL's UNIQUE KEY name (...)
+var keyConstraintRegex = regexp.MustCompile(`(?i)^(?:CONSTRAINT\s+\S+\s+)?(PRIMARY\s+KEY|UNIQUE)(?:\s+(?:KEY|INDEX))?(?:\s+[^\s(]+)?\s*\(([^)]*)\)`)
+
+// applyKeyConstraints marks the columns named by the table-level PRIMARY
+// KEY and single-column UNIQUE constraints of a CREATE TABLE body, and
+// records UNIQUE constraints over several columns on the table
+func applyKeyConstraints(table *models.Table, definition string) {
+	mark := func(names []string, set func(column *models.Column)) {
+		for _, name := range names {
+			for i := range table.Columns {
+				if strings.EqualFold(table.Columns[i].Name, name) {
+					set(&table.Columns[i])
+				}
+			}
+		}
+	}
+
+	for _, part := range splitDefinitions(definition) {
+		match := keyConstraintRegex.FindStringSubmatch(strings.TrimSpace(part))
+		if match == nil {
+			continue
+		}
+		var names []string
+		for _, name := range strings.Split(match[2], ",") {
+			if name = strings.Trim(strings.TrimSpace(name), "`\"[]"); name != "" {
+				names = append(names, name)
+			}
+		}
+		switch {
+		case strings.HasPrefix(strings.ToUpper(match[1]), "PRIMARY"):
+			mark(names, func(column *models.Column) {
+				column.PrimaryKey = true
+				column.Nullable = false
+			})
+		case len(names) == 1:
+			mark(names, func(column *models.Column) { column.Unique = true })
+		case len(names) > 1:
+			table.UniqueKeys = append(table.UniqueKeys, names)
+		}
+	}
+}
+
+// piiCommentRegex matches a column definition flagged with a "-- pii" comment
+var piiCommentRegex = regexp.MustCompile(`(?im)^\s*(\w+)\s+[^\n]*?--[^\n]*\bpii\b`)
+
+// piiCommentColumns returns the lowercased names of columns flagged as
+// personal data by a trailing comment, e.g. "email TEXT, -- pii"
+func piiCommentColumns(definition string) map[string]bool {
+	flagged := make(map[string]bool)
+	for _, match := range piiCommentRegex.FindAllStringSubmatch(definition, -1) {
+		flagged[strings.ToLower(match[1])] = true
+	}
+	return flagged
+}
+
+// splitDefinitions splits a CREATE TABLE body on top-level commas so that
+// types like DECIMAL(10,2) stay intact, dropping SQL line comments
+func splitDefinitions(definition string) []string {
+	var cleaned []string
+	for _, line := range strings.Split(definition, "\n") {
+		if idx := strings.Index(line, "--"); idx >= 0 {
+			line = line[:idx]
+		}
+		cleaned = append(cleaned, line)
+	}
+	definition = strings.Join(cleaned, "\n")
+
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range definition {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, definition[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, definition[start:])
+}
+
+// foreignKeyRegex matches table-level FOREIGN KEY constraints
+var foreignKeyRegex = regexp.MustCompile(`(?i)FOREIGN\s+KEY\s*\(\s*(\w+)\s*\)\s*REFERENCES\s+(\w+)\s*\(\s*(\w+)\s*\)`)
+
+// inlineReferenceRegex matches column-level REFERENCES clauses
+var inlineReferenceRegex = regexp.MustCompile(`(?i)^(\w+)\s+.*?\bREFERENCES\s+(\w+)\s*\(\s*(\w+)\s*\)`)
+
+// parseForeignKeys extracts foreign key relationships from a CREATE TABLE body
+func (dh *DatabaseHandler) parseForeignKeys(definition string) []models.ForeignKey {
+	var foreignKeys []models.ForeignKey
+
+	for _, part := range splitDefinitions(definition) {
+		part = strings.TrimSpace(part)
+		if match := foreignKeyRegex.FindStringSubmatch(part); match != nil {
+			foreignKeys = append(foreignKeys, models.ForeignKey{Column: match[1], RefTable: match[2], RefColumn: match[3]})
+		} else if match := inlineReferenceRegex.FindStringSubmatch(part); match != nil {
+			foreignKeys = append(foreignKeys, models.ForeignKey{Column: match[1], RefTable: match[2], RefColumn: match[3]})
+		}
+	}
+
+	return foreignKeys
+}
+
 // parseIndexes extracts index information for a table
 func (dh *DatabaseHandler) parseIndexes(sql, tableName string) []models.Index {
 	var indexes []models.Index
@@ -187,7 +332,7 @@ func (dh *DatabaseHandler) parseIndexes(sql, tableName string) []models.Index {
 		if len(match) >= 4 {
 			index := models.Index{
 				Name:    match[2],
-				Unique:  strings.ToUpper(match[1]) == "UNIQUE",
+				Unique:  strings.EqualFold(strings.TrimSpace(match[1]), "UNIQUE"),
 				Columns: strings.Split(strings.ReplaceAll(match[3], " ", ""), ","),
 			}
 			indexes = append(indexes, index)
@@ -277,22 +422,44 @@ func (dh *DatabaseHandler) extractTableNames(query string) []string {
 	return tableNames
 }
 
+// DatabaseArgs are the arguments of buddy_get_database_info
+type DatabaseArgs struct {
+	Action        string `arg:"action" enum:"export" desc:"Set to export for the full parsed schema as JSON or normalized SQL DDL (optional)"`
+	Format        string `arg:"format" enum:"json,sql" desc:"Export format: json or sql (default: json)"`
+	TableName     string `arg:"table_name" desc:"Get info for specific table, or limit an export to it (optional)"`
+	Search        string `arg:"search" desc:"Search tables by name, columns or indexes (optional)"`
+	ValidateQuery string `arg:"validate_query" desc:"SQL query to validate against schema (optional)"`
+	SuggestQuery  string `arg:"suggest_query" desc:"Natural-language intent to turn into join paths and a skeleton query, e.g. 'orders with user email' (optional)"`
+	TokenBudgetArgs
+}
+
 // GetToolHandler returns the tool handler function for database info
 func (dh *DatabaseHandler) GetToolHandler() server.ToolHandlerFunc {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		args := request.GetArguments()
-		tableName, _ := args["table_name"].(string)
-		validateQuery, _ := args["validate_query"].(string)
-		searchQuery, _ := args["search"].(string)
+	return toolargs.Handler(func(ctx context.Context, args DatabaseArgs) (*mcp.CallToolResult, error) {
+		tableName, validateQuery, searchQuery, suggestQuery := args.TableName, args.ValidateQuery, args.Search, args.SuggestQuery
 
 		dbInfo := dh.GetDatabaseInfo()
 		if dbInfo == nil {
 			return mcp.NewToolResultText("No database information loaded"), nil
 		}
 
+		// Handle schema export for other tooling
+		switch action := args.Action; action {
+		case "":
+		case "export":
+			export, err := dh.ExportSchema(args.Format, tableName)
+			if err != nil {
+				return nil, err
+			}
+			return mcp.NewToolResultText(export), nil
+		default:
+			return nil, fmt.Errorf("invalid action: %s", action)
+		}
+
 		// Handle search query using Bleve
 		if searchQuery != "" {
-			searchResults, err := dh.searchManager.Search(
+			searchResults, err := dh.searchManager.SearchContext(
+				ctx,
 				search.IndexTypeDatabase,
 				searchQuery,
 				20, // Limit to 20 results
@@ -313,7 +480,7 @@ func (dh *DatabaseHandler) GetToolHandler() server.ToolHandlerFunc {
 				}
 			}
 
-			result := dh.formatSearchResults(searchQuery, tables)
+			result := dh.formatSearchResults(ctx, searchQuery, tables)
 			return mcp.NewToolResultText(result), nil
 		}
 
@@ -333,6 +500,21 @@ func (dh *DatabaseHandler) GetToolHandler() server.ToolHandlerFunc {
 			return mcp.NewToolResultText(result), nil
 		}
 
+		// Handle query suggestion from intent
+		if suggestQuery != "" {
+			suggestion, err := dh.SuggestQuery(suggestQuery)
+			if err != nil {
+				result := fmt.Sprintf("Could not suggest a query: %v\n\n", err)
+				result += "Available tables:\n"
+				for _, t := range dbInfo.Tables {
+					result += fmt.Sprintf("- %s\n", t.Name)
+				}
+				return mcp.NewToolResultText(result), nil
+			}
+
+			return mcp.NewToolResultText(dh.formatQuerySuggestion(suggestQuery, suggestion)), nil
+		}
+
 		// Handle query validation
 		if validateQuery != "" {
 			valid, message := dh.ValidateQuery(validateQuery)
@@ -355,7 +537,7 @@ func (dh *DatabaseHandler) GetToolHandler() server.ToolHandlerFunc {
 		// Return general database info
 		result := dh.formatDatabaseOverview()
 		return mcp.NewToolResultText(result), nil
-	}
+	})
 }
 
 // formatDatabaseOverview formats the database overview
@@ -370,7 +552,7 @@ func (dh *DatabaseHandler) formatDatabaseOverview() string {
 	result += fmt.Sprintf("ERD Path: %s\n", dbInfo.ERDPath)
 	result += fmt.Sprintf("Has Connection Info: %v\n", dbInfo.ConnectionInfo != "")
 	result += fmt.Sprintf("Total Tables: %d\n", len(dbInfo.Tables))
-	result += fmt.Sprintf("Last Updated: %s\n\n", dbInfo.UpdatedAt.Format("2006-01-02 15:04:05"))
+	result += fmt.Sprintf("Last Updated: %s\n\n", dh.timeFormat.Format(dbInfo.UpdatedAt))
 
 	if len(dbInfo.Tables) > 0 {
 		result += "Tables Summary:\n"
@@ -405,6 +587,9 @@ func (dh *DatabaseHandler) formatTableDetails(table models.Table) string {
 			if col.DefaultValue != "" {
 				attributes = append(attributes, fmt.Sprintf("DEFAULT %s", col.DefaultValue))
 			}
+			if col.PII {
+				attributes = append(attributes, "PII")
+			}
 
 			if len(attributes) > 0 {
 				result += fmt.Sprintf(" (%s)", strings.Join(attributes, ", "))
@@ -426,6 +611,14 @@ func (dh *DatabaseHandler) formatTableDetails(table models.Table) string {
 		}
 	}
 
+	// Foreign keys
+	if len(table.ForeignKeys) > 0 {
+		result += "\nForeign Keys:\n"
+		for _, fk := range table.ForeignKeys {
+			result += fmt.Sprintf("- %s → %s.%s\n", fk.Column, fk.RefTable, fk.RefColumn)
+		}
+	}
+
 	// Sample queries
 	result += "\nSample Queries:\n"
 	result += fmt.Sprintf("- SELECT * FROM %s LIMIT 10;\n", table.Name)
@@ -434,19 +627,17 @@ func (dh *DatabaseHandler) formatTableDetails(table models.Table) string {
 	return result
 }
 
-// formatSearchResults formats database search results
-func (dh *DatabaseHandler) formatSearchResults(query string, tables []models.Table) string {
+// formatSearchResults formats database search results, or suggestions
+// when nothing matched
+func (dh *DatabaseHandler) formatSearchResults(ctx context.Context, query string, tables []models.Table) string {
 	if len(tables) == 0 {
 		result := fmt.Sprintf("No tables found for search: %s\n", query)
 
 		// Get document count
 		count, _ := dh.searchManager.GetDocumentCount(search.IndexTypeDatabase)
 		if count > 0 {
-			result += fmt.Sprintf("\nThere are %d tables in the database. Try:\n", count)
-			result += "- Table names or partial names\n"
-			result += "- Column names or types\n"
-			result += "- 'index' to find indexed tables\n"
-			result += "- Data types (e.g., 'varchar', 'integer', 'timestamp')\n"
+			result += fmt.Sprintf("\nThere are %d tables in the database.\n", count)
+			result += formatSuggestions(ctx, dh.searchManager, search.IndexTypeDatabase, query, "table_name", "tables")
 		}
 
 		// Show available tables
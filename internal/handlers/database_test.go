@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchema_MultiLineCreateTable(t *testing.T) {
+	dir := t.TempDir()
+	schema := `CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL, -- login, unique
+    balance DECIMAL(10,2) DEFAULT 0
+);
+
+create table if not exists orders (id INT, total NUMERIC(12, 4));
+`
+	path := filepath.Join(dir, "schema.sql")
+	require.NoError(t, os.WriteFile(path, []byte(schema), 0644))
+
+	dh := NewDatabaseHandler(dir, nil)
+	tables, err := dh.parseSchema(path)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+
+	assert.Equal(t, "users", tables[0].Name)
+	require.Len(t, tables[0].Columns, 3)
+	assert.Equal(t, "id", tables[0].Columns[0].Name)
+	assert.Equal(t, "email", tables[0].Columns[1].Name)
+	assert.False(t, tables[0].Columns[1].Nullable)
+	assert.Equal(t, "balance", tables[0].Columns[2].Name)
+	assert.Equal(t, "DECIMAL(10,2)", tables[0].Columns[2].Type)
+	assert.Equal(t, "0", tables[0].Columns[2].DefaultValue)
+
+	assert.Equal(t, "orders", tables[1].Name)
+	require.Len(t, tables[1].Columns, 2)
+	assert.Equal(t, "total", tables[1].Columns[1].Name)
+}
+
+func TestParseSchema_Keys(t *testing.T) {
+	dir := t.TempDir()
+	schema := `CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE, -- pii
+    org_id INT REFERENCES orgs(id),
+    handle TEXT,
+    CONSTRAINT uq_handle UNIQUE (handle)
+);
+
+CREATE TABLE memberships (
+    user_id INT,
+    org_id INT,
+    role TEXT DEFAULT 'member',
+    PRIMARY KEY (user_id, org_id),
+    UNIQUE (org_id, role),
+    FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX idx_memberships_role ON memberships (role);
+`
+	path := filepath.Join(dir, "schema.sql")
+	require.NoError(t, os.WriteFile(path, []byte(schema), 0644))
+
+	dh := NewDatabaseHandler(dir, nil)
+	tables, err := dh.parseSchema(path)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+
+	keys := func(table models.Table) map[string]string {
+		flags := make(map[string]string)
+		for _, column := range table.Columns {
+			switch {
+			case column.PrimaryKey:
+				flags[column.Name] = "primary"
+			case column.Unique:
+				flags[column.Name] = "unique"
+			}
+		}
+		return flags
+	}
+	assert.Equal(t, map[string]string{"id": "primary", "email": "unique", "handle": "unique"}, keys(tables[0]))
+	assert.False(t, tables[0].Columns[0].Nullable, "key columns can't be null")
+	assert.Equal(t, map[string]string{"user_id": "primary", "org_id": "primary"}, keys(tables[1]))
+	assert.Equal(t, [][]string{{"org_id", "role"}}, tables[1].UniqueKeys)
+
+	// Exported DDL parses back to the same schema, and exports the same way
+	ddl := schemaDDL(tables)
+	assert.Contains(t, ddl, "id SERIAL NOT NULL PRIMARY KEY,")
+	assert.Contains(t, ddl, "PRIMARY KEY (user_id, org_id),")
+	assert.Contains(t, ddl, "UNIQUE (org_id, role),")
+	exported := filepath.Join(dir, "exported.sql")
+	require.NoError(t, os.WriteFile(exported, []byte(ddl), 0644))
+	reparsed, err := dh.parseSchema(exported)
+	require.NoError(t, err)
+	assert.Equal(t, tables, reparsed)
+	assert.Equal(t, ddl, schemaDDL(reparsed))
+}
+
+func TestSplitDefinitions(t *testing.T) {
+	parts := splitDefinitions("a INT, -- first, second\n b DECIMAL(10,2),\n c TEXT")
+	require.Len(t, parts, 3)
+	assert.Equal(t, "a INT", strings.TrimSpace(parts[0]))
+	assert.Equal(t, "b DECIMAL(10,2)", strings.TrimSpace(parts[1]))
+	assert.Equal(t, "c TEXT", strings.TrimSpace(parts[2]))
+}
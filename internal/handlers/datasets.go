@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/redact"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/schema"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultDatasetSample is how many rows are shown when a dataset is requested
+const defaultDatasetSample = 10
+
+// DatasetsHandler manages small reference datasets such as country codes or
+// error code mappings. Each CSV or TSV file has a header row and may have a
+// markdown sidecar with the same name describing it.
+type DatasetsHandler struct {
+	*DocumentHandler[models.Dataset]
+	redaction setting[redact.Policy] // marks personal data columns by name
+}
+
+// datasetFrontmatter is the optional YAML header of a dataset sidecar
+type datasetFrontmatter struct {
+	PII []string `yaml:"pii"` // columns holding personal data
+}
+
+// splitFrontmatter separates a leading "---" delimited YAML block from a
+// markdown document
+func splitFrontmatter(content string) (string, string) {
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return "", content
+	}
+	end := strings.Index(normalized[4:], "\n---")
+	if end < 0 {
+		return "", content
+	}
+	body := normalized[4+end+4:]
+	if idx := strings.Index(body, "\n"); idx >= 0 {
+		body = body[idx+1:]
+	} else {
+		body = ""
+	}
+	return normalized[4 : 4+end], body
+}
+
+// NewDatasetsHandler creates a new datasets handler
+func NewDatasetsHandler(path string, searchManager *search.SearchManager) *DatasetsHandler {
+	dh := &DatasetsHandler{}
+	dh.redaction.Store(redact.NewPolicy(nil))
+	dh.DocumentHandler = NewDocumentHandler(path, searchManager, DocumentSpec[models.Dataset]{
+		IndexType:  search.IndexTypeDatasets,
+		Extensions: []string{".csv", ".tsv"},
+		Recursive:  true,
+		Truncate:   truncateRows,
+		Parse:      dh.parseDatasetFile,
+		ID:         func(dataset models.Dataset) string { return dataset.ID },
+		Index:      func(dataset models.Dataset) interface{} { return search.FromDataset(dataset) },
+		Less:       func(a, b models.Dataset) bool { return a.Name < b.Name },
+	})
+	return dh
+}
+
+// parseDatasetFile parses a single CSV or TSV file and its description sidecar
+func (dh *DatasetsHandler) parseDatasetFile(file storage.FileInfo, content []byte) ([]models.Dataset, error) {
+	ext := filepath.Ext(file.Path)
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	if strings.EqualFold(ext, ".tsv") {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", strings.TrimPrefix(ext, "."), err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := records[1:]
+
+	// Pad or trim ragged rows so every row lines up with the header
+	for i, row := range rows {
+		if len(row) < len(header) {
+			row = append(row, make([]string, len(header)-len(row))...)
+		}
+		rows[i] = row[:len(header)]
+	}
+
+	// Name datasets by their path inside the datasets folder, without extension
+	relPath, err := filepath.Rel(dh.path, file.Path)
+	if err != nil {
+		relPath = filepath.Base(file.Path)
+	}
+	name := filepath.ToSlash(strings.TrimSuffix(relPath, ext))
+
+	var (
+		description string
+		meta        datasetFrontmatter
+	)
+	if sidecar, err := dh.store.Read(strings.TrimSuffix(file.Path, ext) + ".md"); err == nil {
+		front, body := splitFrontmatter(string(sidecar))
+		if front != "" {
+			var raw map[string]interface{}
+			if err := yaml.Unmarshal([]byte(front), &raw); err != nil {
+				return nil, fmt.Errorf("invalid frontmatter in %s.md: %w", name, err)
+			}
+			if err := schema.ValidateValue(schema.DatasetFrontmatter, raw); err != nil {
+				return nil, fmt.Errorf("invalid frontmatter in %s.md: %w", name, err)
+			}
+			if err := yaml.Unmarshal([]byte(front), &meta); err != nil {
+				return nil, fmt.Errorf("invalid frontmatter in %s.md: %w", name, err)
+			}
+		}
+		description = strings.TrimSpace(body)
+	}
+
+	// Content shorter than the file was cut to the size limit; the rows
+	// can't carry a notice, so the description does
+	if int64(len(content)) < file.Size {
+		notice := fmt.Sprintf("⚠️ Truncated: the file is over the size limit (limits.max_file_kb in config.yaml), so only its first %d rows were loaded.", len(rows))
+		description = strings.TrimSpace(description + "\n\n" + notice)
+	}
+
+	flagged := make(map[string]bool)
+	for _, column := range meta.PII {
+		flagged[strings.ToLower(strings.TrimSpace(column))] = true
+	}
+
+	columns := make([]models.DatasetColumn, len(header))
+	for i, columnName := range header {
+		columnName = strings.TrimSpace(columnName)
+		columns[i] = models.DatasetColumn{
+			Name: columnName,
+			Type: inferColumnType(rows, i),
+			PII:  flagged[strings.ToLower(columnName)] || dh.redaction.Load().Sensitive(columnName),
+		}
+	}
+
+	return []models.Dataset{{
+		ID:          documentID(dh.path, file.Path),
+		Name:        name,
+		Description: description,
+		Columns:     columns,
+		Rows:        rows,
+		FilePath:    file.Path,
+		UpdatedAt:   file.ModTime,
+	}}, nil
+}
+
+// inferColumnType picks the narrowest type that fits every non-empty value
+func inferColumnType(rows [][]string, column int) string {
+	candidates := []struct {
+		name    string
+		matches func(string) bool
+	}{
+		{"integer", func(v string) bool { _, err := strconv.ParseInt(v, 10, 64); return err == nil }},
+		{"number", func(v string) bool { _, err := strconv.ParseFloat(v, 64); return err == nil }},
+		{"boolean", func(v string) bool { v = strings.ToLower(v); return v == "true" || v == "false" }},
+		{"date", func(v string) bool {
+			if _, err := time.Parse("2006-01-02", v); err == nil {
+				return true
+			}
+			_, err := time.Parse(time.RFC3339, v)
+			return err == nil
+		}},
+	}
+
+	seen := false
+	for _, candidate := range candidates {
+		fits := true
+		for _, row := range rows {
+			value := strings.TrimSpace(row[column])
+			if value == "" {
+				continue
+			}
+			seen = true
+			if !candidate.matches(value) {
+				fits = false
+				break
+			}
+		}
+		if !seen {
+			break
+		}
+		if fits {
+			return candidate.name
+		}
+	}
+
+	return "string"
+}
+
+// GetDatasets returns all loaded datasets
+func (dh *DatasetsHandler) GetDatasets() []models.Dataset {
+	return dh.Documents()
+}
+
+// GetDataset returns the dataset with the given name
+func (dh *DatasetsHandler) GetDataset(name string) (models.Dataset, bool) {
+	matches := dh.Filter(func(dataset models.Dataset) bool {
+		return dataset.Name == name
+	})
+	if len(matches) == 0 {
+		return models.Dataset{}, false
+	}
+	return matches[0], true
+}
+
+// DatasetsArgs are the arguments of buddy_get_datasets
+type DatasetsArgs struct {
+	Name   string `arg:"name" desc:"Dataset name to show schema and rows for; omit to list datasets"`
+	Query  string `arg:"query" desc:"Search datasets by name, description, columns or values when listing (optional)"`
+	Filter string `arg:"filter" desc:"Only show rows containing this text (optional)"`
+	Sample int    `arg:"sample" desc:"Maximum number of rows to show (default: 10)"`
+	TokenBudgetArgs
+}
+
+// GetToolHandler returns the tool handler function for datasets
+func (dh *DatasetsHandler) GetToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args DatasetsArgs) (*mcp.CallToolResult, error) {
+		name, query := args.Name, args.Query
+
+		if name == "" {
+			datasets := dh.GetDatasets()
+			if query != "" {
+				var err error
+				datasets, err = dh.SearchDocuments(ctx, query, nil, 50)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return mcp.NewToolResultText(dh.formatDatasetList(datasets)), nil
+		}
+
+		dataset, ok := dh.GetDataset(name)
+		if !ok {
+			return nil, fmt.Errorf("dataset not found: %s", name)
+		}
+
+		sample := defaultDatasetSample
+		if args.Sample > 0 {
+			sample = args.Sample
+		}
+
+		return mcp.NewToolResultText(dh.formatDataset(dataset, args.Filter, sample)), nil
+	})
+}
+
+// formatDatasetList summarizes datasets with their columns
+func (dh *DatasetsHandler) formatDatasetList(datasets []models.Dataset) string {
+	if len(datasets) == 0 {
+		return "No datasets found. Add CSV or TSV files with a header row to .buddy/datasets/"
+	}
+
+	result := fmt.Sprintf("Found %d datasets\n", len(datasets))
+	for i, dataset := range datasets {
+		result += fmt.Sprintf("\n%d. %s (%d rows)\n", i+1, dataset.Name, len(dataset.Rows))
+		if dataset.Description != "" {
+			description := dataset.Description
+			if idx := strings.Index(description, "\n"); idx > 0 {
+				description = description[:idx]
+			}
+			result += fmt.Sprintf("   %s\n", description)
+		}
+
+		var columns []string
+		for _, col := range dataset.Columns {
+			if col.PII {
+				columns = append(columns, fmt.Sprintf("%s (%s, PII)", col.Name, col.Type))
+				continue
+			}
+			columns = append(columns, fmt.Sprintf("%s (%s)", col.Name, col.Type))
+		}
+		result += fmt.Sprintf("   Columns: %s\n", strings.Join(columns, ", "))
+	}
+
+	result += "\n💡 Pass a dataset name to see its schema and rows"
+	return result
+}
+
+// formatDataset shows a dataset's schema and a sample of its rows, or the
+// rows containing filter when one is given. Personal data columns are
+// masked and never matched by filter, so it can't probe their values.
+func (dh *DatasetsHandler) formatDataset(dataset models.Dataset, filter string, sample int) string {
+	result := fmt.Sprintf("📊 Dataset: %s\n", dataset.Name)
+	result += strings.Repeat("=", 30) + "\n"
+	if dataset.Description != "" {
+		result += "\n" + dataset.Description + "\n"
+	}
+
+	result += "\nColumns:\n"
+	redacted := false
+	for _, col := range dataset.Columns {
+		if col.PII {
+			result += fmt.Sprintf("- %s: %s (PII, redacted)\n", col.Name, col.Type)
+			redacted = true
+			continue
+		}
+		result += fmt.Sprintf("- %s: %s\n", col.Name, col.Type)
+	}
+
+	rows := dataset.Rows
+	if filter != "" {
+		needle := strings.ToLower(filter)
+		var matched [][]string
+		for _, row := range rows {
+			for i, cell := range row {
+				if dataset.Columns[i].PII {
+					continue
+				}
+				if strings.Contains(strings.ToLower(cell), needle) {
+					matched = append(matched, row)
+					break
+				}
+			}
+		}
+		rows = matched
+		result += fmt.Sprintf("\nRows matching %q: %d of %d\n", filter, len(rows), len(dataset.Rows))
+	} else {
+		result += fmt.Sprintf("\nRows: %d\n", len(rows))
+	}
+
+	shown := rows
+	if len(shown) > sample {
+		shown = shown[:sample]
+	}
+	if len(shown) == 0 {
+		return result
+	}
+
+	var header []string
+	for _, col := range dataset.Columns {
+		header = append(header, col.Name)
+	}
+	result += "\n| " + strings.Join(header, " | ") + " |\n"
+	result += "|" + strings.Repeat(" --- |", len(header)) + "\n"
+	for _, row := range shown {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if dataset.Columns[i].PII {
+				cell = redact.Value(cell)
+			}
+			cells[i] = cell
+		}
+		result += "| " + strings.Join(cells, " | ") + " |\n"
+	}
+	if redacted {
+		result += "\n🔒 PII columns are redacted\n"
+	}
+
+	if len(shown) < len(rows) {
+		result += fmt.Sprintf("\n… %d more rows (raise sample or use filter)\n", len(rows)-len(shown))
+	}
+
+	return result
+}
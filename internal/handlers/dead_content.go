@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+)
+
+// skippedProjectDirs are directories never searched for referenced files,
+// besides hidden ones and the buddy folder
+var skippedProjectDirs = map[string]bool{"node_modules": true}
+
+// sourceExtensions mark a slash-separated reference as a file rather than
+// a package directory
+var sourceExtensions = map[string]bool{
+	".go": true, ".mod": true, ".ts": true, ".tsx": true, ".js": true, ".jsx": true, ".mjs": true,
+	".py": true, ".rb": true, ".rs": true, ".java": true, ".kt": true, ".cs": true, ".c": true,
+	".h": true, ".cpp": true, ".hpp": true, ".swift": true, ".php": true, ".scala": true,
+	".sql": true, ".proto": true, ".graphql": true, ".yaml": true, ".yml": true, ".json": true,
+	".toml": true, ".md": true, ".sh": true, ".css": true, ".scss": true, ".html": true,
+	".vue": true, ".svelte": true, ".tf": true,
+}
+
+var (
+	// inlineCode and linkTarget find the spans of a document that may name files
+	inlineCode = regexp.MustCompile("`([^`\n]+)`")
+	linkTarget = regexp.MustCompile(`\]\(([^)\s]+)\)`)
+	// referenceSuffix is a line number or anchor after a file name
+	referenceSuffix = regexp.MustCompile(`(#.*|:\d+(:\d+)?)$`)
+	// referencePath is what a project path may consist of
+	referencePath = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)+$`)
+)
+
+// deadContent is a rule or knowledge document that points at project
+// files which aren't there
+type deadContent struct {
+	Section string // rules or knowledge
+	Path    string // the buddy file
+	Title   string
+	Missing []string // references, or applies_to globs matching no file
+	All     bool     // every applies_to glob of a rule matches nothing
+}
+
+// projectFiles is what a scan of the workspace found
+type projectFiles struct {
+	files    []string        // slash-separated, relative to the root
+	exists   map[string]bool // files and directories, relative to the root
+	dirNames map[string]bool // base names of the directories
+	module   string          // the Go module path from go.mod, if any
+}
+
+// scanProject lists the files of the workspace at root, skipping hidden
+// directories, node_modules and the buddy folder
+func scanProject(root, buddyPath string) (*projectFiles, error) {
+	project := &projectFiles{exists: make(map[string]bool), dirNames: make(map[string]bool)}
+	buddyAbs, _ := filepath.Abs(buddyPath)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") || skippedProjectDirs[d.Name()] || p == buddyAbs {
+				return filepath.SkipDir
+			}
+			project.exists[rel] = true
+			project.dirNames[d.Name()] = true
+			return nil
+		}
+		project.exists[rel] = true
+		project.files = append(project.files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan the project: %w", err)
+	}
+	project.module = goModulePath(filepath.Join(root, "go.mod"))
+	return project, nil
+}
+
+// goModulePath reads the module path from a go.mod file
+func goModulePath(goMod string) string {
+	file, err := os.Open(goMod)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// findDeadContent reports the rules whose applies_to globs match none of
+// the project's files and the knowledge documents that refer to files or
+// packages the project no longer has
+func findDeadContent(project *projectFiles, rules []models.Rule, knowledge []models.Knowledge) []deadContent {
+	var dead []deadContent
+	for _, rule := range rules {
+		var missing []string
+		for _, pattern := range rule.AppliesTo {
+			if !project.matchesAny(pattern) {
+				missing = append(missing, pattern)
+			}
+		}
+		if len(missing) > 0 {
+			dead = append(dead, deadContent{Section: "rules", Path: rule.FilePath, Title: rule.Title,
+				Missing: missing, All: len(missing) == len(rule.AppliesTo)})
+		}
+	}
+	for _, doc := range knowledge {
+		var missing []string
+		for _, ref := range fileReferences(doc.Content) {
+			if !project.resolves(ref, doc.FilePath) && !containsFold(missing, ref) {
+				missing = append(missing, ref)
+			}
+		}
+		if len(missing) > 0 {
+			dead = append(dead, deadContent{Section: "knowledge", Path: doc.FilePath, Title: doc.Title, Missing: missing})
+		}
+	}
+	return dead
+}
+
+// matchesAny reports whether any project file matches the glob
+func (p *projectFiles) matchesAny(pattern string) bool {
+	glob, err := config.CompileGlob(strings.TrimPrefix(filepath.ToSlash(pattern), "./"))
+	if err != nil {
+		return false
+	}
+	for _, file := range p.files {
+		if glob.Match(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileReferences returns the project paths named in a document's inline
+// code and link targets. Only slash-separated names count, since bare
+// file names and words are too ambiguous to check.
+func fileReferences(content string) []string {
+	var refs []string
+	var candidates []string
+	for _, match := range inlineCode.FindAllStringSubmatch(content, -1) {
+		candidates = append(candidates, match[1])
+	}
+	for _, match := range linkTarget.FindAllStringSubmatch(content, -1) {
+		candidates = append(candidates, match[1])
+	}
+	for _, candidate := range candidates {
+		if strings.Contains(candidate, "://") {
+			continue
+		}
+		ref := referenceSuffix.ReplaceAllString(strings.TrimSpace(candidate), "")
+		ref = strings.TrimSuffix(strings.TrimPrefix(ref, "./"), "/")
+		if !referencePath.MatchString(ref) {
+			continue
+		}
+		hidden := false
+		for _, segment := range strings.Split(ref, "/") {
+			if strings.HasPrefix(segment, ".") {
+				hidden = true
+			}
+		}
+		if !hidden {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// resolves reports whether a reference names something in the project,
+// relative to the root, to the document referring to it or to any
+// directory. References that don't look like they point into the project
+// resolve too: package paths need a known first directory, and import
+// paths outside the project's module are skipped.
+func (p *projectFiles) resolves(ref, docPath string) bool {
+	if p.module != "" && (ref == p.module || strings.HasPrefix(ref, p.module+"/")) {
+		ref = strings.TrimPrefix(strings.TrimPrefix(ref, p.module), "/")
+		return ref == "" || p.exists[ref]
+	}
+	first := strings.SplitN(ref, "/", 2)[0]
+	isFile := sourceExtensions[strings.ToLower(path.Ext(ref))]
+	if !p.dirNames[first] && (!isFile || strings.Contains(first, ".")) {
+		return true
+	}
+
+	if p.exists[ref] {
+		return true
+	}
+	if docPath != "" {
+		nextToDoc := filepath.Join(filepath.Dir(docPath), filepath.FromSlash(ref))
+		if _, err := os.Stat(nextToDoc); err == nil {
+			return true
+		}
+	}
+	for existing := range p.exists {
+		if strings.HasSuffix(existing, "/"+ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe says what is dead about a piece of content
+func (dc deadContent) describe() string {
+	if dc.Section == "rules" {
+		if dc.All {
+			return fmt.Sprintf("Rule %q applies to no files; nothing in the project matches %s", dc.Title, strings.Join(dc.Missing, ", "))
+		}
+		return fmt.Sprintf("Rule %q has applies_to globs matching nothing in the project: %s", dc.Title, strings.Join(dc.Missing, ", "))
+	}
+	return fmt.Sprintf("Knowledge %q refers to paths the project no longer has: %s", dc.Title, strings.Join(dc.Missing, ", "))
+}
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/schema"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+)
+
+// Severities of doctor issues
+const (
+	DoctorError   = "error"   // the file is rejected or its content can't be used
+	DoctorWarning = "warning" // the file loads but some of it is ignored or misread
+)
+
+// DoctorIssue is a problem found in one buddy file
+type DoctorIssue struct {
+	Severity string
+	Path     string // relative to the buddy folder
+	Problem  string
+	Fix      string
+}
+
+// DoctorReport is the outcome of checking a buddy folder
+type DoctorReport struct {
+	Checked int // files checked
+	Issues  []DoctorIssue
+}
+
+// Errors returns how many issues are errors
+func (r *DoctorReport) Errors() int {
+	count := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == DoctorError {
+			count++
+		}
+	}
+	return count
+}
+
+// doctor collects issues while a buddy folder is checked
+type doctor struct {
+	buddyPath   string
+	store       storage.Storage
+	maxFileSize int64 // bytes; zero means no limit
+	report      DoctorReport
+}
+
+// rel returns path relative to the buddy folder, slash-separated
+func (d *doctor) rel(path string) string {
+	if rel, err := filepath.Rel(d.buddyPath, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+func (d *doctor) add(severity, path, problem, fix string) {
+	d.report.Issues = append(d.report.Issues, DoctorIssue{
+		Severity: severity,
+		Path:     d.rel(path),
+		Problem:  problem,
+		Fix:      fix,
+	})
+}
+
+// Diagnose checks every file in a buddy folder with the same parsers the
+// server loads them with, reporting the files a load would reject or
+// misread. It reads the files directly and never opens the search
+// indexes, so it can run while a server is using the folder.
+func Diagnose(buddyPath string) (*DoctorReport, error) {
+	return DiagnoseWithStorage(buddyPath, storage.NewLocal())
+}
+
+// DiagnoseWithStorage is Diagnose reading the buddy files through store
+func DiagnoseWithStorage(buddyPath string, store storage.Storage) (*DoctorReport, error) {
+	if info, err := store.Stat(buddyPath); err != nil {
+		return nil, fmt.Errorf("buddy folder not found: %w", err)
+	} else if !info.IsDir {
+		return nil, fmt.Errorf("not a directory: %s", buddyPath)
+	}
+
+	d := &doctor{buddyPath: buddyPath, store: store, maxFileSize: maxFileBytes(0)}
+
+	cfg := config.Default()
+	configPath := config.Path(buddyPath)
+	if _, err := store.Stat(configPath); err == nil {
+		d.report.Checked++
+		if filepath.Base(configPath) == config.FileName {
+			legacyPath := filepath.Join(buddyPath, config.LegacyFileName)
+			if _, err := store.Stat(legacyPath); err == nil {
+				d.add(DoctorWarning, legacyPath, "Ignored, since config.yaml takes precedence", "Move any settings still in it to config.yaml and delete it")
+			}
+		}
+		if loaded, err := config.Load(buddyPath); err != nil {
+			d.add(DoctorError, configPath, err.Error(), "Fix the file; the server refuses to start with an invalid configuration")
+		} else {
+			cfg = loaded
+			d.maxFileSize = maxFileBytes(cfg.Limits.MaxFileKB)
+		}
+	}
+
+	var allRules []models.Rule
+	var allKnowledge []models.Knowledge
+
+	rules := NewRulesHandler(filepath.Join(buddyPath, "rules"), nil)
+	rules.store = store
+	diagnoseDocuments(d, rules.DocumentHandler, func(path string, rule models.Rule) {
+		allRules = append(allRules, rule)
+		if strings.TrimSpace(rule.Title) == "" {
+			d.add(DoctorError, path, "Rule has no title", "Start the file with a '# Title' line")
+		}
+		switch rule.Priority {
+		case "critical", "recommended", "optional":
+		case "":
+			d.add(DoctorWarning, path, "Rule has no priority, so it is never treated as critical", "Add a 'Priority: critical|recommended|optional' line under the title")
+		default:
+			d.add(DoctorWarning, path, fmt.Sprintf("Unknown priority %q", rule.Priority), "Use one of critical, recommended or optional")
+		}
+		if rule.Category == "" {
+			d.add(DoctorWarning, path, "Rule has no category, so category filters skip it", "Add a 'Category: <name>' line under the title")
+		}
+	})
+	_, extendsProblems := rules.resolveExtends(allRules)
+	for _, problem := range extendsProblems {
+		d.add(DoctorError, problem.Path, problem.Err.Error(), "Point Extends at an existing rule and break any loop; until then the rule loads without what it inherits")
+	}
+
+	knowledge := NewKnowledgeHandler(filepath.Join(buddyPath, "knowledge"), nil)
+	knowledge.store = store
+	diagnoseDocuments(d, knowledge.DocumentHandler, func(path string, doc models.Knowledge) {
+		allKnowledge = append(allKnowledge, doc)
+		if strings.TrimSpace(doc.Title) == "" {
+			d.add(DoctorWarning, path, "Document has no title, so it is listed without a name", "Start the file with a '# Title' line (or '= Title' in AsciiDoc)")
+		}
+		if strings.TrimSpace(doc.Content) == "" {
+			d.add(DoctorWarning, path, "Document has no content", "Write the document body below its headers, or delete the file")
+		}
+	})
+
+	todos := NewTodoHandler(filepath.Join(buddyPath, "todos"), nil)
+	todos.store = store
+	diagnoseDocuments(d, todos.DocumentHandler, nil)
+	diagnoseTodoLines(d, todos.DocumentHandler)
+
+	history := NewHistoryHandler(filepath.Join(buddyPath, "history"), nil)
+	history.store = store
+	seenEntries := make(map[string]string)
+	diagnoseDocuments(d, history.DocumentHandler, func(path string, entry models.HistoryEntry) {
+		// Entries without an id fail the history-entry schema when parsed
+		if other, ok := seenEntries[entry.ID]; ok {
+			d.add(DoctorError, path, fmt.Sprintf("History entry id %q is also used by %s", entry.ID, other), "Give one of the entries a new id")
+		} else {
+			seenEntries[entry.ID] = d.rel(path)
+		}
+		if entry.Timestamp.IsZero() {
+			d.add(DoctorWarning, path, "History entry has no timestamp, so it sorts as the oldest", "Add a \"timestamp\" field in RFC 3339 format")
+		}
+		if entry.Feature == "" {
+			d.add(DoctorWarning, path, "History entry has no feature", "Add a \"feature\" field so the entry can be found by feature")
+		}
+	})
+
+	datasets := NewDatasetsHandler(filepath.Join(buddyPath, "datasets"), nil)
+	datasets.store = store
+	diagnoseDocuments(d, datasets.DocumentHandler, nil)
+
+	compliance := NewComplianceHandler(filepath.Join(buddyPath, "compliance"), nil)
+	compliance.store = store
+	diagnoseDocuments(d, compliance.DocumentHandler, func(path string, policy models.CompliancePolicy) {
+		if strings.TrimSpace(policy.Title) == "" {
+			d.add(DoctorWarning, path, "Policy has no title", "Start the file with a '# Title' line")
+		}
+	})
+
+	views := NewViewsHandler(filepath.Join(buddyPath, "views"))
+	views.store = store
+	diagnoseDocuments(d, views.DocumentHandler, nil)
+
+	database := NewDatabaseHandler(filepath.Join(buddyPath, "database"), nil)
+	database.store = store
+	diagnoseDatabase(d, database)
+	diagnoseBackups(d, filepath.Join(buddyPath, "backups"))
+
+	budgetsPath := filepath.Join(buddyPath, budgetsFile)
+	if _, err := store.Stat(budgetsPath); err == nil {
+		d.report.Checked++
+		if err := NewBudgetsHandler(buddyPath, filepath.Join(buddyPath, "budgets"), store).Load(); err != nil {
+			d.add(DoctorError, budgetsPath, err.Error(), "Fix the budget definitions; see the Performance Budgets section of the README")
+		}
+	}
+
+	diagnoseDeadContent(d, cfg, allRules, allKnowledge)
+
+	sort.SliceStable(d.report.Issues, func(i, j int) bool {
+		return d.report.Issues[i].Path < d.report.Issues[j].Path
+	})
+	return &d.report, nil
+}
+
+// diagnoseDocuments parses every file of a document section, reporting
+// the files that fail to parse and passing the documents of the others
+// to check, if given, along with their file
+func diagnoseDocuments[T any](d *doctor, dh *DocumentHandler[T], check func(path string, doc T)) {
+	files, err := dh.store.List(dh.path, dh.spec.Recursive)
+	if err != nil {
+		if !storage.IsNotExist(err) {
+			d.add(DoctorError, dh.path, err.Error(), "Check the directory's permissions")
+		}
+		return
+	}
+
+	for _, file := range files {
+		if !dh.matchesExtension(file.Path) {
+			continue
+		}
+		d.report.Checked++
+
+		content, err := dh.store.Read(file.Path)
+		if err != nil {
+			d.add(DoctorError, file.Path, err.Error(), "Check the file's permissions")
+			continue
+		}
+		if size := int64(len(content)); d.maxFileSize > 0 && size > d.maxFileSize {
+			problem := fmt.Sprintf("File is %d KB, over the %d KB size limit, so it slows loading and search", (size+1023)/1024, d.maxFileSize/1024)
+			if dh.spec.Truncate != nil {
+				problem = fmt.Sprintf("File is %d KB, over the %d KB size limit, so only its beginning is loaded", (size+1023)/1024, d.maxFileSize/1024)
+			}
+			d.add(DoctorWarning, file.Path, problem, "Split it into smaller files, or raise limits.max_file_kb in config.yaml")
+		}
+		docs, err := dh.spec.Parse(file, content)
+		if err != nil {
+			d.add(DoctorError, file.Path, err.Error(), "Fix the file; while it fails to parse, its whole section fails to reload")
+			continue
+		}
+		if check != nil {
+			for _, doc := range docs {
+				check(file.Path, doc)
+			}
+		}
+	}
+}
+
+// diagnoseDeadContent reports the rules and knowledge documents that
+// point at project files which don't exist
+func diagnoseDeadContent(d *doctor, cfg *config.Config, rules []models.Rule, knowledge []models.Knowledge) {
+	if len(rules) == 0 && len(knowledge) == 0 {
+		return
+	}
+	root, err := workspaceRoot(d.buddyPath, cfg)
+	if err != nil {
+		d.add(DoctorWarning, d.buddyPath, err.Error(), "Check paths.root in config.yaml")
+		return
+	}
+	project, err := scanProject(root.Dir(), d.buddyPath)
+	if err != nil {
+		d.add(DoctorWarning, d.buddyPath, err.Error(), "Check paths.root in config.yaml")
+		return
+	}
+	for _, dead := range findDeadContent(project, rules, knowledge) {
+		fix := "Update or remove the references, or delete the document if its subject is gone"
+		if dead.Section == "rules" {
+			fix = "Fix the applies_to globs, or delete the rule if the code it covered is gone"
+		}
+		d.add(DoctorWarning, dead.Path, dead.describe(), fix)
+	}
+}
+
+// looseTaskLine matches lines that look like checklist items
+var looseTaskLine = regexp.MustCompile(`^\s*[-*+]\s*\[.?\]`)
+
+// diagnoseTodoLines reports todo files without tasks and checklist lines
+// the todo parser doesn't recognize, which are silently dropped
+func diagnoseTodoLines(d *doctor, dh *DocumentHandler[models.Todo]) {
+	files, err := dh.store.List(dh.path, dh.spec.Recursive)
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		if !dh.matchesExtension(file.Path) {
+			continue
+		}
+		content, err := dh.store.Read(file.Path)
+		if err != nil {
+			continue
+		}
+
+		tasks := 0
+		for i, line := range strings.Split(string(content), "\n") {
+			if strings.HasPrefix(line, "- [ ]") || strings.HasPrefix(line, "- [x]") {
+				tasks++
+				continue
+			}
+			if looseTaskLine.MatchString(line) {
+				d.add(DoctorWarning, file.Path, fmt.Sprintf("Line %d looks like a task but is ignored: %s", i+1, strings.TrimSpace(line)),
+					"Write tasks as '- [ ] task' or '- [x] task' (lowercase x) at the start of the line")
+			}
+		}
+		if tasks == 0 {
+			d.add(DoctorWarning, file.Path, "File has no tasks", "Add tasks as '- [ ] task' lines under a '# Feature: Name' heading")
+		}
+	}
+}
+
+// diagnoseDatabase checks that a schema file yields tables
+func diagnoseDatabase(d *doctor, dh *DatabaseHandler) {
+	schemaPath := filepath.Join(dh.path, "schema.sql")
+	if _, err := dh.store.Stat(schemaPath); err != nil {
+		return
+	}
+	d.report.Checked++
+
+	tables, err := dh.parseSchema(schemaPath)
+	if err != nil {
+		d.add(DoctorError, schemaPath, err.Error(), "Check the file's permissions")
+		return
+	}
+	if len(tables) == 0 {
+		d.add(DoctorWarning, schemaPath, "No tables found in the schema", "Write tables as 'CREATE TABLE name (...);' statements, each ending with ');'")
+	}
+}
+
+// diagnoseBackups checks that every backup record has its stored file and
+// that every stored backup has a record
+func diagnoseBackups(d *doctor, backupsPath string) {
+	metadataPath := filepath.Join(backupsPath, "metadata.json")
+	var backups []models.Backup
+	if content, err := d.store.Read(metadataPath); err == nil {
+		d.report.Checked++
+		if err := schema.Validate(schema.BackupMetadata, content); err != nil {
+			d.add(DoctorError, metadataPath, "Invalid backup metadata: "+err.Error(), "Fix the records; see buddy://schemas/backup-metadata. Until then no backups are listed")
+			return
+		}
+		if err := json.Unmarshal(content, &backups); err != nil {
+			d.add(DoctorError, metadataPath, "Invalid backup metadata: "+err.Error(), "Fix the JSON, or restore it from a copy; until then no backups are listed")
+			return
+		}
+	} else if !storage.IsNotExist(err) {
+		d.add(DoctorError, metadataPath, err.Error(), "Check the file's permissions")
+		return
+	}
+
+	recorded := make(map[string]bool, len(backups))
+	for _, backup := range backups {
+		recorded[backup.ID] = true
+		// Check by ID rather than the recorded path, which may be relative
+		// to wherever the server was started
+		stored := filepath.Join(backupsPath, backup.ID, filepath.Base(backup.BackupPath))
+		if _, err := d.store.Stat(stored); storage.IsNotExist(err) {
+			d.add(DoctorError, metadataPath, fmt.Sprintf("Backup %s of %s has no stored file", backup.ID, backup.OriginalPath),
+				"Remove the record from backups/metadata.json; the backup can't be restored")
+		}
+	}
+
+	files, err := d.store.List(backupsPath, true)
+	if err != nil {
+		return
+	}
+	orphaned := make(map[string]bool)
+	for _, file := range files {
+		rel, err := filepath.Rel(backupsPath, file.Path)
+		if err != nil {
+			continue
+		}
+		dir, _, nested := strings.Cut(filepath.ToSlash(rel), "/")
+		if !nested || dir == "objects" || recorded[dir] || orphaned[dir] {
+			continue
+		}
+		orphaned[dir] = true
+		d.add(DoctorWarning, filepath.Join(backupsPath, dir), "Orphaned backup: no record in metadata.json refers to it",
+			"Delete the directory, or add its record back to metadata.json")
+	}
+}
@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+)
+
+// DocumentSpec describes how one content type is discovered, parsed,
+// indexed and ordered. A new content type only has to supply a spec and
+// its tool formatting; loading, locking and indexing come from DocumentHandler.
+type DocumentSpec[T any] struct {
+	// IndexType is the search index documents are added to
+	IndexType search.IndexType
+	// Extensions selects which files are parsed, e.g. ".md"
+	Extensions []string
+	// Recursive includes files in subdirectories
+	Recursive bool
+	// Parse turns one file into zero or more documents
+	Parse func(file storage.FileInfo, content []byte) ([]T, error)
+	// ID returns a document's unique ID
+	ID func(T) string
+	// Index converts a document into its search representation
+	Index func(T) interface{}
+	// Less orders documents after loading; nil keeps file order
+	Less func(a, b T) bool
+	// Resolve runs once every file is parsed, before indexing, for
+	// documents that build on each other; it may merge or drop documents.
+	// Optional.
+	Resolve func(docs []T) ([]T, error)
+	// Loaded is called for each document as it is loaded; optional
+	Loaded func(T)
+	// Truncate cuts an oversized file down to limit bytes, adding the
+	// notice where readers will see it. Without it, oversized files are
+	// only reported and load whole.
+	Truncate func(content []byte, limit int64, notice string) []byte
+}
+
+// DocumentHandler loads a directory of files into typed documents and keeps
+// them in sync with the search index
+type DocumentHandler[T any] struct {
+	path          string
+	spec          DocumentSpec[T]
+	docs          []T
+	searchManager *search.SearchManager
+	store         storage.Storage
+	maxFileSize   int64 // bytes; zero means no limit
+	oversized     []OversizedFile
+	mu            sync.RWMutex
+}
+
+// NewDocumentHandler creates a document handler for the given spec
+func NewDocumentHandler[T any](path string, searchManager *search.SearchManager, spec DocumentSpec[T]) *DocumentHandler[T] {
+	return &DocumentHandler[T]{
+		path:          path,
+		spec:          spec,
+		docs:          []T{},
+		searchManager: searchManager,
+		store:         storage.NewLocal(),
+		maxFileSize:   maxFileBytes(0),
+	}
+}
+
+// FileError is a load failure caused by one file. Reloads name the file
+// so it can be reported, and watched for a fix.
+type FileError struct {
+	Op   string // read or load
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("failed to %s %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// Load reads all matching files, replacing the loaded documents and their
+// index entries. Files are parsed without holding the lock so queries keep
+// being served from the previous documents until the new set is swapped in.
+func (dh *DocumentHandler[T]) Load() error {
+	return dh.LoadContext(context.Background())
+}
+
+// LoadContext is Load that stops when ctx is cancelled. Files are parsed
+// before the index is rebuilt, so a cancelled load leaves the previous
+// documents and index entries in place.
+func (dh *DocumentHandler[T]) LoadContext(ctx context.Context) error {
+	loaded := []T{}
+	var oversized []OversizedFile
+	dh.mu.RLock()
+	limit := dh.maxFileSize
+	dh.mu.RUnlock()
+
+	files, err := dh.store.List(dh.path, dh.spec.Recursive)
+	if err != nil && !storage.IsNotExist(err) {
+		return err
+	}
+
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !dh.matchesExtension(file.Path) {
+			continue
+		}
+
+		content, err := dh.store.Read(file.Path)
+		if err != nil {
+			return &FileError{Op: "read", Path: file.Path, Err: err}
+		}
+		if size := int64(len(content)); limit > 0 && size > limit {
+			over := OversizedFile{Path: file.Path, Size: size, Limit: limit}
+			if dh.spec.Truncate != nil {
+				content = dh.spec.Truncate(content, limit, truncationNotice(size, limit))
+				over.Truncated = true
+			}
+			oversized = append(oversized, over)
+			slog.WarnContext(ctx, "buddy file over size limit", "path", file.Path, "size", size, "limit", limit, "truncated", over.Truncated)
+		}
+
+		docs, err := dh.spec.Parse(file, content)
+		if err != nil {
+			return &FileError{Op: "load", Path: file.Path, Err: err}
+		}
+		loaded = append(loaded, docs...)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if dh.spec.Resolve != nil {
+		if loaded, err = dh.spec.Resolve(loaded); err != nil {
+			return err
+		}
+	}
+
+	// Rebuild the index of this type from the parsed documents. Handlers
+	// without a search manager, as used by CLI subcommands, skip indexing.
+	if dh.searchManager != nil {
+		if err := dh.searchManager.ReindexAll(dh.spec.IndexType); err != nil {
+			return fmt.Errorf("failed to reindex %s: %w", dh.spec.IndexType, err)
+		}
+	}
+	for _, doc := range loaded {
+		if dh.spec.Loaded != nil {
+			dh.spec.Loaded(doc)
+		}
+		if dh.searchManager == nil {
+			continue
+		}
+
+		// Index the document in Bleve
+		id := dh.spec.ID(doc)
+		if err := dh.searchManager.IndexDocument(dh.spec.IndexType, id, dh.spec.Index(doc)); err != nil {
+			return fmt.Errorf("failed to index %s %s: %w", dh.spec.IndexType, id, err)
+		}
+	}
+
+	if dh.spec.Less != nil {
+		sort.SliceStable(loaded, func(i, j int) bool {
+			return dh.spec.Less(loaded[i], loaded[j])
+		})
+	}
+
+	dh.setDocuments(loaded, oversized)
+	return nil
+}
+
+// matchesExtension reports whether a file has one of the spec's extensions
+func (dh *DocumentHandler[T]) matchesExtension(path string) bool {
+	for _, ext := range dh.spec.Extensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// setDocuments swaps in a freshly loaded document set
+func (dh *DocumentHandler[T]) setDocuments(docs []T, oversized []OversizedFile) {
+	dh.mu.Lock()
+	defer dh.mu.Unlock()
+	dh.docs = docs
+	dh.oversized = oversized
+}
+
+// SetMaxFileSize sets the size limit in bytes applied from the next load;
+// zero turns it off
+func (dh *DocumentHandler[T]) SetMaxFileSize(limit int64) {
+	dh.mu.Lock()
+	defer dh.mu.Unlock()
+	dh.maxFileSize = limit
+}
+
+// Oversized returns the files over the size limit at the last load
+func (dh *DocumentHandler[T]) Oversized() []OversizedFile {
+	dh.mu.RLock()
+	defer dh.mu.RUnlock()
+	return dh.oversized
+}
+
+// Documents returns all loaded documents
+func (dh *DocumentHandler[T]) Documents() []T {
+	dh.mu.RLock()
+	defer dh.mu.RUnlock()
+	return dh.docs
+}
+
+// Filter returns the loaded documents that match keep
+func (dh *DocumentHandler[T]) Filter(keep func(T) bool) []T {
+	dh.mu.RLock()
+	defer dh.mu.RUnlock()
+
+	var filtered []T
+	for _, doc := range dh.docs {
+		if keep(doc) {
+			filtered = append(filtered, doc)
+		}
+	}
+	return filtered
+}
+
+// Get returns the document with the given ID
+func (dh *DocumentHandler[T]) Get(id string) (T, bool) {
+	dh.mu.RLock()
+	defer dh.mu.RUnlock()
+
+	for _, doc := range dh.docs {
+		if dh.spec.ID(doc) == id {
+			return doc, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// SearchDocuments runs a filtered full-text search and returns matching
+// documents in relevance order
+func (dh *DocumentHandler[T]) SearchDocuments(ctx context.Context, query string, filters map[string]interface{}, limit int) ([]T, error) {
+	searchResults, err := dh.searchManager.SearchWithFiltersContext(ctx, dh.spec.IndexType, query, filters, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	dh.mu.RLock()
+	defer dh.mu.RUnlock()
+
+	byID := make(map[string]T, len(dh.docs))
+	for _, doc := range dh.docs {
+		byID[dh.spec.ID(doc)] = doc
+	}
+
+	var results []T
+	for _, hit := range searchResults.Hits {
+		if doc, ok := byID[hit.ID]; ok {
+			results = append(results, doc)
+		}
+	}
+	return results, nil
+}
+
+// MultiQueryHit is a document matched by one or more queries of a multi-query search
+type MultiQueryHit[T any] struct {
+	Document T        `json:"document"`
+	Queries  []string `json:"queries"`
+	Score    float64  `json:"score"`
+}
+
+// maxSearchQueries bounds the searches one multi-query call runs at once
+const maxSearchQueries = 10
+
+// uniqueQueries returns queries without blank or repeated entries, in
+// order, failing when more than maxSearchQueries remain
+func uniqueQueries(queries []string) ([]string, error) {
+	var unique []string
+	seen := make(map[string]bool, len(queries))
+	for _, query := range queries {
+		query = strings.TrimSpace(query)
+		if query == "" || seen[query] {
+			continue
+		}
+		seen[query] = true
+		unique = append(unique, query)
+	}
+	if len(unique) > maxSearchQueries {
+		return nil, fmt.Errorf("too many queries: %d (at most %d)", len(unique), maxSearchQueries)
+	}
+	return unique, nil
+}
+
+// SearchDocumentsMulti runs several filtered searches in parallel and merges
+// the results. Each document appears once, attributed to every query that
+// matched it; documents matched by more queries rank first, then by best score.
+// Repeated queries run once, and at most maxSearchQueries are accepted.
+func (dh *DocumentHandler[T]) SearchDocumentsMulti(ctx context.Context, queries []string, filters map[string]interface{}, limit int) ([]MultiQueryHit[T], error) {
+	queries, err := uniqueQueries(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	type queryHits struct {
+		ids    []string
+		scores []float64
+		err    error
+	}
+	perQuery := make([]queryHits, len(queries))
+
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			searchResults, err := dh.searchManager.SearchWithFiltersContext(ctx, dh.spec.IndexType, query, filters, limit)
+			if err != nil {
+				perQuery[i].err = fmt.Errorf("search failed for %q: %w", query, err)
+				return
+			}
+			for _, hit := range searchResults.Hits {
+				perQuery[i].ids = append(perQuery[i].ids, hit.ID)
+				perQuery[i].scores = append(perQuery[i].scores, hit.Score)
+			}
+		}(i, query)
+	}
+	wg.Wait()
+
+	for _, hits := range perQuery {
+		if hits.err != nil {
+			return nil, hits.err
+		}
+	}
+
+	dh.mu.RLock()
+	defer dh.mu.RUnlock()
+
+	byID := make(map[string]T, len(dh.docs))
+	for _, doc := range dh.docs {
+		byID[dh.spec.ID(doc)] = doc
+	}
+
+	// Merge in query order so ties keep a deterministic order
+	var merged []*MultiQueryHit[T]
+	seen := make(map[string]*MultiQueryHit[T])
+	for i, hits := range perQuery {
+		for j, id := range hits.ids {
+			doc, ok := byID[id]
+			if !ok {
+				continue
+			}
+			hit, exists := seen[id]
+			if !exists {
+				hit = &MultiQueryHit[T]{Document: doc}
+				seen[id] = hit
+				merged = append(merged, hit)
+			}
+			hit.Queries = append(hit.Queries, queries[i])
+			if hits.scores[j] > hit.Score {
+				hit.Score = hits.scores[j]
+			}
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if len(merged[i].Queries) != len(merged[j].Queries) {
+			return len(merged[i].Queries) > len(merged[j].Queries)
+		}
+		return merged[i].Score > merged[j].Score
+	})
+
+	results := make([]MultiQueryHit[T], 0, len(merged))
+	for _, hit := range merged {
+		results = append(results, *hit)
+	}
+	return results, nil
+}
+
+// ListArgs are the shared "offset", "limit" and "output" arguments of
+// tools returning result lists, for embedding in their arguments
+type ListArgs struct {
+	Offset int    `arg:"offset" desc:"Number of results to skip (optional)"`
+	Limit  int    `arg:"limit" desc:"Maximum number of results to return (optional)"`
+	Output string `arg:"output" enum:"text,json" desc:"Output format: text or json (default: text)"`
+}
+
+// RenderList applies the list arguments to a result list. With
+// output=json the page is returned as a JSON envelope; otherwise format
+// renders it as text.
+func (dh *DocumentHandler[T]) RenderList(docs []T, list ListArgs, format func([]T) string) (string, error) {
+	return renderList(docs, list, format)
+}
+
+// renderList implements RenderList for any item type
+func renderList[T any](docs []T, list ListArgs, format func([]T) string) (string, error) {
+	total := len(docs)
+
+	offset, limit := max(list.Offset, 0), max(list.Limit, 0)
+	page := paginate(docs, offset, limit)
+
+	output := list.Output
+	switch output {
+	case "", "text":
+		result := format(page)
+		if len(page) < total {
+			result += fmt.Sprintf("\n\nShowing %d-%d of %d (use offset and limit to page)", offset+1, offset+len(page), total)
+		}
+		return result, nil
+	case "json":
+		if page == nil {
+			page = []T{}
+		}
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"total":  total,
+			"offset": offset,
+			"items":  page,
+		}, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal results: %w", err)
+		}
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("invalid output: %s (expected text or json)", output)
+	}
+}
+
+// paginate returns the slice window starting at offset; a zero limit means no limit
+func paginate[T any](docs []T, offset, limit int) []T {
+	if offset >= len(docs) {
+		return nil
+	}
+	docs = docs[offset:]
+	if limit > 0 && limit < len(docs) {
+		docs = docs[:limit]
+	}
+	return docs
+}
+
+// documentID derives the ID of a document from its file's path within the
+// section folder dir, so a file keeps its ID wherever the buddy folder is
+// checked out and however its path was given
+func documentID(dir, path string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(relativeTo(dir, path))))
+}
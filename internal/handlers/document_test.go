@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// knowledgeHandler returns a loaded knowledge handler over files, by name
+func knowledgeHandler(t *testing.T, files map[string]string) *KnowledgeHandler {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dir, name), content)
+	}
+	searchManager, err := search.NewSearchManagerIn(filepath.Join(t.TempDir(), "indexes"))
+	require.NoError(t, err)
+	t.Cleanup(func() { searchManager.Close() })
+
+	kh := NewKnowledgeHandler(dir, searchManager)
+	require.NoError(t, kh.Load())
+	return kh
+}
+
+func TestSearchDocumentsMulti_MergesAndAttributes(t *testing.T) {
+	kh := knowledgeHandler(t, map[string]string{
+		"auth.md":   "# Authentication\nCategory: security\n\nSessions are stored in Redis and expire after 24 hours.\n",
+		"cache.md":  "# Caching\nCategory: performance\n\nResponses are cached in Redis for five minutes.\n",
+		"deploy.md": "# Deployment\nCategory: ops\n\nDeploys run from CI on every merge.\n",
+	})
+
+	hits, err := kh.SearchDocumentsMulti(context.Background(), []string{"sessions", "redis", "deploys", " redis "}, nil, 50)
+	require.NoError(t, err)
+	require.Len(t, hits, 3)
+
+	// Matched by two queries, so first; the repeated query counts once
+	assert.Equal(t, "Authentication", hits[0].Document.Title)
+	assert.Equal(t, []string{"sessions", "redis"}, hits[0].Queries)
+
+	attributed := make(map[string][]string)
+	for _, hit := range hits[1:] {
+		assert.Len(t, hit.Queries, 1)
+		assert.Positive(t, hit.Score)
+		attributed[hit.Document.Title] = hit.Queries
+	}
+	assert.Equal(t, map[string][]string{"Caching": {"redis"}, "Deployment": {"deploys"}}, attributed)
+	assert.GreaterOrEqual(t, hits[1].Score, hits[2].Score)
+}
+
+func TestSearchDocumentsMulti_TooManyQueries(t *testing.T) {
+	kh := knowledgeHandler(t, nil)
+
+	var queries []string
+	for i := 0; i <= maxSearchQueries; i++ {
+		queries = append(queries, fmt.Sprintf("query %d", i))
+	}
+	_, err := kh.SearchDocumentsMulti(context.Background(), queries, nil, 50)
+	assert.EqualError(t, err, "too many queries: 11 (at most 10)")
+
+	// Repeats don't count against the limit
+	queries = append(queries[:maxSearchQueries], queries[0], queries[1])
+	hits, err := kh.SearchDocumentsMulti(context.Background(), queries, nil, 50)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+}
+
+func TestUniqueQueries(t *testing.T) {
+	queries, err := uniqueQueries([]string{"auth", " ", "auth ", "redis", "Auth"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"auth", "redis", "Auth"}, queries)
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/slug"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// DraftHandler turns free-form instructions into rule or knowledge drafts
+// that a human can review before moving them into place
+type DraftHandler struct {
+	path  string
+	store storage.Storage
+	files setting[config.Files] // how draft files are named
+}
+
+// NewDraftHandler creates a new draft handler writing drafts through store
+func NewDraftHandler(path string, store storage.Storage) *DraftHandler {
+	return &DraftHandler{
+		path:  path,
+		store: store,
+	}
+}
+
+// ruleSignals are words that suggest an instruction is a rule rather than
+// knowledge. They match whole words, so "user" or "mustard" are no signal.
+var ruleSignals = regexp.MustCompile(`(?i)\b(always|never|must|should|don['’]t|do not|avoid|prefer|use|only)\b`)
+
+// criticalSignals and recommendedSignals pick a rule draft's priority
+var (
+	criticalSignals    = regexp.MustCompile(`(?i)\b(must|never|always)\b`)
+	recommendedSignals = regexp.MustCompile(`(?i)\b(should|prefer|avoid)\b`)
+)
+
+// leadingFillers are stripped from the start of an instruction to derive a title
+var leadingFillers = regexp.MustCompile(`(?i)^(we|our team|the team|please|you)\s+`)
+
+// Draft describes a generated draft file
+type Draft struct {
+	Kind     string
+	Title    string
+	Category string
+	Priority string
+	Content  string
+	FilePath string
+}
+
+// CreateDraft builds a draft from an instruction and writes it to the drafts folder
+func (dh *DraftHandler) CreateDraft(ctx context.Context, instruction, kind, title, category, priority string) (*Draft, error) {
+	instruction = strings.TrimSpace(instruction)
+	if instruction == "" {
+		return nil, fmt.Errorf("instruction is required")
+	}
+
+	if kind == "" {
+		kind = inferDraftKind(instruction)
+	}
+	if kind != "rule" && kind != "knowledge" {
+		return nil, fmt.Errorf("invalid kind: %s (expected rule or knowledge)", kind)
+	}
+	if title == "" {
+		title = deriveDraftTitle(instruction)
+	}
+	if category == "" {
+		category = "general"
+	}
+
+	draft := &Draft{
+		Kind:     kind,
+		Title:    title,
+		Category: category,
+	}
+
+	if kind == "rule" {
+		if priority == "" {
+			priority = inferDraftPriority(instruction)
+		}
+		draft.Priority = priority
+		draft.Content = renderRuleDraft(title, category, priority, instruction)
+	} else {
+		draft.Content = renderKnowledgeDraft(title, category, instruction)
+	}
+
+	filePath, err := dh.WriteDraft(ctx, kind, title, draft.Content)
+	if err != nil {
+		return nil, err
+	}
+	draft.FilePath = filePath
+
+	return draft, nil
+}
+
+// WriteDraft saves draft content under the drafts folder for kind and
+// returns its path; existing drafts are never overwritten
+func (dh *DraftHandler) WriteDraft(ctx context.Context, kind, title, content string) (string, error) {
+	files := dh.files.Load()
+	name, err := slug.FileName(files.NameTemplate, slug.Fields{
+		Slug: slug.Make(title, files.MaxSlugLength, "draft"),
+		Kind: kind,
+		Date: time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(dh.path, kind)
+	filePath := filepath.Join(dir, slug.Unique(name, ".md", func(fileName string) bool {
+		_, err := dh.store.Stat(filepath.Join(dir, fileName))
+		return !storage.IsNotExist(err)
+	}))
+	if err := writeFile(ctx, dh.store, filePath, []byte(content)); err != nil {
+		return "", fmt.Errorf("failed to write draft: %w", err)
+	}
+	return filePath, nil
+}
+
+// inferDraftKind guesses whether an instruction describes a rule or knowledge
+func inferDraftKind(instruction string) string {
+	if ruleSignals.MatchString(instruction) {
+		return "rule"
+	}
+	return "knowledge"
+}
+
+// inferDraftPriority guesses a rule priority from the instruction's wording
+func inferDraftPriority(instruction string) string {
+	switch {
+	case criticalSignals.MatchString(instruction):
+		return "critical"
+	case recommendedSignals.MatchString(instruction):
+		return "recommended"
+	default:
+		return "optional"
+	}
+}
+
+// deriveDraftTitle builds a short title from the first sentence of an instruction
+func deriveDraftTitle(instruction string) string {
+	title := instruction
+	if idx := strings.IndexAny(title, ".\n"); idx > 0 {
+		title = title[:idx]
+	}
+	title = leadingFillers.ReplaceAllString(strings.TrimSpace(title), "")
+
+	words := strings.Fields(title)
+	if len(words) > 8 {
+		words = words[:8]
+	}
+	title = strings.Join(words, " ")
+	if title == "" {
+		return "Untitled"
+	}
+	return strings.ToUpper(title[:1]) + title[1:]
+}
+
+// renderRuleDraft produces a rule file in the format the rules handler loads
+func renderRuleDraft(title, category, priority, instruction string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s\n", title))
+	sb.WriteString(fmt.Sprintf("Category: %s\n", category))
+	sb.WriteString(fmt.Sprintf("Priority: %s\n", priority))
+	sb.WriteString("\n")
+	sb.WriteString("## Rule\n\n")
+	sb.WriteString(instruction + "\n\n")
+	sb.WriteString("## Rationale\n\n")
+	sb.WriteString("TODO: explain why this rule exists.\n\n")
+	sb.WriteString("## Examples\n\n")
+	sb.WriteString("### ✅ Do\n\n```\nTODO: add a compliant example\n```\n\n")
+	sb.WriteString("### ❌ Don't\n\n```\nTODO: add a violating example\n```\n")
+	return sb.String()
+}
+
+// renderKnowledgeDraft produces a knowledge file in the format the knowledge handler loads
+func renderKnowledgeDraft(title, category, instruction string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s\n", title))
+	sb.WriteString(fmt.Sprintf("Category: %s\n", category))
+	sb.WriteString("Tags: \n")
+	sb.WriteString("\n")
+	sb.WriteString("## Overview\n\n")
+	sb.WriteString(instruction + "\n\n")
+	sb.WriteString("## Details\n\n")
+	sb.WriteString("TODO: add background, links and examples.\n")
+	return sb.String()
+}
+
+// DraftArgs are the arguments of buddy_draft
+type DraftArgs struct {
+	Instruction string `arg:"instruction,required" desc:"The convention or fact to capture, e.g. 'we always use zap for logging'"`
+	Kind        string `arg:"kind" enum:"rule,knowledge" desc:"Draft kind: rule or knowledge (optional, inferred from wording)"`
+	Title       string `arg:"title" desc:"Title for the draft (optional, derived from instruction)"`
+	Category    string `arg:"category" desc:"Category for the draft (optional, default: general)"`
+	Priority    string `arg:"priority" enum:"critical,recommended,optional" desc:"Rule priority (optional, inferred from wording)"`
+}
+
+// GetToolHandler returns the tool handler function for drafts
+func (dh *DraftHandler) GetToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args DraftArgs) (*mcp.CallToolResult, error) {
+		draft, err := dh.CreateDraft(ctx, args.Instruction, args.Kind, args.Title, args.Category, args.Priority)
+		if err != nil {
+			return nil, err
+		}
+
+		destination := "rules"
+		if draft.Kind == "knowledge" {
+			destination = "knowledge"
+		}
+
+		result := fmt.Sprintf("📝 Draft %s created\n\n", draft.Kind)
+		result += fmt.Sprintf("Title: %s\n", draft.Title)
+		result += fmt.Sprintf("Category: %s\n", draft.Category)
+		if draft.Priority != "" {
+			result += fmt.Sprintf("Priority: %s\n", draft.Priority)
+		}
+		result += fmt.Sprintf("File: %s\n\n", draft.FilePath)
+		result += strings.Repeat("-", 40) + "\n"
+		result += draft.Content
+		result += strings.Repeat("-", 40) + "\n"
+		result += fmt.Sprintf("\n💡 Review the draft, fill in the TODOs, then move it into .buddy/%s to activate it", destination)
+
+		return mcp.NewToolResultText(result), nil
+	})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferDraftKind(t *testing.T) {
+	for instruction, want := range map[string]string{
+		"Always wrap errors with context":             "rule",
+		"Don't log secrets":                           "rule",
+		"We do not deploy on Fridays":                 "rule",
+		"Use pgx for database access":                 "rule",
+		"The user service owns accounts":              "knowledge",
+		"Mustard is the brand colour":                 "knowledge",
+		"Payments are reconciled nightly by the cron": "knowledge",
+		"Onlyfans-style previews are generated":       "knowledge",
+	} {
+		assert.Equal(t, want, inferDraftKind(instruction), instruction)
+	}
+}
+
+func TestInferDraftPriority(t *testing.T) {
+	for instruction, want := range map[string]string{
+		"You MUST validate input":         "critical",
+		"Never commit .env files":         "critical",
+		"Prefer table-driven tests":       "recommended",
+		"Avoid global state":              "recommended",
+		"Use pgx for database access":     "optional",
+		"Mustard-coloured buttons shown":  "optional",
+		"Avoidance of panics is expected": "optional",
+	} {
+		assert.Equal(t, want, inferDraftPriority(instruction), instruction)
+	}
+}
+
+func TestDeriveDraftTitle(t *testing.T) {
+	assert.Equal(t, "Always wrap errors with context", deriveDraftTitle("we always wrap errors with context. It helps debugging."))
+	assert.Equal(t, "One two three four five six seven eight", deriveDraftTitle("Please one two three four five six seven eight nine ten"))
+	assert.Equal(t, "Untitled", deriveDraftTitle("   "))
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ErrShuttingDown is returned for tool calls that arrive after shutdown began
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// CallDrainer lets shutdown wait for running tool calls. Once closed, new
+// calls are refused, and running calls keep their context when the
+// transport's ends so they can finish; Wait cancels the ones that outlast
+// it.
+type CallDrainer struct {
+	mu       sync.Mutex
+	inflight map[*mcp.CallToolRequest]context.CancelFunc
+	closed   bool
+	idle     chan struct{} // closed once closed and no call is running
+}
+
+// NewCallDrainer creates a drainer. Register its middleware with the MCP
+// server before use.
+func NewCallDrainer() *CallDrainer {
+	return &CallDrainer{
+		inflight: make(map[*mcp.CallToolRequest]context.CancelFunc),
+		idle:     make(chan struct{}),
+	}
+}
+
+// Middleware tracks each tool call while it runs, refusing calls once the
+// drainer is closed
+func (cd *CallDrainer) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// The call's context ends with the transport's unless shutdown has
+		// begun, in which case Wait decides when it ends
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		stop := context.AfterFunc(ctx, func() {
+			cd.mu.Lock()
+			closed := cd.closed
+			cd.mu.Unlock()
+			if !closed {
+				cancel()
+			}
+		})
+		defer stop()
+		defer cancel()
+
+		cd.mu.Lock()
+		if cd.closed {
+			cd.mu.Unlock()
+			return nil, ErrShuttingDown
+		}
+		cd.inflight[&request] = cancel
+		cd.mu.Unlock()
+
+		defer func() {
+			cd.mu.Lock()
+			delete(cd.inflight, &request)
+			cd.signalIdleLocked()
+			cd.mu.Unlock()
+		}()
+
+		return next(callCtx, request)
+	}
+}
+
+// Close stops new tool calls from starting. It can be called more than once.
+func (cd *CallDrainer) Close() {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	cd.closed = true
+	cd.signalIdleLocked()
+}
+
+// signalIdleLocked closes idle when the drainer is closed and no call is
+// running. Callers hold cd.mu.
+func (cd *CallDrainer) signalIdleLocked() {
+	if !cd.closed || len(cd.inflight) > 0 {
+		return
+	}
+	select {
+	case <-cd.idle:
+	default:
+		close(cd.idle)
+	}
+}
+
+// Wait closes the drainer and waits for running tool calls to return. If
+// ctx ends first, the calls still running are cancelled and Wait returns
+// how many there were along with ctx's error.
+func (cd *CallDrainer) Wait(ctx context.Context) (int, error) {
+	cd.Close()
+
+	select {
+	case <-cd.idle:
+		return 0, nil
+	case <-ctx.Done():
+	}
+
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	for _, cancel := range cd.inflight {
+		cancel()
+	}
+	return len(cd.inflight), ctx.Err()
+}
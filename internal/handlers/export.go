@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/redact"
+)
+
+// ExportBundle is a buddy folder's whole state in one document, for
+// sharing with teammates or pasting into other AI tools
+type ExportBundle struct {
+	Project    string                `json:"project"`
+	Feature    string                `json:"feature,omitempty"` // set when narrowed to one feature for a handoff
+	ExportedAt time.Time             `json:"exported_at"`
+	Rules      []models.Rule         `json:"rules"`
+	Knowledge  []models.Knowledge    `json:"knowledge"`
+	Todos      []models.Todo         `json:"todos"`
+	Database   *models.DatabaseInfo  `json:"database,omitempty"`
+	History    []models.HistoryEntry `json:"history"` // newest first
+}
+
+// Export reads a buddy folder into a bundle with up to historyLimit of the
+// newest history entries; zero or less means the resources' default.
+// Like Diagnose it reads the files directly and never opens the search
+// indexes, so it can run while a server is using the folder.
+func Export(buddyPath, project string, historyLimit int) (*ExportBundle, error) {
+	if info, err := os.Stat(buddyPath); err != nil {
+		return nil, fmt.Errorf("buddy folder not found: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", buddyPath)
+	}
+	cfg, err := config.Load(buddyPath)
+	if err != nil {
+		return nil, err
+	}
+	if historyLimit <= 0 {
+		historyLimit = recentHistoryLimit
+	}
+	maxFileSize := maxFileBytes(cfg.Limits.MaxFileKB)
+
+	rulesHandler := NewRulesHandler(filepath.Join(buddyPath, "rules"), nil)
+	knowledgeHandler := NewKnowledgeHandler(filepath.Join(buddyPath, "knowledge"), nil)
+	knowledgeHandler.fields.Store(cfg.Knowledge.Fields)
+	todoHandler := NewTodoHandler(filepath.Join(buddyPath, "todos"), nil)
+	historyHandler := NewHistoryHandler(filepath.Join(buddyPath, "history"), nil)
+	databaseHandler := NewDatabaseHandler(filepath.Join(buddyPath, "database"), nil)
+	databaseHandler.redaction.Store(redact.NewPolicy(cfg.Redaction.Columns))
+
+	rulesHandler.SetMaxFileSize(maxFileSize)
+	knowledgeHandler.SetMaxFileSize(maxFileSize)
+	todoHandler.SetMaxFileSize(maxFileSize)
+	historyHandler.SetMaxFileSize(maxFileSize)
+
+	sections := []struct {
+		name string
+		load func() error
+	}{
+		{"rules", rulesHandler.Load},
+		{"knowledge", knowledgeHandler.Load},
+		{"todos", todoHandler.Load},
+		{"history", historyHandler.Load},
+		{"database", databaseHandler.Load},
+	}
+	for _, section := range sections {
+		if err := section.load(); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", section.name, err)
+		}
+	}
+
+	bundle := &ExportBundle{
+		Project:    project,
+		ExportedAt: time.Now().UTC(),
+		Rules:      rulesHandler.GetRules(),
+		Knowledge:  knowledgeHandler.GetKnowledge(),
+		Todos:      todoHandler.GetTodos(),
+		History:    historyHandler.GetRecentHistory(historyLimit),
+	}
+	if info := databaseHandler.GetDatabaseInfo(); info != nil && (len(info.Tables) > 0 || info.ConnectionInfo != "") {
+		bundle.Database = info
+	}
+	return bundle, nil
+}
+
+// FormatExportMarkdown renders a bundle as one markdown document with a
+// section per kind of content
+func FormatExportMarkdown(bundle *ExportBundle) string {
+	var sb strings.Builder
+	if bundle.Feature != "" {
+		fmt.Fprintf(&sb, "# %s: %s handoff\n\n", bundle.Project, bundle.Feature)
+	} else {
+		fmt.Fprintf(&sb, "# %s: project context\n\n", bundle.Project)
+	}
+	open := 0
+	for _, todo := range bundle.Todos {
+		if !todo.Completed {
+			open++
+		}
+	}
+	tables := 0
+	if bundle.Database != nil {
+		tables = len(bundle.Database.Tables)
+	}
+	fmt.Fprintf(&sb, "Exported %s: %d rules, %d knowledge documents, %d todos (%d open), %d tables, %d recent history entries.\n",
+		bundle.ExportedAt.Format(time.RFC3339), len(bundle.Rules), len(bundle.Knowledge), len(bundle.Todos), open, tables, len(bundle.History))
+
+	if len(bundle.Rules) > 0 {
+		sb.WriteString("\n## Rules\n")
+		for _, rule := range bundle.Rules {
+			fmt.Fprintf(&sb, "\n### %s\n\n", rule.Title)
+			fmt.Fprintf(&sb, "Priority: %s · Category: %s\n\n", rule.Priority, rule.Category)
+			writeExportBody(&sb, rule.Content)
+		}
+	}
+
+	if len(bundle.Knowledge) > 0 {
+		sb.WriteString("\n## Knowledge\n")
+		for _, doc := range bundle.Knowledge {
+			fmt.Fprintf(&sb, "\n### %s\n\n", doc.Title)
+			meta := "Category: " + doc.Category
+			if len(doc.Tags) > 0 {
+				meta += " · Tags: " + strings.Join(doc.Tags, ", ")
+			}
+			fmt.Fprintf(&sb, "%s\n\n", meta)
+			writeExportBody(&sb, doc.Content)
+		}
+	}
+
+	if len(bundle.Todos) > 0 {
+		sb.WriteString("\n## Todos\n")
+		feature := ""
+		for i, todo := range bundle.Todos {
+			if i == 0 || todo.Feature != feature {
+				feature = todo.Feature
+				fmt.Fprintf(&sb, "\n### %s\n\n", feature)
+			}
+			mark := " "
+			if todo.Completed {
+				mark = "x"
+			}
+			fmt.Fprintf(&sb, "- [%s] %s", mark, todo.Task)
+			if todo.Archived {
+				sb.WriteString(" (archived)")
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	if bundle.Database != nil {
+		sb.WriteString("\n## Database Schema\n")
+		if bundle.Database.Type != "" {
+			fmt.Fprintf(&sb, "\nType: %s\n", bundle.Database.Type)
+		}
+		for _, table := range bundle.Database.Tables {
+			fmt.Fprintf(&sb, "\n### %s\n\n", table.Name)
+			if table.Description != "" {
+				fmt.Fprintf(&sb, "%s\n\n", table.Description)
+			}
+			sb.WriteString("| Column | Type | Nullable | Default |\n|---|---|---|---|\n")
+			for _, column := range table.Columns {
+				name := column.Name
+				if column.PII {
+					name += " (personal data)"
+				}
+				fmt.Fprintf(&sb, "| %s | %s | %t | %s |\n", name, column.Type, column.Nullable, column.DefaultValue)
+			}
+			if len(table.ForeignKeys) > 0 {
+				sb.WriteString("\n")
+			}
+			for _, fk := range table.ForeignKeys {
+				fmt.Fprintf(&sb, "- %s references %s.%s\n", fk.Column, fk.RefTable, fk.RefColumn)
+			}
+		}
+	}
+
+	if len(bundle.History) > 0 {
+		if bundle.Feature != "" {
+			sb.WriteString("\n## History\n")
+		} else {
+			sb.WriteString("\n## Recent History\n")
+		}
+		for _, entry := range bundle.History {
+			fmt.Fprintf(&sb, "\n### %s · %s\n\n", entry.Timestamp.Format("2006-01-02"), entry.Feature)
+			fmt.Fprintf(&sb, "%s\n", entry.Description)
+			if entry.Reasoning != "" {
+				fmt.Fprintf(&sb, "\nWhy: %s\n", entry.Reasoning)
+			}
+			if len(entry.Changes) > 0 {
+				sb.WriteString("\n")
+				for _, change := range entry.Changes {
+					fmt.Fprintf(&sb, "- %s %s\n", change.ChangeType, change.FilePath)
+				}
+			}
+		}
+	}
+	return sb.String()
+}
+
+// writeExportBody writes document content followed by a newline
+func writeExportBody(sb *strings.Builder, content string) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return
+	}
+	sb.WriteString(content)
+	sb.WriteString("\n")
+}
@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// columnKind is the broad category of a SQL column type used for fake values
+type columnKind int
+
+const (
+	kindString columnKind = iota
+	kindInteger
+	kindFloat
+	kindBool
+	kindTime
+	kindJSON
+)
+
+// columnKinds maps SQL base type names to their columnKind; types not
+// listed, like INTERVAL, POINT or arrays, are rendered as strings
+var columnKinds = map[string]columnKind{
+	"int": kindInteger, "integer": kindInteger, "tinyint": kindInteger, "smallint": kindInteger,
+	"mediumint": kindInteger, "bigint": kindInteger, "int2": kindInteger, "int4": kindInteger,
+	"int8": kindInteger, "serial": kindInteger, "smallserial": kindInteger, "bigserial": kindInteger,
+	"decimal": kindFloat, "numeric": kindFloat, "float": kindFloat, "float4": kindFloat,
+	"float8": kindFloat, "double": kindFloat, "real": kindFloat, "money": kindFloat,
+	"bool": kindBool, "boolean": kindBool,
+	"date": kindTime, "time": kindTime, "timetz": kindTime, "timestamp": kindTime,
+	"timestamptz": kindTime, "datetime": kindTime,
+	"json": kindJSON, "jsonb": kindJSON,
+}
+
+// classifyColumn maps a SQL column type to a columnKind by its base type
+// name, so "DOUBLE PRECISION" is a float but "POINT" isn't an integer
+func classifyColumn(sqlType string) columnKind {
+	t := strings.ToLower(sqlType)
+	if idx := strings.Index(t, "("); idx >= 0 {
+		t = t[:idx]
+	}
+	words := strings.Fields(t)
+	if len(words) == 0 {
+		return kindString
+	}
+	if kind, ok := columnKinds[words[0]]; ok {
+		return kind
+	}
+	return kindString
+}
+
+// fakeValue produces a deterministic fake value for the n-th row (1-based)
+func fakeValue(col models.Column, n int) interface{} {
+	name := strings.ToLower(col.Name)
+
+	switch classifyColumn(col.Type) {
+	case kindInteger:
+		return n
+	case kindFloat:
+		return float64(n) * 10.5
+	case kindBool:
+		return n%2 == 1
+	case kindTime:
+		return fmt.Sprintf("2024-01-%02dT12:00:00Z", (n-1)%28+1)
+	case kindJSON:
+		return map[string]interface{}{}
+	}
+
+	switch {
+	case strings.Contains(name, "email"):
+		return fmt.Sprintf("user%d@example.com", n)
+	case strings.Contains(name, "uuid") || strings.Contains(strings.ToLower(col.Type), "uuid"):
+		return fmt.Sprintf("00000000-0000-4000-8000-%012d", n)
+	case strings.Contains(name, "url"):
+		return fmt.Sprintf("https://example.com/%s/%d", name, n)
+	case strings.Contains(name, "phone"):
+		return fmt.Sprintf("+1555000%04d", n)
+	case strings.Contains(name, "name"):
+		return fmt.Sprintf("Sample %s %d", strings.ReplaceAll(name, "_", " "), n)
+	default:
+		return fmt.Sprintf("%s_%d", name, n)
+	}
+}
+
+// goFieldName converts a snake_case column name into an exported Go field name
+func goFieldName(column string) string {
+	parts := strings.Split(column, "_")
+	for i, part := range parts {
+		switch strings.ToLower(part) {
+		case "id", "url", "uuid", "api", "ip", "json", "sql":
+			parts[i] = strings.ToUpper(part)
+		default:
+			if part != "" {
+				parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+			}
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// goTypeName maps a column to a Go type, using pointers for nullable columns
+func goTypeName(col models.Column) string {
+	var goType string
+	switch classifyColumn(col.Type) {
+	case kindInteger:
+		goType = "int64"
+	case kindFloat:
+		goType = "float64"
+	case kindBool:
+		goType = "bool"
+	case kindTime:
+		goType = "time.Time"
+	case kindJSON:
+		goType = "json.RawMessage"
+	default:
+		goType = "string"
+	}
+
+	if col.Nullable {
+		return "*" + goType
+	}
+	return goType
+}
+
+// goLiteral renders a fake value as a Go expression for the column's type
+func goLiteral(col models.Column, value interface{}) string {
+	var literal string
+	switch classifyColumn(col.Type) {
+	case kindTime:
+		literal = fmt.Sprintf("mustParseTime(%q)", value)
+	case kindJSON:
+		literal = "json.RawMessage(`{}`)"
+	case kindString:
+		literal = fmt.Sprintf("%q", value)
+	case kindInteger:
+		literal = fmt.Sprintf("int64(%v)", value)
+	default:
+		literal = fmt.Sprintf("%v", value)
+	}
+
+	if col.Nullable {
+		return fmt.Sprintf("ptr(%s)", literal)
+	}
+	return literal
+}
+
+// sqlLiteral renders a fake value as a SQL literal
+func sqlLiteral(col models.Column, value interface{}) string {
+	switch classifyColumn(col.Type) {
+	case kindInteger, kindFloat:
+		return fmt.Sprintf("%v", value)
+	case kindBool:
+		if value.(bool) {
+			return "TRUE"
+		}
+		return "FALSE"
+	case kindJSON:
+		return "'{}'"
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprintf("%v", value), "'", "''") + "'"
+	}
+}
+
+// maxFixtureRows caps the rows generated per call
+const maxFixtureRows = 100
+
+// GenerateFixtures renders count fixture rows for a table in the given format
+// (go, sql or json). count is capped at maxFixtureRows.
+func (dh *DatabaseHandler) GenerateFixtures(tableName, format string, count int) (string, error) {
+	table := dh.GetTableByName(tableName)
+	if table == nil {
+		return "", fmt.Errorf("table '%s' not found in schema", tableName)
+	}
+	if len(table.Columns) == 0 {
+		return "", fmt.Errorf("table '%s' has no parsed columns", tableName)
+	}
+	if count <= 0 {
+		count = 1
+	}
+	if count > maxFixtureRows {
+		count = maxFixtureRows
+	}
+
+	switch format {
+	case "", "json":
+		return dh.fixturesJSON(*table, count)
+	case "sql":
+		return dh.fixturesSQL(*table, count), nil
+	case "go":
+		return dh.fixturesGo(*table, count), nil
+	default:
+		return "", fmt.Errorf("invalid format: %s (expected go, sql or json)", format)
+	}
+}
+
+// fixturesJSON renders fixtures as a JSON array of objects
+func (dh *DatabaseHandler) fixturesJSON(table models.Table, count int) (string, error) {
+	var rows []map[string]interface{}
+	for n := 1; n <= count; n++ {
+		row := make(map[string]interface{})
+		for _, col := range table.Columns {
+			row[col.Name] = fakeValue(col, n)
+		}
+		rows = append(rows, row)
+	}
+
+	data, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// fixturesSQL renders fixtures as INSERT statements
+func (dh *DatabaseHandler) fixturesSQL(table models.Table, count int) string {
+	var names []string
+	for _, col := range table.Columns {
+		names = append(names, col.Name)
+	}
+
+	var sb strings.Builder
+	for n := 1; n <= count; n++ {
+		var values []string
+		for _, col := range table.Columns {
+			values = append(values, sqlLiteral(col, fakeValue(col, n)))
+		}
+		sb.WriteString(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);\n",
+			table.Name, strings.Join(names, ", "), strings.Join(values, ", ")))
+	}
+	return sb.String()
+}
+
+// fixturesGo renders a struct definition and a slice of fixture values,
+// with the imports and helpers they use
+func (dh *DatabaseHandler) fixturesGo(table models.Table, count int) string {
+	typeName := goFieldName(strings.TrimSuffix(table.Name, "s"))
+	if typeName == "" || !unicode.IsLetter(rune(typeName[0])) {
+		// Tables like "s" or "_" leave no usable identifier
+		typeName = "Fixture" + typeName
+	}
+
+	var usesTime, usesJSON, usesPtr bool
+	for _, col := range table.Columns {
+		switch classifyColumn(col.Type) {
+		case kindTime:
+			usesTime = true
+		case kindJSON:
+			usesJSON = true
+		}
+		usesPtr = usesPtr || col.Nullable
+	}
+
+	var sb strings.Builder
+	if usesTime || usesJSON {
+		sb.WriteString("import (\n")
+		if usesJSON {
+			sb.WriteString("\t\"encoding/json\"\n")
+		}
+		if usesTime {
+			sb.WriteString("\t\"time\"\n")
+		}
+		sb.WriteString(")\n\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("type %s struct {\n", typeName))
+	for _, col := range table.Columns {
+		sb.WriteString(fmt.Sprintf("\t%s %s `json:\"%s\" db:\"%s\"`\n",
+			goFieldName(col.Name), goTypeName(col), col.Name, col.Name))
+	}
+	sb.WriteString("}\n\n")
+
+	sb.WriteString(fmt.Sprintf("var %sFixtures = []%s{\n", strings.ToLower(typeName[:1])+typeName[1:], typeName))
+	for n := 1; n <= count; n++ {
+		sb.WriteString("\t{\n")
+		for _, col := range table.Columns {
+			sb.WriteString(fmt.Sprintf("\t\t%s: %s,\n", goFieldName(col.Name), goLiteral(col, fakeValue(col, n))))
+		}
+		sb.WriteString("\t},\n")
+	}
+	sb.WriteString("}\n")
+
+	if usesPtr {
+		sb.WriteString("\nfunc ptr[T any](v T) *T { return &v }\n")
+	}
+	if usesTime {
+		sb.WriteString("\nfunc mustParseTime(s string) time.Time {\n")
+		sb.WriteString("\tt, err := time.Parse(time.RFC3339, s)\n")
+		sb.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+		sb.WriteString("\treturn t\n}\n")
+	}
+
+	return sb.String()
+}
+
+// FixtureArgs are the arguments of buddy_generate_fixtures
+type FixtureArgs struct {
+	TableName string `arg:"table_name,required" desc:"Table to generate fixtures for"`
+	Format    string `arg:"format" enum:"go,sql,json" desc:"Output format: go, sql, json (default: json)"`
+	Count     int    `arg:"count" desc:"Number of fixture rows (default: 3, at most 100)"`
+}
+
+// GetFixtureToolHandler returns the tool handler function for fixture generation
+func (dh *DatabaseHandler) GetFixtureToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args FixtureArgs) (*mcp.CallToolResult, error) {
+		tableName, format := args.TableName, args.Format
+		count := 3
+		if args.Count >= 1 {
+			count = min(args.Count, maxFixtureRows)
+		}
+
+		fixtures, err := dh.GenerateFixtures(tableName, format, count)
+		if err != nil {
+			return nil, err
+		}
+
+		if format == "" {
+			format = "json"
+		}
+
+		result := fmt.Sprintf("Fixtures for %s (%s, %d rows)\n", tableName, format, count)
+		result += strings.Repeat("-", 40) + "\n\n"
+		result += fixtures
+
+		return mcp.NewToolResultText(result), nil
+	})
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyColumn(t *testing.T) {
+	tests := []struct {
+		sqlType string
+		want    columnKind
+	}{
+		{"INT", kindInteger},
+		{"bigint", kindInteger},
+		{"int unsigned", kindInteger},
+		{"SERIAL", kindInteger},
+		{"DECIMAL(10,2)", kindFloat},
+		{"double precision", kindFloat},
+		{"BOOLEAN", kindBool},
+		{"TIMESTAMP WITH TIME ZONE", kindTime},
+		{"date", kindTime},
+		{"jsonb", kindJSON},
+		{"VARCHAR(255)", kindString},
+		{"POINT", kindString},
+		{"INTERVAL", kindString},
+		{"int[]", kindString},
+		{"", kindString},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, classifyColumn(tt.sqlType), tt.sqlType)
+	}
+}
+
+func TestGoFieldName(t *testing.T) {
+	tests := []struct {
+		column string
+		want   string
+	}{
+		{"id", "ID"},
+		{"user_id", "UserID"},
+		{"avatar_url", "AvatarURL"},
+		{"CREATED_AT", "CreatedAt"},
+		{"name", "Name"},
+		{"_", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, goFieldName(tt.column), tt.column)
+	}
+}
+
+// fixtureHandler returns a database handler whose schema holds tables
+func fixtureHandler(t *testing.T, tables ...models.Table) *DatabaseHandler {
+	dh := NewDatabaseHandler(t.TempDir(), nil)
+	dh.dbInfo = &models.DatabaseInfo{Tables: tables}
+	return dh
+}
+
+var usersTable = models.Table{Name: "users", Columns: []models.Column{
+	{Name: "id", Type: "SERIAL"},
+	{Name: "email", Type: "VARCHAR(255)"},
+	{Name: "active", Type: "BOOLEAN"},
+	{Name: "created_at", Type: "TIMESTAMP", Nullable: true},
+}}
+
+func TestGenerateFixtures_Formats(t *testing.T) {
+	dh := fixtureHandler(t, usersTable)
+
+	tests := []struct {
+		format string
+		check  func(t *testing.T, out string)
+	}{
+		{"json", func(t *testing.T, out string) {
+			var rows []map[string]any
+			require.NoError(t, json.Unmarshal([]byte(out), &rows))
+			require.Len(t, rows, 2)
+			assert.Equal(t, float64(2), rows[1]["id"])
+			assert.Equal(t, "user2@example.com", rows[1]["email"])
+			assert.Equal(t, false, rows[1]["active"])
+		}},
+		{"sql", func(t *testing.T, out string) {
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			require.Len(t, lines, 2)
+			assert.Equal(t, "INSERT INTO users (id, email, active, created_at) VALUES (1, 'user1@example.com', TRUE, '2024-01-01T12:00:00Z');", lines[0])
+		}},
+		{"go", func(t *testing.T, out string) {
+			assert.True(t, strings.HasPrefix(out, "import (\n\t\"time\"\n)\n"), out)
+			assert.Contains(t, out, "type User struct {\n")
+			assert.Contains(t, out, "\tCreatedAt *time.Time `json:\"created_at\" db:\"created_at\"`\n")
+			assert.Contains(t, out, "var userFixtures = []User{\n")
+			assert.Contains(t, out, "\t\tCreatedAt: ptr(mustParseTime(\"2024-01-02T12:00:00Z\")),\n")
+			assert.Contains(t, out, "func ptr[T any](v T) *T")
+			assert.Contains(t, out, "func mustParseTime(s string) time.Time")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out, err := dh.GenerateFixtures("users", tt.format, 2)
+			require.NoError(t, err)
+			tt.check(t, out)
+		})
+	}
+
+	_, err := dh.GenerateFixtures("users", "yaml", 1)
+	assert.EqualError(t, err, "invalid format: yaml (expected go, sql or json)")
+}
+
+func TestGenerateFixtures_GoEdgeCases(t *testing.T) {
+	dh := fixtureHandler(t,
+		models.Table{Name: "s", Columns: []models.Column{{Name: "label", Type: "TEXT"}}},
+		models.Table{Name: "_", Columns: []models.Column{{Name: "label", Type: "TEXT"}}},
+	)
+
+	for _, name := range []string{"s", "_"} {
+		out, err := dh.GenerateFixtures(name, "go", 1)
+		require.NoError(t, err, name)
+		assert.Contains(t, out, "type Fixture struct {\n", name)
+		// Nothing needs importing or the helpers
+		assert.NotContains(t, out, "import", name)
+		assert.NotContains(t, out, "func ", name)
+	}
+}
+
+func TestGenerateFixtures_CapsCount(t *testing.T) {
+	dh := fixtureHandler(t, usersTable)
+
+	out, err := dh.GenerateFixtures("users", "sql", 10000)
+	require.NoError(t, err)
+	assert.Equal(t, maxFixtureRows, strings.Count(out, "INSERT INTO"))
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/timeutil"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// focusFile holds the current focus, in the buddy folder
+const focusFile = "focus.json"
+
+// ignoreFocusArgument turns the focus off for one read tool call
+const ignoreFocusArgument = "ignore_focus"
+
+// Focus is the features currently being worked on. Read tools narrow
+// their lists to them and rank content about them first.
+type Focus struct {
+	Features []string  `json:"features"`
+	Note     string    `json:"note,omitempty"`
+	SetAt    time.Time `json:"set_at"`
+}
+
+// FocusStore keeps the focus in focus.json, so it outlives the session
+// and is shared by every client of the project
+type FocusStore struct {
+	path  string
+	store storage.Storage
+	mu    sync.RWMutex
+	focus Focus
+}
+
+// NewFocusStore creates a focus store kept in path
+func NewFocusStore(path string, store storage.Storage) *FocusStore {
+	return &FocusStore{path: path, store: store}
+}
+
+// Load reads the focus file; a missing file means no focus
+func (fs *FocusStore) Load() error {
+	var focus Focus
+	content, err := fs.store.Read(fs.path)
+	if err != nil && !storage.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, &focus); err != nil {
+			return fmt.Errorf("invalid %s: %w", focusFile, err)
+		}
+	}
+	fs.mu.Lock()
+	fs.focus = focus
+	fs.mu.Unlock()
+	return nil
+}
+
+// Get returns the current focus
+func (fs *FocusStore) Get() Focus {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	focus := fs.focus
+	focus.Features = append([]string(nil), fs.focus.Features...)
+	return focus
+}
+
+// Set replaces the focus and saves it, so the tool call can be undone; no
+// features clears it
+func (fs *FocusStore) Set(ctx context.Context, features []string, note string) (Focus, error) {
+	focus := Focus{Features: cleanFeatures(features), Note: strings.TrimSpace(note)}
+	if len(focus.Features) == 0 {
+		focus = Focus{}
+	} else {
+		focus.SetAt = time.Now().UTC()
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if len(focus.Features) == 0 {
+		if err := removeFile(ctx, fs.store, fs.path); err != nil && !storage.IsNotExist(err) {
+			return fs.focus, fmt.Errorf("failed to clear focus: %w", err)
+		}
+	} else {
+		content, err := json.MarshalIndent(focus, "", "  ")
+		if err != nil {
+			return fs.focus, fmt.Errorf("failed to marshal focus: %w", err)
+		}
+		if err := writeFile(ctx, fs.store, fs.path, append(content, '\n')); err != nil {
+			return fs.focus, fmt.Errorf("failed to save focus: %w", err)
+		}
+	}
+	fs.focus = focus
+	return focus, nil
+}
+
+// cleanFeatures trims feature names and drops blanks and duplicates
+func cleanFeatures(features []string) []string {
+	var cleaned []string
+	for _, feature := range features {
+		feature = strings.TrimSpace(feature)
+		if feature == "" || containsFold(cleaned, feature) {
+			continue
+		}
+		cleaned = append(cleaned, feature)
+	}
+	return cleaned
+}
+
+// containsFold reports whether list holds s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active returns the focused features a read tool call should apply:
+// none when the store is nil or ignore, the call's ignore_focus argument,
+// is set
+func (fs *FocusStore) Active(ignore bool) []string {
+	if fs == nil || ignore {
+		return nil
+	}
+	return fs.Get().Features
+}
+
+// focusNote tells the reader a list was shaped by the focus
+func focusNote(features []string, narrowed bool) string {
+	if len(features) == 0 {
+		return ""
+	}
+	effect := "listed first"
+	if narrowed {
+		effect = "only"
+	}
+	return fmt.Sprintf("🎯 Focus: %s (%s; pass %s to see everything)\n\n", strings.Join(features, ", "), effect, ignoreFocusArgument)
+}
+
+// focusedFirst moves the items that match the focus to the front,
+// keeping the order within both groups
+func focusedFirst[T any](items []T, matches func(T) bool) []T {
+	sorted := append([]T(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return matches(sorted[i]) && !matches(sorted[j])
+	})
+	return sorted
+}
+
+// mentionsAny reports whether text mentions any of the features, ignoring case
+func mentionsAny(text string, features []string) bool {
+	text = strings.ToLower(text)
+	for _, feature := range features {
+		if strings.Contains(text, strings.ToLower(feature)) {
+			return true
+		}
+	}
+	return false
+}
+
+// FocusArgs are the arguments of buddy_focus
+type FocusArgs struct {
+	Action   string   `arg:"action" enum:"get,set,add,clear" desc:"get (default), set, add or clear"`
+	Features []string `arg:"features" desc:"Feature names, as used in todos and history (required for set and add)"`
+	Note     string   `arg:"note" desc:"What the focus is for, e.g. a ticket or goal (optional for set and add)"`
+}
+
+// GetFocusToolHandler returns the tool handler that shows and sets the focus
+func (bh *BuddyHandlers) GetFocusToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(bh.focusTool)
+}
+
+func (bh *BuddyHandlers) focusTool(ctx context.Context, args FocusArgs) (*mcp.CallToolResult, error) {
+	features, note := args.Features, args.Note
+	current := bh.focus.Get()
+	switch args.Action {
+	case "", "get":
+		return mcp.NewToolResultText(formatFocus(current, bh.timeFormat)), nil
+	case "set":
+		if len(cleanFeatures(features)) == 0 {
+			return nil, fmt.Errorf("features is required for set action; use clear to remove the focus")
+		}
+	case "add":
+		if len(cleanFeatures(features)) == 0 {
+			return nil, fmt.Errorf("features is required for add action")
+		}
+		features = append(current.Features, features...)
+		if note == "" {
+			note = current.Note
+		}
+	case "clear":
+		features, note = nil, ""
+	default:
+		return nil, fmt.Errorf("unknown action %q (expected get, set, add or clear)", args.Action)
+	}
+
+	focus, err := bh.focus.Set(ctx, features, note)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(formatFocus(focus, bh.timeFormat)), nil
+}
+
+// formatFocus describes the focus for the tool's answer
+func formatFocus(focus Focus, timeFormat *timeutil.Formatter) string {
+	if len(focus.Features) == 0 {
+		return "No focus set; read tools show everything. Set one with action set and the features you are working on."
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🎯 Current focus: %s\n", strings.Join(focus.Features, ", "))
+	if focus.Note != "" {
+		fmt.Fprintf(&sb, "Note: %s\n", focus.Note)
+	}
+	fmt.Fprintf(&sb, "Set: %s\n", timeFormat.Format(focus.SetAt))
+	sb.WriteString("\nTodo and history lists show only these features, and knowledge, todo and history searches rank them first. Pass ignore_focus to a read tool to see everything.\n")
+	return sb.String()
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DirtyFile is a file with uncommitted git modifications
+type DirtyFile struct {
+	Path   string
+	Status string
+}
+
+// gitWorkTree returns the top directory of the git work tree holding
+// path, found by looking for .git in path's nearest existing directory and
+// its parents, or "" when path isn't in one
+func gitWorkTree(path string) string {
+	dir := filepath.Dir(path)
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// gitStatuses runs git status once for the work tree at top and returns
+// the porcelain status line of every changed or untracked file, by path
+// relative to top in slash form
+func gitStatuses(top string) (map[string]string, error) {
+	cmd := exec.Command("git", "-C", top, "status", "--porcelain", "-z", "--untracked-files=all")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, err
+	}
+
+	statuses := make(map[string]string)
+	entries := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		statuses[entry[3:]] = entry
+		// Renames and copies are followed by the original path
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+	}
+	return statuses, nil
+}
+
+// findDirtyFiles returns the files among paths that have uncommitted
+// changes. git runs once per work tree, however many files it holds;
+// files outside any work tree have nothing uncommitted. When git can't
+// tell, because it is missing or fails, an error says the paths couldn't
+// be verified rather than reporting them clean.
+func findDirtyFiles(paths []string) ([]DirtyFile, error) {
+	byTree := make(map[string][]string)
+	var trees []string
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, &GitCheckError{Path: path, Err: err}
+		}
+		top := gitWorkTree(absPath)
+		if top == "" {
+			continue
+		}
+		if _, ok := byTree[top]; !ok {
+			trees = append(trees, top)
+		}
+		byTree[top] = append(byTree[top], absPath)
+	}
+
+	var dirty []DirtyFile
+	for _, top := range trees {
+		statuses, err := gitStatuses(top)
+		if err != nil {
+			return nil, &GitCheckError{Path: top, Err: err}
+		}
+		for _, path := range byTree[top] {
+			rel, err := filepath.Rel(top, path)
+			if err != nil {
+				return nil, &GitCheckError{Path: path, Err: err}
+			}
+			if status, ok := statuses[filepath.ToSlash(rel)]; ok {
+				dirty = append(dirty, DirtyFile{Path: path, Status: status})
+			}
+		}
+	}
+	return dirty, nil
+}
+
+// checkUncommitted returns a *DirtyFilesError when any of paths has
+// uncommitted changes, or a *GitCheckError when that can't be told
+func checkUncommitted(paths []string) error {
+	dirty, err := findDirtyFiles(paths)
+	if err != nil {
+		return err
+	}
+	if len(dirty) > 0 {
+		return &DirtyFilesError{Files: dirty}
+	}
+	return nil
+}
+
+// GitCheckError reports that a restore couldn't verify its files have no
+// uncommitted changes
+type GitCheckError struct {
+	Path string
+	Err  error
+}
+
+func (e *GitCheckError) Error() string {
+	return fmt.Sprintf("⚠️ Could not verify %s has no uncommitted git changes: %v\nPass force: true to restore without the check", e.Path, e.Err)
+}
+
+func (e *GitCheckError) Unwrap() error {
+	return e.Err
+}
+
+// DirtyFilesError reports that a restore would overwrite uncommitted work
+type DirtyFilesError struct {
+	Files []DirtyFile
+}
+
+func (e *DirtyFilesError) Error() string {
+	result := "⚠️ Refusing to restore over uncommitted git changes:\n"
+	for _, file := range e.Files {
+		result += fmt.Sprintf("  %s\n", file.Status)
+	}
+	result += "Commit or stash these changes, or pass force: true to overwrite them"
+	return result
+}
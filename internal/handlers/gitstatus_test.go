@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gitRepo creates a repository with committed files and returns its path
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	git("init", "-q")
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(name)), content)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	return dir
+}
+
+func TestFindDirtyFiles(t *testing.T) {
+	repo := gitRepo(t, map[string]string{
+		"clean.go":       "package main\n",
+		"modified.go":    "package main\n",
+		"pkg/renamed.go": "package pkg\n",
+	})
+	writeTestFile(t, filepath.Join(repo, "modified.go"), "package main // edited\n")
+	writeTestFile(t, filepath.Join(repo, "pkg", "new file.go"), "package pkg\n")
+	require.NoError(t, exec.Command("git", "-C", repo, "mv", "pkg/renamed.go", "pkg/moved.go").Run())
+
+	paths := []string{
+		filepath.Join(repo, "clean.go"),
+		filepath.Join(repo, "modified.go"),
+		filepath.Join(repo, "pkg", "new file.go"),
+		filepath.Join(repo, "pkg", "moved.go"),
+		filepath.Join(repo, "gone", "deeper", "missing.go"),
+	}
+	dirty, err := findDirtyFiles(paths)
+	require.NoError(t, err)
+
+	statuses := make(map[string]string)
+	for _, file := range dirty {
+		statuses[file.Path] = file.Status
+	}
+	assert.Equal(t, map[string]string{
+		paths[1]: " M modified.go",
+		paths[2]: "?? pkg/new file.go",
+		paths[3]: "R  pkg/moved.go",
+	}, statuses)
+}
+
+func TestFindDirtyFiles_OutsideWorkTree(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.go"), "package a\n")
+
+	dirty, err := findDirtyFiles([]string{filepath.Join(dir, "a.go")})
+	require.NoError(t, err)
+	assert.Empty(t, dirty)
+}
+
+func TestCheckUncommitted(t *testing.T) {
+	repo := gitRepo(t, map[string]string{"a.go": "package a\n"})
+	path := filepath.Join(repo, "a.go")
+	require.NoError(t, checkUncommitted([]string{path}))
+
+	writeTestFile(t, path, "package a // edited\n")
+	var dirtyErr *DirtyFilesError
+	require.ErrorAs(t, checkUncommitted([]string{path}), &dirtyErr)
+	assert.Equal(t, path, dirtyErr.Files[0].Path)
+
+	// A work tree git can't read isn't reported clean
+	broken := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(broken, ".git"), 0755))
+	var checkErr *GitCheckError
+	require.ErrorAs(t, checkUncommitted([]string{filepath.Join(broken, "a.go")}), &checkErr)
+	assert.Equal(t, broken, checkErr.Path)
+	assert.Contains(t, checkErr.Error(), "Could not verify")
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// FeatureBundle narrows a bundle to what someone taking over a feature
+// needs: its todos and history entries, the knowledge mentioning it or the
+// files its history touched, the rules about it or applying to those
+// files, and the tables any of these mention. Features match ignoring
+// case. The result is an export bundle, so it imports like one.
+func FeatureBundle(bundle *ExportBundle, feature string) *ExportBundle {
+	features := []string{feature}
+	handoff := &ExportBundle{
+		Project:    bundle.Project,
+		Feature:    feature,
+		ExportedAt: bundle.ExportedAt,
+	}
+
+	var corpus []string
+	for _, todo := range bundle.Todos {
+		if strings.EqualFold(todo.Feature, feature) {
+			handoff.Todos = append(handoff.Todos, todo)
+			corpus = append(corpus, todo.Task)
+		}
+	}
+	touched := make(map[string]bool)
+	for _, entry := range bundle.History {
+		if !strings.EqualFold(entry.Feature, feature) {
+			continue
+		}
+		handoff.History = append(handoff.History, entry)
+		corpus = append(corpus, entry.Description, entry.Reasoning)
+		for _, change := range entry.Changes {
+			touched[filepath.ToSlash(change.FilePath)] = true
+			corpus = append(corpus, change.Before, change.After)
+		}
+	}
+
+	for _, doc := range bundle.Knowledge {
+		about := strings.Join(append([]string{doc.Title, doc.Category, doc.Content}, doc.Tags...), "\n")
+		if mentionsAny(about, features) || mentionsFile(doc.Content, touched) {
+			handoff.Knowledge = append(handoff.Knowledge, doc)
+			corpus = append(corpus, doc.Content)
+		}
+	}
+	for _, rule := range bundle.Rules {
+		if mentionsAny(rule.Title+"\n"+rule.Category+"\n"+rule.Content, features) || appliesToAny(rule, touched) {
+			handoff.Rules = append(handoff.Rules, rule)
+		}
+	}
+
+	if bundle.Database != nil {
+		text := strings.Join(corpus, "\n")
+		var tables []models.Table
+		for _, table := range bundle.Database.Tables {
+			if mentionsWord(text, table.Name) {
+				tables = append(tables, table)
+			}
+		}
+		if len(tables) > 0 {
+			database := *bundle.Database
+			database.Tables = tables
+			handoff.Database = &database
+		}
+	}
+	return handoff
+}
+
+// mentionsFile reports whether text names any of the files, by path or,
+// failing that, by file name
+func mentionsFile(text string, files map[string]bool) bool {
+	for file := range files {
+		if strings.Contains(text, file) || strings.Contains(text, filepath.Base(file)) {
+			return true
+		}
+	}
+	return false
+}
+
+// appliesToAny reports whether any of a rule's applies_to globs matches
+// one of the files
+func appliesToAny(rule models.Rule, files map[string]bool) bool {
+	for _, pattern := range rule.AppliesTo {
+		glob, err := config.CompileGlob(pattern)
+		if err != nil {
+			continue
+		}
+		for file := range files {
+			if glob.Match(file) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mentionsWord reports whether text holds word as a whole word, ignoring case
+func mentionsWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	return regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(word) + `\b`).MatchString(text)
+}
+
+// knownFeatures lists the features named by todos and history, sorted
+func knownFeatures(snap *ContextSnapshot) []string {
+	seen := make(map[string]bool)
+	var features []string
+	add := func(feature string) {
+		if feature != "" && !seen[strings.ToLower(feature)] {
+			seen[strings.ToLower(feature)] = true
+			features = append(features, feature)
+		}
+	}
+	for _, todo := range snap.Todos {
+		add(todo.Feature)
+	}
+	for _, entry := range snap.History {
+		add(entry.Feature)
+	}
+	sort.Strings(features)
+	return features
+}
+
+// HandoffArgs are the arguments of buddy_handoff
+type HandoffArgs struct {
+	Feature string `arg:"feature,required" desc:"The feature, as named in todos and history (case-insensitive)"`
+	Format  string `arg:"format" enum:"markdown,json" desc:"markdown (default) to read or share, json to load with buddy-mcp import"`
+}
+
+// GetHandoffToolHandler returns the tool handler that packages a feature
+// for handing it over
+func (bh *BuddyHandlers) GetHandoffToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args HandoffArgs) (*mcp.CallToolResult, error) {
+		feature := strings.TrimSpace(args.Feature)
+		if feature == "" {
+			return nil, fmt.Errorf("feature is required")
+		}
+		format := args.Format
+
+		snap := bh.Snapshot()
+		project := filepath.Base(bh.backupHandler.root.Dir())
+		bundle := FeatureBundle(&ExportBundle{
+			Project:    project,
+			ExportedAt: time.Now().UTC(),
+			Rules:      snap.Rules,
+			Knowledge:  snap.Knowledge,
+			Todos:      snap.Todos,
+			Database:   snap.Database,
+			History:    snap.History,
+		}, feature)
+		if len(bundle.Todos) == 0 && len(bundle.History) == 0 {
+			known := knownFeatures(snap)
+			if len(known) == 0 {
+				return nil, fmt.Errorf("no todos or history for feature %q, and none for any other feature", feature)
+			}
+			return nil, fmt.Errorf("no todos or history for feature %q (known features: %s)", feature, strings.Join(known, ", "))
+		}
+
+		switch format {
+		case "", "markdown":
+			return mcp.NewToolResultText(FormatExportMarkdown(bundle)), nil
+		case "json":
+			content, err := json.MarshalIndent(bundle, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal handoff bundle: %w", err)
+			}
+			return mcp.NewToolResultText(string(content)), nil
+		default:
+			return nil, fmt.Errorf("unknown format %q (expected markdown or json)", format)
+		}
+	})
+}
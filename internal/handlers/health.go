@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Health statuses
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// HealthCheck is the outcome of one health check
+type HealthCheck struct {
+	Name    string `json:"name"`
+	Project string `json:"project,omitempty"`
+	OK      bool   `json:"ok"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// HealthReport is the outcome of every health check. The status is
+// degraded when any check failed.
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// healthProbe is a check registered with AddCheck
+type healthProbe struct {
+	name  string
+	check func() error
+}
+
+// HealthChecker reports whether the server can serve its projects: their
+// search indexes are open, their buddy folders readable, and any checks
+// added for background work pass. It backs the buddy_health tool and an
+// HTTP endpoint for container orchestrators.
+type HealthChecker struct {
+	projects *Projects
+	mu       sync.Mutex
+	probes   []healthProbe
+}
+
+// NewHealthChecker creates a health checker for projects
+func NewHealthChecker(projects *Projects) *HealthChecker {
+	return &HealthChecker{projects: projects}
+}
+
+// AddCheck registers a further check, such as whether file monitoring is
+// still running. A nil error means healthy.
+func (hc *HealthChecker) AddCheck(name string, check func() error) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.probes = append(hc.probes, healthProbe{name: name, check: check})
+}
+
+// Check runs every health check
+func (hc *HealthChecker) Check() HealthReport {
+	var checks []HealthCheck
+	for _, project := range hc.projects.List() {
+		for _, check := range project.Handlers.HealthChecks() {
+			check.Project = project.Name
+			checks = append(checks, check)
+		}
+	}
+
+	hc.mu.Lock()
+	probes := append([]healthProbe(nil), hc.probes...)
+	hc.mu.Unlock()
+	for _, probe := range probes {
+		check := HealthCheck{Name: probe.name, OK: true}
+		if err := probe.check(); err != nil {
+			check.OK = false
+			check.Detail = err.Error()
+		}
+		checks = append(checks, check)
+	}
+
+	report := HealthReport{Status: HealthOK, Checks: checks}
+	for _, check := range checks {
+		if !check.OK {
+			report.Status = HealthDegraded
+			break
+		}
+	}
+	return report
+}
+
+// HealthChecks checks that the project's search indexes are open, its
+// buddy folder can be read and no section has used up its reload failure
+// budget
+func (bh *BuddyHandlers) HealthChecks() []HealthCheck {
+	indexes := HealthCheck{Name: "indexes", OK: true}
+	if failed := bh.searchManager.CheckIndexes(); len(failed) > 0 {
+		problems := make([]string, 0, len(failed))
+		for indexType, err := range failed {
+			problems = append(problems, fmt.Sprintf("%s: %v", indexType, err))
+		}
+		sort.Strings(problems)
+		indexes.OK = false
+		indexes.Detail = strings.Join(problems, "; ")
+	}
+
+	buddyPath := HealthCheck{Name: "buddy_path", OK: true}
+	if _, err := os.ReadDir(bh.buddyPath); err != nil {
+		buddyPath.OK = false
+		buddyPath.Detail = err.Error()
+	}
+
+	// A section past its budget serves stale content until it is fixed
+	reloads := HealthCheck{Name: "reloads", OK: true}
+	var failing []string
+	for _, name := range bh.reloadOrder {
+		if failures, path, open := bh.reloaders[name].tripped(); open {
+			detail := fmt.Sprintf("%s: %d failed reloads", name, failures)
+			if path != "" {
+				detail += " on " + bh.relativeBuddyPath(path)
+			}
+			failing = append(failing, detail)
+		}
+	}
+	if len(failing) > 0 {
+		reloads.OK = false
+		reloads.Detail = strings.Join(failing, "; ")
+	}
+
+	return []HealthCheck{indexes, buddyPath, reloads}
+}
+
+// ServeHTTP answers with the health report as JSON: 200 when healthy, 503
+// when degraded
+func (hc *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := hc.Check()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != HealthOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetToolHandler returns the buddy_health tool handler
+func (hc *HealthChecker) GetToolHandler() server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(formatHealthReport(hc.Check())), nil
+	}
+}
+
+// formatHealthReport lists each check, failing ones with their reason
+func formatHealthReport(report HealthReport) string {
+	var sb strings.Builder
+	failing := 0
+	for _, check := range report.Checks {
+		if !check.OK {
+			failing++
+		}
+	}
+	if failing == 0 {
+		fmt.Fprintf(&sb, "🩺 Health: %s\n\n", report.Status)
+	} else {
+		fmt.Fprintf(&sb, "🩺 Health: %s (%d of %d checks failing)\n\n", report.Status, failing, len(report.Checks))
+	}
+
+	for _, check := range report.Checks {
+		status := "✅"
+		if !check.OK {
+			status = "❌"
+		}
+		name := check.Name
+		if check.Project != "" {
+			name = fmt.Sprintf("%s (%s)", name, check.Project)
+		}
+		if check.Detail != "" {
+			fmt.Fprintf(&sb, "%s %s: %s\n", status, name, check.Detail)
+		} else {
+			fmt.Fprintf(&sb, "%s %s\n", status, name)
+		}
+	}
+	return sb.String()
+}
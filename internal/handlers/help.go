@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// toolExamples holds example arguments for each tool, shown by buddy_help
+var toolExamples = map[string][]map[string]interface{}{
+	"buddy_get_rules": {
+		{"priority": "critical"},
+		{"category": "go", "output": "json"},
+		{"priority": "critical", "max_tokens": 500},
+		{"file_path": "internal/handlers/rules.go"},
+		{"tags": []string{"security"}},
+		{"action": "test"},
+		{"action": "lint", "max_length": 2000},
+		{"action": "create", "title": "Wrap Errors", "category": "errors", "priority": "recommended", "content": "Wrap returned errors with fmt.Errorf and %w.", "applies_to": []string{"**/*.go"}},
+		{"action": "update", "rule": "Wrap Errors", "priority": "critical"},
+		{"action": "update", "rule": "Wrap Errors", "tags": []string{"reliability", "observability"}},
+		{"action": "create", "title": "Wrap Errors in Handlers", "extends": "wrap-errors.md", "content": "Name the tool in the message."},
+		{"action": "delete", "rule": "wrap-errors.md"},
+		{"action": "disable", "rule": "wrap-errors.md"},
+		{"action": "history", "rule": "Wrap Errors"},
+		{"action": "templates"},
+		{"action": "create_from_template", "template": "testing-policy", "values": map[string]string{"test_command": "go test ./...", "coverage": "85"}},
+		{"action": "export_cursor", "format": "mdc", "dry_run": true},
+	},
+	"buddy_undo": {
+		{"action": "list"},
+		{"count": 2},
+	},
+	"buddy_check_names": {
+		{"names": []string{"getClientData", "fetch_user"}, "kind": "function"},
+	},
+	"buddy_search_knowledge": {
+		{"query": "authentication"},
+		{"query": "rate limit", "category": "api", "limit": 5},
+		{"queries": []string{"jwt", "token refresh", "session expiry"}},
+		{"query": "retries", "metadata": map[string]string{"service": "payments"}},
+		{"query": "Datenbank", "language": "de"},
+		{"query": "how do we roll back a deploy?", "answer": true},
+	},
+	"buddy_get_database_info": {
+		{},
+		{"table_name": "users"},
+		{"suggest_query": "orders with user email"},
+		{"action": "export", "format": "json"},
+		{"action": "export", "format": "sql", "table_name": "orders"},
+	},
+	"buddy_generate_fixtures": {
+		{"table_name": "users", "format": "go", "count": 2},
+	},
+	"buddy_mock": {
+		{"table_name": "users", "count": 5},
+		{"table_name": "users", "id": 42},
+		{"path": "/users/42"},
+		{"method": "POST", "path": "/orders", "status": "422"},
+	},
+	"buddy_manage_todos": {
+		{"action": "list", "only_incomplete": true},
+		{"action": "update", "todo_id": "<id from list>", "completed": true},
+		{"action": "progress"},
+		{"action": "import_code"},
+		{"action": "list", "only_unclaimed": true},
+		{"action": "claim", "todo_id": "<id from list>", "agent": "agent-a", "ttl_minutes": 60},
+		{"action": "release", "todo_id": "<id from list>", "agent": "agent-a"},
+	},
+	"buddy_lock": {
+		{"action": "acquire", "paths": []string{"internal/auth/login.go"}, "agent": "agent-a", "note": "refactoring login"},
+		{"action": "release", "paths": []string{"internal/auth/login.go"}, "agent": "agent-a"},
+		{},
+	},
+	"buddy_history": {
+		{"action": "list", "since": "last 7 days"},
+		{"action": "search", "query": "retry", "search_scope": "content"},
+		{"action": "add", "feature": "auth", "description": "Add login", "reasoning": "Needed for SSO",
+			"changes": []map[string]string{{"file_path": "auth.go", "change_type": "created"}}},
+		{"action": "list", "test_status": "failed"},
+		{"action": "attach_tests", "feature": "auth", "status": "failed", "failing_tests": []string{"TestLogin"}, "source": "<ci run url>"},
+	},
+	"buddy_focus": {
+		{"action": "set", "features": []string{"checkout", "payments"}, "note": "Q3 checkout rewrite"},
+		{"action": "add", "features": []string{"refunds"}},
+		{},
+		{"action": "clear"},
+	},
+	"buddy_scratchpad": {
+		{"action": "set", "name": "plan", "content": "1. migrate schema\n2. update handlers"},
+		{"action": "set", "name": "retry bug cause", "content": "Timeouts come from the pool size", "promote": "history", "feature": "payments"},
+		{"action": "get", "name": "plan"},
+		{},
+		{"action": "promote", "name": "plan", "promote": "knowledge", "feature": "architecture"},
+	},
+	"buddy_backup": {
+		{"action": "create", "file_path": "main.go", "context": "refactor", "reasoning": "large edit"},
+		{"action": "create_set", "file_paths": []string{"a.go", "b.go"}, "context": "rename", "reasoning": "multi-file change"},
+		{"action": "restore", "backup_id": "<id from list>"},
+		{"action": "restore", "backup_id": "<id from list>", "force": true},
+		{"action": "create_tree", "dir_path": "internal/api", "context": "rename refactor", "reasoning": "sweeping rename"},
+		{"action": "restore", "backup_id": "<directory backup id>", "file_path": "handlers/user.go"},
+	},
+	"buddy_apply_changeset": {
+		{"changes": []map[string]string{{"path": "internal/auth/login.go", "content": "<full new content>"}, {"path": "internal/auth/login_test.go", "content": "<full new content>"}},
+			"context": "add login", "reasoning": "SSO support"},
+	},
+	"buddy_reorganize": {
+		{"category": "auth", "to_category": "security", "dry_run": true},
+		{"section": "knowledge", "renames": map[string]string{"auth/jwt.md": "security/tokens.md"}},
+	},
+	"buddy_draft": {
+		{"instruction": "we always use zap for logging"},
+	},
+	"buddy_get_datasets": {
+		{},
+		{"name": "countries", "filter": "DE"},
+	},
+	"buddy_check_compliance": {
+		{"dependency": "github.com/foo/bar", "license": "Apache-2.0"},
+		{"license": "GPL-3.0 OR MIT"},
+		{"category": "data"},
+	},
+	"buddy_budgets": {
+		{"component": "checkout-api"},
+		{"action": "record", "component": "checkout-api", "metric": "p95_latency", "value": "230ms", "source": "ci run 1842"},
+	},
+	"buddy_security_check": {
+		{"diff": "<output of git diff>"},
+		{"file_path": "internal/db/users.go", "content": "<proposed file content>"},
+	},
+	"buddy_capture_session": {
+		{"feature": "auth", "since": "last 1 day"},
+		{"entry_ids": []string{"<id from buddy_history>"}, "title": "How we added SSO login"},
+	},
+	"buddy_summarize": {
+		{"action": "knowledge", "query": "authentication"},
+		{"action": "digest", "since": "last 7 days"},
+	},
+	"buddy_time_travel": {
+		{"at": "2024-06-01"},
+		{"at": "2024-06-01T09:00:00Z", "include_content": false, "history_limit": 5},
+	},
+	"buddy_handoff": {
+		{"feature": "checkout"},
+		{"feature": "checkout", "format": "json"},
+	},
+	"buddy_views": {
+		{},
+		{"name": "critical-changes"},
+		{"name": "feature-rules", "params": map[string]string{"feature": "checkout"}, "output": "json"},
+	},
+	"buddy_audit": {
+		{"errors_only": true, "since": "last 1 day"},
+		{"tool": "buddy_manage_todos", "limit": 5},
+	},
+	"buddy_health": {
+		{},
+	},
+	"buddy_status": {
+		{},
+		{"action": "compact"},
+	},
+	"buddy_quality": {
+		{},
+		{"section": "knowledge", "stale_days": 90},
+	},
+	"buddy_setup": {
+		{},
+		{"language": "go", "database": "postgresql", "project_name": "checkout", "conventions": "use zap for logging\nwrap errors with context"},
+	},
+	"buddy_server_info": {
+		{},
+	},
+	"buddy_help": {
+		{},
+		{"tool": "buddy_backup"},
+	},
+}
+
+// ToolArgument describes one tool argument
+type ToolArgument struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Description string        `json:"description,omitempty"`
+	Required    bool          `json:"required"`
+	Values      []interface{} `json:"values,omitempty"`
+}
+
+// ToolDescription is the machine-readable help for one tool
+type ToolDescription struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Actions     []interface{}            `json:"actions,omitempty"`
+	Arguments   []ToolArgument           `json:"arguments"`
+	Examples    []map[string]interface{} `json:"examples,omitempty"`
+}
+
+// ToolRegistry registers tools with the MCP server and remembers them so
+// the server can describe its own capabilities. Tool names are rewritten
+// and filtered according to the tools configuration.
+type ToolRegistry struct {
+	server    *server.MCPServer
+	naming    config.Tools
+	tools     []mcp.Tool
+	baseNames map[string]string // registered name -> unprefixed name
+	projects  []string          // offered as the project argument when more than one
+	mu        sync.RWMutex
+}
+
+// NewToolRegistry creates a registry that registers tools on the given server
+func NewToolRegistry(mcpServer *server.MCPServer, naming config.Tools) *ToolRegistry {
+	return &ToolRegistry{
+		server:    mcpServer,
+		naming:    naming,
+		baseNames: make(map[string]string),
+	}
+}
+
+// SetProjects makes tools added afterwards accept a project argument
+// choosing between the named projects. It has no effect for one project.
+func (tr *ToolRegistry) SetProjects(names []string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(names) > 1 {
+		tr.projects = append([]string(nil), names...)
+	} else {
+		tr.projects = nil
+	}
+}
+
+// AddTool registers a tool with the server under its configured name and
+// records it. Disabled tools are skipped.
+func (tr *ToolRegistry) AddTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	baseName := tool.Name
+	if !tr.naming.Enabled(baseName) {
+		return
+	}
+	tool.Name = tr.naming.Name(baseName)
+
+	tr.mu.RLock()
+	projects := tr.projects
+	tr.mu.RUnlock()
+	if len(projects) > 0 {
+		if tool.InputSchema.Properties == nil {
+			tool.InputSchema.Properties = make(map[string]interface{})
+		}
+		tool.InputSchema.Properties[ProjectArgument] = map[string]interface{}{
+			"type":        "string",
+			"description": fmt.Sprintf("Project to use (default: %s)", projects[0]),
+			"enum":        projects,
+		}
+	}
+
+	tr.mu.Lock()
+	tr.tools = append(tr.tools, tool)
+	tr.baseNames[tool.Name] = baseName
+	tr.mu.Unlock()
+
+	tr.server.AddTool(tool, handler)
+}
+
+// Tools returns all registered tools in registration order
+func (tr *ToolRegistry) Tools() []mcp.Tool {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	return append([]mcp.Tool(nil), tr.tools...)
+}
+
+// Describe builds help for a tool from its input schema
+func (tr *ToolRegistry) Describe(tool mcp.Tool) ToolDescription {
+	required := make(map[string]bool)
+	for _, name := range tool.InputSchema.Required {
+		required[name] = true
+	}
+
+	tr.mu.RLock()
+	baseName, ok := tr.baseNames[tool.Name]
+	tr.mu.RUnlock()
+	if !ok {
+		baseName = tool.Name
+	}
+
+	desc := ToolDescription{
+		Name:        tool.Name,
+		Description: tool.Description,
+		Arguments:   []ToolArgument{},
+		Examples:    toolExamples[baseName],
+	}
+
+	var names []string
+	for name := range tool.InputSchema.Properties {
+		names = append(names, name)
+	}
+	// Required arguments first, then alphabetical
+	sort.Slice(names, func(i, j int) bool {
+		if required[names[i]] != required[names[j]] {
+			return required[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		prop, _ := tool.InputSchema.Properties[name].(map[string]interface{})
+		arg := ToolArgument{
+			Name:     name,
+			Required: required[name],
+		}
+		arg.Type, _ = prop["type"].(string)
+		arg.Description, _ = prop["description"].(string)
+		arg.Values = enumValues(prop["enum"])
+		desc.Arguments = append(desc.Arguments, arg)
+
+		if name == "action" {
+			desc.Actions = arg.Values
+		}
+	}
+
+	return desc
+}
+
+// enumValues normalizes a schema enum into a slice
+func enumValues(enum interface{}) []interface{} {
+	switch values := enum.(type) {
+	case []interface{}:
+		return values
+	case []string:
+		var result []interface{}
+		for _, v := range values {
+			result = append(result, v)
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// HelpArgs are the arguments of buddy_help
+type HelpArgs struct {
+	Tool string `arg:"tool" desc:"Only describe this tool (optional)"`
+}
+
+// GetHelpToolHandler returns the tool handler that describes every registered tool
+func (tr *ToolRegistry) GetHelpToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args HelpArgs) (*mcp.CallToolResult, error) {
+		toolName := args.Tool
+
+		var descriptions []ToolDescription
+		for _, tool := range tr.Tools() {
+			if toolName == "" || tool.Name == toolName {
+				descriptions = append(descriptions, tr.Describe(tool))
+			}
+		}
+
+		if toolName != "" && len(descriptions) == 0 {
+			return nil, fmt.Errorf("unknown tool: %s", toolName)
+		}
+
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"tools": descriptions,
+		}, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal help: %w", err)
+		}
+
+		return mcp.NewToolResultText(string(data)), nil
+	})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noopTool handles a tool call without doing anything
+func noopTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return mcp.NewToolResultText(""), nil
+}
+
+func TestToolRegistry_AddTool(t *testing.T) {
+	tests := []struct {
+		name     string
+		naming   config.Tools
+		projects []string
+		want     []string
+		project  bool // whether tools take a project argument
+	}{
+		{"as declared", config.Tools{}, nil, []string{"buddy_get_rules", "buddy_history"}, false},
+		{"prefix and suffix", config.Tools{Prefix: "acme_", Suffix: "_v2"}, nil, []string{"acme_buddy_get_rules_v2", "acme_buddy_history_v2"}, false},
+		{"disabled", config.Tools{Disable: []string{"buddy_history"}}, nil, []string{"buddy_get_rules"}, false},
+		{"enabled only", config.Tools{Enable: []string{"buddy_history"}}, nil, []string{"buddy_history"}, false},
+		{"one project", config.Tools{}, []string{"api"}, []string{"buddy_get_rules", "buddy_history"}, false},
+		{"several projects", config.Tools{}, []string{"api", "web"}, []string{"buddy_get_rules", "buddy_history"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewToolRegistry(server.NewMCPServer("test", "1.0.0"), tt.naming)
+			registry.SetProjects(tt.projects)
+			registry.AddTool(mcp.NewTool("buddy_get_rules"), noopTool)
+			registry.AddTool(mcp.NewTool("buddy_history", mcp.WithString("feature")), noopTool)
+
+			var names []string
+			for _, tool := range registry.Tools() {
+				names = append(names, tool.Name)
+				project, ok := tool.InputSchema.Properties[ProjectArgument].(map[string]interface{})
+				assert.Equal(t, tt.project, ok, tool.Name)
+				if ok {
+					assert.Equal(t, tt.projects, project["enum"])
+				}
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestToolRegistry_Describe(t *testing.T) {
+	registry := NewToolRegistry(server.NewMCPServer("test", "1.0.0"), config.Tools{Prefix: "acme_"})
+	registry.AddTool(mcp.NewTool("buddy_get_rules",
+		mcp.WithDescription("Get rules"),
+		mcp.WithString("category", mcp.Description("Rule category")),
+		mcp.WithString("action", mcp.Enum("test", "lint")),
+		mcp.WithString("priority", mcp.Required(), mcp.Enum("critical", "recommended")),
+		mcp.WithNumber("max_tokens"),
+	), noopTool)
+
+	desc := registry.Describe(registry.Tools()[0])
+	assert.Equal(t, "acme_buddy_get_rules", desc.Name)
+	assert.Equal(t, "Get rules", desc.Description)
+	assert.Equal(t, []interface{}{"test", "lint"}, desc.Actions)
+	assert.Equal(t, toolExamples["buddy_get_rules"], desc.Examples, "examples are found by the unprefixed name")
+
+	// Required arguments first, then alphabetical
+	var names []string
+	for _, arg := range desc.Arguments {
+		names = append(names, arg.Name)
+	}
+	assert.Equal(t, []string{"priority", "action", "category", "max_tokens"}, names)
+	assert.Equal(t, ToolArgument{Name: "priority", Type: "string", Required: true, Values: []interface{}{"critical", "recommended"}}, desc.Arguments[0])
+	assert.Equal(t, ToolArgument{Name: "category", Type: "string", Description: "Rule category"}, desc.Arguments[2])
+	assert.Equal(t, ToolArgument{Name: "max_tokens", Type: "number"}, desc.Arguments[3])
+
+	// A tool the registry didn't add is described without examples
+	bare := registry.Describe(mcp.NewTool("buddy_unknown"))
+	assert.Empty(t, bare.Examples)
+	assert.Equal(t, []ToolArgument{}, bare.Arguments)
+}
+
+func TestEnumValues(t *testing.T) {
+	tests := []struct {
+		name string
+		enum interface{}
+		want []interface{}
+	}{
+		{"strings", []string{"a", "b"}, []interface{}{"a", "b"}},
+		{"decoded JSON", []interface{}{"a", 1.0}, []interface{}{"a", 1.0}},
+		{"missing", nil, nil},
+		{"not a list", "a", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, enumValues(tt.enum))
+		})
+	}
+}
+
+func TestToolRegistry_HelpTool(t *testing.T) {
+	registry := NewToolRegistry(server.NewMCPServer("test", "1.0.0"), config.Tools{})
+	registry.AddTool(mcp.NewTool("buddy_get_rules"), noopTool)
+	registry.AddTool(mcp.NewTool("buddy_history"), noopTool)
+
+	tests := []struct {
+		name    string
+		tool    string
+		want    []string
+		wantErr string
+	}{
+		{"every tool", "", []string{"buddy_get_rules", "buddy_history"}, ""},
+		{"one tool", "buddy_history", []string{"buddy_history"}, ""},
+		{"unknown tool", "buddy_nope", nil, "unknown tool: buddy_nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = map[string]any{"tool": tt.tool}
+			result, err := registry.GetHelpToolHandler()(context.Background(), request)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var help struct {
+				Tools []ToolDescription `json:"tools"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &help))
+			var names []string
+			for _, tool := range help.Tools {
+				names = append(names, tool.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
@@ -5,108 +5,77 @@ import (
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
+	"maps"
 	"path/filepath"
-	"sort"
+	"slices"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/schema"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/testresults"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/timeutil"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/workspace"
 )
 
 // HistoryHandler manages implementation history
 type HistoryHandler struct {
-	path          string
-	entries       []models.HistoryEntry
-	searchManager *search.SearchManager
-	mu            sync.RWMutex
+	*DocumentHandler[models.HistoryEntry]
+	timeFormat *timeutil.Formatter
+	root       workspace.Root // changed files are stored relative to it
+	focus      *FocusStore    // narrows lists and ranks searches; nil for none
 }
 
 // NewHistoryHandler creates a new history handler
 func NewHistoryHandler(path string, searchManager *search.SearchManager) *HistoryHandler {
-	return &HistoryHandler{
-		path:          path,
-		entries:       []models.HistoryEntry{},
-		searchManager: searchManager,
-	}
-}
-
-// Load loads all history entries
-func (hh *HistoryHandler) Load() error {
-	hh.mu.Lock()
-	defer hh.mu.Unlock()
-
-	hh.entries = []models.HistoryEntry{}
-
-	// First, reindex all history
-	if err := hh.searchManager.ReindexAll(search.IndexTypeHistory); err != nil {
-		return fmt.Errorf("failed to reindex history: %w", err)
-	}
-
-	files, err := ioutil.ReadDir(hh.path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
-
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
-			entry, err := hh.loadHistoryFile(filepath.Join(hh.path, file.Name()))
-			if err != nil {
-				return fmt.Errorf("failed to load history %s: %w", file.Name(), err)
-			}
-			hh.entries = append(hh.entries, entry)
-
-			// Index the entry in Bleve
-			doc := search.FromHistoryEntry(entry)
-			if err := hh.searchManager.IndexDocument(search.IndexTypeHistory, entry.ID, doc); err != nil {
-				return fmt.Errorf("failed to index history %s: %w", entry.ID, err)
-			}
-		}
-	}
-
-	// Sort by timestamp (newest first)
-	sort.Slice(hh.entries, func(i, j int) bool {
-		return hh.entries[i].Timestamp.After(hh.entries[j].Timestamp)
+	hh := &HistoryHandler{}
+	hh.DocumentHandler = NewDocumentHandler(path, searchManager, DocumentSpec[models.HistoryEntry]{
+		IndexType:  search.IndexTypeHistory,
+		Extensions: []string{".json"},
+		Parse:      hh.parseHistoryFile,
+		ID:         func(entry models.HistoryEntry) string { return entry.ID },
+		Index:      func(entry models.HistoryEntry) interface{} { return search.FromHistoryEntry(entry) },
+		// Newest first
+		Less: func(a, b models.HistoryEntry) bool { return a.Timestamp.After(b.Timestamp) },
 	})
-
-	return nil
+	return hh
 }
 
-// loadHistoryFile loads a single history file
-func (hh *HistoryHandler) loadHistoryFile(filePath string) (models.HistoryEntry, error) {
-	content, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return models.HistoryEntry{}, err
+// parseHistoryFile parses a single history file
+func (hh *HistoryHandler) parseHistoryFile(file storage.FileInfo, content []byte) ([]models.HistoryEntry, error) {
+	if err := schema.Validate(schema.HistoryEntry, content); err != nil {
+		return nil, err
 	}
 
 	var entry models.HistoryEntry
 	if err := json.Unmarshal(content, &entry); err != nil {
-		return models.HistoryEntry{}, err
+		return nil, err
 	}
 
-	return entry, nil
+	return []models.HistoryEntry{entry}, nil
 }
 
 // AddEntry adds a new history entry
-func (hh *HistoryHandler) AddEntry(feature, description, reasoning string, changes []models.Change) error {
+func (hh *HistoryHandler) AddEntry(ctx context.Context, feature, description, reasoning string, changes []models.Change) error {
 	hh.mu.Lock()
 	defer hh.mu.Unlock()
 
+	changes = append([]models.Change(nil), changes...)
+	for i := range changes {
+		changes[i].FilePath = hh.root.Rel(changes[i].FilePath)
+	}
 	entry := models.HistoryEntry{
 		ID:          fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%d", feature, time.Now().UnixNano())))),
 		Feature:     feature,
 		Description: description,
 		Reasoning:   reasoning,
 		Changes:     changes,
-		Timestamp:   time.Now(),
+		Timestamp:   time.Now().UTC(),
 	}
 
 	// Save to file
@@ -116,12 +85,12 @@ func (hh *HistoryHandler) AddEntry(feature, description, reasoning string, chang
 		return err
 	}
 
-	if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
+	if err := writeFile(ctx, hh.store, filePath, data); err != nil {
 		return err
 	}
 
 	// Add to memory
-	hh.entries = append([]models.HistoryEntry{entry}, hh.entries...)
+	hh.docs = append([]models.HistoryEntry{entry}, hh.docs...)
 
 	// Index the entry in Bleve
 	doc := search.FromHistoryEntry(entry)
@@ -132,139 +101,291 @@ func (hh *HistoryHandler) AddEntry(feature, description, reasoning string, chang
 	return nil
 }
 
+// AttachTestRun records a CI test outcome on a history entry and reindexes
+// history so the entry can be found by its failing tests
+func (hh *HistoryHandler) AttachTestRun(target testresults.Target, run models.TestRun) (models.HistoryEntry, error) {
+	entry, err := testresults.Attach(hh.store, hh.path, target, run)
+	if err != nil {
+		return models.HistoryEntry{}, err
+	}
+	return entry, hh.Load()
+}
+
+// filterHistoryByTestStatus keeps entries whose latest test run has the
+// given status; an empty status keeps everything
+func filterHistoryByTestStatus(entries []models.HistoryEntry, status string) []models.HistoryEntry {
+	if status == "" {
+		return entries
+	}
+
+	var filtered []models.HistoryEntry
+	for _, entry := range entries {
+		if testresults.MatchesStatus(entry, status) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// GetHistory returns all history entries, newest first
+func (hh *HistoryHandler) GetHistory() []models.HistoryEntry {
+	return hh.Documents()
+}
+
 // GetRecentHistory returns the most recent history entries
 func (hh *HistoryHandler) GetRecentHistory(limit int) []models.HistoryEntry {
 	hh.mu.RLock()
 	defer hh.mu.RUnlock()
 
-	if limit > len(hh.entries) {
-		limit = len(hh.entries)
+	if limit > len(hh.docs) {
+		limit = len(hh.docs)
 	}
 
-	return hh.entries[:limit]
+	return hh.docs[:limit]
 }
 
 // GetHistoryByFeature returns history entries for a specific feature
 func (hh *HistoryHandler) GetHistoryByFeature(feature string) []models.HistoryEntry {
-	hh.mu.RLock()
-	defer hh.mu.RUnlock()
+	return hh.Filter(func(entry models.HistoryEntry) bool {
+		return strings.EqualFold(entry.Feature, feature)
+	})
+}
 
-	var filtered []models.HistoryEntry
-	for _, entry := range hh.entries {
-		if strings.EqualFold(entry.Feature, feature) {
-			filtered = append(filtered, entry)
-		}
-	}
-	return filtered
+// HistoryArgs are the arguments of buddy_history
+type HistoryArgs struct {
+	Action       string          `arg:"action,required" enum:"list,add,search,attach_tests" desc:"Action to perform: list, add, search, attach_tests (record CI test results on an entry)"`
+	Feature      string          `arg:"feature" desc:"Feature name (for filtering or adding)"`
+	Description  string          `arg:"description" desc:"Description of changes (required for add)"`
+	Reasoning    string          `arg:"reasoning" desc:"Reasoning behind changes (required for add)"`
+	Changes      []models.Change `arg:"changes" desc:"List of file changes (required for add)"`
+	Query        string          `arg:"query" desc:"Search query (required for search)"`
+	Limit        int             `arg:"limit" desc:"Limit results (default: 10)"`
+	SearchScope  string          `arg:"search_scope" enum:"metadata,content" desc:"What search matches: metadata (feature, description, files) or content (before/after code of changes). Default: metadata"`
+	Since        string          `arg:"since" desc:"Only entries at or after this time: RFC3339, YYYY-MM-DD, 'last 3 days', 'P1W' (optional)"`
+	Until        string          `arg:"until" desc:"Only entries at or before this time, same formats as since (optional)"`
+	TestStatus   string          `arg:"test_status" enum:"passed,failed,untested" desc:"Only entries whose latest test run passed or failed, or that have none (optional for list and search)"`
+	IgnoreFocus  bool            `arg:"ignore_focus" desc:"List every feature's entries instead of only those set with buddy_focus (optional for list and search)"`
+	EntryID      string          `arg:"entry_id" desc:"History entry to attach test results to; defaults to the newest entry, or the newest for feature (optional for attach_tests)"`
+	Report       string          `arg:"report" desc:"JUnit XML report or go test -json output (attach_tests; alternative to status)"`
+	Status       string          `arg:"status" enum:"passed,failed" desc:"Test outcome when no report is given (attach_tests)"`
+	FailingTests []string        `arg:"failing_tests" desc:"Names of failing tests when no report is given (optional for attach_tests)"`
+	Total        int             `arg:"total" desc:"Number of tests run when no report is given (optional for attach_tests)"`
+	Source       string          `arg:"source" desc:"Where the results came from, e.g. a CI run URL (optional for attach_tests)"`
+	TokenBudgetArgs
 }
 
 // GetToolHandler returns the tool handler function for history
 func (hh *HistoryHandler) GetToolHandler() server.ToolHandlerFunc {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		args := request.GetArguments()
-		action, ok := args["action"].(string)
-		if !ok {
-			return nil, fmt.Errorf("action is required")
-		}
-
-		switch action {
+	return toolargs.Handler(func(ctx context.Context, args HistoryArgs) (*mcp.CallToolResult, error) {
+		switch args.Action {
 		case "list":
-			feature, _ := args["feature"].(string)
-			limitFloat, ok := args["limit"].(float64)
+			feature, testStatus := args.Feature, args.TestStatus
 			limit := 10
-			if ok {
-				limit = int(limitFloat)
+			if args.Limit > 0 {
+				limit = args.Limit
+			}
+
+			since, until, err := parseTimeRange(args.Since, args.Until, hh.timeFormat.Location())
+			if err != nil {
+				return nil, err
+			}
+
+			// Without a feature filter, only the focused features are listed
+			var focused []string
+			if feature == "" {
+				focused = hh.focus.Active(args.IgnoreFocus)
 			}
 
 			var entries []models.HistoryEntry
 			if feature != "" {
 				entries = hh.GetHistoryByFeature(feature)
+			} else if len(focused) > 0 {
+				entries = hh.Filter(func(entry models.HistoryEntry) bool {
+					return containsFold(focused, entry.Feature)
+				})
+			} else if !since.IsZero() || !until.IsZero() || testStatus != "" {
+				entries = hh.GetHistory()
 			} else {
 				entries = hh.GetRecentHistory(limit)
 			}
+			entries = filterHistoryByTime(entries, since, until)
+			entries = filterHistoryByTestStatus(entries, testStatus)
+			if len(entries) > limit {
+				entries = entries[:limit]
+			}
 
-			result := hh.formatHistoryResults(entries)
+			result := focusNote(focused, true) + hh.formatHistoryResults(entries)
 			return mcp.NewToolResultText(result), nil
 
 		case "add":
-			feature, ok := args["feature"].(string)
-			if !ok {
+			if args.Feature == "" {
 				return nil, fmt.Errorf("feature is required for add action")
 			}
-
-			description, ok := args["description"].(string)
-			if !ok {
+			if args.Description == "" {
 				return nil, fmt.Errorf("description is required for add action")
 			}
-
-			reasoning, ok := args["reasoning"].(string)
-			if !ok {
+			if args.Reasoning == "" {
 				return nil, fmt.Errorf("reasoning is required for add action")
 			}
-
-			changesData, ok := args["changes"].([]interface{})
-			if !ok {
+			if args.Changes == nil {
 				return nil, fmt.Errorf("changes array is required for add action")
 			}
 
-			var changes []models.Change
-			for _, changeData := range changesData {
-				if changeMap, ok := changeData.(map[string]interface{}); ok {
-					change := models.Change{
-						FilePath:   changeMap["file_path"].(string),
-						ChangeType: changeMap["change_type"].(string),
-					}
-					if before, ok := changeMap["before"].(string); ok {
-						change.Before = before
-					}
-					if after, ok := changeMap["after"].(string); ok {
-						change.After = after
-					}
-					changes = append(changes, change)
-				}
-			}
-
-			if err := hh.AddEntry(feature, description, reasoning, changes); err != nil {
+			if err := hh.AddEntry(ctx, args.Feature, args.Description, args.Reasoning, args.Changes); err != nil {
 				return nil, err
 			}
 
 			return mcp.NewToolResultText("Successfully added history entry"), nil
 
 		case "search":
-			query, ok := args["query"].(string)
-			if !ok {
+			query := args.Query
+			if query == "" {
 				return nil, fmt.Errorf("query is required for search action")
 			}
 
-			// Use Bleve search
-			searchResults, err := hh.searchManager.Search(
-				search.IndexTypeHistory,
-				query,
-				50, // Limit to 50 results
-			)
+			since, until, err := parseTimeRange(args.Since, args.Until, hh.timeFormat.Location())
 			if err != nil {
-				return nil, fmt.Errorf("search failed: %w", err)
+				return nil, err
+			}
+
+			scope := args.SearchScope
+			if scope == "" {
+				scope = "metadata"
+			}
+
+			ids, err := hh.searchHistoryIDs(query, scope)
+			if err != nil {
+				return nil, err
 			}
 
 			// Convert search results to history entries
 			var entries []models.HistoryEntry
-			for _, hit := range searchResults.Hits {
-				// Find the entry by ID
-				for _, entry := range hh.entries {
-					if entry.ID == hit.ID {
-						entries = append(entries, entry)
-						break
-					}
+			for _, id := range ids {
+				if entry, ok := hh.Get(id); ok {
+					entries = append(entries, entry)
 				}
 			}
 
-			result := hh.formatSearchResults(query, entries)
+			entries = filterHistoryByTime(entries, since, until)
+			entries = filterHistoryByTestStatus(entries, args.TestStatus)
+			focused := hh.focus.Active(args.IgnoreFocus)
+			entries = focusedFirst(entries, func(entry models.HistoryEntry) bool {
+				return containsFold(focused, entry.Feature)
+			})
+
+			result := focusNote(focused, false) + hh.formatSearchResults(ctx, query, entries)
+			if scope == "content" {
+				result += formatContentMatches(query, entries)
+			}
 			return mcp.NewToolResultText(result), nil
 
+		case "attach_tests":
+			report, status := args.Report, args.Status
+
+			var run models.TestRun
+			if report != "" {
+				var err error
+				run, err = testresults.Parse([]byte(report))
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				if status != testresults.StatusPassed && status != testresults.StatusFailed {
+					return nil, fmt.Errorf("report or status (passed or failed) is required for attach_tests action")
+				}
+
+				var failing []string
+				for _, name := range args.FailingTests {
+					if name != "" {
+						failing = append(failing, name)
+					}
+				}
+
+				run = testresults.NewRun(args.Total, failing)
+				run.Status = status
+			}
+			run.Source = args.Source
+
+			entry, err := hh.AttachTestRun(testresults.Target{EntryID: args.EntryID, Feature: args.Feature}, run)
+			if err != nil {
+				return nil, err
+			}
+
+			return mcp.NewToolResultText(fmt.Sprintf("Attached test results to [%s] %s\n%s", entry.Feature, entry.Description, formatTestRun(run))), nil
+
 		default:
-			return nil, fmt.Errorf("invalid action: %s", action)
+			return nil, fmt.Errorf("invalid action: %s", args.Action)
+		}
+	})
+}
+
+// formatTestRun summarizes a test run on one line, with failing tests
+func formatTestRun(run models.TestRun) string {
+	var result string
+	if run.Status == testresults.StatusFailed {
+		result = "❌ failed"
+		if run.Total > 0 {
+			result += fmt.Sprintf(" (%d of %d)", run.Failed, run.Total)
+		}
+		if len(run.FailingTests) > 0 {
+			failing := run.FailingTests
+			more := ""
+			if len(failing) > 5 {
+				more = fmt.Sprintf(" and %d more", len(failing)-5)
+				failing = failing[:5]
+			}
+			result += ": " + strings.Join(failing, ", ") + more
+		}
+	} else {
+		result = "✅ passed"
+		if run.Total > 0 {
+			result += fmt.Sprintf(" (%d tests)", run.Total)
+		}
+	}
+	if run.Source != "" {
+		result += fmt.Sprintf(" [%s]", run.Source)
+	}
+	return result
+}
+
+// searchHistoryIDs runs a history search over the metadata fields or the
+// indexed change content and returns matching entry IDs by relevance
+func (hh *HistoryHandler) searchHistoryIDs(query, scope string) ([]string, error) {
+	var field string
+	switch scope {
+	case "metadata":
+		field = "" // all metadata fields
+	case "content":
+		field = "content"
+	default:
+		return nil, fmt.Errorf("invalid search_scope: %s (expected metadata or content)", scope)
+	}
+
+	searchResults, err := hh.searchManager.SearchField(search.IndexTypeHistory, field, query, 50) // Limit to 50 results
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	var ids []string
+	for _, hit := range searchResults.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids, nil
+}
+
+// filterHistoryByTime keeps entries whose timestamp falls within the range
+func filterHistoryByTime(entries []models.HistoryEntry, since, until time.Time) []models.HistoryEntry {
+	if since.IsZero() && until.IsZero() {
+		return entries
+	}
+
+	var filtered []models.HistoryEntry
+	for _, entry := range entries {
+		if inTimeRange(entry.Timestamp, since, until) {
+			filtered = append(filtered, entry)
 		}
 	}
+	return filtered
 }
 
 // formatHistoryResults formats history entries for display
@@ -277,8 +398,11 @@ func (hh *HistoryHandler) formatHistoryResults(entries []models.HistoryEntry) st
 
 	for i, entry := range entries {
 		result += fmt.Sprintf("\n%d. [%s] %s\n", i+1, entry.Feature, entry.Description)
-		result += fmt.Sprintf("   Time: %s\n", entry.Timestamp.Format("2006-01-02 15:04:05"))
+		result += fmt.Sprintf("   Time: %s\n", hh.timeFormat.Format(entry.Timestamp))
 		result += fmt.Sprintf("   Reasoning: %s\n", entry.Reasoning)
+		if run, ok := testresults.Latest(entry); ok {
+			result += fmt.Sprintf("   Tests: %s\n", formatTestRun(run))
+		}
 
 		if len(entry.Changes) > 0 {
 			result += "   Changes:\n"
@@ -296,19 +420,17 @@ func (hh *HistoryHandler) formatHistoryResults(entries []models.HistoryEntry) st
 	return result
 }
 
-// formatSearchResults formats search results with enhanced context
-func (hh *HistoryHandler) formatSearchResults(query string, entries []models.HistoryEntry) string {
+// formatSearchResults formats search results with enhanced context, or
+// suggestions when nothing matched
+func (hh *HistoryHandler) formatSearchResults(ctx context.Context, query string, entries []models.HistoryEntry) string {
 	if len(entries) == 0 {
 		result := fmt.Sprintf("No history entries found for: %s\n", query)
 
 		// Get document count
 		count, _ := hh.searchManager.GetDocumentCount(search.IndexTypeHistory)
 		if count > 0 {
-			result += fmt.Sprintf("\nThere are %d history entries available. Try:\n", count)
-			result += "- Different keywords or phrases\n"
-			result += "- Feature names (e.g., 'authentication', 'database')\n"
-			result += "- Change types (e.g., 'created', 'modified', 'deleted')\n"
-			result += "- File names or paths\n"
+			result += fmt.Sprintf("\nThere are %d history entries available.\n", count)
+			result += formatSuggestions(ctx, hh.searchManager, search.IndexTypeHistory, query, "feature", "features")
 		}
 
 		// Show available features
@@ -320,7 +442,7 @@ func (hh *HistoryHandler) formatSearchResults(query string, entries []models.His
 
 		if len(features) > 0 {
 			result += "\n\nAvailable features in history:"
-			for feature := range features {
+			for _, feature := range slices.Sorted(maps.Keys(features)) {
 				result += fmt.Sprintf("\n- %s", feature)
 			}
 		}
@@ -373,8 +495,11 @@ func (hh *HistoryHandler) formatSearchResults(query string, entries []models.His
 // formatSingleEntry formats a single history entry
 func (hh *HistoryHandler) formatSingleEntry(num int, entry models.HistoryEntry) string {
 	result := fmt.Sprintf("\n%d. [%s] %s\n", num, entry.Feature, entry.Description)
-	result += fmt.Sprintf("   Time: %s\n", entry.Timestamp.Format("2006-01-02 15:04:05"))
+	result += fmt.Sprintf("   Time: %s\n", hh.timeFormat.Format(entry.Timestamp))
 	result += fmt.Sprintf("   Reasoning: %s\n", entry.Reasoning)
+	if run, ok := testresults.Latest(entry); ok {
+		result += fmt.Sprintf("   Tests: %s\n", formatTestRun(run))
+	}
 
 	if len(entry.Changes) > 0 {
 		result += "   Changes:\n"
@@ -387,6 +512,57 @@ func (hh *HistoryHandler) formatSingleEntry(num int, entry models.HistoryEntry)
 	return result + "\n"
 }
 
+// formatContentMatches lists the changed lines in each entry that mention the query terms
+func formatContentMatches(query string, entries []models.HistoryEntry) string {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 || len(entries) == 0 {
+		return ""
+	}
+
+	matches := func(line string) bool {
+		lower := strings.ToLower(line)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := "\n🔎 MATCHING CODE:\n"
+	for _, entry := range entries {
+		for _, change := range entry.Changes {
+			var lines []string
+			for _, side := range []struct {
+				prefix string
+				body   string
+			}{{"-", change.Before}, {"+", change.After}} {
+				for _, line := range strings.Split(side.body, "\n") {
+					if len(lines) >= 3 {
+						break
+					}
+					if matches(line) {
+						line = strings.TrimSpace(line)
+						if len(line) > 120 {
+							line = line[:120] + "..."
+						}
+						lines = append(lines, side.prefix+" "+line)
+					}
+				}
+			}
+
+			if len(lines) > 0 {
+				result += fmt.Sprintf("\n[%s] %s\n", entry.Feature, change.FilePath)
+				for _, line := range lines {
+					result += fmt.Sprintf("   %s\n", line)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
 // getChangeTypeEmoji returns an emoji for the change type
 func (hh *HistoryHandler) getChangeTypeEmoji(changeType string) string {
 	switch strings.ToLower(changeType) {
@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/slug"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+)
+
+// What Import did with a file
+const (
+	ImportCreated = "created" // written as in the bundle
+	ImportRenamed = "renamed" // written under a new name or ID because the bundle's collided
+	ImportMerged  = "merged"  // added to an existing file
+	ImportSkipped = "skipped" // already present
+)
+
+// ImportChange is one file Import wrote, or would write, or skipped
+type ImportChange struct {
+	Section string `json:"section"`
+	Path    string `json:"path"` // relative to the buddy folder, with forward slashes
+	Action  string `json:"action"`
+	Note    string `json:"note,omitempty"`
+}
+
+// ImportResult is the outcome of an import
+type ImportResult struct {
+	Changes    []ImportChange `json:"changes"`
+	SnapshotID string         `json:"snapshot_id,omitempty"` // restore point undoing the import
+}
+
+// importPlan collects the changes of an import and the files they write
+type importPlan struct {
+	buddyPath string
+	store     storage.Storage
+	changes   []ImportChange
+	writes    map[string][]byte // by absolute path
+	order     []string
+}
+
+// Import merges an exported bundle into the buddy folder at buddyPath.
+// Each section is written in the files its handler loads: rules verbatim,
+// knowledge as markdown with its headers, todos as checklists, history as
+// one JSON file per entry and missing tables appended to schema.sql.
+// Content already present is skipped; files whose path or ID is taken by
+// different content are written under a new name or ID instead of
+// replacing it, and todo files gain only the tasks they lack. With dryRun
+// the changes are reported but nothing is written; otherwise a restore
+// point of the files about to be written is taken first.
+func Import(buddyPath string, bundle *ExportBundle, dryRun bool) (*ImportResult, error) {
+	return ImportWithStorage(buddyPath, storage.NewLocal(), bundle, dryRun)
+}
+
+// ImportWithStorage is Import, reading and writing the buddy folder
+// through store
+func ImportWithStorage(buddyPath string, store storage.Storage, bundle *ExportBundle, dryRun bool) (*ImportResult, error) {
+	// Restore points record absolute paths
+	buddyPath, err := filepath.Abs(buddyPath)
+	if err != nil {
+		return nil, err
+	}
+	current, err := Export(buddyPath, "", math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &importPlan{
+		buddyPath: buddyPath,
+		store:     store,
+		writes:    make(map[string][]byte),
+	}
+	plan.importRules(bundle.Rules, current.Rules)
+	plan.importKnowledge(bundle.Knowledge, current.Knowledge)
+	if err := plan.importTodos(bundle.Todos); err != nil {
+		return nil, err
+	}
+	if err := plan.importHistory(bundle.History, current.History); err != nil {
+		return nil, err
+	}
+	if err := plan.importDatabase(bundle.Database, current.Database); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Changes: plan.changes}
+	if dryRun || len(plan.order) == 0 {
+		return result, nil
+	}
+
+	label := "import"
+	if bundle.Project != "" {
+		label += " of " + bundle.Project
+	}
+	snapshot, err := BuddySafetyStore(buddyPath, plan.store).RestorePoint(context.Background(), "import", label, plan.order)
+	if err != nil {
+		return nil, err
+	}
+	result.SnapshotID = snapshot.ID
+	for _, path := range plan.order {
+		if err := plan.store.Write(path, plan.writes[path]); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", plan.rel(path), err)
+		}
+	}
+	return result, nil
+}
+
+// importRules writes each rule file verbatim under its path in the
+// exporting folder
+func (p *importPlan) importRules(rules, existing []models.Rule) {
+	present := make(map[string]bool)
+	for _, rule := range existing {
+		present[strings.TrimSpace(rule.Content)] = true
+	}
+	for _, rule := range rules {
+		content := rule.Content
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		name := bundleRelPath(rule.FilePath, "rules", slug.Make(rule.Title, 0, "imported")+".md")
+		if present[strings.TrimSpace(content)] {
+			p.skip("rules", name, "an identical rule exists")
+			continue
+		}
+		p.addFile("rules", name, []byte(content))
+		present[strings.TrimSpace(content)] = true
+	}
+}
+
+// importKnowledge writes each document as markdown with its title,
+// category, tags and metadata headers; documents exported from AsciiDoc or
+// reStructuredText files keep their name with a .md extension
+func (p *importPlan) importKnowledge(docs, existing []models.Knowledge) {
+	present := make(map[string]bool)
+	for _, doc := range existing {
+		present[knowledgeKey(doc)] = true
+	}
+	for _, doc := range docs {
+		name := bundleRelPath(doc.FilePath, "knowledge", slug.Make(doc.Title, 0, "imported")+".md")
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + ".md"
+		if present[knowledgeKey(doc)] {
+			p.skip("knowledge", name, "an identical document exists")
+			continue
+		}
+		p.addFile("knowledge", name, []byte(renderKnowledgeFile(doc)))
+		present[knowledgeKey(doc)] = true
+	}
+}
+
+// knowledgeKey identifies a document by what its file shows
+func knowledgeKey(doc models.Knowledge) string {
+	return strings.ToLower(doc.Title) + "\x00" + doc.Category + "\x00" + strings.TrimSpace(doc.Content)
+}
+
+// renderKnowledgeFile produces a markdown knowledge file that parses back
+// to doc
+func renderKnowledgeFile(doc models.Knowledge) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s\n", doc.Title))
+	if doc.Category != "" {
+		sb.WriteString(fmt.Sprintf("Category: %s\n", doc.Category))
+	}
+	if len(doc.Tags) > 0 {
+		sb.WriteString(fmt.Sprintf("Tags: %s\n", strings.Join(doc.Tags, ", ")))
+	}
+	fields := make([]string, 0, len(doc.Metadata))
+	for field := range doc.Metadata {
+		if markdownFieldName(field) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", strings.ToUpper(field[:1])+field[1:], doc.Metadata[field]))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(strings.TrimSpace(doc.Content) + "\n")
+	return sb.String()
+}
+
+// importTodos writes the todos of each exported file as a checklist under
+// its feature heading. An existing file of the same name gains the tasks
+// it doesn't list yet, whether done or not.
+func (p *importPlan) importTodos(todos []models.Todo) error {
+	var names []string
+	byFile := make(map[string][]models.Todo)
+	for _, todo := range todos {
+		name := bundleRelPath(todo.FilePath, "todos", slug.Make(todo.Feature, 0, "imported")+".md")
+		if _, seen := byFile[name]; !seen {
+			names = append(names, name)
+		}
+		byFile[name] = append(byFile[name], todo)
+	}
+
+	for _, name := range names {
+		path := p.sectionFile("todos", name)
+		content, err := p.store.Read(path)
+		if err != nil && !storage.IsNotExist(err) {
+			return err
+		}
+		if err != nil {
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("# %s\n\n", byFile[name][0].Feature))
+			for _, todo := range byFile[name] {
+				sb.WriteString(todoLine(todo))
+			}
+			p.write("todos", path, []byte(sb.String()), ImportCreated, "")
+			continue
+		}
+
+		listed := make(map[string]bool)
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.HasPrefix(line, "- [ ]") || strings.HasPrefix(line, "- [x]") {
+				listed[strings.TrimSpace(line[len("- [ ]"):])] = true
+			}
+		}
+		var added []string
+		for _, todo := range byFile[name] {
+			if !listed[todo.Task] {
+				added = append(added, todoLine(todo))
+				listed[todo.Task] = true
+			}
+		}
+		if len(added) == 0 {
+			p.skip("todos", name, "every task is already listed")
+			continue
+		}
+		text := string(content)
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		text += strings.Join(added, "")
+		p.write("todos", path, []byte(text), ImportMerged, fmt.Sprintf("%d tasks added", len(added)))
+	}
+	return nil
+}
+
+// todoLine renders a todo as a checklist line
+func todoLine(todo models.Todo) string {
+	mark := " "
+	if todo.Completed {
+		mark = "x"
+	}
+	return fmt.Sprintf("- [%s] %s\n", mark, todo.Task)
+}
+
+// importHistory writes each entry to history/<id>.json. Entries recording
+// a change the folder already has are skipped; an entry whose ID is taken
+// by a different entry gets a new ID.
+func (p *importPlan) importHistory(entries, existing []models.HistoryEntry) error {
+	taken := make(map[string]bool)
+	recorded := make(map[string]bool)
+	for _, entry := range existing {
+		taken[entry.ID] = true
+		recorded[historyKey(entry)] = true
+	}
+	for _, entry := range entries {
+		if recorded[historyKey(entry)] {
+			p.skip("history", "history/"+entry.ID+".json", "the entry exists")
+			continue
+		}
+		entry.FilePath = ""
+		action, note := ImportCreated, ""
+		if taken[entry.ID] {
+			oldID := entry.ID
+			entry.ID = fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%s-%d", oldID, entry.Feature, time.Now().UnixNano()))))
+			action, note = ImportRenamed, fmt.Sprintf("ID %s is taken by another entry", oldID)
+		}
+		if entry.ID == "" {
+			entry.ID = fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%d", entry.Feature, time.Now().UnixNano()))))
+		}
+
+		data, err := json.MarshalIndent(entry, "", "  ")
+		if err != nil {
+			return err
+		}
+		p.write("history", filepath.Join(p.buddyPath, "history", entry.ID+".json"), data, action, note)
+		taken[entry.ID] = true
+		recorded[historyKey(entry)] = true
+	}
+	return nil
+}
+
+// historyKey identifies an entry by the change it records
+func historyKey(entry models.HistoryEntry) string {
+	return entry.Timestamp.UTC().Format(time.RFC3339Nano) + "\x00" + entry.Feature + "\x00" + entry.Description
+}
+
+// importDatabase appends the tables schema.sql doesn't define to it, and
+// writes connection.md when there is none
+func (p *importPlan) importDatabase(info, existing *models.DatabaseInfo) error {
+	if info == nil {
+		return nil
+	}
+	dir := filepath.Join(p.buddyPath, "database")
+
+	defined := make(map[string]bool)
+	if existing != nil {
+		for _, table := range existing.Tables {
+			defined[strings.ToLower(table.Name)] = true
+		}
+	}
+	var missing []models.Table
+	for _, table := range info.Tables {
+		if defined[strings.ToLower(table.Name)] {
+			p.skip("database", "database/schema.sql", fmt.Sprintf("table %s is already defined", table.Name))
+			continue
+		}
+		missing = append(missing, table)
+	}
+	if len(missing) > 0 {
+		sort.Slice(missing, func(i, j int) bool { return missing[i].Name < missing[j].Name })
+		schemaPath := filepath.Join(dir, "schema.sql")
+		content, err := p.store.Read(schemaPath)
+		if err != nil && !storage.IsNotExist(err) {
+			return err
+		}
+		action := ImportCreated
+		text := string(content)
+		if text != "" {
+			action = ImportMerged
+			text = strings.TrimRight(text, "\n") + "\n\n"
+		}
+		text += schemaDDL(missing)
+		names := make([]string, len(missing))
+		for i, table := range missing {
+			names[i] = table.Name
+		}
+		p.write("database", schemaPath, []byte(text), action, "tables "+strings.Join(names, ", "))
+	}
+
+	if info.ConnectionInfo != "" {
+		connPath := filepath.Join(dir, "connection.md")
+		if existing != nil && existing.ConnectionInfo != "" {
+			p.skip("database", "database/connection.md", "connection info exists")
+		} else {
+			p.write("database", connPath, []byte(info.ConnectionInfo), ImportCreated, "")
+		}
+	}
+	return nil
+}
+
+// addFile plans a new file in a section, renaming it when the name is
+// taken by a file with other content
+func (p *importPlan) addFile(section, name string, content []byte) {
+	path := p.sectionFile(section, name)
+	if !p.taken(path) {
+		p.write(section, path, content, ImportCreated, "")
+		return
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-imported%s", base, ext)
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-imported-%d%s", base, i, ext)
+		}
+		if !p.taken(candidate) {
+			p.write(section, candidate, content, ImportRenamed, fmt.Sprintf("%s exists with other content", p.rel(path)))
+			return
+		}
+	}
+}
+
+// sectionFile returns the absolute path of a file in a section folder.
+// Paths leaving the folder keep only their file name.
+func (p *importPlan) sectionFile(section, name string) string {
+	dir := filepath.Join(p.buddyPath, section)
+	path, err := sectionPath(dir, name)
+	if err != nil {
+		return filepath.Join(dir, filepath.Base(filepath.FromSlash(name)))
+	}
+	return path
+}
+
+// taken reports whether a file exists or is already planned
+func (p *importPlan) taken(path string) bool {
+	if _, planned := p.writes[path]; planned {
+		return true
+	}
+	_, err := p.store.Stat(path)
+	return err == nil
+}
+
+// write plans writing content to an absolute path
+func (p *importPlan) write(section, path string, content []byte, action, note string) {
+	if _, planned := p.writes[path]; !planned {
+		p.order = append(p.order, path)
+	}
+	p.writes[path] = content
+	p.changes = append(p.changes, ImportChange{Section: section, Path: p.rel(path), Action: action, Note: note})
+}
+
+// skip records content that is already present
+func (p *importPlan) skip(section, path, note string) {
+	if !strings.HasPrefix(path, section+"/") {
+		path = section + "/" + path
+	}
+	p.changes = append(p.changes, ImportChange{Section: section, Path: path, Action: ImportSkipped, Note: note})
+}
+
+// rel returns an absolute path relative to the buddy folder
+func (p *importPlan) rel(path string) string {
+	return relativeTo(p.buddyPath, path)
+}
+
+// bundleRelPath returns where an exported file sat within its section
+// folder, from the path the exporting machine recorded; fallback names
+// files whose path doesn't show the section
+func bundleRelPath(filePath, section, fallback string) string {
+	path := strings.ReplaceAll(filePath, "\\", "/")
+	if idx := strings.LastIndex(path, "/"+section+"/"); idx >= 0 {
+		return path[idx+len(section)+2:]
+	}
+	if path != "" {
+		return filepath.Base(filepath.FromSlash(path))
+	}
+	return fallback
+}
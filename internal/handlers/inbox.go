@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+)
+
+// inboxRecentWindow is how far back changed critical rules are surfaced
+const inboxRecentWindow = 7 * 24 * time.Hour
+
+// duePattern matches a due date written into a todo, e.g. "(due: 2024-06-01)"
+var duePattern = regexp.MustCompile(`(?i)\bdue:?\s*(\d{4}-\d{2}-\d{2})`)
+
+// questionPrefix marks a todo as a question for a human, e.g.
+// "- [ ] Question: which queue should billing events use"
+const questionPrefix = "question:"
+
+// Inbox item priorities, most urgent first
+var inboxPriorityOrder = map[string]int{
+	"high":   0,
+	"medium": 1,
+	"low":    2,
+}
+
+// InboxItem is one entry in the priority inbox
+type InboxItem struct {
+	Priority string    `json:"priority"` // high, medium, low
+	Kind     string    `json:"kind"`     // reload_error, rule_extends, setup, overdue_todo, question, critical_rule, pending_review
+	Title    string    `json:"title"`
+	Detail   string    `json:"detail,omitempty"`
+	ID       string    `json:"id,omitempty"`
+	FilePath string    `json:"file_path,omitempty"`
+	Time     time.Time `json:"time,omitempty"`
+}
+
+// todoDueDate extracts a due date from a todo's text
+func todoDueDate(task string, loc *time.Location) (time.Time, bool) {
+	match := duePattern.FindStringSubmatch(task)
+	if match == nil {
+		return time.Time{}, false
+	}
+	due, err := time.ParseInLocation("2006-01-02", match[1], loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return due, true
+}
+
+// todoQuestion returns the question a todo asks a human: its text after a
+// "Question:" prefix, or the whole task when it ends in a question mark
+func todoQuestion(task string) (string, bool) {
+	if len(task) >= len(questionPrefix) && strings.EqualFold(task[:len(questionPrefix)], questionPrefix) {
+		question := strings.TrimSpace(task[len(questionPrefix):])
+		return question, question != ""
+	}
+	return task, strings.HasSuffix(task, "?")
+}
+
+// collectInbox gathers urgent items across all subsystems, most urgent first
+func (bh *BuddyHandlers) collectInbox(snap *ContextSnapshot, now time.Time) []InboxItem {
+	var items []InboxItem
+
+	// Sections whose last reload failed are serving stale content
+	for _, name := range bh.reloadOrder {
+		failedAt, err := bh.reloaders[name].failure()
+		if err != nil {
+			title := fmt.Sprintf("Reloading %s failed", name)
+			if failures, path, open := bh.reloaders[name].tripped(); open && path != "" {
+				title = fmt.Sprintf("Reloading %s failed %d times in a row on %s", name, failures, bh.relativeBuddyPath(path))
+			}
+			items = append(items, InboxItem{
+				Priority: "high",
+				Kind:     "reload_error",
+				Title:    title,
+				Detail:   err.Error(),
+				Time:     failedAt,
+			})
+		}
+	}
+
+	// Rules whose Extends is broken are served without what they inherit
+	for _, problem := range bh.rulesHandler.ExtendsProblems() {
+		items = append(items, InboxItem{
+			Priority: "high",
+			Kind:     "rule_extends",
+			Title:    fmt.Sprintf("Rule %s loads without the rule it extends", bh.relativeBuddyPath(problem.Path)),
+			Detail:   problem.Err.Error(),
+			FilePath: problem.Path,
+		})
+	}
+
+	// A fresh buddy folder gets a pointer to the setup wizard
+	if snap.Empty() {
+		items = append(items, InboxItem{
+			Priority: "medium",
+			Kind:     "setup",
+			Title:    "Set up this project's buddy content",
+			Detail:   "The .buddy folder is empty; call buddy_setup to generate starter rules, knowledge and todos",
+			Time:     snap.TakenAt,
+		})
+	}
+
+	// Incomplete todos past their due date; the day of the due date still
+	// counts. Open questions left for a human in the todos come next.
+	for _, todo := range snap.Todos {
+		if todo.Completed {
+			continue
+		}
+		due, ok := todoDueDate(todo.Task, bh.timeFormat.Location())
+		if !ok || !now.After(due.AddDate(0, 0, 1)) {
+			if question, ok := todoQuestion(todo.Task); ok {
+				items = append(items, InboxItem{
+					Priority: "medium",
+					Kind:     "question",
+					Title:    question,
+					Detail:   fmt.Sprintf("[%s] unanswered question", todo.Feature),
+					ID:       todo.ID,
+					FilePath: todo.FilePath,
+					Time:     todo.UpdatedAt.UTC(),
+				})
+			}
+			continue
+		}
+		items = append(items, InboxItem{
+			Priority: "high",
+			Kind:     "overdue_todo",
+			Title:    todo.Task,
+			Detail:   fmt.Sprintf("[%s] due %s", todo.Feature, due.Format("2006-01-02")),
+			ID:       todo.ID,
+			FilePath: todo.FilePath,
+			Time:     due.UTC(),
+		})
+	}
+
+	// Critical rules added or changed recently, so agents pick them up
+	for _, rule := range snap.Rules {
+		if rule.Priority != "critical" || rule.UpdatedAt.Before(now.Add(-inboxRecentWindow)) {
+			continue
+		}
+		items = append(items, InboxItem{
+			Priority: "medium",
+			Kind:     "critical_rule",
+			Title:    rule.Title,
+			Detail:   fmt.Sprintf("Critical %s rule changed recently", rule.Category),
+			ID:       rule.ID,
+			FilePath: rule.FilePath,
+			Time:     rule.UpdatedAt.UTC(),
+		})
+	}
+
+	// Drafts waiting for a human to review and move into place
+	drafts, err := bh.store.List(bh.draftHandler.path, true)
+	if err == nil {
+		for _, draft := range drafts {
+			if !strings.HasSuffix(draft.Path, ".md") {
+				continue
+			}
+			relPath, _ := filepath.Rel(bh.draftHandler.path, draft.Path)
+			items = append(items, InboxItem{
+				Priority: "low",
+				Kind:     "pending_review",
+				Title:    fmt.Sprintf("Review draft %s", filepath.ToSlash(relPath)),
+				FilePath: draft.Path,
+				Time:     draft.ModTime.UTC(),
+			})
+		}
+	} else if !storage.IsNotExist(err) {
+		items = append(items, InboxItem{
+			Priority: "medium",
+			Kind:     "pending_review",
+			Title:    "Drafts could not be listed",
+			Detail:   err.Error(),
+		})
+	}
+
+	// Most urgent first, newest first within a priority
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Priority != items[j].Priority {
+			return inboxPriorityOrder[items[i].Priority] < inboxPriorityOrder[items[j].Priority]
+		}
+		return items[i].Time.After(items[j].Time)
+	})
+
+	return items
+}
+
+// inboxMaxAge bounds how long a cached inbox is served. Overdue todos and
+// drafts change without a reload, so the inbox can't wait for one.
+const inboxMaxAge = time.Minute
+
+// GetInboxResourceHandler returns the resource handler for buddy://inbox
+func (bh *BuddyHandlers) GetInboxResourceHandler() server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return bh.readCachedResource(request, inboxMaxAge, func(snap *ContextSnapshot, now time.Time) (map[string]interface{}, error) {
+			items := bh.collectInbox(snap, now)
+			if items == nil {
+				items = []InboxItem{}
+			}
+			return map[string]interface{}{
+				"generated_at": now.UTC(),
+				"items":        items,
+			}, nil
+		})
+	}
+}
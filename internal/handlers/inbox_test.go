@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoQuestion(t *testing.T) {
+	tests := []struct {
+		task     string
+		question string
+		ok       bool
+	}{
+		{"Question: which queue do billing events use", "which queue do billing events use", true},
+		{"QUESTION:   Keep the v1 API?", "Keep the v1 API?", true},
+		{"Should refunds be partial?", "Should refunds be partial?", true},
+		{"Question:", "", false},
+		{"Ship invoices", "Ship invoices", false},
+		{"Ask about the question? later", "Ask about the question? later", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.task, func(t *testing.T) {
+			question, ok := todoQuestion(tt.task)
+			assert.Equal(t, tt.ok, ok)
+			if ok {
+				assert.Equal(t, tt.question, question)
+			}
+		})
+	}
+}
+
+func TestCollectInbox_GathersAndOrdersItems(t *testing.T) {
+	bh, _ := newTestBuddyHandlers(t, map[string]string{
+		"rules/style.md":        "# Style\nCategory: style\nPriority: critical\n\n- Use gofmt\n",
+		"rules/broken.md":       "# Broken\nExtends: Nowhere\n\n- Wrap errors\n",
+		"todos/billing.md":      "# Feature: billing\n\n- [ ] Ship invoices (due: 2024-01-01)\n- [ ] Question: which currency do refunds use\n- [x] Should we bill yearly?\n- [ ] Is tax charged per region?\n- [ ] Add receipts\n",
+		"drafts/knowledge/a.md": "# Caching\n\nResponses are cached.\n",
+	})
+
+	items := bh.collectInbox(bh.Snapshot(), time.Now())
+
+	var kinds []string
+	titles := make(map[string][]string)
+	for _, item := range items {
+		if len(kinds) == 0 || kinds[len(kinds)-1] != item.Kind {
+			kinds = append(kinds, item.Kind)
+		}
+		titles[item.Kind] = append(titles[item.Kind], item.Title)
+	}
+	// High first, newest first within a priority: the questions were
+	// loaded after the critical rule's file was written
+	assert.Equal(t, []string{"overdue_todo", "rule_extends", "question", "critical_rule", "pending_review"}, kinds)
+	assert.Equal(t, []string{"Ship invoices (due: 2024-01-01)"}, titles["overdue_todo"])
+	assert.ElementsMatch(t, []string{"which currency do refunds use", "Is tax charged per region?"}, titles["question"])
+	assert.Equal(t, []string{"Style"}, titles["critical_rule"])
+	assert.Equal(t, []string{"Review draft knowledge/a.md"}, titles["pending_review"])
+}
+
+func TestCollectInbox_EmptyFolderAsksForSetup(t *testing.T) {
+	bh, _ := newTestBuddyHandlers(t, nil)
+
+	items := bh.collectInbox(bh.Snapshot(), time.Now())
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "setup", items[0].Kind)
+		assert.Equal(t, "medium", items[0].Priority)
+	}
+}
+
+func TestCollectInbox_OverdueQuestionIsListedOnce(t *testing.T) {
+	bh, _ := newTestBuddyHandlers(t, map[string]string{
+		"todos/api.md": "# Feature: api\n\n- [ ] Question: keep v1 (due: 2024-01-01)\n",
+	})
+
+	items := bh.collectInbox(bh.Snapshot(), time.Now())
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "overdue_todo", items[0].Kind)
+	}
+}
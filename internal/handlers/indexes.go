@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+)
+
+// indexCheckInterval is how often the maintenance loop checks whether the
+// indexes are due for compaction or over the size cap
+const indexCheckInterval = time.Minute
+
+// indexDir returns where a project's search indexes live: indexes/ in the
+// buddy folder, or a subdirectory of the configured index directory named
+// after the project so several projects can share it
+func indexDir(buddyPath string, cfg config.Search) (string, error) {
+	if cfg.IndexDir == "" {
+		return filepath.Join(buddyPath, "indexes"), nil
+	}
+
+	absBuddyPath, err := filepath.Abs(buddyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve buddy path: %w", err)
+	}
+	dir := os.ExpandEnv(cfg.IndexDir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(absBuddyPath, dir)
+	}
+	sum := sha256.Sum256([]byte(absBuddyPath))
+	project := fmt.Sprintf("%s-%x", filepath.Base(filepath.Dir(absBuddyPath)), sum[:4])
+	return filepath.Join(dir, project), nil
+}
+
+// IndexUsage is the disk space taken by a project's search indexes
+type IndexUsage struct {
+	Dir           string
+	Bytes         map[string]int64 // per index
+	Total         int64
+	Cap           int64     // bytes; zero means no cap
+	LastCompacted time.Time // zero until the first compaction
+}
+
+// OverCap reports whether the indexes take more space than the cap allows
+func (u IndexUsage) OverCap() bool {
+	return u.Cap > 0 && u.Total > u.Cap
+}
+
+// indexMaintenance tracks compaction of a project's indexes
+type indexMaintenance struct {
+	mu            sync.Mutex // held while compacting
+	lastCompacted time.Time
+	done          chan struct{} // closed when the maintenance loop exits
+}
+
+// IndexUsage measures the disk space of the project's search indexes
+func (bh *BuddyHandlers) IndexUsage() IndexUsage {
+	usage := bh.measureIndexes()
+	bh.indexes.mu.Lock()
+	usage.LastCompacted = bh.indexes.lastCompacted
+	bh.indexes.mu.Unlock()
+	return usage
+}
+
+// measureIndexes is IndexUsage without the time of the last compaction
+func (bh *BuddyHandlers) measureIndexes() IndexUsage {
+	usage := IndexUsage{
+		Dir:   bh.searchManager.Dir(),
+		Bytes: make(map[string]int64),
+		Cap:   int64(bh.Config().Search.MaxIndexMB) * 1024 * 1024,
+	}
+	for indexType, size := range bh.searchManager.DiskUsage() {
+		usage.Bytes[string(indexType)] = size
+		usage.Total += size
+	}
+	return usage
+}
+
+// CompactIndexes compacts the project's search indexes. When they are still
+// over the size cap afterwards, they are rebuilt from the buddy files,
+// which drops everything a compaction can't. It returns the usage before
+// and after.
+func (bh *BuddyHandlers) CompactIndexes(ctx context.Context) (IndexUsage, IndexUsage, error) {
+	bh.indexes.mu.Lock()
+	defer bh.indexes.mu.Unlock()
+	before := bh.measureIndexes()
+	before.LastCompacted = bh.indexes.lastCompacted
+
+	if err := bh.searchManager.Compact(ctx); err != nil {
+		return before, before, err
+	}
+	bh.indexes.lastCompacted = time.Now().UTC()
+
+	after := bh.measureIndexes()
+	after.LastCompacted = bh.indexes.lastCompacted
+	if after.OverCap() {
+		slog.InfoContext(ctx, "search indexes over size cap after compaction; rebuilding", "bytes", after.Total, "cap", after.Cap)
+		if err := bh.loadSections(); err != nil {
+			return before, after, fmt.Errorf("failed to rebuild search indexes: %w", err)
+		}
+		rebuilt := bh.measureIndexes()
+		after.Bytes, after.Total = rebuilt.Bytes, rebuilt.Total
+	}
+
+	slog.InfoContext(ctx, "compacted search indexes", "before_bytes", before.Total, "after_bytes", after.Total)
+	return before, after, nil
+}
+
+// startIndexMaintenance compacts the indexes on the configured schedule
+// and whenever they grow over the size cap, until ctx ends. The schedule
+// and cap are reread on every check, so reloaded settings apply.
+func (bh *BuddyHandlers) startIndexMaintenance(ctx context.Context) {
+	bh.indexes.done = make(chan struct{})
+	started := time.Now()
+
+	go func() {
+		defer close(bh.indexes.done)
+		ticker := time.NewTicker(indexCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			cfg := bh.Config().Search
+			if cfg.CompactMinutes <= 0 && cfg.MaxIndexMB <= 0 {
+				continue
+			}
+			usage := bh.IndexUsage()
+			last := usage.LastCompacted
+			if last.Before(started) {
+				last = started
+			}
+			schedule := time.Duration(cfg.CompactMinutes) * time.Minute
+			due := schedule > 0 && time.Since(last) >= schedule
+			if !due && !usage.OverCap() {
+				continue
+			}
+			if _, _, err := bh.CompactIndexes(ctx); err != nil && ctx.Err() == nil {
+				slog.Warn("failed to compact search indexes", "error", err)
+			}
+		}
+	}()
+}
+
+// formatIndexUsage lists the indexes by size, largest first
+func formatIndexUsage(usage IndexUsage) string {
+	names := make([]string, 0, len(usage.Bytes))
+	for name := range usage.Bytes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if usage.Bytes[names[i]] != usage.Bytes[names[j]] {
+			return usage.Bytes[names[i]] > usage.Bytes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	result := fmt.Sprintf("🗂️ Search indexes: %s in %s", formatFileSize(usage.Total), usage.Dir)
+	if usage.Cap > 0 {
+		result += fmt.Sprintf(" (cap %s)", formatFileSize(usage.Cap))
+	}
+	result += "\n"
+	for i, name := range names {
+		branch := "├─"
+		if i == len(names)-1 {
+			branch = "└─"
+		}
+		result += fmt.Sprintf("%s %s: %s\n", branch, name, formatFileSize(usage.Bytes[name]))
+	}
+	return result
+}
@@ -2,18 +2,20 @@ package handlers
 
 import (
 	"context"
-	"crypto/md5"
 	"fmt"
-	"io/ioutil"
-	"os"
+	"maps"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
-	"sync"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/language"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
 )
 
 // SearchResult represents a search result with score
@@ -25,208 +27,235 @@ type SearchResult struct {
 
 // KnowledgeHandler manages the knowledge base
 type KnowledgeHandler struct {
-	path          string
-	knowledge     []models.Knowledge
-	searchManager *search.SearchManager
-	mu            sync.RWMutex
+	*DocumentHandler[models.Knowledge]
+	fields setting[[]string] // header fields kept as metadata
+	focus  *FocusStore       // ranks results about the focused features first; nil for none
 }
 
 // NewKnowledgeHandler creates a new knowledge handler
 func NewKnowledgeHandler(path string, searchManager *search.SearchManager) *KnowledgeHandler {
-	return &KnowledgeHandler{
-		path:          path,
-		knowledge:     []models.Knowledge{},
-		searchManager: searchManager,
-	}
-}
-
-// Load loads all knowledge from the knowledge directory
-func (kh *KnowledgeHandler) Load() error {
-	kh.mu.Lock()
-	defer kh.mu.Unlock()
-
-	kh.knowledge = []models.Knowledge{}
-
-	// First, reindex all knowledge
-	if err := kh.searchManager.ReindexAll(search.IndexTypeKnowledge); err != nil {
-		return fmt.Errorf("failed to reindex knowledge: %w", err)
-	}
-
-	err := filepath.Walk(kh.path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".md") {
-			kb, err := kh.loadKnowledgeFile(path)
-			if err != nil {
-				return fmt.Errorf("failed to load knowledge %s: %w", path, err)
-			}
-			kh.knowledge = append(kh.knowledge, kb)
-
-			// Index the knowledge in Bleve
-			doc := search.FromKnowledge(kb)
-			if err := kh.searchManager.IndexDocument(search.IndexTypeKnowledge, kb.ID, doc); err != nil {
-				return fmt.Errorf("failed to index knowledge %s: %w", kb.ID, err)
-			}
-		}
-
-		return nil
+	kh := &KnowledgeHandler{}
+	kh.DocumentHandler = NewDocumentHandler(path, searchManager, DocumentSpec[models.Knowledge]{
+		IndexType:  search.IndexTypeKnowledge,
+		Extensions: []string{".md", ".adoc", ".asciidoc", ".rst"},
+		Truncate:   truncateText,
+		Recursive:  true,
+		Parse:      kh.parseKnowledgeFile,
+		ID:         func(kb models.Knowledge) string { return kb.ID },
+		Index:      func(kb models.Knowledge) interface{} { return search.FromKnowledge(kb) },
 	})
-
-	if err != nil && !os.IsNotExist(err) {
-		return err
-	}
-
-	return nil
+	return kh
 }
 
-// loadKnowledgeFile loads a single knowledge file
-func (kh *KnowledgeHandler) loadKnowledgeFile(filePath string) (models.Knowledge, error) {
-	content, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return models.Knowledge{}, err
-	}
-
-	// Parse the knowledge file
-	lines := strings.Split(string(content), "\n")
-	var title, category string
-	var tags []string
-	var contentStart int
-
-	// Extract metadata from the first few lines
-	for i, line := range lines {
-		if strings.HasPrefix(line, "# ") {
-			title = strings.TrimPrefix(line, "# ")
-		} else if strings.HasPrefix(line, "Category: ") {
-			category = strings.TrimPrefix(line, "Category: ")
-		} else if strings.HasPrefix(line, "Tags: ") {
-			tagStr := strings.TrimPrefix(line, "Tags: ")
-			tags = strings.Split(tagStr, ", ")
-		} else if line == "" && i > 0 {
-			contentStart = i + 1
-			break
-		}
-	}
-
-	// Extract content
-	contentText := ""
-	if contentStart < len(lines) {
-		contentText = strings.Join(lines[contentStart:], "\n")
+// parseKnowledgeFile parses a single knowledge file, picking the format
+// from the file extension
+func (kh *KnowledgeHandler) parseKnowledgeFile(file storage.FileInfo, content []byte) ([]models.Knowledge, error) {
+	filePath := file.Path
+
+	var doc knowledgeDocument
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".adoc", ".asciidoc":
+		doc = parseAsciiDocKnowledge(string(content))
+	case ".rst":
+		doc = parseRSTKnowledge(string(content))
+	default:
+		doc = parseMarkdownKnowledge(string(content))
 	}
 
-	// Generate ID from file path
-	id := fmt.Sprintf("%x", md5.Sum([]byte(filePath)))
+	id := documentID(kh.path, filePath)
 
 	// Determine category from path if not specified
-	if category == "" {
+	if doc.category == "" {
 		relPath, _ := filepath.Rel(kh.path, filePath)
 		parts := strings.Split(relPath, string(filepath.Separator))
 		if len(parts) > 1 {
-			category = parts[0]
+			doc.category = parts[0]
 		}
 	}
 
-	fileInfo, _ := os.Stat(filePath)
+	var metadata map[string]string
+	for _, field := range kh.fields.Load() {
+		field = strings.ToLower(field)
+		if value, ok := doc.fields[field]; ok {
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			metadata[field] = value
+		}
+	}
 
-	return models.Knowledge{
+	// A Language header names the language when detection would guess wrong
+	lang, ok := language.Parse(doc.fields["language"])
+	if !ok {
+		lang = language.Detect(doc.title + "\n" + doc.content)
+	}
+
+	return []models.Knowledge{{
 		ID:        id,
-		Title:     title,
-		Category:  category,
-		Content:   contentText,
-		Tags:      tags,
+		Title:     doc.title,
+		Category:  doc.category,
+		Content:   doc.content,
+		Tags:      doc.tags,
+		Metadata:  metadata,
+		Language:  lang,
 		FilePath:  filePath,
-		UpdatedAt: fileInfo.ModTime(),
-	}, nil
+		UpdatedAt: file.ModTime,
+	}}, nil
 }
 
 // GetKnowledge returns all loaded knowledge
 func (kh *KnowledgeHandler) GetKnowledge() []models.Knowledge {
-	kh.mu.RLock()
-	defer kh.mu.RUnlock()
-	return kh.knowledge
+	return kh.Documents()
 }
 
 // GetKnowledgeByCategory returns knowledge filtered by category
 func (kh *KnowledgeHandler) GetKnowledgeByCategory(category string) []models.Knowledge {
-	kh.mu.RLock()
-	defer kh.mu.RUnlock()
+	return kh.Filter(func(kb models.Knowledge) bool {
+		return kb.Category == category
+	})
+}
 
-	var filtered []models.Knowledge
-	for _, kb := range kh.knowledge {
-		if kb.Category == category {
-			filtered = append(filtered, kb)
-		}
-	}
-	return filtered
+// KnowledgeArgs are the arguments of buddy_search_knowledge
+type KnowledgeArgs struct {
+	Query       string            `arg:"query" desc:"Search query to find relevant knowledge (required unless queries is given)"`
+	Queries     []string          `arg:"queries" desc:"Up to 10 related queries to run in parallel; repeats run once and results are merged and deduplicated (optional)"`
+	Category    string            `arg:"category" desc:"Filter by category (optional)"`
+	Metadata    map[string]string `arg:"metadata" desc:"Only knowledge whose header fields (knowledge.fields in config.yaml) have these values, e.g. {\"service\": \"payments\"} (optional)"`
+	Language    string            `arg:"language" desc:"Only knowledge in this language, as a code or name, e.g. de or German; the query is analyzed like that language's documents (optional)"`
+	Answer      bool              `arg:"answer" desc:"Answer the query in a few sentences with numbered citations (document ID and heading) instead of listing results; written by the client's model when it supports sampling (optional)"`
+	TopK        int               `arg:"top_k" desc:"Number of passages an answer draws on (default: 5)"`
+	IgnoreFocus bool              `arg:"ignore_focus" desc:"Rank results without favouring the features set with buddy_focus (optional)"`
+	ListArgs
+	TokenBudgetArgs
 }
 
 // GetToolHandler returns the tool handler function for knowledge
 func (kh *KnowledgeHandler) GetToolHandler() server.ToolHandlerFunc {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		args := request.GetArguments()
-		query, ok := args["query"].(string)
-		if !ok {
-			return nil, fmt.Errorf("query is required")
+	return toolargs.Handler(kh.search)
+}
+
+// search runs a buddy_search_knowledge call that lists results
+func (kh *KnowledgeHandler) search(ctx context.Context, args KnowledgeArgs) (*mcp.CallToolResult, error) {
+	query := args.Query
+
+	var queries []string
+	for _, q := range args.Queries {
+		if strings.TrimSpace(q) != "" {
+			queries = append(queries, q)
 		}
+	}
+	if query == "" && len(queries) == 0 {
+		return nil, fmt.Errorf("query or queries is required")
+	}
+
+	// Use Bleve search
+	filters, err := searchFilters(args)
+	if err != nil {
+		return nil, err
+	}
 
-		category, _ := args["category"].(string)
+	// Documents about the focused features rank first; the note is
+	// left out of JSON output
+	focused := kh.focus.Active(args.IgnoreFocus)
+	note := ""
+	if args.Output != "json" {
+		note = focusNote(focused, false)
+	}
 
-		// Use Bleve search
-		filters := make(map[string]interface{})
-		if category != "" {
-			filters["category"] = category
+	if len(queries) > 0 {
+		if query != "" {
+			queries = append([]string{query}, queries...)
+		}
+		// Deduplicated here too so the results list each query once
+		if queries, err = uniqueQueries(queries); err != nil {
+			return nil, err
 		}
 
-		searchResults, err := kh.searchManager.SearchWithFilters(
-			search.IndexTypeKnowledge,
-			query,
-			filters,
-			50, // Limit to 50 results
-		)
+		hits, err := kh.SearchDocumentsMulti(ctx, queries, filters, 50)
 		if err != nil {
-			return nil, fmt.Errorf("search failed: %w", err)
+			return nil, err
 		}
+		hits = focusedFirst(hits, func(hit MultiQueryHit[models.Knowledge]) bool {
+			return aboutFeatures(hit.Document, focused)
+		})
 
-		// Convert search results to knowledge entries
-		var results []models.Knowledge
-		for _, hit := range searchResults.Hits {
-			// Find the knowledge by ID
-			for _, kb := range kh.knowledge {
-				if kb.ID == hit.ID {
-					results = append(results, kb)
-					break
-				}
-			}
+		result, err := renderList(hits, args.ListArgs, func(page []MultiQueryHit[models.Knowledge]) string {
+			return kh.formatMultiSearchResults(ctx, queries, page)
+		})
+		if err != nil {
+			return nil, err
 		}
 
-		// Enhanced result formatting
-		result := kh.formatSearchResults(query, results)
+		return mcp.NewToolResultText(note + result), nil
+	}
+
+	results, err := kh.SearchDocuments(ctx, query, filters, 50) // Limit to 50 results
+	if err != nil {
+		return nil, err
+	}
+	results = focusedFirst(results, func(kb models.Knowledge) bool {
+		return aboutFeatures(kb, focused)
+	})
+
+	// Enhanced result formatting
+	result, err := kh.RenderList(results, args.ListArgs, func(page []models.Knowledge) string {
+		return kh.formatSearchResults(ctx, query, page)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(note + result), nil
+}
+
+// searchFilters builds the index filters of a knowledge search from its
+// category, language and metadata arguments
+func searchFilters(args KnowledgeArgs) (map[string]interface{}, error) {
+	filters := make(map[string]interface{})
+	if args.Category != "" {
+		filters["category"] = args.Category
+	}
+	if name := args.Language; name != "" {
+		code, ok := language.Parse(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown language %q (expected one of %s)", name, strings.Join(language.Codes(), ", "))
+		}
+		filters["language"] = code
+	}
+	for field, value := range args.Metadata {
+		filters[search.MetadataField(field)] = search.MetadataValue(value)
+	}
+	return filters, nil
+}
 
-		return mcp.NewToolResultText(result), nil
+// aboutFeatures reports whether a document's title, category, tags or
+// content mention any of the features
+func aboutFeatures(kb models.Knowledge, features []string) bool {
+	if len(features) == 0 {
+		return false
 	}
+	return mentionsAny(kb.Title+"\n"+kb.Category+"\n"+strings.Join(kb.Tags, " ")+"\n"+kb.Content, features)
 }
 
-// formatSearchResults formats search results with better context
-func (kh *KnowledgeHandler) formatSearchResults(query string, results []models.Knowledge) string {
+// formatSearchResults formats search results with better context, or
+// suggestions when nothing matched
+func (kh *KnowledgeHandler) formatSearchResults(ctx context.Context, query string, results []models.Knowledge) string {
 	if len(results) == 0 {
 		result := fmt.Sprintf("No results found for: %s\n", query)
 
 		// Get document count to show available knowledge
 		count, _ := kh.searchManager.GetDocumentCount(search.IndexTypeKnowledge)
 		if count > 0 {
-			result += fmt.Sprintf("\nThere are %d knowledge entries available. Try:\n", count)
-			result += "- Using different keywords\n"
-			result += "- Searching for broader terms\n"
-			result += "- Checking available categories\n"
+			result += fmt.Sprintf("\nThere are %d knowledge entries available.\n", count)
+			result += formatSuggestions(ctx, kh.searchManager, search.IndexTypeKnowledge, query, "category", "categories")
 		}
 
 		result += "\nAvailable categories:"
 		categories := make(map[string]bool)
-		for _, kb := range kh.knowledge {
+		for _, kb := range kh.GetKnowledge() {
 			categories[kb.Category] = true
 		}
-		for category := range categories {
+		for _, category := range slices.Sorted(maps.Keys(categories)) {
 			result += fmt.Sprintf("\n- %s", category)
 		}
 
@@ -241,6 +270,10 @@ func (kh *KnowledgeHandler) formatSearchResults(query string, results []models.K
 		if len(kb.Tags) > 0 {
 			result += fmt.Sprintf("   Tags: %s\n", strings.Join(kb.Tags, ", "))
 		}
+		if kb.Language != "" && kb.Language != "en" {
+			result += fmt.Sprintf("   Language: %s\n", language.Name(kb.Language))
+		}
+		result += formatMetadata(kb.Metadata)
 
 		// Show content preview
 		content := strings.TrimSpace(kb.Content)
@@ -257,3 +290,61 @@ func (kh *KnowledgeHandler) formatSearchResults(query string, results []models.K
 
 	return result
 }
+
+// formatMultiSearchResults formats merged results of a multi-query search,
+// showing which queries matched each entry
+func (kh *KnowledgeHandler) formatMultiSearchResults(ctx context.Context, queries []string, hits []MultiQueryHit[models.Knowledge]) string {
+	quoted := make([]string, len(queries))
+	for i, query := range queries {
+		quoted[i] = fmt.Sprintf("%q", query)
+	}
+
+	if len(hits) == 0 {
+		return fmt.Sprintf("No results found for any of: %s\n", strings.Join(quoted, ", ")) +
+			formatSuggestions(ctx, kh.searchManager, search.IndexTypeKnowledge, strings.Join(queries, " "), "category", "categories")
+	}
+
+	result := fmt.Sprintf("Found %d knowledge entries for %d queries: %s\n", len(hits), len(queries), strings.Join(quoted, ", "))
+
+	for i, hit := range hits {
+		kb := hit.Document
+		result += fmt.Sprintf("\n%d. [%s] %s\n", i+1, kb.Category, kb.Title)
+		result += fmt.Sprintf("   Matched: %s\n", strings.Join(hit.Queries, ", "))
+		if len(kb.Tags) > 0 {
+			result += fmt.Sprintf("   Tags: %s\n", strings.Join(kb.Tags, ", "))
+		}
+		if kb.Language != "" && kb.Language != "en" {
+			result += fmt.Sprintf("   Language: %s\n", language.Name(kb.Language))
+		}
+		result += formatMetadata(kb.Metadata)
+
+		// Show content preview
+		content := strings.TrimSpace(kb.Content)
+		if len(content) > 200 {
+			content = content[:200] + "..."
+		}
+		result += fmt.Sprintf("   %s\n", content)
+
+		if i < len(hits)-1 {
+			result += "\n" + strings.Repeat("-", 50) + "\n"
+		}
+	}
+
+	return result
+}
+
+// formatMetadata lists a document's metadata fields, one per line, sorted
+// by name
+func formatMetadata(metadata map[string]string) string {
+	fields := make([]string, 0, len(metadata))
+	for field := range metadata {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var result string
+	for _, field := range fields {
+		result += fmt.Sprintf("   %s: %s\n", field, metadata[field])
+	}
+	return result
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"strings"
+	"unicode"
+)
+
+// knowledgeDocument holds the fields extracted from a knowledge file,
+// whatever its markup format
+type knowledgeDocument struct {
+	title    string
+	category string
+	tags     []string
+	content  string
+	fields   map[string]string // other header fields, by lowercase name
+}
+
+// setField keeps a header field other than category and tags
+func (doc *knowledgeDocument) setField(name, value string) {
+	if doc.fields == nil {
+		doc.fields = make(map[string]string)
+	}
+	doc.fields[strings.ToLower(name)] = value
+}
+
+// markdownFieldName reports whether name can be a "Name: value" header
+// field: a single word of letters, digits, hyphens and underscores
+func markdownFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitTags splits a comma-separated tag list, dropping empty entries
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// parseMarkdownKnowledge reads a "# Title" heading followed by optional
+// "Category:", "Tags:" and other "Name: value" lines; the body starts
+// after the first blank line
+func parseMarkdownKnowledge(text string) knowledgeDocument {
+	var doc knowledgeDocument
+	lines := strings.Split(text, "\n")
+	var contentStart int
+
+	// Extract metadata from the first few lines
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# ") {
+			doc.title = strings.TrimPrefix(line, "# ")
+		} else if strings.HasPrefix(line, "Category: ") {
+			doc.category = strings.TrimPrefix(line, "Category: ")
+		} else if strings.HasPrefix(line, "Tags: ") {
+			tagStr := strings.TrimPrefix(line, "Tags: ")
+			doc.tags = strings.Split(tagStr, ", ")
+		} else if line == "" && i > 0 {
+			contentStart = i + 1
+			break
+		} else if name, value, ok := strings.Cut(line, ": "); ok && markdownFieldName(name) {
+			doc.setField(name, strings.TrimSpace(value))
+		}
+	}
+
+	// Extract content
+	if contentStart < len(lines) {
+		doc.content = strings.Join(lines[contentStart:], "\n")
+	}
+
+	return doc
+}
+
+// parseAsciiDocKnowledge reads an AsciiDoc document header: a "= Title"
+// line followed by attribute entries such as ":category: api" and
+// ":tags: auth, jwt" (":keywords:" is accepted for tags). The header ends
+// at the first blank line; section titles ("== Section") stay in the body.
+func parseAsciiDocKnowledge(text string) knowledgeDocument {
+	var doc knowledgeDocument
+	lines := strings.Split(text, "\n")
+
+	i := 0
+	// Skip leading comments and blank lines before the title
+	for i < len(lines) && (strings.TrimSpace(lines[i]) == "" || strings.HasPrefix(lines[i], "//")) {
+		i++
+	}
+
+	if i < len(lines) && strings.HasPrefix(lines[i], "= ") {
+		doc.title = strings.TrimSpace(strings.TrimPrefix(lines[i], "= "))
+		i++
+
+		for ; i < len(lines); i++ {
+			line := strings.TrimRight(lines[i], "\r")
+			if strings.TrimSpace(line) == "" {
+				i++
+				break
+			}
+			name, value, ok := parseFieldLine(line)
+			if !ok {
+				// Author and revision lines are part of the header too
+				continue
+			}
+			switch name {
+			case "category":
+				doc.category = value
+			case "tags", "keywords":
+				doc.tags = splitTags(value)
+			default:
+				doc.setField(name, value)
+			}
+		}
+	}
+
+	if i < len(lines) {
+		doc.content = strings.Join(lines[i:], "\n")
+	}
+	return doc
+}
+
+// rstAdornment reports whether a line is a reStructuredText section
+// adornment: a run of one repeated punctuation character
+func rstAdornment(line string) bool {
+	line = strings.TrimRight(line, " \r")
+	if len(line) < 2 || !strings.ContainsRune("=-`:'\"~^_*+#<>.", rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// parseRSTKnowledge reads a reStructuredText document title (a line
+// underlined, and optionally overlined, with punctuation) followed by a
+// field list such as ":category: api" and ":tags: auth, jwt". Section
+// titles after the header stay in the body.
+func parseRSTKnowledge(text string) knowledgeDocument {
+	var doc knowledgeDocument
+	lines := strings.Split(text, "\n")
+
+	i := 0
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+
+	// Overlined title: adornment, title, adornment
+	if i+2 < len(lines) && rstAdornment(lines[i]) && rstAdornment(lines[i+2]) {
+		doc.title = strings.TrimSpace(lines[i+1])
+		i += 3
+	} else if i+1 < len(lines) && !rstAdornment(lines[i]) && rstAdornment(lines[i+1]) {
+		doc.title = strings.TrimSpace(lines[i])
+		i += 2
+	}
+
+	// Field list directly after the title, possibly after blank lines
+	j := i
+	for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+		j++
+	}
+	fieldsFound := false
+	for ; j < len(lines); j++ {
+		name, value, ok := parseFieldLine(lines[j])
+		if !ok {
+			break
+		}
+		fieldsFound = true
+		switch name {
+		case "category":
+			doc.category = value
+		case "tags", "keywords":
+			doc.tags = splitTags(value)
+		default:
+			doc.setField(name, value)
+		}
+	}
+	if fieldsFound {
+		i = j
+	}
+
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	if i < len(lines) {
+		doc.content = strings.Join(lines[i:], "\n")
+	}
+	return doc
+}
+
+// parseFieldLine parses a ":name: value" line as used by AsciiDoc
+// attributes and reStructuredText field lists
+func parseFieldLine(line string) (string, string, bool) {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasPrefix(line, ":") {
+		return "", "", false
+	}
+	end := strings.Index(line[1:], ":")
+	if end <= 0 {
+		return "", "", false
+	}
+	name := strings.ToLower(strings.TrimSpace(line[1 : end+1]))
+	value := strings.TrimSpace(line[end+2:])
+	return name, value, true
+}
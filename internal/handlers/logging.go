@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/logging"
+)
+
+// requestIDMetaField is the result _meta field carrying the request ID
+const requestIDMetaField = "buddy/requestId"
+
+// TraceRequests is tool handler middleware that gives every call a request
+// ID. The ID travels in the call's context into log lines and safety
+// snapshots, is returned in the result's _meta and is appended to errors,
+// so a failed step of an agent's run can be found in the server's logs.
+// Register it before other middleware so they see the ID.
+func TraceRequests(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id := logging.NewRequestID()
+		result, err := next(logging.WithRequestID(ctx, id), request)
+		if err != nil {
+			return result, fmt.Errorf("%w (request %s)", err, id)
+		}
+
+		if result != nil {
+			if result.Meta == nil {
+				result.Meta = make(map[string]any)
+			}
+			result.Meta[requestIDMetaField] = id
+			if result.IsError {
+				result.Content = append(result.Content, mcp.NewTextContent("Request ID: "+id))
+			}
+		}
+		return result, nil
+	}
+}
+
+// LogToolCalls is tool handler middleware that logs every call with its
+// duration. Failed calls are logged as warnings, others at debug level;
+// argument values are left out because they may hold file contents.
+func LogToolCalls(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		attrs := []any{"tool", request.Params.Name, "duration", time.Since(start)}
+		if action, ok := request.GetArguments()["action"].(string); ok {
+			attrs = append(attrs, "action", action)
+		}
+		if project, ok := request.GetArguments()[ProjectArgument].(string); ok {
+			attrs = append(attrs, "project", project)
+		}
+
+		switch {
+		case err != nil:
+			slog.WarnContext(ctx, "tool call failed", append(attrs, "error", err)...)
+		case result != nil && result.IsError:
+			slog.WarnContext(ctx, "tool call returned an error result", attrs...)
+		default:
+			slog.DebugContext(ctx, "tool call", attrs...)
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/metrics"
+)
+
+var (
+	toolCalls      = metrics.Default.NewCounterVec("buddy_tool_calls_total", "Tool calls by tool and outcome.", "tool", "status")
+	toolDuration   = metrics.Default.NewHistogramVec("buddy_tool_call_duration_seconds", "Tool call latency in seconds.", nil, "tool")
+	reloadDuration = metrics.Default.NewHistogramVec("buddy_reload_duration_seconds", "Time to reload a content section in seconds.", nil, "section")
+	reloadFailures = metrics.Default.NewCounterVec("buddy_reload_failures_total", "Content section reloads that failed.", "section")
+)
+
+// RecordToolMetrics is tool handler middleware counting calls by outcome
+// and recording their latency
+func RecordToolMetrics(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		status := "ok"
+		if err != nil || (result != nil && result.IsError) {
+			status = "error"
+		}
+		toolCalls.Inc(request.Params.Name, status)
+		toolDuration.Observe(time.Since(start).Seconds(), request.Params.Name)
+
+		return result, err
+	}
+}
+
+// IndexDocumentCounts returns the number of documents in each search index
+func (bh *BuddyHandlers) IndexDocumentCounts() map[string]uint64 {
+	counts := make(map[string]uint64)
+	for indexType, count := range bh.searchManager.DocumentCounts() {
+		counts[string(indexType)] = count
+	}
+	return counts
+}
@@ -0,0 +1,549 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+	"gopkg.in/yaml.v3"
+)
+
+// apiDir is the buddy subdirectory holding OpenAPI (or Swagger 2) specs
+const apiDir = "api"
+
+// mockListSize is how many items mocked lists and arrays hold by default
+const mockListSize = 3
+
+// openAPISpec is a parsed spec file, kept as generic JSON values so both
+// OpenAPI 3 and Swagger 2 documents can be read
+type openAPISpec struct {
+	file string
+	doc  map[string]interface{}
+}
+
+// mockOperation is a spec operation matched against a request path
+type mockOperation struct {
+	spec      *openAPISpec
+	method    string
+	path      string // the spec's path template
+	operation map[string]interface{}
+	n         int // the row mocked values are generated for
+}
+
+// loadOpenAPISpecs reads the JSON and YAML files in the api folder that
+// declare an openapi or swagger version. A missing folder has no specs.
+func (bh *BuddyHandlers) loadOpenAPISpecs() ([]openAPISpec, error) {
+	files, err := bh.store.List(filepath.Join(bh.buddyPath, apiDir), true)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var specs []openAPISpec
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Path))
+		if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		content, err := bh.store.Read(file.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		var raw interface{}
+		if ext == ".json" {
+			err = json.Unmarshal(content, &raw)
+		} else {
+			err = yaml.Unmarshal(content, &raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid API spec %s: %w", filepath.Base(file.Path), err)
+		}
+		doc, ok := normalizeYAML(raw).(map[string]interface{})
+		if !ok || (doc["openapi"] == nil && doc["swagger"] == nil) {
+			continue
+		}
+		specs = append(specs, openAPISpec{file: file.Path, doc: doc})
+	}
+	return specs, nil
+}
+
+// normalizeYAML converts the maps YAML decodes with non-string keys, such
+// as unquoted response codes, to the string-keyed maps JSON uses
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return m
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// findOperation finds the operation serving method and path, a concrete
+// request path such as /users/42. When several path templates match, the
+// one with the most literal segments wins, so /users/me beats /users/{id}.
+func findOperation(specs []openAPISpec, method, path string) (*mockOperation, error) {
+	method = strings.ToLower(method)
+	if idx := strings.IndexAny(path, "?#"); idx >= 0 {
+		path = path[:idx]
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	var (
+		best        *mockOperation
+		bestLiteral = -1
+	)
+	for i := range specs {
+		paths, _ := specs[i].doc["paths"].(map[string]interface{})
+		templates := make([]string, 0, len(paths))
+		for template := range paths {
+			templates = append(templates, template)
+		}
+		sort.Strings(templates)
+
+		for _, template := range templates {
+			literal, n, ok := matchPathTemplate(template, segments)
+			if !ok || literal <= bestLiteral {
+				continue
+			}
+			item, _ := paths[template].(map[string]interface{})
+			operation, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			best = &mockOperation{spec: &specs[i], method: strings.ToUpper(method), path: template, operation: operation, n: n}
+			bestLiteral = literal
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no operation for %s %s in the API specs", strings.ToUpper(method), path)
+	}
+	return best, nil
+}
+
+// matchPathTemplate reports whether a path template such as /users/{id}
+// matches the request path segments, how many of its segments are literal,
+// and the row to mock: the last numeric path parameter, or 1
+func matchPathTemplate(template string, segments []string) (int, int, bool) {
+	parts := strings.Split(strings.Trim(template, "/"), "/")
+	if len(parts) != len(segments) {
+		return 0, 0, false
+	}
+	literal, n := 0, 1
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if segments[i] == "" {
+				return 0, 0, false
+			}
+			if value, err := strconv.Atoi(segments[i]); err == nil && value > 0 {
+				n = value
+			}
+			continue
+		}
+		if part != segments[i] {
+			return 0, 0, false
+		}
+		literal++
+	}
+	return literal, n, true
+}
+
+// mockResponse generates the body of one of the operation's responses: the
+// given status, or the first 2xx one, or default. The body is nil when the
+// response has none.
+func (op *mockOperation) mockResponse(status string) (string, string, interface{}, error) {
+	responses, _ := op.operation["responses"].(map[string]interface{})
+	if status == "" {
+		codes := make([]string, 0, len(responses))
+		for code := range responses {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			if strings.HasPrefix(code, "2") {
+				status = code
+				break
+			}
+		}
+		if status == "" && responses["default"] != nil {
+			status = "default"
+		}
+	}
+	response, ok := responses[status].(map[string]interface{})
+	if !ok {
+		if status == "" {
+			status = "success"
+		}
+		return "", "", nil, fmt.Errorf("%s %s has no %s response", op.method, op.path, status)
+	}
+	response = op.resolve(response)
+
+	g := &mockGenerator{doc: op.spec.doc}
+
+	// Swagger 2 puts the schema and examples on the response itself
+	if schema, ok := response["schema"]; ok {
+		if examples, ok := response["examples"].(map[string]interface{}); ok && examples["application/json"] != nil {
+			return status, "application/json", examples["application/json"], nil
+		}
+		return status, "application/json", g.value(schema, "", op.n), nil
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return status, "", nil, nil
+	}
+	mediaType := ""
+	if content["application/json"] != nil {
+		mediaType = "application/json"
+	} else {
+		types := make([]string, 0, len(content))
+		for name := range content {
+			types = append(types, name)
+		}
+		sort.Strings(types)
+		mediaType = types[0]
+		for _, name := range types {
+			if strings.Contains(name, "json") {
+				mediaType = name
+				break
+			}
+		}
+	}
+
+	media, _ := content[mediaType].(map[string]interface{})
+	if example, ok := media["example"]; ok {
+		return status, mediaType, example, nil
+	}
+	if examples, ok := media["examples"].(map[string]interface{}); ok && len(examples) > 0 {
+		names := make([]string, 0, len(examples))
+		for name := range examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if example, ok := op.resolve(examples[names[0]])["value"]; ok {
+			return status, mediaType, example, nil
+		}
+	}
+	return status, mediaType, g.value(media["schema"], "", op.n), nil
+}
+
+// resolve follows a $ref in the operation's spec, if value is one
+func (op *mockOperation) resolve(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	if ref, ok := m["$ref"].(string); ok {
+		if target, ok := resolveRef(op.spec.doc, ref).(map[string]interface{}); ok {
+			return target
+		}
+	}
+	return m
+}
+
+// resolveRef looks up a local JSON pointer such as
+// #/components/schemas/User; references to other files aren't followed
+func resolveRef(doc map[string]interface{}, ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var current interface{} = doc
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[token]
+	}
+	return current
+}
+
+// mockGenerator produces deterministic values for JSON schemas, named like
+// table columns so they read the same as fixtures
+type mockGenerator struct {
+	doc  map[string]interface{}
+	refs []string // references being expanded, to stop at cycles
+}
+
+// value generates the value of a schema for the n-th row (1-based); name
+// is the property holding it, if any
+func (g *mockGenerator) value(schemaValue interface{}, name string, n int) interface{} {
+	schema, ok := schemaValue.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		for _, expanding := range g.refs {
+			if expanding == ref {
+				return nil
+			}
+		}
+		g.refs = append(g.refs, ref)
+		defer func() { g.refs = g.refs[:len(g.refs)-1] }()
+		return g.value(resolveRef(g.doc, ref), name, n)
+	}
+
+	if example, ok := schema["example"]; ok {
+		return example
+	}
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[(n-1)%len(examples)]
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[(n-1)%len(enum)]
+	}
+	if constant, ok := schema["const"]; ok {
+		return constant
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		merged := make(map[string]interface{})
+		for _, part := range allOf {
+			if object, ok := g.value(part, name, n).(map[string]interface{}); ok {
+				for key, value := range object {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if options, ok := schema[key].([]interface{}); ok && len(options) > 0 {
+			return g.value(options[0], name, n)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		object := make(map[string]interface{})
+		properties, _ := schema["properties"].(map[string]interface{})
+		for property, propertySchema := range properties {
+			object[property] = g.value(propertySchema, property, n)
+		}
+		if len(properties) == 0 {
+			if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				object["key1"] = g.value(additional, name, n)
+			}
+		}
+		return object
+	case "array":
+		size := mockListSize
+		if minItems, ok := schema["minItems"].(float64); ok && minItems > 0 {
+			size = int(minItems)
+		} else if minItems, ok := schema["minItems"].(int); ok && minItems > 0 {
+			size = minItems
+		}
+		items := make([]interface{}, size)
+		for i := range items {
+			items[i] = g.value(schema["items"], singular(name), (n-1)*size+i+1)
+		}
+		return items
+	case "integer":
+		if minimum, ok := numberField(schema, "minimum"); ok {
+			return int(minimum) + n - 1
+		}
+		return fakeValue(models.Column{Name: name, Type: "integer"}, n)
+	case "number":
+		return fakeValue(models.Column{Name: name, Type: "numeric"}, n)
+	case "boolean":
+		return fakeValue(models.Column{Name: name, Type: "boolean"}, n)
+	case "null":
+		return nil
+	}
+
+	if name == "" {
+		name = "value"
+	}
+	format, _ := schema["format"].(string)
+	var value string
+	switch format {
+	case "date-time":
+		value = fakeValue(models.Column{Name: name, Type: "timestamp"}, n).(string)
+	case "date":
+		value = fmt.Sprintf("2024-01-%02d", (n-1)%28+1)
+	case "uuid":
+		value = fakeValue(models.Column{Name: name, Type: "uuid"}, n).(string)
+	case "email":
+		value = fakeValue(models.Column{Name: "email", Type: "text"}, n).(string)
+	case "uri", "url":
+		value = fmt.Sprintf("https://example.com/%s/%d", strings.ToLower(name), n)
+	default:
+		value = fakeValue(models.Column{Name: name, Type: "text"}, n).(string)
+	}
+	if maxLength, ok := numberField(schema, "maxLength"); ok && maxLength > 0 && len(value) > int(maxLength) {
+		value = value[:int(maxLength)]
+	}
+	return value
+}
+
+// schemaType returns a schema's type, inferring object and array from
+// their keywords. OpenAPI 3.1 type lists use their first non-null type.
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, item := range t {
+			if name, ok := item.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	if schema["properties"] != nil || schema["additionalProperties"] != nil {
+		return "object"
+	}
+	if schema["items"] != nil {
+		return "array"
+	}
+	return "string"
+}
+
+// numberField reads a numeric schema keyword decoded from JSON or YAML
+func numberField(schema map[string]interface{}, key string) (float64, bool) {
+	switch v := schema[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// MockTable generates a mocked REST response for a table: the row with the
+// given id, or a list of count rows when id is zero. Rows are the JSON
+// fixtures, so the same id always yields the same row and foreign key
+// values point at rows the referenced table's mock returns.
+func (dh *DatabaseHandler) MockTable(tableName string, id, count int) (interface{}, error) {
+	table := dh.GetTableByName(tableName)
+	if table == nil {
+		return nil, fmt.Errorf("table '%s' not found in schema", tableName)
+	}
+	if len(table.Columns) == 0 {
+		return nil, fmt.Errorf("table '%s' has no parsed columns", tableName)
+	}
+
+	row := func(n int) map[string]interface{} {
+		values := make(map[string]interface{}, len(table.Columns))
+		for _, col := range table.Columns {
+			values[col.Name] = fakeValue(col, n)
+		}
+		return values
+	}
+	if id > 0 {
+		return row(id), nil
+	}
+	if count <= 0 {
+		count = mockListSize
+	}
+	rows := make([]map[string]interface{}, count)
+	for i := range rows {
+		rows[i] = row(i + 1)
+	}
+	return rows, nil
+}
+
+// MockEndpoint generates a response for a request to an operation in the
+// OpenAPI specs of the api folder
+func (bh *BuddyHandlers) MockEndpoint(method, path, status string) (string, interface{}, error) {
+	specs, err := bh.loadOpenAPISpecs()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(specs) == 0 {
+		return "", nil, fmt.Errorf("no OpenAPI specs found; add openapi.yaml or openapi.json to %s/", apiDir)
+	}
+	if method == "" {
+		method = "GET"
+	}
+	op, err := findOperation(specs, method, path)
+	if err != nil {
+		return "", nil, err
+	}
+	code, mediaType, body, err := op.mockResponse(status)
+	if err != nil {
+		return "", nil, err
+	}
+
+	header := fmt.Sprintf("Mock response for %s %s (%s %s", op.method, path, filepath.Base(op.spec.file), op.path)
+	header += ", status " + code
+	if mediaType != "" {
+		header += ", " + mediaType
+	}
+	return header + ")", body, nil
+}
+
+// MockArgs are the arguments of buddy_mock
+type MockArgs struct {
+	TableName string `arg:"table_name" desc:"Table to mock GET /<table> or GET /<table>/<id> for"`
+	ID        int    `arg:"id" desc:"Row to return instead of a list; the same id always gives the same row (optional for table_name)"`
+	Count     int    `arg:"count" desc:"Number of rows in a list (default: 3)"`
+	Path      string `arg:"path" desc:"Request path to mock from the OpenAPI specs, e.g. /users/42"`
+	Method    string `arg:"method" desc:"HTTP method for path (default: GET)"`
+	Status    string `arg:"status" desc:"Response status to mock, e.g. 404 (default: the first 2xx response)"`
+}
+
+// GetMockToolHandler returns the tool handler for mocked API responses
+func (bh *BuddyHandlers) GetMockToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args MockArgs) (*mcp.CallToolResult, error) {
+		tableName, path := args.TableName, args.Path
+
+		var (
+			header string
+			body   interface{}
+			err    error
+		)
+		switch {
+		case tableName != "" && path != "":
+			return nil, fmt.Errorf("give either table_name or path, not both")
+		case tableName != "":
+			id, count := max(args.ID, 0), max(args.Count, 0)
+			body, err = bh.databaseHandler.MockTable(tableName, id, count)
+			if id > 0 {
+				header = fmt.Sprintf("Mock response for GET /%s/%d", tableName, id)
+			} else {
+				header = fmt.Sprintf("Mock response for GET /%s", tableName)
+			}
+		case path != "":
+			header, body, err = bh.MockEndpoint(args.Method, path, args.Status)
+		default:
+			return nil, fmt.Errorf("table_name or path is required")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result := header + "\n" + strings.Repeat("-", 40) + "\n\n"
+		if body == nil {
+			return mcp.NewToolResultText(result + "(no body)"), nil
+		}
+		data, err := json.MarshalIndent(body, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(result + string(data)), nil
+	})
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// namingStyles maps a style name to a pattern that identifiers in that style match
+var namingStyles = map[string]*regexp.Regexp{
+	"camelCase":            regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`),
+	"PascalCase":           regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`),
+	"snake_case":           regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`),
+	"SCREAMING_SNAKE_CASE": regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`),
+	"kebab-case":           regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`),
+}
+
+// NameCheck is the outcome of checking one proposed identifier
+type NameCheck struct {
+	Name      string
+	Kind      string
+	Issues    []string
+	Suggested string
+}
+
+// parseGlossary parses a "Glossary:" header value of the form
+// "customer: client, buyer; invoice: bill" into canonical -> aliases
+func parseGlossary(value string) map[string][]string {
+	glossary := make(map[string][]string)
+	for _, entry := range strings.Split(value, ";") {
+		parts := strings.SplitN(entry, ":", 2)
+		canonical := strings.ToLower(strings.TrimSpace(parts[0]))
+		if canonical == "" {
+			continue
+		}
+		glossary[canonical] = nil
+		if len(parts) == 2 {
+			for _, alias := range strings.Split(parts[1], ",") {
+				if alias = strings.ToLower(strings.TrimSpace(alias)); alias != "" {
+					glossary[canonical] = append(glossary[canonical], alias)
+				}
+			}
+		}
+	}
+	return glossary
+}
+
+// parseNaming parses a "Naming:" header value of the form
+// "function=camelCase, table=snake_case" into kind -> style
+func parseNaming(value string) map[string]string {
+	naming := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		kind := strings.ToLower(strings.TrimSpace(parts[0]))
+		style := strings.TrimSpace(parts[1])
+		if kind != "" && style != "" {
+			naming[kind] = style
+		}
+	}
+	return naming
+}
+
+// splitIdentifier breaks an identifier into lowercase words across
+// camelCase, PascalCase, snake_case and kebab-case boundaries
+func splitIdentifier(name string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(name)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || unicode.IsSpace(r):
+			flush()
+		case unicode.IsUpper(r):
+			// Start a new word at a lower->upper boundary, or at the last
+			// capital of an acronym followed by lowercase (HTTPServer -> http, server)
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				flush()
+			}
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+
+	return words
+}
+
+// joinIdentifier renders words in the given naming style
+func joinIdentifier(words []string, style string) string {
+	title := func(w string) string {
+		if w == "" {
+			return w
+		}
+		return strings.ToUpper(w[:1]) + w[1:]
+	}
+
+	switch style {
+	case "camelCase":
+		var sb strings.Builder
+		for i, w := range words {
+			if i == 0 {
+				sb.WriteString(w)
+			} else {
+				sb.WriteString(title(w))
+			}
+		}
+		return sb.String()
+	case "PascalCase":
+		var sb strings.Builder
+		for _, w := range words {
+			sb.WriteString(title(w))
+		}
+		return sb.String()
+	case "SCREAMING_SNAKE_CASE":
+		return strings.ToUpper(strings.Join(words, "_"))
+	case "kebab-case":
+		return strings.Join(words, "-")
+	default:
+		return strings.Join(words, "_")
+	}
+}
+
+// glossaryAliases builds alias -> canonical term from all loaded rules
+func (rh *RulesHandler) glossaryAliases() map[string]string {
+	aliases := make(map[string]string)
+	for _, rule := range rh.GetRules() {
+		for canonical, terms := range rule.Glossary {
+			for _, alias := range terms {
+				aliases[alias] = canonical
+			}
+		}
+	}
+	return aliases
+}
+
+// namingConventions merges naming conventions from all loaded rules,
+// letting higher-priority rules win conflicts
+func (rh *RulesHandler) namingConventions() map[string]string {
+	rank := map[string]int{"critical": 3, "recommended": 2, "optional": 1}
+	rules := append([]models.Rule{}, rh.GetRules()...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rank[rules[i].Priority] < rank[rules[j].Priority]
+	})
+
+	conventions := make(map[string]string)
+	for _, rule := range rules {
+		for kind, style := range rule.Naming {
+			conventions[kind] = style
+		}
+	}
+	return conventions
+}
+
+// CheckNames checks proposed identifiers of a kind (function, table,
+// variable, type, ...) against glossary terms and naming conventions
+func (rh *RulesHandler) CheckNames(names []string, kind string) []NameCheck {
+	aliases := rh.glossaryAliases()
+	style := rh.namingConventions()[strings.ToLower(kind)]
+
+	var checks []NameCheck
+	for _, name := range names {
+		check := NameCheck{Name: name, Kind: kind}
+		words := splitIdentifier(name)
+
+		for i, word := range words {
+			if canonical, ok := aliases[word]; ok {
+				check.Issues = append(check.Issues, fmt.Sprintf("'%s' is not the domain term; use '%s'", word, canonical))
+				words[i] = canonical
+			}
+		}
+
+		suggested := name
+		if style != "" {
+			if pattern, ok := namingStyles[style]; ok && !pattern.MatchString(name) {
+				check.Issues = append(check.Issues, fmt.Sprintf("%s names should be %s", kind, style))
+			}
+			suggested = joinIdentifier(words, style)
+		} else if len(check.Issues) > 0 {
+			suggested = joinIdentifier(words, detectStyle(name))
+		}
+
+		if suggested != name {
+			check.Suggested = suggested
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// detectStyle guesses the naming style an identifier already uses
+func detectStyle(name string) string {
+	for _, style := range []string{"snake_case", "SCREAMING_SNAKE_CASE", "kebab-case", "PascalCase", "camelCase"} {
+		if namingStyles[style].MatchString(name) {
+			return style
+		}
+	}
+	return "camelCase"
+}
+
+// NamingArgs are the arguments of buddy_check_names
+type NamingArgs struct {
+	Names []string `arg:"names,required" desc:"Identifier names to check"`
+	Kind  string   `arg:"kind" desc:"Identifier kind, e.g. function, type, table, variable (default: variable)"`
+}
+
+// GetNamingToolHandler returns the tool handler function for identifier checks
+func (rh *RulesHandler) GetNamingToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args NamingArgs) (*mcp.CallToolResult, error) {
+		var names []string
+		for _, name := range args.Names {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("names is required")
+		}
+
+		kind := args.Kind
+		if kind == "" {
+			kind = "variable"
+		}
+
+		checks := rh.CheckNames(names, kind)
+
+		result := fmt.Sprintf("Naming check for %d %s names\n", len(checks), kind)
+		if style := rh.namingConventions()[strings.ToLower(kind)]; style != "" {
+			result += fmt.Sprintf("Convention: %s\n", style)
+		}
+		result += "\n"
+
+		for _, check := range checks {
+			if len(check.Issues) == 0 {
+				result += fmt.Sprintf("✅ %s\n", check.Name)
+				continue
+			}
+			result += fmt.Sprintf("⚠️ %s\n", check.Name)
+			for _, issue := range check.Issues {
+				result += fmt.Sprintf("   - %s\n", issue)
+			}
+			if check.Suggested != "" {
+				result += fmt.Sprintf("   Suggested: %s\n", check.Suggested)
+			}
+		}
+
+		return mcp.NewToolResultText(result), nil
+	})
+}
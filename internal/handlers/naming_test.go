@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"getUserID", []string{"get", "user", "id"}},
+		{"HTTPServer", []string{"http", "server"}},
+		{"parseHTTPRequest", []string{"parse", "http", "request"}},
+		{"order_line_items", []string{"order", "line", "items"}},
+		{"MAX_RETRY_COUNT", []string{"max", "retry", "count"}},
+		{"user-profile", []string{"user", "profile"}},
+		{"oauth2Token", []string{"oauth2", "token"}},
+		{"__private__", []string{"private"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitIdentifier(tt.name))
+		})
+	}
+}
+
+func TestJoinIdentifier(t *testing.T) {
+	words := []string{"customer", "id"}
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{"camelCase", "customerId"},
+		{"PascalCase", "CustomerId"},
+		{"snake_case", "customer_id"},
+		{"SCREAMING_SNAKE_CASE", "CUSTOMER_ID"},
+		{"kebab-case", "customer-id"},
+		{"Train-Case", "customer_id"}, // unknown styles fall back to snake_case
+	}
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			assert.Equal(t, tt.want, joinIdentifier(words, tt.style))
+		})
+	}
+	assert.Equal(t, "", joinIdentifier(nil, "camelCase"))
+}
+
+func TestDetectStyle(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"order_total", "snake_case"},
+		{"ORDER_TOTAL", "SCREAMING_SNAKE_CASE"},
+		{"order-total", "kebab-case"},
+		{"OrderTotal", "PascalCase"},
+		{"orderTotal", "camelCase"},
+		{"total", "snake_case"}, // one lowercase word fits snake_case first
+		{"order total", "camelCase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, detectStyle(tt.name))
+		})
+	}
+}
+
+func TestParseGlossary(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string][]string
+	}{
+		{"customer: client, buyer; invoice: bill", map[string][]string{"customer": {"client", "buyer"}, "invoice": {"bill"}}},
+		{" Customer : Client ,, ", map[string][]string{"customer": {"client"}}},
+		{"ledger", map[string][]string{"ledger": nil}},
+		{"; : orphan", map[string][]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseGlossary(tt.value))
+		})
+	}
+}
+
+func TestParseNaming(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]string
+	}{
+		{"function=camelCase, table=snake_case", map[string]string{"function": "camelCase", "table": "snake_case"}},
+		{" Type = PascalCase ", map[string]string{"type": "PascalCase"}},
+		{"function, =snake_case, table=", map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseNaming(tt.value))
+		})
+	}
+}
+
+func TestCheckNames(t *testing.T) {
+	bh, _ := newTestBuddyHandlers(t, map[string]string{
+		"rules/domain.md": "# Domain\nCategory: naming\nPriority: recommended\nGlossary: customer: client, buyer\nNaming: table=snake_case, function=PascalCase\n\nUse the domain terms.\n",
+		"rules/go.md":     "# Go\nCategory: naming\nPriority: critical\nNaming: function=camelCase\n\nGo names.\n",
+	})
+
+	tests := []struct {
+		name      string
+		kind      string
+		issues    int
+		suggested string
+	}{
+		{"getCustomer", "function", 0, ""},
+		{"getClient", "function", 1, "getCustomer"},
+		{"GetBuyer", "function", 2, "getCustomer"}, // the critical rule's style wins
+		{"customer_orders", "table", 0, ""},
+		{"ClientOrders", "table", 2, "customer_orders"},
+		{"client-notes", "file", 1, "customer-notes"}, // no convention: keep the name's own style
+		{"orderNotes", "file", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := bh.rulesHandler.CheckNames([]string{tt.name}, tt.kind)
+			if assert.Len(t, checks, 1) {
+				assert.Len(t, checks[0].Issues, tt.issues, checks[0].Issues)
+				assert.Equal(t, tt.suggested, checks[0].Suggested)
+			}
+		})
+	}
+}
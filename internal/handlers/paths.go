@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/workspace"
+)
+
+// PathMigration reports what MigratePaths rewrote
+type PathMigration struct {
+	Root    string   // the workspace root paths are now relative to
+	Files   []string // buddy files rewritten
+	Paths   int      // stored paths made relative
+	Outside []string // absolute paths left alone because they lie outside the workspace
+}
+
+// pathMigrator rewrites the absolute paths of buddy files one file at a time
+type pathMigrator struct {
+	root    workspace.Root
+	oldRoot string
+	store   storage.Storage
+	result  *PathMigration
+	changed bool // the file being migrated had a path rewritten
+}
+
+// migrate returns path relative to the workspace when it can be
+func (m *pathMigrator) migrate(path string) string {
+	migrated, ok := m.root.Migrate(path, m.oldRoot)
+	if ok {
+		m.result.Paths++
+		m.changed = true
+	} else if filepath.IsAbs(path) {
+		m.result.Outside = append(m.result.Outside, path)
+	}
+	return migrated
+}
+
+// rewriteJSON migrates one JSON file in place, saving it when a path changed
+func rewriteJSON[T any](m *pathMigrator, path string, migrate func(*T)) error {
+	content, err := m.store.Read(path)
+	if storage.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var value T
+	if err := json.Unmarshal(content, &value); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	m.changed = false
+	migrate(&value)
+	if !m.changed {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := m.store.Write(path, data); err != nil {
+		return err
+	}
+	m.result.Files = append(m.result.Files, path)
+	return nil
+}
+
+// MigratePaths rewrites the absolute paths stored in backup metadata,
+// directory backup manifests and history changes as paths relative to the
+// workspace root. Paths under oldRoot, where the workspace was before it
+// moved, are rebased onto the current root; oldRoot may be empty.
+func MigratePaths(buddyPath, oldRoot string) (PathMigration, error) {
+	var result PathMigration
+
+	cfg, err := config.Load(buddyPath)
+	if err != nil {
+		return result, err
+	}
+	absBuddyPath, err := filepath.Abs(buddyPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to resolve buddy path: %w", err)
+	}
+	root, err := workspaceRoot(absBuddyPath, cfg)
+	if err != nil {
+		return result, err
+	}
+	result.Root = root.Dir()
+
+	m := &pathMigrator{root: root, store: storage.NewLocal(), result: &result}
+	if oldRoot != "" {
+		if m.oldRoot, err = filepath.Abs(oldRoot); err != nil {
+			return result, fmt.Errorf("failed to resolve old root: %w", err)
+		}
+	}
+
+	// Manifests are found through the metadata, so read them before their
+	// paths are rewritten
+	metadataPath := filepath.Join(absBuddyPath, "backups", "metadata.json")
+	var manifests []string
+	err = rewriteJSON(m, metadataPath, func(backups *[]models.Backup) {
+		for i, backup := range *backups {
+			if backup.Type == models.BackupTypeTree {
+				manifests = append(manifests, root.Abs(rebase(root, backup.BackupPath, m.oldRoot)))
+			}
+			(*backups)[i].OriginalPath = m.migrate(backup.OriginalPath)
+			(*backups)[i].BackupPath = m.migrate(backup.BackupPath)
+		}
+	})
+	if err != nil {
+		return result, err
+	}
+	for _, manifestPath := range manifests {
+		err := rewriteJSON(m, manifestPath, func(manifest *treeManifest) {
+			manifest.Root = m.migrate(manifest.Root)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	historyFiles, err := m.store.List(filepath.Join(absBuddyPath, "history"), false)
+	if err != nil && !storage.IsNotExist(err) {
+		return result, err
+	}
+	for _, file := range historyFiles {
+		if filepath.Ext(file.Path) != ".json" {
+			continue
+		}
+		err := rewriteJSON(m, file.Path, func(entry *models.HistoryEntry) {
+			for i, change := range entry.Changes {
+				entry.Changes[i].FilePath = m.migrate(change.FilePath)
+			}
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// rebase returns path relative to root when it lies under root or oldRoot,
+// and unchanged otherwise
+func rebase(root workspace.Root, path, oldRoot string) string {
+	migrated, _ := root.Migrate(path, oldRoot)
+	return migrated
+}
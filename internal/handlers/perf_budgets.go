@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/timeutil"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// budgetsFile holds the budget definitions, in the buddy root
+	budgetsFile = "budgets.yaml"
+	// maxMeasurementsPerMetric bounds the recorded history of each metric
+	maxMeasurementsPerMetric = 100
+	// budgetNearRatio is the share of a budget above which a metric is flagged as close to it
+	budgetNearRatio = 0.9
+	// budgetRegressionRatio is the growth over the previous measurement flagged as a regression
+	budgetRegressionRatio = 0.1
+)
+
+// budgetsFileFormat is the layout of budgets.yaml. Limits are quantities
+// such as "250ms", "1.5s", "20MB" or plain numbers.
+type budgetsFileFormat struct {
+	Budgets []struct {
+		Component   string `yaml:"component"`
+		Metric      string `yaml:"metric"`
+		Max         string `yaml:"max"`
+		Description string `yaml:"description"`
+	} `yaml:"budgets"`
+}
+
+// Units accepted in quantities, as a base unit and a factor into it
+var quantityUnits = map[string]struct {
+	base   string
+	factor float64
+}{
+	"":    {"", 1},
+	"ns":  {"ms", 1e-6},
+	"us":  {"ms", 1e-3},
+	"µs":  {"ms", 1e-3},
+	"ms":  {"ms", 1},
+	"s":   {"ms", 1000},
+	"m":   {"ms", 60000},
+	"min": {"ms", 60000},
+	"b":   {"bytes", 1},
+	"kb":  {"bytes", 1e3},
+	"mb":  {"bytes", 1e6},
+	"gb":  {"bytes", 1e9},
+	"kib": {"bytes", 1 << 10},
+	"mib": {"bytes", 1 << 20},
+	"gib": {"bytes", 1 << 30},
+}
+
+var quantityPattern = regexp.MustCompile(`^([0-9]*\.?[0-9]+)\s*([a-zA-Zµ]*)$`)
+
+// parseQuantity converts a quantity such as "250ms" or "1.5MB" to its base
+// unit: milliseconds for durations, bytes for sizes
+func parseQuantity(text string) (float64, string, error) {
+	match := quantityPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return 0, "", fmt.Errorf("invalid quantity %q; use a number with an optional unit such as 250ms or 20MB", text)
+	}
+
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid quantity %q: %w", text, err)
+	}
+
+	unit, ok := quantityUnits[strings.ToLower(match[2])]
+	if !ok {
+		return 0, "", fmt.Errorf("unknown unit %q in %q", match[2], text)
+	}
+	return value * unit.factor, unit.base, nil
+}
+
+// formatQuantity renders a value in its base unit readably
+func formatQuantity(value float64, unit string) string {
+	format := func(v float64, suffix string) string {
+		return strconv.FormatFloat(v, 'f', -1, 64) + suffix
+	}
+	round := func(v float64) float64 {
+		rounded, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'f', 2, 64), 64)
+		return rounded
+	}
+
+	switch unit {
+	case "ms":
+		if value >= 1000 {
+			return format(round(value/1000), "s")
+		}
+		return format(round(value), "ms")
+	case "bytes":
+		switch {
+		case value >= 1e9:
+			return format(round(value/1e9), "GB")
+		case value >= 1e6:
+			return format(round(value/1e6), "MB")
+		case value >= 1e3:
+			return format(round(value/1e3), "KB")
+		}
+		return format(round(value), "B")
+	}
+	return format(round(value), "")
+}
+
+// BudgetsHandler manages performance budgets and the measurements recorded
+// against them over time
+type BudgetsHandler struct {
+	root         string // buddy root holding budgets.yaml
+	path         string // directory holding recorded measurements
+	budgets      []models.PerformanceBudget
+	measurements []models.PerformanceMeasurement
+	// declaredUnits is the unit each budget was written in, by component/metric;
+	// plain numbers are recorded in it
+	declaredUnits map[string]string
+	store         storage.Storage
+	timeFormat    *timeutil.Formatter
+	mu            sync.RWMutex
+}
+
+// NewBudgetsHandler creates a new performance budgets handler reading and
+// recording through store
+func NewBudgetsHandler(root, path string, store storage.Storage) *BudgetsHandler {
+	return &BudgetsHandler{
+		root:  root,
+		path:  path,
+		store: store,
+	}
+}
+
+// Load loads budget definitions and recorded measurements
+func (bh *BudgetsHandler) Load() error {
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+
+	bh.budgets = nil
+	bh.measurements = nil
+	bh.declaredUnits = make(map[string]string)
+
+	budgetsPath := filepath.Join(bh.root, budgetsFile)
+	if _, err := bh.store.Stat(budgetsPath); err == nil {
+		content, err := bh.store.Read(budgetsPath)
+		if err != nil {
+			return err
+		}
+
+		var file budgetsFileFormat
+		if err := yaml.Unmarshal(content, &file); err != nil {
+			return fmt.Errorf("invalid %s: %w", budgetsFile, err)
+		}
+
+		for i, entry := range file.Budgets {
+			if entry.Component == "" || entry.Metric == "" {
+				return fmt.Errorf("invalid %s: budget %d needs a component and a metric", budgetsFile, i+1)
+			}
+			max, unit, err := parseQuantity(entry.Max)
+			if err != nil {
+				return fmt.Errorf("invalid %s: budget %s/%s: %w", budgetsFile, entry.Component, entry.Metric, err)
+			}
+			bh.declaredUnits[entry.Component+"/"+entry.Metric] = quantityPattern.FindStringSubmatch(strings.TrimSpace(entry.Max))[2]
+			bh.budgets = append(bh.budgets, models.PerformanceBudget{
+				Component:   entry.Component,
+				Metric:      entry.Metric,
+				Max:         max,
+				Unit:        unit,
+				Description: entry.Description,
+			})
+		}
+	}
+
+	measurementsPath := filepath.Join(bh.path, "measurements.json")
+	if _, err := bh.store.Stat(measurementsPath); err == nil {
+		content, err := bh.store.Read(measurementsPath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(content, &bh.measurements); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// save saves recorded measurements
+func (bh *BudgetsHandler) save(ctx context.Context) error {
+	data, err := json.MarshalIndent(bh.measurements, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(ctx, bh.store, filepath.Join(bh.path, "measurements.json"), data)
+}
+
+// GetBudgets returns the budgets, optionally only those of one component
+func (bh *BudgetsHandler) GetBudgets(component string) []models.PerformanceBudget {
+	bh.mu.RLock()
+	defer bh.mu.RUnlock()
+
+	var budgets []models.PerformanceBudget
+	for _, budget := range bh.budgets {
+		if component == "" || budget.Component == component {
+			budgets = append(budgets, budget)
+		}
+	}
+	return budgets
+}
+
+// history returns the measurements of a metric, oldest first; callers hold mu
+func (bh *BudgetsHandler) history(component, metric string) []models.PerformanceMeasurement {
+	var measurements []models.PerformanceMeasurement
+	for _, m := range bh.measurements {
+		if m.Component == component && m.Metric == metric {
+			measurements = append(measurements, m)
+		}
+	}
+	return measurements
+}
+
+// Record stores a measured value for a budgeted metric and returns any
+// warnings about it: over budget, close to budget or regressed since the
+// previous measurement
+func (bh *BudgetsHandler) Record(ctx context.Context, component, metric, value, source string) (models.PerformanceMeasurement, []string, error) {
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+
+	var budget *models.PerformanceBudget
+	for i := range bh.budgets {
+		if bh.budgets[i].Component == component && bh.budgets[i].Metric == metric {
+			budget = &bh.budgets[i]
+			break
+		}
+	}
+	if budget == nil {
+		return models.PerformanceMeasurement{}, nil, fmt.Errorf("no budget for %s/%s; add it to .buddy/%s", component, metric, budgetsFile)
+	}
+
+	// Plain numbers are taken in the unit the budget was written in
+	if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+		value = strings.TrimSpace(value) + bh.declaredUnits[component+"/"+metric]
+	}
+	amount, unit, err := parseQuantity(value)
+	if err != nil {
+		return models.PerformanceMeasurement{}, nil, err
+	}
+	if unit != budget.Unit {
+		return models.PerformanceMeasurement{}, nil, fmt.Errorf("%s is not comparable with the %s/%s budget of %s", value, component, metric, formatQuantity(budget.Max, budget.Unit))
+	}
+
+	previous := bh.history(component, metric)
+	measurement := models.PerformanceMeasurement{
+		Component:  component,
+		Metric:     metric,
+		Value:      amount,
+		Unit:       unit,
+		Source:     source,
+		RecordedAt: time.Now().UTC(),
+	}
+
+	bh.measurements = append(bh.measurements, measurement)
+	if len(previous)+1 > maxMeasurementsPerMetric {
+		bh.dropOldest(component, metric)
+	}
+	if err := bh.save(ctx); err != nil {
+		return models.PerformanceMeasurement{}, nil, fmt.Errorf("failed to save measurements: %w", err)
+	}
+
+	var warnings []string
+	if status := budgetStatus(*budget, amount); status != "" {
+		warnings = append(warnings, status)
+	}
+	if len(previous) > 0 {
+		last := previous[len(previous)-1].Value
+		if last > 0 && amount > last*(1+budgetRegressionRatio) {
+			warnings = append(warnings, fmt.Sprintf("📈 Regression: up %.0f%% from %s", (amount/last-1)*100, formatQuantity(last, unit)))
+		}
+	}
+
+	return measurement, warnings, nil
+}
+
+// dropOldest removes the oldest measurement of a metric; callers hold mu
+func (bh *BudgetsHandler) dropOldest(component, metric string) {
+	for i, m := range bh.measurements {
+		if m.Component == component && m.Metric == metric {
+			bh.measurements = append(bh.measurements[:i], bh.measurements[i+1:]...)
+			return
+		}
+	}
+}
+
+// budgetStatus describes a value that exceeds or nears its budget, or
+// returns an empty string when it is comfortably within it
+func budgetStatus(budget models.PerformanceBudget, value float64) string {
+	switch {
+	case value > budget.Max:
+		return fmt.Sprintf("🚨 Over budget: %s exceeds %s by %s", formatQuantity(value, budget.Unit), formatQuantity(budget.Max, budget.Unit), formatQuantity(value-budget.Max, budget.Unit))
+	case value >= budget.Max*budgetNearRatio:
+		return fmt.Sprintf("⚠️ Near budget: %s is %.0f%% of %s", formatQuantity(value, budget.Unit), value/budget.Max*100, formatQuantity(budget.Max, budget.Unit))
+	}
+	return ""
+}
+
+// BudgetsArgs are the arguments of buddy_budgets
+type BudgetsArgs struct {
+	Action    string `arg:"action" enum:"report,record" desc:"Action to perform: report (default) or record"`
+	Component string `arg:"component" desc:"Component the budget belongs to, e.g. checkout-api (required for record)"`
+	Metric    string `arg:"metric" desc:"Budgeted metric, e.g. p95_latency or binary_size (required for record)"`
+	Value     string `arg:"value" desc:"Measured value with an optional unit, e.g. 230ms, 1.2s, 18.5MB (required for record)"`
+	Source    string `arg:"source" desc:"Where the measurement came from, e.g. a commit or CI run (optional)"`
+}
+
+// GetToolHandler returns the tool handler function for performance budgets
+func (bh *BudgetsHandler) GetToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args BudgetsArgs) (*mcp.CallToolResult, error) {
+		action, component, metric := args.Action, args.Component, args.Metric
+
+		switch action {
+		case "", "report":
+			return mcp.NewToolResultText(bh.formatReport(component, metric)), nil
+
+		case "record":
+			if component == "" || metric == "" {
+				return nil, fmt.Errorf("component and metric are required for record")
+			}
+			if args.Value == "" {
+				return nil, fmt.Errorf("value is required for record")
+			}
+
+			measurement, warnings, err := bh.Record(ctx, component, metric, args.Value, args.Source)
+			if err != nil {
+				return nil, err
+			}
+
+			result := fmt.Sprintf("📏 Recorded %s/%s = %s\n", component, metric, formatQuantity(measurement.Value, measurement.Unit))
+			if len(warnings) == 0 {
+				result += "✅ Within budget\n"
+			}
+			for _, warning := range warnings {
+				result += warning + "\n"
+			}
+			return mcp.NewToolResultText(result), nil
+
+		default:
+			return nil, fmt.Errorf("unknown action: %s", action)
+		}
+	})
+}
+
+// formatReport lists budgets with their latest measurements and recent trend
+func (bh *BudgetsHandler) formatReport(component, metric string) string {
+	bh.mu.RLock()
+	defer bh.mu.RUnlock()
+
+	var budgets []models.PerformanceBudget
+	for _, budget := range bh.budgets {
+		if (component == "" || budget.Component == component) && (metric == "" || budget.Metric == metric) {
+			budgets = append(budgets, budget)
+		}
+	}
+
+	if len(budgets) == 0 {
+		if component != "" {
+			return fmt.Sprintf("No performance budgets for %s. Add them to .buddy/%s", component, budgetsFile)
+		}
+		return fmt.Sprintf("No performance budgets defined. Add them to .buddy/%s", budgetsFile)
+	}
+
+	sort.SliceStable(budgets, func(i, j int) bool {
+		if budgets[i].Component != budgets[j].Component {
+			return budgets[i].Component < budgets[j].Component
+		}
+		return budgets[i].Metric < budgets[j].Metric
+	})
+
+	result := "⏱️ Performance Budgets\n"
+	result += strings.Repeat("=", 30) + "\n"
+
+	over := 0
+	currentComponent := ""
+	for _, budget := range budgets {
+		if budget.Component != currentComponent {
+			currentComponent = budget.Component
+			result += fmt.Sprintf("\n## %s\n", currentComponent)
+		}
+
+		result += fmt.Sprintf("\n- %s: budget %s\n", budget.Metric, formatQuantity(budget.Max, budget.Unit))
+		if budget.Description != "" {
+			result += fmt.Sprintf("  %s\n", budget.Description)
+		}
+
+		history := bh.history(budget.Component, budget.Metric)
+		if len(history) == 0 {
+			result += "  No measurements recorded\n"
+			continue
+		}
+
+		latest := history[len(history)-1]
+		result += fmt.Sprintf("  Latest: %s (%s", formatQuantity(latest.Value, latest.Unit), bh.timeFormat.Format(latest.RecordedAt))
+		if latest.Source != "" {
+			result += ", " + latest.Source
+		}
+		result += ")\n"
+
+		if status := budgetStatus(budget, latest.Value); status != "" {
+			result += "  " + status + "\n"
+			if latest.Value > budget.Max {
+				over++
+			}
+		}
+
+		if len(history) > 1 {
+			recent := history
+			if len(recent) > 5 {
+				recent = recent[len(recent)-5:]
+			}
+			var trend []string
+			for _, m := range recent {
+				trend = append(trend, formatQuantity(m.Value, m.Unit))
+			}
+			result += fmt.Sprintf("  Trend: %s\n", strings.Join(trend, " → "))
+		}
+	}
+
+	if over > 0 {
+		result += fmt.Sprintf("\n🚨 %d metrics over budget\n", over)
+	}
+
+	return result
+}
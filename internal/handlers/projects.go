@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ProjectArgument is the tool argument selecting a project when one server
+// serves several buddy directories
+const ProjectArgument = "project"
+
+// Project is one buddy directory served by the process
+type Project struct {
+	Name     string
+	Path     string
+	Handlers *BuddyHandlers
+}
+
+// Projects holds the handlers of every buddy directory a server serves.
+// The first project added is the default, used when a tool call doesn't
+// name one and for resources.
+type Projects struct {
+	list   []Project
+	byName map[string]*BuddyHandlers
+}
+
+// NewProjects creates an empty project set
+func NewProjects() *Projects {
+	return &Projects{byName: make(map[string]*BuddyHandlers)}
+}
+
+// Add registers a project. Names must be unique.
+func (p *Projects) Add(name, path string, bh *BuddyHandlers) error {
+	if name == "" {
+		return fmt.Errorf("project name is required")
+	}
+	if _, exists := p.byName[name]; exists {
+		return fmt.Errorf("duplicate project name %q", name)
+	}
+	p.list = append(p.list, Project{Name: name, Path: path, Handlers: bh})
+	p.byName[name] = bh
+	return nil
+}
+
+// Default returns the handlers of the first project
+func (p *Projects) Default() *BuddyHandlers {
+	if len(p.list) == 0 {
+		return nil
+	}
+	return p.list[0].Handlers
+}
+
+// List returns the projects in the order they were added
+func (p *Projects) List() []Project {
+	return append([]Project(nil), p.list...)
+}
+
+// Names returns the project names in the order they were added
+func (p *Projects) Names() []string {
+	names := make([]string, 0, len(p.list))
+	for _, project := range p.list {
+		names = append(names, project.Name)
+	}
+	return names
+}
+
+// Get returns the handlers of a project; an empty name selects the default
+func (p *Projects) Get(name string) (*BuddyHandlers, error) {
+	if name == "" {
+		return p.Default(), nil
+	}
+	bh, ok := p.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown project %q (available: %s)", name, strings.Join(p.Names(), ", "))
+	}
+	return bh, nil
+}
+
+// Tool builds a tool handler that runs on the project named by the
+// project argument. get picks the handler from a project's handlers, e.g.
+// (*BuddyHandlers).GetRulesToolHandler. With a single project the handler
+// is returned as is.
+func (p *Projects) Tool(get func(*BuddyHandlers) server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if len(p.list) == 1 {
+		return get(p.list[0].Handlers)
+	}
+
+	byProject := make(map[*BuddyHandlers]server.ToolHandlerFunc, len(p.list))
+	for _, project := range p.list {
+		byProject[project.Handlers] = get(project.Handlers)
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name, _ := request.GetArguments()[ProjectArgument].(string)
+		bh, err := p.Get(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		return byProject[bh](ctx, request)
+	}
+}
+
+// Prompt builds a prompt handler that runs on the project named by the
+// project argument, like Tool does for tools
+func (p *Projects) Prompt(get func(*BuddyHandlers) server.PromptHandlerFunc) server.PromptHandlerFunc {
+	if len(p.list) == 1 {
+		return get(p.list[0].Handlers)
+	}
+
+	byProject := make(map[*BuddyHandlers]server.PromptHandlerFunc, len(p.list))
+	for _, project := range p.list {
+		byProject[project.Handlers] = get(project.Handlers)
+	}
+
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		bh, err := p.Get(strings.TrimSpace(request.Params.Arguments[ProjectArgument]))
+		if err != nil {
+			return nil, err
+		}
+		return byProject[bh](ctx, request)
+	}
+}
+
+// RegisterHooks ends a session's scratchpad in every project when its
+// client disconnects
+func (p *Projects) RegisterHooks(hooks *server.Hooks) {
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		ctx = context.WithoutCancel(ctx)
+		for _, project := range p.list {
+			if promoted := project.Handlers.scratchpad.End(ctx, session.SessionID()); len(promoted) > 0 {
+				slog.InfoContext(ctx, "promoted scratchpad notes", "project", project.Name, "session", session.SessionID(), "to", promoted)
+			}
+		}
+	})
+}
+
+// Close closes the handlers of every project, returning the first error
+func (p *Projects) Close() error {
+	var firstErr error
+	for _, project := range p.list {
+		if err := project.Handlers.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
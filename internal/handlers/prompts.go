@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+)
+
+// PromptRegistry registers prompts with the MCP server and remembers them,
+// like ToolRegistry does for tools
+type PromptRegistry struct {
+	server   *server.MCPServer
+	prompts  []mcp.Prompt
+	projects []string // offered as the project argument when more than one
+	mu       sync.RWMutex
+}
+
+// NewPromptRegistry creates a registry that registers prompts on the given server
+func NewPromptRegistry(mcpServer *server.MCPServer) *PromptRegistry {
+	return &PromptRegistry{server: mcpServer}
+}
+
+// SetProjects makes prompts added afterwards accept a project argument
+// choosing between the named projects. It has no effect for one project.
+func (pr *PromptRegistry) SetProjects(names []string) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	if len(names) > 1 {
+		pr.projects = append([]string(nil), names...)
+	} else {
+		pr.projects = nil
+	}
+}
+
+// AddPrompt registers a prompt with the server and records it
+func (pr *PromptRegistry) AddPrompt(prompt mcp.Prompt, handler server.PromptHandlerFunc) {
+	pr.mu.Lock()
+	if len(pr.projects) > 0 {
+		prompt.Arguments = append(prompt.Arguments, mcp.PromptArgument{
+			Name:        ProjectArgument,
+			Description: fmt.Sprintf("Project to use: %s (default: %s)", strings.Join(pr.projects, ", "), pr.projects[0]),
+		})
+	}
+	pr.prompts = append(pr.prompts, prompt)
+	pr.mu.Unlock()
+
+	pr.server.AddPrompt(prompt, handler)
+}
+
+// Prompts returns the registered prompts in registration order
+func (pr *PromptRegistry) Prompts() []mcp.Prompt {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	return append([]mcp.Prompt(nil), pr.prompts...)
+}
+
+// promptResult wraps text as a single user message
+func promptResult(description, text string) *mcp.GetPromptResult {
+	return mcp.NewGetPromptResult(description, []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+	})
+}
+
+// GetApplyCriticalRulesPromptHandler returns the handler of the
+// apply-critical-rules prompt, which asks the model to carry out a task
+// while following the project's critical rules
+func (bh *BuddyHandlers) GetApplyCriticalRulesPromptHandler() server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		args := request.Params.Arguments
+		task := strings.TrimSpace(args["task"])
+		category := strings.TrimSpace(args["category"])
+		includeRecommended := args["include_recommended"] == "true"
+
+		var rules []models.Rule
+		for _, rule := range bh.Snapshot().Rules {
+			if category != "" && !strings.EqualFold(rule.Category, category) {
+				continue
+			}
+			if rule.Priority == "critical" || (includeRecommended && rule.Priority == "recommended") {
+				rules = append(rules, rule)
+			}
+		}
+		sort.SliceStable(rules, func(i, j int) bool {
+			if rules[i].Priority != rules[j].Priority {
+				return rules[i].Priority == "critical"
+			}
+			return rules[i].Title < rules[j].Title
+		})
+
+		var sb strings.Builder
+		if task != "" {
+			sb.WriteString(fmt.Sprintf("Task: %s\n\n", task))
+		}
+		if len(rules) == 0 {
+			sb.WriteString("This project has no critical rules")
+			if category != "" {
+				sb.WriteString(fmt.Sprintf(" in category %q", category))
+			}
+			sb.WriteString(". Follow the existing conventions of the code you touch.\n")
+			return promptResult("Apply the project's critical rules", sb.String()), nil
+		}
+
+		sb.WriteString("Follow these project rules in everything you write. Critical rules are mandatory; if the task conflicts with one, stop and say so instead of breaking it.\n")
+		for _, rule := range rules {
+			sb.WriteString(fmt.Sprintf("\n## %s (%s, %s)\n\n", rule.Title, rule.Priority, rule.Category))
+			sb.WriteString(strings.TrimSpace(rule.Description) + "\n")
+		}
+		sb.WriteString("\nBefore finishing, check your changes against each rule above and list any you could not follow.\n")
+
+		return promptResult("Apply the project's critical rules", sb.String()), nil
+	}
+}
+
+// GetSummarizeTodosPromptHandler returns the handler of the summarize-todos
+// prompt, which asks the model for a progress report on the todo lists
+func (bh *BuddyHandlers) GetSummarizeTodosPromptHandler() server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		feature := strings.TrimSpace(request.Params.Arguments["feature"])
+
+		byFeature := make(map[string][]models.Todo)
+		var features []string
+		for _, todo := range bh.Snapshot().Todos {
+			if feature != "" && !strings.EqualFold(todo.Feature, feature) {
+				continue
+			}
+			if _, seen := byFeature[todo.Feature]; !seen {
+				features = append(features, todo.Feature)
+			}
+			byFeature[todo.Feature] = append(byFeature[todo.Feature], todo)
+		}
+		sort.Strings(features)
+
+		if len(features) == 0 {
+			text := "There are no todos"
+			if feature != "" {
+				text += fmt.Sprintf(" for feature %q", feature)
+			}
+			return promptResult("Summarize todo progress", text+". Say so and suggest adding a todo list under .buddy/todos.\n"), nil
+		}
+
+		var sb strings.Builder
+		sb.WriteString("Summarize the progress of these todo lists for the team: what is done, what remains, and what looks blocked or stale. Then suggest the next three tasks to pick up and why.\n")
+		for _, name := range features {
+			todos := byFeature[name]
+			done := 0
+			for _, todo := range todos {
+				if todo.Completed {
+					done++
+				}
+			}
+			sb.WriteString(fmt.Sprintf("\n## %s (%d/%d done)\n\n", name, done, len(todos)))
+			for _, todo := range todos {
+				box := " "
+				if todo.Completed {
+					box = "x"
+				}
+				sb.WriteString(fmt.Sprintf("- [%s] %s\n", box, todo.Task))
+			}
+		}
+
+		return promptResult("Summarize todo progress", sb.String()), nil
+	}
+}
+
+// GetWriteHistoryEntryPromptHandler returns the handler of the
+// write-history-entry prompt, which asks the model to record the work it
+// just did as a history entry
+func (bh *BuddyHandlers) GetWriteHistoryEntryPromptHandler() server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		feature := strings.TrimSpace(request.Params.Arguments["feature"])
+		if feature == "" {
+			return nil, fmt.Errorf("feature is required")
+		}
+		summary := strings.TrimSpace(request.Params.Arguments["summary"])
+		historyTool := bh.Config().Tools.Name("buddy_history")
+		snap := bh.Snapshot()
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Record the changes you just made for feature %q as a history entry by calling %s with action \"add\" and these arguments:\n\n", feature, historyTool))
+		sb.WriteString(fmt.Sprintf("- feature: %q\n", feature))
+		sb.WriteString("- description: one line saying what changed, in the past tense\n")
+		sb.WriteString("- reasoning: why it was done this way, including alternatives you rejected\n")
+		sb.WriteString("- changes: one object per file with file_path, change_type (created, modified or deleted) and, for small edits, before and after snippets\n")
+		if summary != "" {
+			sb.WriteString(fmt.Sprintf("\nThe user describes the work as: %s\n", summary))
+		}
+
+		var recent []models.HistoryEntry
+		for _, entry := range snap.History {
+			if strings.EqualFold(entry.Feature, feature) {
+				recent = append(recent, entry)
+			}
+		}
+		sort.SliceStable(recent, func(i, j int) bool {
+			return recent[i].Timestamp.After(recent[j].Timestamp)
+		})
+		if len(recent) > 3 {
+			recent = recent[:3]
+		}
+		if len(recent) > 0 {
+			sb.WriteString("\nEarlier entries for this feature, to match their style and avoid repeating them:\n\n")
+			for _, entry := range recent {
+				sb.WriteString(fmt.Sprintf("- %s: %s\n", bh.timeFormat.Format(entry.Timestamp), entry.Description))
+			}
+		}
+
+		var open []string
+		for _, todo := range snap.Todos {
+			if !todo.Completed && strings.EqualFold(todo.Feature, feature) {
+				open = append(open, todo.Task)
+			}
+		}
+		if len(open) > 0 {
+			sb.WriteString("\nOpen todos for this feature; mention any the change completes:\n\n")
+			for _, task := range open {
+				sb.WriteString("- " + task + "\n")
+			}
+		}
+
+		return promptResult("Write a history entry", sb.String()), nil
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getPrompt calls a prompt handler with the given arguments and returns the
+// text of its message
+func getPrompt(t *testing.T, handler server.PromptHandlerFunc, args map[string]string) (string, error) {
+	t.Helper()
+	request := mcp.GetPromptRequest{}
+	request.Params.Arguments = args
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		return "", err
+	}
+	require.Len(t, result.Messages, 1)
+	return result.Messages[0].Content.(mcp.TextContent).Text, nil
+}
+
+// assertInOrder checks that every string in want appears in text, in order,
+// and that none of missing does
+func assertInOrder(t *testing.T, text string, want, missing []string) {
+	t.Helper()
+	rest := text
+	for _, w := range want {
+		i := strings.Index(rest, w)
+		if !assert.GreaterOrEqual(t, i, 0, "%q missing or out of order in:\n%s", w, text) {
+			return
+		}
+		rest = rest[i+len(w):]
+	}
+	for _, m := range missing {
+		assert.NotContains(t, text, m)
+	}
+}
+
+func TestPromptRegistry_AddPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		projects []string
+		want     []string // argument names of the prompt
+	}{
+		{"no projects", nil, []string{"feature"}},
+		{"one project", []string{"api"}, []string{"feature"}},
+		{"several projects", []string{"api", "web"}, []string{"feature", ProjectArgument}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewPromptRegistry(server.NewMCPServer("test", "1.0.0"))
+			registry.SetProjects(tt.projects)
+			registry.AddPrompt(mcp.NewPrompt("summarize-todos", mcp.WithArgument("feature")), nil)
+
+			prompts := registry.Prompts()
+			require.Len(t, prompts, 1)
+			var names []string
+			for _, arg := range prompts[0].Arguments {
+				names = append(names, arg.Name)
+			}
+			assert.Equal(t, tt.want, names)
+			if len(tt.want) > 1 {
+				assert.Equal(t, "Project to use: api, web (default: api)", prompts[0].Arguments[1].Description)
+			}
+		})
+	}
+}
+
+func TestApplyCriticalRulesPrompt(t *testing.T) {
+	bh, _ := newTestBuddyHandlers(t, map[string]string{
+		"rules/style.md":  "# Style\nCategory: style\nPriority: critical\n\nUse gofmt.\n",
+		"rules/errors.md": "# Errors\nCategory: style\nPriority: recommended\n\nWrap errors.\n",
+		"rules/auth.md":   "# Auth\nCategory: security\nPriority: critical\n\nCheck tokens.\n",
+	})
+	handler := bh.GetApplyCriticalRulesPromptHandler()
+
+	tests := []struct {
+		name    string
+		args    map[string]string
+		want    []string // in order
+		missing []string
+	}{
+		{
+			name:    "no arguments",
+			args:    nil,
+			want:    []string{"Follow these project rules", "## Auth (critical, security)", "Check tokens.", "## Style (critical, style)"},
+			missing: []string{"Task:", "Errors"},
+		},
+		{
+			name:    "task, category and recommended rules",
+			args:    map[string]string{"task": " Add logging ", "category": "STYLE", "include_recommended": "true"},
+			want:    []string{"Task: Add logging\n", "## Style (critical, style)", "## Errors (recommended, style)", "Wrap errors."},
+			missing: []string{"Auth"},
+		},
+		{
+			name:    "category without rules",
+			args:    map[string]string{"category": "testing"},
+			want:    []string{"This project has no critical rules in category \"testing\". Follow the existing conventions"},
+			missing: []string{"##"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := getPrompt(t, handler, tt.args)
+			require.NoError(t, err)
+			assertInOrder(t, text, tt.want, tt.missing)
+		})
+	}
+
+	empty, _ := newTestBuddyHandlers(t, nil)
+	text, err := getPrompt(t, empty.GetApplyCriticalRulesPromptHandler(), map[string]string{"task": "Add logging"})
+	require.NoError(t, err)
+	assert.Equal(t, "Task: Add logging\n\nThis project has no critical rules. Follow the existing conventions of the code you touch.\n", text)
+}
+
+func TestSummarizeTodosPrompt(t *testing.T) {
+	bh, _ := newTestBuddyHandlers(t, map[string]string{
+		"todos/billing.md": "# Feature: billing\n\n- [x] Ship invoices\n- [ ] Add receipts\n",
+		"todos/auth.md":    "# Feature: auth\n\n- [ ] Add login\n",
+	})
+	handler := bh.GetSummarizeTodosPromptHandler()
+
+	tests := []struct {
+		name    string
+		args    map[string]string
+		want    []string // in order
+		missing []string
+	}{
+		{
+			name: "every feature, alphabetical",
+			args: nil,
+			want: []string{"Summarize the progress", "## auth (0/1 done)", "- [ ] Add login", "## billing (1/2 done)", "- [x] Ship invoices", "- [ ] Add receipts"},
+		},
+		{
+			name:    "one feature",
+			args:    map[string]string{"feature": "Billing"},
+			want:    []string{"## billing (1/2 done)"},
+			missing: []string{"auth"},
+		},
+		{
+			name: "unknown feature",
+			args: map[string]string{"feature": "search"},
+			want: []string{"There are no todos for feature \"search\". Say so"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := getPrompt(t, handler, tt.args)
+			require.NoError(t, err)
+			assertInOrder(t, text, tt.want, tt.missing)
+		})
+	}
+}
+
+func TestWriteHistoryEntryPrompt(t *testing.T) {
+	bh, _ := newTestBuddyHandlers(t, map[string]string{
+		"history/h1.json":  `{"id":"h1","timestamp":"2024-03-01T00:00:00Z","feature":"billing","description":"Added invoices"}`,
+		"history/h2.json":  `{"id":"h2","timestamp":"2024-03-02T00:00:00Z","feature":"billing","description":"Added refunds"}`,
+		"history/h3.json":  `{"id":"h3","timestamp":"2024-03-03T00:00:00Z","feature":"auth","description":"Added login"}`,
+		"todos/billing.md": "# Feature: billing\n\n- [x] Ship invoices\n- [ ] Add receipts\n",
+	})
+	handler := bh.GetWriteHistoryEntryPromptHandler()
+
+	tests := []struct {
+		name    string
+		args    map[string]string
+		want    []string // in order
+		missing []string
+		wantErr string
+	}{
+		{
+			name:    "missing feature",
+			args:    map[string]string{"summary": "Added receipts"},
+			wantErr: "feature is required",
+		},
+		{
+			name:    "blank feature",
+			args:    map[string]string{"feature": "  "},
+			wantErr: "feature is required",
+		},
+		{
+			name: "earlier entries and open todos",
+			args: map[string]string{"feature": "billing", "summary": "Added receipts"},
+			want: []string{
+				"feature \"billing\"", "calling buddy_history with action \"add\"",
+				"The user describes the work as: Added receipts",
+				"Earlier entries", "Added refunds", "Added invoices", // newest first
+				"Open todos", "- Add receipts",
+			},
+			missing: []string{"Added login", "Ship invoices"},
+		},
+		{
+			name:    "new feature",
+			args:    map[string]string{"feature": "search"},
+			want:    []string{"feature \"search\""},
+			missing: []string{"The user describes", "Earlier entries", "Open todos"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := getPrompt(t, handler, tt.args)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assertInOrder(t, text, tt.want, tt.missing)
+		})
+	}
+}
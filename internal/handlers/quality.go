@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+const (
+	// defaultStaleDays is how old a rule or knowledge document may get before it is flagged
+	defaultStaleDays = 180
+	// featureTodoThreshold is how many open todos make an untested feature worth flagging
+	featureTodoThreshold = 5
+)
+
+// Deduction from the quality score for each finding, by severity
+var severityPenalty = map[string]int{
+	"warning": 5,
+	"info":    2,
+}
+
+// QualityFinding is one actionable curation issue
+type QualityFinding struct {
+	Severity string // warning, info
+	Section  string // rules, knowledge, todos, database, history
+	Item     string // file path or feature name; empty for section-wide findings
+	Message  string
+}
+
+// QualityReport is the outcome of a quality assessment
+type QualityReport struct {
+	Score    int
+	Findings []QualityFinding
+}
+
+// assessQuality scores the buddy content in a snapshot, starting from 100
+// and deducting per finding. dead lists the content pointing at project
+// files that don't exist.
+func assessQuality(snap *ContextSnapshot, dead []deadContent, staleDays int, now time.Time) QualityReport {
+	var findings []QualityFinding
+	add := func(severity, section, item, message string) {
+		findings = append(findings, QualityFinding{Severity: severity, Section: section, Item: item, Message: message})
+	}
+
+	staleBefore := now.AddDate(0, 0, -staleDays)
+
+	// Empty sections
+	if len(snap.Rules) == 0 {
+		add("warning", "rules", "", "No rules defined; add coding standards under rules/")
+	}
+	if len(snap.Knowledge) == 0 {
+		add("warning", "knowledge", "", "No knowledge documents; document architecture and APIs under knowledge/")
+	}
+	if len(snap.Todos) == 0 {
+		add("info", "todos", "", "No todos tracked")
+	}
+	if snap.Database == nil || len(snap.Database.Tables) == 0 {
+		add("info", "database", "", "No database schema loaded")
+	}
+
+	for _, rule := range snap.Rules {
+		switch rule.Priority {
+		case "critical", "recommended", "optional":
+		case "":
+			add("warning", "rules", rule.FilePath, fmt.Sprintf("Rule %q has no priority; add 'Priority: critical|recommended|optional'", rule.Title))
+		default:
+			add("warning", "rules", rule.FilePath, fmt.Sprintf("Rule %q has unknown priority %q", rule.Title, rule.Priority))
+		}
+		if rule.Category == "" {
+			add("info", "rules", rule.FilePath, fmt.Sprintf("Rule %q has no category", rule.Title))
+		}
+		if !rule.UpdatedAt.IsZero() && rule.UpdatedAt.Before(staleBefore) {
+			add("info", "rules", rule.FilePath, fmt.Sprintf("Rule %q not updated in over %d days; confirm it still applies", rule.Title, staleDays))
+		}
+	}
+
+	for _, kb := range snap.Knowledge {
+		if kb.Category == "" {
+			add("warning", "knowledge", kb.FilePath, fmt.Sprintf("Knowledge %q has no category; move it into a category folder", kb.Title))
+		}
+		if len(kb.Tags) == 0 {
+			add("info", "knowledge", kb.FilePath, fmt.Sprintf("Knowledge %q has no tags; add a 'Tags:' line to improve search", kb.Title))
+		}
+		if strings.TrimSpace(kb.Content) == "" {
+			add("warning", "knowledge", kb.FilePath, fmt.Sprintf("Knowledge %q is empty", kb.Title))
+		}
+		if !kb.UpdatedAt.IsZero() && kb.UpdatedAt.Before(staleBefore) {
+			add("info", "knowledge", kb.FilePath, fmt.Sprintf("Knowledge %q not updated in over %d days; confirm it is still accurate", kb.Title, staleDays))
+		}
+	}
+
+	for _, content := range dead {
+		severity := "warning"
+		if content.Section == "rules" && !content.All {
+			severity = "info"
+		}
+		add(severity, content.Section, content.Path, content.describe())
+	}
+
+	// Features with a pile of open todos and no testing task
+	type featureTodos struct {
+		open   int
+		tested bool
+	}
+	features := make(map[string]*featureTodos)
+	for _, todo := range snap.Todos {
+		feature := features[todo.Feature]
+		if feature == nil {
+			feature = &featureTodos{}
+			features[todo.Feature] = feature
+		}
+		if !todo.Completed {
+			feature.open++
+		}
+		if strings.Contains(strings.ToLower(todo.Task), "test") {
+			feature.tested = true
+		}
+	}
+	var featureNames []string
+	for name := range features {
+		featureNames = append(featureNames, name)
+	}
+	sort.Strings(featureNames)
+	for _, name := range featureNames {
+		feature := features[name]
+		if feature.open >= featureTodoThreshold && !feature.tested {
+			add("warning", "todos", name, fmt.Sprintf("Feature %q has %d open todos but none for testing; add a test task", name, feature.open))
+		}
+	}
+
+	for _, entry := range snap.History {
+		if strings.TrimSpace(entry.Reasoning) == "" {
+			add("info", "history", entry.FilePath, fmt.Sprintf("History entry %q has no reasoning", entry.Description))
+		}
+	}
+
+	score := 100
+	for _, finding := range findings {
+		score -= severityPenalty[finding.Severity]
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	return QualityReport{Score: score, Findings: findings}
+}
+
+// QualityArgs are the arguments of buddy_quality
+type QualityArgs struct {
+	Section   string `arg:"section" enum:"rules,knowledge,todos,database,history" desc:"Only show findings for this section (optional)"`
+	StaleDays int    `arg:"stale_days" desc:"Flag rules and knowledge not updated in this many days (optional, default 180)"`
+}
+
+// GetQualityToolHandler returns the tool handler for the buddy quality report
+func (bh *BuddyHandlers) GetQualityToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args QualityArgs) (*mcp.CallToolResult, error) {
+		staleDays := defaultStaleDays
+		if args.StaleDays > 0 {
+			staleDays = args.StaleDays
+		}
+
+		snap := bh.Snapshot()
+		report := assessQuality(snap, bh.deadContent(snap), staleDays, time.Now())
+		return mcp.NewToolResultText(formatQualityReport(report, args.Section)), nil
+	})
+}
+
+// deadContent scans the workspace for the files the snapshot's rules and
+// knowledge refer to. A failed scan is logged and reports nothing.
+func (bh *BuddyHandlers) deadContent(snap *ContextSnapshot) []deadContent {
+	project, err := scanProject(bh.backupHandler.root.Dir(), bh.buddyPath)
+	if err != nil {
+		slog.Warn("skipping dead content checks", "error", err)
+		return nil
+	}
+	return findDeadContent(project, snap.Rules, snap.Knowledge)
+}
+
+// formatQualityReport formats a quality report, optionally limited to one section
+func formatQualityReport(report QualityReport, section string) string {
+	result := fmt.Sprintf("🩺 Buddy Quality Score: %d/100\n", report.Score)
+	result += strings.Repeat("=", 30) + "\n"
+
+	var findings []QualityFinding
+	for _, finding := range report.Findings {
+		if section == "" || finding.Section == section {
+			findings = append(findings, finding)
+		}
+	}
+
+	if len(findings) == 0 {
+		result += "\n✅ No findings\n"
+		return result
+	}
+
+	// Group findings by section in a stable order
+	sectionOrder := []string{"rules", "knowledge", "todos", "database", "history"}
+	grouped := make(map[string][]QualityFinding)
+	for _, finding := range findings {
+		grouped[finding.Section] = append(grouped[finding.Section], finding)
+	}
+
+	for _, name := range sectionOrder {
+		sectionFindings, exists := grouped[name]
+		if !exists {
+			continue
+		}
+
+		result += fmt.Sprintf("\n📂 %s (%d)\n", strings.ToUpper(name), len(sectionFindings))
+		for _, finding := range sectionFindings {
+			icon := "💡"
+			if finding.Severity == "warning" {
+				icon = "⚠️"
+			}
+			result += fmt.Sprintf("%s %s\n", icon, finding.Message)
+			if finding.Item != "" {
+				result += fmt.Sprintf("   └─ %s\n", finding.Item)
+			}
+		}
+	}
+
+	return result
+}
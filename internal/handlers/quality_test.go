@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssessQuality(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	fresh := now.AddDate(0, 0, -1)
+	stale := now.AddDate(0, 0, -200)
+
+	rule := models.Rule{Title: "Style", Category: "style", Priority: "critical", FilePath: "rules/style.md", UpdatedAt: fresh}
+	kb := models.Knowledge{Title: "Auth", Category: "security", Tags: []string{"auth"}, Content: "Sessions live in Redis.", FilePath: "knowledge/auth.md", UpdatedAt: fresh}
+	todo := models.Todo{Feature: "billing", Task: "Test invoices"}
+	database := &models.DatabaseInfo{Tables: []models.Table{{Name: "users"}}}
+	entry := models.HistoryEntry{Description: "Added invoices", Reasoning: "Customers asked", FilePath: "history/h1.json"}
+	healthy := func() *ContextSnapshot {
+		return &ContextSnapshot{
+			Rules:     []models.Rule{rule},
+			Knowledge: []models.Knowledge{kb},
+			Todos:     []models.Todo{todo},
+			Database:  database,
+			History:   []models.HistoryEntry{entry},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		snap     func() *ContextSnapshot
+		dead     []deadContent
+		score    int
+		findings []string // severity section item
+	}{
+		{
+			name:  "healthy",
+			snap:  healthy,
+			score: 100,
+		},
+		{
+			name:  "empty",
+			snap:  func() *ContextSnapshot { return &ContextSnapshot{} },
+			score: 100 - 5 - 5 - 2 - 2,
+			findings: []string{
+				"warning rules ",
+				"warning knowledge ",
+				"info todos ",
+				"info database ",
+			},
+		},
+		{
+			name: "rule problems",
+			snap: func() *ContextSnapshot {
+				snap := healthy()
+				snap.Rules = []models.Rule{
+					{Title: "Untitled", FilePath: "rules/a.md", UpdatedAt: stale},
+					{Title: "Odd", Category: "style", Priority: "urgent", FilePath: "rules/b.md"},
+				}
+				return snap
+			},
+			score: 100 - 5 - 2 - 2 - 5,
+			findings: []string{
+				"warning rules rules/a.md",
+				"info rules rules/a.md",
+				"info rules rules/a.md",
+				"warning rules rules/b.md",
+			},
+		},
+		{
+			name: "knowledge problems",
+			snap: func() *ContextSnapshot {
+				snap := healthy()
+				snap.Knowledge = []models.Knowledge{{Title: "Notes", Content: " \n", FilePath: "knowledge/notes.md", UpdatedAt: stale}}
+				return snap
+			},
+			score: 100 - 5 - 2 - 5 - 2,
+			findings: []string{
+				"warning knowledge knowledge/notes.md",
+				"info knowledge knowledge/notes.md",
+				"warning knowledge knowledge/notes.md",
+				"info knowledge knowledge/notes.md",
+			},
+		},
+		{
+			name: "dead content",
+			snap: healthy,
+			dead: []deadContent{
+				{Section: "rules", Path: "rules/php.md", Title: "PHP", Missing: []string{"**/*.php"}, All: true},
+				{Section: "rules", Path: "rules/sql.md", Title: "SQL", Missing: []string{"migrations/*.sql"}},
+				{Section: "knowledge", Path: "knowledge/auth.md", Title: "Auth", Missing: []string{"internal/auth/token.go"}},
+			},
+			score: 100 - 5 - 2 - 5,
+			findings: []string{
+				"warning rules rules/php.md",
+				"info rules rules/sql.md",
+				"warning knowledge knowledge/auth.md",
+			},
+		},
+		{
+			name: "untested features and history without reasoning",
+			snap: func() *ContextSnapshot {
+				snap := healthy()
+				for i := 0; i < featureTodoThreshold; i++ {
+					snap.Todos = append(snap.Todos,
+						models.Todo{Feature: "search", Task: fmt.Sprintf("Step %d", i)},
+						models.Todo{Feature: "export", Task: fmt.Sprintf("Step %d", i)},
+						models.Todo{Feature: "import", Task: fmt.Sprintf("Step %d", i), Completed: i > 0})
+				}
+				snap.History = append(snap.History, models.HistoryEntry{Description: "Quick fix", FilePath: "history/h2.json"})
+				return snap
+			},
+			score: 100 - 5 - 5 - 2,
+			findings: []string{
+				"warning todos export", // alphabetical
+				"warning todos search",
+				"info history history/h2.json",
+			},
+		},
+		{
+			name: "score stops at zero",
+			snap: func() *ContextSnapshot {
+				snap := healthy()
+				snap.Rules = nil
+				for i := 0; i < 20; i++ {
+					snap.Rules = append(snap.Rules, models.Rule{Title: "Bare", FilePath: fmt.Sprintf("rules/%d.md", i)})
+				}
+				return snap
+			},
+			score: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := assessQuality(tt.snap(), tt.dead, defaultStaleDays, now)
+			assert.Equal(t, tt.score, report.Score)
+			if tt.score == 0 {
+				return
+			}
+			var findings []string
+			for _, finding := range report.Findings {
+				findings = append(findings, strings.Join([]string{finding.Severity, finding.Section, finding.Item}, " "))
+			}
+			assert.Equal(t, tt.findings, findings)
+		})
+	}
+}
+
+func TestFormatQualityReport(t *testing.T) {
+	report := QualityReport{Score: 88, Findings: []QualityFinding{
+		{Severity: "info", Section: "history", Item: "history/h2.json", Message: "History entry \"Quick fix\" has no reasoning"},
+		{Severity: "warning", Section: "rules", Item: "rules/a.md", Message: "Rule \"A\" has no priority"},
+		{Severity: "info", Section: "database", Message: "No database schema loaded"},
+	}}
+
+	tests := []struct {
+		name    string
+		section string
+		want    []string // in order
+		missing []string
+	}{
+		{"all sections in a fixed order", "", []string{"88/100", "📂 RULES (1)", "⚠️ Rule \"A\" has no priority\n   └─ rules/a.md", "📂 DATABASE (1)", "💡 No database schema loaded\n", "📂 HISTORY (1)"}, nil},
+		{"one section", "rules", []string{"88/100", "📂 RULES (1)"}, []string{"DATABASE", "HISTORY"}},
+		{"section without findings", "todos", []string{"88/100", "✅ No findings"}, []string{"RULES"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := formatQualityReport(report, tt.section)
+			rest := text
+			for _, want := range tt.want {
+				i := strings.Index(rest, want)
+				if !assert.GreaterOrEqual(t, i, 0, "%q missing or out of order in:\n%s", want, text) {
+					return
+				}
+				rest = rest[i+len(want):]
+			}
+			for _, missing := range tt.missing {
+				assert.NotContains(t, text, missing)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+)
+
+// maxJoinDepth limits how many joins a candidate path may contain
+const maxJoinDepth = 4
+
+// joinEdge is one foreign key hop between two tables
+type joinEdge struct {
+	From       string
+	FromColumn string
+	To         string
+	ToColumn   string
+}
+
+// JoinPath is a chain of joins connecting two tables
+type JoinPath struct {
+	Edges []joinEdge
+}
+
+// String renders the path as "a.x = b.y -> b.z = c.w"
+func (jp JoinPath) String() string {
+	var hops []string
+	for _, edge := range jp.Edges {
+		hops = append(hops, fmt.Sprintf("%s.%s = %s.%s", edge.From, edge.FromColumn, edge.To, edge.ToColumn))
+	}
+	return strings.Join(hops, " → ")
+}
+
+// QuerySuggestion is the result of matching an intent against the schema
+type QuerySuggestion struct {
+	Tables     []string
+	Columns    map[string][]string // table -> mentioned columns
+	Candidates []JoinPath
+	Skeleton   string
+}
+
+// intentWordRegex splits an intent into lowercase words
+var intentWordRegex = regexp.MustCompile(`[a-z0-9_]+`)
+
+// SuggestQuery maps a natural-language intent onto schema tables and
+// columns, then uses foreign keys to propose join paths and a skeleton query
+func (dh *DatabaseHandler) SuggestQuery(intent string) (*QuerySuggestion, error) {
+	dbInfo := dh.GetDatabaseInfo()
+	if dbInfo == nil || len(dbInfo.Tables) == 0 {
+		return nil, fmt.Errorf("no schema loaded")
+	}
+
+	words := intentWordRegex.FindAllString(strings.ToLower(intent), -1)
+	wordSet := make(map[string]bool)
+	for _, word := range words {
+		wordSet[word] = true
+		wordSet[singular(word)] = true
+	}
+	// Allow multi-word table names such as "user roles" to match user_roles
+	for i := 0; i+1 < len(words); i++ {
+		wordSet[words[i]+"_"+words[i+1]] = true
+		wordSet[singular(words[i]+"_"+words[i+1])] = true
+	}
+
+	suggestion := &QuerySuggestion{Columns: make(map[string][]string)}
+	for _, table := range dbInfo.Tables {
+		name := strings.ToLower(table.Name)
+		if wordSet[name] || wordSet[singular(name)] {
+			suggestion.Tables = append(suggestion.Tables, table.Name)
+		}
+	}
+	if len(suggestion.Tables) == 0 {
+		return nil, fmt.Errorf("no tables in the schema match the intent")
+	}
+
+	for _, tableName := range suggestion.Tables {
+		table := dh.GetTableByName(tableName)
+		for _, col := range table.Columns {
+			name := strings.ToLower(col.Name)
+			if name != "id" && wordSet[name] {
+				suggestion.Columns[tableName] = append(suggestion.Columns[tableName], col.Name)
+			}
+		}
+	}
+
+	graph := buildJoinGraph(dbInfo.Tables)
+	root := suggestion.Tables[0]
+	var tree []joinEdge
+	for _, target := range suggestion.Tables[1:] {
+		paths := findJoinPaths(graph, root, target, maxJoinDepth)
+		suggestion.Candidates = append(suggestion.Candidates, paths...)
+		if len(paths) > 0 {
+			tree = mergeEdges(tree, paths[0].Edges)
+		}
+	}
+
+	suggestion.Skeleton = buildSkeleton(root, tree, suggestion, wordSet)
+	return suggestion, nil
+}
+
+// singular strips a simple English plural suffix
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 3:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "ses") || strings.HasSuffix(word, "xes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return strings.TrimSuffix(word, "s")
+	default:
+		return word
+	}
+}
+
+// buildJoinGraph builds an undirected adjacency list from foreign keys
+func buildJoinGraph(tables []models.Table) map[string][]joinEdge {
+	graph := make(map[string][]joinEdge)
+	for _, table := range tables {
+		for _, fk := range table.ForeignKeys {
+			graph[table.Name] = append(graph[table.Name], joinEdge{From: table.Name, FromColumn: fk.Column, To: fk.RefTable, ToColumn: fk.RefColumn})
+			graph[fk.RefTable] = append(graph[fk.RefTable], joinEdge{From: fk.RefTable, FromColumn: fk.RefColumn, To: table.Name, ToColumn: fk.Column})
+		}
+	}
+	return graph
+}
+
+// findJoinPaths enumerates simple paths from one table to another, shortest first
+func findJoinPaths(graph map[string][]joinEdge, from, to string, maxDepth int) []JoinPath {
+	var paths []JoinPath
+	visited := map[string]bool{from: true}
+
+	var walk func(current string, edges []joinEdge)
+	walk = func(current string, edges []joinEdge) {
+		if current == to {
+			paths = append(paths, JoinPath{Edges: append([]joinEdge{}, edges...)})
+			return
+		}
+		if len(edges) >= maxDepth {
+			return
+		}
+		for _, edge := range graph[current] {
+			if visited[edge.To] {
+				continue
+			}
+			visited[edge.To] = true
+			walk(edge.To, append(edges, edge))
+			visited[edge.To] = false
+		}
+	}
+	walk(from, nil)
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i].Edges) < len(paths[j].Edges)
+	})
+	if len(paths) > 3 {
+		paths = paths[:3]
+	}
+	return paths
+}
+
+// mergeEdges adds edges that join tables not yet present in the tree
+func mergeEdges(tree, edges []joinEdge) []joinEdge {
+	joined := make(map[string]bool)
+	for _, edge := range tree {
+		joined[edge.To] = true
+	}
+	for _, edge := range edges {
+		if !joined[edge.To] {
+			tree = append(tree, edge)
+			joined[edge.To] = true
+		}
+	}
+	return tree
+}
+
+// buildSkeleton renders a SELECT statement using the chosen join tree
+func buildSkeleton(root string, tree []joinEdge, suggestion *QuerySuggestion, wordSet map[string]bool) string {
+	var selectList []string
+	if wordSet["count"] || (wordSet["how"] && wordSet["many"]) {
+		selectList = append(selectList, "COUNT(*)")
+	} else {
+		for _, tableName := range suggestion.Tables {
+			for _, col := range suggestion.Columns[tableName] {
+				selectList = append(selectList, fmt.Sprintf("%s.%s", tableName, col))
+			}
+		}
+		if len(selectList) == 0 {
+			selectList = append(selectList, root+".*")
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("SELECT %s\nFROM %s", strings.Join(selectList, ", "), root))
+	for _, edge := range tree {
+		sb.WriteString(fmt.Sprintf("\nJOIN %s ON %s.%s = %s.%s", edge.To, edge.From, edge.FromColumn, edge.To, edge.ToColumn))
+	}
+	sb.WriteString("\nWHERE /* conditions */\nLIMIT 100;")
+	return sb.String()
+}
+
+// formatQuerySuggestion formats a query suggestion for display
+func (dh *DatabaseHandler) formatQuerySuggestion(intent string, suggestion *QuerySuggestion) string {
+	result := "Query Suggestion:\n"
+	result += strings.Repeat("-", 20) + "\n\n"
+	result += fmt.Sprintf("Intent: %s\n", intent)
+	result += fmt.Sprintf("Matched tables: %s\n", strings.Join(suggestion.Tables, ", "))
+
+	for _, tableName := range suggestion.Tables {
+		if cols := suggestion.Columns[tableName]; len(cols) > 0 {
+			result += fmt.Sprintf("Matched columns in %s: %s\n", tableName, strings.Join(cols, ", "))
+		}
+	}
+
+	if len(suggestion.Tables) > 1 {
+		result += "\nCandidate join paths:\n"
+		if len(suggestion.Candidates) == 0 {
+			result += "- No foreign key path connects these tables; join conditions must be written by hand\n"
+		}
+		for i, path := range suggestion.Candidates {
+			result += fmt.Sprintf("%d. %s\n", i+1, path.String())
+		}
+	}
+
+	result += "\nSkeleton query:\n"
+	result += suggestion.Skeleton + "\n"
+
+	return result
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shopTables is a schema where users reach products through their orders,
+// or directly through favorites
+var shopTables = []models.Table{
+	{Name: "users", Columns: []models.Column{{Name: "id"}, {Name: "email"}}},
+	{Name: "orders", Columns: []models.Column{{Name: "id"}, {Name: "user_id"}, {Name: "total"}},
+		ForeignKeys: []models.ForeignKey{{Column: "user_id", RefTable: "users", RefColumn: "id"}}},
+	{Name: "order_items", Columns: []models.Column{{Name: "order_id"}, {Name: "product_id"}},
+		ForeignKeys: []models.ForeignKey{
+			{Column: "order_id", RefTable: "orders", RefColumn: "id"},
+			{Column: "product_id", RefTable: "products", RefColumn: "id"},
+		}},
+	{Name: "products", Columns: []models.Column{{Name: "id"}, {Name: "name"}}},
+	{Name: "favorites", Columns: []models.Column{{Name: "user_id"}, {Name: "product_id"}},
+		ForeignKeys: []models.ForeignKey{
+			{Column: "user_id", RefTable: "users", RefColumn: "id"},
+			{Column: "product_id", RefTable: "products", RefColumn: "id"},
+		}},
+}
+
+func TestFindJoinPaths(t *testing.T) {
+	graph := buildJoinGraph(shopTables)
+
+	paths := findJoinPaths(graph, "users", "products", maxJoinDepth)
+	require.Len(t, paths, 2)
+	assert.Equal(t, "users.id = favorites.user_id → favorites.product_id = products.id", paths[0].String())
+	assert.Equal(t, "users.id = orders.user_id → orders.id = order_items.order_id → order_items.product_id = products.id", paths[1].String())
+
+	// Paths longer than the depth limit are left out
+	paths = findJoinPaths(graph, "users", "products", 2)
+	require.Len(t, paths, 1)
+	assert.Len(t, paths[0].Edges, 2)
+
+	assert.Empty(t, findJoinPaths(graph, "users", "audit_log", maxJoinDepth))
+}
+
+func TestSuggestQuery(t *testing.T) {
+	dh := fixtureHandler(t, shopTables...)
+
+	suggestion, err := dh.SuggestQuery("emails of users")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users"}, suggestion.Tables)
+	assert.Equal(t, map[string][]string{"users": {"email"}}, suggestion.Columns)
+	assert.Equal(t, "SELECT users.email\nFROM users\nWHERE /* conditions */\nLIMIT 100;", suggestion.Skeleton)
+
+	suggestion, err = dh.SuggestQuery("How many orders per user")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users", "orders"}, suggestion.Tables)
+	require.NotEmpty(t, suggestion.Candidates)
+	assert.Equal(t, "users.id = orders.user_id", suggestion.Candidates[0].String())
+	assert.Equal(t, "SELECT COUNT(*)\nFROM users\nJOIN orders ON users.id = orders.user_id\nWHERE /* conditions */\nLIMIT 100;", suggestion.Skeleton)
+
+	// Multi-word table names match too
+	suggestion, err = dh.SuggestQuery("order items for each product")
+	require.NoError(t, err)
+	assert.Contains(t, suggestion.Tables, "order_items")
+
+	_, err = dh.SuggestQuery("weather forecast")
+	assert.EqualError(t, err, "no tables in the schema match the intent")
+	_, err = fixtureHandler(t).SuggestQuery("users")
+	assert.EqualError(t, err, "no schema loaded")
+}
+
+func TestParseForeignKeys(t *testing.T) {
+	dh := NewDatabaseHandler(t.TempDir(), nil)
+	keys := dh.parseForeignKeys(`
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(10,2),
+    product_id INT,
+    CONSTRAINT fk_product FOREIGN KEY (product_id)
+        REFERENCES products (id)`)
+
+	assert.Equal(t, []models.ForeignKey{
+		{Column: "user_id", RefTable: "users", RefColumn: "id"},
+		{Column: "product_id", RefTable: "products", RefColumn: "id"},
+	}, keys)
+	assert.Empty(t, dh.parseForeignKeys("id INT, name TEXT"))
+}
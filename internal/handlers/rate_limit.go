@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+)
+
+// rateWindow is the span per-minute limits count calls over
+const rateWindow = time.Minute
+
+// RateLimiter caps how many calls of each tool run at once and start per
+// minute, across all sessions. Calls over a limit are refused right away
+// with an error result saying when to retry, rather than queued.
+type RateLimiter struct {
+	config func() *config.Config // the current configuration, reread per call
+
+	mu    sync.Mutex
+	tools map[string]*toolUsage // by unprefixed tool name
+}
+
+// toolUsage is what one tool's limits are checked against
+type toolUsage struct {
+	running int
+	started []time.Time // call starts within the last rateWindow, oldest first
+}
+
+// NewRateLimiter creates a limiter enforcing the rate limits of the
+// configuration cfg returns, so reloaded limits apply to the next call.
+// Register its middleware with the MCP server before use.
+func NewRateLimiter(cfg func() *config.Config) *RateLimiter {
+	return &RateLimiter{
+		config: cfg,
+		tools:  make(map[string]*toolUsage),
+	}
+}
+
+// Middleware refuses tool calls over their tool's limits
+func (rl *RateLimiter) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		cfg := rl.config()
+		name := cfg.Tools.BaseName(request.Params.Name)
+		limit := cfg.RateLimitFor(name)
+		if limit.MaxConcurrent <= 0 && limit.PerMinute <= 0 {
+			return next(ctx, request)
+		}
+
+		if refusal := rl.acquire(name, limit); refusal != "" {
+			slog.WarnContext(ctx, "tool call rate limited", "tool", name, "reason", refusal)
+			return mcp.NewToolResultError("⏳ " + refusal), nil
+		}
+		defer rl.release(name)
+
+		return next(ctx, request)
+	}
+}
+
+// acquire counts a call as started and running, or says why it can't start
+func (rl *RateLimiter) acquire(name string, limit config.RateLimit) string {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	usage := rl.tools[name]
+	if usage == nil {
+		usage = &toolUsage{}
+		rl.tools[name] = usage
+	}
+
+	now := time.Now()
+	expired := 0
+	for expired < len(usage.started) && now.Sub(usage.started[expired]) >= rateWindow {
+		expired++
+	}
+	usage.started = usage.started[expired:]
+
+	if limit.MaxConcurrent > 0 && usage.running >= limit.MaxConcurrent {
+		return fmt.Sprintf("%s is limited to %d concurrent calls and that many are running; retry once one finishes", name, limit.MaxConcurrent)
+	}
+	if limit.PerMinute > 0 && len(usage.started) >= limit.PerMinute {
+		retry := (usage.started[0].Add(rateWindow).Sub(now) + time.Second - 1).Truncate(time.Second)
+		return fmt.Sprintf("%s is limited to %d calls per minute; retry in %s", name, limit.PerMinute, retry)
+	}
+
+	usage.running++
+	usage.started = append(usage.started, now)
+	return ""
+}
+
+// release marks a call as finished
+func (rl *RateLimiter) release(name string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.tools[name].running--
+}
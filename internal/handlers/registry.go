@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/logging"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+)
+
+// Handler is a buddy subsystem added alongside the built-in ones, such as
+// one for experiments or incidents. Its files live in a subdirectory of
+// the buddy folder named after it, which is created, watched and reloaded
+// like the built-in sections, and its tool is served by every project.
+type Handler interface {
+	// Load reads the handler's files, at startup and whenever one of
+	// them changes. It is never called concurrently with itself.
+	Load(ctx context.Context) error
+	// IndexType names the search index the handler writes to, which is
+	// opened for it before the first load; empty for none
+	IndexType() search.IndexType
+	// Tool describes the tool serving the handler's content. Calls of it
+	// are treated as writes and run one at a time.
+	Tool() mcp.Tool
+	// GetToolHandler returns the handler of that tool
+	GetToolHandler() server.ToolHandlerFunc
+}
+
+// HandlerFactory builds a project's instance of a custom handler, given
+// its directory and the project's storage and search indexes. The search
+// manager doesn't hold the handler's own index until the factory returns.
+type HandlerFactory func(dir string, store storage.Storage, searchManager *search.SearchManager) (Handler, error)
+
+// customHandlerName is what a custom handler's directory may be called
+var customHandlerName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// reservedDirs are the buddy subdirectories the server keeps besides the
+// built-in sections, which custom handlers can't be named after either
+var reservedDirs = []string{apiDir, scratchpadDir, timelineDir, logging.DirName}
+
+// registeredHandler is a custom handler registration
+type registeredHandler struct {
+	name    string
+	factory HandlerFactory
+}
+
+// customHandler is a project's instance of a registered handler
+type customHandler struct {
+	name    string
+	handler Handler
+}
+
+var (
+	registeredHandlers   []registeredHandler
+	registeredHandlersMu sync.RWMutex
+)
+
+// RegisterHandler makes every BuddyHandlers created afterwards build a
+// custom handler with factory, keeping its files in the named
+// subdirectory. Call it from an init function. It panics when the name is
+// invalid, names a directory the server owns (a built-in section, api,
+// scratchpad, timeline or logs) or is already registered.
+func RegisterHandler(name string, factory HandlerFactory) {
+	registeredHandlersMu.Lock()
+	defer registeredHandlersMu.Unlock()
+
+	if !customHandlerName.MatchString(name) {
+		panic(fmt.Sprintf("handlers: invalid handler name %q", name))
+	}
+	if factory == nil {
+		panic("handlers: RegisterHandler factory is nil for " + name)
+	}
+	for _, dir := range buddyDirs {
+		if dir == name {
+			panic("handlers: " + name + " is a built-in section")
+		}
+	}
+	for _, dir := range reservedDirs {
+		if dir == name {
+			panic("handlers: " + name + " is a directory the server owns")
+		}
+	}
+	for _, registered := range registeredHandlers {
+		if registered.name == name {
+			panic("handlers: RegisterHandler called twice for " + name)
+		}
+	}
+	registeredHandlers = append(registeredHandlers, registeredHandler{name: name, factory: factory})
+}
+
+// RegisteredHandlers returns the names of the custom handlers in the
+// order they were registered
+func RegisteredHandlers() []string {
+	registeredHandlersMu.RLock()
+	defer registeredHandlersMu.RUnlock()
+
+	names := make([]string, 0, len(registeredHandlers))
+	for _, registered := range registeredHandlers {
+		names = append(names, registered.name)
+	}
+	return names
+}
+
+// initCustomHandlers builds the project's instance of every registered
+// handler and opens the search indexes they ask for
+func (bh *BuddyHandlers) initCustomHandlers(buddyPath string, store storage.Storage) error {
+	registeredHandlersMu.RLock()
+	registered := append([]registeredHandler(nil), registeredHandlers...)
+	registeredHandlersMu.RUnlock()
+
+	for _, reg := range registered {
+		handler, err := reg.factory(filepath.Join(buddyPath, reg.name), store, bh.searchManager)
+		if err != nil {
+			return fmt.Errorf("failed to create %s handler: %w", reg.name, err)
+		}
+		if indexType := handler.IndexType(); indexType != "" {
+			if err := bh.searchManager.AddIndex(indexType); err != nil {
+				return fmt.Errorf("failed to open the %s handler's index: %w", reg.name, err)
+			}
+		}
+		bh.custom = append(bh.custom, customHandler{name: reg.name, handler: handler})
+	}
+	return nil
+}
+
+// CustomHandler returns the project's instance of a registered handler,
+// or nil when none is registered under name
+func (bh *BuddyHandlers) CustomHandler(name string) Handler {
+	for _, custom := range bh.custom {
+		if custom.name == name {
+			return custom.handler
+		}
+	}
+	return nil
+}
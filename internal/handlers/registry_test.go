@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterHandler_RejectsDirectoriesTheServerOwns(t *testing.T) {
+	factory := func(dir string, store storage.Storage, searchManager *search.SearchManager) (Handler, error) {
+		return nil, nil
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"rules", "handlers: rules is a built-in section"},
+		{"indexes", "handlers: indexes is a built-in section"},
+		{"api", "handlers: api is a directory the server owns"},
+		{"scratchpad", "handlers: scratchpad is a directory the server owns"},
+		{"timeline", "handlers: timeline is a directory the server owns"},
+		{"logs", "handlers: logs is a directory the server owns"},
+		{"Incidents", `handlers: invalid handler name "Incidents"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PanicsWithValue(t, tt.want, func() { RegisterHandler(tt.name, factory) })
+		})
+	}
+
+	// Whatever else the server comes to own is rejected too
+	for _, dir := range append(buddyDirs[:len(buddyDirs):len(buddyDirs)], reservedDirs...) {
+		assert.Panics(t, func() { RegisterHandler(dir, factory) }, dir)
+	}
+}
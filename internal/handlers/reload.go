@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+)
+
+const (
+	// reloadFailureBudget is how many reloads of a section may fail in a
+	// row before its breaker opens
+	reloadFailureBudget = 3
+	// minReloadBackoff and maxReloadBackoff bound how long a section whose
+	// breaker is open waits before retrying for changes to other files
+	minReloadBackoff = 30 * time.Second
+	maxReloadBackoff = 10 * time.Minute
+)
+
+// sectionReloader reloads one content type independently of the others.
+// Background reloads coalesce bursts of changes into at most one follow-up
+// reload, and loads of the same section never overlap.
+//
+// A failed load leaves the previous documents in place. Once
+// reloadFailureBudget loads fail in a row the section's breaker opens: it
+// keeps serving that last good content, and watcher events only retry the
+// load when they touch the failing file, or once a backoff has passed.
+// The next successful load closes the breaker.
+type sectionReloader struct {
+	name    string
+	load    func(ctx context.Context) error
+	ctx     context.Context // cancelled when the handlers close
+	begin   func()
+	end     func()
+	loadMu  sync.Mutex
+	mu      sync.Mutex
+	running bool
+	pending bool
+	// lastErr is the error from the most recent load, if it failed
+	lastErr   error
+	lastErrAt time.Time
+	// failures counts the loads that failed in a row; failingPath is the
+	// file the latest one failed on, when the error named one
+	failures    int
+	failingPath string
+	retryAt     time.Time // while the breaker is open, when other changes may retry
+	backoff     time.Duration
+	retryTimer  *time.Timer // retries changes skipped while backing off
+}
+
+// loadNow reloads the section synchronously
+func (sr *sectionReloader) loadNow() error {
+	sr.loadMu.Lock()
+	defer sr.loadMu.Unlock()
+
+	// Closed handlers may have closed their indexes already
+	if err := sr.ctx.Err(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := sr.load(sr.ctx)
+	reloadDuration.Observe(time.Since(start).Seconds(), sr.name)
+	if err != nil {
+		reloadFailures.Inc(sr.name)
+	}
+
+	sr.mu.Lock()
+	sr.lastErr = err
+	if err != nil {
+		sr.lastErrAt = time.Now().UTC()
+	}
+	if sr.ctx.Err() == nil {
+		sr.recordLocked(err)
+	}
+	sr.mu.Unlock()
+
+	return err
+}
+
+// recordLocked counts a load's outcome against the failure budget,
+// opening the breaker or backing off further on failure and closing it on
+// success. Callers hold sr.mu.
+func (sr *sectionReloader) recordLocked(err error) {
+	if err == nil {
+		if sr.failures >= reloadFailureBudget {
+			slog.Info("reload recovered", "section", sr.name, "failures", sr.failures)
+		}
+		sr.failures, sr.failingPath, sr.backoff = 0, "", 0
+		if sr.retryTimer != nil {
+			sr.retryTimer.Stop()
+			sr.retryTimer = nil
+		}
+		return
+	}
+
+	sr.failures++
+	sr.failingPath = ""
+	var fileErr *FileError
+	if errors.As(err, &fileErr) {
+		sr.failingPath = filepath.Clean(fileErr.Path)
+	}
+	if sr.failures < reloadFailureBudget {
+		return
+	}
+	if sr.backoff == 0 {
+		sr.backoff = minReloadBackoff
+		slog.Warn("reloads keep failing; serving the last good content until the failing file changes",
+			"section", sr.name, "failures", sr.failures, "path", sr.failingPath, "error", err)
+	} else {
+		sr.backoff = min(2*sr.backoff, maxReloadBackoff)
+	}
+	sr.retryAt = time.Now().Add(sr.backoff)
+}
+
+// tripped reports whether the section's breaker is open, with the number
+// of loads that failed in a row and the file they failed on, if known
+func (sr *sectionReloader) tripped() (failures int, path string, open bool) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.failures, sr.failingPath, sr.failures >= reloadFailureBudget
+}
+
+// failure returns the error from the most recent load, if it failed
+func (sr *sectionReloader) failure() (time.Time, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.lastErrAt, sr.lastErr
+}
+
+// trigger schedules a background reload of the section after path
+// changed. While the breaker is open, changes to other files wait for the
+// backoff to pass.
+func (sr *sectionReloader) trigger(path string) {
+	sr.mu.Lock()
+	if sr.failures >= reloadFailureBudget && (path == "" || filepath.Clean(path) != sr.failingPath) {
+		if wait := time.Until(sr.retryAt); wait > 0 {
+			if sr.retryTimer == nil {
+				sr.retryTimer = time.AfterFunc(wait, func() {
+					sr.mu.Lock()
+					sr.retryTimer = nil
+					sr.mu.Unlock()
+					sr.trigger("")
+				})
+			}
+			sr.mu.Unlock()
+			return
+		}
+	}
+	if sr.running {
+		sr.pending = true
+		sr.mu.Unlock()
+		return
+	}
+	sr.running = true
+	sr.mu.Unlock()
+
+	go sr.run()
+}
+
+// run reloads until no further changes are pending
+func (sr *sectionReloader) run() {
+	sr.begin()
+	defer sr.end()
+
+	for {
+		if err := sr.loadNow(); err != nil && sr.ctx.Err() == nil {
+			slog.Error("failed to reload", "section", sr.name, "error", err)
+		}
+
+		sr.mu.Lock()
+		if !sr.pending {
+			sr.running = false
+			sr.mu.Unlock()
+			return
+		}
+		sr.pending = false
+		sr.mu.Unlock()
+	}
+}
+
+// withoutContext adapts a load that reads a single file, which is quick
+// enough not to need cancelling
+func withoutContext(load func() error) func(context.Context) error {
+	return func(context.Context) error { return load() }
+}
+
+// initReloaders creates a reloader for each buddy subdirectory, custom
+// handlers' included. Loads stop early once the handlers are closed.
+func (bh *BuddyHandlers) initReloaders() {
+	bh.loadCtx, bh.stopLoads = context.WithCancel(context.Background())
+	bh.reloadsIdle = sync.NewCond(&bh.mu)
+
+	type section struct {
+		dir  string
+		load func(context.Context) error
+	}
+	sections := []section{
+		{"rules", bh.rulesHandler.LoadContext},
+		{"knowledge", bh.knowledgeHandler.LoadContext},
+		{"database", withoutContext(bh.databaseHandler.Load)},
+		{"todos", bh.todoHandler.LoadContext},
+		{"history", bh.historyHandler.LoadContext},
+		{"backups", withoutContext(bh.backupHandler.Load)},
+		{"datasets", bh.datasetsHandler.LoadContext},
+		{"compliance", bh.complianceHandler.LoadContext},
+		{"budgets", withoutContext(bh.budgetsHandler.Load)},
+		{"views", bh.viewsHandler.LoadContext},
+	}
+	for _, custom := range bh.custom {
+		sections = append(sections, section{custom.name, custom.handler.Load})
+	}
+
+	bh.reloaders = make(map[string]*sectionReloader, len(sections))
+	bh.reloadOrder = nil
+	for _, section := range sections {
+		bh.reloaders[section.dir] = &sectionReloader{
+			name:  section.dir,
+			load:  section.load,
+			ctx:   bh.loadCtx,
+			begin: bh.beginReload,
+			end:   bh.endReload,
+		}
+		bh.reloadOrder = append(bh.reloadOrder, section.dir)
+	}
+}
+
+// loadSections loads every section concurrently and waits for all of them.
+// The whole batch counts as one reload, so a single snapshot follows it.
+func (bh *BuddyHandlers) loadSections() error {
+	bh.beginReload()
+	defer bh.endReload()
+
+	errs := make([]error, len(bh.reloadOrder))
+
+	var wg sync.WaitGroup
+	for i, dir := range bh.reloadOrder {
+		wg.Add(1)
+		go func(i int, reloader *sectionReloader) {
+			defer wg.Done()
+			if err := reloader.loadNow(); err != nil {
+				errs[i] = fmt.Errorf("failed to load %s: %w", reloader.name, err)
+			}
+		}(i, bh.reloaders[dir])
+	}
+	wg.Wait()
+
+	// Report the first failure in section order so errors are deterministic
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReloadPath reloads only the section that owns the changed file, in the
+// background, so a slow reload of one content type doesn't hold up
+// queries or reloads of the others. A changed config.yaml is re-applied
+// to the handlers; other files outside a known section trigger a full
+// reload.
+func (bh *BuddyHandlers) ReloadPath(path string) error {
+	reloader, err := bh.reloaderFor(path)
+	if reloader == nil {
+		return err
+	}
+	reloader.trigger(path)
+	return nil
+}
+
+// ReloadPathNow is ReloadPath finishing the section's reload before it
+// returns, for changes clients are told about at once
+func (bh *BuddyHandlers) ReloadPathNow(path string) error {
+	reloader, err := bh.reloaderFor(path)
+	if reloader == nil {
+		return err
+	}
+	bh.beginReload()
+	defer bh.endReload()
+	return reloader.loadNow()
+}
+
+// reloaderFor returns the reloader of the section owning path. Files
+// outside a known section are reloaded as ReloadPath describes, and the
+// result of that reload returned instead.
+func (bh *BuddyHandlers) reloaderFor(path string) (*sectionReloader, error) {
+	rel, err := filepath.Rel(bh.buddyPath, path)
+	if err != nil {
+		return nil, bh.ReloadData()
+	}
+
+	if config.IsFile(rel) {
+		return nil, bh.ReloadConfig()
+	}
+	if rel == focusFile {
+		return nil, bh.focus.Load()
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 {
+		return nil, bh.ReloadData()
+	}
+
+	reloader, ok := bh.reloaders[parts[0]]
+	if !ok {
+		return nil, bh.ReloadData()
+	}
+	return reloader, nil
+}
@@ -0,0 +1,656 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// Sections buddy_reorganize can restructure
+var reorganizeSections = []string{"knowledge", "rules"}
+
+// linkPatterns match relative links in markdown ("[text](path)"), AsciiDoc
+// ("link:path[text]", "xref:path[text]") and reStructuredText
+// ("`text <path>`_"); the first group is the link target
+var linkPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\]\(([^)\s]+)`),
+	regexp.MustCompile(`\b(?:link|xref):([^\[\s]+)\[`),
+	regexp.MustCompile("<([^>\\s]+)>`_"),
+}
+
+// ReorganizeRequest selects knowledge or rules files and where they go
+type ReorganizeRequest struct {
+	Section    string            // "knowledge" or "rules"
+	Category   string            // select every file in this category
+	Files      []string          // select these files, relative to the section folder
+	ToCategory string            // category to give the selected files; empty keeps theirs
+	Renames    map[string]string // new paths by old path, relative to the section folder
+	DryRun     bool              // plan the changes without writing them
+}
+
+// ReorganizeMove is one selected file and what happens to it
+type ReorganizeMove struct {
+	From     string // relative to the section folder
+	To       string // relative to the section folder; same as From when it stays
+	Category string // the category the file ends up with
+}
+
+// ReorganizeResult reports what Reorganize changed, or would change
+type ReorganizeResult struct {
+	Moves      []ReorganizeMove
+	Links      map[string]int // links rewritten, by file relative to the buddy folder
+	SnapshotID string         // restore point undoing the whole change
+}
+
+// Reorganize moves and recategorizes a group of knowledge or rules files
+// in one step: each file's category header is rewritten in its own
+// format, knowledge files kept in a folder named after their old category
+// move to the new category's folder, and relative links to and from the
+// moved files are updated across knowledge and rules. The touched files
+// are snapshotted first and the indexes reloaded after; a failed write
+// puts back the files already changed.
+func (bh *BuddyHandlers) Reorganize(ctx context.Context, req ReorganizeRequest) (*ReorganizeResult, error) {
+	if !slices.Contains(reorganizeSections, req.Section) {
+		return nil, fmt.Errorf("invalid section: %s (expected one of %s)", req.Section, strings.Join(reorganizeSections, ", "))
+	}
+	if req.ToCategory == "" && len(req.Renames) == 0 {
+		return nil, fmt.Errorf("to_category or renames is required")
+	}
+	sectionDir := filepath.Join(bh.buddyPath, req.Section)
+
+	selected, err := bh.selectReorganizeFiles(req, sectionDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no %s files match the selection", req.Section)
+	}
+
+	// Read everything that may link to a moved file before planning
+	contents, err := bh.readLinkedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReorganizeResult{Links: make(map[string]int)}
+	moved := make(map[string]string, len(selected))
+	targets := make(map[string]string, len(selected))
+	categories := make(map[string]string, len(selected))
+	for _, from := range selected {
+		content, ok := contents[from]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a %s file", bh.relativeBuddyPath(from), req.Section)
+		}
+		category := reorganizeCategory(req.Section, sectionDir, from, content)
+		to, err := reorganizeTarget(req, sectionDir, from, category)
+		if err != nil {
+			return nil, err
+		}
+		if other, taken := targets[to]; taken {
+			return nil, fmt.Errorf("%s and %s would both move to %s", bh.relativeBuddyPath(other), bh.relativeBuddyPath(from), bh.relativeBuddyPath(to))
+		}
+		targets[to] = from
+		moved[from] = to
+		if req.ToCategory != "" {
+			category = req.ToCategory
+		}
+		categories[from] = category
+	}
+	for to, from := range targets {
+		if _, leaving := moved[to]; to == from || leaving {
+			continue
+		}
+		if _, err := bh.store.Stat(to); err == nil {
+			return nil, fmt.Errorf("%s already exists", bh.relativeBuddyPath(to))
+		}
+	}
+
+	// Work out every file's new content
+	writes := make(map[string][]byte)
+	var removes []string
+	for path, content := range contents {
+		to, isMoved := moved[path]
+		if !isMoved {
+			to = path
+		}
+		text := string(content)
+		if isMoved && req.ToCategory != "" {
+			if text, err = setCategoryHeader(text, req.ToCategory, filepath.Ext(path)); err != nil {
+				return nil, fmt.Errorf("%s: %w", bh.relativeBuddyPath(path), err)
+			}
+		}
+		text, links := bh.rewriteLinks(text, path, to, moved)
+		if links > 0 {
+			result.Links[bh.relativeBuddyPath(to)] = links
+		}
+		if to != path {
+			removes = append(removes, path)
+		}
+		if to != path || text != string(content) {
+			writes[to] = []byte(text)
+		}
+	}
+	// A file moved onto another moved file's old path is written, not removed
+	kept := removes[:0]
+	for _, path := range removes {
+		if _, written := writes[path]; !written {
+			kept = append(kept, path)
+		}
+	}
+	removes = kept
+	sort.Strings(removes)
+
+	for _, from := range selected {
+		result.Moves = append(result.Moves, ReorganizeMove{
+			From:     relativeTo(sectionDir, from),
+			To:       relativeTo(sectionDir, moved[from]),
+			Category: categories[from],
+		})
+	}
+	if req.DryRun || (len(writes) == 0 && len(removes) == 0) {
+		return result, nil
+	}
+
+	paths := make([]string, 0, len(writes)+len(removes))
+	for path := range writes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	snapshot, err := bh.backupHandler.safety.RestorePoint(ctx, "reorganize", reorganizeLabel(req, len(selected)), append(append([]string(nil), paths...), removes...))
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		result.SnapshotID = snapshot.ID
+	}
+
+	if err := bh.applyReorganize(ctx, paths, writes, removes); err != nil {
+		bh.ReloadData()
+		return nil, err
+	}
+	return result, bh.ReloadData()
+}
+
+// reorganizeLabel describes a reorganization for its restore point
+func reorganizeLabel(req ReorganizeRequest, files int) string {
+	label := fmt.Sprintf("%d %s files", files, req.Section)
+	if req.Category != "" {
+		label += " in category " + req.Category
+	}
+	if req.ToCategory != "" {
+		label += " moved to category " + req.ToCategory
+	}
+	if len(req.Renames) > 0 {
+		label += fmt.Sprintf(", %d renamed", len(req.Renames))
+	}
+	return label
+}
+
+// applyReorganize writes and removes the planned files, putting back the
+// ones already changed when one fails
+func (bh *BuddyHandlers) applyReorganize(ctx context.Context, paths []string, writes map[string][]byte, removes []string) error {
+	var applied UndoEntry
+	fail := func(err error) error {
+		if rollbackErr := revertEntry(applied); rollbackErr != nil {
+			return fmt.Errorf("%w (rolling back also failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	for _, path := range paths {
+		previous, existed, err := readExisting(bh.store, path)
+		if err != nil {
+			return fail(err)
+		}
+		if err := writeFile(ctx, bh.store, path, writes[path]); err != nil {
+			return fail(fmt.Errorf("failed to write %s: %w", bh.relativeBuddyPath(path), err))
+		}
+		applied.Changes = append(applied.Changes, UndoChange{Path: path, Existed: existed, Previous: previous, Current: writes[path], store: bh.store})
+	}
+	for _, path := range removes {
+		previous, _, err := readExisting(bh.store, path)
+		if err != nil {
+			return fail(err)
+		}
+		if err := removeFile(ctx, bh.store, path); err != nil {
+			return fail(fmt.Errorf("failed to remove %s: %w", bh.relativeBuddyPath(path), err))
+		}
+		applied.Changes = append(applied.Changes, UndoChange{Path: path, Existed: true, Previous: previous, Removed: true, store: bh.store})
+	}
+	return nil
+}
+
+// selectReorganizeFiles returns the absolute paths of the files in the
+// request's category, its files list and its renames, sorted
+func (bh *BuddyHandlers) selectReorganizeFiles(req ReorganizeRequest, sectionDir string) ([]string, error) {
+	seen := make(map[string]bool)
+	var selected []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			selected = append(selected, path)
+		}
+	}
+
+	if req.Category != "" {
+		if req.Section == "rules" {
+			for _, rule := range bh.rulesHandler.GetRulesByCategory(req.Category) {
+				add(filepath.Clean(rule.FilePath))
+			}
+		} else {
+			for _, kb := range bh.knowledgeHandler.GetKnowledgeByCategory(req.Category) {
+				add(filepath.Clean(kb.FilePath))
+			}
+		}
+	}
+
+	names := append(append([]string(nil), req.Files...), slices.Sorted(maps.Keys(req.Renames))...)
+	for _, name := range names {
+		path, err := sectionPath(sectionDir, name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := bh.store.Stat(path); err != nil {
+			if storage.IsNotExist(err) {
+				return nil, fmt.Errorf("%s not found in %s", name, req.Section)
+			}
+			return nil, err
+		}
+		add(path)
+	}
+
+	sort.Strings(selected)
+	return selected, nil
+}
+
+// readLinkedFiles reads every knowledge and rules file, by absolute path
+func (bh *BuddyHandlers) readLinkedFiles() (map[string][]byte, error) {
+	contents := make(map[string][]byte)
+	sections := []struct {
+		dir        string
+		extensions []string
+		recursive  bool
+	}{
+		{filepath.Join(bh.buddyPath, "knowledge"), bh.knowledgeHandler.spec.Extensions, bh.knowledgeHandler.spec.Recursive},
+		{filepath.Join(bh.buddyPath, "rules"), bh.rulesHandler.spec.Extensions, bh.rulesHandler.spec.Recursive},
+	}
+	for _, section := range sections {
+		files, err := bh.store.List(section.dir, section.recursive)
+		if err != nil {
+			if storage.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, file := range files {
+			if file.IsDir || !slices.Contains(section.extensions, strings.ToLower(filepath.Ext(file.Path))) {
+				continue
+			}
+			content, err := bh.store.Read(file.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", bh.relativeBuddyPath(file.Path), err)
+			}
+			contents[filepath.Clean(file.Path)] = content
+		}
+	}
+	return contents, nil
+}
+
+// reorganizeTarget returns where a selected file goes: its rename when it
+// has one, otherwise, for knowledge kept in a folder named after its old
+// category, the same path under the new category's folder
+func reorganizeTarget(req ReorganizeRequest, sectionDir, from, category string) (string, error) {
+	rel := relativeTo(sectionDir, from)
+	for old, renamed := range req.Renames {
+		if filepath.ToSlash(filepath.Clean(old)) != rel {
+			continue
+		}
+		to, err := sectionPath(sectionDir, renamed)
+		if err != nil {
+			return "", err
+		}
+		if req.Section == "rules" && filepath.Dir(to) != sectionDir {
+			return "", fmt.Errorf("invalid rename %s: rules can't be in subfolders", renamed)
+		}
+		if !strings.EqualFold(filepath.Ext(to), filepath.Ext(from)) {
+			return "", fmt.Errorf("invalid rename %s: the extension must stay %s", renamed, filepath.Ext(from))
+		}
+		return to, nil
+	}
+
+	if req.Section == "knowledge" && req.ToCategory != "" && category != "" {
+		parts := strings.SplitN(rel, "/", 2)
+		if len(parts) == 2 && parts[0] == category {
+			return sectionPath(sectionDir, req.ToCategory+"/"+parts[1])
+		}
+	}
+	return from, nil
+}
+
+// sectionPath resolves a path relative to a section folder, refusing
+// paths that leave it
+func sectionPath(sectionDir, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if name == "" || filepath.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path %q: paths are relative to the %s folder", name, filepath.Base(sectionDir))
+	}
+	return filepath.Join(sectionDir, clean), nil
+}
+
+// relativeTo returns path relative to dir with forward slashes
+func relativeTo(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// relativeBuddyPath returns path relative to the buddy folder for messages
+func (bh *BuddyHandlers) relativeBuddyPath(path string) string {
+	return relativeTo(bh.buddyPath, path)
+}
+
+// reorganizeCategory returns a file's current category the way its
+// section's parser reads it
+func reorganizeCategory(section, sectionDir, path string, content []byte) string {
+	if section == "rules" {
+		_, body := splitFrontmatter(string(content))
+		for i, line := range strings.Split(body, "\n") {
+			if strings.HasPrefix(line, "Category: ") {
+				return strings.TrimSpace(strings.TrimPrefix(line, "Category: "))
+			}
+			if line == "" && i > 0 {
+				break
+			}
+		}
+		return ""
+	}
+
+	var doc knowledgeDocument
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".adoc", ".asciidoc":
+		doc = parseAsciiDocKnowledge(string(content))
+	case ".rst":
+		doc = parseRSTKnowledge(string(content))
+	default:
+		doc = parseMarkdownKnowledge(string(content))
+	}
+	if doc.category != "" {
+		return doc.category
+	}
+	if parts := strings.SplitN(relativeTo(sectionDir, path), "/", 2); len(parts) == 2 {
+		return parts[0]
+	}
+	return ""
+}
+
+// setCategoryHeader replaces a document's category header, or adds one
+// after its title, in the header syntax of the file's format
+func setCategoryHeader(text, category, ext string) (string, error) {
+	if strings.Contains(text, "\r\n") {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+
+	switch strings.ToLower(ext) {
+	case ".adoc", ".asciidoc":
+		return setFieldHeader(text, category, func(lines []string) (int, int) {
+			i := 0
+			for i < len(lines) && (strings.TrimSpace(lines[i]) == "" || strings.HasPrefix(lines[i], "//")) {
+				i++
+			}
+			if i >= len(lines) || !strings.HasPrefix(lines[i], "= ") {
+				return -1, -1
+			}
+			end := i + 1
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "" {
+				end++
+			}
+			return i + 1, end
+		})
+	case ".rst":
+		return setFieldHeader(text, category, func(lines []string) (int, int) {
+			i := 0
+			for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+				i++
+			}
+			if i+2 < len(lines) && rstAdornment(lines[i]) && rstAdornment(lines[i+2]) {
+				i += 3
+			} else if i+1 < len(lines) && !rstAdornment(lines[i]) && rstAdornment(lines[i+1]) {
+				i += 2
+			} else {
+				return -1, -1
+			}
+			start := i
+			for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+				start++
+			}
+			end := start
+			for end < len(lines) {
+				if _, _, ok := parseFieldLine(lines[end]); !ok {
+					break
+				}
+				end++
+			}
+			if end == start {
+				// No field list yet: add one after the title
+				return i, i
+			}
+			return start, end
+		})
+	}
+
+	// Markdown keeps "Category: " in the lines before the first blank one,
+	// after any frontmatter
+	front, body := splitFrontmatter(text)
+	prefix := ""
+	if front != "" {
+		prefix = text[:len(text)-len(body)]
+	}
+	lines := strings.Split(body, "\n")
+	insert := 0
+	for i, line := range lines {
+		if strings.HasPrefix(line, "Category: ") {
+			lines[i] = "Category: " + category
+			return prefix + strings.Join(lines, "\n"), nil
+		}
+		if strings.HasPrefix(line, "# ") {
+			insert = i + 1
+		}
+		if line == "" && i > 0 {
+			break
+		}
+	}
+	lines = append(lines[:insert], append([]string{"Category: " + category}, lines[insert:]...)...)
+	return prefix + strings.Join(lines, "\n"), nil
+}
+
+// setFieldHeader sets a ":category:" field line within the header lines
+// [start, end) that header finds, appending one when there is none.
+// header returns -1 when the document has no title to hold a header.
+func setFieldHeader(text, category string, header func(lines []string) (int, int)) (string, error) {
+	lines := strings.Split(text, "\n")
+	start, end := header(lines)
+	if start < 0 {
+		return "", fmt.Errorf("no document title, so there is no header to hold the category")
+	}
+	field := ":category: " + category
+	for i := start; i < end; i++ {
+		if name, _, ok := parseFieldLine(lines[i]); ok && name == "category" {
+			lines[i] = field
+			return strings.Join(lines, "\n"), nil
+		}
+	}
+
+	added := []string{field}
+	if start == end && end < len(lines) {
+		// A new reStructuredText field list is set apart by blank lines
+		added = []string{"", field}
+		if strings.TrimSpace(lines[end]) != "" {
+			added = append(added, "")
+		}
+	}
+	lines = append(lines[:end], append(added, lines[end:]...)...)
+	return strings.Join(lines, "\n"), nil
+}
+
+// rewriteLinks points relative links in a file at the new locations of
+// moved files, and keeps a moved file's own links pointing where they did.
+// It returns the new text and how many links changed.
+func (bh *BuddyHandlers) rewriteLinks(text, from, to string, moved map[string]string) (string, int) {
+	count := 0
+	for _, pattern := range linkPatterns {
+		matches := pattern.FindAllStringSubmatchIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var sb strings.Builder
+		last := 0
+		for _, match := range matches {
+			target := text[match[2]:match[3]]
+			rewritten, ok := bh.rewriteLink(target, from, to, moved)
+			if !ok {
+				continue
+			}
+			sb.WriteString(text[last:match[2]])
+			sb.WriteString(rewritten)
+			last = match[3]
+			count++
+		}
+		sb.WriteString(text[last:])
+		text = sb.String()
+	}
+	return text, count
+}
+
+// rewriteLink returns a relative link target as seen from the file's new
+// location, and whether it changed. Links to missing files, absolute paths
+// and URLs are left alone.
+func (bh *BuddyHandlers) rewriteLink(target, from, to string, moved map[string]string) (string, bool) {
+	if strings.Contains(target, "://") || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") || strings.HasPrefix(target, "mailto:") {
+		return "", false
+	}
+	linkPath, fragment, _ := strings.Cut(target, "#")
+	if linkPath == "" {
+		return "", false
+	}
+
+	linked := filepath.Join(filepath.Dir(from), filepath.FromSlash(linkPath))
+	newLinked, isMoved := moved[linked]
+	if !isMoved {
+		if from == to {
+			return "", false
+		}
+		if _, err := bh.store.Stat(linked); err != nil {
+			return "", false
+		}
+		newLinked = linked
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(to), newLinked)
+	if err != nil {
+		return "", false
+	}
+	rewritten := filepath.ToSlash(rel)
+	if rewritten == linkPath {
+		return "", false
+	}
+	if fragment != "" {
+		rewritten += "#" + fragment
+	}
+	return rewritten, true
+}
+
+// ReorganizeArgs are the arguments of buddy_reorganize
+type ReorganizeArgs struct {
+	Section    string            `arg:"section" enum:"knowledge,rules" desc:"Files to reorganize (default: knowledge)"`
+	Category   string            `arg:"category" desc:"Select every file in this category (optional)"`
+	Files      []string          `arg:"files" desc:"Select these files, relative to the section folder (optional)"`
+	ToCategory string            `arg:"to_category" desc:"Category to give the selected files (optional if renames is given)"`
+	Renames    map[string]string `arg:"renames" desc:"New paths by old path, relative to the section folder, e.g. {\"auth/jwt.md\": \"security/tokens.md\"} (optional)"`
+	DryRun     bool              `arg:"dry_run" desc:"List the changes without making them (optional)"`
+}
+
+// GetReorganizeToolHandler returns the tool handler that moves groups of
+// knowledge or rules files between categories
+func (bh *BuddyHandlers) GetReorganizeToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args ReorganizeArgs) (*mcp.CallToolResult, error) {
+		req := ReorganizeRequest{
+			Section:    args.Section,
+			Category:   args.Category,
+			ToCategory: args.ToCategory,
+			Renames:    args.Renames,
+			DryRun:     args.DryRun,
+		}
+		if req.Section == "" {
+			req.Section = "knowledge"
+		}
+		for _, file := range args.Files {
+			if file != "" {
+				req.Files = append(req.Files, file)
+			}
+		}
+		for _, to := range args.Renames {
+			if to == "" {
+				return nil, fmt.Errorf("renames must map each old path to a new path")
+			}
+		}
+		if req.Category == "" && len(req.Files) == 0 && len(req.Renames) == 0 {
+			return nil, fmt.Errorf("category, files or renames is required to select files")
+		}
+
+		result, err := bh.Reorganize(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(formatReorganizeResult(req, result)), nil
+	})
+}
+
+// formatReorganizeResult lists the moved files and rewritten links
+func formatReorganizeResult(req ReorganizeRequest, result *ReorganizeResult) string {
+	var sb strings.Builder
+	if req.DryRun {
+		fmt.Fprintf(&sb, "🔍 Dry run: would reorganize %d %s files\n\n", len(result.Moves), req.Section)
+	} else {
+		fmt.Fprintf(&sb, "📦 Reorganized %d %s files\n\n", len(result.Moves), req.Section)
+	}
+
+	for _, move := range result.Moves {
+		if move.From == move.To {
+			fmt.Fprintf(&sb, "- %s (category: %s)\n", move.From, move.Category)
+		} else {
+			fmt.Fprintf(&sb, "- %s → %s (category: %s)\n", move.From, move.To, move.Category)
+		}
+	}
+
+	if len(result.Links) > 0 {
+		files := make([]string, 0, len(result.Links))
+		total := 0
+		for file, count := range result.Links {
+			files = append(files, file)
+			total += count
+		}
+		sort.Strings(files)
+		fmt.Fprintf(&sb, "\n🔗 Links updated: %d in %d files\n", total, len(files))
+		for _, file := range files {
+			fmt.Fprintf(&sb, "- %s (%d)\n", file, result.Links[file])
+		}
+	}
+
+	if result.SnapshotID != "" {
+		fmt.Fprintf(&sb, "\n🛟 Restore point: %s\n", result.SnapshotID)
+		sb.WriteString("💡 Undo the whole reorganization with buddy_backup action restore_safety and this snapshot_id, or with buddy_undo in this session")
+	}
+	return sb.String()
+}
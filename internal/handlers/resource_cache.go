@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxCachedResources bounds the cache, since templated resources such as
+// buddy://changes can be read under any number of URIs
+const maxCachedResources = 64
+
+// cachedResource is the rendered body of one resource URI
+type cachedResource struct {
+	snap     *ContextSnapshot // snapshot the body was built from
+	text     string
+	etag     string
+	cachedAt time.Time
+}
+
+// ResourceCache keeps rendered resource bodies until the next reload
+// publishes a new snapshot, so clients polling resources don't recompute
+// them on every read.
+type ResourceCache struct {
+	entries map[string]cachedResource
+	mu      sync.Mutex
+}
+
+// NewResourceCache creates an empty resource cache
+func NewResourceCache() *ResourceCache {
+	return &ResourceCache{entries: make(map[string]cachedResource)}
+}
+
+// get returns the cached body of uri if it was built from snap and is
+// younger than maxAge. A zero maxAge means it never ages out.
+func (rc *ResourceCache) get(uri string, snap *ContextSnapshot, maxAge time.Duration, now time.Time) (cachedResource, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	entry, ok := rc.entries[uri]
+	if !ok || entry.snap != snap {
+		return cachedResource{}, false
+	}
+	if maxAge > 0 && now.Sub(entry.cachedAt) >= maxAge {
+		return cachedResource{}, false
+	}
+	return entry, true
+}
+
+// put stores a body, dropping entries built from older snapshots
+func (rc *ResourceCache) put(uri string, entry cachedResource) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	for key, existing := range rc.entries {
+		if existing.snap != entry.snap {
+			delete(rc.entries, key)
+		}
+	}
+	if _, ok := rc.entries[uri]; !ok && len(rc.entries) >= maxCachedResources {
+		for key := range rc.entries {
+			delete(rc.entries, key)
+			break
+		}
+	}
+	rc.entries[uri] = entry
+}
+
+// contentETag derives a strong ETag from a resource body
+func contentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// ifNoneMatch reads the ETag a client already holds, from the
+// if_none_match read argument or query parameter
+func ifNoneMatch(request mcp.ReadResourceRequest) string {
+	if etag, ok := request.Params.Arguments["if_none_match"].(string); ok && etag != "" {
+		return etag
+	}
+	if uri, err := url.Parse(request.Params.URI); err == nil {
+		return uri.Query().Get("if_none_match")
+	}
+	return ""
+}
+
+// cacheKey drops if_none_match from a URI so conditional and plain reads
+// share one cache entry
+func cacheKey(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.RawQuery == "" {
+		return uri
+	}
+	query := parsed.Query()
+	query.Del("if_none_match")
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// readCachedResource serves a JSON resource through the cache. build
+// renders the body from the snapshot; the body gets an "etag" field, and a
+// client whose if_none_match equals it gets a short not-modified body
+// instead. maxAge bounds bodies that also depend on the clock.
+func (bh *BuddyHandlers) readCachedResource(request mcp.ReadResourceRequest, maxAge time.Duration, build func(snap *ContextSnapshot, now time.Time) (map[string]interface{}, error)) ([]mcp.ResourceContents, error) {
+	uri := request.Params.URI
+	key := cacheKey(uri)
+	now := time.Now()
+	snap := bh.Snapshot()
+
+	entry, ok := bh.resources.get(key, snap, maxAge, now)
+	if !ok {
+		payload, err := build(snap, now)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := marshalFunc(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", key, err)
+		}
+		payload["etag"] = contentETag(data)
+		if data, err = marshalFunc(payload); err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", key, err)
+		}
+
+		entry = cachedResource{
+			snap:     snap,
+			text:     string(data),
+			etag:     payload["etag"].(string),
+			cachedAt: now,
+		}
+		bh.resources.put(key, entry)
+	}
+
+	text := entry.text
+	if ifNoneMatch(request) == entry.etag {
+		data, err := marshalFunc(map[string]interface{}{
+			"etag":         entry.etag,
+			"not_modified": true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", key, err)
+		}
+		text = string(data)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "application/json",
+			Text:     text,
+		},
+	}, nil
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// resourceNotifyDelay coalesces the reloads of a burst of file changes,
+// such as a branch switch, into one notification per resource
+const resourceNotifyDelay = 250 * time.Millisecond
+
+// ResourceNotifier tells connected clients that resources built from the
+// buddy files changed, by sending notifications/resources/updated.
+//
+// mcp-go doesn't route resources/subscribe requests, so there is no
+// subscription list to consult; every initialized session is notified and
+// clients not interested in a resource ignore it.
+type ResourceNotifier struct {
+	send    func(method string, params map[string]any)
+	uris    []string
+	pending *time.Timer
+	mu      sync.Mutex
+}
+
+// NewResourceNotifier creates a notifier announcing updates of uris to
+// every client of mcpServer
+func NewResourceNotifier(mcpServer *server.MCPServer, uris ...string) *ResourceNotifier {
+	return &ResourceNotifier{
+		send: mcpServer.SendNotificationToAllClients,
+		uris: uris,
+	}
+}
+
+// Notify schedules an update notification for every resource. Calls
+// within resourceNotifyDelay of each other send a single notification.
+func (rn *ResourceNotifier) Notify() {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if rn.pending != nil {
+		return
+	}
+	rn.pending = time.AfterFunc(resourceNotifyDelay, rn.flush)
+}
+
+// NotifyNow sends an update notification for every resource at once,
+// taking the place of any scheduled one
+func (rn *ResourceNotifier) NotifyNow() {
+	rn.mu.Lock()
+	if rn.pending != nil {
+		rn.pending.Stop()
+	}
+	rn.mu.Unlock()
+	rn.flush()
+}
+
+// flush sends the pending notifications
+func (rn *ResourceNotifier) flush() {
+	rn.mu.Lock()
+	rn.pending = nil
+	rn.mu.Unlock()
+
+	for _, uri := range rn.uris {
+		rn.send(mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+	}
+}
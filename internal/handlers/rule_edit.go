@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/slug"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// rulePriorities are the priorities a rule can have
+var rulePriorities = []string{"critical", "recommended", "optional"}
+
+// RuleEdit is what creating or updating a rule sets. On update, empty
+// fields keep the rule's current values.
+type RuleEdit struct {
+	Title     string
+	Category  string
+	Priority  string
+	Content   string   // the rule's text below its headers
+	Tags      []string // for the Tags header; nil keeps the current ones and empty removes them
+	AppliesTo []string // globs for the applies_to frontmatter; nil keeps the current ones
+	File      string   // file name for a new rule; derived from the title when empty
+	Extends   string   // ID, file name or title of the rule it builds on
+	headers   []string // further header lines for a new rule, as templates add
+}
+
+// CreateRule writes a new rule file in the format the rules are loaded
+// from. The category defaults to general and the priority to recommended,
+// unless the rule extends another and inherits them.
+func (rh *RulesHandler) CreateRule(ctx context.Context, edit RuleEdit) (models.Rule, error) {
+	edit.Title = strings.TrimSpace(edit.Title)
+	if edit.Title == "" {
+		return models.Rule{}, fmt.Errorf("title is required to create a rule")
+	}
+	if strings.TrimSpace(edit.Content) == "" {
+		return models.Rule{}, fmt.Errorf("content is required to create a rule")
+	}
+	if edit.Category == "" && edit.Extends == "" {
+		edit.Category = "general"
+	}
+	if edit.Priority == "" && edit.Extends == "" {
+		edit.Priority = "recommended"
+	}
+	if err := checkRuleEdit(edit); err != nil {
+		return models.Rule{}, err
+	}
+
+	path, err := rh.newRulePath(edit)
+	if err != nil {
+		return models.Rule{}, err
+	}
+
+	var sb strings.Builder
+	if len(edit.AppliesTo) > 0 {
+		front, err := yaml.Marshal(map[string][]string{"applies_to": edit.AppliesTo})
+		if err != nil {
+			return models.Rule{}, err
+		}
+		fmt.Fprintf(&sb, "---\n%s---\n", front)
+	}
+	fmt.Fprintf(&sb, "# %s\n", edit.Title)
+	for _, header := range []struct{ prefix, value string }{{"Category: ", edit.Category}, {"Priority: ", edit.Priority}, {"Tags: ", strings.Join(edit.Tags, ", ")}, {"Extends: ", edit.Extends}} {
+		if header.value != "" {
+			fmt.Fprintf(&sb, "%s%s\n", header.prefix, header.value)
+		}
+	}
+	for _, header := range edit.headers {
+		fmt.Fprintf(&sb, "%s\n", header)
+	}
+	fmt.Fprintf(&sb, "\n%s\n", strings.TrimSpace(edit.Content))
+	return rh.writeRule(ctx, path, sb.String())
+}
+
+// newRulePath picks the file a new rule is written to: the requested name,
+// which must be free, or one made from the title
+func (rh *RulesHandler) newRulePath(edit RuleEdit) (string, error) {
+	if edit.File != "" {
+		name := edit.File
+		if !strings.EqualFold(filepath.Ext(name), ".md") {
+			name += ".md"
+		}
+		if strings.ContainsAny(name, `/\`) {
+			return "", fmt.Errorf("invalid file %q: rules can't be in subfolders", edit.File)
+		}
+		path, err := sectionPath(rh.path, name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := rh.store.Stat(path); !storage.IsNotExist(err) {
+			return "", fmt.Errorf("rules/%s already exists; use action update to change it", name)
+		}
+		return path, nil
+	}
+
+	files := rh.files.Load()
+	name, err := slug.FileName(files.NameTemplate, slug.Fields{
+		Slug: slug.Make(edit.Title, files.MaxSlugLength, "rule"),
+		Kind: "rule",
+		Date: time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(rh.path, slug.Unique(name, ".md", func(fileName string) bool {
+		_, err := rh.store.Stat(filepath.Join(rh.path, fileName))
+		return !storage.IsNotExist(err)
+	})), nil
+}
+
+// UpdateRule rewrites the headers, text or applies_to globs of the rule
+// named by ref, leaving the rest of its file as it is. The file is
+// snapshotted first.
+func (rh *RulesHandler) UpdateRule(ctx context.Context, ref string, edit RuleEdit) (models.Rule, error) {
+	if edit.Title == "" && edit.Category == "" && edit.Priority == "" && edit.Tags == nil && edit.Extends == "" && edit.Content == "" && edit.AppliesTo == nil {
+		return models.Rule{}, fmt.Errorf("nothing to update: set title, category, priority, tags, extends, content or applies_to")
+	}
+	if err := checkRuleEdit(edit); err != nil {
+		return models.Rule{}, err
+	}
+	rule, err := rh.FindRule(ref)
+	if err != nil {
+		return models.Rule{}, err
+	}
+	content, err := rh.store.Read(rule.FilePath)
+	if err != nil {
+		return models.Rule{}, err
+	}
+
+	front, body := splitFrontmatter(string(content))
+	if edit.AppliesTo != nil {
+		if front, err = setFrontmatterList(front, "applies_to", edit.AppliesTo); err != nil {
+			return models.Rule{}, fmt.Errorf("failed to update frontmatter: %w", err)
+		}
+	}
+	text := setRuleHeaders(body, edit)
+	if front != "" {
+		text = "---\n" + strings.TrimSuffix(front, "\n") + "\n---\n" + text
+	}
+
+	if _, err := rh.safety.Snapshot(ctx, "rule_update", []string{rule.FilePath}); err != nil {
+		return models.Rule{}, err
+	}
+	return rh.writeRule(ctx, rule.FilePath, text)
+}
+
+// DeleteRule removes the file of the rule named by ref, snapshotting it
+// first
+func (rh *RulesHandler) DeleteRule(ctx context.Context, ref string) (models.Rule, error) {
+	rule, err := rh.FindRule(ref)
+	if err != nil {
+		return models.Rule{}, err
+	}
+	if err := rh.checkExtends(rule, true); err != nil {
+		return models.Rule{}, fmt.Errorf("can't delete %q, other rules build on it: %w", rule.Title, err)
+	}
+	if _, err := rh.safety.Snapshot(ctx, "rule_delete", []string{rule.FilePath}); err != nil {
+		return models.Rule{}, err
+	}
+	if err := removeFile(ctx, rh.store, rule.FilePath); err != nil {
+		return models.Rule{}, fmt.Errorf("failed to delete %s: %w", filepath.Base(rule.FilePath), err)
+	}
+	return rule, rh.LoadContext(ctx)
+}
+
+// FindRule returns the rule whose ID, file name or title is ref. Titles
+// match ignoring case and must be unique.
+func (rh *RulesHandler) FindRule(ref string) (models.Rule, error) {
+	return rh.findRuleIn(rh.GetRules(), ref)
+}
+
+// findRuleIn is FindRule among the given rules
+func (rh *RulesHandler) findRuleIn(rules []models.Rule, ref string) (models.Rule, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return models.Rule{}, fmt.Errorf("rule is required: give its ID, file name or title")
+	}
+	name := strings.TrimPrefix(filepath.ToSlash(ref), "rules/")
+	var byTitle []models.Rule
+	for _, rule := range rules {
+		file := relativeTo(rh.path, rule.FilePath)
+		if rule.ID == ref || file == name || file == name+".md" {
+			return rule, nil
+		}
+		if strings.EqualFold(strings.TrimSpace(rule.Title), ref) {
+			byTitle = append(byTitle, rule)
+		}
+	}
+	switch len(byTitle) {
+	case 0:
+		return models.Rule{}, fmt.Errorf("no rule matches %q", ref)
+	case 1:
+		return byTitle[0], nil
+	default:
+		return models.Rule{}, fmt.Errorf("%d rules are titled %q; give the file name instead", len(byTitle), ref)
+	}
+}
+
+// DisabledRules reads the rule files marked "Disabled: true", which
+// loading skips. Files that don't parse are left to the reload to report.
+func (rh *RulesHandler) DisabledRules() ([]models.Rule, error) {
+	files, err := rh.store.List(rh.path, false)
+	if storage.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rules []models.Rule
+	for _, file := range files {
+		if !rh.matchesExtension(file.Path) {
+			continue
+		}
+		content, err := rh.store.Read(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if rule, err := parseRule(rh.path, file, content); err == nil && rule.Disabled {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// SetRuleDisabled disables or re-enables the rule named by ref by adding or
+// removing its "Disabled: true" header line. A disabled rule stays on disk
+// but is left out of listings, exports, checks and the search index.
+func (rh *RulesHandler) SetRuleDisabled(ctx context.Context, ref string, disabled bool) (models.Rule, error) {
+	off, err := rh.DisabledRules()
+	if err != nil {
+		return models.Rule{}, err
+	}
+	rule, err := rh.findRuleIn(append(rh.GetRules(), off...), ref)
+	if err != nil {
+		return models.Rule{}, err
+	}
+	if rule.Disabled == disabled {
+		state := "enabled"
+		if disabled {
+			state = "disabled"
+		}
+		return models.Rule{}, fmt.Errorf("rule %q is already %s", rule.Title, state)
+	}
+
+	content, err := rh.store.Read(rule.FilePath)
+	if err != nil {
+		return models.Rule{}, err
+	}
+	front, body := splitFrontmatter(string(content))
+	header, rest := splitRuleHeader(body)
+	kept := header[:0]
+	for _, line := range header {
+		if !strings.HasPrefix(line, "Disabled: ") {
+			kept = append(kept, line)
+		}
+	}
+	if disabled {
+		kept = append(kept, "Disabled: true")
+	}
+	text := strings.Join(kept, "\n") + "\n\n" + strings.Join(rest, "\n")
+	if front != "" {
+		text = "---\n" + strings.TrimSuffix(front, "\n") + "\n---\n" + text
+	}
+	return rh.writeRule(ctx, rule.FilePath, text)
+}
+
+// checkRuleEdit rejects values the rule headers can't hold
+func checkRuleEdit(edit RuleEdit) error {
+	if edit.Priority != "" && !slices.Contains(rulePriorities, edit.Priority) {
+		return fmt.Errorf("invalid priority: %s (expected one of %s)", edit.Priority, strings.Join(rulePriorities, ", "))
+	}
+	for _, field := range []struct{ name, value string }{{"title", edit.Title}, {"category", edit.Category}, {"extends", edit.Extends}} {
+		if strings.ContainsAny(field.value, "\r\n") {
+			return fmt.Errorf("%s must be a single line", field.name)
+		}
+	}
+	for _, tag := range edit.Tags {
+		if strings.ContainsAny(tag, ",\r\n") {
+			return fmt.Errorf("invalid tag %q: tags are single words or phrases without commas", tag)
+		}
+	}
+	return nil
+}
+
+// writeRule checks that content parses as a rule and that its Extends
+// still resolve, writes it and reloads the rules, returning the rule as
+// loaded
+func (rh *RulesHandler) writeRule(ctx context.Context, path, content string) (models.Rule, error) {
+	rule, err := parseRule(rh.path, storage.FileInfo{Path: path, ModTime: time.Now()}, []byte(content))
+	if err != nil {
+		return models.Rule{}, fmt.Errorf("invalid rule: %w", err)
+	}
+	if err := rh.checkExtends(rule, false); err != nil {
+		return models.Rule{}, fmt.Errorf("invalid rule: %w", err)
+	}
+	if err := writeFile(ctx, rh.store, path, []byte(content)); err != nil {
+		return models.Rule{}, fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := rh.LoadContext(ctx); err != nil {
+		return rule, err
+	}
+	if loaded, ok := rh.Get(rule.ID); ok {
+		rule = loaded
+	}
+	return rule, nil
+}
+
+// setRuleHeaders replaces the title, category, priority, tags and extends
+// lines of a rule body and the text below them with the edit's non-empty
+// values, adding header lines the body lacks. Empty tags remove the Tags
+// line.
+func setRuleHeaders(body string, edit RuleEdit) string {
+	header, rest := splitRuleHeader(body)
+	set := func(prefix, value string, at int) {
+		if value == "" {
+			return
+		}
+		for i, line := range header {
+			if strings.HasPrefix(line, prefix) {
+				header[i] = prefix + value
+				return
+			}
+		}
+		if at > len(header) {
+			at = len(header)
+		}
+		header = append(header[:at], append([]string{prefix + value}, header[at:]...)...)
+	}
+	set("# ", edit.Title, 0)
+	set("Category: ", edit.Category, 1)
+	set("Priority: ", edit.Priority, 2)
+	if edit.Tags != nil && len(edit.Tags) == 0 {
+		for i, line := range header {
+			if strings.HasPrefix(line, "Tags: ") {
+				header = append(header[:i], header[i+1:]...)
+				break
+			}
+		}
+	}
+	set("Tags: ", strings.Join(edit.Tags, ", "), 3)
+	set("Extends: ", edit.Extends, 4)
+
+	text := strings.TrimRight(strings.Join(rest, "\n"), "\n")
+	if edit.Content != "" {
+		text = strings.TrimSpace(edit.Content)
+	}
+	return strings.Join(header, "\n") + "\n\n" + text + "\n"
+}
+
+// splitRuleHeader splits a rule body into its header lines and the lines
+// of text after the blank line ending them
+func splitRuleHeader(body string) (header, rest []string) {
+	lines := strings.Split(body, "\n")
+	end := len(lines)
+	for i, line := range lines {
+		// The header ends at the first blank line, as parseRule reads it
+		if line == "" && i > 0 {
+			end = i
+			break
+		}
+	}
+	header = append([]string(nil), lines[:end]...)
+	if end < len(lines) {
+		rest = lines[end+1:]
+	}
+	return header, rest
+}
+
+// setFrontmatterList sets a list in YAML frontmatter, keeping its other
+// keys and comments; an empty list removes the key
+func setFrontmatterList(front, key string, values []string) (string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(front), &doc); err != nil {
+		return "", err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	mapping := doc.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("frontmatter is not a mapping")
+	}
+
+	var list yaml.Node
+	if err := list.Encode(values); err != nil {
+		return "", err
+	}
+	found := false
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			found = true
+			if len(values) == 0 {
+				mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			} else {
+				mapping.Content[i+1] = &list
+			}
+			break
+		}
+	}
+	if !found && len(values) > 0 {
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &list)
+	}
+	if len(mapping.Content) == 0 {
+		return "", nil
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
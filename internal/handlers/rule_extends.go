@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/language"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+)
+
+// resolveRules merges every rule with an "Extends:" header into the rule
+// it names, then drops disabled rules. Disabled rules can still be
+// extended, so a base that only exists to be layered on needn't be listed
+// itself. A rule whose base is missing, or that extends itself through a
+// loop, is loaded without inheriting anything and reported by
+// ExtendsProblems, so one bad header doesn't take every rule down.
+func (rh *RulesHandler) resolveRules(rules []models.Rule) ([]models.Rule, error) {
+	loaded, problems := rh.resolveExtends(rules)
+	for _, problem := range problems {
+		slog.Warn("rule loaded without what it extends", "path", problem.Path, "error", problem.Err)
+	}
+	rh.extendsMu.Lock()
+	rh.extendsProblems = problems
+	rh.extendsMu.Unlock()
+	return loaded, nil
+}
+
+// ExtendsProblems returns the rules whose Extends couldn't be resolved at
+// the last load
+func (rh *RulesHandler) ExtendsProblems() []*FileError {
+	rh.extendsMu.Lock()
+	defer rh.extendsMu.Unlock()
+	return rh.extendsProblems
+}
+
+// resolveExtends is resolveRules without the bookkeeping: it returns the
+// enabled rules, merged with their bases, and a problem per rule whose
+// Extends is broken
+func (rh *RulesHandler) resolveExtends(rules []models.Rule) ([]models.Rule, []*FileError) {
+	resolved := make(map[string]models.Rule, len(rules)) // by ID
+	var problems []*FileError
+
+	var resolve func(rule models.Rule, chain []string) (models.Rule, error)
+	resolve = func(rule models.Rule, chain []string) (models.Rule, error) {
+		if done, ok := resolved[rule.ID]; ok {
+			return done, nil
+		}
+		if rule.Extends == "" {
+			resolved[rule.ID] = rule
+			return rule, nil
+		}
+
+		name := relativeTo(rh.path, rule.FilePath)
+		chain = append(chain, name)
+		base, err := rh.findRuleIn(rules, rule.Extends)
+		if err != nil {
+			problems = append(problems, &FileError{Op: "load", Path: rule.FilePath, Err: fmt.Errorf("invalid Extends: %w", err)})
+			resolved[rule.ID] = rule
+			return rule, nil
+		}
+		if slices.Contains(chain, relativeTo(rh.path, base.FilePath)) {
+			chain = append(chain, relativeTo(rh.path, base.FilePath))
+			return models.Rule{}, fmt.Errorf("invalid Extends: rules extend each other in a loop (%s)", strings.Join(chain, " → "))
+		}
+		if base, err = resolve(base, chain); err != nil {
+			return models.Rule{}, err
+		}
+		merged := mergeRule(base, rule)
+		resolved[rule.ID] = merged
+		return merged, nil
+	}
+
+	loaded := make([]models.Rule, 0, len(rules))
+	for _, rule := range rules {
+		merged, err := resolve(rule, nil)
+		if err != nil {
+			// A loop is cut at the first of its rules, which then stands
+			// on its own; the others extend it as written
+			problems = append(problems, &FileError{Op: "load", Path: rule.FilePath, Err: err})
+			merged = rule
+			resolved[rule.ID] = rule
+		}
+		if !merged.Disabled {
+			loaded = append(loaded, merged)
+		}
+	}
+	return loaded, problems
+}
+
+// mergeRule layers a rule over the base it extends. The base's text comes
+// first; the rule's own headers and frontmatter win, and those it leaves
+// out are inherited. Tags, glossary and naming entries and forbid patterns
+// are combined.
+func mergeRule(base, rule models.Rule) models.Rule {
+	merged := rule
+	if merged.Category == "" {
+		merged.Category = base.Category
+	}
+	if merged.Priority == "" {
+		merged.Priority = base.Priority
+	}
+	if len(merged.AppliesTo) == 0 {
+		merged.AppliesTo = base.AppliesTo
+	}
+	if merged.Tests == nil {
+		merged.Tests = base.Tests
+	}
+	merged.Glossary = mergeMaps(base.Glossary, rule.Glossary)
+	merged.Naming = mergeMaps(base.Naming, rule.Naming)
+
+	merged.Forbid = mergeLists(base.Forbid, rule.Forbid)
+	merged.Tags = mergeLists(base.Tags, rule.Tags)
+
+	own, inherited := strings.TrimSpace(rule.Description), strings.TrimSpace(base.Description)
+	switch {
+	case own == "":
+		merged.Description = base.Description
+	case inherited != "":
+		merged.Description = inherited + "\n\n" + own
+	}
+	merged.Language = language.Detect(merged.Title + "\n" + merged.Description)
+	return merged
+}
+
+// mergeLists returns base with the entries of over it lacks appended
+func mergeLists(base, over []string) []string {
+	merged := append([]string(nil), base...)
+	for _, value := range over {
+		if !slices.Contains(merged, value) {
+			merged = append(merged, value)
+		}
+	}
+	return merged
+}
+
+// mergeMaps returns base with over's entries added, replacing base's for
+// the same key, or nil when both are empty
+func mergeMaps[V any](base, over map[string]V) map[string]V {
+	if len(base) == 0 && len(over) == 0 {
+		return nil
+	}
+	merged := make(map[string]V, len(base)+len(over))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range over {
+		merged[key] = value
+	}
+	return merged
+}
+
+// checkExtends resolves the rules as they would be with rule written, or
+// removed when remove is set, so an edit can't leave a rule extending one
+// that no longer matches, or a loop. Rules already broken before the edit
+// don't hold it up.
+func (rh *RulesHandler) checkExtends(rule models.Rule, remove bool) error {
+	disabled, err := rh.DisabledRules()
+	if err != nil {
+		return err
+	}
+	var rules []models.Rule
+	if !remove {
+		rules = append(rules, rule)
+	}
+	for _, other := range append(rh.GetRules(), disabled...) {
+		if other.ID != rule.ID {
+			rules = append(rules, other)
+		}
+	}
+
+	broken := make(map[string]bool)
+	for _, problem := range rh.ExtendsProblems() {
+		broken[problem.Path] = true
+	}
+	_, problems := rh.resolveExtends(rules)
+	for _, problem := range problems {
+		if problem.Path == rule.FilePath || !broken[problem.Path] {
+			return fmt.Errorf("rules/%s: %w", relativeTo(rh.path, problem.Path), problem.Err)
+		}
+	}
+	return nil
+}
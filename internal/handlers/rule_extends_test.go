@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRules_BrokenExtendsLoadsRuleOnItsOwn(t *testing.T) {
+	bh, _ := newTestBuddyHandlers(t, map[string]string{
+		"rules/base.md":    "# Base\n\nCategory: style\nPriority: critical\n\nUse gofmt.\n",
+		"rules/child.md":   "# Child\n\nExtends: Base\n\nWrap errors.\n",
+		"rules/missing.md": "# Missing\n\nCategory: api\nExtends: Nowhere\n\nVersion the API.\n",
+		"rules/ping.md":    "# Ping\n\nExtends: Pong\n\nPing text.\n",
+		"rules/pong.md":    "# Pong\n\nCategory: net\nExtends: Ping\n\nPong text.\n",
+	})
+
+	rules := make(map[string]string) // title to category
+	for _, rule := range bh.rulesHandler.GetRules() {
+		rules[rule.Title] = rule.Category
+	}
+	assert.Equal(t, map[string]string{
+		"Base":    "style",
+		"Child":   "style", // inherited as usual
+		"Missing": "api",   // loaded without a base
+		"Ping":    "",      // the loop is cut here
+		"Pong":    "net",
+	}, rules)
+
+	problems := bh.rulesHandler.ExtendsProblems()
+	require.Len(t, problems, 2)
+	assert.Contains(t, problems[0].Err.Error(), `no rule matches "Nowhere"`)
+	assert.Contains(t, problems[1].Err.Error(), "loop (ping.md → pong.md → ping.md)")
+
+	var titles []string
+	for _, item := range bh.collectInbox(bh.Snapshot(), time.Now()) {
+		if item.Kind == "rule_extends" {
+			titles = append(titles, item.Title)
+		}
+	}
+	assert.Equal(t, []string{
+		"Rule rules/missing.md loads without the rule it extends",
+		"Rule rules/ping.md loads without the rule it extends",
+	}, titles)
+}
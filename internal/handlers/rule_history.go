@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxDiffLines caps the changed lines shown for one rule version
+const maxDiffLines = 40
+
+// RuleVersion is one version of a rule file as the timeline recorded it
+type RuleVersion struct {
+	Number   int
+	Since    time.Time // when the timeline first recorded this version
+	Document TimelineDocument
+	Removed  bool // the file was deleted at Since; Document is its last version
+}
+
+// RuleVersions returns the versions of the rule file at path, relative to
+// the buddy folder, oldest first. A new version starts with each record
+// whose content for the file differs from the one before.
+func (tl *Timeline) RuleVersions(path string) ([]RuleVersion, error) {
+	names, err := tl.recordNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []RuleVersion
+	present := false
+	for _, name := range names {
+		record, err := tl.read(name)
+		if err != nil {
+			return nil, err
+		}
+		doc, ok := findTimelineDocument(record.Rules, path)
+		switch {
+		case ok && (!present || versions[len(versions)-1].Document.Content != doc.Content):
+			versions = append(versions, RuleVersion{Number: len(versions) + 1, Since: record.Time, Document: doc})
+		case !ok && present:
+			versions = append(versions, RuleVersion{Number: len(versions) + 1, Since: record.Time, Document: versions[len(versions)-1].Document, Removed: true})
+		}
+		present = ok
+	}
+	return versions, nil
+}
+
+// findRulePath returns the recorded path of the newest rule whose file
+// name or title is ref, for rules that have since been deleted
+func (tl *Timeline) findRulePath(ref string) (string, bool) {
+	names, err := tl.recordNames()
+	if err != nil {
+		return "", false
+	}
+	name := "rules/" + strings.TrimPrefix(strings.TrimSpace(ref), "rules/")
+	for i := len(names) - 1; i >= 0; i-- {
+		record, err := tl.read(names[i])
+		if err != nil {
+			continue
+		}
+		for _, doc := range record.Rules {
+			if doc.FilePath == name || doc.FilePath == name+".md" || strings.EqualFold(doc.Title, strings.TrimSpace(ref)) {
+				return doc.FilePath, true
+			}
+		}
+	}
+	return "", false
+}
+
+// findTimelineDocument returns the recorded document at path
+func findTimelineDocument(docs []TimelineDocument, path string) (TimelineDocument, bool) {
+	for _, doc := range docs {
+		if doc.FilePath == path {
+			return doc, true
+		}
+	}
+	return TimelineDocument{}, false
+}
+
+// ruleHistory lists how a rule changed over time, from the timeline. The
+// rule may be named by ID, file name or title, and may have been deleted.
+func (bh *BuddyHandlers) ruleHistory(ctx context.Context, ref string) (*mcp.CallToolResult, error) {
+	rule, err := bh.rulesHandler.FindRule(ref)
+	var path string
+	switch {
+	case err == nil:
+		path = relativeTo(bh.buddyPath, rule.FilePath)
+	case strings.TrimSpace(ref) != "":
+		found, ok := bh.timeline.findRulePath(ref)
+		if !ok {
+			return nil, err
+		}
+		path = found
+	default:
+		return nil, err
+	}
+
+	versions, err := bh.timeline.RuleVersions(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the timeline: %w", err)
+	}
+	return mcp.NewToolResultText(bh.formatRuleHistory(path, versions)), nil
+}
+
+// formatRuleHistory describes each version of a rule: the first in full,
+// later ones by their header and line changes
+func (bh *BuddyHandlers) formatRuleHistory(path string, versions []RuleVersion) string {
+	if len(versions) == 0 {
+		return fmt.Sprintf("No recorded versions of %s yet; the timeline records rules each time they are reloaded\n", path)
+	}
+
+	latest := versions[len(versions)-1]
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📜 History of \"%s\" (%s): %d versions\n", latest.Document.Title, path, len(versions))
+
+	for i, version := range versions {
+		fmt.Fprintf(&sb, "\nv%d · %s", version.Number, bh.timeFormat.Format(version.Since))
+		var previous *RuleVersion
+		if i > 0 && !versions[i-1].Removed {
+			previous = &versions[i-1]
+		}
+		switch {
+		case version.Removed:
+			sb.WriteString(" — deleted\n")
+			continue
+		case previous == nil:
+			sb.WriteString(" — created\n")
+		case i == len(versions)-1:
+			sb.WriteString(" — current\n")
+		default:
+			sb.WriteString("\n")
+		}
+
+		doc := version.Document
+		content, err := bh.timeline.Content(doc.Content)
+		if err != nil {
+			sb.WriteString("   (content not recorded)\n")
+			continue
+		}
+		if previous == nil {
+			fmt.Fprintf(&sb, "   Priority: %s · Category: %s\n", doc.Priority, doc.Category)
+			for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+				fmt.Fprintf(&sb, "   %s\n", line)
+			}
+			continue
+		}
+
+		before := previous.Document
+		for _, change := range []struct{ name, was, now string }{
+			{"Title", before.Title, doc.Title},
+			{"Priority", before.Priority, doc.Priority},
+			{"Category", before.Category, doc.Category},
+		} {
+			if change.was != change.now {
+				fmt.Fprintf(&sb, "   %s: %s (was %s)\n", change.name, change.now, change.was)
+			}
+		}
+		if old, err := bh.timeline.Content(before.Content); err == nil {
+			changed := lineDiff(old, content)
+			if len(changed) > maxDiffLines {
+				changed = append(changed[:maxDiffLines], fmt.Sprintf("… %d more changed lines", len(changed)-maxDiffLines))
+			}
+			for _, line := range changed {
+				fmt.Fprintf(&sb, "   %s\n", line)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// lineDiff lists the lines removed from old ("- ") and added in new
+// ("+ "), in order, using their longest common subsequence. Rules are
+// short enough for the quadratic table.
+func lineDiff(old, new string) []string {
+	a := strings.Split(strings.TrimSpace(old), "\n")
+	b := strings.Split(strings.TrimSpace(new), "\n")
+
+	// common[i][j] is the LCS length of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j < len(b) && (i == len(a) || common[i][j+1] >= common[i+1][j]):
+			lines = append(lines, "+ "+b[j])
+			j++
+		default:
+			lines = append(lines, "- "+a[i])
+			i++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+)
+
+// defaultMaxRuleLength is how many characters a rule's text may have
+// before lint flags it; longer rules crowd out the context they're
+// injected into and are usually several rules in one
+const defaultMaxRuleLength = 4000
+
+// RuleLintIssue is one problem keeping a rule from being used reliably
+type RuleLintIssue struct {
+	Rule     string `json:"rule"`
+	FilePath string `json:"file_path"`
+	Severity string `json:"severity"` // error or warning
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+}
+
+// LintRules checks that every rule has a title, a category, a known
+// priority and a description no longer than maxLength characters, and
+// that no two rules share a title. Issues are sorted by file.
+func LintRules(rules []models.Rule, maxLength int) []RuleLintIssue {
+	if maxLength <= 0 {
+		maxLength = defaultMaxRuleLength
+	}
+	var issues []RuleLintIssue
+	add := func(rule models.Rule, severity, check, message string) {
+		issues = append(issues, RuleLintIssue{Rule: rule.Title, FilePath: rule.FilePath, Severity: severity, Check: check, Message: message})
+	}
+
+	byTitle := make(map[string][]models.Rule)
+	for _, rule := range rules {
+		title := strings.TrimSpace(rule.Title)
+		if title == "" {
+			add(rule, "error", "missing_title", "no '# Title' line; the rule can't be named in updates, deletes or the rule list")
+		} else {
+			byTitle[strings.ToLower(title)] = append(byTitle[strings.ToLower(title)], rule)
+		}
+
+		if strings.TrimSpace(rule.Category) == "" {
+			add(rule, "warning", "missing_category", "no 'Category:' line; category filters and grouping skip it")
+		}
+		switch rule.Priority {
+		case "critical", "recommended", "optional":
+		case "":
+			add(rule, "error", "missing_priority", "no 'Priority:' line; add critical, recommended or optional")
+		default:
+			add(rule, "error", "unknown_priority", fmt.Sprintf("priority %q is not critical, recommended or optional", rule.Priority))
+		}
+
+		description := strings.TrimSpace(rule.Description)
+		if description == "" {
+			add(rule, "error", "empty_description", "no text below the headers; add a blank line after them, then the rule itself")
+		} else if length := utf8.RuneCountInString(description); length > maxLength {
+			add(rule, "warning", "too_long", fmt.Sprintf("%d characters, over the %d limit; split it into focused rules or move background into knowledge", length, maxLength))
+		}
+	}
+
+	for _, group := range byTitle {
+		if len(group) < 2 {
+			continue
+		}
+		for _, rule := range group {
+			var others []string
+			for _, other := range group {
+				if other.FilePath != rule.FilePath {
+					others = append(others, other.FilePath)
+				}
+			}
+			add(rule, "error", "duplicate_title", fmt.Sprintf("same title as %s; references by title are ambiguous", strings.Join(others, ", ")))
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].FilePath < issues[j].FilePath
+	})
+	return issues
+}
+
+// FormatRuleLintResults renders lint issues grouped by rule file, after a
+// count of the rules checked
+func FormatRuleLintResults(issues []RuleLintIssue, checked int) string {
+	if len(issues) == 0 {
+		return fmt.Sprintf("✅ Rule lint: %d rules checked, no issues\n", checked)
+	}
+
+	errors := 0
+	for _, issue := range issues {
+		if issue.Severity == "error" {
+			errors++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Rule lint: %d rules checked, %d errors, %d warnings\n", checked, errors, len(issues)-errors)
+	file := ""
+	for _, issue := range issues {
+		if issue.FilePath != file {
+			file = issue.FilePath
+			title := issue.Rule
+			if title == "" {
+				title = "(untitled)"
+			}
+			fmt.Fprintf(&sb, "\n%s (%s)\n", title, file)
+		}
+		icon := "⚠️"
+		if issue.Severity == "error" {
+			icon = "❌"
+		}
+		fmt.Fprintf(&sb, "   %s %s: %s\n", icon, issue.Check, issue.Message)
+	}
+	return sb.String()
+}
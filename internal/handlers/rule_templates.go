@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+)
+
+// ruleTemplate is a built-in rule whose {placeholders} are filled in from
+// arguments when it is written to the rules folder
+type ruleTemplate struct {
+	Name         string
+	Description  string
+	Title        string
+	Category     string
+	Priority     string
+	Headers      []string // header lines below Priority, such as Naming:
+	Body         string
+	Placeholders []templatePlaceholder
+}
+
+// templatePlaceholder is one value a template asks for
+type templatePlaceholder struct {
+	Name        string
+	Description string
+	Default     string   // empty when the value is required
+	Choices     []string // the values allowed, when limited
+}
+
+// caseStyles are the naming styles buddy_check_names understands
+var caseStyles = []string{"camelCase", "PascalCase", "snake_case", "SCREAMING_SNAKE_CASE", "kebab-case"}
+
+// ruleTemplates are the built-in templates, in the order they are listed
+var ruleTemplates = []ruleTemplate{
+	{
+		Name:        "naming-conventions",
+		Description: "Case styles for types, functions, variables, constants, tables and files, checked by buddy_check_names",
+		Title:       "Naming Conventions",
+		Category:    "naming",
+		Priority:    "recommended",
+		Headers:     []string{"Naming: type={type_case}, function={function_case}, variable={variable_case}, constant={constant_case}, table={table_case}, file={file_case}"},
+		Body: "- Types are {type_case}, functions {function_case}, variables {variable_case} and constants {constant_case}.\n" +
+			"- Database tables and columns are {table_case}.\n" +
+			"- Source files are named in {file_case}.\n" +
+			"- Use the domain terms from the glossary rather than synonyms.\n" +
+			"- Check new identifiers with buddy_check_names before introducing them.",
+		Placeholders: []templatePlaceholder{
+			{Name: "type_case", Description: "Style of type and class names", Default: "PascalCase", Choices: caseStyles},
+			{Name: "function_case", Description: "Style of function and method names", Default: "camelCase", Choices: caseStyles},
+			{Name: "variable_case", Description: "Style of variable names", Default: "camelCase", Choices: caseStyles},
+			{Name: "constant_case", Description: "Style of constant names", Default: "SCREAMING_SNAKE_CASE", Choices: caseStyles},
+			{Name: "table_case", Description: "Style of database table and column names", Default: "snake_case", Choices: caseStyles},
+			{Name: "file_case", Description: "Style of source file names", Default: "snake_case", Choices: caseStyles},
+		},
+	},
+	{
+		Name:        "error-handling",
+		Description: "Checking, wrapping, logging and exposing errors",
+		Title:       "Error Handling",
+		Category:    "errors",
+		Priority:    "critical",
+		Body: "- Check every returned error; never discard one silently.\n" +
+			"- Add context when passing an error up: {wrap_with}.\n" +
+			"- Log an error once, where it is handled, with {logger}; don't log it and return it too.\n" +
+			"- Return errors to the caller instead of exiting or panicking in library code.\n" +
+			"- Errors shown to users must not expose {sensitive}.",
+		Placeholders: []templatePlaceholder{
+			{Name: "wrap_with", Description: "How errors get context", Default: "wrap them with a message saying what failed"},
+			{Name: "logger", Description: "The logger errors are reported with", Default: "the project's logger"},
+			{Name: "sensitive", Description: "What error messages must not reveal", Default: "stack traces, queries or credentials"},
+		},
+	},
+	{
+		Name:        "testing-policy",
+		Description: "When tests are written, where they live, how they run and the coverage expected",
+		Title:       "Testing Policy",
+		Category:    "testing",
+		Priority:    "critical",
+		Body: "- Every change ships with tests; a bug fix starts with a test reproducing the bug.\n" +
+			"- Tests live {test_location}.\n" +
+			"- Run `{test_command}` before committing; it must pass.\n" +
+			"- Keep the coverage of changed code at {coverage}% or more.\n" +
+			"- Tests don't reach the network or shared services; fake them at the boundary.",
+		Placeholders: []templatePlaceholder{
+			{Name: "test_command", Description: "Command running the test suite, e.g. go test ./..."},
+			{Name: "test_location", Description: "Where test files go", Default: "next to the code they test"},
+			{Name: "coverage", Description: "Minimum coverage percentage of changed code", Default: "80"},
+		},
+	},
+}
+
+// findRuleTemplate returns the built-in template with the given name
+func findRuleTemplate(name string) (ruleTemplate, error) {
+	var names []string
+	for _, tmpl := range ruleTemplates {
+		if strings.EqualFold(tmpl.Name, strings.TrimSpace(name)) {
+			return tmpl, nil
+		}
+		names = append(names, tmpl.Name)
+	}
+	if strings.TrimSpace(name) == "" {
+		return ruleTemplate{}, fmt.Errorf("template is required (one of %s)", strings.Join(names, ", "))
+	}
+	return ruleTemplate{}, fmt.Errorf("unknown template %q (expected one of %s)", name, strings.Join(names, ", "))
+}
+
+// fill replaces the template's placeholders with values, falling back to
+// their defaults. Values for placeholders the template lacks, missing
+// required values and values outside a placeholder's choices are errors.
+func (tmpl ruleTemplate) fill(values map[string]string) (title string, headers []string, body string, err error) {
+	known := make(map[string]templatePlaceholder, len(tmpl.Placeholders))
+	for _, placeholder := range tmpl.Placeholders {
+		known[placeholder.Name] = placeholder
+	}
+	var unknown []string
+	for name := range values {
+		if _, ok := known[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", nil, "", fmt.Errorf("template %s has no placeholder %s", tmpl.Name, strings.Join(unknown, ", "))
+	}
+
+	var pairs, missing []string
+	for _, placeholder := range tmpl.Placeholders {
+		value := strings.TrimSpace(values[placeholder.Name])
+		if value == "" {
+			value = placeholder.Default
+		}
+		switch {
+		case value == "":
+			missing = append(missing, placeholder.Name)
+			continue
+		case strings.ContainsAny(value, "\r\n"):
+			return "", nil, "", fmt.Errorf("%s must be a single line", placeholder.Name)
+		case len(placeholder.Choices) > 0 && !slices.Contains(placeholder.Choices, value):
+			return "", nil, "", fmt.Errorf("invalid %s: %s (expected one of %s)", placeholder.Name, value, strings.Join(placeholder.Choices, ", "))
+		}
+		pairs = append(pairs, "{"+placeholder.Name+"}", value)
+	}
+	if len(missing) > 0 {
+		return "", nil, "", fmt.Errorf("template %s needs a value for %s", tmpl.Name, strings.Join(missing, ", "))
+	}
+
+	replacer := strings.NewReplacer(pairs...)
+	for _, header := range tmpl.Headers {
+		headers = append(headers, replacer.Replace(header))
+	}
+	return replacer.Replace(tmpl.Title), headers, replacer.Replace(tmpl.Body), nil
+}
+
+// CreateRuleFromTemplate writes a new rule from a built-in template, its
+// placeholders filled from values. The edit's title, category and priority
+// override the template's; its applies_to globs and file name are used as
+// by CreateRule.
+func (rh *RulesHandler) CreateRuleFromTemplate(ctx context.Context, name string, values map[string]string, edit RuleEdit) (models.Rule, error) {
+	tmpl, err := findRuleTemplate(name)
+	if err != nil {
+		return models.Rule{}, err
+	}
+	title, headers, body, err := tmpl.fill(values)
+	if err != nil {
+		return models.Rule{}, err
+	}
+	if edit.Title == "" {
+		edit.Title = title
+	}
+	if edit.Category == "" {
+		edit.Category = tmpl.Category
+	}
+	if edit.Priority == "" {
+		edit.Priority = tmpl.Priority
+	}
+	edit.Content = body
+	edit.headers = headers
+	return rh.CreateRule(ctx, edit)
+}
+
+// formatRuleTemplates lists the built-in templates and their placeholders
+func formatRuleTemplates() string {
+	var sb strings.Builder
+	sb.WriteString("📐 Rule templates\n")
+	for _, tmpl := range ruleTemplates {
+		fmt.Fprintf(&sb, "\n%s: %s\n", tmpl.Name, tmpl.Description)
+		fmt.Fprintf(&sb, "   Creates \"%s\" (%s, %s)\n", tmpl.Title, tmpl.Category, tmpl.Priority)
+		for _, placeholder := range tmpl.Placeholders {
+			detail := "required"
+			if placeholder.Default != "" {
+				detail = "default: " + placeholder.Default
+			}
+			if len(placeholder.Choices) > 0 {
+				detail += "; one of " + strings.Join(placeholder.Choices, ", ")
+			}
+			fmt.Fprintf(&sb, "   - %s: %s (%s)\n", placeholder.Name, placeholder.Description, detail)
+		}
+	}
+	sb.WriteString("\n💡 Create one with action create_from_template, the template name and its placeholder values")
+	return sb.String()
+}
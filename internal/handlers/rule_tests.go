@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+)
+
+// identifierPattern finds identifiers in a code snippet
+var identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// RuleTestResult is the outcome of running one of a rule's test snippets
+// through its checks
+type RuleTestResult struct {
+	Rule       string   `json:"rule"`
+	FilePath   string   `json:"file_path"`
+	Expect     string   `json:"expect"` // good or bad
+	Snippet    string   `json:"snippet"`
+	Violations []string `json:"violations,omitempty"`
+	Passed     bool     `json:"passed"`
+}
+
+// CheckCode runs a rule's machine-checkable parts against code: each
+// forbid pattern that matches, and each glossary alias used as a word of
+// an identifier, is a violation
+func CheckCode(rule models.Rule, code string) []string {
+	var violations []string
+
+	for _, pattern := range rule.Forbid {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("invalid forbid pattern %q: %v", pattern, err))
+			continue
+		}
+		for _, loc := range re.FindAllStringIndex(code, -1) {
+			line := strings.Count(code[:loc[0]], "\n") + 1
+			violations = append(violations, fmt.Sprintf("line %d matches forbidden pattern %q", line, pattern))
+		}
+	}
+
+	if len(rule.Glossary) > 0 {
+		aliases := make(map[string]string)
+		for canonical, list := range rule.Glossary {
+			for _, alias := range list {
+				aliases[alias] = canonical
+			}
+		}
+		seen := make(map[string]bool)
+		for _, identifier := range identifierPattern.FindAllString(code, -1) {
+			for _, word := range splitIdentifier(identifier) {
+				canonical, ok := aliases[word]
+				if !ok || seen[identifier+"/"+word] {
+					continue
+				}
+				seen[identifier+"/"+word] = true
+				violations = append(violations, fmt.Sprintf("'%s' uses '%s', which is not the domain term; use '%s'", identifier, word, canonical))
+			}
+		}
+	}
+
+	return violations
+}
+
+// RunRuleTests checks every test snippet of the given rules: good snippets
+// pass when no check flags them, bad ones when at least one does. Rules
+// without tests are skipped.
+func RunRuleTests(rules []models.Rule) []RuleTestResult {
+	var results []RuleTestResult
+	for _, rule := range rules {
+		if rule.Tests == nil {
+			continue
+		}
+		for _, snippet := range rule.Tests.Good {
+			violations := CheckCode(rule, snippet)
+			results = append(results, RuleTestResult{
+				Rule: rule.Title, FilePath: rule.FilePath, Expect: "good", Snippet: snippet,
+				Violations: violations, Passed: len(violations) == 0,
+			})
+		}
+		for _, snippet := range rule.Tests.Bad {
+			violations := CheckCode(rule, snippet)
+			results = append(results, RuleTestResult{
+				Rule: rule.Title, FilePath: rule.FilePath, Expect: "bad", Snippet: snippet,
+				Violations: violations, Passed: len(violations) > 0,
+			})
+		}
+	}
+	return results
+}
+
+// FormatRuleTestResults renders rule test results with failures explained
+func FormatRuleTestResults(results []RuleTestResult) string {
+	if len(results) == 0 {
+		return "No rule has test cases. Add good and bad snippets under 'tests:' in a rule's frontmatter.\n"
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Passed {
+			failed++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Rule tests: %d passed, %d failed\n", len(results)-failed, failed)
+	for _, result := range results {
+		if result.Passed {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n❌ %s (%s): %s snippet\n", result.Rule, result.FilePath, result.Expect)
+		fmt.Fprintf(&sb, "   %s\n", strings.ReplaceAll(strings.TrimRight(result.Snippet, "\n"), "\n", "\n   "))
+		if result.Expect == "good" {
+			for _, violation := range result.Violations {
+				fmt.Fprintf(&sb, "   - flagged: %s\n", violation)
+			}
+		} else {
+			sb.WriteString("   - not flagged by any forbid pattern or glossary term\n")
+		}
+	}
+	return sb.String()
+}
@@ -2,86 +2,118 @@ package handlers
 
 import (
 	"context"
-	"crypto/md5"
 	"fmt"
-	"io/ioutil"
-	"os"
+	"log/slog"
+	"maps"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/language"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/schema"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/workspace"
+	"gopkg.in/yaml.v3"
 )
 
+// ruleFrontmatter is the optional YAML header of a rule file
+type ruleFrontmatter struct {
+	Forbid    []string          `yaml:"forbid"` // patterns code must not match
+	Tests     *models.RuleTests `yaml:"tests"`
+	AppliesTo []string          `yaml:"applies_to"` // globs of the project files the rule covers
+}
+
 // RulesHandler manages coding rules and guidelines
 type RulesHandler struct {
-	path          string
-	rules         []models.Rule
-	searchManager *search.SearchManager
-	mu            sync.RWMutex
+	*DocumentHandler[models.Rule]
+	churn   *ChurnTracker
+	safety  *SafetyStore                // snapshots rules before updates and deletes
+	files   setting[config.Files]       // how new rule files are named
+	sandbox setting[*workspace.Sandbox] // confines the Cursor rules export
+	root    workspace.Root              // applies_to globs are relative to it
+
+	extendsMu       sync.Mutex
+	extendsProblems []*FileError // rules whose Extends broke at the last load
 }
 
 // NewRulesHandler creates a new rules handler
 func NewRulesHandler(path string, searchManager *search.SearchManager) *RulesHandler {
-	return &RulesHandler{
-		path:          path,
-		rules:         []models.Rule{},
-		searchManager: searchManager,
+	rh := &RulesHandler{
+		churn: NewChurnTracker(defaultChurnWindow, defaultChurnThreshold),
 	}
+	rh.DocumentHandler = NewDocumentHandler(path, searchManager, DocumentSpec[models.Rule]{
+		IndexType:  search.IndexTypeRules,
+		Extensions: []string{".md"},
+		Truncate:   truncateText,
+		Parse:      rh.parseRuleFile,
+		ID:         func(rule models.Rule) string { return rule.ID },
+		Index:      func(rule models.Rule) interface{} { return search.FromRule(rule) },
+		Resolve:    rh.resolveRules,
+		Loaded:     rh.observeChurn,
+	})
+	return rh
 }
 
-// Load loads all rules from the rules directory
-func (rh *RulesHandler) Load() error {
-	rh.mu.Lock()
-	defer rh.mu.Unlock()
-
-	rh.rules = []models.Rule{}
-
-	// First, reindex all rules
-	if err := rh.searchManager.ReindexAll(search.IndexTypeRules); err != nil {
-		return fmt.Errorf("failed to reindex rules: %w", err)
+// observeChurn warns when a rule file is being rewritten unusually often
+func (rh *RulesHandler) observeChurn(rule models.Rule) {
+	if rh.churn.Observe(rule.FilePath, rule.UpdatedAt) {
+		window, threshold := rh.churn.Limits()
+		slog.Warn("rule changed unusually often; check for conflicting edits",
+			"path", rule.FilePath, "changes", threshold, "window", window)
 	}
+}
 
-	files, err := ioutil.ReadDir(rh.path)
+// parseRuleFile parses a single rule file. Disabled rules are kept until
+// resolveRules has merged the rules extending them, then dropped.
+func (rh *RulesHandler) parseRuleFile(file storage.FileInfo, content []byte) ([]models.Rule, error) {
+	rule, err := parseRule(rh.path, file, content)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
+		return nil, err
 	}
+	return []models.Rule{rule}, nil
+}
 
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".md") {
-			rule, err := rh.loadRuleFile(filepath.Join(rh.path, file.Name()))
-			if err != nil {
-				return fmt.Errorf("failed to load rule %s: %w", file.Name(), err)
-			}
-			rh.rules = append(rh.rules, rule)
+// parseRule parses a rule file in the rules folder dir, disabled or not
+func parseRule(dir string, file storage.FileInfo, content []byte) (models.Rule, error) {
+	filePath := file.Path
 
-			// Index the rule in Bleve
-			doc := search.FromRule(rule)
-			if err := rh.searchManager.IndexDocument(search.IndexTypeRules, rule.ID, doc); err != nil {
-				return fmt.Errorf("failed to index rule %s: %w", rule.ID, err)
+	front, body := splitFrontmatter(string(content))
+	var meta ruleFrontmatter
+	if front != "" {
+		var raw map[string]interface{}
+		if err := yaml.Unmarshal([]byte(front), &raw); err != nil {
+			return models.Rule{}, fmt.Errorf("invalid frontmatter: %w", err)
+		}
+		if err := schema.ValidateValue(schema.RuleFrontmatter, raw); err != nil {
+			return models.Rule{}, fmt.Errorf("invalid frontmatter: %w", err)
+		}
+		if err := yaml.Unmarshal([]byte(front), &meta); err != nil {
+			return models.Rule{}, fmt.Errorf("invalid frontmatter: %w", err)
+		}
+		for _, pattern := range meta.Forbid {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return models.Rule{}, fmt.Errorf("invalid forbid pattern %q: %w", pattern, err)
 			}
 		}
 	}
 
-	return nil
-}
-
-// loadRuleFile loads a single rule file
-func (rh *RulesHandler) loadRuleFile(filePath string) (models.Rule, error) {
-	content, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return models.Rule{}, err
-	}
-
 	// Parse the rule file
-	lines := strings.Split(string(content), "\n")
-	var title, category, priority string
+	lines := strings.Split(body, "\n")
+	var title, category, priority, extends string
+	var disabled bool
+	var tags []string
+	var glossary map[string][]string
+	var naming map[string]string
 	var descriptionStart int
 
 	// Extract metadata from the first few lines
@@ -92,6 +124,20 @@ func (rh *RulesHandler) loadRuleFile(filePath string) (models.Rule, error) {
 			category = strings.TrimPrefix(line, "Category: ")
 		} else if strings.HasPrefix(line, "Priority: ") {
 			priority = strings.TrimPrefix(line, "Priority: ")
+		} else if strings.HasPrefix(line, "Tags: ") {
+			tags = splitTags(strings.TrimPrefix(line, "Tags: "))
+		} else if strings.HasPrefix(line, "Glossary: ") {
+			glossary = parseGlossary(strings.TrimPrefix(line, "Glossary: "))
+		} else if strings.HasPrefix(line, "Naming: ") {
+			naming = parseNaming(strings.TrimPrefix(line, "Naming: "))
+		} else if strings.HasPrefix(line, "Extends: ") {
+			extends = strings.TrimSpace(strings.TrimPrefix(line, "Extends: "))
+		} else if strings.HasPrefix(line, "Disabled: ") {
+			value := strings.TrimSpace(strings.TrimPrefix(line, "Disabled: "))
+			var err error
+			if disabled, err = strconv.ParseBool(value); err != nil {
+				return models.Rule{}, fmt.Errorf("invalid Disabled value %q (expected true or false)", value)
+			}
 		} else if line == "" && i > 0 {
 			descriptionStart = i + 1
 			break
@@ -104,10 +150,7 @@ func (rh *RulesHandler) loadRuleFile(filePath string) (models.Rule, error) {
 		description = strings.Join(lines[descriptionStart:], "\n")
 	}
 
-	// Generate ID from file path
-	id := fmt.Sprintf("%x", md5.Sum([]byte(filePath)))
-
-	fileInfo, _ := os.Stat(filePath)
+	id := documentID(dir, filePath)
 
 	return models.Rule{
 		ID:          id,
@@ -115,116 +158,325 @@ func (rh *RulesHandler) loadRuleFile(filePath string) (models.Rule, error) {
 		Title:       title,
 		Description: description,
 		Priority:    priority,
+		Tags:        tags,
 		Content:     string(content),
 		FilePath:    filePath,
-		UpdatedAt:   fileInfo.ModTime(),
+		Glossary:    glossary,
+		Naming:      naming,
+		Forbid:      meta.Forbid,
+		Tests:       meta.Tests,
+		AppliesTo:   meta.AppliesTo,
+		Language:    language.Detect(title + "\n" + description),
+		Extends:     extends,
+		Disabled:    disabled,
+		UpdatedAt:   file.ModTime,
 	}, nil
 }
 
 // GetRules returns all loaded rules
 func (rh *RulesHandler) GetRules() []models.Rule {
-	rh.mu.RLock()
-	defer rh.mu.RUnlock()
-	return rh.rules
+	return rh.Documents()
+}
+
+// GetChurnWarnings returns rule files that are changing unusually often
+func (rh *RulesHandler) GetChurnWarnings() []ChurnWarning {
+	return rh.churn.Warnings(time.Now())
 }
 
 // GetRulesByCategory returns rules filtered by category
 func (rh *RulesHandler) GetRulesByCategory(category string) []models.Rule {
-	rh.mu.RLock()
-	defer rh.mu.RUnlock()
-
-	var filtered []models.Rule
-	for _, rule := range rh.rules {
-		if rule.Category == category {
-			filtered = append(filtered, rule)
-		}
-	}
-	return filtered
+	return rh.Filter(func(rule models.Rule) bool {
+		return rule.Category == category
+	})
 }
 
 // GetRulesByPriority returns rules filtered by priority
 func (rh *RulesHandler) GetRulesByPriority(priority string) []models.Rule {
-	rh.mu.RLock()
-	defer rh.mu.RUnlock()
+	return rh.Filter(func(rule models.Rule) bool {
+		return rule.Priority == priority
+	})
+}
 
-	var filtered []models.Rule
-	for _, rule := range rh.rules {
-		if rule.Priority == priority {
-			filtered = append(filtered, rule)
-		}
-	}
-	return filtered
+// RulesArgs are the arguments of buddy_get_rules
+type RulesArgs struct {
+	Action    string            `arg:"action" enum:"list,test,lint,create,update,delete,disable,enable,history,templates,create_from_template,export_cursor" desc:"list (default); test to check each rule's good and bad test snippets against its forbid patterns and glossary; lint to report rules missing a title, category, priority or description, sharing a title or too long; create, update or delete to change rule files; disable to keep a rule on disk but out of listings, checks and search, and enable to restore it; history to show how one rule changed over time, including deleted rules; templates to list the built-in rule templates and create_from_template to write one; export_cursor to write the rules as Cursor's native rules files in the project"`
+	Category  string            `arg:"category" desc:"Filter rules by category; for create and update, the rule's category (optional)"`
+	Priority  string            `arg:"priority" enum:"critical,recommended,optional" desc:"Filter rules by priority; for create and update, the rule's priority (optional)"`
+	Tags      []string          `arg:"tags" desc:"List only rules tagged with every one of these, such as security or performance, across categories; for create and update, the rule's tags, where an empty list removes them (optional)"`
+	Search    string            `arg:"search" desc:"Search rules by title, content, description or tags (optional for list)"`
+	Rule      string            `arg:"rule" desc:"Rule to update, delete, disable, enable or show the history of: its ID, file name or title"`
+	Title     string            `arg:"title" desc:"Rule title for create and update"`
+	Content   string            `arg:"content" desc:"Rule text below its headers, in markdown, for create and update"`
+	AppliesTo []string          `arg:"applies_to" desc:"Globs of the project files the rule covers, for create and update; an empty list removes them"`
+	File      string            `arg:"file" desc:"File name for a new rule in the rules folder (default: derived from the title)"`
+	Extends   string            `arg:"extends" desc:"For create and update: the ID, file name or title of a rule this one builds on. Its text comes first, and the category, priority, applies_to and tests this rule leaves out are inherited"`
+	Template  string            `arg:"template" desc:"Built-in template for create_from_template, e.g. naming-conventions, error-handling or testing-policy"`
+	Values    map[string]string `arg:"values" desc:"Placeholder values for create_from_template, e.g. {\"test_command\": \"go test ./...\"}; placeholders left out take their defaults"`
+	MaxLength int               `arg:"max_length" desc:"For lint: the most characters a rule's text may have (default: 4000)"`
+	Format    string            `arg:"format" enum:"mdc,cursorrules" desc:"For export_cursor: mdc for one .cursor/rules/*.mdc file per rule (default) or cursorrules for a single .cursorrules file"`
+	Force     bool              `arg:"force" desc:"For export_cursor: overwrite Cursor rules files buddy didn't generate"`
+	DryRun    bool              `arg:"dry_run" desc:"For export_cursor: list the files that would be written and removed without changing anything"`
+	FilePath  string            `arg:"file_path" desc:"List only the rules covering this project file: those whose applies_to globs match it, and those without applies_to (optional)"`
+	ListArgs
+	TokenBudgetArgs
 }
 
 // GetToolHandler returns the tool handler function for rules
 func (rh *RulesHandler) GetToolHandler() server.ToolHandlerFunc {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		// Use GetArguments() method to access arguments
-		args := request.GetArguments()
-		category, _ := args["category"].(string)
-		priority, _ := args["priority"].(string)
-		searchQuery, _ := args["search"].(string)
+	return toolargs.Handler(rh.handle)
+}
 
-		var rules []models.Rule
+// handle runs a buddy_get_rules call
+func (rh *RulesHandler) handle(ctx context.Context, args RulesArgs) (*mcp.CallToolResult, error) {
+	category, priority, searchQuery, filePath := args.Category, args.Priority, args.Search, args.FilePath
+	var tags []string
+	for _, tag := range args.Tags {
+		if text := strings.TrimSpace(tag); text != "" {
+			tags = append(tags, text)
+		}
+	}
 
-		// If search query is provided, use Bleve search
-		if searchQuery != "" {
-			filters := make(map[string]interface{})
-			if category != "" {
-				filters["category"] = category
-			}
-			if priority != "" {
-				filters["priority"] = priority
-			}
+	switch action := args.Action; action {
+	case "", "list":
+	case "test":
+		rules := rh.GetRules()
+		if category != "" {
+			rules = rh.GetRulesByCategory(category)
+		}
+		if len(tags) > 0 {
+			rules = rulesWithTags(rules, tags)
+		}
+		return mcp.NewToolResultText(FormatRuleTestResults(RunRuleTests(rules))), nil
+	case "lint":
+		rules := rh.GetRules()
+		if category != "" {
+			rules = rh.GetRulesByCategory(category)
+		}
+		if len(tags) > 0 {
+			rules = rulesWithTags(rules, tags)
+		}
+		// Report files as they appear in the rules folder
+		named := make([]models.Rule, len(rules))
+		for i, rule := range rules {
+			rule.FilePath = "rules/" + relativeTo(rh.path, rule.FilePath)
+			named[i] = rule
+		}
+		return mcp.NewToolResultText(FormatRuleLintResults(LintRules(named, args.MaxLength), len(rules))), nil
+	case "templates":
+		return mcp.NewToolResultText(formatRuleTemplates()), nil
+	case "create", "update", "delete", "create_from_template", "disable", "enable":
+		return rh.editRule(ctx, action, args)
+	case "export_cursor":
+		export, err := rh.ExportCursorRules(ctx, args.Format, args.Force, args.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(FormatCursorExport(export, args.DryRun)), nil
+	default:
+		return nil, fmt.Errorf("invalid action: %s", action)
+	}
 
-			searchResults, err := rh.searchManager.SearchWithFilters(
-				search.IndexTypeRules,
-				searchQuery,
-				filters,
-				50, // Limit to 50 results
-			)
-			if err != nil {
-				return nil, fmt.Errorf("search failed: %w", err)
-			}
+	var rules []models.Rule
 
-			// Convert search results to rules
-			for _, hit := range searchResults.Hits {
-				// Find the rule by ID
-				for _, rule := range rh.rules {
-					if rule.ID == hit.ID {
-						rules = append(rules, rule)
-						break
-					}
+	// If search query is provided, use Bleve search
+	if searchQuery != "" {
+		filters := make(map[string]interface{})
+		if category != "" {
+			filters["category"] = category
+		}
+		if priority != "" {
+			filters["priority"] = priority
+		}
+
+		var err error
+		rules, err = rh.SearchDocuments(ctx, searchQuery, filters, 50) // Limit to 50 results
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Use traditional filtering
+		rules = rh.GetRules()
+
+		// Apply filters
+		if category != "" {
+			rules = rh.GetRulesByCategory(category)
+		}
+		if priority != "" {
+			var filtered []models.Rule
+			for _, rule := range rules {
+				if rule.Priority == priority {
+					filtered = append(filtered, rule)
 				}
 			}
-		} else {
-			// Use traditional filtering
-			rules = rh.GetRules()
+			rules = filtered
+		}
+	}
+	if len(tags) > 0 {
+		rules = rulesWithTags(rules, tags)
+	}
+	if filePath != "" {
+		filePath = rh.root.Rel(filePath)
+		rules = rulesForFile(rules, filePath)
+	}
+
+	// Enhanced result formatting
+	result, err := rh.RenderList(rules, args.ListArgs, func(page []models.Rule) string {
+		return rh.formatRulesResults(category, priority, filePath, tags, page, searchQuery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if args.Output != "json" {
+		if disabled, err := rh.DisabledRules(); err == nil && len(disabled) > 0 {
+			titles := make([]string, len(disabled))
+			for i, rule := range disabled {
+				titles[i] = rule.Title
+			}
+			result += fmt.Sprintf("\n\n⏸️ %d disabled rules not shown: %s (use action enable to restore one)", len(disabled), strings.Join(titles, ", "))
+		}
+	}
+
+	return mcp.NewToolResultText(result), nil
+}
 
-			// Apply filters
-			if category != "" {
-				rules = rh.GetRulesByCategory(category)
+// editRule runs the create, create_from_template, update, delete, disable
+// and enable actions
+func (rh *RulesHandler) editRule(ctx context.Context, action string, args RulesArgs) (*mcp.CallToolResult, error) {
+	ref := args.Rule
+	edit := RuleEdit{
+		Title:    args.Title,
+		Category: args.Category,
+		Priority: args.Priority,
+		Content:  args.Content,
+		File:     args.File,
+		Extends:  args.Extends,
+	}
+	// A given list, even an empty one, replaces the rule's; nil keeps it
+	if args.Tags != nil {
+		edit.Tags = []string{}
+		for _, tag := range args.Tags {
+			if text := strings.TrimSpace(tag); text != "" {
+				edit.Tags = append(edit.Tags, text)
 			}
-			if priority != "" {
-				var filtered []models.Rule
-				for _, rule := range rules {
-					if rule.Priority == priority {
-						filtered = append(filtered, rule)
-					}
-				}
-				rules = filtered
+		}
+	}
+	if args.AppliesTo != nil {
+		edit.AppliesTo = []string{}
+		for _, glob := range args.AppliesTo {
+			if text := strings.TrimSpace(glob); text != "" {
+				edit.AppliesTo = append(edit.AppliesTo, text)
 			}
 		}
+	}
+
+	var (
+		rule models.Rule
+		err  error
+		verb string
+	)
+	switch action {
+	case "create":
+		rule, err = rh.CreateRule(ctx, edit)
+		verb = "Created"
+	case "create_from_template":
+		values := args.Values
+		if values == nil {
+			values = make(map[string]string)
+		}
+		rule, err = rh.CreateRuleFromTemplate(ctx, args.Template, values, edit)
+		verb = "Created"
+	case "update":
+		rule, err = rh.UpdateRule(ctx, ref, edit)
+		verb = "Updated"
+	case "delete":
+		rule, err = rh.DeleteRule(ctx, ref)
+		verb = "Deleted"
+	case "disable":
+		rule, err = rh.SetRuleDisabled(ctx, ref, true)
+		verb = "Disabled"
+	case "enable":
+		rule, err = rh.SetRuleDisabled(ctx, ref, false)
+		verb = "Enabled"
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		// Enhanced result formatting
-		result := rh.formatRulesResults(category, priority, rules, searchQuery)
+	result := fmt.Sprintf("✅ %s rule \"%s\"\n\n", verb, rule.Title)
+	result += fmt.Sprintf("File: rules/%s\n", relativeTo(rh.path, rule.FilePath))
+	if action != "delete" {
+		result += fmt.Sprintf("Category: %s\nPriority: %s\n", rule.Category, rule.Priority)
+		if len(rule.Tags) > 0 {
+			result += fmt.Sprintf("Tags: %s\n", strings.Join(rule.Tags, ", "))
+		}
+		if rule.Extends != "" {
+			result += fmt.Sprintf("Extends: %s\n", rule.Extends)
+		}
+		if len(rule.AppliesTo) > 0 {
+			result += fmt.Sprintf("Applies to: %s\n", strings.Join(rule.AppliesTo, ", "))
+		}
+	}
+	if action == "disable" {
+		result += "\n💡 The file stays in the rules folder, but the rule is left out of listings, checks, exports and search until you enable it again"
+	}
+	if action == "update" || action == "delete" {
+		result += "\n💡 The previous version is in a safety snapshot; restore it with buddy_backup action restore_safety, or buddy_undo in this session"
+	}
+	return mcp.NewToolResultText(result), nil
+}
 
-		return mcp.NewToolResultText(result), nil
+// rulesWithTags keeps the rules tagged with every one of tags, ignoring
+// case
+func rulesWithTags(rules []models.Rule, tags []string) []models.Rule {
+	var matching []models.Rule
+	for _, rule := range rules {
+		if hasTags(rule.Tags, tags) {
+			matching = append(matching, rule)
+		}
 	}
+	return matching
+}
+
+// hasTags reports whether have includes every one of want, ignoring case
+func hasTags(have, want []string) bool {
+	for _, tag := range want {
+		found := false
+		for _, own := range have {
+			if strings.EqualFold(own, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// rulesForFile keeps the rules that cover a file: those with an applies_to
+// glob matching it, relative to the workspace root, and those without
+// applies_to, which cover every file
+func rulesForFile(rules []models.Rule, path string) []models.Rule {
+	var matching []models.Rule
+	for _, rule := range rules {
+		if len(rule.AppliesTo) == 0 {
+			matching = append(matching, rule)
+			continue
+		}
+		for _, pattern := range rule.AppliesTo {
+			glob, err := config.CompileGlob(strings.TrimPrefix(filepath.ToSlash(pattern), "./"))
+			if err == nil && glob.Match(path) {
+				matching = append(matching, rule)
+				break
+			}
+		}
+	}
+	return matching
 }
 
 // formatRulesResults formats rules results with enhanced context
-func (rh *RulesHandler) formatRulesResults(category, priority string, rules []models.Rule, searchQuery string) string {
+func (rh *RulesHandler) formatRulesResults(category, priority, filePath string, tags []string, rules []models.Rule, searchQuery string) string {
 	if len(rules) == 0 {
 		result := "No rules found"
 		if searchQuery != "" {
@@ -236,6 +488,12 @@ func (rh *RulesHandler) formatRulesResults(category, priority string, rules []mo
 		if priority != "" {
 			result += fmt.Sprintf(" with priority: %s", priority)
 		}
+		if len(tags) > 0 {
+			result += fmt.Sprintf(" tagged: %s", strings.Join(tags, ", "))
+		}
+		if filePath != "" {
+			result += fmt.Sprintf(" for file: %s", filePath)
+		}
 		result += "\n\nAvailable categories:"
 
 		// Show available categories
@@ -244,9 +502,20 @@ func (rh *RulesHandler) formatRulesResults(category, priority string, rules []mo
 		for _, rule := range allRules {
 			categories[rule.Category] = true
 		}
-		for cat := range categories {
+		for _, cat := range slices.Sorted(maps.Keys(categories)) {
 			result += fmt.Sprintf("\n- %s", cat)
 		}
+		if len(tags) > 0 {
+			available := make(map[string]bool)
+			for _, rule := range allRules {
+				for _, tag := range rule.Tags {
+					available[strings.ToLower(tag)] = true
+				}
+			}
+			if len(available) > 0 {
+				result += fmt.Sprintf("\n\nAvailable tags: %s", strings.Join(slices.Sorted(maps.Keys(available)), ", "))
+			}
+		}
 
 		result += "\n\nAvailable priorities: critical, recommended, optional"
 		return result
@@ -262,6 +531,12 @@ func (rh *RulesHandler) formatRulesResults(category, priority string, rules []mo
 	if priority != "" {
 		result += fmt.Sprintf(" with priority: %s", priority)
 	}
+	if len(tags) > 0 {
+		result += fmt.Sprintf(" tagged: %s", strings.Join(tags, ", "))
+	}
+	if filePath != "" {
+		result += fmt.Sprintf(" for file: %s", filePath)
+	}
 	result += "\n"
 
 	// Group rules by priority for better organization
@@ -282,6 +557,15 @@ func (rh *RulesHandler) formatRulesResults(category, priority string, rules []mo
 
 			for i, rule := range rulesInPriority {
 				result += fmt.Sprintf("\n%d. [%s] %s\n", i+1, rule.Category, rule.Title)
+				if len(rule.Tags) > 0 {
+					result += fmt.Sprintf("   Tags: %s\n", strings.Join(rule.Tags, ", "))
+				}
+				if rule.Extends != "" {
+					result += fmt.Sprintf("   Extends: %s\n", rule.Extends)
+				}
+				if len(rule.AppliesTo) > 0 {
+					result += fmt.Sprintf("   Applies to: %s\n", strings.Join(rule.AppliesTo, ", "))
+				}
 
 				// Show description with better formatting
 				description := strings.TrimSpace(rule.Description)
@@ -306,3 +590,22 @@ func (rh *RulesHandler) formatRulesResults(category, priority string, rules []mo
 
 	return result
 }
+
+// CriticalRuleFile reports whether the rule file at path in store has
+// priority critical. Files that can't be read aren't.
+func CriticalRuleFile(store storage.Storage, path string) bool {
+	content, err := store.Read(path)
+	if err != nil {
+		return false
+	}
+	_, body := splitFrontmatter(string(content))
+	for i, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "Priority: ") {
+			return strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(line, "Priority: ")), "critical")
+		}
+		if line == "" && i > 0 {
+			break
+		}
+	}
+	return false
+}
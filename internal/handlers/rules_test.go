@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCriticalRuleFile(t *testing.T) {
+	store := storage.NewMem()
+	require.NoError(t, store.Write("/buddy/rules/style.md", []byte("# Style\nCategory: style\nPriority: critical\n\n- Use gofmt\n")))
+	require.NoError(t, store.Write("/buddy/rules/naming.md", []byte("# Naming\nPriority: high\n\n- Priority: critical\n")))
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"critical", "/buddy/rules/style.md", true},
+		{"priority only in the body", "/buddy/rules/naming.md", false},
+		{"missing", "/buddy/rules/gone.md", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CriticalRuleFile(store, tt.path))
+		})
+	}
+}
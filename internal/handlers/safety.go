@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/logging"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+)
+
+// defaultSafetyRetention is how long automatic safety snapshots are kept
+const defaultSafetyRetention = 7 * 24 * time.Hour
+
+// safetyDir is the folder in the buddy folder holding the safety snapshots
+const safetyDir = ".safety"
+
+// SafetySnapshot describes an automatic snapshot taken before a destructive action
+type SafetySnapshot struct {
+	ID        string            `json:"id"`
+	Action    string            `json:"action"`
+	Timestamp time.Time         `json:"timestamp"`
+	Files     map[string]string `json:"files"` // original path -> stored file name
+	// Label says what a restore point was taken for, e.g. the categories
+	// a reorganization moved files between
+	Label string `json:"label,omitempty"`
+	// Created lists the files the action was about to create; restoring
+	// a restore point removes them
+	Created []string `json:"created,omitempty"`
+	// RequestID is the tool call that took the snapshot, for tracing it in the logs
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// SafetyStore keeps short-lived copies of files that a tool action is about
+// to overwrite or delete, so mistakes made through the tools are recoverable
+type SafetyStore struct {
+	path      string
+	store     storage.Storage
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewSafetyStore creates a safety store rooted at path, reading and
+// writing through store
+func NewSafetyStore(path string, store storage.Storage) *SafetyStore {
+	return &SafetyStore{
+		path:      path,
+		store:     store,
+		retention: defaultSafetyRetention,
+		now:       time.Now,
+	}
+}
+
+// BuddySafetyStore returns the safety store of the buddy folder at buddyPath
+func BuddySafetyStore(buddyPath string, store storage.Storage) *SafetyStore {
+	return NewSafetyStore(filepath.Join(buddyPath, safetyDir), store)
+}
+
+// Snapshot copies the given files into a new snapshot. Files that don't
+// exist are skipped. Expired snapshots are pruned on every call.
+func (ss *SafetyStore) Snapshot(ctx context.Context, action string, paths []string) (*SafetySnapshot, error) {
+	return ss.take(ctx, action, "", paths, false)
+}
+
+// RestorePoint snapshots every file a bulk edit of buddy content is about
+// to write or remove, so restoring it undoes the whole edit: files that
+// existed get their content back and files that didn't are removed.
+func (ss *SafetyStore) RestorePoint(ctx context.Context, action, label string, paths []string) (*SafetySnapshot, error) {
+	return ss.take(ctx, action, label, paths, true)
+}
+
+// take writes a snapshot of paths, listing the missing ones as created
+// when trackCreated is set
+func (ss *SafetyStore) take(ctx context.Context, action, label string, paths []string, trackCreated bool) (*SafetySnapshot, error) {
+	if ss == nil {
+		return nil, nil
+	}
+
+	ss.prune()
+
+	timestamp := ss.now().UTC()
+	snapshot := &SafetySnapshot{
+		ID:        fmt.Sprintf("%s-%s", timestamp.Format("20060102_150405.000000000"), action),
+		Action:    action,
+		Timestamp: timestamp,
+		Files:     make(map[string]string),
+		Label:     label,
+		RequestID: logging.RequestID(ctx),
+	}
+	dir := filepath.Join(ss.path, snapshot.ID)
+
+	for i, path := range paths {
+		content, err := ss.store.Read(path)
+		if err != nil {
+			if storage.IsNotExist(err) {
+				if trackCreated {
+					snapshot.Created = append(snapshot.Created, path)
+				}
+				continue
+			}
+			return nil, fmt.Errorf("failed to snapshot %s: %w", path, err)
+		}
+
+		name := fmt.Sprintf("%03d_%s", i, filepath.Base(path))
+		if err := ss.store.Write(filepath.Join(dir, name), content); err != nil {
+			return nil, fmt.Errorf("failed to snapshot %s: %w", path, err)
+		}
+		snapshot.Files[path] = name
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ss.store.Write(filepath.Join(dir, "manifest.json"), data); err != nil {
+		return nil, fmt.Errorf("failed to write safety manifest: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// List returns all unexpired snapshots, newest first
+func (ss *SafetyStore) List() ([]SafetySnapshot, error) {
+	if ss == nil {
+		return nil, nil
+	}
+
+	ss.prune()
+
+	manifests, err := ss.manifests()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []SafetySnapshot
+	for _, manifest := range manifests {
+		snapshot, err := ss.load(filepath.Base(filepath.Dir(manifest.Path)))
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Timestamp.After(snapshots[j].Timestamp)
+	})
+
+	return snapshots, nil
+}
+
+// Restore writes every file in a snapshot back to its original location
+// and removes the files a restore point's action created
+func (ss *SafetyStore) Restore(id string) (*SafetySnapshot, error) {
+	if ss == nil {
+		return nil, fmt.Errorf("safety snapshots are not enabled")
+	}
+
+	snapshot, err := ss.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	for original, name := range snapshot.Files {
+		content, err := ss.store.Read(filepath.Join(ss.path, id, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot of %s: %w", original, err)
+		}
+		if err := ss.store.Write(original, content); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", original, err)
+		}
+	}
+	for _, created := range snapshot.Created {
+		if err := ss.store.Remove(created); err != nil && !storage.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove %s: %w", created, err)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// Get returns the manifest of a snapshot, listing the files restoring it
+// would write and remove
+func (ss *SafetyStore) Get(id string) (*SafetySnapshot, error) {
+	if ss == nil {
+		return nil, fmt.Errorf("safety snapshots are not enabled")
+	}
+
+	// Guard against ids escaping the safety directory
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return nil, fmt.Errorf("invalid safety snapshot id: %s", id)
+	}
+
+	snapshot, err := ss.load(id)
+	if err != nil {
+		return nil, fmt.Errorf("safety snapshot not found: %s", id)
+	}
+	return snapshot, nil
+}
+
+// load reads a snapshot manifest
+func (ss *SafetyStore) load(id string) (*SafetySnapshot, error) {
+	content, err := ss.store.Read(filepath.Join(ss.path, id, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot SafetySnapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// manifests lists the manifest of every snapshot directory
+func (ss *SafetyStore) manifests() ([]storage.FileInfo, error) {
+	files, err := ss.store.List(ss.path, true)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var manifests []storage.FileInfo
+	for _, file := range files {
+		if filepath.Base(file.Path) == "manifest.json" && filepath.Dir(filepath.Dir(file.Path)) == filepath.Clean(ss.path) {
+			manifests = append(manifests, file)
+		}
+	}
+	return manifests, nil
+}
+
+// prune removes snapshots whose manifest is older than the retention
+// period
+func (ss *SafetyStore) prune() {
+	manifests, err := ss.manifests()
+	if err != nil {
+		return
+	}
+
+	cutoff := ss.now().Add(-ss.retention)
+	for _, manifest := range manifests {
+		if manifest.ModTime.Before(cutoff) {
+			dir := filepath.Dir(manifest.Path)
+			if err := ss.store.Remove(dir); err != nil {
+				slog.Warn("failed to remove expired safety snapshot", "snapshot", filepath.Base(dir), "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafetyStore_SnapshotAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	ss := BuddySafetyStore(filepath.Join(dir, ".buddy"), storage.NewLocal())
+	rule := filepath.Join(dir, "rules", "errors.md")
+	writeTestFile(t, rule, "# Errors\n")
+
+	snapshot, err := ss.Snapshot(context.Background(), "rule_delete", []string{rule, filepath.Join(dir, "missing.md")})
+	require.NoError(t, err)
+	assert.Equal(t, "rule_delete", snapshot.Action)
+	assert.Len(t, snapshot.Files, 1)
+	assert.Empty(t, snapshot.Created)
+
+	require.NoError(t, os.Remove(rule))
+	restored, err := ss.Restore(snapshot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.ID, restored.ID)
+	assertFileContent(t, rule, "# Errors\n")
+
+	snapshots, err := ss.List()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, snapshot.ID, snapshots[0].ID)
+}
+
+func TestSafetyStore_RestorePointRemovesCreatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	ss := BuddySafetyStore(filepath.Join(dir, ".buddy"), storage.NewLocal())
+	existing := filepath.Join(dir, "todos", "sprint.md")
+	created := filepath.Join(dir, "todos", "imported.md")
+	writeTestFile(t, existing, "- [ ] ship\n")
+
+	snapshot, err := ss.RestorePoint(context.Background(), "todo_import", "from issues", []string{existing, created})
+	require.NoError(t, err)
+	assert.Equal(t, []string{created}, snapshot.Created)
+
+	writeTestFile(t, existing, "- [x] ship\n")
+	writeTestFile(t, created, "- [ ] imported\n")
+	_, err = ss.Restore(snapshot.ID)
+	require.NoError(t, err)
+	assertFileContent(t, existing, "- [ ] ship\n")
+	assert.NoFileExists(t, created)
+}
+
+func TestSafetyStore_InvalidIDs(t *testing.T) {
+	ss := NewSafetyStore(t.TempDir(), storage.NewLocal())
+	for _, id := range []string{"", "../secrets", `a\b`, "a/b", "unknown"} {
+		_, err := ss.Restore(id)
+		assert.Error(t, err, id)
+	}
+}
+
+func TestSafetyStore_PrunesExpiredSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	ss := NewSafetyStore(filepath.Join(dir, ".safety"), storage.NewLocal())
+	file := filepath.Join(dir, "a.md")
+	writeTestFile(t, file, "a\n")
+
+	old, err := ss.Snapshot(context.Background(), "clean", []string{file})
+	require.NoError(t, err)
+
+	// A day before the retention runs out the snapshot is kept
+	ss.now = func() time.Time { return time.Now().Add(defaultSafetyRetention - 24*time.Hour) }
+	snapshots, err := ss.List()
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 1)
+
+	// Past it, the next snapshot prunes it
+	ss.now = func() time.Time { return time.Now().Add(defaultSafetyRetention + time.Hour) }
+	current, err := ss.Snapshot(context.Background(), "clean", []string{file})
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(dir, ".safety", old.ID))
+	assert.DirExists(t, filepath.Join(dir, ".safety", current.ID))
+	_, err = ss.Restore(old.ID)
+	assert.ErrorContains(t, err, "safety snapshot not found")
+}
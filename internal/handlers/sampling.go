@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/summary"
+)
+
+// samplingTimeout bounds how long a summary waits for the client, which
+// may ask the user to approve the request first
+const samplingTimeout = 60 * time.Second
+
+// Sampler has the connected client's model write summaries through MCP
+// sampling, so the server needs no API keys of its own. Only sessions whose
+// client declared the sampling capability are asked.
+type Sampler struct {
+	request  func(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error)
+	sessions map[string]bool
+	timeout  time.Duration
+	mu       sync.RWMutex
+}
+
+// NewSampler creates a sampler. Register its hooks with the MCP server and
+// Attach the server before use.
+func NewSampler() *Sampler {
+	return &Sampler{
+		sessions: make(map[string]bool),
+		timeout:  samplingTimeout,
+	}
+}
+
+// RegisterHooks records which sessions can sample as clients initialize
+// and forgets them when they disconnect
+func (s *Sampler) RegisterHooks(hooks *server.Hooks) {
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, message *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil || message.Params.Capabilities.Sampling == nil {
+			return
+		}
+		s.mu.Lock()
+		s.sessions[session.SessionID()] = true
+		s.mu.Unlock()
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		s.mu.Lock()
+		delete(s.sessions, session.SessionID())
+		s.mu.Unlock()
+	})
+}
+
+// Attach sends sampling requests through mcpServer
+func (s *Sampler) Attach(mcpServer *server.MCPServer) {
+	mcpServer.EnableSampling()
+	s.request = mcpServer.RequestSampling
+}
+
+// Available reports whether the client behind ctx can sample
+func (s *Sampler) Available(ctx context.Context) bool {
+	if s == nil || s.request == nil {
+		return false
+	}
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return false
+	}
+	if _, ok := session.(server.SessionWithSampling); !ok {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sessions[session.SessionID()]
+}
+
+// Complete asks the client's model to answer prompt and returns the text
+// of its reply along with the model name
+func (s *Sampler) Complete(ctx context.Context, systemPrompt, prompt string, maxTokens int) (string, string, error) {
+	if !s.Available(ctx) {
+		return "", "", fmt.Errorf("client does not support sampling")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	request := mcp.CreateMessageRequest{}
+	request.Messages = []mcp.SamplingMessage{{
+		Role:    mcp.RoleUser,
+		Content: mcp.NewTextContent(prompt),
+	}}
+	request.SystemPrompt = systemPrompt
+	request.MaxTokens = maxTokens
+	request.Temperature = 0.2
+
+	result, err := s.request(ctx, request)
+	if err != nil {
+		return "", "", err
+	}
+
+	var text string
+	switch content := result.Content.(type) {
+	case mcp.TextContent:
+		text = content.Text
+	case map[string]interface{}:
+		// Replies decoded from JSON arrive as plain maps
+		text, _ = content["text"].(string)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", "", fmt.Errorf("client returned no text")
+	}
+	return text, result.Model, nil
+}
+
+// Summary is generated text and where it came from
+type Summary struct {
+	Text  string
+	Model string // the client's model, empty for extractive summaries
+}
+
+// Source describes how the summary was produced
+func (s Summary) Source() string {
+	if s.Model == "" {
+		return "extractive (client sampling unavailable)"
+	}
+	return "client model " + s.Model
+}
+
+// summarize has the client's model follow instruction on text, falling
+// back to an extractive summary of sentences sentences when the client
+// can't sample or the request fails
+func (bh *BuddyHandlers) summarize(ctx context.Context, instruction, text string, sentences int) Summary {
+	if bh.sampler.Available(ctx) {
+		reply, model, err := bh.sampler.Complete(ctx,
+			"You summarize software project documentation for developers. Be concise and factual; use only the information given.",
+			instruction+"\n\n---\n\n"+text, 500)
+		if err == nil {
+			if model == "" {
+				model = "unknown"
+			}
+			return Summary{Text: reply, Model: model}
+		}
+		slog.WarnContext(ctx, "sampling failed, using extractive summary", "error", err)
+	}
+
+	return Summary{Text: summary.Extract(text, sentences)}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/workspace"
+)
+
+// newSandbox builds the configured sandbox, with relative roots and
+// allowed paths resolved from the workspace root, or nil when it is off.
+// The buddy folder at absBuddyPath is always allowed, as its own files
+// are restored and exported from.
+func newSandbox(cfg config.Sandbox, root workspace.Root, absBuddyPath string) *workspace.Sandbox {
+	if !cfg.Enabled {
+		return nil
+	}
+	roots := []string{root.Dir()}
+	if len(cfg.Roots) > 0 {
+		roots = make([]string, len(cfg.Roots))
+		for i, dir := range cfg.Roots {
+			roots[i] = root.Abs(dir)
+		}
+	}
+	allow := []string{absBuddyPath}
+	for _, path := range cfg.Allow {
+		allow = append(allow, root.Abs(path))
+	}
+	return workspace.NewSandbox(roots, allow)
+}
+
+// checkSandbox returns the sandbox's refusal of the first absolute path
+// op may not touch
+func (bh *BackupHandler) checkSandbox(op string, paths ...string) error {
+	return checkSandbox(bh.sandbox.Load(), op, paths...)
+}
+
+// checkSandbox returns sandbox's refusal of the first absolute path op may
+// not touch; the nil sandbox refuses nothing
+func checkSandbox(sandbox *workspace.Sandbox, op string, paths ...string) error {
+	for _, path := range paths {
+		if err := sandbox.Check(op, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReportSandboxViolations turns a call refused by the sandbox into a tool
+// error whose text is JSON naming the operation, path and roots, so
+// clients can tell it apart from other failures:
+//
+//	{"error":"sandbox_violation","message":"...","op":"restore","path":"/etc/hosts","roots":["/src/app"]}
+func ReportSandboxViolations(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		var violation *workspace.SandboxError
+		if !errors.As(err, &violation) {
+			return result, err
+		}
+		data, marshalErr := json.Marshal(struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+			*workspace.SandboxError
+		}{"sandbox_violation", err.Error(), violation})
+		if marshalErr != nil {
+			return result, err
+		}
+		return mcp.NewToolResultError(string(data)), nil
+	}
+}
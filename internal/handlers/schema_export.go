@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+)
+
+// SchemaRelationship is one foreign key, listed on its own so consumers
+// can build join graphs without walking every table
+type SchemaRelationship struct {
+	FromTable  string `json:"from_table"`
+	FromColumn string `json:"from_column"`
+	ToTable    string `json:"to_table"`
+	ToColumn   string `json:"to_column"`
+}
+
+// SchemaExport is the parsed schema in machine-readable form
+type SchemaExport struct {
+	Type          string               `json:"type,omitempty"`
+	Tables        []models.Table       `json:"tables"`
+	Relationships []SchemaRelationship `json:"relationships"`
+}
+
+// ExportSchema renders the parsed schema as JSON or as normalized SQL DDL.
+// Tables are sorted by name so exports diff cleanly; a table name limits
+// the export to that table.
+func (dh *DatabaseHandler) ExportSchema(format, tableName string) (string, error) {
+	dbInfo := dh.GetDatabaseInfo()
+	if dbInfo == nil {
+		return "", fmt.Errorf("no database information loaded")
+	}
+
+	var tables []models.Table
+	for _, table := range dbInfo.Tables {
+		if tableName == "" || strings.EqualFold(table.Name, tableName) {
+			if table.Indexes == nil {
+				table.Indexes = []models.Index{}
+			}
+			tables = append(tables, table)
+		}
+	}
+	if tableName != "" && len(tables) == 0 {
+		return "", fmt.Errorf("table not found: %s", tableName)
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		return strings.ToLower(tables[i].Name) < strings.ToLower(tables[j].Name)
+	})
+
+	switch format {
+	case "", "json":
+		export := SchemaExport{
+			Type:          dbInfo.Type,
+			Tables:        tables,
+			Relationships: []SchemaRelationship{},
+		}
+		if export.Tables == nil {
+			export.Tables = []models.Table{}
+		}
+		for _, table := range tables {
+			for _, fk := range table.ForeignKeys {
+				export.Relationships = append(export.Relationships, SchemaRelationship{
+					FromTable:  table.Name,
+					FromColumn: fk.Column,
+					ToTable:    fk.RefTable,
+					ToColumn:   fk.RefColumn,
+				})
+			}
+		}
+
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal schema: %w", err)
+		}
+		return string(data), nil
+
+	case "sql":
+		return schemaDDL(tables), nil
+
+	default:
+		return "", fmt.Errorf("invalid format: %s (expected json or sql)", format)
+	}
+}
+
+// schemaDDL renders tables as CREATE TABLE and CREATE INDEX statements in
+// one layout: upper-case keywords and types, one column per line, keys on
+// their column unless they span several, foreign keys as table
+// constraints. Personal data columns keep their "-- pii" comment, so the
+// output parses back to the same schema.
+func schemaDDL(tables []models.Table) string {
+	var sb strings.Builder
+	for i, table := range tables {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		var primaryKey []string
+		for _, column := range table.Columns {
+			if column.PrimaryKey {
+				primaryKey = append(primaryKey, column.Name)
+			}
+		}
+
+		var lines, comments []string
+		for _, column := range table.Columns {
+			line := column.Name + " " + strings.ToUpper(column.Type)
+			if !column.Nullable {
+				line += " NOT NULL"
+			}
+			if column.DefaultValue != "" {
+				line += " DEFAULT " + column.DefaultValue
+			}
+			if column.PrimaryKey && len(primaryKey) == 1 {
+				line += " PRIMARY KEY"
+			}
+			if column.Unique {
+				line += " UNIQUE"
+			}
+			comment := ""
+			if column.PII {
+				comment = " -- pii"
+			}
+			lines = append(lines, line)
+			comments = append(comments, comment)
+		}
+		if len(primaryKey) > 1 {
+			lines = append(lines, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKey, ", ")))
+			comments = append(comments, "")
+		}
+		for _, key := range table.UniqueKeys {
+			lines = append(lines, fmt.Sprintf("UNIQUE (%s)", strings.Join(key, ", ")))
+			comments = append(comments, "")
+		}
+		for _, fk := range table.ForeignKeys {
+			lines = append(lines, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", fk.Column, fk.RefTable, fk.RefColumn))
+			comments = append(comments, "")
+		}
+
+		fmt.Fprintf(&sb, "CREATE TABLE %s (\n", table.Name)
+		for j, line := range lines {
+			separator := ","
+			if j == len(lines)-1 {
+				separator = ""
+			}
+			fmt.Fprintf(&sb, "    %s%s%s\n", line, separator, comments[j])
+		}
+		sb.WriteString(");\n")
+
+		for _, index := range table.Indexes {
+			unique := ""
+			if index.Unique {
+				unique = "UNIQUE "
+			}
+			fmt.Fprintf(&sb, "CREATE %sINDEX %s ON %s (%s);\n", unique, index.Name, table.Name, strings.Join(index.Columns, ", "))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/schema"
+)
+
+// GetSchemasResourceHandler lists the JSON Schemas buddy content is
+// validated against, with the URI each is published at
+func GetSchemasResourceHandler() server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		data, err := json.MarshalIndent(map[string]interface{}{"schemas": schema.List()}, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal schemas: %w", err)
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(data),
+			},
+		}, nil
+	}
+}
+
+// GetSchemaResourceHandler serves one bundled JSON Schema
+func GetSchemaResourceHandler(name string) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		content, ok := schema.Source(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown schema %q", name)
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/schema+json",
+				Text:     string(content),
+			},
+		}, nil
+	}
+}
@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// scratchpadDir holds the notes of running sessions, in the buddy folder
+const scratchpadDir = "scratchpad"
+
+const (
+	// maxScratchNotes caps the notes one session keeps
+	maxScratchNotes = 50
+	// maxScratchNoteBytes caps one note; longer text belongs in knowledge
+	maxScratchNoteBytes = 16 << 10
+	// maxScratchNameLength caps a note's name, in characters
+	maxScratchNameLength = 100
+)
+
+// scratchPromotions are where a note can be saved when its session ends
+var scratchPromotions = []string{"history", "knowledge"}
+
+// unsafeSessionChars are replaced in session IDs used as file names
+var unsafeSessionChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// ScratchNote is a named note an agent keeps between tool calls
+type ScratchNote struct {
+	Name      string    `json:"name"`
+	Content   string    `json:"content"`
+	Promote   string    `json:"promote,omitempty"` // history or knowledge: saved there when the session ends
+	Feature   string    `json:"feature,omitempty"` // history feature or knowledge category when promoted
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Scratchpad keeps each session's notes in a file under scratchpad/, so
+// intermediate results survive between tool calls without becoming
+// project content. A session's notes are removed when it ends, after
+// those marked for promotion are saved as a history entry or knowledge
+// draft. Each server keeps its own folder, so servers sharing a buddy
+// folder don't end each other's sessions.
+type Scratchpad struct {
+	dir     string
+	store   storage.Storage
+	promote func(ctx context.Context, note ScratchNote) (string, error) // returns where the note went
+	mu      sync.Mutex
+}
+
+// NewScratchpad creates a scratchpad kept in a folder of its own under
+// dir. promote saves a note marked for promotion.
+func NewScratchpad(dir string, store storage.Storage, promote func(ctx context.Context, note ScratchNote) (string, error)) *Scratchpad {
+	instance := fmt.Sprintf("%s-%d", time.Now().UTC().Format("20060102T150405"), os.Getpid())
+	return &Scratchpad{dir: filepath.Join(dir, instance), store: store, promote: promote}
+}
+
+// file returns the notes file of a session
+func (sp *Scratchpad) file(session string) string {
+	name := unsafeSessionChars.ReplaceAllString(session, "_")
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(sp.dir, name+".json")
+}
+
+// read returns a session's notes sorted by name; callers hold mu
+func (sp *Scratchpad) read(session string) ([]ScratchNote, error) {
+	content, err := sp.store.Read(sp.file(session))
+	if storage.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var notes []ScratchNote
+	if err := json.Unmarshal(content, &notes); err != nil {
+		return nil, fmt.Errorf("invalid scratchpad %s: %w", filepath.Base(sp.file(session)), err)
+	}
+	return notes, nil
+}
+
+// write saves a session's notes, removing the file when there are none;
+// callers hold mu
+func (sp *Scratchpad) write(session string, notes []ScratchNote) error {
+	if len(notes) == 0 {
+		if err := sp.store.Remove(sp.file(session)); err != nil && !storage.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].Name < notes[j].Name })
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return sp.store.Write(sp.file(session), data)
+}
+
+// Notes returns a session's notes sorted by name
+func (sp *Scratchpad) Notes(session string) ([]ScratchNote, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.read(session)
+}
+
+// Get returns a session's note by name
+func (sp *Scratchpad) Get(session, name string) (ScratchNote, error) {
+	notes, err := sp.Notes(session)
+	if err != nil {
+		return ScratchNote{}, err
+	}
+	name = strings.TrimSpace(name)
+	for _, note := range notes {
+		if note.Name == name {
+			return note, nil
+		}
+	}
+	return ScratchNote{}, fmt.Errorf("no scratchpad note named %q in this session", name)
+}
+
+// Set adds a note to a session's scratchpad or replaces the one with the
+// same name
+func (sp *Scratchpad) Set(session string, note ScratchNote) (ScratchNote, error) {
+	note.Name = strings.TrimSpace(note.Name)
+	switch {
+	case note.Name == "":
+		return ScratchNote{}, fmt.Errorf("name is required")
+	case strings.ContainsAny(note.Name, "\r\n"):
+		return ScratchNote{}, fmt.Errorf("name must be a single line")
+	case utf8.RuneCountInString(note.Name) > maxScratchNameLength:
+		return ScratchNote{}, fmt.Errorf("name is longer than %d characters", maxScratchNameLength)
+	case strings.TrimSpace(note.Content) == "":
+		return ScratchNote{}, fmt.Errorf("content is required")
+	case len(note.Content) > maxScratchNoteBytes:
+		return ScratchNote{}, fmt.Errorf("note is %d bytes, over the %d byte limit; save longer text as knowledge", len(note.Content), maxScratchNoteBytes)
+	case note.Promote != "" && !slices.Contains(scratchPromotions, note.Promote):
+		return ScratchNote{}, fmt.Errorf("invalid promote: %s (expected %s)", note.Promote, strings.Join(scratchPromotions, " or "))
+	}
+	note.Feature = strings.TrimSpace(note.Feature)
+	note.UpdatedAt = time.Now().UTC()
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	notes, err := sp.read(session)
+	if err != nil {
+		return ScratchNote{}, err
+	}
+	replaced := false
+	for i := range notes {
+		if notes[i].Name == note.Name {
+			notes[i] = note
+			replaced = true
+		}
+	}
+	if !replaced {
+		if len(notes) >= maxScratchNotes {
+			return ScratchNote{}, fmt.Errorf("the scratchpad holds at most %d notes; delete some first", maxScratchNotes)
+		}
+		notes = append(notes, note)
+	}
+	return note, sp.write(session, notes)
+}
+
+// Delete removes a session's note by name and returns it
+func (sp *Scratchpad) Delete(session, name string) (ScratchNote, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	notes, err := sp.read(session)
+	if err != nil {
+		return ScratchNote{}, err
+	}
+	name = strings.TrimSpace(name)
+	for i, note := range notes {
+		if note.Name == name {
+			return note, sp.write(session, append(notes[:i], notes[i+1:]...))
+		}
+	}
+	return ScratchNote{}, fmt.Errorf("no scratchpad note named %q in this session", name)
+}
+
+// Clear removes all of a session's notes without promoting any, and
+// returns how many there were
+func (sp *Scratchpad) Clear(session string) (int, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	notes, err := sp.read(session)
+	if err != nil {
+		return 0, err
+	}
+	return len(notes), sp.write(session, nil)
+}
+
+// Promote saves a session's note now, to where of history or knowledge,
+// and removes it from the scratchpad. It returns where the note went.
+func (sp *Scratchpad) Promote(ctx context.Context, session, name, where, feature string) (string, error) {
+	note, err := sp.Get(session, name)
+	if err != nil {
+		return "", err
+	}
+	if where != "" {
+		note.Promote = where
+	}
+	if feature = strings.TrimSpace(feature); feature != "" {
+		note.Feature = feature
+	}
+	if !slices.Contains(scratchPromotions, note.Promote) {
+		return "", fmt.Errorf("promote is required: %s", strings.Join(scratchPromotions, " or "))
+	}
+	target, err := sp.promote(ctx, note)
+	if err != nil {
+		return "", fmt.Errorf("failed to promote %q: %w", note.Name, err)
+	}
+	if _, err := sp.Delete(session, note.Name); err != nil {
+		return target, err
+	}
+	return target, nil
+}
+
+// End promotes a session's notes marked for promotion and removes the
+// rest, returning where the promoted notes went. A note that fails to
+// promote is logged and kept on disk, so it isn't lost.
+func (sp *Scratchpad) End(ctx context.Context, session string) []string {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	notes, err := sp.read(session)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read scratchpad at session end", "session", session, "error", err)
+		return nil
+	}
+
+	var promoted []string
+	var failed []ScratchNote
+	for _, note := range notes {
+		if note.Promote == "" {
+			continue
+		}
+		target, err := sp.promote(ctx, note)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to promote scratchpad note; it stays in the scratchpad folder", "session", session, "note", note.Name, "error", err)
+			failed = append(failed, note)
+			continue
+		}
+		promoted = append(promoted, target)
+	}
+	if err := sp.write(session, failed); err != nil {
+		slog.WarnContext(ctx, "failed to remove scratchpad at session end", "session", session, "error", err)
+	}
+	return promoted
+}
+
+// EndAll ends every session with notes, as when the server stops, and
+// removes the server's folder once it is empty
+func (sp *Scratchpad) EndAll(ctx context.Context) {
+	files, err := sp.store.List(sp.dir, false)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if filepath.Ext(file.Path) == ".json" {
+			sp.End(ctx, strings.TrimSuffix(filepath.Base(file.Path), ".json"))
+		}
+	}
+	if remaining, err := sp.store.List(sp.dir, true); err == nil && len(remaining) == 0 {
+		sp.store.Remove(sp.dir)
+	}
+}
+
+// promoteScratchNote saves a note as a history entry under its feature, or
+// as a knowledge draft in its category for review, returning where it went
+func (bh *BuddyHandlers) promoteScratchNote(ctx context.Context, note ScratchNote) (string, error) {
+	switch note.Promote {
+	case "history":
+		feature := note.Feature
+		if feature == "" {
+			feature = "scratchpad"
+		}
+		if err := bh.historyHandler.AddEntry(ctx, feature, note.Name, note.Content, nil); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("history (%s)", feature), nil
+	case "knowledge":
+		category := note.Feature
+		if category == "" {
+			category = "notes"
+		}
+		content := fmt.Sprintf("# %s\nCategory: %s\n\n%s\n", note.Name, category, strings.TrimSpace(note.Content))
+		path, err := bh.draftHandler.WriteDraft(ctx, "knowledge", note.Name, content)
+		if err != nil {
+			return "", err
+		}
+		return bh.relativeBuddyPath(path), nil
+	default:
+		return "", fmt.Errorf("invalid promote: %s (expected %s)", note.Promote, strings.Join(scratchPromotions, " or "))
+	}
+}
+
+// ScratchpadArgs are the arguments of buddy_scratchpad
+type ScratchpadArgs struct {
+	Action  string `arg:"action" enum:"list,get,set,delete,clear,promote" desc:"list (default), get, set, delete, clear or promote"`
+	Name    string `arg:"name" desc:"Note name, one line (required for get, set, delete and promote)"`
+	Content string `arg:"content" desc:"Note text, up to 16 KB; setting an existing name replaces it (required for set)"`
+	Promote string `arg:"promote" enum:"history,knowledge" desc:"Save the note as a history entry or knowledge draft: at session end for set, now for promote (optional)"`
+	Feature string `arg:"feature" desc:"History feature or knowledge category of a promoted note (default: scratchpad for history, notes for knowledge)"`
+}
+
+// GetScratchpadToolHandler returns the tool handler for the calling
+// session's scratchpad
+func (bh *BuddyHandlers) GetScratchpadToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(bh.scratchpadTool)
+}
+
+func (bh *BuddyHandlers) scratchpadTool(ctx context.Context, args ScratchpadArgs) (*mcp.CallToolResult, error) {
+	session := undoSession(ctx)
+	switch args.Action {
+	case "", "list":
+		notes, err := bh.scratchpad.Notes(session)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(bh.formatScratchNotes(notes)), nil
+
+	case "get":
+		note, err := bh.scratchpad.Get(session, args.Name)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(note.Content), nil
+
+	case "set":
+		note, err := bh.scratchpad.Set(session, ScratchNote{Name: args.Name, Content: args.Content, Promote: args.Promote, Feature: args.Feature})
+		if err != nil {
+			return nil, err
+		}
+		result := fmt.Sprintf("🗒️ Saved note \"%s\" (%d characters)", note.Name, utf8.RuneCountInString(note.Content))
+		if note.Promote != "" {
+			result += fmt.Sprintf("\n💡 Saved to %s when the session ends", note.Promote)
+		}
+		return mcp.NewToolResultText(result), nil
+
+	case "delete":
+		note, err := bh.scratchpad.Delete(session, args.Name)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("🗑️ Deleted note \"%s\"", note.Name)), nil
+
+	case "clear":
+		count, err := bh.scratchpad.Clear(session)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("🗑️ Cleared %d notes", count)), nil
+
+	case "promote":
+		target, err := bh.scratchpad.Promote(ctx, session, args.Name, args.Promote, args.Feature)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("📌 Promoted note \"%s\" to %s", strings.TrimSpace(args.Name), target)), nil
+
+	default:
+		return nil, fmt.Errorf("unknown action: %s (expected list, get, set, delete, clear or promote)", args.Action)
+	}
+}
+
+// formatScratchNotes lists a session's notes with a preview of each
+func (bh *BuddyHandlers) formatScratchNotes(notes []ScratchNote) string {
+	if len(notes) == 0 {
+		return "🗒️ The scratchpad is empty; save notes with action set to keep them between tool calls"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🗒️ %d scratchpad notes in this session\n", len(notes))
+	for _, note := range notes {
+		fmt.Fprintf(&sb, "\n- %s (%d characters, updated %s)", note.Name, utf8.RuneCountInString(note.Content), bh.timeFormat.Format(note.UpdatedAt))
+		if note.Promote != "" {
+			fmt.Fprintf(&sb, " → %s at session end", note.Promote)
+		}
+		preview := strings.TrimSpace(strings.SplitN(strings.TrimSpace(note.Content), "\n", 2)[0])
+		if utf8.RuneCountInString(preview) > 80 {
+			preview = string([]rune(preview)[:80]) + "…"
+		}
+		fmt.Fprintf(&sb, "\n  %s", preview)
+	}
+	sb.WriteString("\n\n💡 Notes are removed when the session ends; set promote to keep one as history or a knowledge draft")
+	return sb.String()
+}
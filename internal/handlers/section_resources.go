@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// recentHistoryLimit is how many history entries resources include
+const recentHistoryLimit = 10
+
+// ContextSections are the parts of buddy://project-context, each also
+// served on its own as buddy://<section> so clients can read just what
+// they need
+var ContextSections = []string{"rules", "knowledge", "todos", "database", "history"}
+
+// section returns one part of the project context
+func (cs *ContextSnapshot) section(name string) (interface{}, error) {
+	switch name {
+	case "rules":
+		return cs.Rules, nil
+	case "knowledge":
+		return cs.Knowledge, nil
+	case "todos":
+		return cs.Todos, nil
+	case "database":
+		return cs.Database, nil
+	case "history":
+		return cs.RecentHistory(recentHistoryLimit), nil
+	}
+	return nil, fmt.Errorf("unknown context section %q", name)
+}
+
+// GetSectionResourceHandler returns the resource handler for one section
+// of the project context, with the same shape as its field in
+// buddy://project-context
+func (bh *BuddyHandlers) GetSectionResourceHandler(name string) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return bh.readCachedResource(request, 0, func(snap *ContextSnapshot, now time.Time) (map[string]interface{}, error) {
+			value, err := snap.section(name)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{name: value}, nil
+		})
+	}
+}
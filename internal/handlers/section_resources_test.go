@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextSnapshot_Section(t *testing.T) {
+	var history []models.HistoryEntry
+	for i := 0; i < recentHistoryLimit+5; i++ {
+		history = append(history, models.HistoryEntry{ID: fmt.Sprintf("h%d", i)})
+	}
+	snap := &ContextSnapshot{
+		Rules:     []models.Rule{{Title: "Style"}},
+		Knowledge: []models.Knowledge{{Title: "Auth"}},
+		Todos:     []models.Todo{{Task: "Add login"}},
+		Database:  &models.DatabaseInfo{Tables: []models.Table{{Name: "users"}}},
+		History:   history,
+	}
+
+	tests := []struct {
+		name    string
+		want    interface{}
+		wantErr string
+	}{
+		{name: "rules", want: snap.Rules},
+		{name: "knowledge", want: snap.Knowledge},
+		{name: "todos", want: snap.Todos},
+		{name: "database", want: snap.Database},
+		{name: "history", want: history[:recentHistoryLimit]},
+		{name: "settings", wantErr: `unknown context section "settings"`},
+		{name: "", wantErr: `unknown context section ""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := snap.section(tt.name)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, value)
+		})
+	}
+}
+
+func TestGetSectionResourceHandler(t *testing.T) {
+	bh, _ := newTestBuddyHandlers(t, map[string]string{
+		"rules/style.md":      "# Style\nCategory: style\nPriority: critical\n\nUse gofmt.\n",
+		"knowledge/auth.md":   "# Authentication\nCategory: security\n\nSessions live in Redis.\n",
+		"todos/auth.md":       "# Feature: auth\n\n- [ ] Add login\n",
+		"database/schema.sql": "CREATE TABLE users (id INTEGER PRIMARY KEY);\n",
+		"history/h1.json":     `{"id":"h1","timestamp":"2024-03-01T00:00:00Z","feature":"auth","description":"Added sessions"}`,
+	})
+
+	tests := []struct {
+		section string
+		want    string // part of the section's JSON
+		wantErr string
+	}{
+		{section: "rules", want: `"title":"Style"`},
+		{section: "knowledge", want: `"title":"Authentication"`},
+		{section: "todos", want: `"task":"Add login"`},
+		{section: "database", want: `"name":"users"`},
+		{section: "history", want: `"id":"h1"`},
+		{section: "settings", wantErr: `unknown context section "settings"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.section, func(t *testing.T) {
+			request := mcp.ReadResourceRequest{}
+			request.Params.URI = "buddy://" + tt.section
+			contents, err := bh.GetSectionResourceHandler(tt.section)(context.Background(), request)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, contents, 1)
+			text := contents[0].(mcp.TextResourceContents)
+			assert.Equal(t, request.Params.URI, text.URI)
+
+			// Only the section itself, plus the etag every resource carries
+			var payload map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal([]byte(text.Text), &payload))
+			assert.Len(t, payload, 2)
+			assert.Contains(t, payload, "etag")
+			assert.Contains(t, string(payload[tt.section]), tt.want)
+		})
+	}
+}
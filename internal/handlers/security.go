@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/security"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// securityRuleKeywords link detector categories to project rules that
+// mention them, so findings can cite the team's own guidance
+var securityRuleKeywords = map[string][]string{
+	"secrets":       {"secret", "credential", "password", "api key", "token", "private key"},
+	"sql-injection": {"sql injection", "parameterized", "prepared statement", "query parameter"},
+	"tls":           {"tls", "ssl", "certificate", "insecureskipverify"},
+}
+
+// securityRulesFor returns the titles of rules relevant to a detector
+// category: rules in the security category, or any rule mentioning one of
+// the category's keywords
+func securityRulesFor(rules []models.Rule, category string) []string {
+	keywords := securityRuleKeywords[category]
+	var titles []string
+	for _, rule := range rules {
+		text := strings.ToLower(rule.Title + "\n" + rule.Description + "\n" + rule.Content)
+		for _, keyword := range keywords {
+			if strings.Contains(text, keyword) {
+				titles = append(titles, rule.Title)
+				break
+			}
+		}
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+// SecurityCheckArgs are the arguments of buddy_security_check
+type SecurityCheckArgs struct {
+	Diff     string `arg:"diff" desc:"Unified diff of the change; only added lines are checked (optional)"`
+	Content  string `arg:"content" desc:"Full content of a new or changed file, used when no diff is given (optional)"`
+	FilePath string `arg:"file_path" desc:"Path of the file whose content is given (optional)"`
+}
+
+// GetSecurityCheckToolHandler returns the tool handler that scans a proposed
+// change for secrets, injectable SQL and disabled TLS verification
+func (bh *BuddyHandlers) GetSecurityCheckToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args SecurityCheckArgs) (*mcp.CallToolResult, error) {
+		diff, content, filePath := args.Diff, args.Content, args.FilePath
+
+		if diff == "" && content == "" {
+			return nil, fmt.Errorf("diff or content is required")
+		}
+
+		engine := security.NewEngine()
+		var findings []security.Finding
+		var skipped []string
+
+		if diff != "" {
+			for file, lines := range security.AddedLines(diff) {
+				if file != "" && !bh.Config().Paths.Allows(file) {
+					skipped = append(skipped, file)
+					continue
+				}
+				findings = append(findings, engine.ScanLines(file, lines)...)
+			}
+		} else if filePath != "" && !bh.Config().Paths.Allows(filePath) {
+			skipped = append(skipped, filePath)
+		} else {
+			findings = engine.ScanContent(filePath, content)
+		}
+
+		security.SortFindings(findings)
+		sort.Strings(skipped)
+
+		return mcp.NewToolResultText(formatSecurityFindings(findings, skipped, bh.Snapshot().Rules)), nil
+	})
+}
+
+// formatSecurityFindings lists findings by severity with their fixes,
+// references and related project rules
+func formatSecurityFindings(findings []security.Finding, skipped []string, rules []models.Rule) string {
+	result := "🔒 Security Check\n"
+	result += strings.Repeat("=", 30) + "\n"
+
+	if len(skipped) > 0 {
+		result += fmt.Sprintf("\nSkipped %d excluded paths: %s\n", len(skipped), strings.Join(skipped, ", "))
+	}
+
+	if len(findings) == 0 {
+		result += "\n✅ No security findings\n"
+		return result
+	}
+
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+	var summary []string
+	for _, severity := range []string{security.SeverityCritical, security.SeverityHigh, security.SeverityMedium, security.SeverityLow} {
+		if counts[severity] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	result += fmt.Sprintf("\nFound %d findings (%s)\n", len(findings), strings.Join(summary, ", "))
+
+	icons := map[string]string{
+		security.SeverityCritical: "🚨",
+		security.SeverityHigh:     "🔴",
+		security.SeverityMedium:   "🟡",
+		security.SeverityLow:      "🔵",
+	}
+
+	for i, finding := range findings {
+		location := fmt.Sprintf("line %d", finding.Line)
+		if finding.File != "" {
+			location = fmt.Sprintf("%s:%d", finding.File, finding.Line)
+		}
+
+		result += fmt.Sprintf("\n%d. %s [%s] %s (%s)\n", i+1, icons[finding.Severity], strings.ToUpper(finding.Severity), finding.Title, location)
+		result += fmt.Sprintf("   Code: %s\n", finding.Snippet)
+		result += fmt.Sprintf("   Fix: %s\n", finding.Message)
+		result += fmt.Sprintf("   Reference: %s (%s)\n", finding.Reference, finding.DetectorID)
+		if related := securityRulesFor(rules, finding.Category); len(related) > 0 {
+			result += fmt.Sprintf("   Project rules: %s\n", strings.Join(related, ", "))
+		}
+	}
+
+	return result
+}
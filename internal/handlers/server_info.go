@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+)
+
+// ServerInfo identifies the running server
+type ServerInfo struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Transport string `json:"transport"`
+	// AlsoListen is the address of the HTTP listener served beside stdio
+	AlsoListen string    `json:"also_listen,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	// AuthRequired is set when tool calls must carry a bearer token
+	AuthRequired bool `json:"auth_required"`
+}
+
+// ProjectInfo describes what one project's handlers currently hold
+type ProjectInfo struct {
+	Name           string            `json:"name"`
+	BuddyPath      string            `json:"buddy_path"`
+	Documents      map[string]int    `json:"documents"`       // loaded per section
+	IndexDocuments map[string]uint64 `json:"index_documents"` // per search index
+	LastReload     *time.Time        `json:"last_reload,omitempty"`
+	ReloadErrors   map[string]string `json:"reload_errors,omitempty"` // section -> error
+	Config         ConfigSummary     `json:"config"`
+}
+
+// ConfigSummary is the part of config.yaml that changes server behaviour,
+// without secrets
+type ConfigSummary struct {
+	ToolPrefix       string   `json:"tool_prefix,omitempty"`
+	ToolSuffix       string   `json:"tool_suffix,omitempty"`
+	EnabledTools     []string `json:"enabled_tools,omitempty"`
+	DisabledTools    []string `json:"disabled_tools,omitempty"`
+	IncludePaths     []string `json:"include_paths,omitempty"`
+	ExcludePaths     []string `json:"exclude_paths,omitempty"`
+	SandboxRoots     []string `json:"sandbox_roots,omitempty"` // set only while the sandbox is on
+	MaxFileKB        int      `json:"max_file_kb"`             // effective limit; 0 means none
+	AutoArchiveTodos bool     `json:"auto_archive_todos"`
+	TimeZone         string   `json:"time_zone"`
+
+	RateLimits map[string]config.RateLimit `json:"rate_limits,omitempty"`
+}
+
+// Info describes the project's loaded content, reload state and settings
+func (bh *BuddyHandlers) Info(name string) ProjectInfo {
+	snap := bh.Snapshot()
+	info := ProjectInfo{
+		Name:      name,
+		BuddyPath: bh.buddyPath,
+		Documents: map[string]int{
+			"rules":      len(snap.Rules),
+			"knowledge":  len(snap.Knowledge),
+			"todos":      len(snap.Todos),
+			"history":    len(snap.History),
+			"backups":    len(snap.Backups),
+			"datasets":   len(bh.datasetsHandler.Documents()),
+			"compliance": len(bh.complianceHandler.Documents()),
+			"views":      len(bh.viewsHandler.Documents()),
+		},
+		IndexDocuments: bh.IndexDocumentCounts(),
+	}
+	if snap.Database != nil {
+		info.Documents["database_tables"] = len(snap.Database.Tables)
+	}
+	// Snapshots are published when a reload finishes
+	if published := bh.snapshot.Load(); published != nil {
+		info.LastReload = &published.TakenAt
+	}
+
+	for _, section := range bh.reloadOrder {
+		if _, err := bh.reloaders[section].failure(); err != nil {
+			if info.ReloadErrors == nil {
+				info.ReloadErrors = make(map[string]string)
+			}
+			info.ReloadErrors[section] = err.Error()
+		}
+	}
+
+	cfg := bh.Config()
+	timeZone := cfg.Display.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	info.Config = ConfigSummary{
+		ToolPrefix:       cfg.Tools.Prefix,
+		ToolSuffix:       cfg.Tools.Suffix,
+		EnabledTools:     cfg.Tools.Enable,
+		DisabledTools:    cfg.Tools.Disable,
+		IncludePaths:     cfg.Paths.Include,
+		ExcludePaths:     cfg.Paths.Exclude,
+		SandboxRoots:     bh.backupHandler.sandbox.Load().Roots(),
+		MaxFileKB:        int(maxFileBytes(cfg.Limits.MaxFileKB) / 1024),
+		AutoArchiveTodos: cfg.Todos.AutoArchive,
+		TimeZone:         timeZone,
+		RateLimits:       cfg.RateLimits,
+	}
+	return info
+}
+
+// GetServerInfoToolHandler returns the tool handler that reports the
+// server's version, registered tools, connected sessions and what every
+// project has loaded
+func (tr *ToolRegistry) GetServerInfoToolHandler(info ServerInfo, projects *Projects, sessions *SessionGuard) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var tools []string
+		for _, tool := range tr.Tools() {
+			tools = append(tools, tool.Name)
+		}
+
+		var projectInfos []ProjectInfo
+		for _, project := range projects.List() {
+			projectInfos = append(projectInfos, project.Handlers.Info(project.Name))
+		}
+
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"server":         info,
+			"uptime_seconds": int(time.Since(info.StartedAt).Seconds()),
+			"tools":          tools,
+			"sessions":       sessions.Sessions(),
+			"projects":       projectInfos,
+		}, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal server info: %w", err)
+		}
+
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/logging"
+)
+
+// slowWriteWait is how long a mutating call may wait for others before the
+// wait is logged
+const slowWriteWait = time.Second
+
+// readOnlyActions lists, per unprefixed tool name, the actions that only
+// read buddy files; a nil list means every call of the tool only reads.
+// Calls of tools and actions not listed may write, so they run one at a
+// time.
+var readOnlyActions = map[string][]string{
+	"buddy_get_rules":         {"", "list", "test", "lint", "history", "templates"},
+	"buddy_check_names":       nil,
+	"buddy_search_knowledge":  nil,
+	"buddy_get_database_info": nil,
+	"buddy_generate_fixtures": nil,
+	"buddy_mock":              nil,
+	"buddy_manage_todos":      {"list", "progress"},
+	"buddy_history":           {"list", "search"},
+	"buddy_backup":            {"list", "list_safety"},
+	"buddy_get_datasets":      nil,
+	"buddy_check_compliance":  nil,
+	"buddy_lock":              {"", "list"},
+	"buddy_budgets":           {"", "report"},
+	"buddy_security_check":    nil,
+	"buddy_summarize":         nil,
+	"buddy_time_travel":       nil,
+	"buddy_handoff":           nil,
+	"buddy_views":             nil,
+	"buddy_focus":             {"", "get"},
+	"buddy_scratchpad":        {"", "list", "get"},
+	"buddy_status":            {""},
+	"buddy_quality":           nil,
+	"buddy_undo":              {"list"},
+	"buddy_audit":             nil,
+	"buddy_health":            nil,
+	"buddy_server_info":       nil,
+	"buddy_help":              nil,
+}
+
+// scopedWriteTools lists, by unprefixed name, the tools that write only
+// after asking the client's model, which can take up to samplingTimeout.
+// They take the write lock themselves, around the write alone, so other
+// sessions' changes don't wait on the client.
+var scopedWriteTools = map[string]bool{
+	"buddy_capture_session": true,
+}
+
+// writeLockKey is the context key of the write lock scoped writers take
+type writeLockKey struct{}
+
+// lockWrites takes the write lock the session guard handed a scoped writer
+// through ctx and returns its release. Without a guard, as in tests, it
+// takes nothing.
+func lockWrites(ctx context.Context) func() {
+	mu, ok := ctx.Value(writeLockKey{}).(*sync.Mutex)
+	if !ok {
+		return func() {}
+	}
+	mu.Lock()
+	return mu.Unlock
+}
+
+// SessionInfo is what the server knows about one client session
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	FirstCall time.Time `json:"first_call"`
+	LastCall  time.Time `json:"last_call"`
+	Calls     int       `json:"calls"`
+	Writes    int       `json:"writes"` // calls that could change buddy files
+	Running   int       `json:"running"`
+}
+
+// SessionGuard keeps clients sharing the server from corrupting each
+// other's work. Calls that can change buddy files, backups/metadata.json
+// included, run one at a time across all sessions, so two clients' todo
+// updates or backups can't interleave; read-only calls run freely. Each
+// session's calls are counted.
+type SessionGuard struct {
+	config func() *config.Config // the current configuration, reread per call
+
+	writes sync.Mutex // held by the running mutating call
+
+	mu       sync.Mutex
+	sessions map[string]*SessionInfo
+}
+
+// NewSessionGuard creates a guard that recognizes tools by the naming of
+// the configuration cfg returns. Register its hooks and middleware with
+// the MCP server before use.
+func NewSessionGuard(cfg func() *config.Config) *SessionGuard {
+	return &SessionGuard{
+		config:   cfg,
+		sessions: make(map[string]*SessionInfo),
+	}
+}
+
+// RegisterHooks forgets sessions when their client disconnects
+func (sg *SessionGuard) RegisterHooks(hooks *server.Hooks) {
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		sg.mu.Lock()
+		delete(sg.sessions, session.SessionID())
+		sg.mu.Unlock()
+	})
+}
+
+// TagSessions is tool handler middleware that puts the caller's MCP
+// session ID in the call's context, so log lines show which client made
+// them. Register it right after TraceRequests.
+func TagSessions(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if session := undoSession(ctx); session != "" {
+			ctx = logging.WithSessionID(ctx, session)
+		}
+		return next(ctx, request)
+	}
+}
+
+// Middleware counts each call against its session and runs mutating calls
+// one at a time; scoped writers lock only around their writes
+func (sg *SessionGuard) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		session := undoSession(ctx)
+		mutating := sg.Mutating(request)
+		sg.begin(session, mutating)
+		defer sg.end(session)
+
+		if !mutating {
+			return next(ctx, request)
+		}
+		if scopedWriteTools[sg.config().Tools.BaseName(request.Params.Name)] {
+			return next(context.WithValue(ctx, writeLockKey{}, &sg.writes), request)
+		}
+
+		start := time.Now()
+		sg.writes.Lock()
+		defer sg.writes.Unlock()
+		if waited := time.Since(start); waited >= slowWriteWait {
+			slog.InfoContext(ctx, "tool call waited for other sessions' changes", "tool", request.Params.Name, "waited", waited)
+		}
+		return next(ctx, request)
+	}
+}
+
+// Mutating reports whether a call can change buddy files
+func (sg *SessionGuard) Mutating(request mcp.CallToolRequest) bool {
+	name := sg.config().Tools.BaseName(request.Params.Name)
+	actions, known := readOnlyActions[name]
+	if !known {
+		return true
+	}
+	if actions == nil {
+		return false
+	}
+	action, _ := request.GetArguments()["action"].(string)
+	for _, readOnly := range actions {
+		if action == readOnly {
+			return false
+		}
+	}
+	return true
+}
+
+// begin counts a call as started by session
+func (sg *SessionGuard) begin(session string, mutating bool) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	now := time.Now().UTC()
+	info := sg.sessions[session]
+	if info == nil {
+		info = &SessionInfo{ID: session, FirstCall: now}
+		sg.sessions[session] = info
+	}
+	info.LastCall = now
+	info.Calls++
+	info.Running++
+	if mutating {
+		info.Writes++
+	}
+}
+
+// end marks a call of session as finished
+func (sg *SessionGuard) end(session string) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	if info := sg.sessions[session]; info != nil {
+		info.Running--
+	}
+}
+
+// Sessions returns the sessions that have called tools, most recently
+// active first
+func (sg *SessionGuard) Sessions() []SessionInfo {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	sessions := make([]SessionInfo, 0, len(sg.sessions))
+	for _, info := range sg.sessions {
+		sessions = append(sessions, *info)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastCall.Equal(sessions[j].LastCall) {
+			return sessions[i].LastCall.After(sessions[j].LastCall)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// samplingSession is a client session that declares sampling support
+type samplingSession struct{ testSession }
+
+func (s samplingSession) RequestSampling(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	return nil, nil
+}
+
+func TestSessionGuard_CaptureSamplingDoesNotHoldWriteLock(t *testing.T) {
+	bh, buddyPath := newTestBuddyHandlers(t, map[string]string{
+		"history/h1.json": `{"id":"h1","timestamp":"2024-03-01T00:00:00Z","feature":"billing","description":"Added invoices","changes":[{"file_path":"billing.go","change_type":"created"}]}`,
+	})
+
+	// The slow session's client never answers until released
+	sampling := make(chan struct{})
+	release := make(chan struct{})
+	bh.SetSampler(&Sampler{
+		sessions: map[string]bool{"slow": true, "fast": true},
+		timeout:  time.Minute,
+		request: func(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+			if undoSession(ctx) == "slow" {
+				close(sampling)
+				select {
+				case <-release:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+			result := &mcp.CreateMessageResult{Model: "test"}
+			result.Role = mcp.RoleAssistant
+			result.Content = mcp.NewTextContent("Invoices were added.")
+			return result, nil
+		},
+	})
+
+	guard := NewSessionGuard(bh.Config)
+	handler := guard.Middleware(bh.GetCaptureSessionToolHandler())
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	call := func(session string) error {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = "buddy_capture_session"
+		request.Params.Arguments = map[string]any{"feature": "billing"}
+		ctx := mcpServer.WithContext(context.Background(), samplingSession{testSession{id: session}})
+		_, err := handler(ctx, request)
+		return err
+	}
+
+	slow := make(chan error, 1)
+	go func() { slow <- call("slow") }()
+	<-sampling
+
+	fast := make(chan error, 1)
+	go func() { fast <- call("fast") }()
+	select {
+	case err := <-fast:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("capture waited on another session's sampling")
+	}
+
+	close(release)
+	require.NoError(t, <-slow)
+
+	drafts, err := os.ReadDir(filepath.Join(buddyPath, "drafts", "knowledge"))
+	require.NoError(t, err)
+	assert.Len(t, drafts, 2)
+	for _, info := range guard.Sessions() {
+		assert.Equal(t, 1, info.Writes, info.ID)
+	}
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/slug"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// Answers the setup wizard accepts
+var (
+	setupLanguages = []string{"go", "typescript", "python", "java", "rust", "other"}
+	setupDatabases = []string{"postgresql", "mysql", "sqlite", "mongodb", "none"}
+)
+
+// setupQuestions is the wizard's questionnaire, in the requestedSchema
+// shape of MCP elicitation so a client can render it as a form
+var setupQuestions = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"project_name": map[string]interface{}{
+			"type":        "string",
+			"title":       "Project name",
+			"description": "Name used in the generated project overview",
+		},
+		"language": map[string]interface{}{
+			"type":        "string",
+			"title":       "Primary language",
+			"description": "Language most of the code is written in",
+			"enum":        setupLanguages,
+		},
+		"database": map[string]interface{}{
+			"type":        "string",
+			"title":       "Database",
+			"description": "Main database the project uses",
+			"enum":        setupDatabases,
+		},
+		"conventions": map[string]interface{}{
+			"type":        "string",
+			"title":       "Team conventions",
+			"description": "Conventions the AI should follow, one per line, e.g. 'use zap for logging'",
+		},
+	},
+	"required": []string{"language"},
+}
+
+// SetupAnswers are the user's answers to the setup questions
+type SetupAnswers struct {
+	ProjectName string
+	Language    string
+	Database    string
+	Conventions []string
+}
+
+// starterRule is one rule generated for a language
+type starterRule struct {
+	Title    string
+	Category string
+	Priority string
+	Body     string
+}
+
+// starterRules are the rules generated for each primary language
+var starterRules = map[string][]starterRule{
+	"go": {
+		{"Go Formatting and Style", "style", "critical", "- Format all code with gofmt and keep imports grouped: standard library, third party, internal.\n- Name packages with short lowercase words and avoid stutter (log.Logger, not log.LogLogger)."},
+		{"Go Error Handling", "errors", "critical", "- Check every returned error.\n- Wrap errors with context: fmt.Errorf(\"failed to load config: %w\", err).\n- Don't panic in library code."},
+		{"Go Testing", "testing", "recommended", "- Put tests next to the code in _test.go files.\n- Prefer table-driven tests and t.TempDir() for file system fixtures."},
+	},
+	"typescript": {
+		{"TypeScript Strictness", "style", "critical", "- Keep \"strict\": true in tsconfig.json.\n- Avoid any; use unknown and narrow it.\n- Prefer type-only imports for types."},
+		{"TypeScript Error Handling", "errors", "critical", "- Never swallow rejected promises; await them or handle .catch.\n- Throw Error subclasses, not strings."},
+		{"TypeScript Testing", "testing", "recommended", "- Co-locate *.test.ts files with the code under test.\n- Mock network calls at the boundary, not deep inside modules."},
+	},
+	"python": {
+		{"Python Style", "style", "critical", "- Follow PEP 8 and format with black.\n- Add type hints to public functions and check them with mypy."},
+		{"Python Error Handling", "errors", "critical", "- Catch specific exceptions, never a bare except.\n- Re-raise with context: raise ConfigError(...) from err."},
+		{"Python Testing", "testing", "recommended", "- Write tests with pytest under tests/.\n- Use fixtures instead of module-level setup."},
+	},
+	"java": {
+		{"Java Style", "style", "critical", "- Follow the Google Java Style Guide.\n- Prefer immutable value classes (records) for data."},
+		{"Java Error Handling", "errors", "critical", "- Don't catch Exception or Throwable broadly.\n- Use try-with-resources for anything Closeable."},
+		{"Java Testing", "testing", "recommended", "- Write JUnit 5 tests mirroring the main package layout.\n- Prefer constructor injection so classes are easy to test."},
+	},
+	"rust": {
+		{"Rust Style", "style", "critical", "- Format with rustfmt and keep clippy warnings at zero.\n- Prefer borrowing over cloning."},
+		{"Rust Error Handling", "errors", "critical", "- Return Result instead of panicking; avoid unwrap outside tests.\n- Add context to errors as they cross module boundaries."},
+		{"Rust Testing", "testing", "recommended", "- Put unit tests in a #[cfg(test)] module next to the code.\n- Put integration tests in tests/."},
+	},
+	"other": {
+		{"Code Style", "style", "recommended", "- Follow the formatter and linter configured for the project.\n- Keep functions small and names descriptive."},
+	},
+}
+
+// StarterFile is one generated file, relative to the buddy folder
+type StarterFile struct {
+	Path    string
+	Content string
+}
+
+// StarterFiles returns starter rules, knowledge and todos tailored to the
+// answers. Without a project name, the project is named after the
+// directory holding buddyPath.
+func StarterFiles(buddyPath string, answers SetupAnswers) ([]StarterFile, error) {
+	rules, ok := starterRules[answers.Language]
+	if !ok {
+		return nil, fmt.Errorf("invalid language: %s (expected one of %s)", answers.Language, strings.Join(setupLanguages, ", "))
+	}
+	if answers.Database == "" {
+		answers.Database = "none"
+	}
+	if !slices.Contains(setupDatabases, answers.Database) {
+		return nil, fmt.Errorf("invalid database: %s (expected one of %s)", answers.Database, strings.Join(setupDatabases, ", "))
+	}
+	if answers.ProjectName == "" {
+		if absPath, err := filepath.Abs(buddyPath); err == nil {
+			answers.ProjectName = filepath.Base(filepath.Dir(absPath))
+		}
+	}
+
+	var files []StarterFile
+	add := func(path, content string) {
+		files = append(files, StarterFile{Path: path, Content: content})
+	}
+
+	for _, rule := range rules {
+		add(filepath.Join("rules", slug.Make(rule.Title, 0, "rule")+".md"),
+			fmt.Sprintf("# %s\nCategory: %s\nPriority: %s\n\n%s\n", rule.Title, rule.Category, rule.Priority, rule.Body))
+	}
+
+	if len(answers.Conventions) > 0 {
+		var sb strings.Builder
+		sb.WriteString("# Team Conventions\nCategory: conventions\nPriority: critical\n\n")
+		for _, convention := range answers.Conventions {
+			sb.WriteString("- " + convention + "\n")
+		}
+		add(filepath.Join("rules", "team-conventions.md"), sb.String())
+	}
+
+	var overview strings.Builder
+	overview.WriteString(fmt.Sprintf("# %s Overview\nCategory: project\nTags: overview, %s\n\n", answers.ProjectName, answers.Language))
+	overview.WriteString("## Stack\n\n")
+	overview.WriteString(fmt.Sprintf("- Primary language: %s\n", answers.Language))
+	overview.WriteString(fmt.Sprintf("- Database: %s\n\n", answers.Database))
+	overview.WriteString("## Architecture\n\nTODO: describe the main components and how they talk to each other.\n")
+	add(filepath.Join("knowledge", "project-overview.md"), overview.String())
+
+	tasks := []string{"Describe the architecture in knowledge/project-overview.md", "Review the generated rules and adjust priorities"}
+	if answers.Database != "none" {
+		add(filepath.Join("database", "connection.md"),
+			fmt.Sprintf("# Database Connection\n\nType: %s\n\nTODO: document how to connect locally. Never commit credentials.\n", answers.Database))
+		tasks = append(tasks, "Export the schema to database/schema.sql")
+	}
+	var todo strings.Builder
+	todo.WriteString("# Feature: Buddy Onboarding\n\n")
+	for _, task := range tasks {
+		todo.WriteString("- [ ] " + task + "\n")
+	}
+	add(filepath.Join("todos", "onboarding.md"), todo.String())
+
+	return files, nil
+}
+
+// writeStarterFiles writes files under buddyPath, skipping any that
+// already exist, and returns the paths written
+func writeStarterFiles(ctx context.Context, store storage.Storage, buddyPath string, files []StarterFile) ([]string, error) {
+	var written []string
+	for _, file := range files {
+		path := filepath.Join(buddyPath, file.Path)
+		if _, err := store.Stat(path); !storage.IsNotExist(err) {
+			continue
+		}
+		if err := writeFile(ctx, store, path, []byte(file.Content)); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// GenerateStarterContent writes starter rules, knowledge and todos tailored
+// to the answers, then reloads. Existing files are never overwritten; the
+// paths written are returned.
+func (bh *BuddyHandlers) GenerateStarterContent(ctx context.Context, answers SetupAnswers) ([]string, error) {
+	files, err := StarterFiles(bh.buddyPath, answers)
+	if err != nil {
+		return nil, err
+	}
+
+	written, err := writeStarterFiles(ctx, bh.store, bh.buddyPath, files)
+	if err != nil {
+		return written, err
+	}
+	return written, bh.ReloadData()
+}
+
+// exampleSchema shows the schema format the database parser reads
+const exampleSchema = `-- Example schema: replace it with your real CREATE TABLE statements.
+-- Tables are read from CREATE TABLE statements and indexes from CREATE INDEX.
+-- Flag columns holding personal data with a "-- pii" comment so their
+-- values are redacted.
+
+CREATE TABLE example_users (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) UNIQUE NOT NULL, -- pii
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_example_users_email ON example_users(email);
+`
+
+// exampleConfig lists a few optional settings, all commented out so the
+// defaults apply until the user picks some
+const exampleConfig = `# Optional settings for this buddy folder. Everything below is commented
+# out, so the defaults apply; see the Configuration section of the README
+# for the rest.
+
+# tools:
+#   prefix: acme_            # prepended to every tool name
+#   disable: [buddy_backup]  # tools never registered
+#
+# paths:
+#   include: ["src/**"]
+#   exclude: ["vendor/**", "node_modules/**"]
+#
+# limits:
+#   max_file_kb: 1024
+#
+# todos:
+#   auto_archive: true
+`
+
+// InitBuddyFolder creates the buddy directory structure at buddyPath with
+// starter content, an example schema and a commented config.yaml, so new
+// users see the formats the parsers expect. It works on the files directly, without loading them,
+// and never overwrites existing files; the paths written are returned.
+func InitBuddyFolder(buddyPath string, answers SetupAnswers) ([]string, error) {
+	files, err := StarterFiles(buddyPath, answers)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files,
+		StarterFile{Path: filepath.Join("database", "schema.sql"), Content: exampleSchema},
+		StarterFile{Path: config.FileName, Content: exampleConfig})
+
+	if err := createBuddyStructure(buddyPath); err != nil {
+		return nil, fmt.Errorf("failed to create buddy structure: %w", err)
+	}
+	return writeStarterFiles(context.Background(), storage.NewLocal(), buddyPath, files)
+}
+
+// SetupArgs are the arguments of buddy_setup
+type SetupArgs struct {
+	Language    string `arg:"language" enum:"go,typescript,python,java,rust,other" desc:"Primary language of the project (omit to get the questions)"`
+	Database    string `arg:"database" enum:"postgresql,mysql,sqlite,mongodb,none" desc:"Main database (default: none)"`
+	ProjectName string `arg:"project_name" desc:"Project name for the overview (default: the directory holding .buddy)"`
+	Conventions string `arg:"conventions" desc:"Team conventions, one per line (optional)"`
+	Force       bool   `arg:"force" desc:"Add missing starter files even though the folder already has content (optional)"`
+}
+
+// GetSetupToolHandler returns the tool handler for the onboarding wizard.
+// Called without answers it returns the questions to put to the user;
+// called with them it generates starter content.
+func (bh *BuddyHandlers) GetSetupToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args SetupArgs) (*mcp.CallToolResult, error) {
+		if !bh.Snapshot().Empty() && !args.Force {
+			return mcp.NewToolResultText("ℹ️ This .buddy folder already has content, so setup was skipped.\n\n💡 Call buddy_setup with force: true to add the starter files that are still missing"), nil
+		}
+
+		if args.Language == "" {
+			questions, err := json.MarshalIndent(setupQuestions, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal setup questions: %w", err)
+			}
+
+			result := "🧭 Buddy setup\n\n"
+			result += "This project's .buddy folder is empty. Ask the user these questions, then call buddy_setup again with the answers as arguments:\n\n"
+			result += string(questions)
+			return mcp.NewToolResultText(result), nil
+		}
+
+		answers := SetupAnswers{Language: args.Language, ProjectName: args.ProjectName, Database: args.Database}
+		if args.Conventions != "" {
+			for _, line := range strings.Split(args.Conventions, "\n") {
+				if line = strings.TrimSpace(strings.TrimLeft(line, "-* ")); line != "" {
+					answers.Conventions = append(answers.Conventions, line)
+				}
+			}
+		}
+
+		written, err := bh.GenerateStarterContent(ctx, answers)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(written) == 0 {
+			return mcp.NewToolResultText("✅ All starter files already exist; nothing was written"), nil
+		}
+		result := fmt.Sprintf("✅ Generated %d starter files\n\n", len(written))
+		for _, path := range written {
+			result += fmt.Sprintf("- %s\n", path)
+		}
+		result += "\n💡 Review the rules and fill in the TODOs in knowledge/project-overview.md"
+
+		return mcp.NewToolResultText(result), nil
+	})
+}
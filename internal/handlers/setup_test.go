@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callSetup calls buddy_setup with arguments and returns its text
+func callSetup(t *testing.T, bh *BuddyHandlers, arguments map[string]any) (string, error) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "buddy_setup"
+	request.Params.Arguments = arguments
+	result, err := bh.GetSetupToolHandler()(context.Background(), request)
+	if err != nil {
+		return "", err
+	}
+	return result.Content[0].(mcp.TextContent).Text, nil
+}
+
+func TestSetup_Questions(t *testing.T) {
+	bh, _ := newTestBuddyHandlers(t, nil)
+
+	text, err := callSetup(t, bh, nil)
+	require.NoError(t, err)
+	assert.Contains(t, text, "Ask the user these questions")
+
+	// The questions are an elicitation requestedSchema
+	var questions struct {
+		Type       string `json:"type"`
+		Properties map[string]struct {
+			Type string   `json:"type"`
+			Enum []string `json:"enum"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(text[strings.Index(text, "{"):]), &questions))
+	assert.Equal(t, "object", questions.Type)
+	assert.Equal(t, []string{"language"}, questions.Required)
+	require.Len(t, questions.Properties, 4)
+	for name, property := range questions.Properties {
+		assert.Equal(t, "string", property.Type, name)
+	}
+	assert.Equal(t, setupLanguages, questions.Properties["language"].Enum)
+	assert.Equal(t, setupDatabases, questions.Properties["database"].Enum)
+	assert.Empty(t, questions.Properties["conventions"].Enum)
+
+	// Every language offered has starter rules
+	for _, language := range setupLanguages {
+		assert.NotEmpty(t, starterRules[language], language)
+	}
+}
+
+func TestSetup_AppliesAnswers(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments map[string]any
+		rules     int
+		files     []string // expected besides the rules and the overview
+		missing   []string
+		contains  map[string]string // file to text it holds
+		wantErr   string
+	}{
+		{
+			name:      "language only",
+			arguments: map[string]any{"language": "go"},
+			rules:     3,
+			files:     []string{"todos/onboarding.md"},
+			missing:   []string{"database/connection.md", "rules/team-conventions.md"},
+			contains: map[string]string{
+				"knowledge/project-overview.md": "- Database: none\n",
+			},
+		},
+		{
+			name: "every answer",
+			arguments: map[string]any{
+				"language":     "python",
+				"database":     "postgresql",
+				"project_name": "shop",
+				"conventions":  "- use structlog\n* no ORMs\n\n   pin dependencies  \n",
+			},
+			rules: 4, // three for Python and the conventions
+			files: []string{"database/connection.md", "rules/team-conventions.md"},
+			contains: map[string]string{
+				"rules/team-conventions.md":     "Priority: critical\n\n- use structlog\n- no ORMs\n- pin dependencies\n",
+				"knowledge/project-overview.md": "# shop Overview\n",
+				"database/connection.md":        "Type: postgresql\n",
+				"todos/onboarding.md":           "- [ ] Export the schema to database/schema.sql\n",
+			},
+		},
+		{
+			name:      "unknown language",
+			arguments: map[string]any{"language": "cobol"},
+			wantErr:   "cobol",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bh, buddyPath := newTestBuddyHandlers(t, nil)
+
+			text, err := callSetup(t, bh, tt.arguments)
+			if tt.wantErr != "" {
+				if err == nil {
+					assert.Contains(t, text, tt.wantErr)
+				} else {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				assert.True(t, bh.Snapshot().Empty())
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, text, "Generated")
+
+			assert.Len(t, bh.Snapshot().Rules, tt.rules)
+			for _, file := range append(tt.files, "knowledge/project-overview.md") {
+				assert.FileExists(t, filepath.Join(buddyPath, file))
+			}
+			for _, file := range tt.missing {
+				assert.NoFileExists(t, filepath.Join(buddyPath, file))
+			}
+			for file, want := range tt.contains {
+				content, err := os.ReadFile(filepath.Join(buddyPath, file))
+				require.NoError(t, err)
+				assert.Contains(t, string(content), want, file)
+			}
+		})
+	}
+}
+
+func TestSetup_SkipsFolderWithContent(t *testing.T) {
+	bh, buddyPath := newTestBuddyHandlers(t, map[string]string{
+		"rules/go-error-handling.md": "# Our Error Handling\nCategory: errors\nPriority: critical\n\n- Use errors.Join\n",
+	})
+
+	text, err := callSetup(t, bh, map[string]any{"language": "go"})
+	require.NoError(t, err)
+	assert.Contains(t, text, "setup was skipped")
+	assert.NoFileExists(t, filepath.Join(buddyPath, "todos", "onboarding.md"))
+
+	// With force the missing files are added and the existing one kept
+	text, err = callSetup(t, bh, map[string]any{"language": "go", "force": true})
+	require.NoError(t, err)
+	assert.Contains(t, text, "Generated")
+	assert.FileExists(t, filepath.Join(buddyPath, "todos", "onboarding.md"))
+	content, err := os.ReadFile(filepath.Join(buddyPath, "rules", "go-error-handling.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "errors.Join")
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultMaxFileKB is the size limit of one buddy file when none is configured
+const DefaultMaxFileKB = 1024
+
+// OversizedFile is a buddy file over the configured size limit
+type OversizedFile struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Limit     int64  `json:"limit"`
+	Truncated bool   `json:"truncated"` // only the first Limit bytes were loaded
+}
+
+// maxFileBytes converts the configured limit in KB to bytes. Zero means
+// the default and a negative value turns the limit off.
+func maxFileBytes(kb int) int64 {
+	switch {
+	case kb == 0:
+		return DefaultMaxFileKB * 1024
+	case kb < 0:
+		return 0
+	}
+	return int64(kb) * 1024
+}
+
+// truncationNotice explains in a document why its content ends early
+func truncationNotice(size, limit int64) string {
+	return fmt.Sprintf("⚠️ Truncated: this file is %d KB, over the %d KB limit (limits.max_file_kb in config.yaml), so only its beginning was loaded. Split it into smaller files to load all of it.",
+		(size+1023)/1024, limit/1024)
+}
+
+// cutAtLine returns the longest prefix of content up to limit bytes that
+// ends at a line break, falling back to a rune boundary for a first line
+// longer than limit
+func cutAtLine(content []byte, limit int64) []byte {
+	if int64(len(content)) <= limit {
+		return content
+	}
+	head := content[:limit]
+	if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+		return head[:i+1]
+	}
+	for len(head) > 0 && !utf8.Valid(head) {
+		head = head[:len(head)-1]
+	}
+	return head
+}
+
+// truncateText keeps the beginning of a text document and appends a
+// paragraph with the notice, so readers of the document see it
+func truncateText(content []byte, limit int64, notice string) []byte {
+	kept := bytes.TrimRight(cutAtLine(content, limit), "\r\n")
+	return append(append(kept[:len(kept):len(kept)], "\n\n"...), notice+"\n"...)
+}
+
+// truncateRows keeps the whole lines of a tabular file that fit the
+// limit. The notice can't go in the file without becoming a row.
+func truncateRows(content []byte, limit int64, notice string) []byte {
+	return cutAtLine(content, limit)
+}
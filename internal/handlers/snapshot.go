@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+)
+
+// ContextSnapshot is an immutable point-in-time view of all buddy content.
+// It is only published once no reload is in flight, so every field comes
+// from the same generation of files.
+type ContextSnapshot struct {
+	Rules     []models.Rule
+	Knowledge []models.Knowledge
+	Todos     []models.Todo
+	History   []models.HistoryEntry
+	Database  *models.DatabaseInfo
+	Backups   []models.Backup
+	TakenAt   time.Time
+}
+
+// RecentHistory returns up to limit of the newest history entries
+func (cs *ContextSnapshot) RecentHistory(limit int) []models.HistoryEntry {
+	if limit > len(cs.History) {
+		limit = len(cs.History)
+	}
+	return cs.History[:limit]
+}
+
+// Empty reports whether the snapshot holds no rules, knowledge, todos or
+// database schema, as in a freshly created buddy folder
+func (cs *ContextSnapshot) Empty() bool {
+	hasSchema := cs.Database != nil && len(cs.Database.Tables) > 0
+	return len(cs.Rules) == 0 && len(cs.Knowledge) == 0 && len(cs.Todos) == 0 && !hasSchema
+}
+
+// Snapshot returns the latest consistent view of all content
+func (bh *BuddyHandlers) Snapshot() *ContextSnapshot {
+	if snap := bh.snapshot.Load(); snap != nil {
+		return snap
+	}
+	return bh.captureSnapshot()
+}
+
+// captureSnapshot copies the current state of every handler. Slices are
+// copied so in-place updates (e.g. todo status) don't leak into it.
+func (bh *BuddyHandlers) captureSnapshot() *ContextSnapshot {
+	return &ContextSnapshot{
+		Rules:     append([]models.Rule(nil), bh.rulesHandler.GetRules()...),
+		Knowledge: append([]models.Knowledge(nil), bh.knowledgeHandler.GetKnowledge()...),
+		Todos:     append([]models.Todo(nil), bh.todoHandler.GetTodos()...),
+		History:   append([]models.HistoryEntry(nil), bh.historyHandler.GetHistory()...),
+		Database:  bh.databaseHandler.GetDatabaseInfo(),
+		Backups:   append([]models.Backup(nil), bh.backupHandler.ListBackups("")...),
+		TakenAt:   time.Now().UTC(),
+	}
+}
+
+// beginReload marks a reload as in flight, holding back new snapshots
+func (bh *BuddyHandlers) beginReload() {
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+	bh.reloadsInFlight++
+}
+
+// endReload marks a reload as finished. When it was the last one in
+// flight, a new snapshot is captured, diffed into the change log and published.
+func (bh *BuddyHandlers) endReload() {
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+
+	bh.reloadsInFlight--
+	if bh.reloadsInFlight > 0 {
+		return
+	}
+	if bh.reloadsIdle != nil {
+		bh.reloadsIdle.Broadcast()
+	}
+
+	// Changes are recorded before publishing so a resource cached against
+	// the new snapshot never misses them
+	snap := bh.captureSnapshot()
+	bh.recordChanges(snap)
+	bh.snapshot.Store(snap)
+
+	if err := bh.timeline.Record(snap); err != nil {
+		slog.Warn("failed to record timeline", "error", err)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBuddyHandlers returns loaded handlers over a buddy folder holding files
+func newTestBuddyHandlers(t *testing.T, files map[string]string) (*BuddyHandlers, string) {
+	t.Helper()
+	buddyPath := filepath.Join(t.TempDir(), ".buddy")
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(buddyPath, name), content)
+	}
+	bh, err := NewBuddyHandlers(buddyPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { bh.Close() })
+	return bh, buddyPath
+}
+
+func TestSnapshot_ReadDuringReloadSeesOneGeneration(t *testing.T) {
+	bh, buddyPath := newTestBuddyHandlers(t, map[string]string{
+		"rules/style.md":    "# Style\nCategory: style\nPriority: critical\n\n- Use gofmt\n",
+		"knowledge/auth.md": "# Authentication\nCategory: security\n\nSessions live in Redis.\n",
+	})
+	before := bh.Snapshot()
+	require.Len(t, before.Rules, 1)
+	require.Len(t, before.Knowledge, 1)
+
+	writeTestFile(t, filepath.Join(buddyPath, "rules", "errors.md"), "# Errors\nCategory: style\nPriority: critical\n\n- Wrap errors\n")
+	writeTestFile(t, filepath.Join(buddyPath, "knowledge", "cache.md"), "# Caching\nCategory: performance\n\nResponses are cached.\n")
+
+	// Sections load one by one; until the last finishes, reads keep the
+	// snapshot from before the reload rather than a mix
+	bh.beginReload()
+	require.NoError(t, bh.rulesHandler.Load())
+	assert.Len(t, bh.rulesHandler.GetRules(), 2)
+	assert.Same(t, before, bh.Snapshot())
+
+	require.NoError(t, bh.knowledgeHandler.Load())
+	assert.Same(t, before, bh.Snapshot())
+	bh.endReload()
+
+	after := bh.Snapshot()
+	assert.NotSame(t, before, after)
+	assert.Len(t, after.Rules, 2)
+	assert.Len(t, after.Knowledge, 2)
+}
+
+// readContext reads buddy://project-context, with etag as if_none_match
+// when given, and returns the decoded body
+func readContext(t *testing.T, bh *BuddyHandlers, etag string) map[string]any {
+	t.Helper()
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = "buddy://project-context"
+	if etag != "" {
+		request.Params.Arguments = map[string]any{"if_none_match": etag}
+	}
+	contents, err := bh.GetProjectContextResourceHandler()(context.Background(), request)
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &body))
+	return body
+}
+
+func TestReadCachedResource_IfNoneMatch(t *testing.T) {
+	bh, buddyPath := newTestBuddyHandlers(t, map[string]string{
+		"rules/style.md": "# Style\nCategory: style\nPriority: critical\n\n- Use gofmt\n",
+	})
+
+	body := readContext(t, bh, "")
+	etag, _ := body["etag"].(string)
+	require.NotEmpty(t, etag)
+	assert.Contains(t, body, "rules")
+
+	// The client's copy is current
+	assert.Equal(t, map[string]any{"etag": etag, "not_modified": true}, readContext(t, bh, etag))
+
+	// A stale ETag gets the full body
+	body = readContext(t, bh, `"stale"`)
+	assert.Equal(t, etag, body["etag"])
+	assert.NotContains(t, body, "not_modified")
+
+	// Once content changes, the old ETag no longer matches
+	writeTestFile(t, filepath.Join(buddyPath, "rules", "errors.md"), "# Errors\nCategory: style\nPriority: critical\n\n- Wrap errors\n")
+	require.NoError(t, bh.ReloadData())
+	body = readContext(t, bh, etag)
+	assert.NotContains(t, body, "not_modified")
+	assert.NotEqual(t, etag, body["etag"])
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/config"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+)
+
+// Search index states reported by Stats
+const (
+	IndexOK      = "ok"
+	IndexMissing = "missing" // built when the server next starts
+	IndexBehind  = "behind"  // files changed after the index was last written
+	IndexDamaged = "damaged" // the index has no readable metadata
+)
+
+// indexMetaFile is the file every bleve index directory starts with
+const indexMetaFile = "index_meta.json"
+
+// SectionStats describes one kind of buddy content
+type SectionStats struct {
+	Name      string     `json:"name"`
+	Files     int        `json:"files"`
+	Documents int        `json:"documents"`
+	Bytes     int64      `json:"bytes"`
+	Newest    time.Time  `json:"newest,omitempty"` // latest file modification
+	Oldest    time.Time  `json:"oldest,omitempty"` // earliest file modification
+	Stale     int        `json:"stale"`            // files unchanged for longer than the report's StaleDays
+	Detail    string     `json:"detail,omitempty"` // a section-specific summary, e.g. open todos
+	Error     string     `json:"error,omitempty"`  // why the section failed to load
+	Index     IndexStats `json:"index"`
+}
+
+// IndexStats describes a section's search index on disk
+type IndexStats struct {
+	Status  string    `json:"status"`
+	Bytes   int64     `json:"bytes"`
+	Updated time.Time `json:"updated,omitempty"` // last write to any index file
+}
+
+// StatsReport is the outcome of measuring a buddy folder
+type StatsReport struct {
+	BuddyPath   string         `json:"buddy_path"`
+	IndexDir    string         `json:"index_dir"`
+	StaleDays   int            `json:"stale_days"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Sections    []SectionStats `json:"sections"`
+}
+
+// Stats counts and measures the rules, knowledge, todos, history and
+// backups of a buddy folder and checks each section's search index. Files
+// unchanged for more than staleDays are counted as stale. Like Diagnose
+// it reads the files directly and never opens the search indexes, so it
+// can run while a server is using the folder.
+func Stats(buddyPath string, staleDays int) (*StatsReport, error) {
+	return StatsWithStorage(buddyPath, staleDays, storage.NewLocal())
+}
+
+// StatsWithStorage is Stats reading the buddy files and indexes through
+// store
+func StatsWithStorage(buddyPath string, staleDays int, store storage.Storage) (*StatsReport, error) {
+	if info, err := store.Stat(buddyPath); err != nil {
+		return nil, fmt.Errorf("buddy folder not found: %w", err)
+	} else if !info.IsDir {
+		return nil, fmt.Errorf("%s is not a directory", buddyPath)
+	}
+	cfg, err := config.Load(buddyPath)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := indexDir(buddyPath, cfg.Search)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	report := &StatsReport{BuddyPath: buddyPath, IndexDir: indexes, StaleDays: staleDays, GeneratedAt: now}
+	staleBefore := now.AddDate(0, 0, -staleDays)
+	maxFileSize := maxFileBytes(cfg.Limits.MaxFileKB)
+
+	rules := NewRulesHandler(filepath.Join(buddyPath, "rules"), nil)
+	rules.store = store
+	rules.SetMaxFileSize(maxFileSize)
+	report.Sections = append(report.Sections, documentStats("rules", rules.DocumentHandler, staleBefore, func() string {
+		critical := 0
+		for _, rule := range rules.GetRules() {
+			if rule.Priority == "critical" {
+				critical++
+			}
+		}
+		return fmt.Sprintf("%d critical", critical)
+	}))
+
+	knowledge := NewKnowledgeHandler(filepath.Join(buddyPath, "knowledge"), nil)
+	knowledge.store = store
+	knowledge.fields.Store(cfg.Knowledge.Fields)
+	knowledge.SetMaxFileSize(maxFileSize)
+	report.Sections = append(report.Sections, documentStats("knowledge", knowledge.DocumentHandler, staleBefore, func() string {
+		categories := make(map[string]bool)
+		for _, doc := range knowledge.GetKnowledge() {
+			categories[doc.Category] = true
+		}
+		return fmt.Sprintf("%d categories", len(categories))
+	}))
+
+	todos := NewTodoHandler(filepath.Join(buddyPath, "todos"), nil)
+	todos.store = store
+	todos.SetMaxFileSize(maxFileSize)
+	report.Sections = append(report.Sections, documentStats("todos", todos.DocumentHandler, staleBefore, func() string {
+		open, archived := 0, 0
+		for _, todo := range todos.GetTodos() {
+			if !todo.Completed {
+				open++
+			}
+			if todo.Archived {
+				archived++
+			}
+		}
+		return fmt.Sprintf("%d open, %d archived", open, archived)
+	}))
+
+	history := NewHistoryHandler(filepath.Join(buddyPath, "history"), nil)
+	history.store = store
+	history.SetMaxFileSize(maxFileSize)
+	report.Sections = append(report.Sections, documentStats("history", history.DocumentHandler, staleBefore, func() string {
+		recent := history.GetRecentHistory(1)
+		if len(recent) == 0 {
+			return ""
+		}
+		return "latest entry " + recent[0].Timestamp.Format("2006-01-02")
+	}))
+
+	report.Sections = append(report.Sections, backupStats(store, filepath.Join(buddyPath, "backups"), staleBefore))
+
+	for i := range report.Sections {
+		section := &report.Sections[i]
+		section.Index = indexStats(store, filepath.Join(indexes, section.Name), section.Newest)
+	}
+	return report, nil
+}
+
+// documentStats loads a document section and measures its files. detail
+// summarizes the loaded documents; it isn't called when the load fails.
+func documentStats[T any](name string, dh *DocumentHandler[T], staleBefore time.Time, detail func() string) SectionStats {
+	stats := SectionStats{Name: name}
+	files, err := dh.store.List(dh.path, dh.spec.Recursive)
+	if err != nil && !storage.IsNotExist(err) {
+		stats.Error = err.Error()
+		return stats
+	}
+	for _, file := range files {
+		if file.IsDir || !dh.matchesExtension(file.Path) {
+			continue
+		}
+		stats.addFile(file.Size, file.ModTime, staleBefore)
+	}
+
+	if err := dh.Load(); err != nil {
+		stats.Error = err.Error()
+		return stats
+	}
+	stats.Documents = len(dh.Documents())
+	stats.Detail = detail()
+	return stats
+}
+
+// backupStats measures the backups directory, counting a document per
+// backup record
+func backupStats(store storage.Storage, backupsPath string, staleBefore time.Time) SectionStats {
+	stats := SectionStats{Name: "backups"}
+	files, err := store.List(backupsPath, true)
+	if err != nil && !storage.IsNotExist(err) {
+		stats.Error = err.Error()
+		return stats
+	}
+	for _, file := range files {
+		stats.addFile(file.Size, file.ModTime, staleBefore)
+	}
+
+	content, err := store.Read(filepath.Join(backupsPath, "metadata.json"))
+	if storage.IsNotExist(err) {
+		return stats
+	}
+	var backups []models.Backup
+	if err == nil {
+		err = json.Unmarshal(content, &backups)
+	}
+	if err != nil {
+		stats.Error = "invalid backup metadata: " + err.Error()
+		return stats
+	}
+	stats.Documents = len(backups)
+	if len(backups) > 0 {
+		oldest := backups[0].Timestamp
+		for _, backup := range backups[1:] {
+			if backup.Timestamp.Before(oldest) {
+				oldest = backup.Timestamp
+			}
+		}
+		stats.Detail = "oldest backup " + oldest.Format("2006-01-02")
+	}
+	return stats
+}
+
+// addFile counts one file of a section
+func (s *SectionStats) addFile(size int64, modTime time.Time, staleBefore time.Time) {
+	s.Files++
+	s.Bytes += size
+	if modTime.After(s.Newest) {
+		s.Newest = modTime
+	}
+	if s.Oldest.IsZero() || modTime.Before(s.Oldest) {
+		s.Oldest = modTime
+	}
+	if modTime.Before(staleBefore) {
+		s.Stale++
+	}
+}
+
+// indexStats checks the index directory of a section whose newest file
+// changed at newest
+func indexStats(store storage.Storage, dir string, newest time.Time) IndexStats {
+	stats := IndexStats{Status: IndexOK}
+	if _, err := store.Stat(filepath.Join(dir, indexMetaFile)); err != nil {
+		stats.Status = IndexDamaged
+		if _, err := store.Stat(dir); storage.IsNotExist(err) {
+			stats.Status = IndexMissing
+		}
+		return stats
+	}
+	files, _ := store.List(dir, true)
+	for _, file := range files {
+		stats.Bytes += file.Size
+		if file.ModTime.After(stats.Updated) {
+			stats.Updated = file.ModTime
+		}
+	}
+	if newest.After(stats.Updated) {
+		stats.Status = IndexBehind
+	}
+	return stats
+}
+
+// Problems counts the sections that failed to load or whose index isn't ok
+func (r *StatsReport) Problems() int {
+	count := 0
+	for _, section := range r.Sections {
+		if section.Error != "" || section.Index.Status != IndexOK {
+			count++
+		}
+	}
+	return count
+}
+
+// FormatStats renders a report as a table with a line per section,
+// followed by the sections' details and errors
+func FormatStats(r *StatsReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-10s %6s %6s %10s %-11s %6s  %s\n", "SECTION", "FILES", "DOCS", "SIZE", "CHANGED", "STALE", "INDEX")
+	for _, section := range r.Sections {
+		changed := "-"
+		if !section.Newest.IsZero() {
+			changed = section.Newest.Format("2006-01-02")
+		}
+		index := section.Index.Status
+		if section.Index.Bytes > 0 {
+			index += " (" + formatFileSize(section.Index.Bytes) + ")"
+		}
+		fmt.Fprintf(&sb, "%-10s %6d %6d %10s %-11s %6d  %s\n", section.Name, section.Files, section.Documents,
+			formatFileSize(section.Bytes), changed, section.Stale, index)
+	}
+
+	sb.WriteString("\n")
+	for _, section := range r.Sections {
+		switch {
+		case section.Error != "":
+			fmt.Fprintf(&sb, "%s: failed to load: %s\n", section.Name, section.Error)
+		case section.Detail != "":
+			fmt.Fprintf(&sb, "%s: %s\n", section.Name, section.Detail)
+		}
+	}
+	fmt.Fprintf(&sb, "Stale means unchanged for over %d days. Indexes in %s", r.StaleDays, r.IndexDir)
+	if r.Problems() > 0 {
+		sb.WriteString("; missing or behind indexes are rebuilt when the server starts, damaged ones need the directory deleted first")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// StatusArgs are the arguments of buddy_status
+type StatusArgs struct {
+	Action string `arg:"action" enum:"compact" desc:"Set to compact to compact the search indexes first (optional)"`
+}
+
+// GetStatusToolHandler returns the tool handler for the buddy status overview
+func (bh *BuddyHandlers) GetStatusToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args StatusArgs) (*mcp.CallToolResult, error) {
+		if args.Action == "compact" {
+			before, after, err := bh.CompactIndexes(ctx)
+			if err != nil {
+				return nil, err
+			}
+			result := fmt.Sprintf("🗜️ Compacted search indexes: %s → %s\n\n", formatFileSize(before.Total), formatFileSize(after.Total))
+			return mcp.NewToolResultText(result + bh.formatStatus()), nil
+		}
+		return mcp.NewToolResultText(bh.formatStatus()), nil
+	})
+}
+
+// formatStatus summarizes loaded content and any active warnings
+func (bh *BuddyHandlers) formatStatus() string {
+	result := "📊 Buddy Status\n"
+	result += strings.Repeat("=", 30) + "\n\n"
+
+	snap := bh.Snapshot()
+	completed := 0
+	for _, todo := range snap.Todos {
+		if todo.Completed {
+			completed++
+		}
+	}
+
+	if bh.activity != nil {
+		last := bh.activity.LastActivity()
+		result += fmt.Sprintf("⏱️ Uptime: %s (since %s)\n", bh.activity.Uptime().Round(time.Second), bh.timeFormat.Format(bh.activity.StartedAt()))
+		result += fmt.Sprintf("   Last activity: %s (%s ago), %d tool call(s) running\n\n",
+			bh.timeFormat.Format(last), time.Since(last).Round(time.Second), bh.activity.Running())
+	}
+
+	result += fmt.Sprintf("├─ Rules: %d\n", len(snap.Rules))
+	result += fmt.Sprintf("├─ Knowledge: %d\n", len(snap.Knowledge))
+	result += fmt.Sprintf("├─ Todos: %d/%d completed\n", completed, len(snap.Todos))
+	result += fmt.Sprintf("├─ History entries: %d\n", len(snap.History))
+	result += fmt.Sprintf("└─ Backups: %d\n", len(snap.Backups))
+	result += "\n" + formatIndexUsage(bh.IndexUsage())
+
+	warnings := bh.collectWarnings()
+	if len(warnings) == 0 {
+		result += "\n✅ No warnings\n"
+		return result
+	}
+
+	result += fmt.Sprintf("\n⚠️ Warnings (%d):\n", len(warnings))
+	for _, warning := range warnings {
+		result += fmt.Sprintf("- %s\n", warning)
+	}
+
+	return result
+}
+
+// collectWarnings gathers warnings from all handlers
+func (bh *BuddyHandlers) collectWarnings() []string {
+	var warnings []string
+
+	// Sections past their failure budget come first: their content is stale
+	for _, name := range bh.reloadOrder {
+		failures, path, open := bh.reloaders[name].tripped()
+		if !open {
+			continue
+		}
+		_, err := bh.reloaders[name].failure()
+		file := "a file that couldn't be identified"
+		if path != "" {
+			file = bh.relativeBuddyPath(path)
+		}
+		warnings = append(warnings, fmt.Sprintf(
+			"Reloading %s failed %d times in a row on %s (%v); serving its last good content, which reloads once that file changes",
+			name, failures, file, err))
+	}
+
+	for _, churn := range bh.rulesHandler.GetChurnWarnings() {
+		warnings = append(warnings, fmt.Sprintf(
+			"Rule %s changed %d times in the last %s (last at %s); an agent and a human may be overwriting each other",
+			churn.FilePath, churn.Changes, churn.Window, bh.timeFormat.Format(churn.Last)))
+	}
+
+	for _, file := range bh.OversizedFiles() {
+		loaded := "loaded whole"
+		if file.Truncated {
+			loaded = fmt.Sprintf("only the first %d KB is loaded", file.Limit/1024)
+		}
+		warnings = append(warnings, fmt.Sprintf(
+			"%s is %d KB, over the %d KB file size limit; %s. Split it into smaller files",
+			file.Path, (file.Size+1023)/1024, file.Limit/1024, loaded))
+	}
+
+	if usage := bh.IndexUsage(); usage.OverCap() {
+		warnings = append(warnings, fmt.Sprintf(
+			"Search indexes take %s, over the %s cap; if compacting them (buddy_status with action compact) doesn't help, raise search.max_index_mb",
+			formatFileSize(usage.Total), formatFileSize(usage.Cap)))
+	}
+
+	return warnings
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+)
+
+// formatSuggestions lists near misses for a query that found nothing: a
+// spelling correction, related indexed terms, and the most common values
+// of groupField among partial matches, labelled groupLabel (such as
+// "categories"). It returns "" when there is nothing to suggest or the
+// index can't be read.
+func formatSuggestions(ctx context.Context, sm *search.SearchManager, indexType search.IndexType, query, groupField, groupLabel string) string {
+	if sm == nil {
+		return ""
+	}
+	suggestions, err := sm.Suggest(ctx, indexType, query, groupField)
+	if err != nil {
+		slog.DebugContext(ctx, "failed to suggest queries", "index", indexType, "error", err)
+		return ""
+	}
+	if suggestions.Empty() {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n")
+	if suggestions.Query != "" {
+		fmt.Fprintf(&sb, "💡 Did you mean: %q?\n", suggestions.Query)
+	}
+	if len(suggestions.Terms) > 0 {
+		fmt.Fprintf(&sb, "🔤 Related terms: %s\n", strings.Join(suggestions.Terms, ", "))
+	}
+	if len(suggestions.Groups) > 0 {
+		groups := make([]string, len(suggestions.Groups))
+		for i, group := range suggestions.Groups {
+			groups[i] = fmt.Sprintf("%s (%d)", group.Value, group.Count)
+		}
+		fmt.Fprintf(&sb, "📂 Closest %s: %s\n", groupLabel, strings.Join(groups, ", "))
+	}
+	return sb.String()
+}
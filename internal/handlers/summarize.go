@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// defaultDigestPeriod is how far back a digest looks when no since is given
+const defaultDigestPeriod = "last 7 days"
+
+// SummarizeArgs are the arguments of buddy_summarize
+type SummarizeArgs struct {
+	Action   string `arg:"action,required" enum:"knowledge,digest" desc:"What to summarize"`
+	Query    string `arg:"query" desc:"Topic to summarize the knowledge base on (required for knowledge)"`
+	Category string `arg:"category" desc:"Only summarize knowledge in this category (optional)"`
+	Limit    int    `arg:"limit" desc:"Number of knowledge documents to summarize (default: 3)"`
+	Since    string `arg:"since" desc:"Digest start: RFC3339, YYYY-MM-DD, 'last 3 days', 'P1W' (default: last 7 days)"`
+	Until    string `arg:"until" desc:"Digest end, same formats as since (optional)"`
+}
+
+// GetSummarizeToolHandler returns the tool handler that summarizes
+// knowledge and writes digests of recent activity. Summaries come from the
+// client's model through sampling when the client supports it, otherwise
+// they are extractive.
+func (bh *BuddyHandlers) GetSummarizeToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args SummarizeArgs) (*mcp.CallToolResult, error) {
+		switch args.Action {
+		case "knowledge":
+			if args.Query == "" {
+				return nil, fmt.Errorf("query is required for knowledge action")
+			}
+			limit := 3
+			if args.Limit > 0 {
+				limit = args.Limit
+			}
+
+			result, err := bh.summarizeKnowledge(ctx, args.Query, args.Category, limit)
+			if err != nil {
+				return nil, err
+			}
+			return mcp.NewToolResultText(result), nil
+
+		case "digest":
+			sinceArg := args.Since
+			if sinceArg == "" {
+				sinceArg = defaultDigestPeriod
+			}
+			since, until, err := parseTimeRange(sinceArg, args.Until, bh.timeFormat.Location())
+			if err != nil {
+				return nil, err
+			}
+
+			return mcp.NewToolResultText(bh.digest(ctx, since, until)), nil
+
+		default:
+			return nil, fmt.Errorf("invalid action: %s (expected knowledge or digest)", args.Action)
+		}
+	})
+}
+
+// summarizeKnowledge summarizes what the best matching knowledge documents
+// say about query
+func (bh *BuddyHandlers) summarizeKnowledge(ctx context.Context, query, category string, limit int) (string, error) {
+	filters := make(map[string]interface{})
+	if category != "" {
+		filters["category"] = category
+	}
+	docs, err := bh.knowledgeHandler.SearchDocuments(ctx, query, filters, limit)
+	if err != nil {
+		return "", err
+	}
+	if len(docs) == 0 {
+		if suggestions := formatSuggestions(ctx, bh.searchManager, search.IndexTypeKnowledge, query, "category", "categories"); suggestions != "" {
+			return fmt.Sprintf("No knowledge found for: %s\n%s", query, suggestions), nil
+		}
+		return fmt.Sprintf("No knowledge found for: %s\n\n💡 Try broader terms or check buddy_search_knowledge", query), nil
+	}
+
+	var material strings.Builder
+	for _, doc := range docs {
+		material.WriteString(fmt.Sprintf("# %s\n\n%s\n\n", doc.Title, doc.Content))
+	}
+
+	sentences := 2 * len(docs)
+	if sentences > 6 {
+		sentences = 6
+	}
+	sum := bh.summarize(ctx,
+		fmt.Sprintf("Summarize what these knowledge base documents say about %q in one short paragraph. Mention the document a point comes from when it matters.", query),
+		material.String(), sentences)
+
+	result := fmt.Sprintf("📚 Knowledge summary: %s\n\n", query)
+	result += sum.Text + "\n\n"
+	result += fmt.Sprintf("Sources (%d):\n", len(docs))
+	for _, doc := range docs {
+		result += fmt.Sprintf("- %s (%s)\n", doc.Title, doc.FilePath)
+	}
+	result += fmt.Sprintf("\nSummary: %s", sum.Source())
+	return result, nil
+}
+
+// digest describes what happened between since and until: history entries,
+// changed buddy documents and the open todos left
+func (bh *BuddyHandlers) digest(ctx context.Context, since, until time.Time) string {
+	snap := bh.Snapshot()
+
+	var entries []models.HistoryEntry
+	for _, entry := range snap.History {
+		if inTimeRange(entry.Timestamp, since, until) {
+			entries = append(entries, entry)
+		}
+	}
+
+	var events []ChangeEvent
+	for _, event := range bh.changeLog.Since(since) {
+		if inTimeRange(event.Time, since, until) {
+			events = append(events, event)
+		}
+	}
+
+	openTodos := 0
+	openFeatures := make(map[string]bool)
+	for _, todo := range snap.Todos {
+		if !todo.Completed {
+			openTodos++
+			openFeatures[todo.Feature] = true
+		}
+	}
+
+	period := "since " + bh.timeFormat.Format(since)
+	if !until.IsZero() {
+		period += " until " + bh.timeFormat.Format(until)
+	}
+	result := fmt.Sprintf("📰 Digest %s\n\n", period)
+
+	if len(entries) == 0 && len(events) == 0 {
+		result += "Nothing was recorded in this period.\n"
+		result += fmt.Sprintf("\n✅ Open todos: %d across %d features", openTodos, len(openFeatures))
+		return result
+	}
+
+	var material strings.Builder
+	for _, entry := range entries {
+		material.WriteString(fmt.Sprintf("%s: %s.", entry.Feature, strings.TrimSuffix(entry.Description, ".")))
+		if entry.Reasoning != "" {
+			material.WriteString(" " + entry.Reasoning)
+		}
+		material.WriteString("\n\n")
+	}
+	for _, event := range events {
+		material.WriteString(fmt.Sprintf("- The %s %q was %s\n", event.Type, event.Title, event.Change))
+	}
+
+	sum := bh.summarize(ctx,
+		"Write a short digest for the team of the work recorded below: what was built or changed and why, in a few sentences.",
+		material.String(), 4)
+	result += sum.Text + "\n"
+
+	if len(entries) > 0 {
+		result += fmt.Sprintf("\n📚 History (%d):\n", len(entries))
+		for _, entry := range entries {
+			result += fmt.Sprintf("- %s [%s] %s\n", bh.timeFormat.Format(entry.Timestamp), entry.Feature, entry.Description)
+		}
+	}
+	if len(events) > 0 {
+		result += fmt.Sprintf("\n🔄 Buddy content changes (%d):\n", len(events))
+		for _, event := range events {
+			result += fmt.Sprintf("- %s %s: %s\n", event.Change, event.Type, event.Title)
+		}
+	}
+	result += fmt.Sprintf("\n✅ Open todos: %d across %d features\n", openTodos, len(openFeatures))
+	result += fmt.Sprintf("\nSummary: %s", sum.Source())
+	return result
+}
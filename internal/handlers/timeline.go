@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/timeutil"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// timelineDir is the buddy subdirectory holding the timeline
+const timelineDir = "timeline"
+
+// timelineRecordLayout names timeline records so they sort by time
+const timelineRecordLayout = "20060102T150405.000000000Z"
+
+// defaultTimeTravelHistory is how many history entries a past state lists
+const defaultTimeTravelHistory = 10
+
+// TimelineRecord is the guidance the buddy folder held from Time until the
+// next record
+type TimelineRecord struct {
+	Time      time.Time          `json:"time"`
+	Rules     []TimelineDocument `json:"rules"`
+	Knowledge []TimelineDocument `json:"knowledge"`
+	Todos     []TodoProgress     `json:"todos"`
+}
+
+// TimelineDocument is a recorded rule or knowledge document. Its content
+// is stored once per version under timeline/blobs/, named by Content.
+type TimelineDocument struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Category  string    `json:"category"`
+	Priority  string    `json:"priority,omitempty"`
+	FilePath  string    `json:"file_path"` // relative to the buddy folder
+	Content   string    `json:"content_sha256"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TodoProgress counts the completed tasks of a feature
+type TodoProgress struct {
+	Feature   string `json:"feature"`
+	Completed int    `json:"completed"`
+	Total     int    `json:"total"`
+}
+
+// Timeline keeps a record of the rules, knowledge and todo progress in the
+// buddy folder each time they change, so past states can be reconstructed
+type Timeline struct {
+	path      string // timeline directory
+	buddyPath string
+	store     storage.Storage
+	mu        sync.Mutex
+	last      string // fingerprint of the newest record
+	lastKnown bool
+}
+
+// NewTimeline creates a timeline kept under path
+func NewTimeline(path, buddyPath string, store storage.Storage) *Timeline {
+	return &Timeline{
+		path:      path,
+		buddyPath: buddyPath,
+		store:     store,
+	}
+}
+
+// recordOf extracts the recorded guidance from a snapshot, along with the
+// content of each document by hash
+func (tl *Timeline) recordOf(snap *ContextSnapshot) (TimelineRecord, map[string]string) {
+	record := TimelineRecord{Time: snap.TakenAt}
+	contents := make(map[string]string)
+
+	document := func(id, title, category, priority, filePath, content string, updatedAt time.Time) TimelineDocument {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+		contents[hash] = content
+		if rel, err := filepath.Rel(tl.buddyPath, filePath); err == nil {
+			filePath = filepath.ToSlash(rel)
+		}
+		return TimelineDocument{
+			ID:        id,
+			Title:     title,
+			Category:  category,
+			Priority:  priority,
+			FilePath:  filePath,
+			Content:   hash,
+			UpdatedAt: updatedAt.UTC(),
+		}
+	}
+	for _, rule := range snap.Rules {
+		record.Rules = append(record.Rules, document(rule.ID, rule.Title, rule.Category, rule.Priority, rule.FilePath, rule.Content, rule.UpdatedAt))
+	}
+	for _, kb := range snap.Knowledge {
+		record.Knowledge = append(record.Knowledge, document(kb.ID, kb.Title, kb.Category, "", kb.FilePath, kb.Content, kb.UpdatedAt))
+	}
+	sortTimelineDocuments(record.Rules)
+	sortTimelineDocuments(record.Knowledge)
+	record.Todos = todoProgress(snap.Todos)
+
+	return record, contents
+}
+
+// sortTimelineDocuments orders documents by file and ID
+func sortTimelineDocuments(docs []TimelineDocument) {
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].FilePath != docs[j].FilePath {
+			return docs[i].FilePath < docs[j].FilePath
+		}
+		return docs[i].ID < docs[j].ID
+	})
+}
+
+// todoProgress counts completed tasks per feature, by feature name
+func todoProgress(todos []models.Todo) []TodoProgress {
+	byFeature := make(map[string]*TodoProgress)
+	progress := []TodoProgress{} // empty rather than nil: recorded with no todos
+	for _, todo := range todos {
+		if todo.Archived {
+			continue
+		}
+		p := byFeature[todo.Feature]
+		if p == nil {
+			p = &TodoProgress{Feature: todo.Feature}
+			byFeature[todo.Feature] = p
+		}
+		p.Total++
+		if todo.Completed {
+			p.Completed++
+		}
+	}
+	for _, p := range byFeature {
+		progress = append(progress, *p)
+	}
+	sort.Slice(progress, func(i, j int) bool { return progress[i].Feature < progress[j].Feature })
+	return progress
+}
+
+// recordFingerprint identifies a record's content, ignoring when it was made
+func recordFingerprint(record TimelineRecord) string {
+	record.Time = time.Time{}
+	return fingerprint(record)
+}
+
+// Record saves the guidance in snap unless it matches the newest record
+func (tl *Timeline) Record(snap *ContextSnapshot) error {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	record, contents := tl.recordOf(snap)
+	current := recordFingerprint(record)
+
+	if !tl.lastKnown {
+		names, err := tl.recordNames()
+		if err != nil {
+			return err
+		}
+		if len(names) > 0 {
+			newest, err := tl.read(names[len(names)-1])
+			if err != nil {
+				return err
+			}
+			tl.last = recordFingerprint(*newest)
+		}
+		tl.lastKnown = true
+	}
+	if current == tl.last {
+		return nil
+	}
+
+	for hash, content := range contents {
+		blobPath := filepath.Join(tl.path, "blobs", hash+".md")
+		if _, err := tl.store.Stat(blobPath); err == nil {
+			continue
+		}
+		if err := tl.store.Write(blobPath, []byte(content)); err != nil {
+			return fmt.Errorf("failed to save timeline content: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := record.Time.UTC().Format(timelineRecordLayout) + ".json"
+	if err := tl.store.Write(filepath.Join(tl.path, name), data); err != nil {
+		return fmt.Errorf("failed to save timeline record: %w", err)
+	}
+	tl.last = current
+	return nil
+}
+
+// recordNames lists the record files, oldest first
+func (tl *Timeline) recordNames() ([]string, error) {
+	files, err := tl.store.List(tl.path, false)
+	if err != nil && !storage.IsNotExist(err) {
+		return nil, err
+	}
+	var names []string
+	for _, file := range files {
+		name := filepath.Base(file.Path)
+		if _, err := time.Parse(timelineRecordLayout, strings.TrimSuffix(name, ".json")); err == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// read loads one record
+func (tl *Timeline) read(name string) (*TimelineRecord, error) {
+	data, err := tl.store.Read(filepath.Join(tl.path, name))
+	if err != nil {
+		return nil, err
+	}
+	var record TimelineRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse timeline record %s: %w", name, err)
+	}
+	return &record, nil
+}
+
+// At returns the newest record made at or before t. When every record is
+// later, the oldest one is returned and exact is false. It returns nil
+// when nothing has been recorded.
+func (tl *Timeline) At(t time.Time) (record *TimelineRecord, exact bool, err error) {
+	names, err := tl.recordNames()
+	if err != nil || len(names) == 0 {
+		return nil, false, err
+	}
+
+	// Names sort by time, so the first name after t's marks the spot
+	cutoff := t.UTC().Format(timelineRecordLayout) + ".json"
+	i := sort.Search(len(names), func(i int) bool { return names[i] > cutoff })
+	if i == 0 {
+		record, err = tl.read(names[0])
+		return record, false, err
+	}
+	record, err = tl.read(names[i-1])
+	return record, true, err
+}
+
+// Content returns the recorded content with the given hash
+func (tl *Timeline) Content(hash string) (string, error) {
+	data, err := tl.store.Read(filepath.Join(tl.path, "blobs", hash+".md"))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// PastState is the buddy state reconstructed for a past time
+type PastState struct {
+	At time.Time
+	// RecordedAt is when the timeline record it comes from was made; zero
+	// when it comes from the current content
+	RecordedAt time.Time
+	// Approximate is set when no record is that old. Documents are then
+	// taken from the oldest record, or the current content, and those
+	// changed after At are left out as Unknown.
+	Approximate bool
+	Rules       []TimelineDocument
+	Knowledge   []TimelineDocument
+	Todos       []TodoProgress // nil when todo progress wasn't recorded yet
+	Unknown     []string       // titles of documents added or changed since At
+	History     []models.HistoryEntry
+}
+
+// StateAt reconstructs the rules, knowledge, todo progress and history the
+// buddy folder held at t
+func (bh *BuddyHandlers) StateAt(t time.Time) (*PastState, error) {
+	snap := bh.Snapshot()
+	state := &PastState{At: t}
+
+	record, exact, err := bh.timeline.At(t)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		current, _ := bh.timeline.recordOf(snap)
+		record = &current
+	} else {
+		state.RecordedAt = record.Time
+	}
+
+	state.Rules, state.Knowledge = record.Rules, record.Knowledge
+	if exact {
+		state.Todos = record.Todos
+	} else {
+		state.Approximate = true
+		state.Rules = state.keepUnchanged(record.Rules)
+		state.Knowledge = state.keepUnchanged(record.Knowledge)
+	}
+
+	for _, entry := range snap.History {
+		if !entry.Timestamp.After(t) {
+			state.History = append(state.History, entry)
+		}
+	}
+	return state, nil
+}
+
+// keepUnchanged drops documents modified after the state's time, noting them
+// as unknown
+func (ps *PastState) keepUnchanged(docs []TimelineDocument) []TimelineDocument {
+	var kept []TimelineDocument
+	for _, doc := range docs {
+		if doc.UpdatedAt.After(ps.At) {
+			ps.Unknown = append(ps.Unknown, doc.Title)
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	return kept
+}
+
+// TimeTravelArgs are the arguments of buddy_time_travel
+type TimeTravelArgs struct {
+	At             string `arg:"at,required" desc:"The past time: RFC3339, YYYY-MM-DD, 'last 3 days', 'P1W'"`
+	IncludeContent *bool  `arg:"include_content" desc:"Include the text of each rule (default: true)"`
+	HistoryLimit   *int   `arg:"history_limit" desc:"Number of history entries up to that time to list (default: 10)"`
+}
+
+// GetTimeTravelToolHandler returns the tool handler that reconstructs the
+// buddy state at a past time
+func (bh *BuddyHandlers) GetTimeTravelToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args TimeTravelArgs) (*mcp.CallToolResult, error) {
+		at, err := timeutil.ParseTime(args.At, time.Now(), bh.timeFormat.Location())
+		if err != nil {
+			return nil, fmt.Errorf("invalid at: %w", err)
+		}
+		historyLimit := defaultTimeTravelHistory
+		if args.HistoryLimit != nil && *args.HistoryLimit >= 0 {
+			historyLimit = *args.HistoryLimit
+		}
+		includeContent := true
+		if args.IncludeContent != nil {
+			includeContent = *args.IncludeContent
+		}
+
+		state, err := bh.StateAt(at)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(bh.formatPastState(state, historyLimit, includeContent)), nil
+	})
+}
+
+// formatPastState describes a reconstructed state, rules in full when
+// includeContent is set
+func (bh *BuddyHandlers) formatPastState(state *PastState, historyLimit int, includeContent bool) string {
+	result := fmt.Sprintf("🕰️ Buddy state as of %s\n", bh.timeFormat.Format(state.At))
+	switch {
+	case state.Approximate && state.RecordedAt.IsZero():
+		result += "⚠️ Approximate: nothing has been recorded yet, so this is the current content without documents changed since then.\n"
+	case state.Approximate:
+		result += fmt.Sprintf("⚠️ Approximate: recording started %s, so this is the oldest record without documents changed since then.\n", bh.timeFormat.Format(state.RecordedAt))
+	default:
+		result += fmt.Sprintf("Recorded %s\n", bh.timeFormat.Format(state.RecordedAt))
+	}
+
+	result += fmt.Sprintf("\n📏 Rules (%d):\n", len(state.Rules))
+	for _, rule := range state.Rules {
+		result += fmt.Sprintf("- [%s] %s (%s) — %s\n", rule.Priority, rule.Title, rule.Category, rule.FilePath)
+		if !includeContent {
+			continue
+		}
+		content, err := bh.timeline.Content(rule.Content)
+		if err != nil {
+			result += "  (content not recorded)\n"
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+			result += "  " + line + "\n"
+		}
+	}
+
+	result += fmt.Sprintf("\n📚 Knowledge (%d):\n", len(state.Knowledge))
+	for _, kb := range state.Knowledge {
+		result += fmt.Sprintf("- %s (%s) — %s\n", kb.Title, kb.Category, kb.FilePath)
+	}
+
+	if state.Todos == nil {
+		result += "\n✅ Todo progress: not recorded that far back\n"
+	} else {
+		result += fmt.Sprintf("\n✅ Todo progress (%d features):\n", len(state.Todos))
+		for _, progress := range state.Todos {
+			result += fmt.Sprintf("- %s: %d/%d done\n", progress.Feature, progress.Completed, progress.Total)
+		}
+	}
+
+	if len(state.Unknown) > 0 {
+		result += fmt.Sprintf("\n❓ Added or changed since, earlier version unknown (%d): %s\n", len(state.Unknown), strings.Join(state.Unknown, ", "))
+	}
+
+	history := state.History
+	if len(history) > historyLimit {
+		history = history[:historyLimit]
+	}
+	result += fmt.Sprintf("\n📜 History up to then (%d of %d):\n", len(history), len(state.History))
+	for _, entry := range history {
+		result += fmt.Sprintf("- %s [%s] %s\n", bh.timeFormat.Format(entry.Timestamp), entry.Feature, entry.Description)
+	}
+	return result
+}
@@ -4,88 +4,55 @@ import (
 	"context"
 	"crypto/md5"
 	"fmt"
-	"io/ioutil"
-	"os"
+	"log/slog"
+	"maps"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/codetodos"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
 	"github.com/omar-haris/cursor-buddy-mcp/internal/search"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
 )
 
+// todoArchiveDir is the todos subdirectory completed todo files are moved to
+const todoArchiveDir = "archive"
+
 // TodoHandler manages todo items
 type TodoHandler struct {
-	path          string
-	todos         []models.Todo
-	searchManager *search.SearchManager
-	mu            sync.RWMutex
+	*DocumentHandler[models.Todo]
+	safety      *SafetyStore
+	codeScanner setting[codetodos.Scanner]
+	claims      *ClaimRegistry
+	focus       *FocusStore // narrows lists and ranks searches; nil for none
+	autoArchive atomic.Bool // move files whose tasks are all complete to todos/archive/
 }
 
 // NewTodoHandler creates a new todo handler
 func NewTodoHandler(path string, searchManager *search.SearchManager) *TodoHandler {
-	return &TodoHandler{
-		path:          path,
-		todos:         []models.Todo{},
-		searchManager: searchManager,
-	}
-}
-
-// Load loads all todos from the todos directory
-func (th *TodoHandler) Load() error {
-	th.mu.Lock()
-	defer th.mu.Unlock()
-
-	th.todos = []models.Todo{}
-
-	// First, reindex all todos
-	if err := th.searchManager.ReindexAll(search.IndexTypeTodos); err != nil {
-		return fmt.Errorf("failed to reindex todos: %w", err)
-	}
-
-	err := filepath.Walk(th.path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".md") {
-			todos, err := th.loadTodoFile(path)
-			if err != nil {
-				return fmt.Errorf("failed to load todo file %s: %w", path, err)
-			}
-
-			// Add todos and index them
-			for _, todo := range todos {
-				th.todos = append(th.todos, todo)
-
-				// Index the todo in Bleve
-				doc := search.FromTodo(todo)
-				if err := th.searchManager.IndexDocument(search.IndexTypeTodos, todo.ID, doc); err != nil {
-					return fmt.Errorf("failed to index todo %s: %w", todo.ID, err)
-				}
-			}
-		}
-
-		return nil
+	th := &TodoHandler{}
+	th.DocumentHandler = NewDocumentHandler(path, searchManager, DocumentSpec[models.Todo]{
+		IndexType:  search.IndexTypeTodos,
+		Extensions: []string{".md"},
+		Truncate:   truncateText,
+		Recursive:  true,
+		Parse:      th.parseTodoFile,
+		ID:         func(todo models.Todo) string { return todo.ID },
+		Index:      func(todo models.Todo) interface{} { return search.FromTodo(todo) },
 	})
-
-	if err != nil && !os.IsNotExist(err) {
-		return err
-	}
-
-	return nil
+	return th
 }
 
-// loadTodoFile loads todos from a single file
-func (th *TodoHandler) loadTodoFile(filePath string) ([]models.Todo, error) {
-	content, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return nil, err
-	}
+// parseTodoFile parses the todos in a single file
+func (th *TodoHandler) parseTodoFile(file storage.FileInfo, content []byte) ([]models.Todo, error) {
+	filePath := file.Path
 
 	var todos []models.Todo
 	lines := strings.Split(string(content), "\n")
@@ -94,6 +61,9 @@ func (th *TodoHandler) loadTodoFile(filePath string) ([]models.Todo, error) {
 	feature := filepath.Base(filePath)
 	feature = strings.TrimSuffix(feature, ".md")
 
+	archived := th.isArchived(filePath)
+
+	tasks := th.taskLines(filePath, lines)
 	for i, line := range lines {
 		if strings.HasPrefix(line, "# Feature: ") {
 			feature = strings.TrimPrefix(line, "# Feature: ")
@@ -101,118 +71,255 @@ func (th *TodoHandler) loadTodoFile(filePath string) ([]models.Todo, error) {
 			feature = strings.TrimPrefix(line, "# ")
 		}
 
-		// Look for checkbox items
-		if strings.HasPrefix(line, "- [ ]") || strings.HasPrefix(line, "- [x]") {
-			completed := strings.HasPrefix(line, "- [x]")
-			task := strings.TrimPrefix(line, "- [ ]")
-			task = strings.TrimPrefix(task, "- [x]")
-			task = strings.TrimSpace(task)
-
-			if task != "" {
-				// Generate unique ID
-				id := fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%s-%d", filePath, task, i))))
-
-				todo := models.Todo{
-					ID:        id,
-					Task:      task,
-					Feature:   feature,
-					Completed: completed,
-					FilePath:  filePath,
-					UpdatedAt: time.Now(),
-				}
-
-				todos = append(todos, todo)
-			}
+		if len(tasks) > 0 && tasks[0].index == i {
+			todos = append(todos, models.Todo{
+				ID:        tasks[0].id,
+				Task:      tasks[0].task,
+				Feature:   feature,
+				Completed: tasks[0].completed,
+				FilePath:  filePath,
+				Archived:  archived,
+				UpdatedAt: time.Now().UTC(),
+			})
+			tasks = tasks[1:]
 		}
 	}
 
 	return todos, nil
 }
 
+// taskLine is a checkbox line of a todo file
+type taskLine struct {
+	index     int // line index in the file
+	task      string
+	completed bool
+	id        string
+}
+
+// taskLines returns the checkbox lines of a todo file in order. A task's
+// ID hashes the file's path within the todos folder and the task's text,
+// so it survives lines being added or moved around it; identical tasks in
+// one file are told apart by how many came before.
+func (th *TodoHandler) taskLines(filePath string, lines []string) []taskLine {
+	rel := relativeTo(th.path, filePath)
+	seen := make(map[string]int)
+	var tasks []taskLine
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "- [ ]") && !strings.HasPrefix(line, "- [x]") {
+			continue
+		}
+		task := strings.TrimSpace(line[len("- [ ]"):])
+		if task == "" {
+			continue
+		}
+		key := fmt.Sprintf("%s-%s", rel, task)
+		if n := seen[task]; n > 0 {
+			key = fmt.Sprintf("%s-%d", key, n)
+		}
+		seen[task]++
+		tasks = append(tasks, taskLine{
+			index:     i,
+			task:      task,
+			completed: strings.HasPrefix(line, "- [x]"),
+			id:        fmt.Sprintf("%x", md5.Sum([]byte(key))),
+		})
+	}
+	return tasks
+}
+
 // GetTodos returns all todos
 func (th *TodoHandler) GetTodos() []models.Todo {
-	th.mu.RLock()
-	defer th.mu.RUnlock()
-	return th.todos
+	return th.Documents()
 }
 
 // GetTodosByFeature returns todos for a specific feature
 func (th *TodoHandler) GetTodosByFeature(feature string) []models.Todo {
-	th.mu.RLock()
-	defer th.mu.RUnlock()
-
-	var filtered []models.Todo
-	for _, todo := range th.todos {
-		if strings.EqualFold(todo.Feature, feature) {
-			filtered = append(filtered, todo)
-		}
-	}
-	return filtered
+	return th.Filter(func(todo models.Todo) bool {
+		return strings.EqualFold(todo.Feature, feature)
+	})
 }
 
 // GetIncompleteTodos returns all incomplete todos
 func (th *TodoHandler) GetIncompleteTodos() []models.Todo {
-	th.mu.RLock()
-	defer th.mu.RUnlock()
+	return th.Filter(func(todo models.Todo) bool {
+		return !todo.Completed
+	})
+}
 
-	var filtered []models.Todo
-	for _, todo := range th.todos {
-		if !todo.Completed {
-			filtered = append(filtered, todo)
-		}
+// UpdateTodoStatus updates a todo's completion status. With auto-archive
+// on, completing the last open task of a file moves the file to
+// todos/archive/; the new path is returned, or "" if nothing moved.
+func (th *TodoHandler) UpdateTodoStatus(ctx context.Context, todoID string, completed bool) (string, error) {
+	filePath, err := th.updateTodoStatus(ctx, todoID, completed)
+	if err != nil {
+		return "", err
+	}
+	if !completed || !th.autoArchive.Load() {
+		return "", nil
+	}
+
+	archivedPath, err := th.ArchiveIfComplete(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("todo updated, but archiving %s failed: %w", filepath.Base(filePath), err)
 	}
-	return filtered
+	return archivedPath, nil
 }
 
-// UpdateTodoStatus updates a todo's completion status
-func (th *TodoHandler) UpdateTodoStatus(todoID string, completed bool) error {
+// updateTodoStatus updates a todo and its file, returning the file's path
+func (th *TodoHandler) updateTodoStatus(ctx context.Context, todoID string, completed bool) (string, error) {
 	th.mu.Lock()
 	defer th.mu.Unlock()
 
-	for i, todo := range th.todos {
+	for i, todo := range th.docs {
 		if todo.ID == todoID {
-			th.todos[i].Completed = completed
-			th.todos[i].UpdatedAt = time.Now()
+			// A status change is one undoable line edit, so unlike
+			// imports it takes no safety snapshot
+			th.docs[i].Completed = completed
+			th.docs[i].UpdatedAt = time.Now().UTC()
 
 			// Update the file
-			if err := th.updateTodoFile(&th.todos[i]); err != nil {
-				return err
+			if err := th.updateTodoFile(ctx, &th.docs[i]); err != nil {
+				return "", err
 			}
 
 			// Update the index
-			doc := search.FromTodo(th.todos[i])
+			doc := search.FromTodo(th.docs[i])
 			if err := th.searchManager.UpdateDocument(search.IndexTypeTodos, todoID, doc); err != nil {
-				return fmt.Errorf("failed to update todo in index: %w", err)
+				return "", fmt.Errorf("failed to update todo in index: %w", err)
 			}
 
-			return nil
+			return todo.FilePath, nil
 		}
 	}
 
-	return fmt.Errorf("todo with ID %s not found", todoID)
+	return "", fmt.Errorf("todo with ID %s not found", todoID)
+}
+
+// isArchived reports whether a todo file is in the archive
+func (th *TodoHandler) isArchived(path string) bool {
+	rel, err := filepath.Rel(th.path, path)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(filepath.ToSlash(rel), todoArchiveDir+"/")
+}
+
+// ArchiveIfComplete moves a todo file to todos/archive/, keeping its path
+// below todos/, when every task in it is complete. Archived files, files
+// without tasks and the imported code todo file, which imports rewrite in
+// place, stay where they are. The new path is returned, or "" if the file
+// didn't move.
+func (th *TodoHandler) ArchiveIfComplete(ctx context.Context, filePath string) (string, error) {
+	if th.isArchived(filePath) || filepath.Clean(filePath) == filepath.Join(th.path, codetodos.FileName) {
+		return "", nil
+	}
+
+	todos := th.Filter(func(todo models.Todo) bool { return todo.FilePath == filePath })
+	if len(todos) == 0 {
+		return "", nil
+	}
+	for _, todo := range todos {
+		if !todo.Completed {
+			return "", nil
+		}
+	}
+
+	rel, err := filepath.Rel(th.path, filePath)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(th.path, todoArchiveDir, rel)
+	ext := filepath.Ext(target)
+	for n := 2; ; n++ {
+		if _, err := th.store.Stat(target); storage.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		target = strings.TrimSuffix(filepath.Join(th.path, todoArchiveDir, rel), ext) + fmt.Sprintf("-%d", n) + ext
+	}
+
+	content, err := th.store.Read(filePath)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFile(ctx, th.store, target, content); err != nil {
+		return "", err
+	}
+	if err := removeFile(ctx, th.store, filePath); err != nil {
+		return "", err
+	}
+	slog.InfoContext(ctx, "archived completed todo file", "from", filePath, "to", target)
+
+	return target, th.LoadContext(ctx)
 }
 
 // updateTodoFile updates a todo in its file
-func (th *TodoHandler) updateTodoFile(todo *models.Todo) error {
-	content, err := ioutil.ReadFile(todo.FilePath)
+func (th *TodoHandler) updateTodoFile(ctx context.Context, todo *models.Todo) error {
+	content, err := th.store.Read(todo.FilePath)
 	if err != nil {
 		return err
 	}
 
+	// The line is found by ID, so of identical tasks the right one changes
 	lines := strings.Split(string(content), "\n")
-	for i, line := range lines {
-		if strings.Contains(line, todo.Task) {
+	for _, task := range th.taskLines(todo.FilePath, lines) {
+		if task.id == todo.ID {
 			if todo.Completed {
-				lines[i] = strings.Replace(line, "- [ ]", "- [x]", 1)
+				lines[task.index] = strings.Replace(lines[task.index], "- [ ]", "- [x]", 1)
 			} else {
-				lines[i] = strings.Replace(line, "- [x]", "- [ ]", 1)
+				lines[task.index] = strings.Replace(lines[task.index], "- [x]", "- [ ]", 1)
 			}
 			break
 		}
 	}
 
 	newContent := strings.Join(lines, "\n")
-	return ioutil.WriteFile(todo.FilePath, []byte(newContent), 0644)
+	return writeFile(ctx, th.store, todo.FilePath, []byte(newContent))
+}
+
+// ImportCodeTodos scans source code for TODO/FIXME comments and syncs them
+// into the code todo file, checking off comments that were removed. A
+// cancelled ctx stops the scan; once the file is written the todos are
+// reloaded regardless, so the index matches the file. The ID of the
+// restore point taken before writing is returned, or "" when nothing
+// changed.
+func (th *TodoHandler) ImportCodeTodos(ctx context.Context) (codetodos.SyncResult, string, error) {
+	items, err := th.codeScanner.Load().Scan(ctx)
+	if err != nil {
+		return codetodos.SyncResult{}, "", err
+	}
+
+	path := filepath.Join(th.path, codetodos.FileName)
+	var existing []byte
+	if _, err := th.store.Stat(path); err == nil {
+		if existing, err = th.store.Read(path); err != nil {
+			return codetodos.SyncResult{}, "", err
+		}
+	}
+
+	content, result := codetodos.Sync(string(existing), items)
+	if content == string(existing) {
+		return result, "", nil
+	}
+	if err := ctx.Err(); err != nil {
+		return codetodos.SyncResult{}, "", err
+	}
+
+	label := fmt.Sprintf("%d new, %d resolved, %d reopened code todos", result.Added, result.Resolved, result.Reopened)
+	snapshot, err := th.safety.RestorePoint(ctx, "todo_import", label, []string{path})
+	if err != nil {
+		return codetodos.SyncResult{}, "", err
+	}
+	var snapshotID string
+	if snapshot != nil {
+		snapshotID = snapshot.ID
+	}
+	if err := writeFile(ctx, th.store, path, []byte(content)); err != nil {
+		return codetodos.SyncResult{}, "", fmt.Errorf("failed to write %s: %w", codetodos.FileName, err)
+	}
+
+	return result, snapshotID, th.Load()
 }
 
 // GetProgress calculates completion progress with enhanced metrics
@@ -220,12 +327,12 @@ func (th *TodoHandler) GetProgress() map[string]interface{} {
 	th.mu.RLock()
 	defer th.mu.RUnlock()
 
-	total := len(th.todos)
+	total := len(th.docs)
 	completed := 0
 	byFeature := make(map[string]map[string]int)
 	recentActivity := make(map[string]int)
 
-	for _, todo := range th.todos {
+	for _, todo := range th.docs {
 		if todo.Completed {
 			completed++
 		}
@@ -260,20 +367,30 @@ func (th *TodoHandler) GetProgress() map[string]interface{} {
 	}
 }
 
+// TodoArgs are the arguments of buddy_manage_todos
+type TodoArgs struct {
+	Action          string  `arg:"action,required" enum:"list,update,progress,claim,release,import_code" desc:"Action to perform: list, update, progress, claim, release, import_code (sync TODO/FIXME comments from source into todos/code-todos.md)"`
+	Feature         string  `arg:"feature" desc:"Filter by feature name (optional for list)"`
+	Query           string  `arg:"query" desc:"Search todos by task text (optional for list)"`
+	TodoID          string  `arg:"todo_id" desc:"Todo ID (required for update, claim and release)"`
+	Completed       *bool   `arg:"completed" desc:"New completion status (required for update)"`
+	OnlyIncomplete  bool    `arg:"only_incomplete" desc:"Show only incomplete todos (optional for list)"`
+	OnlyUnclaimed   bool    `arg:"only_unclaimed" desc:"Show only incomplete todos no agent has claimed (optional for list)"`
+	IncludeArchived bool    `arg:"include_archived" desc:"Also list todos from files moved to todos/archive/; searches with a query always include them (optional for list)"`
+	IgnoreFocus     bool    `arg:"ignore_focus" desc:"List every feature's todos instead of only those set with buddy_focus (optional for list)"`
+	Agent           string  `arg:"agent" desc:"Who is claiming or releasing; defaults to the MCP session (optional for claim and release)"`
+	TTLMinutes      float64 `arg:"ttl_minutes" desc:"How long the claim lasts before it expires (default: 30, max: 1440)"`
+	Note            string  `arg:"note" desc:"What the claimer is doing (optional for claim)"`
+	Force           bool    `arg:"force" desc:"Release a claim held by another agent (optional for release)"`
+	TokenBudgetArgs
+}
+
 // GetToolHandler returns the tool handler function for todos
 func (th *TodoHandler) GetToolHandler() server.ToolHandlerFunc {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		args := request.GetArguments()
-		action, ok := args["action"].(string)
-		if !ok {
-			return nil, fmt.Errorf("action is required")
-		}
-
-		switch action {
+	return toolargs.Handler(func(ctx context.Context, args TodoArgs) (*mcp.CallToolResult, error) {
+		switch action := args.Action; action {
 		case "list":
-			feature, _ := args["feature"].(string)
-			onlyIncomplete, _ := args["only_incomplete"].(bool)
-			query, _ := args["query"].(string)
+			feature, query, onlyIncomplete := args.Feature, args.Query, args.OnlyIncomplete
 
 			var todos []models.Todo
 
@@ -300,7 +417,7 @@ func (th *TodoHandler) GetToolHandler() server.ToolHandlerFunc {
 				// Convert search results to todos
 				for _, hit := range searchResults.Hits {
 					// Find the todo by ID
-					for _, todo := range th.todos {
+					for _, todo := range th.docs {
 						if todo.ID == hit.ID {
 							todos = append(todos, todo)
 							break
@@ -315,26 +432,112 @@ func (th *TodoHandler) GetToolHandler() server.ToolHandlerFunc {
 				todos = th.GetTodos()
 			}
 
+			// Without a feature filter, the focus narrows plain lists and
+			// ranks search results
+			var focused []string
+			if feature == "" {
+				focused = th.focus.Active(args.IgnoreFocus)
+			}
+			inFocus := func(todo models.Todo) bool { return containsFold(focused, todo.Feature) }
+			if len(focused) > 0 && query == "" {
+				todos = th.Filter(func(todo models.Todo) bool {
+					return inFocus(todo) && (!onlyIncomplete || !todo.Completed)
+				})
+			} else if len(focused) > 0 {
+				todos = focusedFirst(todos, inFocus)
+			}
+
+			// Searches still find archived todos; plain lists leave them out
+			if query == "" && !args.IncludeArchived {
+				var active []models.Todo
+				for _, todo := range todos {
+					if !todo.Archived {
+						active = append(active, todo)
+					}
+				}
+				todos = active
+			}
+
+			if args.OnlyUnclaimed {
+				var unclaimed []models.Todo
+				for _, todo := range todos {
+					if _, claimed := th.claims.Get(ClaimTodo, todo.ID); !claimed && !todo.Completed {
+						unclaimed = append(unclaimed, todo)
+					}
+				}
+				todos = unclaimed
+			}
+
 			// Enhanced result formatting
-			result := th.formatTodoResults(query, todos)
+			result := focusNote(focused, query == "") + th.formatTodoResults(query, todos)
 			return mcp.NewToolResultText(result), nil
 
 		case "update":
-			todoID, ok := args["todo_id"].(string)
-			if !ok {
+			todoID := args.TodoID
+			if todoID == "" {
 				return nil, fmt.Errorf("todo_id is required for update action")
 			}
+			if args.Completed == nil {
+				return nil, fmt.Errorf("completed status is required for update action")
+			}
+			completed := *args.Completed
+
+			archivedPath, err := th.UpdateTodoStatus(ctx, todoID, completed)
+			if err != nil {
+				return nil, err
+			}
 
-			completed, ok := args["completed"].(bool)
+			result := fmt.Sprintf("Successfully updated todo %s to completed=%v", todoID, completed)
+			if archivedPath != "" {
+				rel, _ := filepath.Rel(th.path, archivedPath)
+				result += fmt.Sprintf("\n📦 Every task in the file is done, so it moved to todos/%s", filepath.ToSlash(rel))
+			}
+			return mcp.NewToolResultText(result), nil
+
+		case "claim", "release":
+			todoID := args.TodoID
+			if todoID == "" {
+				return nil, fmt.Errorf("todo_id is required for %s action", action)
+			}
+			todo, ok := th.Get(todoID)
 			if !ok {
-				return nil, fmt.Errorf("completed status is required for update action")
+				return nil, fmt.Errorf("todo with ID %s not found", todoID)
+			}
+			owner, err := claimOwner(ctx, args.Agent)
+			if err != nil {
+				return nil, err
+			}
+
+			if action == "claim" {
+				claims, err := th.claims.Acquire(ClaimTodo, []string{todoID}, owner, claimTTL(args.TTLMinutes), args.Note)
+				if err != nil {
+					return nil, err
+				}
+				return mcp.NewToolResultText(fmt.Sprintf("🔒 %s claimed \"%s\" until %s. Release it when done, or claim again to extend.",
+					owner, todo.Task, claims[0].ExpiresAt.Format(time.RFC3339))), nil
+			}
+
+			released, err := th.claims.Release(ClaimTodo, []string{todoID}, owner, args.Force)
+			if err != nil {
+				return nil, err
+			}
+			if len(released) == 0 {
+				return mcp.NewToolResultText(fmt.Sprintf("\"%s\" was not claimed", todo.Task)), nil
 			}
+			return mcp.NewToolResultText(fmt.Sprintf("🔓 Released \"%s\"", todo.Task)), nil
 
-			if err := th.UpdateTodoStatus(todoID, completed); err != nil {
+		case "import_code":
+			result, snapshotID, err := th.ImportCodeTodos(ctx)
+			if err != nil {
 				return nil, err
 			}
 
-			return mcp.NewToolResultText(fmt.Sprintf("Successfully updated todo %s to completed=%v", todoID, completed)), nil
+			text := fmt.Sprintf("📥 Synced code comments into todos/%s\n├─ Open: %d\n├─ New: %d\n├─ Resolved: %d\n└─ Reopened: %d",
+				codetodos.FileName, result.Open, result.Added, result.Resolved, result.Reopened)
+			if snapshotID != "" {
+				text += fmt.Sprintf("\n\n🛟 Restore point: %s\n💡 Undo the import with buddy_backup action restore_safety and this snapshot_id", snapshotID)
+			}
+			return mcp.NewToolResultText(text), nil
 
 		case "progress":
 			progress := th.GetProgress()
@@ -344,7 +547,7 @@ func (th *TodoHandler) GetToolHandler() server.ToolHandlerFunc {
 		default:
 			return nil, fmt.Errorf("invalid action: %s", action)
 		}
-	}
+	})
 }
 
 // formatTodoResults formats todo results with enhanced context
@@ -374,7 +577,7 @@ func (th *TodoHandler) formatTodoResults(query string, todos []models.Todo) stri
 
 		if len(features) > 0 {
 			result += "\n\nAvailable features:"
-			for feature := range features {
+			for _, feature := range slices.Sorted(maps.Keys(features)) {
 				result += fmt.Sprintf("\n- %s", feature)
 			}
 		}
@@ -388,13 +591,19 @@ func (th *TodoHandler) formatTodoResults(query string, todos []models.Todo) stri
 	}
 	result += "\n"
 
-	// Group by feature and status
+	// Group by feature and status, features in the order their first todo
+	// appears so a focused or sorted list keeps its ranking
 	byFeature := make(map[string][]models.Todo)
+	var featureOrder []string
 	for _, todo := range todos {
+		if _, ok := byFeature[todo.Feature]; !ok {
+			featureOrder = append(featureOrder, todo.Feature)
+		}
 		byFeature[todo.Feature] = append(byFeature[todo.Feature], todo)
 	}
 
-	for feature, featureTodos := range byFeature {
+	for _, feature := range featureOrder {
+		featureTodos := byFeature[feature]
 		result += fmt.Sprintf("\n=== %s ===\n", strings.ToUpper(feature))
 
 		// Separate completed and incomplete
@@ -412,6 +621,9 @@ func (th *TodoHandler) formatTodoResults(query string, todos []models.Todo) stri
 			result += "\n📝 PENDING:\n"
 			for i, todo := range incomplete {
 				result += fmt.Sprintf("  %d. [ ] %s (ID: %s)\n", i+1, todo.Task, todo.ID)
+				if claim, ok := th.claims.Get(ClaimTodo, todo.ID); ok {
+					result += fmt.Sprintf("     🔒 %s\n", describeClaim(claim, time.Now()))
+				}
 			}
 		}
 
@@ -419,7 +631,11 @@ func (th *TodoHandler) formatTodoResults(query string, todos []models.Todo) stri
 		if len(completed) > 0 {
 			result += "\n✅ COMPLETED:\n"
 			for i, todo := range completed {
-				result += fmt.Sprintf("  %d. [x] %s (ID: %s)\n", i+1, todo.Task, todo.ID)
+				archived := ""
+				if todo.Archived {
+					archived = " 📦 archived"
+				}
+				result += fmt.Sprintf("  %d. [x] %s (ID: %s)%s\n", i+1, todo.Task, todo.ID, archived)
 			}
 		}
 
@@ -468,7 +684,10 @@ func (th *TodoHandler) formatProgressResults(progress map[string]interface{}) st
 		}
 
 		sort.Slice(features, func(i, j int) bool {
-			return features[i].percentage > features[j].percentage
+			if features[i].percentage != features[j].percentage {
+				return features[i].percentage > features[j].percentage
+			}
+			return features[i].name < features[j].name
 		})
 
 		for _, feature := range features {
@@ -486,8 +705,12 @@ func (th *TodoHandler) formatProgressResults(progress map[string]interface{}) st
 
 	if recentActivity, ok := progress["recent_activity"].(map[string]int); ok && len(recentActivity) > 0 {
 		result += "\n🔥 Recent Activity (Last 7 Days):\n"
-		for feature, count := range recentActivity {
-			result += fmt.Sprintf("├─ %s: %d updates\n", feature, count)
+		features := slices.Sorted(maps.Keys(recentActivity))
+		sort.SliceStable(features, func(i, j int) bool {
+			return recentActivity[features[i]] > recentActivity[features[j]]
+		})
+		for _, feature := range features {
+			result += fmt.Sprintf("├─ %s: %d updates\n", feature, recentActivity[feature])
 		}
 	}
 
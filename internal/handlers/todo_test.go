@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTodoIDs_SurviveLinesAddedAround(t *testing.T) {
+	bh, buddyPath := newTestBuddyHandlers(t, map[string]string{
+		"todos/auth.md": "# Feature: auth\n\n- [ ] Add login\n- [x] Hash passwords\n",
+	})
+	ids := make(map[string]string) // task to ID
+	var before []string
+	for _, todo := range bh.todoHandler.GetTodos() {
+		ids[todo.Task] = todo.ID
+		before = append(before, todo.ID)
+	}
+	require.Len(t, ids, 2)
+
+	writeTestFile(t, filepath.Join(buddyPath, "todos", "auth.md"), "# Feature: auth\n\nNotes first.\n\n- [ ] Add signup\n- [x] Hash passwords\n\n- [ ] Add login\n")
+	require.NoError(t, bh.todoHandler.Load())
+
+	todos := bh.todoHandler.GetTodos()
+	require.Len(t, todos, 3)
+	for _, todo := range todos {
+		if id, ok := ids[todo.Task]; ok {
+			assert.Equal(t, id, todo.ID, todo.Task)
+		} else {
+			assert.NotContains(t, before, todo.ID)
+		}
+	}
+}
+
+func TestTodoIDs_DuplicateTasks(t *testing.T) {
+	bh, buddyPath := newTestBuddyHandlers(t, map[string]string{
+		"todos/release.md": "# Feature: release\n\n- [ ] Run tests\n- [ ] Tag the build\n- [ ] Run tests\n",
+	})
+	todos := bh.todoHandler.GetTodos()
+	require.Len(t, todos, 3)
+	assert.Equal(t, todos[0].Task, todos[2].Task)
+	assert.NotEqual(t, todos[0].ID, todos[2].ID)
+
+	// Completing the second copy checks off its own line
+	_, err := bh.todoHandler.UpdateTodoStatus(context.Background(), todos[2].ID, true)
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(buddyPath, "todos", "release.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Feature: release\n\n- [ ] Run tests\n- [ ] Tag the build\n- [x] Run tests\n", string(content))
+
+	// and keeps its ID, since the copy before it is still there
+	require.NoError(t, bh.todoHandler.Load())
+	reloaded := bh.todoHandler.GetTodos()
+	require.Len(t, reloaded, 3)
+	assert.Equal(t, todos[2].ID, reloaded[2].ID)
+	assert.True(t, reloaded[2].Completed)
+}
+
+func TestArchiveIfComplete(t *testing.T) {
+	const complete = "# Feature: release\n\n- [x] Run tests\n- [x] Tag the build\n"
+	tests := []struct {
+		name    string
+		files   map[string]string
+		archive string // file to archive, relative to todos/
+		want    string // where it went, relative to todos/; empty when it stays
+	}{
+		{
+			name:    "complete",
+			files:   map[string]string{"todos/release.md": complete},
+			archive: "release.md",
+			want:    "archive/release.md",
+		},
+		{
+			name:    "nested path is kept",
+			files:   map[string]string{"todos/web/release.md": complete},
+			archive: "web/release.md",
+			want:    "archive/web/release.md",
+		},
+		{
+			name: "name taken in the archive",
+			files: map[string]string{
+				"todos/release.md":         complete,
+				"todos/archive/release.md": "# Feature: old release\n\n- [x] Ship v1\n",
+			},
+			archive: "release.md",
+			want:    "archive/release-2.md",
+		},
+		{
+			name:    "partly complete",
+			files:   map[string]string{"todos/release.md": "# Feature: release\n\n- [x] Run tests\n- [ ] Tag the build\n"},
+			archive: "release.md",
+		},
+		{
+			name:    "already archived",
+			files:   map[string]string{"todos/archive/release.md": complete},
+			archive: "archive/release.md",
+		},
+		{
+			name:    "imported code todos",
+			files:   map[string]string{"todos/code-todos.md": "# Feature: Code TODOs\n\n- [x] main.go:12 drop the flag\n"},
+			archive: "code-todos.md",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bh, buddyPath := newTestBuddyHandlers(t, tt.files)
+			todosPath := filepath.Join(buddyPath, "todos")
+			source := filepath.Join(todosPath, tt.archive)
+
+			target, err := bh.todoHandler.ArchiveIfComplete(context.Background(), source)
+			require.NoError(t, err)
+			if tt.want == "" {
+				assert.Empty(t, target)
+				assert.FileExists(t, source)
+				return
+			}
+			assert.Equal(t, filepath.Join(todosPath, tt.want), target)
+			assert.NoFileExists(t, source)
+			content, err := os.ReadFile(target)
+			require.NoError(t, err)
+			assert.Equal(t, complete, string(content))
+			for name, content := range tt.files {
+				if path := filepath.Join(buddyPath, name); path != source {
+					kept, err := os.ReadFile(path)
+					require.NoError(t, err)
+					assert.Equal(t, content, string(kept), "archiving doesn't touch %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveIfComplete_ReloadsIDsAndIndex(t *testing.T) {
+	bh, buddyPath := newTestBuddyHandlers(t, map[string]string{
+		"todos/release.md": "# Feature: release\n\n- [x] Run tests\n- [x] Tag the build\n",
+	})
+	before := bh.todoHandler.GetTodos()
+	require.Len(t, before, 2)
+
+	target, err := bh.todoHandler.ArchiveIfComplete(context.Background(), filepath.Join(buddyPath, "todos", "release.md"))
+	require.NoError(t, err)
+
+	// The todos now live in the archived file, under new IDs since IDs
+	// hash the path within todos/
+	after := bh.todoHandler.GetTodos()
+	require.Len(t, after, 2)
+	for i, todo := range after {
+		assert.Equal(t, before[i].Task, todo.Task)
+		assert.Equal(t, target, todo.FilePath)
+		assert.True(t, todo.Archived)
+		assert.NotEqual(t, before[i].ID, todo.ID)
+		_, ok := bh.todoHandler.Get(before[i].ID)
+		assert.False(t, ok, "old ID %s is gone", before[i].ID)
+	}
+
+	// Search finds them under the new IDs only
+	found, err := bh.todoHandler.SearchDocuments(context.Background(), "tag", nil, 10)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, after[1].ID, found[0].ID)
+	assert.True(t, found[0].Archived)
+}
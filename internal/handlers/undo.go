@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/logging"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+)
+
+// maxUndoEntries bounds how many tool calls the undo log remembers per session
+const maxUndoEntries = 50
+
+// UndoChange is the inverse of one file mutation: the file's content before
+// the tool call, or its absence, and what the call left behind
+type UndoChange struct {
+	Path     string
+	Existed  bool
+	Previous []byte
+	Current  []byte // nil when the call removed the file
+	Removed  bool
+	store    storage.Storage // the project storage the change went through
+}
+
+// UndoEntry is every file mutation made by one tool call
+type UndoEntry struct {
+	Tool      string
+	Session   string
+	RequestID string
+	Timestamp time.Time
+	Changes   []UndoChange
+}
+
+// UndoLog records the buddy files each tool call changes so the calls can
+// be reverted, newest first, by the session that made them. Entries live in
+// memory and are lost on restart.
+type UndoLog struct {
+	mu      sync.Mutex
+	entries map[string][]UndoEntry // session ID -> entries, oldest first
+}
+
+// NewUndoLog creates an empty undo log. Register its middleware with the
+// MCP server before use. Changes are reverted through the storage of the
+// project that made them.
+func NewUndoLog() *UndoLog {
+	return &UndoLog{
+		entries: make(map[string][]UndoEntry),
+	}
+}
+
+// undoBatchContextKey carries the changes of the running tool call
+type undoBatchContextKey struct{}
+
+// undoBatch collects a tool call's changes as mutation helpers report them
+type undoBatch struct {
+	mu      sync.Mutex
+	changes []UndoChange
+}
+
+// Middleware records the files each tool call changes. Calls that fail are
+// not recorded; undo itself writes around the log, so it never is.
+func (ul *UndoLog) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		batch := &undoBatch{}
+		result, err := next(context.WithValue(ctx, undoBatchContextKey{}, batch), request)
+		if err != nil || (result != nil && result.IsError) {
+			return result, err
+		}
+
+		batch.mu.Lock()
+		changes := batch.changes
+		batch.mu.Unlock()
+		if len(changes) > 0 {
+			ul.add(UndoEntry{
+				Tool:      request.Params.Name,
+				Session:   undoSession(ctx),
+				RequestID: logging.RequestID(ctx),
+				Timestamp: time.Now().UTC(),
+				Changes:   changes,
+			})
+		}
+		return result, err
+	}
+}
+
+// undoSession names the MCP session of a call; calls outside one share ""
+func undoSession(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+func (ul *UndoLog) add(entry UndoEntry) {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+	entries := append(ul.entries[entry.Session], entry)
+	if len(entries) > maxUndoEntries {
+		entries = entries[len(entries)-maxUndoEntries:]
+	}
+	ul.entries[entry.Session] = entries
+}
+
+// RegisterHooks forgets a session's undoable calls when its client
+// disconnects, as no later call can undo them
+func (ul *UndoLog) RegisterHooks(hooks *server.Hooks) {
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		ul.mu.Lock()
+		delete(ul.entries, session.SessionID())
+		ul.mu.Unlock()
+	})
+}
+
+// Entries returns a session's undoable tool calls, newest first
+func (ul *UndoLog) Entries(session string) []UndoEntry {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+	entries := ul.entries[session]
+	newest := make([]UndoEntry, len(entries))
+	for i, entry := range entries {
+		newest[len(entries)-1-i] = entry
+	}
+	return newest
+}
+
+// ErrUndoConflict is returned when a file changed after the tool call being
+// undone, so reverting it would discard those edits
+var ErrUndoConflict = errors.New("file changed since the tool call")
+
+// Undo reverts a session's last n tool calls, newest first, stopping at the
+// first call whose files were edited since. It returns the calls reverted.
+func (ul *UndoLog) Undo(ctx context.Context, session string, n int) ([]UndoEntry, error) {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+
+	var undone []UndoEntry
+	for len(undone) < n {
+		entries := ul.entries[session]
+		if len(entries) == 0 {
+			break
+		}
+		entry := entries[len(entries)-1]
+		if err := revertEntry(entry); err != nil {
+			return undone, fmt.Errorf("cannot undo %s: %w", entry.Tool, err)
+		}
+		ul.entries[session] = entries[:len(entries)-1]
+		undone = append(undone, entry)
+		slog.InfoContext(ctx, "undid tool call", "tool", entry.Tool, "files", len(entry.Changes), "original_request_id", entry.RequestID)
+	}
+	return undone, nil
+}
+
+// revertEntry restores every file an entry changed, after checking none
+// was edited since
+func revertEntry(entry UndoEntry) error {
+	for _, change := range entry.Changes {
+		content, err := change.store.Read(change.Path)
+		exists := err == nil
+		if err != nil && !storage.IsNotExist(err) {
+			return err
+		}
+		if exists == change.Removed || (exists && !bytes.Equal(content, change.Current)) {
+			return fmt.Errorf("%s: %w", change.Path, ErrUndoConflict)
+		}
+	}
+
+	for i := len(entry.Changes) - 1; i >= 0; i-- {
+		change := entry.Changes[i]
+		var err error
+		if change.Existed {
+			err = change.store.Write(change.Path, change.Previous)
+		} else {
+			err = change.store.Remove(change.Path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", change.Path, err)
+		}
+	}
+	return nil
+}
+
+// writeFile writes a buddy file through store, recording the change in the
+// running tool call's undo batch
+func writeFile(ctx context.Context, store storage.Storage, path string, data []byte) error {
+	batch, _ := ctx.Value(undoBatchContextKey{}).(*undoBatch)
+	if batch == nil {
+		return store.Write(path, data)
+	}
+	previous, existed, err := readExisting(store, path)
+	if err != nil {
+		return err
+	}
+	if err := store.Write(path, data); err != nil {
+		return err
+	}
+	batch.record(UndoChange{Path: path, Existed: existed, Previous: previous, Current: append([]byte(nil), data...), store: store})
+	return nil
+}
+
+// removeFile removes a buddy file through store, recording the change in
+// the running tool call's undo batch
+func removeFile(ctx context.Context, store storage.Storage, path string) error {
+	batch, _ := ctx.Value(undoBatchContextKey{}).(*undoBatch)
+	if batch == nil {
+		return store.Remove(path)
+	}
+	previous, existed, err := readExisting(store, path)
+	if err != nil {
+		return err
+	}
+	if err := store.Remove(path); err != nil {
+		return err
+	}
+	batch.record(UndoChange{Path: path, Existed: existed, Previous: previous, Removed: true, store: store})
+	return nil
+}
+
+// readExisting returns a file's content and whether it exists
+func readExisting(store storage.Storage, path string) ([]byte, bool, error) {
+	content, err := store.Read(path)
+	if storage.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
+}
+
+// record adds a change, merging it with an earlier change to the same file
+// so undo restores the state from before the call
+func (b *undoBatch) record(change UndoChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, earlier := range b.changes {
+		if earlier.Path == change.Path {
+			change.Existed, change.Previous = earlier.Existed, earlier.Previous
+			b.changes = append(b.changes[:i], b.changes[i+1:]...)
+			break
+		}
+	}
+	b.changes = append(b.changes, change)
+}
+
+// UndoArgs are the arguments of buddy_undo
+type UndoArgs struct {
+	Action string `arg:"action" enum:"undo,list" desc:"undo (default) reverts calls; list shows what can be undone"`
+	Count  *int   `arg:"count" desc:"How many tool calls to revert, newest first (default: 1)"`
+}
+
+// GetToolHandler returns the tool handler that reverts the caller's
+// session's last tool calls, calling reload once files were restored
+func (ul *UndoLog) GetToolHandler(reload func() error) server.ToolHandlerFunc {
+	return toolargs.Handler(func(ctx context.Context, args UndoArgs) (*mcp.CallToolResult, error) {
+		session := undoSession(ctx)
+
+		if args.Action == "list" {
+			entries := ul.Entries(session)
+			if len(entries) == 0 {
+				return mcp.NewToolResultText("Nothing to undo in this session."), nil
+			}
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "↩️ %d undoable tool calls, newest first:\n\n", len(entries))
+			for i, entry := range entries {
+				fmt.Fprintf(&sb, "%d. %s at %s\n", i+1, entry.Tool, entry.Timestamp.Format(time.RFC3339))
+				for _, change := range entry.Changes {
+					fmt.Fprintf(&sb, "   - %s\n", describeUndoChange(change))
+				}
+			}
+			return mcp.NewToolResultText(sb.String()), nil
+		}
+
+		count := 1
+		if args.Count != nil {
+			count = *args.Count
+		}
+		if count < 1 {
+			return nil, fmt.Errorf("count must be at least 1")
+		}
+
+		undone, err := ul.Undo(ctx, session, count)
+		if len(undone) > 0 && reload != nil {
+			if err := reload(); err != nil {
+				slog.WarnContext(ctx, "failed to reload after undo", "error", err)
+			}
+		}
+		if err != nil && len(undone) == 0 {
+			return nil, err
+		}
+		if len(undone) == 0 {
+			return mcp.NewToolResultText("Nothing to undo in this session."), nil
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "↩️ Undid %d tool calls:\n\n", len(undone))
+		for _, entry := range undone {
+			fmt.Fprintf(&sb, "- %s (%d files)\n", entry.Tool, len(entry.Changes))
+		}
+		if err != nil {
+			fmt.Fprintf(&sb, "\n⚠️ Stopped early: %v\n", err)
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	})
+}
+
+// describeUndoChange says what undoing a change does to its file
+func describeUndoChange(change UndoChange) string {
+	switch {
+	case !change.Existed:
+		return "remove " + change.Path
+	case change.Removed:
+		return "recreate " + change.Path
+	default:
+		return "restore " + change.Path
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSession is a client session known only by its ID
+type testSession struct{ id string }
+
+func (s testSession) Initialize()                                         {}
+func (s testSession) Initialized() bool                                   { return true }
+func (s testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+func (s testSession) SessionID() string                                   { return s.id }
+
+func TestUndoLog_ForgetsUnregisteredSessions(t *testing.T) {
+	ul := NewUndoLog()
+	hooks := &server.Hooks{}
+	ul.RegisterHooks(hooks)
+
+	ul.add(UndoEntry{Tool: "buddy_focus", Session: "gone"})
+	ul.add(UndoEntry{Tool: "buddy_focus", Session: "staying"})
+
+	require.Len(t, hooks.OnUnregisterSession, 1)
+	hooks.OnUnregisterSession[0](context.Background(), testSession{id: "gone"})
+
+	assert.Empty(t, ul.Entries("gone"))
+	assert.Len(t, ul.Entries("staying"), 1)
+	ul.mu.Lock()
+	assert.NotContains(t, ul.entries, "gone")
+	ul.mu.Unlock()
+}
@@ -0,0 +1,573 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/timeutil"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/toolargs"
+	"gopkg.in/yaml.v3"
+)
+
+// viewSearchLimit caps the full-text matches a view's search keeps
+const viewSearchLimit = 200
+
+// viewFields lists, per source a view can read, the fields its where
+// clause may filter on
+var viewFields = map[string][]string{
+	"rules":     {"category", "priority", "tag", "language"},
+	"knowledge": {"category", "tag", "language"},
+	"todos":     {"feature", "completed"},
+	"history":   {"feature"},
+}
+
+// viewName is what a view file may be called, without its extension, so
+// the name can be used in resource URIs as it is
+var viewName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// viewPlaceholder is a {{param}} reference in a view's values
+var viewPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// View is a saved query over the buddy content, defined by a YAML file in
+// .buddy/views. It reads one source, keeps the items matching all of its
+// filters and, with Files, only those related to the files recent history
+// entries changed. Values may use {{param}} placeholders for its Params.
+type View struct {
+	Name        string            `yaml:"-" json:"name"` // the file name without its extension
+	Description string            `yaml:"description" json:"description,omitempty"`
+	From        string            `yaml:"from" json:"from"`
+	Where       map[string]string `yaml:"where" json:"where,omitempty"` // field -> value, or comma-separated alternatives
+	Search      string            `yaml:"search" json:"search,omitempty"`
+	Since       string            `yaml:"since" json:"since,omitempty"` // items updated at or after this time
+	Files       *ViewFiles        `yaml:"files" json:"files,omitempty"`
+	Limit       int               `yaml:"limit" json:"limit,omitempty"`
+	Params      map[string]string `yaml:"params" json:"params,omitempty"` // name -> default; empty means required
+	FilePath    string            `yaml:"-" json:"file_path"`
+}
+
+// ViewFiles joins a view to history: only items related to a file changed
+// by the matching history entries are kept. Rules are related when one of
+// their applies_to globs matches the file; knowledge and todos when they
+// name it; history entries when they changed it.
+type ViewFiles struct {
+	ChangedSince string `yaml:"changed_since" json:"changed_since,omitempty"`
+	Feature      string `yaml:"feature" json:"feature,omitempty"`
+}
+
+// ViewItem is one result of a view
+type ViewItem struct {
+	ID        string      `json:"id"`
+	Title     string      `json:"title"`
+	Detail    string      `json:"detail,omitempty"` // e.g. a rule's priority and category
+	Path      string      `json:"path"`             // relative to the buddy folder
+	UpdatedAt time.Time   `json:"updated_at"`
+	Document  interface{} `json:"document"`
+	text      string      // shown under the title in text output
+}
+
+// ViewResult is the outcome of running a view
+type ViewResult struct {
+	View        string            `json:"view"`
+	Description string            `json:"description,omitempty"`
+	From        string            `json:"from"`
+	Params      map[string]string `json:"params,omitempty"`
+	Files       []string          `json:"files,omitempty"` // the changed files items had to relate to
+	Total       int               `json:"total"`           // matches before the view's limit
+	Items       []ViewItem        `json:"items"`
+}
+
+// ViewsHandler loads the views in .buddy/views
+type ViewsHandler struct {
+	*DocumentHandler[View]
+}
+
+// NewViewsHandler creates a new views handler. Views aren't searchable, so
+// it keeps no index.
+func NewViewsHandler(path string) *ViewsHandler {
+	return &ViewsHandler{
+		DocumentHandler: NewDocumentHandler(path, nil, DocumentSpec[View]{
+			Extensions: []string{".yaml", ".yml"},
+			Parse:      parseViewFile,
+			ID:         func(view View) string { return view.Name },
+			Less:       func(a, b View) bool { return a.Name < b.Name },
+		}),
+	}
+}
+
+// parseViewFile parses and checks a view definition. Unknown keys are
+// errors, so a misspelled filter can't silently widen the view.
+func parseViewFile(file storage.FileInfo, content []byte) ([]View, error) {
+	var view View
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&view); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid view: %w", err)
+	}
+	view.Name = strings.TrimSuffix(filepath.Base(file.Path), filepath.Ext(file.Path))
+	view.FilePath = file.Path
+
+	if !viewName.MatchString(view.Name) {
+		return nil, fmt.Errorf("invalid view name %q: use lowercase letters, digits, - and _", view.Name)
+	}
+	fields, ok := viewFields[view.From]
+	if !ok {
+		return nil, fmt.Errorf("invalid from %q (expected rules, knowledge, todos or history)", view.From)
+	}
+	for _, field := range slices.Sorted(maps.Keys(view.Where)) {
+		if !slices.Contains(fields, field) {
+			return nil, fmt.Errorf("%s can't be filtered by %q (expected %s)", view.From, field, strings.Join(fields, ", "))
+		}
+	}
+	if view.Limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+	for _, value := range view.values() {
+		for _, match := range viewPlaceholder.FindAllStringSubmatch(value, -1) {
+			if _, declared := view.Params[match[1]]; !declared {
+				return nil, fmt.Errorf("{{%s}} is not declared in params", match[1])
+			}
+		}
+	}
+	return []View{view}, nil
+}
+
+// values returns the view's values that may hold placeholders
+func (v View) values() []string {
+	values := []string{v.Search, v.Since}
+	for _, field := range slices.Sorted(maps.Keys(v.Where)) {
+		values = append(values, v.Where[field])
+	}
+	if v.Files != nil {
+		values = append(values, v.Files.ChangedSince, v.Files.Feature)
+	}
+	return values
+}
+
+// expand fills a view's placeholders with the given parameter values,
+// which override its defaults
+func (v View) expand(given map[string]string) (View, map[string]string, error) {
+	values := make(map[string]string, len(v.Params))
+	for name, value := range v.Params {
+		values[name] = value
+	}
+	for _, name := range slices.Sorted(maps.Keys(given)) {
+		value := given[name]
+		if _, declared := v.Params[name]; !declared {
+			return v, nil, fmt.Errorf("view %s has no param %q", v.Name, name)
+		}
+		values[name] = value
+	}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if strings.TrimSpace(values[name]) == "" {
+			return v, nil, fmt.Errorf("view %s needs a value for param %q", v.Name, name)
+		}
+	}
+
+	fill := func(text string) string {
+		return viewPlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+			return values[viewPlaceholder.FindStringSubmatch(placeholder)[1]]
+		})
+	}
+	expanded := v
+	expanded.Search = fill(v.Search)
+	expanded.Since = fill(v.Since)
+	expanded.Where = make(map[string]string, len(v.Where))
+	for field, value := range v.Where {
+		expanded.Where[field] = fill(value)
+	}
+	if v.Files != nil {
+		expanded.Files = &ViewFiles{ChangedSince: fill(v.Files.ChangedSince), Feature: fill(v.Files.Feature)}
+	}
+	return expanded, values, nil
+}
+
+// viewCandidate is an item of a view's source with what its filters test
+type viewCandidate struct {
+	item    ViewItem
+	fields  map[string][]string
+	related func(files map[string]bool) bool
+	rank    int // orders rules by priority; zero for other sources
+}
+
+// viewCandidates lists the items of a source in the snapshot
+func (bh *BuddyHandlers) viewCandidates(snap *ContextSnapshot, from string) []viewCandidate {
+	path := func(file string) string {
+		return filepath.ToSlash(relativeTo(bh.buddyPath, file))
+	}
+	var candidates []viewCandidate
+	switch from {
+	case "rules":
+		for _, rule := range snap.Rules {
+			candidates = append(candidates, viewCandidate{
+				item: ViewItem{ID: rule.ID, Title: rule.Title, Detail: rule.Priority + " · " + rule.Category,
+					Path: path(rule.FilePath), UpdatedAt: rule.UpdatedAt, Document: rule, text: rule.Description},
+				fields:  map[string][]string{"category": {rule.Category}, "priority": {rule.Priority}, "tag": rule.Tags, "language": {rule.Language}},
+				related: func(files map[string]bool) bool { return appliesToAny(rule, files) },
+				rank:    rulePriorityRank(rule),
+			})
+		}
+	case "knowledge":
+		for _, doc := range snap.Knowledge {
+			candidates = append(candidates, viewCandidate{
+				item: ViewItem{ID: doc.ID, Title: doc.Title, Detail: doc.Category,
+					Path: path(doc.FilePath), UpdatedAt: doc.UpdatedAt, Document: doc, text: doc.Content},
+				fields:  map[string][]string{"category": {doc.Category}, "tag": doc.Tags, "language": {doc.Language}},
+				related: func(files map[string]bool) bool { return mentionsFile(doc.Content, files) },
+			})
+		}
+	case "todos":
+		for _, todo := range snap.Todos {
+			state := "open"
+			if todo.Completed {
+				state = "done"
+			}
+			candidates = append(candidates, viewCandidate{
+				item: ViewItem{ID: todo.ID, Title: todo.Task, Detail: todo.Feature + " · " + state,
+					Path: path(todo.FilePath), UpdatedAt: todo.UpdatedAt, Document: todo},
+				fields:  map[string][]string{"feature": {todo.Feature}, "completed": {strconv.FormatBool(todo.Completed)}},
+				related: func(files map[string]bool) bool { return mentionsFile(todo.Task, files) },
+			})
+		}
+	case "history":
+		for _, entry := range snap.History {
+			candidates = append(candidates, viewCandidate{
+				item: ViewItem{ID: entry.ID, Title: entry.Description, Detail: entry.Feature,
+					Path: path(entry.FilePath), UpdatedAt: entry.Timestamp, Document: entry, text: entry.Reasoning},
+				fields: map[string][]string{"feature": {entry.Feature}},
+				related: func(files map[string]bool) bool {
+					for _, change := range entry.Changes {
+						if files[filepath.ToSlash(change.FilePath)] {
+							return true
+						}
+					}
+					return false
+				},
+			})
+		}
+	}
+	return candidates
+}
+
+// viewSearch returns the IDs of the source's documents matching query in
+// its search index
+func (bh *BuddyHandlers) viewSearch(ctx context.Context, from, query string) (map[string]bool, error) {
+	switch from {
+	case "rules":
+		return searchIDs(ctx, bh.rulesHandler.DocumentHandler, query)
+	case "knowledge":
+		return searchIDs(ctx, bh.knowledgeHandler.DocumentHandler, query)
+	case "todos":
+		return searchIDs(ctx, bh.todoHandler.DocumentHandler, query)
+	default:
+		return searchIDs(ctx, bh.historyHandler.DocumentHandler, query)
+	}
+}
+
+// searchIDs runs a full-text search and returns the IDs of the matches
+func searchIDs[T any](ctx context.Context, dh *DocumentHandler[T], query string) (map[string]bool, error) {
+	docs, err := dh.SearchDocuments(ctx, query, nil, viewSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		ids[dh.spec.ID(doc)] = true
+	}
+	return ids, nil
+}
+
+// RunView runs a view over the current snapshot. params override the
+// view's defaults.
+func (bh *BuddyHandlers) RunView(ctx context.Context, view View, params map[string]string) (*ViewResult, error) {
+	view, values, err := view.expand(params)
+	if err != nil {
+		return nil, err
+	}
+	snap := bh.Snapshot()
+	now := time.Now()
+	loc := bh.timeFormat.Location()
+
+	var since time.Time
+	if view.Since != "" {
+		if since, err = timeutil.ParseTime(view.Since, now, loc); err != nil {
+			return nil, fmt.Errorf("invalid since in view %s: %w", view.Name, err)
+		}
+	}
+	var matches map[string]bool
+	if strings.TrimSpace(view.Search) != "" {
+		if matches, err = bh.viewSearch(ctx, view.From, view.Search); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ViewResult{View: view.Name, Description: view.Description, From: view.From, Params: values, Items: []ViewItem{}}
+	var files map[string]bool
+	if view.Files != nil {
+		var changedSince time.Time
+		if view.Files.ChangedSince != "" {
+			if changedSince, err = timeutil.ParseTime(view.Files.ChangedSince, now, loc); err != nil {
+				return nil, fmt.Errorf("invalid files.changed_since in view %s: %w", view.Name, err)
+			}
+		}
+		files = make(map[string]bool)
+		result.Files = []string{}
+		for _, entry := range snap.History {
+			if entry.Timestamp.Before(changedSince) || (view.Files.Feature != "" && !strings.EqualFold(entry.Feature, view.Files.Feature)) {
+				continue
+			}
+			for _, change := range entry.Changes {
+				files[filepath.ToSlash(change.FilePath)] = true
+			}
+		}
+		for file := range files {
+			result.Files = append(result.Files, file)
+		}
+		sort.Strings(result.Files)
+	}
+
+	var kept []viewCandidate
+	for _, candidate := range bh.viewCandidates(snap, view.From) {
+		if !since.IsZero() && candidate.item.UpdatedAt.Before(since) {
+			continue
+		}
+		if matches != nil && !matches[candidate.item.ID] {
+			continue
+		}
+		if files != nil && !candidate.related(files) {
+			continue
+		}
+		if matchesViewWhere(candidate.fields, view.Where) {
+			kept = append(kept, candidate)
+		}
+	}
+	// Rules read best by priority, the rest newest first
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].rank != kept[j].rank {
+			return kept[i].rank < kept[j].rank
+		}
+		return kept[i].item.UpdatedAt.After(kept[j].item.UpdatedAt)
+	})
+
+	result.Total = len(kept)
+	if view.Limit > 0 && len(kept) > view.Limit {
+		kept = kept[:view.Limit]
+	}
+	for _, candidate := range kept {
+		result.Items = append(result.Items, candidate.item)
+	}
+	return result, nil
+}
+
+// matchesViewWhere reports whether the fields hold every filtered value,
+// or one of its comma-separated alternatives, ignoring case
+func matchesViewWhere(fields map[string][]string, where map[string]string) bool {
+	for field, want := range where {
+		found := false
+		for _, alternative := range strings.Split(want, ",") {
+			for _, value := range fields[field] {
+				if strings.EqualFold(strings.TrimSpace(alternative), value) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// findView returns the loaded view called name
+func (bh *BuddyHandlers) findView(name string) (View, error) {
+	if view, ok := bh.viewsHandler.Get(name); ok {
+		return view, nil
+	}
+	var names []string
+	for _, view := range bh.viewsHandler.Documents() {
+		names = append(names, view.Name)
+	}
+	if len(names) == 0 {
+		return View{}, fmt.Errorf("unknown view %q: no views are defined in views/", name)
+	}
+	return View{}, fmt.Errorf("unknown view %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// ViewsArgs are the arguments of buddy_views
+type ViewsArgs struct {
+	Name   string            `arg:"name" desc:"View to run, as named by its file in .buddy/views; omit to list the views"`
+	Params map[string]string `arg:"params" desc:"Values for the view's params, overriding their defaults, e.g. {\"feature\": \"checkout\"}"`
+	Output string            `arg:"output" enum:"text,json" desc:"text (default) or json"`
+	TokenBudgetArgs
+}
+
+// GetViewsToolHandler returns the tool handler that lists and runs views
+func (bh *BuddyHandlers) GetViewsToolHandler() server.ToolHandlerFunc {
+	return toolargs.Handler(bh.viewsTool)
+}
+
+func (bh *BuddyHandlers) viewsTool(ctx context.Context, args ViewsArgs) (*mcp.CallToolResult, error) {
+	if args.Name == "" {
+		return mcp.NewToolResultText(bh.formatViewList()), nil
+	}
+	view, err := bh.findView(args.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := bh.RunView(ctx, view, args.Params)
+	if err != nil {
+		return nil, err
+	}
+	switch args.Output {
+	case "", "text":
+		return mcp.NewToolResultText(bh.formatViewResult(result)), nil
+	case "json":
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal view: %w", err)
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	default:
+		return nil, fmt.Errorf("invalid output: %s (expected text or json)", args.Output)
+	}
+}
+
+// GetViewResourceHandler returns the resource template handler for
+// buddy://views/<name>, whose query parameters are the view's params
+func (bh *BuddyHandlers) GetViewResourceHandler() server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		uri, err := url.Parse(request.Params.URI)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resource URI: %w", err)
+		}
+		view, err := bh.findView(strings.TrimPrefix(uri.Path, "/"))
+		if err != nil {
+			return nil, err
+		}
+		params := make(map[string]string)
+		for key, values := range uri.Query() {
+			params[key] = values[len(values)-1]
+		}
+
+		// A view reads the snapshot, and the view files reload into a new
+		// one. Relative times move with the clock, hence the age limit.
+		return bh.readCachedResource(request, time.Minute, func(snap *ContextSnapshot, now time.Time) (map[string]interface{}, error) {
+			result, err := bh.RunView(ctx, view, params)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"view":        result.View,
+				"description": result.Description,
+				"from":        result.From,
+				"params":      result.Params,
+				"files":       result.Files,
+				"total":       result.Total,
+				"items":       result.Items,
+			}, nil
+		})
+	}
+}
+
+// formatViewList describes the defined views and their params
+func (bh *BuddyHandlers) formatViewList() string {
+	views := bh.viewsHandler.Documents()
+	if len(views) == 0 {
+		return "No views defined. Add a YAML file to .buddy/views, e.g. critical-changes.yaml:\n\n" +
+			"description: Critical rules for files changed this week\n" +
+			"from: rules\n" +
+			"where:\n  priority: critical\n" +
+			"files:\n  changed_since: last 7 days\n"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔭 %d views\n", len(views))
+	for _, view := range views {
+		fmt.Fprintf(&sb, "\n%s (from %s)", view.Name, view.From)
+		if view.Description != "" {
+			fmt.Fprintf(&sb, ": %s", view.Description)
+		}
+		sb.WriteString("\n")
+		if len(view.Params) > 0 {
+			names := make([]string, 0, len(view.Params))
+			for name := range view.Params {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for i, name := range names {
+				if view.Params[name] == "" {
+					names[i] = name + " (required)"
+				} else {
+					names[i] = fmt.Sprintf("%s (default %s)", name, view.Params[name])
+				}
+			}
+			fmt.Fprintf(&sb, "   Params: %s\n", strings.Join(names, ", "))
+		}
+	}
+	sb.WriteString("\n💡 Run one with name, or read buddy://views/<name>")
+	return sb.String()
+}
+
+// formatViewResult lists a view's items with a short excerpt of each
+func (bh *BuddyHandlers) formatViewResult(result *ViewResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔭 %s", result.View)
+	if result.Description != "" {
+		fmt.Fprintf(&sb, ": %s", result.Description)
+	}
+	sb.WriteString("\n")
+	if len(result.Params) > 0 {
+		var pairs []string
+		for name, value := range result.Params {
+			pairs = append(pairs, name+"="+value)
+		}
+		sort.Strings(pairs)
+		fmt.Fprintf(&sb, "Params: %s\n", strings.Join(pairs, ", "))
+	}
+	if result.Files != nil {
+		fmt.Fprintf(&sb, "Changed files: %d\n", len(result.Files))
+	}
+	if result.Total == 0 {
+		fmt.Fprintf(&sb, "\nNo %s match\n", result.From)
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "Found %d %s", result.Total, result.From)
+	if len(result.Items) < result.Total {
+		fmt.Fprintf(&sb, ", showing %d", len(result.Items))
+	}
+	sb.WriteString("\n")
+	for i, item := range result.Items {
+		fmt.Fprintf(&sb, "\n%d. %s", i+1, item.Title)
+		if item.Detail != "" {
+			fmt.Fprintf(&sb, " (%s)", item.Detail)
+		}
+		fmt.Fprintf(&sb, "\n   %s · %s\n", item.Path, bh.timeFormat.Format(item.UpdatedAt))
+		text := strings.TrimSpace(item.text)
+		if len(text) > 300 {
+			text = text[:300] + "..."
+		}
+		for _, line := range strings.Split(text, "\n") {
+			if strings.TrimSpace(line) != "" {
+				fmt.Fprintf(&sb, "   %s\n", strings.TrimSpace(line))
+			}
+		}
+	}
+	return sb.String()
+}
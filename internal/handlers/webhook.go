@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// webhookTimeout bounds each webhook delivery
+const webhookTimeout = 10 * time.Second
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// criticalRuleEvent is the JSON body posted to the critical rule webhook
+type criticalRuleEvent struct {
+	Event     string    `json:"event"`
+	Project   string    `json:"project"`
+	Path      string    `json:"path"` // relative to the buddy folder
+	Title     string    `json:"title,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// AnnounceCriticalRule posts a critical rule's change to the configured
+// webhook, if there is one. Delivery happens in the background; failures
+// are logged.
+func (bh *BuddyHandlers) AnnounceCriticalRule(project, path string) {
+	url := strings.TrimSpace(os.ExpandEnv(bh.Config().Notifications.CriticalRuleWebhook))
+	if url == "" {
+		return
+	}
+	event := criticalRuleEvent{
+		Event:     "critical_rule_changed",
+		Project:   project,
+		Path:      path,
+		ChangedAt: time.Now().UTC(),
+	}
+	if rel, err := filepath.Rel(bh.buddyPath, path); err == nil {
+		event.Path = filepath.ToSlash(rel)
+	}
+	for _, rule := range bh.rulesHandler.GetRules() {
+		if rule.FilePath == path {
+			event.Title = rule.Title
+			event.Category = rule.Category
+			break
+		}
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("failed to encode webhook event", "error", err)
+		return
+	}
+	go func() {
+		if err := postWebhook(url, body); err != nil {
+			slog.Warn("critical rule webhook failed", "path", event.Path, "error", err)
+		}
+	}()
+}
+
+// postWebhook posts a JSON body, treating non-2xx responses as errors
+func postWebhook(url string, body []byte) error {
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// Package language guesses the natural language of buddy documents so
+// they can be indexed and searched with an analyzer for that language.
+// Detection is deliberately simple: a handful of common words per language
+// for Latin scripts and the script itself for the rest. Short or mixed
+// text is reported as unknown rather than guessed.
+package language
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// minHits is how many common words text must contain before a Latin-script
+// language is named
+const minHits = 4
+
+// names are the languages Detect can report, by ISO 639-1 code
+var names = map[string]string{
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"zh": "Chinese",
+}
+
+// commonWords are frequent function words of each Latin-script language.
+// Words several languages share count for each of them.
+var commonWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "as", "are", "be", "this",
+		"on", "by", "not", "or", "from", "at", "an", "which", "when", "you", "should", "we", "have",
+		"will", "can", "if", "was", "they", "their", "but", "all"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "den", "dem", "ein", "eine", "einen", "zu",
+		"von", "für", "auf", "sich", "es", "im", "wird", "werden", "auch", "wenn", "oder", "nach", "bei",
+		"aus", "wir", "sie", "sind", "noch", "kann", "muss", "nur", "über"},
+	"es": {"el", "la", "los", "las", "y", "es", "de", "del", "en", "un", "una", "que", "por", "para",
+		"con", "no", "se", "lo", "al", "como", "más", "pero", "su", "sus", "este", "esta", "son", "está",
+		"hay", "debe", "puede", "cuando", "sin", "sobre"},
+	"fr": {"le", "la", "les", "et", "est", "des", "du", "un", "une", "en", "dans", "pour", "que", "qui",
+		"sur", "pas", "ne", "au", "aux", "avec", "par", "ce", "cette", "sont", "nous", "vous", "il",
+		"elle", "être", "mais", "ou", "peut", "doit", "plus"},
+	"it": {"il", "lo", "la", "gli", "le", "e", "è", "di", "del", "della", "che", "un", "una", "per",
+		"con", "non", "si", "sono", "da", "in", "al", "alla", "come", "questo", "questa", "ma", "anche",
+		"più", "deve", "può", "quando", "nel", "nella"},
+	"nl": {"de", "het", "een", "en", "is", "van", "in", "op", "te", "dat", "die", "voor", "met", "niet",
+		"zijn", "aan", "er", "ook", "als", "bij", "maar", "om", "wordt", "worden", "kan", "moet", "naar",
+		"wij", "we", "je", "dit", "deze", "of", "door"},
+	"pt": {"o", "a", "os", "as", "e", "é", "de", "do", "da", "dos", "das", "que", "um", "uma", "para",
+		"com", "não", "em", "no", "na", "por", "se", "mais", "como", "mas", "ao", "são", "está", "pode",
+		"deve", "quando", "também", "seu", "sua"},
+}
+
+// wordLanguages maps each common word to the languages using it
+var wordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for code, words := range commonWords {
+		for _, word := range words {
+			index[word] = append(index[word], code)
+		}
+	}
+	return index
+}()
+
+var (
+	// codeBlock and inlineCode are left out of detection: identifiers and
+	// keywords would make every document look English
+	codeBlock  = regexp.MustCompile("(?s)```.*?(```|$)")
+	inlineCode = regexp.MustCompile("`[^`\n]*`")
+)
+
+// Detect returns the ISO 639-1 code of the language text is written in,
+// or "" when it can't tell
+func Detect(text string) string {
+	text = inlineCode.ReplaceAllString(codeBlock.ReplaceAllString(text, " "), " ")
+
+	var letters, kana, hangul, han, cyrillic int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Text mostly in another script is named after the script; Japanese
+	// mixes kana with Han characters, so any kana decides it
+	if other := kana + hangul + han + cyrillic; other*10 >= letters*3 {
+		switch {
+		case kana > 0:
+			return "ja"
+		case hangul >= han && hangul >= cyrillic:
+			return "ko"
+		case han >= cyrillic:
+			return "zh"
+		default:
+			return "ru"
+		}
+	}
+
+	hits := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		for _, code := range wordLanguages[word] {
+			hits[code]++
+		}
+	}
+	best, bestHits, runnerUp := "", 0, 0
+	for code, n := range hits {
+		switch {
+		case n > bestHits:
+			best, bestHits, runnerUp = code, n, bestHits
+		case n > runnerUp:
+			runnerUp = n
+		}
+	}
+	if bestHits < minHits || bestHits == runnerUp {
+		return ""
+	}
+	return best
+}
+
+// Parse returns the code of a language given by code or English name,
+// ignoring case
+func Parse(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if _, ok := names[s]; ok {
+		return s, true
+	}
+	for code, name := range names {
+		if strings.ToLower(name) == s {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// Name returns the English name of a language code, or the code itself
+// when it isn't one Detect reports
+func Name(code string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+// Codes lists the codes Detect can report, sorted
+func Codes() []string {
+	codes := make([]string, 0, len(names))
+	for code := range names {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
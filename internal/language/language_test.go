@@ -0,0 +1,53 @@
+package language
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "All handlers should wrap the errors they return, and the caller decides if it is logged.", "en"},
+		{"german", "Die Datenbank wird bei jedem Start migriert, und es ist nicht nötig, das von Hand zu tun.", "de"},
+		{"french", "Les migrations de la base sont lancées au démarrage et il ne faut pas les exécuter dans un test.", "fr"},
+		{"spanish", "Las migraciones de la base se ejecutan al iniciar y no hay que lanzarlas por separado para los tests.", "es"},
+		{"italian", "Le migrazioni del database sono eseguite all'avvio e non si devono lanciare nella pipeline per questo.", "it"},
+		{"dutch", "De migraties van de database worden bij het starten uitgevoerd en het is niet nodig om dat met de hand te doen.", "nl"},
+		{"portuguese", "As migrações do banco são executadas na inicialização e não é preciso rodar elas em um teste, também.", "pt"},
+		{"russian", "Миграции базы данных выполняются при запуске сервиса.", "ru"},
+		{"japanese", "データベースのマイグレーションは起動時に実行されます。", "ja"},
+		{"korean", "데이터베이스 마이그레이션은 시작할 때 실행됩니다.", "ko"},
+		{"chinese", "数据库迁移在服务启动时执行。", "zh"},
+		{"too short", "Use zap", ""},
+		{"empty", "", ""},
+		{"code is ignored", "Die Migrationen werden beim Start ausgeführt und sind nicht optional.\n\n```go\n// the and of to is in that it for with as are\nif err := db.Migrate(); err != nil {\n```\n", "de"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Detect(tt.text))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	code, ok := Parse("German")
+	assert.True(t, ok)
+	assert.Equal(t, "de", code)
+
+	code, ok = Parse(" FR ")
+	assert.True(t, ok)
+	assert.Equal(t, "fr", code)
+
+	_, ok = Parse("klingon")
+	assert.False(t, ok)
+
+	assert.Equal(t, "Japanese", Name("ja"))
+	assert.Equal(t, "xx", Name("xx"))
+	assert.Contains(t, Codes(), "en")
+}
@@ -0,0 +1,105 @@
+// Package logging configures the process-wide slog logger. Logs go to
+// stderr, never stdout, because stdout carries the MCP stdio transport;
+// they can additionally be written to a file under .buddy/logs/.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DirName is the directory under the buddy path holding log files
+	DirName = "logs"
+	// FileName is the log file written when file output is enabled
+	FileName = "buddy-mcp.log"
+)
+
+// Options configures the logger
+type Options struct {
+	Level  string    // debug, info, warn or error; empty means info
+	Format string    // text or json; empty means text
+	File   string    // optional log file, appended to in addition to Output
+	Output io.Writer // defaults to os.Stderr
+}
+
+// ParseLevel parses a level name, case-insensitively
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q (expected debug, info, warn or error)", name)
+	}
+}
+
+// FilePath returns the log file path for a buddy directory
+func FilePath(buddyPath string) string {
+	return filepath.Join(buddyPath, DirName, FileName)
+}
+
+// New builds a logger from opts. The returned closer closes the log file,
+// if any, and must be called when the logger is no longer used.
+func New(opts Options) (*slog.Logger, io.Closer, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	var closer io.Closer = nopCloser{}
+	if opts.File != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.File), 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
+		file, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		output = io.MultiWriter(output, file)
+		closer = file
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(output, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(output, handlerOpts)
+	default:
+		closer.Close()
+		return nil, nil, fmt.Errorf("invalid log format %q (expected text or json)", opts.Format)
+	}
+
+	return slog.New(contextHandler{handler}), closer, nil
+}
+
+// Setup builds a logger from opts and makes it the default, so slog's
+// top-level functions and the standard log package both write through it
+func Setup(opts Options) (io.Closer, error) {
+	logger, closer, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return closer, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
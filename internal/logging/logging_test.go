@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		want slog.Level
+	}{
+		{"", slog.LevelInfo},
+		{"debug", slog.LevelDebug},
+		{"INFO", slog.LevelInfo},
+		{"warning", slog.LevelWarn},
+		{" error ", slog.LevelError},
+	}
+
+	for _, tt := range tests {
+		level, err := ParseLevel(tt.name)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, level, tt.name)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger, closer, err := New(Options{Level: "warn", Output: &buf})
+	require.NoError(t, err)
+	defer closer.Close()
+
+	logger.Info("hidden")
+	logger.Warn("shown", "path", "rules/style.md")
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "msg=shown")
+	assert.Contains(t, buf.String(), "path=rules/style.md")
+}
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, closer, err := New(Options{Format: "json", Output: &buf})
+	require.NoError(t, err)
+	defer closer.Close()
+
+	logger.Error("reload failed", "section", "rules")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "reload failed", record["msg"])
+	assert.Equal(t, "rules", record["section"])
+}
+
+func TestNew_File(t *testing.T) {
+	path := FilePath(filepath.Join(t.TempDir(), ".buddy"))
+
+	var buf bytes.Buffer
+	logger, closer, err := New(Options{File: path, Output: &buf})
+	require.NoError(t, err)
+
+	logger.Info("first")
+	require.NoError(t, closer.Close())
+
+	// Reopening appends rather than truncating
+	logger, closer, err = New(Options{File: path, Output: &buf})
+	require.NoError(t, err)
+	logger.Info("second")
+	require.NoError(t, closer.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "msg=first")
+	assert.Contains(t, string(content), "msg=second")
+	assert.Equal(t, buf.String(), string(content))
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	_, _, err := New(Options{Level: "loud"})
+	assert.Error(t, err)
+
+	_, _, err = New(Options{Format: "xml"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// RequestIDKey is the attribute request IDs are logged under
+const RequestIDKey = "request_id"
+
+// SessionIDKey is the attribute MCP session IDs are logged under
+const SessionIDKey = "session_id"
+
+// requestIDContextKey is the context key holding a request ID
+type requestIDContextKey struct{}
+
+// sessionIDContextKey is the context key holding an MCP session ID
+type sessionIDContextKey struct{}
+
+// NewRequestID returns a random ID for one request
+func NewRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// WithRequestID returns a copy of ctx carrying id. Lines logged with the
+// context, e.g. through slog.InfoContext, include it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" without one
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// WithSessionID returns a copy of ctx carrying the ID of the client session
+// a request came from, so lines logged with it can be told apart by client
+func WithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionIDContextKey{}, id)
+}
+
+// SessionID returns the session ID carried by ctx, or "" without one
+func SessionID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(sessionIDContextKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request and session IDs from a record's context
+// to the record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	if id := SessionID(ctx); id != "" {
+		record.AddAttrs(slog.String(SessionIDKey, id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	id := NewRequestID()
+	assert.Len(t, id, 16)
+	assert.NotEqual(t, id, NewRequestID())
+
+	ctx := WithRequestID(context.Background(), id)
+	assert.Equal(t, id, RequestID(ctx))
+	assert.Empty(t, RequestID(context.Background()))
+}
+
+func TestSessionID(t *testing.T) {
+	ctx := WithSessionID(context.Background(), "session-1")
+	assert.Equal(t, "session-1", SessionID(ctx))
+	assert.Empty(t, SessionID(context.Background()))
+}
+
+func TestNew_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, closer, err := New(Options{Format: "json", Output: &buf})
+	require.NoError(t, err)
+	defer closer.Close()
+
+	ctx := WithRequestID(context.Background(), "abc123")
+	logger.With("tool", "buddy_backup").InfoContext(ctx, "tool call")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "abc123", record[RequestIDKey])
+	assert.Equal(t, "buddy_backup", record["tool"])
+
+	// Lines logged without a request context carry no ID
+	buf.Reset()
+	logger.Info("started")
+	assert.NotContains(t, buf.String(), RequestIDKey)
+}
+
+func TestNew_SessionID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, closer, err := New(Options{Format: "json", Output: &buf})
+	require.NoError(t, err)
+	defer closer.Close()
+
+	ctx := WithSessionID(WithRequestID(context.Background(), "abc123"), "session-1")
+	logger.InfoContext(ctx, "tool call")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "abc123", record[RequestIDKey])
+	assert.Equal(t, "session-1", record[SessionIDKey])
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	calls := r.NewCounterVec("tool_calls_total", "Tool calls.", "tool", "status")
+
+	calls.Inc("buddy_get_rules", "ok")
+	calls.Inc("buddy_get_rules", "ok")
+	calls.Add(3, "buddy_backup", "error")
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteText(&buf))
+	assert.Equal(t, `# HELP tool_calls_total Tool calls.
+# TYPE tool_calls_total counter
+tool_calls_total{tool="buddy_backup",status="error"} 3
+tool_calls_total{tool="buddy_get_rules",status="ok"} 2
+`, buf.String())
+}
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	latency := r.NewHistogramVec("search_seconds", "Search latency.", []float64{0.1, 1}, "index")
+
+	latency.Observe(0.05, "rules")
+	latency.Observe(0.1, "rules")
+	latency.Observe(0.5, "rules")
+	latency.Observe(2, "rules")
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteText(&buf))
+	assert.Equal(t, `# HELP search_seconds Search latency.
+# TYPE search_seconds histogram
+search_seconds_bucket{index="rules",le="0.1"} 2
+search_seconds_bucket{index="rules",le="1"} 3
+search_seconds_bucket{index="rules",le="+Inf"} 4
+search_seconds_sum{index="rules"} 2.65
+search_seconds_count{index="rules"} 4
+`, buf.String())
+}
+
+func TestGaugeFunc(t *testing.T) {
+	r := NewRegistry()
+	r.NewGaugeFunc("index_documents", "Indexed documents.", []string{"index"}, func() []Sample {
+		return []Sample{
+			{LabelValues: []string{"todos"}, Value: 7},
+			{LabelValues: []string{"knowledge"}, Value: 12},
+		}
+	})
+	r.NewCounterVec("a_total", "Sorted first.")
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteText(&buf))
+	assert.Equal(t, `# HELP a_total Sorted first.
+# TYPE a_total counter
+# HELP index_documents Indexed documents.
+# TYPE index_documents gauge
+index_documents{index="knowledge"} 12
+index_documents{index="todos"} 7
+`, buf.String())
+}
+
+func TestLabelEscaping(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("errors_total", "Errors.", "path").Inc("C:\\rules\\\"x\"\n")
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteText(&buf))
+	assert.Contains(t, buf.String(), `errors_total{path="C:\\rules\\\"x\"\n"} 1`)
+}
+
+func TestRegistry_Misuse(t *testing.T) {
+	r := NewRegistry()
+	calls := r.NewCounterVec("calls_total", "Calls.", "tool")
+
+	assert.Panics(t, func() { r.NewCounterVec("calls_total", "Again.") })
+	assert.Panics(t, func() { calls.Inc("a", "b") })
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("calls_total", "Calls.").Inc()
+
+	recorder := httptest.NewRecorder()
+	r.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "version=0.0.4")
+	assert.Contains(t, recorder.Body.String(), "calls_total 1\n")
+}
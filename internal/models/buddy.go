@@ -4,25 +4,43 @@ import "time"
 
 // Rule represents a coding rule or guideline
 type Rule struct {
-	ID          string    `json:"id"`
-	Category    string    `json:"category"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Priority    string    `json:"priority"` // critical, recommended, optional
-	Content     string    `json:"content"`
-	FilePath    string    `json:"file_path"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string              `json:"id"`
+	Category    string              `json:"category"`
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Priority    string              `json:"priority"`       // critical, recommended, optional
+	Tags        []string            `json:"tags,omitempty"` // cross-cutting concerns, such as security, across categories
+	Content     string              `json:"content"`
+	FilePath    string              `json:"file_path"`
+	Glossary    map[string][]string `json:"glossary,omitempty"` // canonical term -> discouraged aliases
+	Naming      map[string]string   `json:"naming,omitempty"`   // identifier kind -> naming style
+	Forbid      []string            `json:"forbid,omitempty"`   // regular expressions code must not match
+	Tests       *RuleTests          `json:"tests,omitempty"`
+	AppliesTo   []string            `json:"applies_to,omitempty"` // globs of the project files the rule covers
+	Language    string              `json:"language,omitempty"`   // ISO 639-1 code of the text, when detected
+	Extends     string              `json:"extends,omitempty"`    // ID, file name or title of the rule this one builds on
+	Disabled    bool                `json:"disabled,omitempty"`   // kept on disk but left out of the loaded rules
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// RuleTests are sample snippets a rule's checks must accept (Good) or
+// flag (Bad)
+type RuleTests struct {
+	Good []string `json:"good,omitempty" yaml:"good"`
+	Bad  []string `json:"bad,omitempty" yaml:"bad"`
 }
 
 // Knowledge represents a knowledge base entry
 type Knowledge struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Category  string    `json:"category"`
-	Content   string    `json:"content"`
-	Tags      []string  `json:"tags"`
-	FilePath  string    `json:"file_path"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string            `json:"id"`
+	Title     string            `json:"title"`
+	Category  string            `json:"category"`
+	Content   string            `json:"content"`
+	Tags      []string          `json:"tags"`
+	Metadata  map[string]string `json:"metadata,omitempty"` // header fields named in knowledge.fields, by lowercase name
+	Language  string            `json:"language,omitempty"` // ISO 639-1 code, from a Language header or detected
+	FilePath  string            `json:"file_path"`
+	UpdatedAt time.Time         `json:"updated_at"`
 }
 
 // DatabaseInfo represents database schema and connection information
@@ -37,11 +55,13 @@ type DatabaseInfo struct {
 
 // Table represents a database table
 type Table struct {
-	Name        string   `json:"name"`
-	Schema      string   `json:"schema"`
-	Columns     []Column `json:"columns"`
-	Indexes     []Index  `json:"indexes"`
-	Description string   `json:"description"`
+	Name        string       `json:"name"`
+	Schema      string       `json:"schema"`
+	Columns     []Column     `json:"columns"`
+	Indexes     []Index      `json:"indexes"`
+	ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
+	UniqueKeys  [][]string   `json:"unique_keys,omitempty"` // UNIQUE constraints over several columns
+	Description string       `json:"description"`
 }
 
 // Column represents a database column
@@ -51,6 +71,16 @@ type Column struct {
 	Nullable     bool   `json:"nullable"`
 	DefaultValue string `json:"default_value"`
 	Description  string `json:"description"`
+	PII          bool   `json:"pii,omitempty"`         // personal data, masked in sample output
+	PrimaryKey   bool   `json:"primary_key,omitempty"` // alone or with the table's other key columns
+	Unique       bool   `json:"unique,omitempty"`
+}
+
+// ForeignKey represents a column referencing another table
+type ForeignKey struct {
+	Column    string `json:"column"`
+	RefTable  string `json:"ref_table"`
+	RefColumn string `json:"ref_column"`
 }
 
 // Index represents a database index
@@ -68,6 +98,7 @@ type Todo struct {
 	Completed  bool      `json:"completed"`
 	FilePath   string    `json:"file_path"`
 	LineNumber int       `json:"line_number"`
+	Archived   bool      `json:"archived,omitempty"` // its file was moved to todos/archive/
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
@@ -80,14 +111,25 @@ type HistoryEntry struct {
 	Changes     []Change  `json:"changes"`
 	Reasoning   string    `json:"reasoning"`
 	FilePath    string    `json:"file_path"`
+	TestRuns    []TestRun `json:"test_runs,omitempty"` // CI outcomes for the change, oldest first
+}
+
+// TestRun represents the outcome of a CI test run for a history entry
+type TestRun struct {
+	Status       string    `json:"status"` // passed, failed
+	Total        int       `json:"total,omitempty"`
+	Failed       int       `json:"failed,omitempty"`
+	FailingTests []string  `json:"failing_tests,omitempty"`
+	Source       string    `json:"source,omitempty"` // e.g. a CI run URL
+	RecordedAt   time.Time `json:"recorded_at"`
 }
 
 // Change represents a single file change
 type Change struct {
-	FilePath   string `json:"file_path"`
-	ChangeType string `json:"change_type"` // created, modified, deleted
-	Before     string `json:"before"`
-	After      string `json:"after"`
+	FilePath   string `json:"file_path" arg:"file_path,required"`
+	ChangeType string `json:"change_type" arg:"change_type,required"` // created, modified, deleted
+	Before     string `json:"before" arg:"before"`
+	After      string `json:"after" arg:"after"`
 }
 
 // Backup represents a file backup
@@ -99,6 +141,66 @@ type Backup struct {
 	ChangeContext string    `json:"change_context"`
 	Reasoning     string    `json:"reasoning"`
 	FileSize      int64     `json:"file_size"`
+	SetID         string    `json:"set_id,omitempty"` // shared by backups created together via create_set
+	Type          string    `json:"type,omitempty"`   // empty for a single file, "tree" for a directory
+	FileCount     int       `json:"file_count,omitempty"`
+}
+
+// BackupTypeTree marks a backup of a whole directory tree. Its BackupPath
+// is a manifest and FileSize the total size of the tree.
+const BackupTypeTree = "tree"
+
+// Dataset represents a small reference table loaded from a CSV or TSV file
+type Dataset struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Columns     []DatasetColumn `json:"columns"`
+	Rows        [][]string      `json:"rows"`
+	FilePath    string          `json:"file_path"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// DatasetColumn represents a dataset column with its inferred type
+type DatasetColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`          // integer, number, boolean, date, string
+	PII  bool   `json:"pii,omitempty"` // personal data, masked in sample output
+}
+
+// CompliancePolicy represents a license or data-handling policy
+type CompliancePolicy struct {
+	ID             string    `json:"id"`
+	Title          string    `json:"title"`
+	Category       string    `json:"category"`                  // e.g. licenses, data
+	Allowed        []string  `json:"allowed,omitempty"`         // license identifiers that may be used
+	Denied         []string  `json:"denied,omitempty"`          // license identifiers that must not be used
+	Review         []string  `json:"review,omitempty"`          // license identifiers that need legal review
+	DeniedPackages []string  `json:"denied_packages,omitempty"` // dependency names or patterns that must not be used
+	Default        string    `json:"default,omitempty"`         // verdict for unlisted licenses: allow, review, deny
+	Content        string    `json:"content"`
+	FilePath       string    `json:"file_path"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// PerformanceBudget represents a limit on one metric of a component, such
+// as endpoint latency, binary size or bundle size
+type PerformanceBudget struct {
+	Component   string  `json:"component"`
+	Metric      string  `json:"metric"`
+	Max         float64 `json:"max"`  // in the unit's base: milliseconds or bytes
+	Unit        string  `json:"unit"` // ms, bytes, or empty for plain numbers
+	Description string  `json:"description,omitempty"`
+}
+
+// PerformanceMeasurement represents a recorded value of a budgeted metric
+type PerformanceMeasurement struct {
+	Component  string    `json:"component"`
+	Metric     string    `json:"metric"`
+	Value      float64   `json:"value"` // in the same base unit as the budget
+	Unit       string    `json:"unit"`
+	Source     string    `json:"source,omitempty"` // e.g. a commit or CI run
+	RecordedAt time.Time `json:"recorded_at"`
 }
 
 // ProjectContext represents the overall project context
@@ -2,118 +2,401 @@ package monitor
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/fsnotify/fsnotify"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
 )
 
-// newWatcherFunc is a test hook for creating watchers
-var newWatcherFunc = fsnotify.NewWatcher
-
 // FileChangeHandler interface for handling file changes
 type FileChangeHandler interface {
 	ReloadData() error
 }
 
-// FileMonitor watches for changes in the buddy folder
-type FileMonitor struct {
+// PathChangeHandler is implemented by handlers that can reload just the
+// content affected by a changed file instead of everything
+type PathChangeHandler interface {
+	ReloadPath(path string) error
+}
+
+// UrgentChangeHandler is implemented by handlers that can finish
+// reloading a changed file before returning, so urgent changes are
+// visible as soon as they are announced
+type UrgentChangeHandler interface {
+	ReloadPathNow(path string) error
+}
+
+// EventPolicy says how changes under one buddy subdirectory are handled
+type EventPolicy struct {
+	// Debounce holds reloads back until the directory has been quiet this
+	// long, so a burst of saves reloads once. Zero reloads at once.
+	Debounce time.Duration
+	// Urgent picks out changes that skip the debounce; they are reloaded
+	// at once and reported to the OnUrgent function. Nil means none are.
+	Urgent func(path string) bool
+}
+
+// pendingReload is a debounced batch of changed files under one
+// subdirectory of one root
+type pendingReload struct {
+	paths []string
+	timer *time.Timer
+}
+
+// watchRoot is an additional buddy folder watched by the same monitor
+type watchRoot struct {
 	path    string
 	handler FileChangeHandler
-	watcher *fsnotify.Watcher
+}
+
+// FileMonitor watches for changes in the buddy folder, and in any further
+// folders registered with AddRoot
+type FileMonitor struct {
+	path     string
+	handler  FileChangeHandler
+	roots    []watchRoot
+	dirs     []string               // subdirectories watched beyond the built-in ones
+	policies map[string]EventPolicy // by subdirectory name
+	onReload func(root string)
+	onUrgent func(root, path string)
+	store    storage.Storage // where changes are watched
+	watcher  storage.Watcher
+	stopped  chan struct{} // closed when the watch loop returns
+
+	// Debounced reloads, touched only by the watch loop; timers report
+	// due batches by their key on due
+	pending map[string]*pendingReload
+	due     chan string
 }
 
 // NewFileMonitor creates a new file monitor
 func NewFileMonitor(path string, handler FileChangeHandler) *FileMonitor {
 	return &FileMonitor{
-		path:    path,
-		handler: handler,
+		path:     path,
+		handler:  handler,
+		policies: make(map[string]EventPolicy),
+		store:    storage.NewLocal(),
+		pending:  make(map[string]*pendingReload),
+		due:      make(chan string),
 	}
 }
 
-// Start starts monitoring the buddy folder
+// AddRoot registers another buddy folder to watch. Changes under it are
+// reloaded by its own handler. Call it before Start.
+func (fm *FileMonitor) AddRoot(path string, handler FileChangeHandler) {
+	fm.roots = append(fm.roots, watchRoot{path: path, handler: handler})
+}
+
+// OnReload registers a function called with the buddy folder's path after
+// a change under it was reloaded successfully. Call it before Start.
+func (fm *FileMonitor) OnReload(fn func(root string)) {
+	fm.onReload = fn
+}
+
+// WatchDir also watches the named subdirectory of every buddy folder, such
+// as the directory of a custom handler. Call it before Start.
+func (fm *FileMonitor) WatchDir(dir string) {
+	fm.dirs = append(fm.dirs, dir)
+}
+
+// SetPolicy sets how changes under a subdirectory of every watched buddy
+// folder, such as "rules", are handled. Directories without a policy
+// reload at once. Call it before Start.
+func (fm *FileMonitor) SetPolicy(dir string, policy EventPolicy) {
+	fm.policies[dir] = policy
+}
+
+// OnUrgent registers a function called with the buddy folder's path and
+// the changed file after an urgent change was reloaded successfully. Call
+// it before Start.
+func (fm *FileMonitor) OnUrgent(fn func(root, path string)) {
+	fm.onUrgent = fn
+}
+
+// SetStorage watches every buddy folder through store instead of the
+// local disk. Call it before Start.
+func (fm *FileMonitor) SetStorage(store storage.Storage) {
+	fm.store = store
+}
+
+// Start starts monitoring the buddy folder until ctx is cancelled
 func (fm *FileMonitor) Start(ctx context.Context) error {
-	watcher, err := newWatcherFunc()
+	watcher, err := fm.store.Watch()
 	if err != nil {
 		return err
 	}
 	fm.watcher = watcher
 
-	// Add all subdirectories to watch
-	subdirs := []string{
-		fm.path,
-		filepath.Join(fm.path, "rules"),
-		filepath.Join(fm.path, "knowledge"),
-		filepath.Join(fm.path, "database"),
-		filepath.Join(fm.path, "todos"),
-		filepath.Join(fm.path, "history"),
-		filepath.Join(fm.path, "backups"),
-	}
-
-	for _, dir := range subdirs {
-		if err := watcher.Add(dir); err != nil {
-			log.Printf("Failed to watch directory %s: %v", dir, err)
+	for _, root := range fm.allRoots() {
+		dirs := watchedDirs(root.path)
+		for _, dir := range fm.dirs {
+			dirs = append(dirs, filepath.Join(root.path, dir))
+		}
+		for _, dir := range dirs {
+			if err := watcher.Add(dir); err != nil {
+				slog.Warn("failed to watch directory", "dir", dir, "error", err)
+			}
 		}
 	}
 
-	go fm.watchLoop(ctx)
+	fm.stopped = make(chan struct{})
+	go func() {
+		defer close(fm.stopped)
+		fm.watchLoop(ctx)
+	}()
 
 	return nil
 }
 
+// Wait blocks until monitoring has stopped, including any reload it was
+// running. It returns at once if Start didn't start monitoring.
+func (fm *FileMonitor) Wait() {
+	if fm.stopped != nil {
+		<-fm.stopped
+	}
+}
+
+// Running reports whether the monitor is watching for changes. It is false
+// before Start and once the watch loop has stopped, whether because ctx was
+// cancelled or because the watcher failed.
+func (fm *FileMonitor) Running() bool {
+	if fm.stopped == nil {
+		return false
+	}
+	select {
+	case <-fm.stopped:
+		return false
+	default:
+		return true
+	}
+}
+
+// watchedDirs lists the directories watched in one buddy folder
+func watchedDirs(path string) []string {
+	return []string{
+		path,
+		filepath.Join(path, "rules"),
+		filepath.Join(path, "knowledge"),
+		filepath.Join(path, "database"),
+		filepath.Join(path, "todos"),
+		filepath.Join(path, "history"),
+		filepath.Join(path, "backups"),
+		filepath.Join(path, "datasets"),
+		filepath.Join(path, "compliance"),
+		filepath.Join(path, "budgets"),
+		filepath.Join(path, "views"),
+	}
+}
+
+// allRoots returns the primary buddy folder followed by the added ones
+func (fm *FileMonitor) allRoots() []watchRoot {
+	return append([]watchRoot{{path: fm.path, handler: fm.handler}}, fm.roots...)
+}
+
+// rootFor picks the root containing path. Roots may be nested, so the
+// deepest match wins; the primary root is the fallback.
+func (fm *FileMonitor) rootFor(path string) watchRoot {
+	roots := fm.allRoots()
+	match := roots[0]
+	longest := -1
+	for _, root := range roots {
+		rel, err := filepath.Rel(root.path, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(root.path) > longest {
+			match = root
+			longest = len(root.path)
+		}
+	}
+	return match
+}
+
 // watchLoop watches for file events
 func (fm *FileMonitor) watchLoop(ctx context.Context) {
 	defer fm.watcher.Close()
+	defer fm.stopPending()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 
-		case event, ok := <-fm.watcher.Events:
+		case event, ok := <-fm.watcher.Events():
 			if !ok {
 				return
 			}
 
 			// Filter relevant events
 			if fm.isRelevantEvent(event) {
-				log.Printf("File change detected: %s (%s)", event.Name, event.Op)
-
-				// Reload data
-				if err := fm.handler.ReloadData(); err != nil {
-					log.Printf("Error reloading data: %v", err)
-				}
+				slog.Debug("file change detected", "path", event.Path, "op", event.Op.String())
+				fm.handle(event.Path)
 			}
 
-		case err, ok := <-fm.watcher.Errors:
+		case key := <-fm.due:
+			fm.flush(key)
+
+		case err, ok := <-fm.watcher.Errors():
 			if !ok {
 				return
 			}
-			log.Printf("File watcher error: %v", err)
+			slog.Error("file watcher error", "error", err)
+		}
+	}
+}
+
+// handle routes a relevant change by the policy of its directory: urgent
+// changes reload at once and are announced, debounced ones join their
+// directory's pending batch, and the rest reload at once
+func (fm *FileMonitor) handle(path string) {
+	root := fm.rootFor(path)
+	dir := subdirectory(root.path, path)
+	policy := fm.policies[dir]
+
+	switch {
+	case policy.Urgent != nil && policy.Urgent(path):
+		slog.Info("urgent change detected", "path", path)
+		if err := fm.reloadUrgent(root, path); err != nil {
+			slog.Error("failed to reload data", "path", path, "error", err)
+		}
+	case policy.Debounce > 0:
+		fm.schedule(root.path+string(filepath.Separator)+dir, path, policy.Debounce)
+	default:
+		if err := fm.reload(path); err != nil {
+			slog.Error("failed to reload data", "path", path, "error", err)
+		}
+	}
+}
+
+// subdirectory returns the top-level directory of root that path is in,
+// or "" for files directly in root
+func subdirectory(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+// schedule adds a change to the batch under key and restarts its timer
+func (fm *FileMonitor) schedule(key, path string, delay time.Duration) {
+	batch := fm.pending[key]
+	if batch == nil {
+		batch = &pendingReload{}
+		stopped := fm.stopped
+		batch.timer = time.AfterFunc(delay, func() {
+			select {
+			case fm.due <- key:
+			case <-stopped:
+			}
+		})
+		fm.pending[key] = batch
+	} else {
+		batch.timer.Reset(delay)
+	}
+	for _, pending := range batch.paths {
+		if pending == path {
+			return
 		}
 	}
+	batch.paths = append(batch.paths, path)
 }
 
+// flush reloads the files of a due batch
+func (fm *FileMonitor) flush(key string) {
+	batch := fm.pending[key]
+	if batch == nil {
+		return
+	}
+	delete(fm.pending, key)
+	for _, path := range batch.paths {
+		if err := fm.reload(path); err != nil {
+			slog.Error("failed to reload data", "path", path, "error", err)
+		}
+	}
+}
+
+// stopPending drops the batches still waiting when monitoring stops
+func (fm *FileMonitor) stopPending() {
+	for key, batch := range fm.pending {
+		batch.timer.Stop()
+		delete(fm.pending, key)
+	}
+}
+
+// reloadUrgent reloads an urgent change, finishing before it returns when
+// the handler can, and announces it
+func (fm *FileMonitor) reloadUrgent(root watchRoot, path string) error {
+	urgentHandler, ok := root.handler.(UrgentChangeHandler)
+	if !ok {
+		if err := fm.reload(path); err != nil {
+			return err
+		}
+	} else {
+		if err := urgentHandler.ReloadPathNow(path); err != nil {
+			return err
+		}
+		if fm.onReload != nil {
+			fm.onReload(root.path)
+		}
+	}
+	if fm.onUrgent != nil {
+		fm.onUrgent(root.path, path)
+	}
+	return nil
+}
+
+// reload reloads the content affected by a changed file, falling back to
+// a full reload when the handler can't reload by path
+func (fm *FileMonitor) reload(path string) error {
+	root := fm.rootFor(path)
+
+	var err error
+	if pathHandler, ok := root.handler.(PathChangeHandler); ok {
+		err = pathHandler.ReloadPath(path)
+	} else {
+		err = root.handler.ReloadData()
+	}
+
+	if err == nil && fm.onReload != nil {
+		fm.onReload(root.path)
+	}
+	return err
+}
+
+// relevantExtensions are the file types buddy content is loaded from
+var relevantExtensions = []string{".md", ".adoc", ".asciidoc", ".rst", ".json", ".sql", ".csv", ".tsv", ".yaml", ".yml"}
+
 // isRelevantEvent checks if the event should trigger a reload
-func (fm *FileMonitor) isRelevantEvent(event fsnotify.Event) bool {
+func (fm *FileMonitor) isRelevantEvent(event storage.Event) bool {
 	// Skip temporary files
-	if strings.HasPrefix(filepath.Base(event.Name), ".") ||
-		strings.HasSuffix(event.Name, "~") ||
-		strings.HasSuffix(event.Name, ".swp") ||
-		strings.HasSuffix(event.Name, ".tmp") {
+	if strings.HasPrefix(filepath.Base(event.Path), ".") ||
+		strings.HasSuffix(event.Path, "~") ||
+		strings.HasSuffix(event.Path, ".swp") ||
+		strings.HasSuffix(event.Path, ".tmp") {
 		return false
 	}
 
-	// Only care about markdown and JSON files
-	if !strings.HasSuffix(event.Name, ".md") &&
-		!strings.HasSuffix(event.Name, ".json") &&
-		!strings.HasSuffix(event.Name, ".sql") {
+	// Only care about content files
+	relevant := false
+	for _, ext := range relevantExtensions {
+		if strings.HasSuffix(event.Path, ext) {
+			relevant = true
+			break
+		}
+	}
+	if !relevant {
 		return false
 	}
 
 	// Only care about write and create events
-	if event.Op&fsnotify.Write == 0 && event.Op&fsnotify.Create == 0 {
+	if !event.Op.Has(storage.Write) && !event.Op.Has(storage.Create) {
 		return false
 	}
 
@@ -13,11 +13,41 @@ import (
 
 	"errors"
 
-	"github.com/fsnotify/fsnotify"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// fakeWatcher is a watcher whose events and errors tests send themselves
+type fakeWatcher struct {
+	events chan storage.Event
+	errors chan error
+	close  sync.Once
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{events: make(chan storage.Event), errors: make(chan error)}
+}
+
+func (w *fakeWatcher) Add(dir string) error         { return nil }
+func (w *fakeWatcher) Events() <-chan storage.Event { return w.events }
+func (w *fakeWatcher) Errors() <-chan error         { return w.errors }
+
+func (w *fakeWatcher) Close() error {
+	w.close.Do(func() {
+		close(w.events)
+		close(w.errors)
+	})
+	return nil
+}
+
+// unwatchableStorage is a storage whose Watch always fails
+type unwatchableStorage struct{ *storage.Mem }
+
+func (unwatchableStorage) Watch() (storage.Watcher, error) {
+	return nil, errors.New("mock watcher creation error")
+}
+
 // Mock handler for testing
 type mockHandler struct {
 	reloadCalled chan bool
@@ -255,20 +285,26 @@ func TestFileMonitor_IsRelevantEvent(t *testing.T) {
 	// Test relevant file extensions and events
 	relevantCases := []struct {
 		name string
-		op   fsnotify.Op
+		op   storage.Op
 	}{
-		{"/test/rules/test.md", fsnotify.Write},
-		{"/test/knowledge/docs.md", fsnotify.Create},
-		{"/test/todos/tasks.md", fsnotify.Write},
-		{"/test/history/changes.json", fsnotify.Write},
-		{"/test/database/schema.sql", fsnotify.Create},
-		{"/any/path/file.md", fsnotify.Write},
-		{"/any/path/file.json", fsnotify.Write},
-		{"/any/path/file.sql", fsnotify.Write},
+		{"/test/rules/test.md", storage.Write},
+		{"/test/knowledge/docs.md", storage.Create},
+		{"/test/knowledge/guide.adoc", storage.Write},
+		{"/test/knowledge/guide.asciidoc", storage.Write},
+		{"/test/knowledge/api.rst", storage.Create},
+		{"/test/todos/tasks.md", storage.Write},
+		{"/test/history/changes.json", storage.Write},
+		{"/test/database/schema.sql", storage.Create},
+		{"/test/datasets/countries.csv", storage.Write},
+		{"/test/datasets/errors.tsv", storage.Create},
+		{"/test/budgets.yaml", storage.Write},
+		{"/any/path/file.md", storage.Write},
+		{"/any/path/file.json", storage.Write},
+		{"/any/path/file.sql", storage.Write},
 	}
 
 	for _, tc := range relevantCases {
-		event := fsnotify.Event{Name: tc.name, Op: tc.op}
+		event := storage.Event{Path: tc.name, Op: tc.op}
 		if !monitor.isRelevantEvent(event) {
 			t.Errorf("Expected %s with op %v to be relevant", tc.name, tc.op)
 		}
@@ -277,26 +313,25 @@ func TestFileMonitor_IsRelevantEvent(t *testing.T) {
 	// Test irrelevant files and events
 	irrelevantCases := []struct {
 		name string
-		op   fsnotify.Op
+		op   storage.Op
 	}{
 		// Hidden files
-		{"/test/.hidden.md", fsnotify.Write},
-		{"/test/rules/.DS_Store", fsnotify.Write},
+		{"/test/.hidden.md", storage.Write},
+		{"/test/rules/.DS_Store", storage.Write},
 		// Temporary files
-		{"/test/temp.tmp", fsnotify.Write},
-		{"/test/file~", fsnotify.Write},
-		{"/test/file.swp", fsnotify.Write},
+		{"/test/temp.tmp", storage.Write},
+		{"/test/file~", storage.Write},
+		{"/test/file.swp", storage.Write},
 		// Wrong extensions
-		{"/test/rules/test.txt", fsnotify.Write},
-		{"/test/rules/test.log", fsnotify.Write},
+		{"/test/rules/test.txt", storage.Write},
+		{"/test/rules/test.log", storage.Write},
 		// Wrong operations
-		{"/test/rules/test.md", fsnotify.Remove},
-		{"/test/rules/test.md", fsnotify.Rename},
-		{"/test/rules/test.md", fsnotify.Chmod},
+		{"/test/rules/test.md", storage.Remove},
+		{"/test/rules/test.md", storage.Rename},
 	}
 
 	for _, tc := range irrelevantCases {
-		event := fsnotify.Event{Name: tc.name, Op: tc.op}
+		event := storage.Event{Path: tc.name, Op: tc.op}
 		if monitor.isRelevantEvent(event) {
 			t.Errorf("Expected %s with op %v to be irrelevant", tc.name, tc.op)
 		}
@@ -956,20 +991,19 @@ func TestFileMonitor_IsRelevantEventPerformance(t *testing.T) {
 	// Test many different event types to ensure good coverage
 	testEvents := []struct {
 		name     string
-		event    fsnotify.Event
+		event    storage.Event
 		expected bool
 	}{
-		{"markdown write", fsnotify.Event{Name: "/path/to/file.md", Op: fsnotify.Write}, true},
-		{"json create", fsnotify.Event{Name: "/path/to/file.json", Op: fsnotify.Create}, true},
-		{"sql write", fsnotify.Event{Name: "/path/to/file.sql", Op: fsnotify.Write}, true},
-		{"temp file", fsnotify.Event{Name: "/path/to/file.md~", Op: fsnotify.Write}, false},
-		{"hidden file", fsnotify.Event{Name: "/path/to/.hidden.md", Op: fsnotify.Write}, false},
-		{"swap file", fsnotify.Event{Name: "/path/to/file.swp", Op: fsnotify.Write}, false},
-		{"tmp file", fsnotify.Event{Name: "/path/to/file.tmp", Op: fsnotify.Write}, false},
-		{"txt file", fsnotify.Event{Name: "/path/to/file.txt", Op: fsnotify.Write}, false},
-		{"remove event", fsnotify.Event{Name: "/path/to/file.md", Op: fsnotify.Remove}, false},
-		{"rename event", fsnotify.Event{Name: "/path/to/file.md", Op: fsnotify.Rename}, false},
-		{"chmod event", fsnotify.Event{Name: "/path/to/file.md", Op: fsnotify.Chmod}, false},
+		{"markdown write", storage.Event{Path: "/path/to/file.md", Op: storage.Write}, true},
+		{"json create", storage.Event{Path: "/path/to/file.json", Op: storage.Create}, true},
+		{"sql write", storage.Event{Path: "/path/to/file.sql", Op: storage.Write}, true},
+		{"temp file", storage.Event{Path: "/path/to/file.md~", Op: storage.Write}, false},
+		{"hidden file", storage.Event{Path: "/path/to/.hidden.md", Op: storage.Write}, false},
+		{"swap file", storage.Event{Path: "/path/to/file.swp", Op: storage.Write}, false},
+		{"tmp file", storage.Event{Path: "/path/to/file.tmp", Op: storage.Write}, false},
+		{"txt file", storage.Event{Path: "/path/to/file.txt", Op: storage.Write}, false},
+		{"remove event", storage.Event{Path: "/path/to/file.md", Op: storage.Remove}, false},
+		{"rename event", storage.Event{Path: "/path/to/file.md", Op: storage.Rename}, false},
 	}
 
 	for _, tc := range testEvents {
@@ -1236,8 +1270,7 @@ func TestFileMonitor_WatchLoop_ErrorChannel(t *testing.T) {
 	monitor := NewFileMonitor(tempDir, handler)
 
 	// Manually create a watcher for testing
-	watcher, err := fsnotify.NewWatcher()
-	require.NoError(t, err)
+	watcher := newFakeWatcher()
 	monitor.watcher = watcher
 
 	// Start watch loop in goroutine
@@ -1260,7 +1293,7 @@ func TestFileMonitor_WatchLoop_ErrorChannel(t *testing.T) {
 	go func() {
 		// Create a simulated filesystem error
 		select {
-		case watcher.Errors <- errors.New("simulated filesystem error"):
+		case watcher.errors <- errors.New("simulated filesystem error"):
 		case <-time.After(time.Second):
 		}
 	}()
@@ -1283,8 +1316,7 @@ func TestFileMonitor_WatchLoop_EventsChannelClosed(t *testing.T) {
 	monitor := NewFileMonitor(tempDir, handler)
 
 	// Manually create a watcher for testing
-	watcher, err := fsnotify.NewWatcher()
-	require.NoError(t, err)
+	watcher := newFakeWatcher()
 	monitor.watcher = watcher
 
 	// Start watch loop in goroutine
@@ -1315,8 +1347,7 @@ func TestFileMonitor_WatchLoop_ErrorsChannelClosed(t *testing.T) {
 	monitor := NewFileMonitor(tempDir, handler)
 
 	// Manually create a watcher for testing
-	watcher, err := fsnotify.NewWatcher()
-	require.NoError(t, err)
+	watcher := newFakeWatcher()
 	monitor.watcher = watcher
 
 	// Start watch loop in goroutine
@@ -1452,8 +1483,7 @@ func TestFileMonitor_WatchLoop_EventsChannelClosedFirst(t *testing.T) {
 	monitor := NewFileMonitor(tempDir, handler)
 
 	// Manually create a watcher for testing
-	watcher, err := fsnotify.NewWatcher()
-	require.NoError(t, err)
+	watcher := newFakeWatcher()
 	monitor.watcher = watcher
 
 	// Start watch loop in goroutine
@@ -1485,8 +1515,7 @@ func TestFileMonitor_WatchLoop_ErrorsChannelClosedFirst(t *testing.T) {
 	monitor := NewFileMonitor(tempDir, handler)
 
 	// Manually create a watcher for testing
-	watcher, err := fsnotify.NewWatcher()
-	require.NoError(t, err)
+	watcher := newFakeWatcher()
 	monitor.watcher = watcher
 
 	// Start watch loop in goroutine
@@ -1513,16 +1542,8 @@ func TestFileMonitor_Start_NewWatcherError(t *testing.T) {
 	handler := &MockFileChangeHandler{}
 	monitor := NewFileMonitor(tempDir, handler)
 
-	// Save original newWatcherFunc
-	originalNewWatcher := newWatcherFunc
-	defer func() {
-		newWatcherFunc = originalNewWatcher
-	}()
-
-	// Replace with function that returns error
-	newWatcherFunc = func() (*fsnotify.Watcher, error) {
-		return nil, errors.New("mock watcher creation error")
-	}
+	// Watch through a storage that can't
+	monitor.SetStorage(unwatchableStorage{storage.NewMem()})
 
 	ctx := context.Background()
 	err := monitor.Start(ctx)
@@ -1536,8 +1557,7 @@ func TestFileMonitor_WatchLoop_ErrorChannelReceivesError(t *testing.T) {
 	monitor := NewFileMonitor(tempDir, handler)
 
 	// Manually create a watcher
-	watcher, err := fsnotify.NewWatcher()
-	require.NoError(t, err)
+	watcher := newFakeWatcher()
 	monitor.watcher = watcher
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1553,7 +1573,7 @@ func TestFileMonitor_WatchLoop_ErrorChannelReceivesError(t *testing.T) {
 	// Send an error to the error channel
 	time.Sleep(50 * time.Millisecond)
 	select {
-	case watcher.Errors <- errors.New("test watcher error"):
+	case watcher.errors <- errors.New("test watcher error"):
 		// Error sent successfully
 	case <-time.After(100 * time.Millisecond):
 		t.Fatal("Failed to send error to watcher")
@@ -1606,3 +1626,302 @@ func TestFileMonitor_WatchLoop_ReloadDataReturnsError(t *testing.T) {
 
 // Test that watchLoop handles closed channels gracefully
 // This test is simplified because directly manipulating fsnotify channels causes panics
+
+// pathHandler records per-path reloads
+type pathHandler struct {
+	mockHandler
+	paths chan string
+}
+
+func (p *pathHandler) ReloadPath(path string) error {
+	p.paths <- path
+	return nil
+}
+
+func TestFileMonitor_ReloadPath(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, createBuddyDirs(tempDir))
+
+	handler := &pathHandler{
+		mockHandler: mockHandler{reloadCalled: make(chan bool, 1)},
+		paths:       make(chan string, 10),
+	}
+	monitor := NewFileMonitor(tempDir, handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, monitor.Start(ctx))
+
+	testFile := filepath.Join(tempDir, "rules", "style.md")
+	require.NoError(t, ioutil.WriteFile(testFile, []byte("# Style"), 0644))
+
+	select {
+	case path := <-handler.paths:
+		assert.Equal(t, testFile, path)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected ReloadPath to be called")
+	}
+
+	// The full reload is not used when the handler supports per-path reloads
+	assert.Equal(t, 0, handler.getReloadCount())
+}
+
+func TestFileMonitor_AddRoot(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+	require.NoError(t, createBuddyDirs(first))
+	require.NoError(t, createBuddyDirs(second))
+
+	newHandler := func() *pathHandler {
+		return &pathHandler{
+			mockHandler: mockHandler{reloadCalled: make(chan bool, 1)},
+			paths:       make(chan string, 10),
+		}
+	}
+	firstHandler, secondHandler := newHandler(), newHandler()
+
+	monitor := NewFileMonitor(first, firstHandler)
+	monitor.AddRoot(second, secondHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, monitor.Start(ctx))
+
+	testFile := filepath.Join(second, "knowledge", "api.md")
+	require.NoError(t, ioutil.WriteFile(testFile, []byte("# API"), 0644))
+
+	select {
+	case path := <-secondHandler.paths:
+		assert.Equal(t, testFile, path)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the second root's handler to reload")
+	}
+	assert.Empty(t, firstHandler.paths)
+}
+
+func TestFileMonitor_HandlerForNestedRoots(t *testing.T) {
+	outer := &mockHandler{}
+	inner := &mockHandler{}
+	monitor := NewFileMonitor("/repo/.buddy", outer)
+	monitor.AddRoot("/repo/.buddy/services/api/.buddy", inner)
+
+	assert.Same(t, outer, monitor.rootFor("/repo/.buddy/rules/style.md").handler)
+	assert.Same(t, inner, monitor.rootFor("/repo/.buddy/services/api/.buddy/rules/style.md").handler)
+	assert.Same(t, outer, monitor.rootFor("/elsewhere/file.md").handler)
+}
+
+func TestFileMonitor_OnReload(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, createBuddyDirs(tempDir))
+
+	handler := &mockHandler{reloadCalled: make(chan bool, 1)}
+	monitor := NewFileMonitor(tempDir, handler)
+
+	reloaded := make(chan string, 10)
+	monitor.OnReload(func(root string) {
+		reloaded <- root
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, monitor.Start(ctx))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, "rules", "style.md"), []byte("# Style"), 0644))
+
+	select {
+	case root := <-reloaded:
+		assert.Equal(t, tempDir, root)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected OnReload to be called")
+	}
+}
+
+func TestFileMonitor_OnReloadSkippedOnError(t *testing.T) {
+	handler := &mockErrorHandler{reloadCalled: make(chan bool, 1)}
+	monitor := NewFileMonitor(t.TempDir(), handler)
+
+	called := false
+	monitor.OnReload(func(string) { called = true })
+
+	assert.Error(t, monitor.reload(filepath.Join(monitor.path, "rules", "style.md")))
+	assert.False(t, called)
+}
+
+func TestFileMonitor_Wait(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, createBuddyDirs(tempDir))
+
+	monitor := NewFileMonitor(tempDir, &mockHandler{reloadCalled: make(chan bool, 1)})
+	monitor.Wait() // not started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, monitor.Start(ctx))
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		monitor.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Wait to return once the context is cancelled")
+	}
+}
+
+func TestFileMonitor_Running(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, createBuddyDirs(tempDir))
+
+	monitor := NewFileMonitor(tempDir, &mockHandler{reloadCalled: make(chan bool, 1)})
+	assert.False(t, monitor.Running())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, monitor.Start(ctx))
+	assert.True(t, monitor.Running())
+
+	cancel()
+	monitor.Wait()
+	assert.False(t, monitor.Running())
+}
+
+// urgentHandler records synchronous reloads of urgent changes
+type urgentHandler struct {
+	pathHandler
+	now chan string
+}
+
+func (u *urgentHandler) ReloadPathNow(path string) error {
+	u.now <- path
+	return nil
+}
+
+func TestFileMonitor_DebouncePolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, createBuddyDirs(tempDir))
+
+	handler := &pathHandler{
+		mockHandler: mockHandler{reloadCalled: make(chan bool, 1)},
+		paths:       make(chan string, 10),
+	}
+	monitor := NewFileMonitor(tempDir, handler)
+	monitor.SetPolicy("knowledge", EventPolicy{Debounce: 300 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, monitor.Start(ctx))
+
+	// A burst of saves reloads each file once, after the burst
+	testFile := filepath.Join(tempDir, "knowledge", "api.md")
+	for i := 0; i < 5; i++ {
+		require.NoError(t, ioutil.WriteFile(testFile, []byte(fmt.Sprintf("# API %d", i)), 0644))
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Empty(t, handler.paths, "the debounced directory reloaded during the burst")
+
+	select {
+	case path := <-handler.paths:
+		assert.Equal(t, testFile, path)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the debounced change to reload")
+	}
+	time.Sleep(400 * time.Millisecond)
+	assert.Empty(t, handler.paths)
+
+	// Directories without a policy still reload at once
+	rulesFile := filepath.Join(tempDir, "rules", "style.md")
+	require.NoError(t, ioutil.WriteFile(rulesFile, []byte("# Style"), 0644))
+	select {
+	case path := <-handler.paths:
+		assert.Equal(t, rulesFile, path)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected ReloadPath to be called")
+	}
+}
+
+func TestFileMonitor_UrgentPolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, createBuddyDirs(tempDir))
+
+	handler := &urgentHandler{
+		pathHandler: pathHandler{
+			mockHandler: mockHandler{reloadCalled: make(chan bool, 1)},
+			paths:       make(chan string, 10),
+		},
+		now: make(chan string, 10),
+	}
+	monitor := NewFileMonitor(tempDir, handler)
+	monitor.SetPolicy("rules", EventPolicy{
+		Debounce: time.Minute,
+		Urgent:   func(path string) bool { return strings.Contains(filepath.Base(path), "critical") },
+	})
+	urgent := make(chan string, 10)
+	monitor.OnUrgent(func(root, path string) {
+		assert.Equal(t, tempDir, root)
+		urgent <- path
+	})
+	reloaded := make(chan string, 10)
+	monitor.OnReload(func(root string) { reloaded <- root })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, monitor.Start(ctx))
+
+	criticalFile := filepath.Join(tempDir, "rules", "critical.md")
+	require.NoError(t, ioutil.WriteFile(criticalFile, []byte("# Security"), 0644))
+	select {
+	case path := <-urgent:
+		assert.Equal(t, criticalFile, path)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the urgent change to be announced")
+	}
+	assert.Equal(t, criticalFile, <-handler.now, "urgent changes reload before they are announced")
+	assert.Equal(t, tempDir, <-reloaded)
+
+	// Other changes in the directory wait out the debounce
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, "rules", "style.md"), []byte("# Style"), 0644))
+	time.Sleep(300 * time.Millisecond)
+	assert.Empty(t, handler.paths)
+	cancel()
+	monitor.Wait()
+	close(urgent)
+	for path := range urgent {
+		// Creating a file reports both its creation and its first write
+		assert.Equal(t, criticalFile, path)
+	}
+}
+
+func TestFileMonitor_Subdirectory(t *testing.T) {
+	root := filepath.Join("repo", ".buddy")
+	assert.Equal(t, "rules", subdirectory(root, filepath.Join(root, "rules", "style.md")))
+	assert.Equal(t, "knowledge", subdirectory(root, filepath.Join(root, "knowledge", "auth", "jwt.md")))
+	assert.Equal(t, "", subdirectory(root, filepath.Join(root, "config.json")))
+}
+
+func TestFileMonitor_WatchDir(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, createBuddyDirs(tempDir))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "incidents"), 0755))
+
+	handler := &pathHandler{
+		mockHandler: mockHandler{reloadCalled: make(chan bool, 1)},
+		paths:       make(chan string, 10),
+	}
+	monitor := NewFileMonitor(tempDir, handler)
+	monitor.WatchDir("incidents")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, monitor.Start(ctx))
+
+	testFile := filepath.Join(tempDir, "incidents", "outage.md")
+	require.NoError(t, os.WriteFile(testFile, []byte("# Outage"), 0644))
+
+	select {
+	case path := <-handler.paths:
+		assert.Equal(t, testFile, path)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a change in the added directory to be reloaded")
+	}
+}
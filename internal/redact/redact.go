@@ -0,0 +1,79 @@
+// Package redact decides which data columns hold personal data and masks
+// their values before sample rows reach tool output.
+package redact
+
+import (
+	"path"
+	"strings"
+)
+
+// Mask replaces the value of a personal data column
+const Mask = "[redacted]"
+
+// DefaultColumns are column name patterns treated as personal data when
+// the configuration doesn't list its own
+var DefaultColumns = []string{
+	"*email*",
+	"*phone*",
+	"*mobile*",
+	"ssn",
+	"*social_security*",
+	"*passport*",
+	"*password*",
+	"first_name",
+	"last_name",
+	"full_name",
+	"surname",
+	"*birth*",
+	"dob",
+	"*address*",
+	"*street*",
+	"*postal_code*",
+	"zip*",
+	"iban",
+	"*card_number*",
+	"*tax_id*",
+	"*national_id*",
+}
+
+// Policy matches column names against personal data patterns
+type Policy struct {
+	patterns []string
+}
+
+// NewPolicy creates a policy from column name patterns, which use "*" and
+// "?" wildcards and match case-insensitively. Nil patterns select
+// DefaultColumns; an empty list matches nothing.
+func NewPolicy(patterns []string) Policy {
+	if patterns == nil {
+		patterns = DefaultColumns
+	}
+
+	policy := Policy{}
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			policy.patterns = append(policy.patterns, pattern)
+		}
+	}
+	return policy
+}
+
+// Sensitive reports whether a column name matches one of the patterns
+func (p Policy) Sensitive(column string) bool {
+	column = strings.ToLower(strings.TrimSpace(column))
+	for _, pattern := range p.patterns {
+		if matched, err := path.Match(pattern, column); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Value masks a personal data value. Empty values stay empty, so missing
+// data remains visible.
+func Value(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return value
+	}
+	return Mask
+}
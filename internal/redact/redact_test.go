@@ -0,0 +1,34 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Defaults(t *testing.T) {
+	policy := NewPolicy(nil)
+
+	for _, column := range []string{"email", "Contact_Email", "phone_number", "SSN", "date_of_birth", "billing_address", "zip_code", "last_name"} {
+		assert.True(t, policy.Sensitive(column), column)
+	}
+	for _, column := range []string{"id", "country_code", "name", "created_at", "status"} {
+		assert.False(t, policy.Sensitive(column), column)
+	}
+}
+
+func TestPolicy_Configured(t *testing.T) {
+	policy := NewPolicy([]string{"customer_*", " IBAN "})
+	assert.True(t, policy.Sensitive("customer_ref"))
+	assert.True(t, policy.Sensitive("iban"))
+	assert.False(t, policy.Sensitive("email"))
+
+	// An empty list turns the defaults off
+	assert.False(t, NewPolicy([]string{}).Sensitive("email"))
+}
+
+func TestValue(t *testing.T) {
+	assert.Equal(t, Mask, Value("alice@example.com"))
+	assert.Equal(t, "", Value(""))
+	assert.Equal(t, " ", Value(" "))
+}
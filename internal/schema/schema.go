@@ -0,0 +1,313 @@
+// Package schema publishes JSON Schemas for the .buddy files tools and
+// people write by hand, and validates content against them.
+//
+// The validator covers the keywords the bundled schemas use: type,
+// properties, required, additionalProperties, items, enum, minLength,
+// minimum and the date-time format.
+package schema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Names of the bundled schemas
+const (
+	HistoryEntry       = "history-entry"
+	BackupMetadata     = "backup-metadata"
+	Config             = "config"
+	DatasetFrontmatter = "dataset-frontmatter"
+	RuleFrontmatter    = "rule-frontmatter"
+)
+
+//go:embed schemas/*.json
+var files embed.FS
+
+// Schema is one parsed JSON Schema
+type Schema struct {
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 typeList           `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *additional        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Format               string             `json:"format,omitempty"`
+}
+
+// additional is a schema's "additionalProperties": a boolean allowing or
+// forbidding unknown properties, or a schema they must match
+type additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	if err := json.Unmarshal(data, &a.Schema); err != nil {
+		return fmt.Errorf("additionalProperties must be a boolean or a schema")
+	}
+	return nil
+}
+
+func (a additional) MarshalJSON() ([]byte, error) {
+	if a.Schema != nil {
+		return json.Marshal(a.Schema)
+	}
+	return json.Marshal(a.Allowed)
+}
+
+// typeList is a schema's "type", which may be one name or a list of them
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = typeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = many
+	return nil
+}
+
+// Info describes a bundled schema
+type Info struct {
+	Name        string `json:"name"`
+	URI         string `json:"uri"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// URI returns the resource URI a schema is published at
+func URI(name string) string {
+	return "buddy://schemas/" + name
+}
+
+// Source returns the JSON text of a bundled schema
+func Source(name string) ([]byte, bool) {
+	content, err := files.ReadFile("schemas/" + name + ".json")
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+// Get returns a parsed bundled schema
+func Get(name string) (*Schema, error) {
+	content, ok := Source(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q", name)
+	}
+	var s Schema
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", name, err)
+	}
+	return &s, nil
+}
+
+// List describes every bundled schema, sorted by name
+func List() []Info {
+	entries, _ := files.ReadDir("schemas")
+	infos := make([]Info, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		s, err := Get(name)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, Info{Name: name, URI: URI(name), Title: s.Title, Description: s.Description})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// ValidationError lists every way a document breaks its schema
+type ValidationError struct {
+	Schema   string
+	Problems []string // each prefixed with the JSON path of the offending value
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("does not match the %s schema: %s", e.Schema, strings.Join(e.Problems, "; "))
+}
+
+// Validate checks JSON content against a bundled schema. Content that
+// isn't JSON at all is reported as a plain parse error.
+func Validate(name string, content []byte) error {
+	s, err := Get(name)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return err
+	}
+
+	var problems []string
+	s.check("$", doc, &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Schema: name, Problems: problems}
+	}
+	return nil
+}
+
+// ValidateValue checks a decoded value, such as parsed YAML, against a
+// bundled schema by way of its JSON form
+func ValidateValue(name string, value interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return Validate(name, content)
+}
+
+// check appends the problems of value, found at path, to problems
+func (s *Schema) check(path string, value interface{}, problems *[]string) {
+	if len(s.Type) > 0 && !s.Type.matches(value) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(s.Type, " or "), typeOf(value)))
+		return
+	}
+
+	if len(s.Enum) > 0 && !s.allows(value) {
+		*problems = append(*problems, fmt.Sprintf("%s: %s is not one of %s", path, describe(value), describeAll(s.Enum)))
+	}
+
+	switch v := value.(type) {
+	case string:
+		if s.MinLength != nil && len([]rune(v)) < *s.MinLength {
+			if *s.MinLength == 1 {
+				*problems = append(*problems, fmt.Sprintf("%s: must not be empty", path))
+			} else {
+				*problems = append(*problems, fmt.Sprintf("%s: must be at least %d characters", path, *s.MinLength))
+			}
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				*problems = append(*problems, fmt.Sprintf("%s: %q is not an RFC 3339 date-time", path, v))
+			}
+		}
+
+	case json.Number:
+		if s.Minimum != nil {
+			if n, err := v.Float64(); err == nil && n < *s.Minimum {
+				*problems = append(*problems, fmt.Sprintf("%s: %s is less than %v", path, v, *s.Minimum))
+			}
+		}
+
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing required property %q", path, key))
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := s.Properties[key]
+			if !ok {
+				switch {
+				case s.AdditionalProperties == nil:
+				case !s.AdditionalProperties.Allowed:
+					*problems = append(*problems, fmt.Sprintf("%s: unknown property %q", path, key))
+				case s.AdditionalProperties.Schema != nil:
+					s.AdditionalProperties.Schema.check(path+"."+key, v[key], problems)
+				}
+				continue
+			}
+			property.check(path+"."+key, v[key], problems)
+		}
+
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.check(fmt.Sprintf("%s[%d]", path, i), item, problems)
+			}
+		}
+	}
+}
+
+// matches reports whether value has one of the listed types
+func (t typeList) matches(value interface{}) bool {
+	actual := typeOf(value)
+	for _, name := range t {
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether value is one of the schema's enum values
+func (s *Schema) allows(value interface{}) bool {
+	for _, option := range s.Enum {
+		if describe(option) == describe(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf names the JSON type of a decoded value
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// describe renders a value as JSON for messages and comparisons
+func describe(value interface{}) string {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(content)
+}
+
+func describeAll(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = describe(value)
+	}
+	return strings.Join(parts, ", ")
+}
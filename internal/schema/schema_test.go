@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	infos := List()
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name
+		assert.Equal(t, "buddy://schemas/"+info.Name, info.URI)
+		assert.NotEmpty(t, info.Title, info.Name)
+	}
+	assert.Equal(t, []string{BackupMetadata, Config, DatasetFrontmatter, HistoryEntry, RuleFrontmatter}, names)
+
+	_, ok := Source("missing")
+	assert.False(t, ok)
+}
+
+func TestValidate_WrittenModels(t *testing.T) {
+	now := time.Now().UTC()
+
+	entry := models.HistoryEntry{
+		ID:        "abc",
+		Timestamp: now,
+		Feature:   "auth",
+		Changes:   []models.Change{{FilePath: "auth.go", ChangeType: "modified"}},
+		TestRuns:  []models.TestRun{{Status: "failed", Total: 3, Failed: 1, FailingTests: []string{"TestLogin"}, RecordedAt: now}},
+	}
+	content, err := json.Marshal(entry)
+	require.NoError(t, err)
+	assert.NoError(t, Validate(HistoryEntry, content))
+
+	backups := []models.Backup{
+		{ID: "b1", OriginalPath: "main.go", BackupPath: "backups/b1/main.go", Timestamp: now, FileSize: 10},
+		{ID: "b2", OriginalPath: "src", BackupPath: "backups/b2/manifest.json", Type: models.BackupTypeTree, FileCount: 4},
+	}
+	content, err = json.Marshal(backups)
+	require.NoError(t, err)
+	assert.NoError(t, Validate(BackupMetadata, content))
+}
+
+func TestValidate_Problems(t *testing.T) {
+	err := Validate(HistoryEntry, []byte(`{"timestamp":"yesterday","feature":7,"test_runs":[{"status":"flaky","total":-1}]}`))
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, HistoryEntry, validationErr.Schema)
+	assert.Equal(t, []string{
+		`$: missing required property "id"`,
+		`$.feature: expected string, got integer`,
+		`$.test_runs[0].status: "flaky" is not one of "passed", "failed"`,
+		`$.test_runs[0].total: -1 is less than 0`,
+		`$.timestamp: "yesterday" is not an RFC 3339 date-time`,
+	}, validationErr.Problems)
+
+	err = Validate(Config, []byte(`{"tools":{"prefx":"x"}}`))
+	assert.ErrorContains(t, err, `$.tools: unknown property "prefx"`)
+
+	// Properties not listed are checked against additionalProperties
+	err = Validate(Config, []byte(`{"rate_limits":{"buddy_history":{"per_minute":-1,"burst":2}}}`))
+	assert.ErrorContains(t, err, `$.rate_limits.buddy_history.per_minute: -1 is less than 0`)
+	assert.ErrorContains(t, err, `$.rate_limits.buddy_history: unknown property "burst"`)
+
+	err = Validate(BackupMetadata, []byte(`[{"id":"","original_path":"a","backup_path":"b"}]`))
+	assert.ErrorContains(t, err, "$[0].id: must not be empty")
+
+	assert.NoError(t, Validate(BackupMetadata, []byte(`null`)))
+}
+
+func TestValidate_NotJSON(t *testing.T) {
+	err := Validate(HistoryEntry, []byte(`{`))
+	require.Error(t, err)
+	_, ok := err.(*ValidationError)
+	assert.False(t, ok)
+}
+
+func TestValidateValue(t *testing.T) {
+	assert.NoError(t, ValidateValue(DatasetFrontmatter, map[string]interface{}{"pii": []string{"email"}}))
+	assert.ErrorContains(t, ValidateValue(DatasetFrontmatter, map[string]interface{}{"pii": "email"}),
+		"$.pii: expected array or null, got string")
+}
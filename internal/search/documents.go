@@ -14,7 +14,14 @@ type RuleDocument struct {
 	Category    string `json:"category"`
 	Content     string `json:"content"`
 	Priority    string `json:"priority"`
+	Tags        string `json:"tags"` // comma-separated, as for knowledge
 	Description string `json:"description"`
+	Language    string `json:"language,omitempty"`
+}
+
+// BleveType routes the rule to the mapping for its language
+func (d RuleDocument) BleveType() string {
+	return documentType("rule", d.Language)
 }
 
 // FromRule creates a RuleDocument from a models.Rule
@@ -25,30 +32,60 @@ func FromRule(rule models.Rule) RuleDocument {
 		Category:    rule.Category,
 		Content:     rule.Content,
 		Priority:    rule.Priority,
+		Tags:        strings.Join(rule.Tags, ", "),
 		Description: rule.Description,
+		Language:    rule.Language,
 	}
 }
 
 // KnowledgeDocument represents a knowledge document for indexing
 type KnowledgeDocument struct {
-	ID       string `json:"id"`
-	Title    string `json:"title"`
-	Category string `json:"category"`
-	Content  string `json:"content"`
-	Tags     string `json:"tags"` // Comma-separated for better search
+	ID       string            `json:"id"`
+	Title    string            `json:"title"`
+	Category string            `json:"category"`
+	Content  string            `json:"content"`
+	Tags     string            `json:"tags"`               // Comma-separated for better search
+	Metadata map[string]string `json:"metadata,omitempty"` // whole values, see MetadataValue
+	Language string            `json:"language,omitempty"`
+}
+
+// BleveType routes the document to the mapping for its language
+func (d KnowledgeDocument) BleveType() string {
+	return documentType("knowledge", d.Language)
 }
 
 // FromKnowledge creates a KnowledgeDocument from a models.Knowledge
 func FromKnowledge(knowledge models.Knowledge) KnowledgeDocument {
+	var metadata map[string]string
+	if len(knowledge.Metadata) > 0 {
+		metadata = make(map[string]string, len(knowledge.Metadata))
+		for field, value := range knowledge.Metadata {
+			metadata[strings.ToLower(field)] = MetadataValue(value)
+		}
+	}
+
 	return KnowledgeDocument{
 		ID:       knowledge.ID,
 		Title:    knowledge.Title,
 		Category: knowledge.Category,
 		Content:  knowledge.Content,
 		Tags:     strings.Join(knowledge.Tags, ", "),
+		Metadata: metadata,
+		Language: knowledge.Language,
 	}
 }
 
+// MetadataField returns the index field holding a knowledge metadata field
+func MetadataField(name string) string {
+	return "metadata." + strings.ToLower(name)
+}
+
+// MetadataValue returns a metadata value as indexed: trimmed and lowercase,
+// so filters match it whole and regardless of case
+func MetadataValue(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
 // TodoDocument represents a todo document for indexing
 type TodoDocument struct {
 	ID        string `json:"id"`
@@ -80,15 +117,45 @@ type HistoryDocument struct {
 	Feature     string    `json:"feature"`
 	Description string    `json:"description"`
 	Reasoning   string    `json:"reasoning"`
-	Files       string    `json:"files"` // Comma-separated file paths
+	Files       string    `json:"files"`   // Comma-separated file paths
+	Content     string    `json:"content"` // Before/after code of the changes, size-limited
+	Tests       string    `json:"tests"`   // Failing test names from attached test runs, with their parts
 	Timestamp   time.Time `json:"timestamp"`
 }
 
+// MaxHistoryContentSize caps how much change content is indexed per history entry
+const MaxHistoryContentSize = 64 * 1024
+
 // FromHistoryEntry creates a HistoryDocument from a models.HistoryEntry
 func FromHistoryEntry(entry models.HistoryEntry) HistoryDocument {
 	var files []string
+	var content strings.Builder
 	for _, change := range entry.Changes {
 		files = append(files, change.FilePath)
+
+		for _, body := range []string{change.Before, change.After} {
+			remaining := MaxHistoryContentSize - content.Len()
+			if remaining <= 0 {
+				break
+			}
+			if len(body) > remaining {
+				body = body[:remaining]
+			}
+			content.WriteString(body)
+			content.WriteString("\n")
+		}
+	}
+
+	// Qualified names such as "auth.LoginTest.testExpired" or "TestLogin/expired"
+	// are one token to the analyzer, so their parts are indexed too
+	var tests []string
+	for _, run := range entry.TestRuns {
+		for _, name := range run.FailingTests {
+			tests = append(tests, name)
+			if parts := strings.FieldsFunc(name, func(r rune) bool { return r == '.' || r == '/' || r == '#' || r == ':' }); len(parts) > 1 {
+				tests = append(tests, parts...)
+			}
+		}
 	}
 
 	return HistoryDocument{
@@ -97,6 +164,8 @@ func FromHistoryEntry(entry models.HistoryEntry) HistoryDocument {
 		Description: entry.Description,
 		Reasoning:   entry.Reasoning,
 		Files:       strings.Join(files, ", "),
+		Content:     content.String(),
+		Tests:       strings.Join(tests, ", "),
 		Timestamp:   entry.Timestamp,
 	}
 }
@@ -150,3 +219,74 @@ func FromBackup(backup models.Backup) BackupDocument {
 		Timestamp:    backup.Timestamp,
 	}
 }
+
+// MaxDatasetValuesSize caps how much cell content is indexed per dataset
+const MaxDatasetValuesSize = 64 * 1024
+
+// DatasetDocument represents a dataset document for indexing
+type DatasetDocument struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Columns     string `json:"columns"`
+	Values      string `json:"values"`
+}
+
+// FromDataset creates a DatasetDocument from a models.Dataset
+func FromDataset(dataset models.Dataset) DatasetDocument {
+	var columnNames []string
+	for _, col := range dataset.Columns {
+		columnNames = append(columnNames, col.Name+" "+col.Type)
+	}
+
+	// Personal data values stay out of the index so searches can't probe for them
+	var values strings.Builder
+	for _, row := range dataset.Rows {
+		var cells []string
+		for i, cell := range row {
+			if i < len(dataset.Columns) && dataset.Columns[i].PII {
+				continue
+			}
+			cells = append(cells, cell)
+		}
+		line := strings.Join(cells, " ")
+		if values.Len()+len(line)+1 > MaxDatasetValuesSize {
+			break
+		}
+		values.WriteString(line)
+		values.WriteString("\n")
+	}
+
+	return DatasetDocument{
+		ID:          dataset.ID,
+		Name:        dataset.Name,
+		Description: dataset.Description,
+		Columns:     strings.Join(columnNames, ", "),
+		Values:      values.String(),
+	}
+}
+
+// ComplianceDocument represents a compliance policy document for indexing
+type ComplianceDocument struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Category string `json:"category"`
+	Licenses string `json:"licenses"`
+	Content  string `json:"content"`
+}
+
+// FromCompliancePolicy creates a ComplianceDocument from a models.CompliancePolicy
+func FromCompliancePolicy(policy models.CompliancePolicy) ComplianceDocument {
+	var licenses []string
+	licenses = append(licenses, policy.Allowed...)
+	licenses = append(licenses, policy.Review...)
+	licenses = append(licenses, policy.Denied...)
+
+	return ComplianceDocument{
+		ID:       policy.ID,
+		Title:    policy.Title,
+		Category: policy.Category,
+		Licenses: strings.Join(licenses, ", "),
+		Content:  policy.Content,
+	}
+}
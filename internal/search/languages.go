@@ -0,0 +1,95 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/analysis/lang/de"
+	"github.com/blevesearch/bleve/v2/analysis/lang/es"
+	"github.com/blevesearch/bleve/v2/analysis/lang/fr"
+	"github.com/blevesearch/bleve/v2/analysis/lang/it"
+	"github.com/blevesearch/bleve/v2/analysis/lang/nl"
+	"github.com/blevesearch/bleve/v2/analysis/lang/pt"
+	"github.com/blevesearch/bleve/v2/analysis/lang/ru"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// languageAnalyzers are the analyzers rules and knowledge are indexed with
+// when detected to be in a language other than English, by language code.
+// English and undetected documents keep the standard analyzer the queries
+// were tuned for.
+var languageAnalyzers = map[string]string{
+	"de": de.AnalyzerName,
+	"es": es.AnalyzerName,
+	"fr": fr.AnalyzerName,
+	"it": it.AnalyzerName,
+	"nl": nl.AnalyzerName,
+	"pt": pt.AnalyzerName,
+	"ru": ru.AnalyzerName,
+	"ja": cjk.AnalyzerName,
+	"ko": cjk.AnalyzerName,
+	"zh": cjk.AnalyzerName,
+}
+
+// documentType names the mapping a document of the base type is indexed
+// with: one of its language, or the base mapping
+func documentType(base, language string) string {
+	if _, ok := languageAnalyzers[language]; ok {
+		return base + "_" + language
+	}
+	return base
+}
+
+// addLanguageMappings registers a variant of a document mapping for each
+// language with its own analyzer; fields without an analyzer of their own
+// take the variant's
+func addLanguageMappings(indexMapping *mapping.IndexMappingImpl, base string, newMapping func() *mapping.DocumentMapping) {
+	for language, analyzer := range languageAnalyzers {
+		variant := newMapping()
+		variant.DefaultAnalyzer = analyzer
+		indexMapping.AddDocumentMapping(documentType(base, language), variant)
+	}
+}
+
+// newLanguageField maps the language code whole, to filter on
+func newLanguageField() *mapping.FieldMapping {
+	field := bleve.NewTextFieldMapping()
+	field.Analyzer = keyword.Name
+	field.Store = true
+	field.IncludeInAll = false
+	return field
+}
+
+// addLanguageMatches adds match queries analyzed like the documents of
+// other languages, so their stemmed terms match inflected query words.
+// A language limits them to that language's analyzer. Only rules and
+// knowledge are indexed per language.
+func addLanguageMatches(indexType IndexType, disjunction *query.DisjunctionQuery, queryStr, language string) {
+	if indexType != IndexTypeRules && indexType != IndexTypeKnowledge {
+		return
+	}
+	var analyzers []string
+	if language != "" {
+		if analyzer, ok := languageAnalyzers[language]; ok {
+			analyzers = append(analyzers, analyzer)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, analyzer := range languageAnalyzers {
+			if !seen[analyzer] {
+				seen[analyzer] = true
+				analyzers = append(analyzers, analyzer)
+			}
+		}
+		sort.Strings(analyzers)
+	}
+	for _, analyzer := range analyzers {
+		matchQuery := bleve.NewMatchQuery(queryStr)
+		matchQuery.Analyzer = analyzer
+		matchQuery.SetBoost(2.0)
+		disjunction.AddQuery(matchQuery)
+	}
+}
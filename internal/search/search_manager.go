@@ -1,61 +1,81 @@
 package search
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/index/scorch/mergeplan"
 	"github.com/blevesearch/bleve/v2/mapping"
 	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/metrics"
 )
 
-// No need for custom analyzer registration - using standard analyzer
+// The standard analyzer is the default; rules and knowledge in other
+// languages get their language's analyzer, see languages.go
 
 // IndexType represents the type of index
 type IndexType string
 
 const (
-	IndexTypeRules     IndexType = "rules"
-	IndexTypeKnowledge IndexType = "knowledge"
-	IndexTypeTodos     IndexType = "todos"
-	IndexTypeHistory   IndexType = "history"
-	IndexTypeDatabase  IndexType = "database"
-	IndexTypeBackups   IndexType = "backups"
+	IndexTypeRules      IndexType = "rules"
+	IndexTypeKnowledge  IndexType = "knowledge"
+	IndexTypeTodos      IndexType = "todos"
+	IndexTypeHistory    IndexType = "history"
+	IndexTypeDatabase   IndexType = "database"
+	IndexTypeBackups    IndexType = "backups"
+	IndexTypeDatasets   IndexType = "datasets"
+	IndexTypeCompliance IndexType = "compliance"
 )
 
+// IndexTypes lists every index a SearchManager opens
+var IndexTypes = []IndexType{
+	IndexTypeRules,
+	IndexTypeKnowledge,
+	IndexTypeTodos,
+	IndexTypeHistory,
+	IndexTypeDatabase,
+	IndexTypeBackups,
+	IndexTypeDatasets,
+	IndexTypeCompliance,
+}
+
 // SearchManager manages all Bleve indexes
 type SearchManager struct {
-	basePath string
-	indexes  map[IndexType]bleve.Index
-	mu       sync.RWMutex
+	dir     string // holds one directory per index
+	indexes map[IndexType]bleve.Index
+	added   []IndexType // opened by AddIndex, beyond IndexTypes
+	mu      sync.RWMutex
 }
 
-// NewSearchManager creates a new search manager
+// NewSearchManager creates a new search manager keeping its indexes in the
+// indexes directory under basePath
 func NewSearchManager(basePath string) (*SearchManager, error) {
+	return NewSearchManagerIn(filepath.Join(basePath, "indexes"))
+}
+
+// NewSearchManagerIn creates a new search manager keeping its indexes in dir
+func NewSearchManagerIn(dir string) (*SearchManager, error) {
 	sm := &SearchManager{
-		basePath: basePath,
-		indexes:  make(map[IndexType]bleve.Index),
+		dir:     dir,
+		indexes: make(map[IndexType]bleve.Index),
 	}
 
 	// Create indexes directory if it doesn't exist
-	indexesPath := filepath.Join(basePath, "indexes")
-	if err := os.MkdirAll(indexesPath, 0755); err != nil {
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create indexes directory: %w", err)
 	}
 
 	// Initialize all indexes
-	indexTypes := []IndexType{
-		IndexTypeRules,
-		IndexTypeKnowledge,
-		IndexTypeTodos,
-		IndexTypeHistory,
-		IndexTypeDatabase,
-		IndexTypeBackups,
-	}
-
-	for _, indexType := range indexTypes {
+	for _, indexType := range IndexTypes {
 		if err := sm.initializeIndex(indexType); err != nil {
 			return nil, fmt.Errorf("failed to initialize %s index: %w", indexType, err)
 		}
@@ -64,9 +84,19 @@ func NewSearchManager(basePath string) (*SearchManager, error) {
 	return sm, nil
 }
 
+// indexPath returns the directory of an index
+func (sm *SearchManager) indexPath(indexType IndexType) string {
+	return filepath.Join(sm.dir, string(indexType))
+}
+
+// Dir returns the directory holding the indexes
+func (sm *SearchManager) Dir() string {
+	return sm.dir
+}
+
 // initializeIndex initializes or opens an index
 func (sm *SearchManager) initializeIndex(indexType IndexType) error {
-	indexPath := filepath.Join(sm.basePath, "indexes", string(indexType))
+	indexPath := sm.indexPath(indexType)
 
 	// Check if index exists
 	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
@@ -76,6 +106,7 @@ func (sm *SearchManager) initializeIndex(indexType IndexType) error {
 		if err != nil {
 			return fmt.Errorf("failed to create index: %w", err)
 		}
+		slog.Debug("created search index", "index", indexType, "path", indexPath)
 		sm.indexes[indexType] = index
 	} else {
 		// Open existing index
@@ -83,12 +114,29 @@ func (sm *SearchManager) initializeIndex(indexType IndexType) error {
 		if err != nil {
 			return fmt.Errorf("failed to open index: %w", err)
 		}
+		slog.Debug("opened search index", "index", indexType, "path", indexPath)
 		sm.indexes[indexType] = index
 	}
 
 	return nil
 }
 
+// AddIndex opens or creates an index of a type not in IndexTypes, such as
+// one a custom handler writes to. Its documents are mapped dynamically.
+func (sm *SearchManager) AddIndex(indexType IndexType) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, exists := sm.indexes[indexType]; exists {
+		return fmt.Errorf("index %s already exists", indexType)
+	}
+	if err := sm.initializeIndex(indexType); err != nil {
+		return fmt.Errorf("failed to initialize %s index: %w", indexType, err)
+	}
+	sm.added = append(sm.added, indexType)
+	return nil
+}
+
 // createIndexMapping creates a custom mapping for an index type
 func (sm *SearchManager) createIndexMapping(indexType IndexType) mapping.IndexMapping {
 	// Create mapping
@@ -98,75 +146,15 @@ func (sm *SearchManager) createIndexMapping(indexType IndexType) mapping.IndexMa
 	// Create document mappings based on type
 	switch indexType {
 	case IndexTypeRules:
-		ruleMapping := bleve.NewDocumentMapping()
-
-		// ID field
-		idField := bleve.NewTextFieldMapping()
-		idField.Store = true
-		idField.Index = false
-		ruleMapping.AddFieldMappingsAt("id", idField)
-
-		// Title field with higher weight
-		titleField := bleve.NewTextFieldMapping()
-		titleField.Store = true
-		titleField.IncludeInAll = true
-		ruleMapping.AddFieldMappingsAt("title", titleField)
-
-		// Category field
-		categoryField := bleve.NewTextFieldMapping()
-		categoryField.Store = true
-		categoryField.IncludeInAll = true
-		ruleMapping.AddFieldMappingsAt("category", categoryField)
-
-		// Content field
-		contentField := bleve.NewTextFieldMapping()
-		contentField.Store = true
-		contentField.IncludeInAll = true
-		ruleMapping.AddFieldMappingsAt("content", contentField)
-
-		// Priority field
-		priorityField := bleve.NewTextFieldMapping()
-		priorityField.Store = true
-		priorityField.IncludeInAll = true
-		ruleMapping.AddFieldMappingsAt("priority", priorityField)
-
+		ruleMapping := newRuleMapping()
 		indexMapping.AddDocumentMapping("rule", ruleMapping)
+		addLanguageMappings(indexMapping, "rule", newRuleMapping)
 		indexMapping.DefaultMapping = ruleMapping
 
 	case IndexTypeKnowledge:
-		knowledgeMapping := bleve.NewDocumentMapping()
-
-		// ID field
-		idField := bleve.NewTextFieldMapping()
-		idField.Store = true
-		idField.Index = false
-		knowledgeMapping.AddFieldMappingsAt("id", idField)
-
-		// Title field
-		titleField := bleve.NewTextFieldMapping()
-		titleField.Store = true
-		titleField.IncludeInAll = true
-		knowledgeMapping.AddFieldMappingsAt("title", titleField)
-
-		// Category field
-		categoryField := bleve.NewTextFieldMapping()
-		categoryField.Store = true
-		categoryField.IncludeInAll = true
-		knowledgeMapping.AddFieldMappingsAt("category", categoryField)
-
-		// Content field
-		contentField := bleve.NewTextFieldMapping()
-		contentField.Store = true
-		contentField.IncludeInAll = true
-		knowledgeMapping.AddFieldMappingsAt("content", contentField)
-
-		// Tags field
-		tagsField := bleve.NewTextFieldMapping()
-		tagsField.Store = true
-		tagsField.IncludeInAll = true
-		knowledgeMapping.AddFieldMappingsAt("tags", tagsField)
-
+		knowledgeMapping := newKnowledgeMapping()
 		indexMapping.AddDocumentMapping("knowledge", knowledgeMapping)
+		addLanguageMappings(indexMapping, "knowledge", newKnowledgeMapping)
 		indexMapping.DefaultMapping = knowledgeMapping
 
 	case IndexTypeTodos:
@@ -238,6 +226,19 @@ func (sm *SearchManager) createIndexMapping(indexType IndexType) mapping.IndexMa
 		filesField.IncludeInAll = true
 		historyMapping.AddFieldMappingsAt("files", filesField)
 
+		// Content field holds change bodies; it is kept out of _all so that
+		// metadata searches aren't swamped by code matches
+		contentField := bleve.NewTextFieldMapping()
+		contentField.Store = false
+		contentField.IncludeInAll = false
+		historyMapping.AddFieldMappingsAt("content", contentField)
+
+		// Tests field holds failing test names so an entry can be found by the tests it broke
+		testsField := bleve.NewTextFieldMapping()
+		testsField.Store = true
+		testsField.IncludeInAll = true
+		historyMapping.AddFieldMappingsAt("tests", testsField)
+
 		// Timestamp field
 		timestampField := bleve.NewDateTimeFieldMapping()
 		timestampField.Store = true
@@ -312,11 +313,163 @@ func (sm *SearchManager) createIndexMapping(indexType IndexType) mapping.IndexMa
 
 		indexMapping.AddDocumentMapping("backup", backupMapping)
 		indexMapping.DefaultMapping = backupMapping
+
+	case IndexTypeDatasets:
+		datasetMapping := bleve.NewDocumentMapping()
+
+		// Name field
+		nameField := bleve.NewTextFieldMapping()
+		nameField.Store = true
+		nameField.IncludeInAll = true
+		datasetMapping.AddFieldMappingsAt("name", nameField)
+
+		// Description field
+		descriptionField := bleve.NewTextFieldMapping()
+		descriptionField.Store = true
+		descriptionField.IncludeInAll = true
+		datasetMapping.AddFieldMappingsAt("description", descriptionField)
+
+		// Columns field
+		columnsField := bleve.NewTextFieldMapping()
+		columnsField.Store = true
+		columnsField.IncludeInAll = true
+		datasetMapping.AddFieldMappingsAt("columns", columnsField)
+
+		// Values field, so lookups by cell content find the dataset
+		valuesField := bleve.NewTextFieldMapping()
+		valuesField.Store = false
+		valuesField.IncludeInAll = true
+		datasetMapping.AddFieldMappingsAt("values", valuesField)
+
+		indexMapping.AddDocumentMapping("dataset", datasetMapping)
+		indexMapping.DefaultMapping = datasetMapping
+
+	case IndexTypeCompliance:
+		complianceMapping := bleve.NewDocumentMapping()
+
+		// Title field
+		titleField := bleve.NewTextFieldMapping()
+		titleField.Store = true
+		titleField.IncludeInAll = true
+		complianceMapping.AddFieldMappingsAt("title", titleField)
+
+		// Category field
+		categoryField := bleve.NewTextFieldMapping()
+		categoryField.Store = true
+		categoryField.IncludeInAll = true
+		complianceMapping.AddFieldMappingsAt("category", categoryField)
+
+		// Licenses field
+		licensesField := bleve.NewTextFieldMapping()
+		licensesField.Store = true
+		licensesField.IncludeInAll = true
+		complianceMapping.AddFieldMappingsAt("licenses", licensesField)
+
+		// Content field
+		contentField := bleve.NewTextFieldMapping()
+		contentField.Store = true
+		contentField.IncludeInAll = true
+		complianceMapping.AddFieldMappingsAt("content", contentField)
+
+		indexMapping.AddDocumentMapping("compliance", complianceMapping)
+		indexMapping.DefaultMapping = complianceMapping
 	}
 
 	return indexMapping
 }
 
+// newRuleMapping creates the mapping of rule documents
+func newRuleMapping() *mapping.DocumentMapping {
+	ruleMapping := bleve.NewDocumentMapping()
+
+	// ID field
+	idField := bleve.NewTextFieldMapping()
+	idField.Store = true
+	idField.Index = false
+	ruleMapping.AddFieldMappingsAt("id", idField)
+
+	// Title field with higher weight
+	titleField := bleve.NewTextFieldMapping()
+	titleField.Store = true
+	titleField.IncludeInAll = true
+	ruleMapping.AddFieldMappingsAt("title", titleField)
+
+	// Category field
+	categoryField := bleve.NewTextFieldMapping()
+	categoryField.Store = true
+	categoryField.IncludeInAll = true
+	ruleMapping.AddFieldMappingsAt("category", categoryField)
+
+	// Content field
+	contentField := bleve.NewTextFieldMapping()
+	contentField.Store = true
+	contentField.IncludeInAll = true
+	ruleMapping.AddFieldMappingsAt("content", contentField)
+
+	// Priority field
+	priorityField := bleve.NewTextFieldMapping()
+	priorityField.Store = true
+	priorityField.IncludeInAll = true
+	ruleMapping.AddFieldMappingsAt("priority", priorityField)
+
+	// Tags field
+	tagsField := bleve.NewTextFieldMapping()
+	tagsField.Store = true
+	tagsField.IncludeInAll = true
+	ruleMapping.AddFieldMappingsAt("tags", tagsField)
+
+	// Language field to filter on
+	ruleMapping.AddFieldMappingsAt("language", newLanguageField())
+
+	return ruleMapping
+}
+
+// newKnowledgeMapping creates the mapping of knowledge documents
+func newKnowledgeMapping() *mapping.DocumentMapping {
+	knowledgeMapping := bleve.NewDocumentMapping()
+
+	// ID field
+	idField := bleve.NewTextFieldMapping()
+	idField.Store = true
+	idField.Index = false
+	knowledgeMapping.AddFieldMappingsAt("id", idField)
+
+	// Title field
+	titleField := bleve.NewTextFieldMapping()
+	titleField.Store = true
+	titleField.IncludeInAll = true
+	knowledgeMapping.AddFieldMappingsAt("title", titleField)
+
+	// Category field
+	categoryField := bleve.NewTextFieldMapping()
+	categoryField.Store = true
+	categoryField.IncludeInAll = true
+	knowledgeMapping.AddFieldMappingsAt("category", categoryField)
+
+	// Content field
+	contentField := bleve.NewTextFieldMapping()
+	contentField.Store = true
+	contentField.IncludeInAll = true
+	knowledgeMapping.AddFieldMappingsAt("content", contentField)
+
+	// Tags field
+	tagsField := bleve.NewTextFieldMapping()
+	tagsField.Store = true
+	tagsField.IncludeInAll = true
+	knowledgeMapping.AddFieldMappingsAt("tags", tagsField)
+
+	// Metadata fields come from configuration, so they are mapped
+	// dynamically, as keywords to filter on
+	metadataMapping := bleve.NewDocumentMapping()
+	metadataMapping.DefaultAnalyzer = keyword.Name
+	knowledgeMapping.AddSubDocumentMapping("metadata", metadataMapping)
+
+	// Language field to filter on
+	knowledgeMapping.AddFieldMappingsAt("language", newLanguageField())
+
+	return knowledgeMapping
+}
+
 // IndexDocument indexes a document
 func (sm *SearchManager) IndexDocument(indexType IndexType, id string, doc interface{}) error {
 	sm.mu.RLock()
@@ -351,6 +504,11 @@ func (sm *SearchManager) DeleteDocument(indexType IndexType, id string) error {
 
 // Search performs a search on an index
 func (sm *SearchManager) Search(indexType IndexType, queryStr string, size int) (*bleve.SearchResult, error) {
+	return sm.SearchContext(context.Background(), indexType, queryStr, size)
+}
+
+// SearchContext is Search that stops when ctx is cancelled
+func (sm *SearchManager) SearchContext(ctx context.Context, indexType IndexType, queryStr string, size int) (*bleve.SearchResult, error) {
 	sm.mu.RLock()
 	index, exists := sm.indexes[indexType]
 	sm.mu.RUnlock()
@@ -388,6 +546,9 @@ func (sm *SearchManager) Search(indexType IndexType, queryStr string, size int)
 		wildcardQuery := bleve.NewWildcardQuery("*" + queryStr + "*")
 		disjunction.AddQuery(wildcardQuery)
 
+		// Match queries for documents indexed with a language's analyzer
+		addLanguageMatches(indexType, disjunction, queryStr, "")
+
 		q = disjunction
 	}
 
@@ -405,11 +566,16 @@ func (sm *SearchManager) Search(indexType IndexType, queryStr string, size int)
 		searchRequest.AddFacet("priority", bleve.NewFacetRequest("priority", 5))
 	}
 
-	return index.Search(searchRequest)
+	return runSearch(ctx, indexType, index, searchRequest)
 }
 
 // SearchWithFilters performs a search with additional filters
 func (sm *SearchManager) SearchWithFilters(indexType IndexType, queryStr string, filters map[string]interface{}, size int) (*bleve.SearchResult, error) {
+	return sm.SearchWithFiltersContext(context.Background(), indexType, queryStr, filters, size)
+}
+
+// SearchWithFiltersContext is SearchWithFilters that stops when ctx is cancelled
+func (sm *SearchManager) SearchWithFiltersContext(ctx context.Context, indexType IndexType, queryStr string, filters map[string]interface{}, size int) (*bleve.SearchResult, error) {
 	sm.mu.RLock()
 	index, exists := sm.indexes[indexType]
 	sm.mu.RUnlock()
@@ -441,6 +607,10 @@ func (sm *SearchManager) SearchWithFilters(indexType IndexType, queryStr string,
 		wildcardQuery := bleve.NewWildcardQuery("*" + queryStr + "*")
 		disjunction.AddQuery(wildcardQuery)
 
+		// A language filter analyzes the query like that language's documents
+		language, _ := filters["language"].(string)
+		addLanguageMatches(indexType, disjunction, queryStr, language)
+
 		mainQuery = disjunction
 	}
 
@@ -471,7 +641,73 @@ func (sm *SearchManager) SearchWithFilters(indexType IndexType, queryStr string,
 	searchRequest.Highlight = bleve.NewHighlight()
 	searchRequest.Fields = []string{"*"}
 
-	return index.Search(searchRequest)
+	return runSearch(ctx, indexType, index, searchRequest)
+}
+
+// SearchField performs a search restricted to a single field. An empty
+// field searches the default fields, like Search.
+func (sm *SearchManager) SearchField(indexType IndexType, field, queryStr string, size int) (*bleve.SearchResult, error) {
+	if field == "" {
+		return sm.Search(indexType, queryStr, size)
+	}
+
+	sm.mu.RLock()
+	index, exists := sm.indexes[indexType]
+	sm.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("index %s not found", indexType)
+	}
+
+	disjunction := bleve.NewDisjunctionQuery()
+
+	matchQuery := bleve.NewMatchQuery(queryStr)
+	matchQuery.SetField(field)
+	matchQuery.SetBoost(2.0)
+	disjunction.AddQuery(matchQuery)
+
+	phraseQuery := bleve.NewMatchPhraseQuery(queryStr)
+	phraseQuery.SetField(field)
+	phraseQuery.SetBoost(3.0)
+	disjunction.AddQuery(phraseQuery)
+
+	// Identifiers such as maxRetries are a single token, so also match them by prefix
+	for _, term := range strings.Fields(strings.ToLower(queryStr)) {
+		prefixQuery := bleve.NewPrefixQuery(term)
+		prefixQuery.SetField(field)
+		disjunction.AddQuery(prefixQuery)
+	}
+
+	searchRequest := bleve.NewSearchRequest(disjunction)
+	searchRequest.Size = size
+	searchRequest.Fields = []string{"*"}
+
+	return runSearch(context.Background(), indexType, index, searchRequest)
+}
+
+// searchDuration records search latency per index
+var searchDuration = metrics.Default.NewHistogramVec("buddy_search_duration_seconds", "Search latency in seconds.", nil, "index")
+
+// runSearch executes a search request, logging its outcome. A cancelled
+// ctx ends the search early with the context's error.
+func runSearch(ctx context.Context, indexType IndexType, index bleve.Index, searchRequest *bleve.SearchRequest) (*bleve.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err := index.SearchInContext(ctx, searchRequest)
+	searchDuration.Observe(time.Since(start).Seconds(), string(indexType))
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		slog.DebugContext(ctx, "search cancelled", "index", indexType, "error", ctxErr)
+		return nil, ctxErr
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "search failed", "index", indexType, "error", err)
+		return nil, err
+	}
+	slog.DebugContext(ctx, "search", "index", indexType, "hits", result.Total, "took", result.Took)
+	return result, nil
 }
 
 // ReindexAll reindexes all documents in an index
@@ -479,14 +715,17 @@ func (sm *SearchManager) ReindexAll(indexType IndexType) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	slog.Debug("rebuilding search index", "index", indexType)
+
 	// Close existing index
 	if index, exists := sm.indexes[indexType]; exists {
-		index.Close()
+		if err := index.Close(); err != nil {
+			slog.Warn("failed to close search index", "index", indexType, "error", err)
+		}
 	}
 
 	// Delete index directory
-	indexPath := filepath.Join(sm.basePath, "indexes", string(indexType))
-	if err := os.RemoveAll(indexPath); err != nil {
+	if err := os.RemoveAll(sm.indexPath(indexType)); err != nil {
 		return fmt.Errorf("failed to remove index directory: %w", err)
 	}
 
@@ -520,3 +759,111 @@ func (sm *SearchManager) GetDocumentCount(indexType IndexType) (uint64, error) {
 
 	return index.DocCount()
 }
+
+// DocumentCounts returns the number of documents in every index. Indexes
+// whose count can't be read are left out.
+func (sm *SearchManager) DocumentCounts() map[IndexType]uint64 {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	counts := make(map[IndexType]uint64, len(sm.indexes))
+	for indexType, index := range sm.indexes {
+		if count, err := index.DocCount(); err == nil {
+			counts[indexType] = count
+		}
+	}
+	return counts
+}
+
+// CheckIndexes returns the indexes that are missing or can't be read,
+// such as one a failed rebuild left closed, with the reason
+func (sm *SearchManager) CheckIndexes() map[IndexType]error {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	failed := make(map[IndexType]error)
+	for _, indexType := range append(IndexTypes[:len(IndexTypes):len(IndexTypes)], sm.added...) {
+		index, exists := sm.indexes[indexType]
+		if !exists {
+			failed[indexType] = fmt.Errorf("index %s not found", indexType)
+			continue
+		}
+		if _, err := index.DocCount(); err != nil {
+			failed[indexType] = err
+		}
+	}
+	return failed
+}
+
+// DiskUsage returns the bytes each index takes on disk. Indexes whose
+// directory can't be walked are left out.
+func (sm *SearchManager) DiskUsage() map[IndexType]int64 {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	usage := make(map[IndexType]int64, len(sm.indexes))
+	for indexType := range sm.indexes {
+		size, err := dirSize(sm.indexPath(indexType))
+		if err != nil {
+			slog.Debug("failed to measure search index", "index", indexType, "error", err)
+			continue
+		}
+		usage[indexType] = size
+	}
+	return usage
+}
+
+// dirSize sums the sizes of the files under dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Segments are removed while merges finish
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, err
+}
+
+// forceMerger is implemented by indexes that can merge their segments on
+// demand, such as scorch
+type forceMerger interface {
+	ForceMerge(ctx context.Context, options *mergeplan.MergePlanOptions) error
+}
+
+// Compact merges each index's segments into one, dropping the space held
+// by deleted and replaced documents. Indexes keep serving searches while
+// they are compacted; rebuilds wait until it is done.
+func (sm *SearchManager) Compact(ctx context.Context) error {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	start := time.Now()
+	for indexType, index := range sm.indexes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		advanced, err := index.Advanced()
+		if err != nil {
+			return fmt.Errorf("failed to compact %s index: %w", indexType, err)
+		}
+		merger, ok := advanced.(forceMerger)
+		if !ok {
+			continue
+		}
+		if err := merger.ForceMerge(ctx, nil); err != nil {
+			return fmt.Errorf("failed to compact %s index: %w", indexType, err)
+		}
+	}
+	slog.DebugContext(ctx, "compacted search indexes", "took", time.Since(start))
+	return nil
+}
@@ -1,10 +1,14 @@
 package search
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -24,6 +28,8 @@ func TestNewSearchManager(t *testing.T) {
 		IndexTypeHistory,
 		IndexTypeDatabase,
 		IndexTypeBackups,
+		IndexTypeDatasets,
+		IndexTypeCompliance,
 	}
 
 	for _, indexType := range indexTypes {
@@ -128,6 +134,44 @@ func TestSearchManager_SearchWithFilters(t *testing.T) {
 	assert.Equal(t, 0, len(results.Hits)) // Should find no documents
 }
 
+func TestSearchManager_SearchMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	sm, err := NewSearchManager(tempDir)
+	require.NoError(t, err)
+	defer sm.Close()
+
+	docs := []*KnowledgeDocument{
+		{
+			ID:       "kb-1",
+			Title:    "Payment Retries",
+			Content:  "Retries back off exponentially",
+			Metadata: map[string]string{"service": MetadataValue("Payments Gateway")},
+		},
+		{
+			ID:       "kb-2",
+			Title:    "Search Retries",
+			Content:  "Retries are not needed for search",
+			Metadata: map[string]string{"service": MetadataValue("search")},
+		},
+	}
+	for _, doc := range docs {
+		require.NoError(t, sm.IndexDocument(IndexTypeKnowledge, doc.ID, doc))
+	}
+
+	// Whole values match, ignoring case
+	filters := map[string]interface{}{MetadataField("Service"): MetadataValue("payments gateway")}
+	results, err := sm.SearchWithFilters(IndexTypeKnowledge, "retries", filters, 10)
+	require.NoError(t, err)
+	require.Len(t, results.Hits, 1)
+	assert.Equal(t, "kb-1", results.Hits[0].ID)
+
+	// Part of a value doesn't
+	filters = map[string]interface{}{MetadataField("service"): MetadataValue("payments")}
+	results, err = sm.SearchWithFilters(IndexTypeKnowledge, "retries", filters, 10)
+	require.NoError(t, err)
+	assert.Empty(t, results.Hits)
+}
+
 func TestSearchManager_Search(t *testing.T) {
 	tempDir := t.TempDir()
 	sm, err := NewSearchManager(tempDir)
@@ -163,6 +207,90 @@ func TestSearchManager_Search(t *testing.T) {
 	}
 }
 
+func TestSearchManager_SearchCancelled(t *testing.T) {
+	tempDir := t.TempDir()
+	sm, err := NewSearchManager(tempDir)
+	require.NoError(t, err)
+	defer sm.Close()
+
+	err = sm.IndexDocument(IndexTypeRules, "rule-1", &RuleDocument{ID: "rule-1", Title: "Code Quality Rule"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = sm.SearchContext(ctx, IndexTypeRules, "quality", 10)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = sm.SearchWithFiltersContext(ctx, IndexTypeRules, "quality", map[string]interface{}{"category": "coding"}, 10)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The uncancelled search still works
+	results, err := sm.SearchContext(context.Background(), IndexTypeRules, "quality", 10)
+	require.NoError(t, err)
+	assert.Len(t, results.Hits, 1)
+}
+
+func TestSearchManager_SearchField(t *testing.T) {
+	tempDir := t.TempDir()
+	sm, err := NewSearchManager(tempDir)
+	require.NoError(t, err)
+	defer sm.Close()
+
+	doc := FromHistoryEntry(models.HistoryEntry{
+		ID:          "history-1",
+		Feature:     "http client",
+		Description: "Tune client behaviour",
+		Changes: []models.Change{{
+			FilePath:   "client.go",
+			ChangeType: "modified",
+			Before:     "const maxRetries = 3",
+			After:      "const maxRetries = 5",
+		}},
+		Timestamp: time.Now(),
+	})
+	require.NoError(t, sm.IndexDocument(IndexTypeHistory, doc.ID, doc))
+
+	// Change content is only searchable through the content field
+	results, err := sm.SearchField(IndexTypeHistory, "content", "maxretries", 10)
+	require.NoError(t, err)
+	require.Len(t, results.Hits, 1)
+	assert.Equal(t, "history-1", results.Hits[0].ID)
+
+	results, err = sm.SearchField(IndexTypeHistory, "", "maxretries", 10)
+	require.NoError(t, err)
+	assert.Len(t, results.Hits, 0)
+
+	results, err = sm.SearchField(IndexTypeHistory, "", "client", 10)
+	require.NoError(t, err)
+	assert.Len(t, results.Hits, 1)
+}
+
+func TestSearchManager_HistoryFailingTests(t *testing.T) {
+	tempDir := t.TempDir()
+	sm, err := NewSearchManager(tempDir)
+	require.NoError(t, err)
+	defer sm.Close()
+
+	doc := FromHistoryEntry(models.HistoryEntry{
+		ID:          "history-tests",
+		Feature:     "auth",
+		Description: "Switch session storage",
+		TestRuns: []models.TestRun{{
+			Status:       "failed",
+			FailingTests: []string{"example.com/auth.TestSessionExpiry/idle"},
+		}},
+		Timestamp: time.Now(),
+	})
+	require.NoError(t, sm.IndexDocument(IndexTypeHistory, doc.ID, doc))
+
+	// Entries are found by the tests they broke
+	results, err := sm.SearchField(IndexTypeHistory, "", "TestSessionExpiry", 10)
+	require.NoError(t, err)
+	require.Len(t, results.Hits, 1)
+	assert.Equal(t, "history-tests", results.Hits[0].ID)
+}
+
 func TestSearchManager_DeleteDocument(t *testing.T) {
 	tempDir := t.TempDir()
 	sm, err := NewSearchManager(tempDir)
@@ -240,6 +368,54 @@ func TestSearchManager_ReindexAll(t *testing.T) {
 	assert.Equal(t, 0, len(results.Hits))
 }
 
+func TestSearchManager_CheckIndexes(t *testing.T) {
+	sm, err := NewSearchManager(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, sm.CheckIndexes())
+
+	require.NoError(t, sm.indexes[IndexTypeTodos].Close())
+	failed := sm.CheckIndexes()
+	require.Len(t, failed, 1)
+	assert.Error(t, failed[IndexTypeTodos])
+
+	delete(sm.indexes, IndexTypeTodos)
+	assert.EqualError(t, sm.CheckIndexes()[IndexTypeTodos], "index todos not found")
+	require.NoError(t, sm.Close())
+}
+
+func TestSearchManager_AddIndex(t *testing.T) {
+	dir := t.TempDir()
+	sm, err := NewSearchManager(dir)
+	require.NoError(t, err)
+
+	incidents := IndexType("incidents")
+	require.NoError(t, sm.AddIndex(incidents))
+	assert.Error(t, sm.AddIndex(incidents))
+	assert.Error(t, sm.AddIndex(IndexTypeRules))
+
+	doc := map[string]interface{}{"title": "Database outage", "content": "Primary failed over at 02:00"}
+	require.NoError(t, sm.IndexDocument(incidents, "inc-1", doc))
+	results, err := sm.Search(incidents, "outage", 10)
+	require.NoError(t, err)
+	require.Len(t, results.Hits, 1)
+	assert.Equal(t, "inc-1", results.Hits[0].ID)
+	assert.Empty(t, sm.CheckIndexes())
+
+	require.NoError(t, sm.indexes[incidents].Close())
+	delete(sm.indexes, incidents)
+	assert.EqualError(t, sm.CheckIndexes()[incidents], "index incidents not found")
+	require.NoError(t, sm.Close())
+
+	// The index is reopened with its documents
+	sm, err = NewSearchManager(dir)
+	require.NoError(t, err)
+	defer sm.Close()
+	require.NoError(t, sm.AddIndex(incidents))
+	count, err := sm.GetDocumentCount(incidents)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+}
+
 func TestSearchManager_GetDocumentCount(t *testing.T) {
 	tempDir := t.TempDir()
 	sm, err := NewSearchManager(tempDir)
@@ -359,6 +535,30 @@ func TestSearchManager_DocumentTypes(t *testing.T) {
 			},
 			docID: "backup-1",
 		},
+		{
+			name:      "DatasetDocument",
+			indexType: IndexTypeDatasets,
+			document: &DatasetDocument{
+				ID:          "dataset-1",
+				Name:        "countries",
+				Description: "Test country codes",
+				Columns:     "code string, name string",
+				Values:      "DE Germany\nFR France\n",
+			},
+			docID: "dataset-1",
+		},
+		{
+			name:      "ComplianceDocument",
+			indexType: IndexTypeCompliance,
+			document: &ComplianceDocument{
+				ID:       "compliance-1",
+				Title:    "License Policy",
+				Category: "licenses",
+				Licenses: "MIT, Apache-2.0, GPL-3.0",
+				Content:  "Test license policy",
+			},
+			docID: "compliance-1",
+		},
 	}
 
 	for _, tt := range tests {
@@ -450,6 +650,8 @@ func TestSearchManager_IndexesDirectory(t *testing.T) {
 		IndexTypeHistory,
 		IndexTypeDatabase,
 		IndexTypeBackups,
+		IndexTypeDatasets,
+		IndexTypeCompliance,
 	}
 
 	for _, indexType := range indexTypes {
@@ -458,3 +660,34 @@ func TestSearchManager_IndexesDirectory(t *testing.T) {
 		assert.NoError(t, err, "Index directory should exist for %s", indexType)
 	}
 }
+
+func TestSearchManager_CompactAndDiskUsage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache", "project")
+	sm, err := NewSearchManagerIn(dir)
+	require.NoError(t, err)
+	defer sm.Close()
+	assert.Equal(t, dir, sm.Dir())
+
+	// Every update leaves a segment behind until the index is merged
+	for i := 0; i < 50; i++ {
+		doc := &KnowledgeDocument{ID: "doc", Title: "Doc", Content: fmt.Sprintf("revision %d of the content", i)}
+		require.NoError(t, sm.IndexDocument(IndexTypeKnowledge, doc.ID, doc))
+	}
+
+	usage := sm.DiskUsage()
+	assert.Len(t, usage, 8)
+	assert.Greater(t, usage[IndexTypeKnowledge], int64(0))
+
+	require.NoError(t, sm.Compact(context.Background()))
+
+	count, err := sm.GetDocumentCount(IndexTypeKnowledge)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+	results, err := sm.Search(IndexTypeKnowledge, "revision", 10)
+	require.NoError(t, err)
+	require.Len(t, results.Hits, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sm.Compact(ctx), context.Canceled)
+}
@@ -0,0 +1,294 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+const (
+	// maxSuggestions caps the terms and groups a suggestion lists
+	maxSuggestions = 5
+	// suggestionHits is how many partially matching documents groups are
+	// counted over
+	suggestionHits = 50
+)
+
+// Suggestions are near misses for a query that found nothing
+type Suggestions struct {
+	// Query is the query with each word that isn't indexed replaced by the
+	// closest indexed term, for "did you mean"; empty when no word could
+	// be corrected
+	Query string `json:"query,omitempty"`
+	// Terms are other indexed terms close to or containing a query word,
+	// most frequent first
+	Terms []string `json:"terms,omitempty"`
+	// Groups are the values of the group field, such as a category, most
+	// common among documents that partially match the query
+	Groups []GroupCount `json:"groups,omitempty"`
+}
+
+// Empty reports whether there is nothing to suggest
+func (s *Suggestions) Empty() bool {
+	return s == nil || (s.Query == "" && len(s.Terms) == 0 && len(s.Groups) == 0)
+}
+
+// GroupCount is a group field value and how many documents have it
+type GroupCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Suggest finds near misses for a query from the index's term
+// dictionaries: a spelling correction, close terms, and the most common
+// values of groupField (a stored field; empty skips groups) among
+// documents matching a query word by prefix or with a typo
+func (sm *SearchManager) Suggest(ctx context.Context, indexType IndexType, queryStr, groupField string) (*Suggestions, error) {
+	sm.mu.RLock()
+	index, exists := sm.indexes[indexType]
+	sm.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("index %s not found", indexType)
+	}
+
+	words := queryWords(queryStr)
+	suggestions := &Suggestions{}
+	if len(words) == 0 {
+		return suggestions, nil
+	}
+
+	terms, err := indexTerms(index)
+	if err != nil {
+		return nil, err
+	}
+
+	// Correct the words that aren't indexed
+	corrected := make([]string, len(words))
+	changed := false
+	for i, word := range words {
+		corrected[i] = word
+		if _, indexed := terms[word]; indexed {
+			continue
+		}
+		if best := closestTerm(word, terms); best != "" {
+			corrected[i] = best
+			changed = true
+		}
+	}
+	if changed {
+		suggestions.Query = strings.Join(corrected, " ")
+	}
+	suggestions.Terms = relatedTerms(words, corrected, terms)
+
+	if groupField != "" {
+		groups, err := sm.suggestGroups(ctx, indexType, index, words, groupField)
+		if err != nil {
+			return nil, err
+		}
+		suggestions.Groups = groups
+	}
+	return suggestions, nil
+}
+
+// queryWords splits a query into lowercase words the way the standard
+// analyzer does, leaving out single characters
+func queryWords(queryStr string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(queryStr), func(r rune) bool {
+		return !isTermRune(r)
+	})
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) > 1 {
+			words = append(words, field)
+		}
+	}
+	return words
+}
+
+// isTermRune reports whether r can be part of a suggested term
+func isTermRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// indexTerms collects the words in every field's term dictionary with the
+// number of documents containing them. Numeric and date fields store
+// encoded terms, which aren't words and are left out.
+func indexTerms(index bleve.Index) (map[string]uint64, error) {
+	fields, err := index.Fields()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list index fields: %w", err)
+	}
+
+	terms := make(map[string]uint64)
+	for _, field := range fields {
+		if strings.HasPrefix(field, "_") || strings.HasPrefix(field, "metadata.") {
+			continue
+		}
+		dict, err := index.FieldDict(field)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s terms: %w", field, err)
+		}
+		for {
+			entry, err := dict.Next()
+			if err != nil {
+				dict.Close()
+				return nil, fmt.Errorf("failed to read %s terms: %w", field, err)
+			}
+			if entry == nil {
+				break
+			}
+			if len(entry.Term) > 1 && strings.IndexFunc(entry.Term, func(r rune) bool { return !isTermRune(r) }) < 0 {
+				terms[entry.Term] += entry.Count
+			}
+		}
+		dict.Close()
+	}
+	return terms, nil
+}
+
+// maxEdits is how many typos a word of this length may have: one for short
+// words, two for longer ones
+func maxEdits(word string) int {
+	if len([]rune(word)) <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// closestTerm returns the indexed term fewest edits away from word, the
+// most frequent on a tie, or "" when none is close enough
+func closestTerm(word string, terms map[string]uint64) string {
+	best, bestDistance, bestCount := "", maxEdits(word), uint64(0)
+	for term, count := range terms {
+		distance := editDistance(word, term, bestDistance+1)
+		if distance > bestDistance {
+			continue
+		}
+		if best == "" || distance < bestDistance || count > bestCount || (count == bestCount && term < best) {
+			best, bestDistance, bestCount = term, distance, count
+		}
+	}
+	return best
+}
+
+// relatedTerms lists indexed terms close to a query word or containing it,
+// other than the words and their corrections, most frequent first
+func relatedTerms(words, corrected []string, terms map[string]uint64) []string {
+	skip := make(map[string]bool, len(words)*2)
+	for i := range words {
+		skip[words[i]] = true
+		skip[corrected[i]] = true
+	}
+
+	var related []string
+	for term := range terms {
+		if skip[term] {
+			continue
+		}
+		for _, word := range words {
+			if (len(word) >= 3 && strings.Contains(term, word)) || editDistance(word, term, maxEdits(word)+1) <= maxEdits(word) {
+				related = append(related, term)
+				break
+			}
+		}
+	}
+
+	sort.Slice(related, func(i, j int) bool {
+		if terms[related[i]] != terms[related[j]] {
+			return terms[related[i]] > terms[related[j]]
+		}
+		return related[i] < related[j]
+	})
+	if len(related) > maxSuggestions {
+		related = related[:maxSuggestions]
+	}
+	return related
+}
+
+// suggestGroups counts the group field values of documents matching any
+// query word by prefix or with a typo
+func (sm *SearchManager) suggestGroups(ctx context.Context, indexType IndexType, index bleve.Index, words []string, groupField string) ([]GroupCount, error) {
+	disjunction := bleve.NewDisjunctionQuery()
+	for _, word := range words {
+		disjunction.AddQuery(bleve.NewPrefixQuery(word))
+		fuzzy := bleve.NewFuzzyQuery(word)
+		fuzzy.SetFuzziness(maxEdits(word))
+		disjunction.AddQuery(fuzzy)
+	}
+
+	request := bleve.NewSearchRequest(disjunction)
+	request.Size = suggestionHits
+	request.Fields = []string{groupField}
+	result, err := runSearch(ctx, indexType, index, request)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, hit := range result.Hits {
+		switch value := hit.Fields[groupField].(type) {
+		case string:
+			if value != "" {
+				counts[value]++
+			}
+		case []interface{}:
+			for _, item := range value {
+				if text, ok := item.(string); ok && text != "" {
+					counts[text]++
+				}
+			}
+		}
+	}
+
+	groups := make([]GroupCount, 0, len(counts))
+	for value, count := range counts {
+		groups = append(groups, GroupCount{Value: value, Count: count})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Value < groups[j].Value
+	})
+	if len(groups) > maxSuggestions {
+		groups = groups[:maxSuggestions]
+	}
+	return groups, nil
+}
+
+// editDistance returns the Levenshtein distance between a and b, or limit
+// once it is certain to be at least limit
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff >= limit || -diff >= limit {
+		return limit
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		previous, current = current, previous
+	}
+	return min(previous[len(rb)], limit)
+}
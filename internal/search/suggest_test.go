@@ -0,0 +1,53 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchManager_Suggest(t *testing.T) {
+	sm, err := NewSearchManager(t.TempDir())
+	require.NoError(t, err)
+	defer sm.Close()
+
+	docs := []*KnowledgeDocument{
+		{ID: "kb-1", Title: "Authentication", Category: "security", Content: "Authentication uses JWT tokens"},
+		{ID: "kb-2", Title: "Authorization", Category: "security", Content: "Roles grant permissions"},
+		{ID: "kb-3", Title: "Deployment", Category: "ops", Content: "Deploy with authentication enabled"},
+		{ID: "kb-4", Title: "Sessions", Category: "security", Content: "Sessions expire after authentication"},
+	}
+	for _, doc := range docs {
+		require.NoError(t, sm.IndexDocument(IndexTypeKnowledge, doc.ID, doc))
+	}
+
+	suggestions, err := sm.Suggest(context.Background(), IndexTypeKnowledge, "Authentcation tokns", "category")
+	require.NoError(t, err)
+	assert.Equal(t, "authentication tokens", suggestions.Query)
+	assert.NotContains(t, suggestions.Terms, "authentication") // already the correction
+	assert.Equal(t, []GroupCount{{Value: "security", Count: 2}, {Value: "ops", Count: 1}}, suggestions.Groups)
+
+	// Partial words suggest the terms containing them
+	suggestions, err = sm.Suggest(context.Background(), IndexTypeKnowledge, "author", "")
+	require.NoError(t, err)
+	assert.Empty(t, suggestions.Query)
+	assert.Contains(t, suggestions.Terms, "authorization")
+	assert.Empty(t, suggestions.Groups)
+
+	// Nothing close
+	suggestions, err = sm.Suggest(context.Background(), IndexTypeKnowledge, "zzzzqqq", "category")
+	require.NoError(t, err)
+	assert.True(t, suggestions.Empty())
+
+	_, err = sm.Suggest(context.Background(), IndexType("missing"), "auth", "")
+	assert.Error(t, err)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("auth", "auth", 3))
+	assert.Equal(t, 1, editDistance("authentcation", "authentication", 3))
+	assert.Equal(t, 2, editDistance("tokne", "token", 3))
+	assert.Equal(t, 3, editDistance("auth", "authentication", 3)) // capped at the limit
+}
@@ -0,0 +1,307 @@
+// Package security scans proposed code changes for common security
+// mistakes: hard-coded secrets, SQL built by string concatenation and
+// disabled TLS verification.
+package security
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Severity levels, most severe first
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// severityRank orders severities for sorting findings
+var severityRank = map[string]int{
+	SeverityCritical: 0,
+	SeverityHigh:     1,
+	SeverityMedium:   2,
+	SeverityLow:      3,
+}
+
+// Detector recognizes one kind of security problem in a line of code
+type Detector struct {
+	ID        string
+	Title     string
+	Category  string // secrets, sql-injection, tls
+	Severity  string
+	Pattern   *regexp.Regexp
+	Message   string // how to fix it
+	Reference string // e.g. a CWE identifier
+	// Redact hides the matched text in findings, for secrets
+	Redact bool
+}
+
+// Finding is a detector match at a specific line
+type Finding struct {
+	DetectorID string `json:"detector_id"`
+	Title      string `json:"title"`
+	Category   string `json:"category"`
+	Severity   string `json:"severity"`
+	File       string `json:"file,omitempty"`
+	Line       int    `json:"line"`
+	Snippet    string `json:"snippet"`
+	Message    string `json:"message"`
+	Reference  string `json:"reference"`
+}
+
+// Engine runs a set of detectors over code
+type Engine struct {
+	detectors []Detector
+}
+
+// NewEngine creates an engine with the given detectors, or the default
+// detectors when none are given
+func NewEngine(detectors ...Detector) *Engine {
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors()
+	}
+	return &Engine{detectors: detectors}
+}
+
+// Detectors returns the engine's detectors
+func (e *Engine) Detectors() []Detector {
+	return e.detectors
+}
+
+// Line is a line of code to scan with its number in the file
+type Line struct {
+	Number int
+	Text   string
+}
+
+// ScanContent scans every line of a file's content
+func (e *Engine) ScanContent(file, content string) []Finding {
+	var lines []Line
+	for i, text := range strings.Split(content, "\n") {
+		lines = append(lines, Line{Number: i + 1, Text: text})
+	}
+	return e.ScanLines(file, lines)
+}
+
+// ScanLines scans the given lines, e.g. only the lines added by a diff.
+// Findings are ordered by severity, then file and line.
+func (e *Engine) ScanLines(file string, lines []Line) []Finding {
+	var findings []Finding
+	for _, line := range lines {
+		for _, detector := range e.detectors {
+			match := detector.Pattern.FindString(line.Text)
+			if match == "" {
+				continue
+			}
+
+			snippet := strings.TrimSpace(line.Text)
+			if detector.Redact {
+				snippet = strings.Replace(snippet, strings.TrimSpace(match), redact(strings.TrimSpace(match)), 1)
+			}
+			if len(snippet) > 160 {
+				snippet = snippet[:160] + "..."
+			}
+
+			findings = append(findings, Finding{
+				DetectorID: detector.ID,
+				Title:      detector.Title,
+				Category:   detector.Category,
+				Severity:   detector.Severity,
+				File:       file,
+				Line:       line.Number,
+				Snippet:    snippet,
+				Message:    detector.Message,
+				Reference:  detector.Reference,
+			})
+		}
+	}
+
+	SortFindings(findings)
+	return findings
+}
+
+// SortFindings orders findings by severity, then file and line
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return severityRank[findings[i].Severity] < severityRank[findings[j].Severity]
+		}
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+}
+
+// RedactSecrets masks the secrets found in text the way findings show them.
+// Secrets whose detectors only match a marker, like a private key header,
+// can't be masked in place, so the whole text is withheld.
+func (e *Engine) RedactSecrets(text string) string {
+	for _, detector := range e.detectors {
+		if detector.Category != "secrets" {
+			continue
+		}
+		if !detector.Redact {
+			if detector.Pattern.MatchString(text) {
+				return "[redacted]"
+			}
+			continue
+		}
+		text = detector.Pattern.ReplaceAllStringFunc(text, redact)
+	}
+	return text
+}
+
+// redact keeps the first few characters of a secret so it can be located
+func redact(secret string) string {
+	keep := 4
+	if len(secret) <= keep*2 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:keep] + strings.Repeat("*", len(secret)-keep)
+}
+
+// hunkHeader matches a unified diff hunk header such as "@@ -1,3 +4,5 @@"
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// AddedLines extracts the lines a unified diff adds, grouped by file and
+// numbered as in the new version of each file
+func AddedLines(diff string) map[string][]Line {
+	added := make(map[string][]Line)
+	file := ""
+	lineNumber := 0
+
+	for _, text := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			file = strings.TrimSpace(strings.TrimPrefix(text, "+++ "))
+			file = strings.TrimPrefix(file, "b/")
+			if idx := strings.Index(file, "\t"); idx >= 0 {
+				file = file[:idx]
+			}
+		case strings.HasPrefix(text, "--- "):
+			// Old file name; the new one follows
+		case strings.HasPrefix(text, "@@"):
+			if match := hunkHeader.FindStringSubmatch(text); match != nil {
+				lineNumber, _ = strconv.Atoi(match[1])
+			}
+		case strings.HasPrefix(text, "+"):
+			added[file] = append(added[file], Line{Number: lineNumber, Text: text[1:]})
+			lineNumber++
+		case strings.HasPrefix(text, "-"):
+			// Removed lines don't exist in the new file
+		default:
+			lineNumber++
+		}
+	}
+
+	return added
+}
+
+// DefaultDetectors returns the built-in detectors
+func DefaultDetectors() []Detector {
+	return []Detector{
+		// Hard-coded secrets
+		{
+			ID:        "secret-private-key",
+			Title:     "Private key in source",
+			Category:  "secrets",
+			Severity:  SeverityCritical,
+			Pattern:   regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP )?PRIVATE KEY-----`),
+			Message:   "Never commit private keys; load them from a secret store or environment",
+			Reference: "CWE-321",
+		},
+		{
+			ID:        "secret-aws-access-key",
+			Title:     "AWS access key ID",
+			Category:  "secrets",
+			Severity:  SeverityCritical,
+			Pattern:   regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+			Message:   "Remove the key, rotate it, and read credentials from the environment or an IAM role",
+			Reference: "CWE-798",
+			Redact:    true,
+		},
+		{
+			ID:        "secret-github-token",
+			Title:     "GitHub token",
+			Category:  "secrets",
+			Severity:  SeverityCritical,
+			Pattern:   regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`),
+			Message:   "Remove the token, revoke it, and read it from the environment",
+			Reference: "CWE-798",
+			Redact:    true,
+		},
+		{
+			ID:        "secret-slack-token",
+			Title:     "Slack token",
+			Category:  "secrets",
+			Severity:  SeverityHigh,
+			Pattern:   regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`),
+			Message:   "Remove the token, revoke it, and read it from the environment",
+			Reference: "CWE-798",
+			Redact:    true,
+		},
+		{
+			ID:        "secret-hardcoded-credential",
+			Title:     "Hard-coded credential",
+			Category:  "secrets",
+			Severity:  SeverityHigh,
+			Pattern:   regexp.MustCompile(`(?i)\b[\w.-]*(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)[\w.-]*["']?\s*(?::=|[:=])\s*["'][^"'\s]{8,}["']`),
+			Message:   "Read credentials from configuration or a secret manager instead of source code",
+			Reference: "CWE-798",
+			Redact:    true,
+		},
+
+		// SQL injection
+		{
+			ID:        "sql-string-concatenation",
+			Title:     "SQL built by string concatenation",
+			Category:  "sql-injection",
+			Severity:  SeverityHigh,
+			Pattern:   regexp.MustCompile(`(?i)["'\x60]\s*(?:select|insert|update|delete)\b[^"'\x60]*["'\x60]\s*\+\s*[\w(]|(?i)["'\x60][^"'\x60]*\bwhere\b[^"'\x60]*["'\x60]\s*\+\s*[\w(]`),
+			Message:   "Use parameterized queries or prepared statements instead of concatenating values",
+			Reference: "CWE-89",
+		},
+		{
+			ID:        "sql-format-string",
+			Title:     "SQL built with a format string",
+			Category:  "sql-injection",
+			Severity:  SeverityHigh,
+			Pattern:   regexp.MustCompile(`(?i)(?:sprintf|format)\(\s*["'\x60][^"'\x60]*\b(?:select|insert|update|delete|where)\b[^"'\x60]*(?:%[sv]|\{\})`),
+			Message:   "Pass values as query parameters instead of formatting them into SQL",
+			Reference: "CWE-89",
+		},
+		{
+			ID:        "sql-interpolation",
+			Title:     "SQL built with string interpolation",
+			Category:  "sql-injection",
+			Severity:  SeverityHigh,
+			Pattern:   regexp.MustCompile(`(?i)\bf"[^"]*\b(?:select|insert|update|delete)\b[^"]*\{|\bf'[^']*\b(?:select|insert|update|delete)\b[^']*\{|\x60[^\x60]*\b(?:select|insert|update|delete)\b[^\x60]*\$\{`),
+			Message:   "Pass values as query parameters instead of interpolating them into SQL",
+			Reference: "CWE-89",
+		},
+
+		// Disabled TLS verification
+		{
+			ID:        "tls-insecure-skip-verify",
+			Title:     "TLS certificate verification disabled",
+			Category:  "tls",
+			Severity:  SeverityHigh,
+			Pattern:   regexp.MustCompile(`InsecureSkipVerify\s*:\s*true`),
+			Message:   "Keep certificate verification on; trust a custom CA with RootCAs instead",
+			Reference: "CWE-295",
+		},
+		{
+			ID:        "tls-verify-false",
+			Title:     "TLS certificate verification disabled",
+			Category:  "tls",
+			Severity:  SeverityHigh,
+			Pattern:   regexp.MustCompile(`\bverify\s*=\s*False\b|\brejectUnauthorized\s*:\s*false\b|NODE_TLS_REJECT_UNAUTHORIZED\s*=\s*["']?0|CURLOPT_SSL_VERIFYPEER\s*,\s*(?:false|0)\b|--insecure\b|\bcurl\s+-k\b`),
+			Message:   "Keep certificate verification on; configure the trusted CA bundle instead",
+			Reference: "CWE-295",
+		},
+	}
+}
@@ -0,0 +1,150 @@
+// Package slug turns titles into file names that are the same on every
+// machine and valid on every common file system.
+package slug
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// DefaultMaxLength bounds slugs when no limit is configured
+	DefaultMaxLength = 60
+	// DefaultTemplate names files after their slug alone
+	DefaultTemplate = "{slug}"
+	// maxNameLength keeps rendered names well below the 255 byte limit of
+	// common file systems, leaving room for collision suffixes
+	maxNameLength = 200
+)
+
+// folds maps letters without a plain ASCII form to their transliteration.
+// The table is fixed rather than taken from the system locale, so the same
+// title gives the same slug everywhere.
+var folds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i", 'İ': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// reservedNames are device names Windows refuses as file names, with or
+// without an extension
+var reservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// Make converts a title into a lowercase ASCII slug of letters, digits and
+// single hyphens, at most maxLength bytes long (DefaultMaxLength if zero or
+// negative). Long slugs are cut at a word boundary where possible. It
+// returns fallback when nothing of the title survives.
+func Make(title string, maxLength int, fallback string) string {
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+
+	var sb strings.Builder
+	hyphen := false
+	for _, r := range title {
+		if fold, ok := folds[r]; ok {
+			sb.WriteString(fold)
+			hyphen = false
+			continue
+		}
+		r = unicode.ToLower(r)
+		if fold, ok := folds[r]; ok {
+			sb.WriteString(fold)
+			hyphen = false
+			continue
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		if !hyphen && sb.Len() > 0 {
+			sb.WriteByte('-')
+			hyphen = true
+		}
+	}
+
+	slug := strings.Trim(sb.String(), "-")
+	if len(slug) > maxLength {
+		cut := slug[:maxLength]
+		if idx := strings.LastIndexByte(cut, '-'); idx > maxLength/2 {
+			cut = cut[:idx]
+		}
+		slug = strings.Trim(cut, "-")
+	}
+
+	if slug == "" {
+		return fallback
+	}
+	if reservedNames[slug] {
+		slug += "-file"
+	}
+	return slug
+}
+
+// Fields are the values a file name template can use
+type Fields struct {
+	Slug string
+	Kind string    // e.g. "rule" or "knowledge"
+	Date time.Time // rendered as YYYY-MM-DD in UTC
+}
+
+// FileName renders a file name template such as "{date}-{slug}" into a
+// name without extension. The placeholders are {slug}, {kind} and {date};
+// an empty template means DefaultTemplate. Characters invalid on Windows,
+// path separators and trailing dots or spaces are replaced or trimmed.
+func FileName(template string, fields Fields) (string, error) {
+	if template == "" {
+		template = DefaultTemplate
+	}
+	if !strings.Contains(template, "{slug}") {
+		return "", fmt.Errorf("file name template %q must contain {slug}", template)
+	}
+
+	name := strings.NewReplacer(
+		"{slug}", fields.Slug,
+		"{kind}", fields.Kind,
+		"{date}", fields.Date.UTC().Format("2006-01-02"),
+	).Replace(template)
+
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r > unicode.MaxASCII || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return "", fmt.Errorf("file name template %q renders an empty name", template)
+	}
+	if reservedNames[strings.ToLower(name)] {
+		name += "-file"
+	}
+
+	return name, nil
+}
+
+// Unique returns name, or name with "-2", "-3", ... inserted before ext
+// when taken reports that it is already used
+func Unique(name, ext string, taken func(fileName string) bool) string {
+	candidate := name + ext
+	for i := 2; taken(candidate); i++ {
+		candidate = fmt.Sprintf("%s-%d%s", name, i, ext)
+	}
+	return candidate
+}
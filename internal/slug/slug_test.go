@@ -0,0 +1,67 @@
+package slug
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Use zap for logging", "use-zap-for-logging"},
+		{"  Error   handling!! ", "error-handling"},
+		{"Café Münchën Straße", "cafe-munchen-strasse"},
+		{"İSTANBUL ışık", "istanbul-isik"},
+		{"Łódź Ærø", "lodz-aero"},
+		{"日本語", "draft"},
+		{"API v2 — auth", "api-v2-auth"},
+		{"CON", "con-file"},
+		{"", "draft"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Make(tt.title, 0, "draft"), tt.title)
+	}
+}
+
+func TestMake_MaxLength(t *testing.T) {
+	slug := Make("always wrap errors returned from repository calls with context", 30, "draft")
+	assert.Equal(t, "always-wrap-errors-returned", slug)
+	assert.LessOrEqual(t, len(slug), 30)
+
+	// A single long word is cut mid-word
+	assert.Equal(t, "abcdefghij", Make("abcdefghijklmnop", 10, "draft"))
+}
+
+func TestFileName(t *testing.T) {
+	date := time.Date(2024, 6, 1, 23, 30, 0, 0, time.FixedZone("X", -5*3600))
+
+	name, err := FileName("", Fields{Slug: "use-zap"})
+	require.NoError(t, err)
+	assert.Equal(t, "use-zap", name)
+
+	name, err = FileName("{date}-{kind}-{slug}", Fields{Slug: "use-zap", Kind: "rule", Date: date})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06-02-rule-use-zap", name)
+
+	// Separators and characters Windows rejects can't come from the template
+	name, err = FileName("team/{slug}: v1.", Fields{Slug: "use-zap"})
+	require.NoError(t, err)
+	assert.Equal(t, "team-use-zap- v1", name)
+
+	_, err = FileName("{date}", Fields{Slug: "use-zap"})
+	assert.Error(t, err)
+}
+
+func TestUnique(t *testing.T) {
+	taken := map[string]bool{"style.md": true, "style-2.md": true}
+	isTaken := func(name string) bool { return taken[name] }
+
+	assert.Equal(t, "style-3.md", Unique("style", ".md", isTaken))
+	assert.Equal(t, "errors.md", Unique("errors", ".md", isTaken))
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mem keeps content in memory, for tests and throwaway projects. Paths are
+// cleaned, so "a/./b" and "a/b" are the same file; directories exist while
+// they hold a file.
+type Mem struct {
+	mu       sync.Mutex
+	files    map[string]memFile
+	watchers map[*memWatcher]struct{}
+	now      func() time.Time
+}
+
+// memFile is one stored file
+type memFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMem creates an empty in-memory storage
+func NewMem() *Mem {
+	return &Mem{
+		files:    make(map[string]memFile),
+		watchers: make(map[*memWatcher]struct{}),
+		now:      time.Now,
+	}
+}
+
+// notExist is the error for a missing path, which IsNotExist recognizes
+func notExist(op, path string) error {
+	return &fs.PathError{Op: op, Path: path, Err: fs.ErrNotExist}
+}
+
+// Read returns the contents of a file
+func (m *Mem) Read(path string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[filepath.Clean(path)]
+	if !ok {
+		return nil, notExist("open", path)
+	}
+	return append([]byte(nil), file.data...), nil
+}
+
+// Write replaces the contents of a file
+func (m *Mem) Write(path string, data []byte) error {
+	path = filepath.Clean(path)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	op := Write
+	if _, ok := m.files[path]; !ok {
+		op = Create
+	}
+	m.files[path] = memFile{data: append([]byte(nil), data...), modTime: m.now()}
+	m.notify(Event{Path: path, Op: op})
+	return nil
+}
+
+// Remove deletes a file or directory tree. Removing a missing path is not
+// an error.
+func (m *Mem) Remove(path string) error {
+	path = filepath.Clean(path)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range m.files {
+		if name == path || within(path, name) {
+			delete(m.files, name)
+			m.notify(Event{Path: name, Op: Remove})
+		}
+	}
+	return nil
+}
+
+// Stat returns information about a single file or directory
+func (m *Mem) Stat(path string) (FileInfo, error) {
+	path = filepath.Clean(path)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if file, ok := m.files[path]; ok {
+		return FileInfo{Path: path, Size: int64(len(file.data)), ModTime: file.modTime}, nil
+	}
+	dir := FileInfo{Path: path, IsDir: true}
+	for name, file := range m.files {
+		if within(path, name) {
+			if file.modTime.After(dir.ModTime) {
+				dir.ModTime = file.modTime
+			}
+		}
+	}
+	if dir.ModTime.IsZero() {
+		return FileInfo{}, notExist("stat", path)
+	}
+	return dir, nil
+}
+
+// List returns the files under dir, sorted by path
+func (m *Mem) List(dir string, recursive bool) ([]FileInfo, error) {
+	dir = filepath.Clean(dir)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var files []FileInfo
+	found := false
+	for name, file := range m.files {
+		if !within(dir, name) {
+			continue
+		}
+		found = true
+		if !recursive && filepath.Dir(name) != dir {
+			continue
+		}
+		files = append(files, FileInfo{Path: name, Size: int64(len(file.data)), ModTime: file.modTime})
+	}
+	if !found {
+		return nil, notExist("open", dir)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// within reports whether path is inside dir
+func within(dir, path string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// Watch returns a watcher told about every write and removal through m
+func (m *Mem) Watch() (Watcher, error) {
+	watcher := &memWatcher{
+		mem:    m,
+		dirs:   make(map[string]bool),
+		events: make(chan Event, 100),
+		errors: make(chan error),
+	}
+	m.mu.Lock()
+	m.watchers[watcher] = struct{}{}
+	m.mu.Unlock()
+	return watcher, nil
+}
+
+// notify tells the watchers of path's directory about a change. Events
+// that don't fit in a watcher's buffer are dropped rather than blocking
+// writes. The caller holds m.mu.
+func (m *Mem) notify(event Event) {
+	for watcher := range m.watchers {
+		if !watcher.dirs[filepath.Dir(event.Path)] {
+			continue
+		}
+		select {
+		case watcher.events <- event:
+		default:
+		}
+	}
+}
+
+// memWatcher is a watcher of a Mem storage; its fields are guarded by the
+// storage's mutex
+type memWatcher struct {
+	mem    *Mem
+	dirs   map[string]bool
+	events chan Event
+	errors chan error
+}
+
+// Add starts watching dir, which needn't hold files yet
+func (w *memWatcher) Add(dir string) error {
+	w.mem.mu.Lock()
+	defer w.mem.mu.Unlock()
+	w.dirs[filepath.Clean(dir)] = true
+	return nil
+}
+
+func (w *memWatcher) Events() <-chan Event { return w.events }
+func (w *memWatcher) Errors() <-chan error { return w.errors }
+
+// Close stops watching; closing twice is harmless
+func (w *memWatcher) Close() error {
+	w.mem.mu.Lock()
+	defer w.mem.mu.Unlock()
+	if _, ok := w.mem.watchers[w]; ok {
+		delete(w.mem.watchers, w)
+		close(w.events)
+		close(w.errors)
+	}
+	return nil
+}
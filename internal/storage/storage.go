@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// FileInfo describes a stored file
+type FileInfo struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// Storage abstracts where handlers persist buddy content so alternate
+// backends (object stores, git, databases) can replace the local disk.
+// Changes made behind the server's back are noticed by the file monitor
+// through Watch.
+type Storage interface {
+	// Read returns the contents of a file
+	Read(path string) ([]byte, error)
+	// Write replaces the contents of a file, creating parent directories
+	Write(path string, data []byte) error
+	// Remove deletes a file or directory tree
+	Remove(path string) error
+	// Stat returns information about a single file
+	Stat(path string) (FileInfo, error)
+	// List returns the files under dir, sorted by path. Directories are
+	// not included. When recursive is false only direct children are listed.
+	List(dir string, recursive bool) ([]FileInfo, error)
+	// Watch returns a watcher reporting changes to files in the
+	// directories added to it
+	Watch() (Watcher, error)
+}
+
+// Op is the kind of change a watch event reports
+type Op uint32
+
+// Watched changes; an event may combine several
+const (
+	Create Op = 1 << iota
+	Write
+	Remove
+	Rename
+)
+
+// Has reports whether op includes change
+func (op Op) Has(change Op) bool {
+	return op&change != 0
+}
+
+func (op Op) String() string {
+	var names []string
+	for _, change := range []struct {
+		op   Op
+		name string
+	}{{Create, "CREATE"}, {Write, "WRITE"}, {Remove, "REMOVE"}, {Rename, "RENAME"}} {
+		if op.Has(change.op) {
+			names = append(names, change.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Event is a change to one file
+type Event struct {
+	Path string
+	Op   Op
+}
+
+// Watcher reports changes to the files directly inside the directories
+// added to it, not in their subdirectories
+type Watcher interface {
+	// Add starts watching dir
+	Add(dir string) error
+	// Events delivers changes until the watcher is closed
+	Events() <-chan Event
+	// Errors delivers failures to watch until the watcher is closed
+	Errors() <-chan error
+	// Close stops watching and closes both channels
+	Close() error
+}
+
+// ModeWriter is implemented by storages that can set the permissions of
+// the files they write, so restored project files keep theirs
+type ModeWriter interface {
+	// WriteMode is Write, creating a missing file with the given mode
+	WriteMode(path string, data []byte, mode os.FileMode) error
+}
+
+// WriteMode writes data to path through s, creating a missing file with
+// mode when s supports permissions
+func WriteMode(s Storage, path string, data []byte, mode os.FileMode) error {
+	if writer, ok := s.(ModeWriter); ok {
+		return writer.WriteMode(path, data, mode)
+	}
+	return s.Write(path, data)
+}
+
+// IsNotExist reports whether err means a file or directory is missing
+func IsNotExist(err error) bool {
+	return os.IsNotExist(err)
+}
+
+// Local stores content on the local filesystem
+type Local struct{}
+
+// NewLocal creates a local filesystem storage
+func NewLocal() *Local {
+	return &Local{}
+}
+
+// Read returns the contents of a file
+func (l *Local) Read(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+// Write replaces the contents of a file, creating parent directories
+func (l *Local) Write(path string, data []byte) error {
+	return l.WriteMode(path, data, 0644)
+}
+
+// WriteMode is Write, creating a missing file with the given mode
+func (l *Local) WriteMode(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, mode)
+}
+
+// Remove deletes a file or directory tree
+func (l *Local) Remove(path string) error {
+	return os.RemoveAll(path)
+}
+
+// Stat returns information about a single file
+func (l *Local) Stat(path string) (FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return FileInfo{
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+	}, nil
+}
+
+// List returns the files under dir, sorted by path
+func (l *Local) List(dir string, recursive bool) ([]FileInfo, error) {
+	var files []FileInfo
+
+	if !recursive {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				files = append(files, FileInfo{
+					Path:    filepath.Join(dir, entry.Name()),
+					Size:    entry.Size(),
+					ModTime: entry.ModTime(),
+				})
+			}
+		}
+		return files, nil
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, FileInfo{
+				Path:    path,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// Watch returns a watcher backed by the operating system's file events
+func (l *Local) Watch() (Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	local := &localWatcher{
+		watcher: watcher,
+		events:  make(chan Event),
+		done:    make(chan struct{}),
+	}
+	go local.relay()
+	return local, nil
+}
+
+// localWatcher translates fsnotify events into storage events
+type localWatcher struct {
+	watcher *fsnotify.Watcher
+	events  chan Event
+	done    chan struct{} // closed by Close to stop relay
+	close   sync.Once
+}
+
+// fsnotifyOps maps fsnotify's operations to watch events'
+var fsnotifyOps = []struct {
+	from fsnotify.Op
+	to   Op
+}{
+	{fsnotify.Create, Create},
+	{fsnotify.Write, Write},
+	{fsnotify.Remove, Remove},
+	{fsnotify.Rename, Rename},
+}
+
+// relay forwards events until the watcher is closed. Changes that are
+// only to permissions aren't reported.
+func (w *localWatcher) relay() {
+	defer close(w.events)
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			var op Op
+			for _, mapping := range fsnotifyOps {
+				if event.Op&mapping.from != 0 {
+					op |= mapping.to
+				}
+			}
+			if op == 0 {
+				continue
+			}
+			select {
+			case w.events <- Event{Path: event.Name, Op: op}:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+func (w *localWatcher) Add(dir string) error { return w.watcher.Add(dir) }
+func (w *localWatcher) Events() <-chan Event { return w.events }
+func (w *localWatcher) Errors() <-chan error { return w.watcher.Errors }
+
+func (w *localWatcher) Close() error {
+	var err error
+	w.close.Do(func() {
+		close(w.done)
+		err = w.watcher.Close()
+	})
+	return err
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalReadWrite(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocal()
+
+	path := filepath.Join(dir, "nested", "file.md")
+	require.NoError(t, store.Write(path, []byte("hello")))
+
+	content, err := store.Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	info, err := store.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), info.Size)
+	assert.False(t, info.IsDir)
+
+	require.NoError(t, store.Remove(path))
+	_, err = store.Read(path)
+	assert.True(t, IsNotExist(err))
+}
+
+func TestLocalList(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocal()
+
+	require.NoError(t, store.Write(filepath.Join(dir, "b.md"), []byte("b")))
+	require.NoError(t, store.Write(filepath.Join(dir, "a.md"), []byte("a")))
+	require.NoError(t, store.Write(filepath.Join(dir, "sub", "c.md"), []byte("c")))
+
+	files, err := store.List(dir, false)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, filepath.Join(dir, "a.md"), files[0].Path)
+	assert.Equal(t, filepath.Join(dir, "b.md"), files[1].Path)
+
+	files, err = store.List(dir, true)
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	_, err = store.List(filepath.Join(dir, "missing"), true)
+	assert.True(t, IsNotExist(err))
+}
+
+func TestWriteMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bin", "run.sh")
+
+	require.NoError(t, WriteMode(NewLocal(), path, []byte("#!/bin/sh\n"), 0755))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+}
+
+func TestLocalWatch(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocal()
+	watcher, err := store.Watch()
+	require.NoError(t, err)
+	defer watcher.Close()
+	require.NoError(t, watcher.Add(dir))
+
+	path := filepath.Join(dir, "rule.md")
+	require.NoError(t, store.Write(path, []byte("# Rule")))
+	select {
+	case event := <-watcher.Events():
+		assert.Equal(t, path, event.Path)
+		assert.True(t, event.Op.Has(Create) || event.Op.Has(Write), event.Op.String())
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event for the write")
+	}
+
+	require.NoError(t, watcher.Close())
+	require.NoError(t, watcher.Close())
+	_, open := <-watcher.Events()
+	assert.False(t, open)
+}
+
+func TestMem(t *testing.T) {
+	store := NewMem()
+	root := filepath.Join("buddy", "rules")
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"top level", filepath.Join(root, "a.md")},
+		{"nested", filepath.Join(root, "sub", "b.md")},
+		{"uncleaned", root + "/./c.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, store.Write(tt.path, []byte(tt.name)))
+			content, err := store.Read(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.name, string(content))
+
+			info, err := store.Stat(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.name)), info.Size)
+			assert.False(t, info.IsDir)
+		})
+	}
+
+	files, err := store.List(root, false)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, filepath.Join(root, "a.md"), files[0].Path)
+	assert.Equal(t, filepath.Join(root, "c.md"), files[1].Path)
+	files, err = store.List(root, true)
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	info, err := store.Stat(filepath.Join(root, "sub"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir)
+
+	// Missing paths report not-exist errors, as on disk
+	_, err = store.Read(filepath.Join(root, "missing.md"))
+	assert.True(t, IsNotExist(err))
+	_, err = store.List(filepath.Join(root, "missing"), true)
+	assert.True(t, IsNotExist(err))
+
+	require.NoError(t, store.Remove(filepath.Join(root, "sub")))
+	_, err = store.Stat(filepath.Join(root, "sub", "b.md"))
+	assert.True(t, IsNotExist(err))
+	require.NoError(t, store.Remove(filepath.Join(root, "sub")))
+}
+
+func TestMemWatch(t *testing.T) {
+	store := NewMem()
+	dir := filepath.Join("buddy", "todos")
+	watcher, err := store.Watch()
+	require.NoError(t, err)
+	require.NoError(t, watcher.Add(dir))
+
+	path := filepath.Join(dir, "auth.md")
+	require.NoError(t, store.Write(path, []byte("- [ ] a")))
+	require.NoError(t, store.Write(path, []byte("- [x] a")))
+	require.NoError(t, store.Write(filepath.Join(dir, "sub", "other.md"), nil))
+	require.NoError(t, store.Remove(path))
+
+	// Files in subdirectories aren't watched
+	for _, want := range []Event{{path, Create}, {path, Write}, {path, Remove}} {
+		assert.Equal(t, want, <-watcher.Events())
+	}
+
+	require.NoError(t, watcher.Close())
+	require.NoError(t, watcher.Close())
+	_, open := <-watcher.Events()
+	assert.False(t, open)
+	require.NoError(t, store.Write(path, nil), "writes go on after watchers close")
+}
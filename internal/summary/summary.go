@@ -0,0 +1,221 @@
+// Package summary builds extractive summaries: it picks the sentences of
+// a text that best cover its frequent words, without any language model.
+// It is the fallback when the client can't generate summaries.
+package summary
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultSentences is how many sentences Extract keeps when no count is given
+const DefaultSentences = 3
+
+// stopWords are common English words that say nothing about a text's topic
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "if": true, "in": true, "into": true,
+	"is": true, "it": true, "its": true, "not": true, "of": true, "on": true, "or": true, "so": true,
+	"that": true, "the": true, "then": true, "there": true, "these": true, "this": true, "to": true,
+	"was": true, "we": true, "were": true, "when": true, "which": true, "will": true, "with": true,
+	"you": true, "your": true, "can": true, "all": true, "any": true, "do": true, "does": true,
+	"should": true, "must": true, "than": true, "they": true, "them": true, "our": true, "use": true,
+}
+
+// Extract returns up to maxSentences sentences of text (DefaultSentences
+// if zero or negative), chosen by how many of the text's frequent words
+// they contain and joined in their original order. Markdown headings,
+// list markers and code blocks are ignored.
+func Extract(text string, maxSentences int) string {
+	if maxSentences <= 0 {
+		maxSentences = DefaultSentences
+	}
+
+	sentences := Sentences(text)
+	if len(sentences) <= maxSentences {
+		return strings.Join(sentences, " ")
+	}
+
+	frequency := make(map[string]int)
+	for _, sentence := range sentences {
+		for _, word := range words(sentence) {
+			frequency[word]++
+		}
+	}
+
+	type scored struct {
+		index int
+		score float64
+	}
+	ranked := make([]scored, len(sentences))
+	for i, sentence := range sentences {
+		sentenceWords := words(sentence)
+		total := 0
+		for _, word := range sentenceWords {
+			total += frequency[word]
+		}
+		score := 0.0
+		if len(sentenceWords) > 0 {
+			// Normalize so long sentences don't win on length alone,
+			// with a slight preference for the opening sentence
+			score = float64(total) / float64(len(sentenceWords)+3)
+		}
+		if i == 0 {
+			score *= 1.2
+		}
+		ranked[i] = scored{index: i, score: score}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+	chosen := ranked[:maxSentences]
+	sort.Slice(chosen, func(i, j int) bool {
+		return chosen[i].index < chosen[j].index
+	})
+
+	picked := make([]string, len(chosen))
+	for i, s := range chosen {
+		picked[i] = sentences[s.index]
+	}
+	return strings.Join(picked, " ")
+}
+
+// Sentences splits markdown or plain text into sentences. Headings, code
+// blocks and blank lines are dropped, and list items count as sentences
+// of their own.
+func Sentences(text string) []string {
+	var sentences []string
+	var paragraph []string
+	inCode := false
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			sentences = append(sentences, splitSentences(strings.Join(paragraph, " "))...)
+			paragraph = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			flush()
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, "#"):
+			flush()
+		case markerLength(trimmed) > 0:
+			flush()
+			if item := listItem(trimmed); item != "" {
+				sentences = append(sentences, terminate(item))
+			}
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return sentences
+}
+
+// markerLength returns the length of a list item's "- " or "1. " marker,
+// or 0 if the line isn't a list item
+func markerLength(line string) int {
+	if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "+ ") {
+		return 2
+	}
+	i := 0
+	for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+		i++
+	}
+	if i > 0 && i+1 < len(line) && (line[i] == '.' || line[i] == ')') && line[i+1] == ' ' {
+		return i + 2
+	}
+	return 0
+}
+
+// listItem returns a list item's text without its marker or checkbox
+func listItem(line string) string {
+	item := strings.TrimSpace(line[markerLength(line):])
+	for _, box := range []string{"[ ]", "[x]", "[X]"} {
+		item = strings.TrimPrefix(item, box)
+	}
+	return strings.TrimSpace(item)
+}
+
+// splitSentences splits a paragraph at '.', '!' or '?' followed by a space
+// and an upper-case letter or digit, so "e.g. foo" and "v1.2" stay whole
+func splitSentences(paragraph string) []string {
+	var sentences []string
+	runes := []rune(paragraph)
+	start := 0
+	for i := 0; i < len(runes)-2; i++ {
+		if (runes[i] == '.' || runes[i] == '!' || runes[i] == '?') && runes[i+1] == ' ' &&
+			(unicode.IsUpper(runes[i+2]) || unicode.IsDigit(runes[i+2])) {
+			sentences = append(sentences, strings.TrimSpace(string(runes[start:i+1])))
+			start = i + 2
+		}
+	}
+	if rest := strings.TrimSpace(string(runes[start:])); rest != "" {
+		sentences = append(sentences, terminate(rest))
+	}
+	return sentences
+}
+
+// terminate ends a sentence with a period unless it already has punctuation
+func terminate(sentence string) string {
+	if strings.HasSuffix(sentence, ".") || strings.HasSuffix(sentence, "!") ||
+		strings.HasSuffix(sentence, "?") || strings.HasSuffix(sentence, ":") {
+		return sentence
+	}
+	return sentence + "."
+}
+
+// words returns a sentence's lowercase content words
+func words(sentence string) []string {
+	var result []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) > 2 && !stopWords[word] {
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+// Relevance returns how many distinct content words of query text contains
+func Relevance(text, query string) int {
+	present := make(map[string]bool)
+	for _, word := range words(text) {
+		present[word] = true
+	}
+	score := 0
+	seen := make(map[string]bool)
+	for _, word := range words(query) {
+		if present[word] && !seen[word] {
+			seen[word] = true
+			score++
+		}
+	}
+	return score
+}
+
+// BestSentence returns the sentence of text most relevant to query, the
+// earliest on a tie, or "" when no sentence shares a content word with it
+func BestSentence(text, query string) string {
+	best, bestScore := "", 0
+	for _, sentence := range Sentences(text) {
+		if score := Relevance(sentence, query); score > bestScore {
+			best, bestScore = sentence, score
+		}
+	}
+	return best
+}
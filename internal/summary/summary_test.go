@@ -0,0 +1,68 @@
+package summary
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentences(t *testing.T) {
+	text := `# Error Handling
+
+Wrap errors with context, e.g. fmt.Errorf. Never drop them! Version 1.2 changed this.
+
+- Check every error
+- [x] 3 retries at most
+1. Log once at the top
+
+` + "```go\nif err != nil { return err }\n```\n"
+
+	assert.Equal(t, []string{
+		"Wrap errors with context, e.g. fmt.Errorf.",
+		"Never drop them!",
+		"Version 1.2 changed this.",
+		"Check every error.",
+		"3 retries at most.",
+		"Log once at the top.",
+	}, Sentences(text))
+}
+
+func TestExtract_ShortTextUnchanged(t *testing.T) {
+	assert.Equal(t, "Use gofmt. Keep imports grouped.", Extract("Use gofmt.\nKeep imports grouped.", 3))
+	assert.Equal(t, "", Extract("# Only a heading", 3))
+}
+
+func TestExtract_PicksCentralSentences(t *testing.T) {
+	text := `The payment service retries failed payment requests with backoff.
+The weather was nice during the design meeting.
+Payment retries stop after five attempts so the payment queue drains.
+Lunch was pizza.
+Failed payment requests are logged with the request ID.`
+
+	result := Extract(text, 2)
+
+	assert.Contains(t, result, "retries failed payment requests")
+	assert.Contains(t, result, "Payment retries stop")
+	assert.NotContains(t, result, "weather")
+	assert.NotContains(t, result, "pizza")
+
+	// Sentences keep their original order
+	assert.Less(t, strings.Index(result, "The payment service"), strings.Index(result, "Payment retries stop"))
+}
+
+func TestExtract_DefaultCount(t *testing.T) {
+	text := "One sentence here. Two sentence here. Three sentence here. Four sentence here. Five sentence here."
+	assert.Len(t, Sentences(Extract(text, 0)), DefaultSentences)
+}
+
+func TestBestSentence(t *testing.T) {
+	text := `Deploys run from the main branch.
+Rollbacks use the previous image tag and take about a minute.
+Ask in the ops channel before a rollback on Fridays.`
+
+	assert.Equal(t, 2, Relevance(text, "how do rollbacks work with image tags"))
+	assert.Equal(t, "Rollbacks use the previous image tag and take about a minute.", BestSentence(text, "rollbacks image"))
+	assert.Equal(t, "Deploys run from the main branch.", BestSentence(text, "which branch do deploys use"))
+	assert.Equal(t, "", BestSentence(text, "database migrations"))
+}
@@ -0,0 +1,283 @@
+// Package testresults parses CI test reports and attaches their outcomes
+// to history entries, so later sessions can see which past changes broke
+// tests.
+package testresults
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+)
+
+// Test run statuses
+const (
+	StatusPassed = "passed"
+	StatusFailed = "failed"
+)
+
+// Parse reads a JUnit XML report or `go test -json` output into a test run
+func Parse(content []byte) (models.TestRun, error) {
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case len(trimmed) == 0:
+		return models.TestRun{}, fmt.Errorf("empty test report")
+	case trimmed[0] == '<':
+		return parseJUnit(trimmed)
+	case trimmed[0] == '{':
+		return parseGoTestJSON(trimmed)
+	}
+	return models.TestRun{}, fmt.Errorf("unrecognized test report: expected JUnit XML or go test -json output")
+}
+
+// NewRun builds a test run from its failing tests; a run with failures is
+// failed, otherwise passed
+func NewRun(total int, failing []string) models.TestRun {
+	run := models.TestRun{
+		Status:       StatusPassed,
+		Total:        total,
+		Failed:       len(failing),
+		FailingTests: failing,
+		RecordedAt:   time.Now().UTC(),
+	}
+	if len(failing) > 0 {
+		run.Status = StatusFailed
+	}
+	return run
+}
+
+// junitSuite covers both <testsuites> and <testsuite> roots, which may nest
+type junitSuite struct {
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []junitCase  `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string    `xml:"name,attr"`
+	Classname string    `xml:"classname,attr"`
+	Failure   *struct{} `xml:"failure"`
+	Error     *struct{} `xml:"error"`
+	Skipped   *struct{} `xml:"skipped"`
+}
+
+// parseJUnit reads a JUnit XML report
+func parseJUnit(content []byte) (models.TestRun, error) {
+	var root junitSuite
+	if err := xml.Unmarshal(content, &root); err != nil {
+		return models.TestRun{}, fmt.Errorf("invalid JUnit report: %w", err)
+	}
+
+	total := 0
+	var failing []string
+	var walk func(suite junitSuite)
+	walk = func(suite junitSuite) {
+		for _, tc := range suite.Cases {
+			if tc.Skipped != nil {
+				continue
+			}
+			total++
+			if tc.Failure != nil || tc.Error != nil {
+				name := tc.Name
+				if tc.Classname != "" {
+					name = tc.Classname + "." + tc.Name
+				}
+				failing = append(failing, name)
+			}
+		}
+		for _, child := range suite.Suites {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return NewRun(total, failing), nil
+}
+
+// goTestEvent is one line of `go test -json` output
+type goTestEvent struct {
+	Action  string `json:"Action"`
+	Package string `json:"Package"`
+	Test    string `json:"Test"`
+}
+
+// parseGoTestJSON reads `go test -json` output. A parent test that failed
+// only because of a failing subtest is reported through the subtest.
+// Packages that failed without a failing test, e.g. because they didn't
+// build, are reported by package name.
+func parseGoTestJSON(content []byte) (models.TestRun, error) {
+	results := make(map[string]string) // package.test -> final action
+	failedPackages := make(map[string]bool)
+	packagesWithFailures := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+
+		var event goTestEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return models.TestRun{}, fmt.Errorf("invalid go test -json output: %w", err)
+		}
+		if event.Action != "pass" && event.Action != "fail" && event.Action != "skip" {
+			continue
+		}
+
+		if event.Test == "" {
+			if event.Action == "fail" {
+				failedPackages[event.Package] = true
+			}
+			continue
+		}
+
+		results[event.Package+"."+event.Test] = event.Action
+		if event.Action == "fail" {
+			packagesWithFailures[event.Package] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return models.TestRun{}, err
+	}
+
+	total := 0
+	var failed []string
+	for name, action := range results {
+		if action == "skip" {
+			continue
+		}
+		total++
+		if action == "fail" {
+			failed = append(failed, name)
+		}
+	}
+
+	var failing []string
+	for _, name := range failed {
+		hasFailingSubtest := false
+		for _, other := range failed {
+			if strings.HasPrefix(other, name+"/") {
+				hasFailingSubtest = true
+				break
+			}
+		}
+		if !hasFailingSubtest {
+			failing = append(failing, name)
+		}
+	}
+
+	for pkg := range failedPackages {
+		if !packagesWithFailures[pkg] {
+			failing = append(failing, pkg)
+		}
+	}
+
+	sort.Strings(failing)
+	return NewRun(total, failing), nil
+}
+
+// Target selects the history entry a test run belongs to: the entry with
+// EntryID, else the newest entry for Feature, else the newest entry
+type Target struct {
+	EntryID string
+	Feature string
+}
+
+// Attach appends a test run to the selected history entry in historyDir
+// and rewrites its file
+func Attach(store storage.Storage, historyDir string, target Target, run models.TestRun) (models.HistoryEntry, error) {
+	files, err := store.List(historyDir, false)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return models.HistoryEntry{}, fmt.Errorf("no history entries found")
+		}
+		return models.HistoryEntry{}, err
+	}
+
+	var (
+		selected     models.HistoryEntry
+		selectedPath string
+	)
+	for _, file := range files {
+		if !strings.EqualFold(filepath.Ext(file.Path), ".json") {
+			continue
+		}
+
+		content, err := store.Read(file.Path)
+		if err != nil {
+			return models.HistoryEntry{}, err
+		}
+		var entry models.HistoryEntry
+		if err := json.Unmarshal(content, &entry); err != nil {
+			continue
+		}
+
+		switch {
+		case target.EntryID != "":
+			if entry.ID != target.EntryID {
+				continue
+			}
+		case target.Feature != "":
+			if !strings.EqualFold(entry.Feature, target.Feature) {
+				continue
+			}
+		}
+
+		if selectedPath == "" || entry.Timestamp.After(selected.Timestamp) {
+			selected = entry
+			selectedPath = file.Path
+		}
+	}
+
+	if selectedPath == "" {
+		switch {
+		case target.EntryID != "":
+			return models.HistoryEntry{}, fmt.Errorf("history entry not found: %s", target.EntryID)
+		case target.Feature != "":
+			return models.HistoryEntry{}, fmt.Errorf("no history entries for feature: %s", target.Feature)
+		}
+		return models.HistoryEntry{}, fmt.Errorf("no history entries found")
+	}
+
+	if run.RecordedAt.IsZero() {
+		run.RecordedAt = time.Now().UTC()
+	}
+	selected.TestRuns = append(selected.TestRuns, run)
+
+	data, err := json.MarshalIndent(selected, "", "  ")
+	if err != nil {
+		return models.HistoryEntry{}, err
+	}
+	if err := store.Write(selectedPath, data); err != nil {
+		return models.HistoryEntry{}, err
+	}
+
+	return selected, nil
+}
+
+// Latest returns the most recent test run of an entry, if any
+func Latest(entry models.HistoryEntry) (models.TestRun, bool) {
+	if len(entry.TestRuns) == 0 {
+		return models.TestRun{}, false
+	}
+	return entry.TestRuns[len(entry.TestRuns)-1], true
+}
+
+// MatchesStatus reports whether an entry's latest test run has the given
+// status: passed, failed, or untested for entries without runs
+func MatchesStatus(entry models.HistoryEntry, status string) bool {
+	run, ok := Latest(entry)
+	if status == "untested" {
+		return !ok
+	}
+	return ok && run.Status == status
+}
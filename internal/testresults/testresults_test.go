@@ -0,0 +1,125 @@
+package testresults
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/omar-haris/cursor-buddy-mcp/internal/models"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_JUnit(t *testing.T) {
+	report := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="auth" tests="3" failures="1">
+    <testcase classname="auth.LoginTest" name="testValidPassword"/>
+    <testcase classname="auth.LoginTest" name="testExpiredToken">
+      <failure message="expected 401">stack</failure>
+    </testcase>
+    <testcase classname="auth.LoginTest" name="testSSO"><skipped/></testcase>
+  </testsuite>
+  <testsuite name="billing">
+    <testcase name="charges card"><error message="boom"/></testcase>
+  </testsuite>
+</testsuites>`
+
+	run, err := Parse([]byte(report))
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, run.Status)
+	assert.Equal(t, 3, run.Total)
+	assert.Equal(t, 2, run.Failed)
+	assert.Equal(t, []string{"auth.LoginTest.testExpiredToken", "charges card"}, run.FailingTests)
+}
+
+func TestParse_JUnitSingleSuite(t *testing.T) {
+	run, err := Parse([]byte(`<testsuite><testcase name="a"/><testcase name="b"/></testsuite>`))
+	require.NoError(t, err)
+	assert.Equal(t, StatusPassed, run.Status)
+	assert.Equal(t, 2, run.Total)
+	assert.Empty(t, run.FailingTests)
+}
+
+func TestParse_GoTestJSON(t *testing.T) {
+	output := `{"Action":"run","Package":"example.com/auth","Test":"TestLogin"}
+{"Action":"run","Package":"example.com/auth","Test":"TestLogin/expired"}
+{"Action":"output","Package":"example.com/auth","Test":"TestLogin/expired","Output":"--- FAIL\n"}
+{"Action":"fail","Package":"example.com/auth","Test":"TestLogin/expired"}
+{"Action":"fail","Package":"example.com/auth","Test":"TestLogin"}
+{"Action":"pass","Package":"example.com/auth","Test":"TestLogout"}
+{"Action":"skip","Package":"example.com/auth","Test":"TestSSO"}
+{"Action":"fail","Package":"example.com/auth"}
+{"Action":"fail","Package":"example.com/broken"}
+{"Action":"pass","Package":"example.com/util","Test":"TestSlug"}
+{"Action":"pass","Package":"example.com/util"}
+`
+
+	run, err := Parse([]byte(output))
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, run.Status)
+	assert.Equal(t, 4, run.Total)
+	assert.Equal(t, []string{"example.com/auth.TestLogin/expired", "example.com/broken"}, run.FailingTests)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte(""))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte("PASS\nok  example.com/util"))
+	assert.Error(t, err)
+}
+
+func writeEntry(t *testing.T, dir string, entry models.HistoryEntry) {
+	data, err := json.Marshal(entry)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, entry.ID+".json"), data, 0644))
+}
+
+func TestAttach(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	writeEntry(t, dir, models.HistoryEntry{ID: "old-auth", Feature: "auth", Timestamp: now.Add(-2 * time.Hour)})
+	writeEntry(t, dir, models.HistoryEntry{ID: "new-auth", Feature: "auth", Timestamp: now.Add(-time.Hour)})
+	writeEntry(t, dir, models.HistoryEntry{ID: "billing", Feature: "billing", Timestamp: now})
+
+	store := storage.NewLocal()
+	failed := NewRun(10, []string{"TestLogin"})
+
+	// Newest entry for a feature
+	entry, err := Attach(store, dir, Target{Feature: "AUTH"}, failed)
+	require.NoError(t, err)
+	assert.Equal(t, "new-auth", entry.ID)
+
+	// Newest entry overall
+	entry, err = Attach(store, dir, Target{}, NewRun(3, nil))
+	require.NoError(t, err)
+	assert.Equal(t, "billing", entry.ID)
+
+	// Explicit entry, appended after earlier runs
+	entry, err = Attach(store, dir, Target{EntryID: "new-auth"}, NewRun(10, nil))
+	require.NoError(t, err)
+	require.Len(t, entry.TestRuns, 2)
+
+	content, err := os.ReadFile(filepath.Join(dir, "new-auth.json"))
+	require.NoError(t, err)
+	var saved models.HistoryEntry
+	require.NoError(t, json.Unmarshal(content, &saved))
+	require.Len(t, saved.TestRuns, 2)
+	assert.Equal(t, StatusFailed, saved.TestRuns[0].Status)
+	assert.True(t, MatchesStatus(saved, StatusPassed))
+	assert.False(t, MatchesStatus(saved, StatusFailed))
+
+	_, err = Attach(store, dir, Target{EntryID: "missing"}, failed)
+	assert.Error(t, err)
+	_, err = Attach(store, dir, Target{Feature: "search"}, failed)
+	assert.Error(t, err)
+}
+
+func TestMatchesStatus_Untested(t *testing.T) {
+	assert.True(t, MatchesStatus(models.HistoryEntry{}, "untested"))
+	assert.False(t, MatchesStatus(models.HistoryEntry{}, StatusFailed))
+}
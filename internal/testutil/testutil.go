@@ -0,0 +1,237 @@
+// Package testutil runs the buddy MCP server in-process with a real MCP
+// client connected, so tests can call tools and read resources the way an
+// editor would. It also serves as a template for checking that a project's
+// .buddy content answers the questions it should:
+//
+//	client := testutil.Start(t, ".buddy")
+//	text := client.CallText(t, "buddy_search_knowledge", map[string]any{"query": "auth"})
+package testutil
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/omar-haris/cursor-buddy-mcp/internal/buddyserver"
+)
+
+// Transports the client can reach the server over
+const (
+	// TransportInMemory passes messages to the server directly
+	TransportInMemory = "memory"
+	// TransportStdio runs the server's stdio transport over pipes, so
+	// messages are encoded and sessions are set up as for an editor
+	TransportStdio = "stdio"
+)
+
+// callTimeout bounds each request the client sends
+const callTimeout = 30 * time.Second
+
+// Option configures a test server
+type Option func(*options)
+
+type options struct {
+	transport string
+	projects  []buddyserver.Project
+	monitor   bool
+	server    buddyserver.Options
+}
+
+// WithTransport selects TransportInMemory (the default) or TransportStdio
+func WithTransport(name string) Option {
+	return func(o *options) { o.transport = name }
+}
+
+// WithProject serves another buddy directory beside the main one
+func WithProject(name, path string) Option {
+	return func(o *options) {
+		o.projects = append(o.projects, buddyserver.Project{Name: name, Path: path})
+	}
+}
+
+// WithFileMonitor reloads the server when buddy files change on disk, as
+// it does in production. Without it, tests see the files present at start
+// and the changes tools make.
+func WithFileMonitor() Option {
+	return func(o *options) { o.monitor = true }
+}
+
+// WithServerOptions sets how the server describes and protects itself
+func WithServerOptions(serverOptions buddyserver.Options) Option {
+	return func(o *options) { o.server = serverOptions }
+}
+
+// Client is an MCP client connected to a buddy server running in the test
+type Client struct {
+	*client.Client
+	// Server is the server the client talks to
+	Server *buddyserver.Server
+	// BuddyPath is the main buddy directory being served
+	BuddyPath string
+}
+
+// Start starts a buddy server for buddyPath and connects an initialized
+// client to it. Both are shut down when the test ends.
+func Start(t testing.TB, buddyPath string, opts ...Option) *Client {
+	t.Helper()
+	o := options{transport: TransportInMemory}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.server.Transport == "" {
+		o.server.Transport = o.transport
+	}
+
+	roots := append([]buddyserver.Project{{Name: "default", Path: buddyPath}}, o.projects...)
+	buddyServer, err := buddyserver.New(roots, o.server)
+	if err != nil {
+		t.Fatalf("failed to start buddy server: %v", err)
+	}
+	stopMonitor := func() {}
+	if o.monitor {
+		stopMonitor = buddyServer.StartMonitor()
+	}
+	// Cleanups run last in, first out: the client goes before the server
+	t.Cleanup(func() {
+		stopMonitor()
+		buddyServer.Close()
+	})
+
+	var mcpClient *client.Client
+	switch o.transport {
+	case TransportInMemory:
+		mcpClient, err = client.NewInProcessClient(buddyServer.MCP)
+		if err != nil {
+			t.Fatalf("failed to create in-process client: %v", err)
+		}
+	case TransportStdio:
+		mcpClient = stdioClient(t, buddyServer.MCP)
+	default:
+		t.Fatalf("unknown test transport %q (expected %s or %s)", o.transport, TransportInMemory, TransportStdio)
+	}
+	t.Cleanup(func() { mcpClient.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	if err := mcpClient.Start(ctx); err != nil {
+		t.Fatalf("failed to start client: %v", err)
+	}
+	initialize := mcp.InitializeRequest{}
+	initialize.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initialize.Params.ClientInfo = mcp.Implementation{Name: "buddy-testutil", Version: buddyserver.Version}
+	if _, err := mcpClient.Initialize(ctx, initialize); err != nil {
+		t.Fatalf("failed to initialize client: %v", err)
+	}
+
+	return &Client{Client: mcpClient, Server: buddyServer, BuddyPath: buddyPath}
+}
+
+// stdioClient serves mcpServer's stdio transport over pipes until the test
+// ends and returns a client speaking to it
+func stdioClient(t testing.TB, mcpServer *server.MCPServer) *client.Client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.NewStdioServer(mcpServer).Listen(ctx, serverIn, serverOut)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		clientOut.Close()
+		serverOut.Close()
+		<-done
+	})
+
+	return client.NewClient(transport.NewIO(clientIn, clientOut, io.NopCloser(strings.NewReader(""))))
+}
+
+// Call calls a tool. Handlers that fail return an error, which reaches
+// the client as a JSON-RPC error; results with IsError set are returned
+// as they are.
+func (c *Client) Call(name string, args map[string]any) (*mcp.CallToolResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	return c.CallTool(ctx, request)
+}
+
+// CallText calls a tool and returns the text of its result, failing the
+// test if the call fails or the result is an error
+func (c *Client) CallText(t testing.TB, name string, args map[string]any) string {
+	t.Helper()
+	result, err := c.Call(name, args)
+	if err != nil {
+		t.Fatalf("%s failed: %v", name, err)
+	}
+	text := Text(result)
+	if result.IsError {
+		t.Fatalf("%s returned an error: %s", name, text)
+	}
+	return text
+}
+
+// ReadText reads a resource and returns its text contents joined
+func (c *Client) ReadText(t testing.TB, uri string) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	result, err := c.ReadResource(ctx, request)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", uri, err)
+	}
+	var parts []string
+	for _, content := range result.Contents {
+		if text, ok := content.(mcp.TextResourceContents); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Text joins the text contents of a tool result
+func Text(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// BuddyDir creates a buddy directory in a temporary folder holding files,
+// keyed by their path relative to it, and returns its path
+func BuddyDir(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), ".buddy")
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
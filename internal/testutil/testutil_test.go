@@ -0,0 +1,81 @@
+package testutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBuddy(t *testing.T) string {
+	return BuddyDir(t, map[string]string{
+		"rules/style.md":         "# Style\nCategory: style\nPriority: critical\n\n- Use gofmt\n",
+		"knowledge/auth.md":      "# Authentication\nCategory: security\n\nSessions are stored in Redis and expire after 24 hours.\n",
+		"todos/login.md":         "# Login\n\n- [ ] Add login form\n- [x] Hash passwords\n",
+		"database/schema.sql":    "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL);\n",
+		"knowledge/unrelated.md": "# Deployment\nCategory: ops\n\nDeploys run from CI on every merge.\n",
+	})
+}
+
+func TestStart_Transports(t *testing.T) {
+	for _, transport := range []string{TransportInMemory, TransportStdio} {
+		t.Run(transport, func(t *testing.T) {
+			client := Start(t, testBuddy(t), WithTransport(transport))
+
+			tools, err := client.ListTools(context.Background(), mcp.ListToolsRequest{})
+			require.NoError(t, err)
+			var names []string
+			for _, tool := range tools.Tools {
+				names = append(names, tool.Name)
+			}
+			assert.Contains(t, names, "buddy_get_rules")
+			assert.Contains(t, names, "buddy_manage_todos")
+
+			assert.Contains(t, client.CallText(t, "buddy_get_rules", map[string]any{}), "Use gofmt")
+			assert.Contains(t, client.CallText(t, "buddy_search_knowledge", map[string]any{"query": "redis sessions"}), "Authentication")
+			assert.Contains(t, client.ReadText(t, "buddy://rules"), "Use gofmt")
+		})
+	}
+}
+
+func TestStart_MutatingTools(t *testing.T) {
+	buddyPath := testBuddy(t)
+	client := Start(t, buddyPath, WithTransport(TransportStdio))
+
+	list := client.CallText(t, "buddy_manage_todos", map[string]any{"action": "list", "only_incomplete": true})
+	assert.Contains(t, list, "Add login form")
+	assert.NotContains(t, list, "Hash passwords")
+
+	client.CallText(t, "buddy_history", map[string]any{
+		"action":      "add",
+		"feature":     "login",
+		"description": "Added the login form",
+		"reasoning":   "Users need to sign in",
+		"changes":     []any{map[string]any{"file_path": "login.go", "change_type": "added"}},
+	})
+	entries, err := filepath.Glob(filepath.Join(buddyPath, "history", "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Contains(t, client.CallText(t, "buddy_history", map[string]any{"action": "list"}), "Added the login form")
+}
+
+func TestCall_Error(t *testing.T) {
+	client := Start(t, testBuddy(t))
+
+	_, err := client.Call("buddy_generate_fixtures", map[string]any{"table_name": "missing"})
+	assert.ErrorContains(t, err, "missing")
+}
+
+func TestBuddyDir(t *testing.T) {
+	dir := BuddyDir(t, map[string]string{"rules/a.md": "# A\n"})
+	assert.Equal(t, ".buddy", filepath.Base(dir))
+	content, err := os.ReadFile(filepath.Join(dir, "rules", "a.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# A\n", string(content))
+
+	assert.DirExists(t, BuddyDir(t, nil))
+}
@@ -0,0 +1,193 @@
+package timeutil
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLayout is the display layout used when no locale is configured
+const DefaultLayout = "2006-01-02 15:04:05"
+
+// localeLayouts maps supported locales to their display layouts
+var localeLayouts = map[string]string{
+	"en-US": "Jan 2, 2006 3:04:05 PM",
+	"en-GB": "02/01/2006 15:04:05",
+	"de-DE": "02.01.2006 15:04:05",
+	"fr-FR": "02/01/2006 15:04:05",
+	"ja-JP": "2006/01/02 15:04:05",
+	"iso":   time.RFC3339,
+}
+
+// Formatter renders stored UTC timestamps in a configured zone and layout
+type Formatter struct {
+	location *time.Location
+	layout   string
+}
+
+// NewFormatter creates a formatter for a time zone name (e.g. "Europe/Berlin")
+// and a locale or explicit Go layout. Empty values fall back to UTC and
+// DefaultLayout.
+func NewFormatter(timeZone, locale, layout string) (*Formatter, error) {
+	f := &Formatter{
+		location: time.UTC,
+		layout:   DefaultLayout,
+	}
+
+	if timeZone != "" {
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", timeZone, err)
+		}
+		f.location = loc
+	}
+
+	if locale != "" {
+		localeLayout, ok := localeLayouts[locale]
+		if !ok {
+			return nil, fmt.Errorf("unsupported locale %q", locale)
+		}
+		f.layout = localeLayout
+	}
+
+	if layout != "" {
+		f.layout = layout
+	}
+
+	return f, nil
+}
+
+// Format renders t in the formatter's zone and layout. A nil formatter
+// uses UTC and DefaultLayout.
+func (f *Formatter) Format(t time.Time) string {
+	if f == nil {
+		return t.UTC().Format(DefaultLayout)
+	}
+	return t.In(f.location).Format(f.layout)
+}
+
+// Location returns the display location
+func (f *Formatter) Location() *time.Location {
+	if f == nil {
+		return time.UTC
+	}
+	return f.location
+}
+
+// relativePattern matches phrases like "last 3 days" or "2 hours ago"
+var relativePattern = regexp.MustCompile(`^(?:last|past)?\s*(\d+)\s*(minute|hour|day|week|month|year)s?(?:\s+ago)?$`)
+
+// isoDurationPattern matches ISO8601 durations like "P3D" or "PT12H"
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// ParseTime parses a date argument relative to now. Accepted forms are
+// RFC3339 timestamps with explicit offsets, plain dates (YYYY-MM-DD, read in
+// loc), ISO8601 durations ("P3D", "PT12H") meaning that long ago, Go
+// durations ("72h"), the words "today" and "yesterday", and phrases like
+// "last 3 days" or "2 weeks ago".
+func ParseTime(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty time value")
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	lower := strings.ToLower(value)
+	localNow := now.In(loc)
+	startOfToday := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, loc)
+	switch lower {
+	case "today":
+		return startOfToday.UTC(), nil
+	case "yesterday":
+		return startOfToday.AddDate(0, 0, -1).UTC(), nil
+	}
+
+	if m := relativePattern.FindStringSubmatch(lower); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return subtractUnits(now, n, m[2]).UTC(), nil
+	}
+
+	if m := isoDurationPattern.FindStringSubmatch(strings.ToUpper(value)); m != nil {
+		t := now
+		matched := false
+		units := []string{"year", "month", "week", "day", "hour", "minute", "second"}
+		for i, unit := range units {
+			if m[i+1] != "" {
+				n, _ := strconv.Atoi(m[i+1])
+				t = subtractUnits(t, n, unit)
+				matched = true
+			}
+		}
+		if matched {
+			return t.UTC(), nil
+		}
+	}
+
+	if d, err := time.ParseDuration(strings.TrimPrefix(lower, "-")); err == nil {
+		return now.Add(-d).UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized time value %q", value)
+}
+
+// ParseUntil parses an upper time bound like ParseTime, except that a
+// value naming a whole day (a plain date, "today" or "yesterday") means
+// the last instant of that day rather than its first, so "until
+// 2024-06-01" includes everything on June 1st.
+func ParseUntil(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	t, err := ParseTime(value, now, loc)
+	if err != nil {
+		return t, err
+	}
+	if !namesDay(value) {
+		return t, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	start := t.In(loc)
+	return time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, loc).Add(-time.Nanosecond).UTC(), nil
+}
+
+// namesDay reports whether value names a whole calendar day
+func namesDay(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "today" || value == "yesterday" {
+		return true
+	}
+	_, err := time.Parse("2006-01-02", value)
+	return err == nil
+}
+
+// subtractUnits moves t back by n of the named calendar unit
+func subtractUnits(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "year":
+		return t.AddDate(-n, 0, 0)
+	case "month":
+		return t.AddDate(0, -n, 0)
+	case "week":
+		return t.AddDate(0, 0, -7*n)
+	case "day":
+		return t.AddDate(0, 0, -n)
+	case "hour":
+		return t.Add(-time.Duration(n) * time.Hour)
+	case "minute":
+		return t.Add(-time.Duration(n) * time.Minute)
+	default:
+		return t.Add(-time.Duration(n) * time.Second)
+	}
+}
@@ -0,0 +1,103 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-06-01T10:00:00+02:00", time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)},
+		{"2024-06-01", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"last 3 days", now.AddDate(0, 0, -3)},
+		{"2 weeks ago", now.AddDate(0, 0, -14)},
+		{"P1D", now.AddDate(0, 0, -1)},
+		{"PT12H", now.Add(-12 * time.Hour)},
+		{"72h", now.Add(-72 * time.Hour)},
+		{"today", time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"yesterday", time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTime(tt.value, now, time.UTC)
+		require.NoError(t, err, tt.value)
+		assert.True(t, tt.want.Equal(got), "%s: want %s, got %s", tt.value, tt.want, got)
+	}
+}
+
+func TestParseTime_PlainDateUsesLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	got, err := ParseTime("2024-06-01", time.Now(), loc)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 31, 22, 0, 0, 0, time.UTC), got)
+}
+
+func TestParseUntil(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	endOf := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)
+	}
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-06-01", endOf(2024, 6, 1)},
+		{"today", endOf(2024, 6, 15)},
+		{"Yesterday", endOf(2024, 6, 14)},
+		{"2024-06-01T10:00:00+02:00", time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)},
+		{"2024-06-01 10:00:00", time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)},
+		{"last 3 days", now.AddDate(0, 0, -3)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseUntil(tt.value, now, time.UTC)
+		require.NoError(t, err, tt.value)
+		assert.True(t, tt.want.Equal(got), "%s: want %s, got %s", tt.value, tt.want, got)
+	}
+
+	// A day ends at midnight in the given location, not in UTC
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	got, err := ParseUntil("2024-06-01", now, loc)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 1, 21, 59, 59, 999999999, time.UTC), got)
+
+	_, err = ParseUntil("sometime soon", now, time.UTC)
+	assert.Error(t, err)
+}
+
+func TestParseTime_Invalid(t *testing.T) {
+	for _, value := range []string{"", "P", "PT", "sometime soon"} {
+		_, err := ParseTime(value, time.Now(), time.UTC)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	ts := time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)
+
+	var nilFormatter *Formatter
+	assert.Equal(t, "2024-06-01 08:30:00", nilFormatter.Format(ts))
+
+	f, err := NewFormatter("", "de-DE", "")
+	require.NoError(t, err)
+	assert.Equal(t, "01.06.2024 08:30:00", f.Format(ts))
+
+	f, err = NewFormatter("", "", "15:04")
+	require.NoError(t, err)
+	assert.Equal(t, "08:30", f.Format(ts))
+
+	_, err = NewFormatter("Not/AZone", "", "")
+	assert.Error(t, err)
+
+	_, err = NewFormatter("", "xx-XX", "")
+	assert.Error(t, err)
+}
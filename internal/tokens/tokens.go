@@ -0,0 +1,196 @@
+// Package tokens measures text in model tokens so tool responses can be
+// fitted into a caller's context budget.
+//
+// Text is split into pieces the same way tiktoken's cl100k_base encoding
+// pre-tokenizes it (contractions, letter runs with one leading character,
+// digit groups of up to three, punctuation runs, whitespace). Each piece is
+// then costed from its length instead of running the full BPE merge table,
+// so counts are estimates that err slightly high: truncating to a budget
+// never overshoots it for typical English text and code.
+//
+// Exact, tiktoken-compatible counts would need cl100k_base's rank table
+// (about 100k merges, 1.7 MB) embedded in the binary. That was left out on
+// purpose to keep the server small and dependency-free; Count and Truncate
+// are the only entry points, so real merges can replace pieceTokens later
+// without touching callers.
+package tokens
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Count returns the estimated number of tokens in text
+func Count(text string) int {
+	total := 0
+	for _, piece := range split(text) {
+		total += pieceTokens(piece)
+	}
+	return total
+}
+
+// Truncate shortens text to at most maxTokens tokens. It prefers to cut at a
+// line boundary so partial lines aren't returned, and reports whether
+// anything was removed. A non-positive maxTokens returns text unchanged.
+func Truncate(text string, maxTokens int) (string, bool) {
+	if maxTokens <= 0 {
+		return text, false
+	}
+
+	used := 0
+	offset := 0
+	lastLineEnd := -1
+	for _, piece := range split(text) {
+		cost := pieceTokens(piece)
+		if used+cost > maxTokens {
+			cut := offset
+			if lastLineEnd > 0 {
+				cut = lastLineEnd
+			}
+			return text[:cut], true
+		}
+		used += cost
+		offset += len(piece)
+		if strings.HasSuffix(piece, "\n") {
+			lastLineEnd = offset
+		}
+	}
+
+	return text, false
+}
+
+// split breaks text into cl100k-style pre-tokenization pieces
+func split(text string) []string {
+	var pieces []string
+	for i := 0; i < len(text); {
+		n := nextPiece(text[i:])
+		pieces = append(pieces, text[i:i+n])
+		i += n
+	}
+	return pieces
+}
+
+// nextPiece returns the byte length of the piece at the start of s
+func nextPiece(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+
+	// Contractions: 's 't 're 've 'm 'll 'd
+	if r == '\'' {
+		lower := strings.ToLower(s)
+		for _, suffix := range []string{"'ll", "'re", "'ve", "'s", "'t", "'m", "'d"} {
+			if strings.HasPrefix(lower, suffix) {
+				return len(suffix)
+			}
+		}
+	}
+
+	// Letters, optionally led by one non-letter, non-digit, non-newline character
+	if isLetter(r) {
+		return size + runLength(s[size:], isLetter)
+	}
+	if !unicode.IsNumber(r) && r != '\r' && r != '\n' && len(s) > size {
+		if next, _ := utf8.DecodeRuneInString(s[size:]); isLetter(next) {
+			return size + runLength(s[size:], isLetter)
+		}
+	}
+
+	// Digits in groups of up to three
+	if unicode.IsNumber(r) {
+		n := size
+		for count := 1; count < 3 && n < len(s); count++ {
+			next, nextSize := utf8.DecodeRuneInString(s[n:])
+			if !unicode.IsNumber(next) {
+				break
+			}
+			n += nextSize
+		}
+		return n
+	}
+
+	// Punctuation, optionally led by a space and followed by newlines
+	if r == ' ' || isPunct(r) {
+		start := 0
+		if r == ' ' {
+			start = size
+		}
+		if n := runLength(s[start:], isPunct); n > 0 {
+			n += start
+			return n + runLength(s[n:], isNewline)
+		}
+	}
+
+	// Whitespace up to and including a newline run
+	if unicode.IsSpace(r) {
+		n := runLength(s, unicode.IsSpace)
+		if idx := strings.LastIndexAny(s[:n], "\r\n"); idx >= 0 {
+			return idx + 1
+		}
+		// Leave the last space to lead the following word
+		if n < len(s) && n > 1 {
+			last, lastSize := utf8.DecodeLastRuneInString(s[:n])
+			if last == ' ' {
+				return n - lastSize
+			}
+		}
+		return n
+	}
+
+	return size
+}
+
+// pieceTokens estimates how many tokens a single piece encodes to
+func pieceTokens(piece string) int {
+	ascii := 0
+	other := 0
+	for _, r := range piece {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+
+	r, _ := utf8.DecodeRuneInString(piece)
+	switch {
+	case strings.TrimSpace(piece) == "":
+		// Whitespace runs merge into a single token
+		return 1
+	case isPunct(r) || (r == ' ' && len(piece) > 1 && isPunct(rune(piece[1]))):
+		// Common operators such as "//", "==" or "..." merge in pairs
+		return (ascii+1)/2 + other
+	default:
+		// Common words are one token; long words split roughly every
+		// six characters. Non-ASCII characters rarely merge.
+		tokens := (ascii+5)/6 + other
+		if tokens == 0 {
+			tokens = 1
+		}
+		return tokens
+	}
+}
+
+// runLength returns the byte length of the leading run of runes matching keep
+func runLength(s string, keep func(rune) bool) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !keep(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func isLetter(r rune) bool {
+	return unicode.IsLetter(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
+
+func isPunct(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
@@ -0,0 +1,87 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"hello world", []string{"hello", " world"}},
+		{"it's fine", []string{"it", "'s", " fine"}},
+		{"12345", []string{"123", "45"}},
+		{"a  b", []string{"a", " ", " b"}},
+		{"x := y\n\nz", []string{"x", " :=", " y", "\n\n", "z"}},
+		{"(foo)", []string{"(foo", ")"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, split(tt.input))
+			assert.Equal(t, tt.input, strings.Join(split(tt.input), ""))
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	assert.Equal(t, 0, Count(""))
+	assert.Equal(t, 2, Count("hello world"))
+	assert.Equal(t, 2, Count("12345"))
+
+	// Long words split into several tokens
+	assert.Greater(t, Count("internationalization"), 1)
+
+	// Roughly in line with ~4 characters per token for English prose
+	prose := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20)
+	count := Count(prose)
+	assert.GreaterOrEqual(t, count, len(prose)/6)
+	assert.LessOrEqual(t, count, len(prose)/3)
+}
+
+func TestCount_NotBelowCl100k(t *testing.T) {
+	// Counts cl100k_base gives these; the estimate may be higher, never lower
+	tests := []struct {
+		input string
+		exact int
+	}{
+		{"hello world", 2},
+		{"12345", 2},
+		{"The quick brown fox jumps over the lazy dog.", 10},
+		{"internationalization", 2},
+		{"    return nil", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.GreaterOrEqual(t, Count(tt.input), tt.exact)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	text := "line one\nline two\nline three\n"
+
+	result, truncated := Truncate(text, 0)
+	assert.False(t, truncated)
+	assert.Equal(t, text, result)
+
+	result, truncated = Truncate(text, 1000)
+	assert.False(t, truncated)
+	assert.Equal(t, text, result)
+
+	// Cuts at the last complete line within the budget
+	result, truncated = Truncate(text, 7)
+	assert.True(t, truncated)
+	assert.Equal(t, "line one\nline two\n", result)
+	assert.LessOrEqual(t, Count(result), 7)
+
+	// Without a line boundary, cuts between pieces
+	result, truncated = Truncate("alpha beta gamma delta", 2)
+	assert.True(t, truncated)
+	assert.Equal(t, "alpha beta", result)
+}
@@ -0,0 +1,343 @@
+// Package toolargs declares a tool's arguments once, as a Go struct, and
+// derives both the MCP input schema clients see and the binding and
+// validation of the arguments a call brings. A handler reading its
+// arguments from the same struct the schema came from can't drift from
+// what the tool advertises.
+//
+// Each exported field with an arg tag is one argument:
+//
+//	type FocusArgs struct {
+//		Action   string   `arg:"action" enum:"get,set,add,clear" desc:"get (default), set, add or clear"`
+//		Features []string `arg:"features,required" desc:"Feature names"`
+//	}
+//
+// The tag names the argument, optionally followed by ",required". desc
+// is its description and enum the comma-separated values a string may
+// take. Fields may be string, bool, int, float64, []string,
+// map[string]any or map[string]string; embedded structs contribute their
+// arguments, so arguments shared by several tools are declared once. A
+// pointer to a string, bool, int or float64 is nil when the argument is
+// left out, for arguments whose zero value isn't their default. A
+// slice of structs is a list of objects, each declared by the struct's
+// own arg tags:
+//
+//	type Change struct {
+//		Path    string `arg:"path,required"`
+//		Content string `arg:"content,required"`
+//	}
+//
+//	Changes []Change `arg:"changes,required" desc:"Files to write"`
+package toolargs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// field is one argument declared by a struct field
+type field struct {
+	name     string
+	required bool
+	desc     string
+	enum     []string
+	index    []int // reflect field index, through embedded structs
+	typ      reflect.Type
+}
+
+// fields returns the arguments declared by struct type t, in field order.
+// It panics on a field of a type arguments can't have, as that is a
+// programming error found the first time the tool is declared.
+func fields(t reflect.Type) []field {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("toolargs: %s is not a struct", t))
+	}
+
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Tag.Get("arg") == "" {
+			for _, inner := range fields(sf.Type) {
+				inner.index = append([]int{i}, inner.index...)
+				out = append(out, inner)
+			}
+			continue
+		}
+		tag, ok := sf.Tag.Lookup("arg")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		f := field{
+			name:     name,
+			required: opts == "required",
+			desc:     sf.Tag.Get("desc"),
+			index:    []int{i},
+			typ:      sf.Type,
+		}
+		if enum := sf.Tag.Get("enum"); enum != "" {
+			f.enum = strings.Split(enum, ",")
+		}
+		if !supported(sf.Type) {
+			panic(fmt.Sprintf("toolargs: argument %s of %s has unsupported type %s", name, t, sf.Type))
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// supported reports whether arguments can be bound to a field of type t
+func supported(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer:
+		switch t.Elem().Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Float64:
+			return true
+		}
+		return false
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Float64:
+		return true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Struct {
+			fields(t.Elem())
+			return true
+		}
+		return t.Elem().Kind() == reflect.String
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && (t.Elem().Kind() == reflect.String || t.Elem().Kind() == reflect.Interface)
+	}
+	return false
+}
+
+// option returns the schema of the argument
+func (f field) option() mcp.ToolOption {
+	var opts []mcp.PropertyOption
+	if f.desc != "" {
+		opts = append(opts, mcp.Description(f.desc))
+	}
+	if f.required {
+		opts = append(opts, mcp.Required())
+	}
+	if len(f.enum) > 0 {
+		opts = append(opts, mcp.Enum(f.enum...))
+	}
+
+	kind := f.typ.Kind()
+	if kind == reflect.Pointer {
+		kind = f.typ.Elem().Kind()
+	}
+	switch kind {
+	case reflect.Bool:
+		return mcp.WithBoolean(f.name, opts...)
+	case reflect.Int, reflect.Float64:
+		return mcp.WithNumber(f.name, opts...)
+	case reflect.Slice:
+		if f.typ.Elem().Kind() == reflect.Struct {
+			return mcp.WithArray(f.name, append(opts, mcp.Items(objectSchema(f.typ.Elem())))...)
+		}
+		return mcp.WithArray(f.name, append(opts, mcp.Items(map[string]any{"type": "string"}))...)
+	case reflect.Map:
+		return mcp.WithObject(f.name, opts...)
+	default:
+		return mcp.WithString(f.name, opts...)
+	}
+}
+
+// objectSchema returns the JSON schema of an object declared by struct
+// type t, for the items of a list of objects
+func objectSchema(t reflect.Type) map[string]any {
+	var tool mcp.Tool
+	tool.InputSchema.Properties = make(map[string]any)
+	for _, f := range fields(t) {
+		f.option()(&tool)
+	}
+
+	schema := map[string]any{"type": "object", "properties": tool.InputSchema.Properties}
+	if len(tool.InputSchema.Required) > 0 {
+		schema["required"] = tool.InputSchema.Required
+	}
+	return schema
+}
+
+// Options returns the schema options of the arguments args declares, to
+// pass to mcp.NewTool beside others
+func Options(args any) []mcp.ToolOption {
+	var opts []mcp.ToolOption
+	for _, f := range fields(reflect.TypeOf(args)) {
+		opts = append(opts, f.option())
+	}
+	return opts
+}
+
+// NewTool returns a tool whose input schema declares the arguments of
+// args, a value of the struct type its handler binds to
+func NewTool(name, description string, args any) mcp.Tool {
+	return mcp.NewTool(name, append([]mcp.ToolOption{mcp.WithDescription(description)}, Options(args)...)...)
+}
+
+// Bind sets the fields of the struct dst points to from a call's
+// arguments. Arguments left out keep their zero value, except that a
+// given empty list binds to an empty, non-nil slice, so handlers can tell
+// "clear" from "keep". It fails on a required argument left out, a value
+// of the wrong type, or a string outside its enum; arguments the struct
+// doesn't declare are ignored, as middleware reads some of them.
+func Bind(args map[string]any, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("toolargs: Bind needs a pointer to a struct, not %T", dst)
+	}
+	v = v.Elem()
+
+	for _, f := range fields(v.Type()) {
+		raw, given := args[f.name]
+		if !given || raw == nil {
+			if f.required {
+				return fmt.Errorf("%s is required", f.name)
+			}
+			continue
+		}
+		value, err := convert(f, raw)
+		if err != nil {
+			return err
+		}
+		v.FieldByIndex(f.index).Set(value)
+	}
+	return nil
+}
+
+// convert checks a JSON-decoded argument against its field and returns
+// it as the field's type
+func convert(f field, raw any) (reflect.Value, error) {
+	invalid := func(expected string) error {
+		return fmt.Errorf("invalid %s: expected %s, got %T", f.name, expected, raw)
+	}
+
+	if f.typ.Kind() == reflect.Pointer {
+		value, err := convert(field{name: f.name, enum: f.enum, typ: f.typ.Elem()}, raw)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(f.typ.Elem())
+		ptr.Elem().Set(value)
+		return ptr, nil
+	}
+
+	switch f.typ.Kind() {
+	case reflect.String:
+		text, ok := raw.(string)
+		if !ok {
+			return reflect.Value{}, invalid("a string")
+		}
+		if text != "" && len(f.enum) > 0 && !slices.Contains(f.enum, text) {
+			return reflect.Value{}, fmt.Errorf("invalid %s: %s (expected one of %s)", f.name, text, strings.Join(f.enum, ", "))
+		}
+		return reflect.ValueOf(text).Convert(f.typ), nil
+
+	case reflect.Bool:
+		flag, ok := raw.(bool)
+		if !ok {
+			return reflect.Value{}, invalid("true or false")
+		}
+		return reflect.ValueOf(flag).Convert(f.typ), nil
+
+	case reflect.Int, reflect.Float64:
+		number, ok := raw.(float64)
+		if !ok {
+			if integer, isInt := raw.(int); isInt {
+				number, ok = float64(integer), true
+			}
+		}
+		if !ok {
+			return reflect.Value{}, invalid("a number")
+		}
+		if f.typ.Kind() == reflect.Int {
+			if number != math.Trunc(number) {
+				return reflect.Value{}, fmt.Errorf("invalid %s: expected a whole number, got %v", f.name, number)
+			}
+			return reflect.ValueOf(int(number)).Convert(f.typ), nil
+		}
+		return reflect.ValueOf(number).Convert(f.typ), nil
+
+	case reflect.Slice:
+		list := reflect.MakeSlice(f.typ, 0, 0)
+		if f.typ.Elem().Kind() == reflect.Struct {
+			items, ok := raw.([]any)
+			if !ok {
+				return reflect.Value{}, invalid("a list of objects")
+			}
+			for i, item := range items {
+				object, ok := item.(map[string]any)
+				if !ok {
+					return reflect.Value{}, fmt.Errorf("invalid %s: expected a list of objects, got a %T item", f.name, item)
+				}
+				elem := reflect.New(f.typ.Elem())
+				if err := Bind(object, elem.Interface()); err != nil {
+					return reflect.Value{}, fmt.Errorf("invalid %s item %d: %w", f.name, i+1, err)
+				}
+				list = reflect.Append(list, elem.Elem())
+			}
+			return list, nil
+		}
+		switch items := raw.(type) {
+		case []string:
+			for _, item := range items {
+				list = reflect.Append(list, reflect.ValueOf(item).Convert(f.typ.Elem()))
+			}
+		case []any:
+			for _, item := range items {
+				text, ok := item.(string)
+				if !ok {
+					return reflect.Value{}, fmt.Errorf("invalid %s: expected a list of strings, got a %T item", f.name, item)
+				}
+				list = reflect.Append(list, reflect.ValueOf(text).Convert(f.typ.Elem()))
+			}
+		default:
+			return reflect.Value{}, invalid("a list of strings")
+		}
+		return list, nil
+
+	case reflect.Map:
+		object, ok := raw.(map[string]any)
+		if !ok {
+			return reflect.Value{}, invalid("an object")
+		}
+		m := reflect.MakeMapWithSize(f.typ, len(object))
+		for key, item := range object {
+			value := reflect.ValueOf(item)
+			if f.typ.Elem().Kind() == reflect.String {
+				// Objects of strings take scalars as written, e.g. {"page": 2}
+				value = reflect.ValueOf(fmt.Sprint(item)).Convert(f.typ.Elem())
+			} else if item == nil {
+				value = reflect.Zero(f.typ.Elem())
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(f.typ.Key()), value)
+		}
+		return m, nil
+	}
+	return reflect.Value{}, fmt.Errorf("toolargs: unsupported argument type %s", f.typ)
+}
+
+// Handler returns a tool handler that binds each call's arguments to a T
+// and passes it to fn. Binding errors are returned as the call's error,
+// as handlers report other invalid arguments.
+func Handler[T any](fn func(ctx context.Context, args T) (*mcp.CallToolResult, error)) server.ToolHandlerFunc {
+	// Check the struct once, when the handler is built
+	fields(reflect.TypeOf((*T)(nil)).Elem())
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args T
+		if err := Bind(request.GetArguments(), &args); err != nil {
+			return nil, err
+		}
+		return fn(ctx, args)
+	}
+}
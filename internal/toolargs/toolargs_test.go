@@ -0,0 +1,200 @@
+package toolargs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type budget struct {
+	MaxTokens int `arg:"max_tokens" desc:"Truncate the response"`
+}
+
+type testArgs struct {
+	Action   string            `arg:"action" enum:"list,set" desc:"list (default) or set"`
+	Name     string            `arg:"name,required" desc:"Note name"`
+	Force    bool              `arg:"force"`
+	Limit    int               `arg:"limit"`
+	Score    float64           `arg:"score"`
+	Tags     []string          `arg:"tags" desc:"Tags"`
+	Params   map[string]string `arg:"params"`
+	Extra    map[string]any    `arg:"extra"`
+	internal string
+	Skipped  string
+	budget
+}
+
+func TestNewTool(t *testing.T) {
+	tool := NewTool("test_tool", "A tool", testArgs{})
+
+	assert.Equal(t, "A tool", tool.Description)
+	assert.Equal(t, []string{"name"}, tool.InputSchema.Required)
+	assert.Len(t, tool.InputSchema.Properties, 9)
+
+	assert.Equal(t, map[string]any{"type": "string", "description": "list (default) or set", "enum": []string{"list", "set"}}, tool.InputSchema.Properties["action"])
+	assert.Equal(t, map[string]any{"type": "boolean"}, tool.InputSchema.Properties["force"])
+	assert.Equal(t, map[string]any{"type": "number"}, tool.InputSchema.Properties["limit"])
+	assert.Equal(t, map[string]any{"type": "array", "description": "Tags", "items": map[string]any{"type": "string"}}, tool.InputSchema.Properties["tags"])
+	assert.Equal(t, map[string]any{"type": "object", "properties": map[string]any{}}, tool.InputSchema.Properties["params"])
+	assert.Equal(t, map[string]any{"type": "number", "description": "Truncate the response"}, tool.InputSchema.Properties["max_tokens"])
+	assert.NotContains(t, tool.InputSchema.Properties, "Skipped")
+}
+
+func TestBind(t *testing.T) {
+	var args testArgs
+	require.NoError(t, Bind(map[string]any{
+		"action":     "set",
+		"name":       "plan",
+		"force":      true,
+		"limit":      float64(5),
+		"score":      0.5,
+		"tags":       []any{"a", "b"},
+		"params":     map[string]any{"page": float64(2), "feature": "billing"},
+		"extra":      map[string]any{"nested": []any{"x"}},
+		"max_tokens": float64(100),
+		"project":    "other", // read by middleware, not the handler
+	}, &args))
+
+	assert.Equal(t, "set", args.Action)
+	assert.Equal(t, "plan", args.Name)
+	assert.True(t, args.Force)
+	assert.Equal(t, 5, args.Limit)
+	assert.Equal(t, 0.5, args.Score)
+	assert.Equal(t, []string{"a", "b"}, args.Tags)
+	assert.Equal(t, map[string]string{"page": "2", "feature": "billing"}, args.Params)
+	assert.Equal(t, map[string]any{"nested": []any{"x"}}, args.Extra)
+	assert.Equal(t, 100, args.MaxTokens)
+}
+
+func TestBind_ListPresence(t *testing.T) {
+	var omitted, empty testArgs
+	require.NoError(t, Bind(map[string]any{"name": "x"}, &omitted))
+	require.NoError(t, Bind(map[string]any{"name": "x", "tags": []any{}}, &empty))
+
+	assert.Nil(t, omitted.Tags)
+	assert.NotNil(t, empty.Tags)
+	assert.Empty(t, empty.Tags)
+}
+
+func TestBind_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		args map[string]any
+		err  string
+	}{
+		{map[string]any{}, "name is required"},
+		{map[string]any{"name": float64(3)}, "invalid name: expected a string, got float64"},
+		{map[string]any{"name": "x", "action": "delete"}, "invalid action: delete (expected one of list, set)"},
+		{map[string]any{"name": "x", "force": "yes"}, "invalid force: expected true or false, got string"},
+		{map[string]any{"name": "x", "limit": 2.5}, "invalid limit: expected a whole number, got 2.5"},
+		{map[string]any{"name": "x", "tags": "a,b"}, "invalid tags: expected a list of strings, got string"},
+		{map[string]any{"name": "x", "tags": []any{"a", float64(1)}}, "invalid tags: expected a list of strings, got a float64 item"},
+		{map[string]any{"name": "x", "params": []any{}}, "invalid params: expected an object, got []interface {}"},
+	} {
+		var args testArgs
+		assert.EqualError(t, Bind(tc.args, &args), tc.err)
+	}
+
+	// Empty strings are left-out values, not enum violations
+	var args testArgs
+	assert.NoError(t, Bind(map[string]any{"name": "x", "action": ""}, &args))
+}
+
+func TestHandler(t *testing.T) {
+	handler := Handler(func(ctx context.Context, args testArgs) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(args.Name), nil
+	})
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"name": "plan"}
+	result, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "plan", result.Content[0].(mcp.TextContent).Text)
+
+	request.Params.Arguments = map[string]any{}
+	_, err = handler(context.Background(), request)
+	assert.EqualError(t, err, "name is required")
+}
+
+type change struct {
+	Path    string `arg:"path,required"`
+	Content string `arg:"content" desc:"New content"`
+}
+
+type changesArgs struct {
+	Changes []change `arg:"changes,required" desc:"Files to write"`
+}
+
+func TestObjectList(t *testing.T) {
+	tool := NewTool("write", "", changesArgs{})
+	assert.Equal(t, map[string]any{
+		"type":        "array",
+		"description": "Files to write",
+		"items": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path":    map[string]any{"type": "string"},
+				"content": map[string]any{"type": "string", "description": "New content"},
+			},
+			"required": []string{"path"},
+		},
+	}, tool.InputSchema.Properties["changes"])
+
+	var args changesArgs
+	require.NoError(t, Bind(map[string]any{"changes": []any{
+		map[string]any{"path": "a.go", "content": "package a"},
+		map[string]any{"path": "b.go"},
+	}}, &args))
+	assert.Equal(t, []change{{Path: "a.go", Content: "package a"}, {Path: "b.go"}}, args.Changes)
+
+	for _, tc := range []struct {
+		changes any
+		err     string
+	}{
+		{"a.go", "invalid changes: expected a list of objects, got string"},
+		{[]any{"a.go"}, "invalid changes: expected a list of objects, got a string item"},
+		{[]any{map[string]any{"path": "a.go"}, map[string]any{"content": "x"}}, "invalid changes item 2: path is required"},
+		{[]any{map[string]any{"path": true}}, "invalid changes item 1: invalid path: expected a string, got bool"},
+	} {
+		assert.EqualError(t, Bind(map[string]any{"changes": tc.changes}, &args), tc.err)
+	}
+}
+
+func TestOptionalScalars(t *testing.T) {
+	type optional struct {
+		Include *bool    `arg:"include" desc:"Include content (default: true)"`
+		Limit   *int     `arg:"limit"`
+		Mode    *string  `arg:"mode" enum:"a,b"`
+		Ratio   *float64 `arg:"ratio"`
+	}
+	tool := NewTool("optional", "", optional{})
+	assert.Equal(t, map[string]any{"type": "boolean", "description": "Include content (default: true)"}, tool.InputSchema.Properties["include"])
+	assert.Equal(t, map[string]any{"type": "string", "enum": []string{"a", "b"}}, tool.InputSchema.Properties["mode"])
+
+	var omitted optional
+	require.NoError(t, Bind(map[string]any{}, &omitted))
+	assert.Nil(t, omitted.Include)
+	assert.Nil(t, omitted.Limit)
+
+	var given optional
+	require.NoError(t, Bind(map[string]any{"include": false, "limit": float64(0), "mode": "b", "ratio": 0.5}, &given))
+	require.NotNil(t, given.Include)
+	assert.False(t, *given.Include)
+	assert.Equal(t, 0, *given.Limit)
+	assert.Equal(t, "b", *given.Mode)
+	assert.Equal(t, 0.5, *given.Ratio)
+
+	assert.EqualError(t, Bind(map[string]any{"mode": "c"}, &given), "invalid mode: c (expected one of a, b)")
+	assert.EqualError(t, Bind(map[string]any{"limit": 1.5}, &given), "invalid limit: expected a whole number, got 1.5")
+}
+
+func TestUnsupportedType(t *testing.T) {
+	type bad struct {
+		Count int64 `arg:"count"`
+	}
+	assert.PanicsWithValue(t, "toolargs: argument count of toolargs.bad has unsupported type int64", func() {
+		NewTool("bad", "", bad{})
+	})
+}
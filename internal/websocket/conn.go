@@ -0,0 +1,359 @@
+// Package websocket serves MCP over WebSocket connections. Each connection
+// is its own MCP session: JSON-RPC messages travel as text frames in both
+// directions, so the server can push notifications and sampling requests
+// without the client polling.
+//
+// Only the server side of RFC 6455 is implemented, without extensions.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// acceptGUID is appended to the client's key to compute the handshake reply
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// MaxMessageSize bounds one incoming message, across all of its frames
+const MaxMessageSize = 16 << 20
+
+// Subprotocol is the WebSocket subprotocol echoed to clients that offer it
+const Subprotocol = "mcp"
+
+// Frame opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close status codes
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseInvalidPayload  = 1007
+	CloseMessageTooLarge = 1009
+)
+
+// ErrClosed is returned by ReadMessage once the peer has closed the
+// connection
+var ErrClosed = errors.New("websocket connection closed")
+
+// Conn is a server-side WebSocket connection. ReadMessage must be called
+// from one goroutine; writes may come from any.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMu sync.Mutex
+	closed  bool // a close frame was sent; guarded by writeMu
+}
+
+// Upgrade completes the WebSocket handshake for r and takes over its
+// connection. Browsers may only connect from loopback origins or those in
+// allowedOrigins, so other sites can't drive the server through a visitor;
+// clients that send no Origin, which browsers always do, are accepted. On
+// failure an HTTP error has already been written.
+func Upgrade(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "websocket upgrade requires GET", http.StatusMethodNotAllowed)
+		return nil, fmt.Errorf("websocket upgrade with method %s", r.Method)
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "expected a websocket upgrade request", http.StatusBadRequest)
+		return nil, errors.New("not a websocket upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("unsupported websocket version %q", r.Header.Get("Sec-WebSocket-Version"))
+	}
+	if origin := r.Header.Get("Origin"); !originAllowed(origin, allowedOrigins) {
+		http.Error(w, "websocket origin not allowed", http.StatusForbidden)
+		return nil, fmt.Errorf("websocket origin %q not allowed", origin)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket upgrade not supported", http.StatusInternalServerError)
+		return nil, errors.New("response writer cannot be hijacked")
+	}
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take over connection: %w", err)
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n"
+	if headerHasToken(r.Header, "Sec-WebSocket-Protocol", Subprotocol) {
+		response += "Sec-WebSocket-Protocol: " + Subprotocol + "\r\n"
+	}
+	if _, err := netConn.Write([]byte(response + "\r\n")); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+
+	return &Conn{conn: netConn, reader: rw.Reader}, nil
+}
+
+// originAllowed reports whether a handshake's Origin header may connect:
+// when it is absent, names a loopback host or is listed in allowed
+func originAllowed(origin string, allowed []string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(candidate, "/"), origin) {
+			return true
+		}
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	host := parsed.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// AcceptKey computes the Sec-WebSocket-Accept reply to a client key
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether a comma-separated header lists token,
+// ignoring case
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next text or binary message, answering pings
+// along the way. It returns ErrClosed once the peer closes the connection;
+// a protocol violation closes it with the matching status and is returned.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			var protocolErr *closeError
+			if errors.As(err, &protocolErr) {
+				c.Close(protocolErr.code, protocolErr.reason)
+			}
+			return nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.Close(code, "")
+			return nil, ErrClosed
+		case opText, opBinary:
+			if started {
+				return nil, c.fail(CloseProtocolError, "new message before the previous one finished")
+			}
+			started = true
+			message = payload
+		case opContinuation:
+			if !started {
+				return nil, c.fail(CloseProtocolError, "continuation frame without a message")
+			}
+			message = append(message, payload...)
+		default:
+			return nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode))
+		}
+
+		if len(message) > MaxMessageSize {
+			return nil, c.fail(CloseMessageTooLarge, "message too large")
+		}
+		if fin {
+			if opcode != opBinary && !utf8.Valid(message) {
+				return nil, c.fail(CloseInvalidPayload, "text message is not valid UTF-8")
+			}
+			return message, nil
+		}
+	}
+}
+
+// closeError is a protocol violation that closes the connection with code
+type closeError struct {
+	code   int
+	reason string
+}
+
+func (e *closeError) Error() string {
+	return fmt.Sprintf("websocket protocol error: %s", e.reason)
+}
+
+// fail closes the connection with code and returns the matching error
+func (c *Conn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	return &closeError{code: code, reason: reason}
+}
+
+// readFrame reads one frame, unmasking its payload
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, &closeError{code: CloseProtocolError, reason: "reserved bits set without an extension"}
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, &closeError{code: CloseProtocolError, reason: "client frames must be masked"}
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if opcode >= opClose && (length > 125 || !fin) {
+		return false, 0, nil, &closeError{code: CloseProtocolError, reason: "invalid control frame"}
+	}
+	if length > MaxMessageSize {
+		return false, 0, nil, &closeError{code: CloseMessageTooLarge, reason: "message too large"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends message as a single text frame
+func (c *Conn) WriteMessage(message []byte) error {
+	return c.writeFrame(opText, message)
+}
+
+// Ping sends a ping frame, keeping idle connections open through proxies
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// writeFrame sends one unmasked, final frame
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+func (c *Conn) writeFrameLocked(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch {
+	case len(payload) < 126:
+		header[1] = byte(len(payload))
+	case len(payload) <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Close sends a close frame with code and reason, then closes the
+// connection. Codes that may not be sent, like 1005 and 1006 which only
+// describe closes locally, go out as CloseNormal. It can be called more
+// than once.
+func (c *Conn) Close(code int, reason string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	if !sendableCloseCode(code) {
+		code = CloseNormal
+	}
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	_ = c.writeFrameLocked(opClose, payload)
+	return c.conn.Close()
+}
+
+// sendableCloseCode reports whether code may appear in a close frame. RFC
+// 6455 reserves 1004-1006 and 1015 and leaves codes below 1000 and from
+// 1016 to 2999 unassigned.
+func sendableCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	default:
+		return code >= 3000 && code <= 4999
+	}
+}
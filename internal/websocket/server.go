@@ -0,0 +1,284 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Path is where the server accepts WebSocket connections
+const Path = "/ws"
+
+// pingInterval is how often idle connections are pinged
+const pingInterval = 30 * time.Second
+
+// ContextFunc adds values from the upgrade request to every message's
+// context on that connection
+type ContextFunc func(ctx context.Context, r *http.Request) context.Context
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithContextFunc sets the function that derives message contexts from the
+// upgrade request
+func WithContextFunc(fn ContextFunc) Option {
+	return func(s *Server) {
+		s.contextFunc = fn
+	}
+}
+
+// WithRoute serves handler at pattern on the same listener, beside Path
+func WithRoute(pattern string, handler http.Handler) Option {
+	return func(s *Server) {
+		s.routes = append(s.routes, route{pattern: pattern, handler: handler})
+	}
+}
+
+// WithAllowedOrigins lets browser pages from origins, like
+// "https://app.example.com", connect besides loopback ones
+func WithAllowedOrigins(origins ...string) Option {
+	return func(s *Server) {
+		s.allowedOrigins = append(s.allowedOrigins, origins...)
+	}
+}
+
+// route is an extra handler served by the listener
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// Server serves an MCP server to WebSocket clients at Path
+type Server struct {
+	mcpServer      *server.MCPServer
+	contextFunc    ContextFunc
+	allowedOrigins []string
+	routes         []route
+	httpServer     *http.Server
+
+	mu      sync.Mutex
+	conns   map[*Conn]struct{}
+	closing bool
+	handled sync.WaitGroup // connection handlers still running
+}
+
+// NewServer creates a WebSocket transport for mcpServer
+func NewServer(mcpServer *server.MCPServer, opts ...Option) *Server {
+	s := &Server{
+		mcpServer: mcpServer,
+		conns:     make(map[*Conn]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start listens on addr and serves connections until Shutdown is called,
+// returning http.ErrServerClosed then
+func (s *Server) Start(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, s)
+	for _, route := range s.routes {
+		mux.Handle(route.pattern, route.handler)
+	}
+
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
+	s.httpServer = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	return httpServer.ListenAndServe()
+}
+
+// Shutdown stops accepting connections, closes open ones as going away and
+// waits for their handlers to return or ctx to end
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	httpServer := s.httpServer
+	conns := make([]*Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+
+	var err error
+	if httpServer != nil {
+		err = httpServer.Shutdown(ctx)
+	}
+	for _, conn := range conns {
+		conn.Close(CloseGoingAway, "server shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.handled.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
+}
+
+// ServeHTTP upgrades the request and serves MCP on the connection until
+// either side closes it
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Browsers can't set headers on WebSocket requests, so the bearer
+	// token may come as a query parameter of the handshake instead. URLs
+	// end up in proxy and access logs, so it is moved into the header and
+	// taken off the URL, and the client is told to prefer the header.
+	if query := r.URL.Query(); query.Has("access_token") && headerHasToken(r.Header, "Upgrade", "websocket") {
+		token := query.Get("access_token")
+		query.Del("access_token")
+		r = r.Clone(r.Context())
+		r.URL.RawQuery = query.Encode()
+		r.RequestURI = r.URL.RequestURI()
+		if r.Header.Get("Authorization") == "" && token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		slog.InfoContext(r.Context(), "websocket client sent its token in the URL, which proxies may log; send an Authorization header where the client can", "remote", r.RemoteAddr)
+	}
+
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	s.handled.Add(1)
+	s.mu.Unlock()
+	defer s.handled.Done()
+
+	conn, err := Upgrade(w, r, s.allowedOrigins)
+	if err != nil {
+		slog.DebugContext(r.Context(), "websocket upgrade failed", "remote", r.RemoteAddr, "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	// The connection outlives the upgrade request, so its context only
+	// carries the request's values
+	ctx := context.WithoutCancel(r.Context())
+	if s.contextFunc != nil {
+		ctx = s.contextFunc(ctx, r)
+	}
+	s.serveConn(ctx, conn, r.RemoteAddr)
+}
+
+// sessionCounter numbers sessions so their IDs are unique per process
+var sessionCounter atomic.Int64
+
+// serveConn registers a session for conn and relays messages until the
+// connection ends
+func (s *Server) serveConn(ctx context.Context, conn *Conn, remote string) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	session := &session{
+		id:            fmt.Sprintf("ws-%d-%d", time.Now().UnixNano(), sessionCounter.Add(1)),
+		conn:          conn,
+		notifications: make(chan mcp.JSONRPCNotification, 100),
+		pending:       make(map[int64]chan samplingResponse),
+	}
+	if err := s.mcpServer.RegisterSession(ctx, session); err != nil {
+		conn.Close(CloseGoingAway, "session unavailable")
+		slog.WarnContext(ctx, "failed to register websocket session", "error", err)
+		return
+	}
+	defer s.mcpServer.UnregisterSession(ctx, session.id)
+	ctx = s.mcpServer.WithContext(ctx, session)
+
+	slog.InfoContext(ctx, "websocket client connected", "session", session.id, "remote", remote)
+	defer slog.InfoContext(ctx, "websocket client disconnected", "session", session.id, "remote", remote)
+
+	// Notifications and pings are written as they come
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case notification := <-session.notifications:
+				if err := session.write(notification); err != nil {
+					slog.DebugContext(ctx, "failed to send websocket notification", "session", session.id, "error", err)
+				}
+			case <-ticker.C:
+				if err := conn.Ping(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			if !errors.Is(err, ErrClosed) && !errors.Is(err, net.ErrClosed) {
+				slog.DebugContext(ctx, "websocket read ended", "session", session.id, "error", err)
+			}
+			conn.Close(CloseNormal, "")
+			return
+		}
+		s.handleMessage(ctx, session, message)
+	}
+}
+
+// handleMessage answers one JSON-RPC message. Tool calls run concurrently,
+// like over stdio, so a slow tool or a sampling request it makes doesn't
+// hold up other messages; everything else is handled in order.
+func (s *Server) handleMessage(ctx context.Context, session *session, message []byte) {
+	var raw json.RawMessage
+	if err := json.Unmarshal(message, &raw); err != nil {
+		session.write(mcp.NewJSONRPCError(mcp.NewRequestId(nil), mcp.PARSE_ERROR, "Parse error", nil))
+		return
+	}
+	if session.deliverSamplingResponse(raw) {
+		return
+	}
+
+	respond := func() {
+		if response := s.mcpServer.HandleMessage(ctx, raw); response != nil {
+			if err := session.write(response); err != nil {
+				slog.DebugContext(ctx, "failed to send websocket response", "session", session.id, "error", err)
+			}
+		}
+	}
+
+	var base struct {
+		Method string `json:"method"`
+	}
+	if json.Unmarshal(raw, &base) == nil && base.Method == string(mcp.MethodToolsCall) {
+		go respond()
+		return
+	}
+	respond()
+}
@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// session is the MCP session of one WebSocket connection
+type session struct {
+	id            string
+	conn          *Conn
+	notifications chan mcp.JSONRPCNotification
+	initialized   atomic.Bool
+	clientInfo    atomic.Value // mcp.Implementation
+
+	requestID atomic.Int64
+	pendingMu sync.Mutex
+	pending   map[int64]chan samplingResponse // sampling requests awaiting the client
+}
+
+// samplingResponse is the client's answer to a sampling request
+type samplingResponse struct {
+	result *mcp.CreateMessageResult
+	err    error
+}
+
+var (
+	_ server.ClientSession         = (*session)(nil)
+	_ server.SessionWithClientInfo = (*session)(nil)
+	_ server.SessionWithSampling   = (*session)(nil)
+)
+
+func (s *session) SessionID() string { return s.id }
+
+func (s *session) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+func (s *session) Initialize() { s.initialized.Store(true) }
+
+func (s *session) Initialized() bool { return s.initialized.Load() }
+
+func (s *session) GetClientInfo() mcp.Implementation {
+	info, _ := s.clientInfo.Load().(mcp.Implementation)
+	return info
+}
+
+func (s *session) SetClientInfo(info mcp.Implementation) {
+	s.clientInfo.Store(info)
+}
+
+// write sends a JSON-RPC message as one text frame
+func (s *session) write(message interface{}) error {
+	content, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	return s.conn.WriteMessage(content)
+}
+
+// RequestSampling asks the client to run a completion and waits for its
+// answer
+func (s *session) RequestSampling(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	id := s.requestID.Add(1)
+	responses := make(chan samplingResponse, 1)
+	s.pendingMu.Lock()
+	s.pending[id] = responses
+	s.pendingMu.Unlock()
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, id)
+		s.pendingMu.Unlock()
+	}()
+
+	err := s.write(struct {
+		JSONRPC string                  `json:"jsonrpc"`
+		ID      int64                   `json:"id"`
+		Method  string                  `json:"method"`
+		Params  mcp.CreateMessageParams `json:"params"`
+	}{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      id,
+		Method:  string(mcp.MethodSamplingCreateMessage),
+		Params:  request.CreateMessageParams,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send sampling request: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case response := <-responses:
+		return response.result, response.err
+	}
+}
+
+// deliverSamplingResponse hands a client's response to the sampling request
+// waiting for it, reporting whether raw was such a response
+func (s *session) deliverSamplingResponse(raw json.RawMessage) bool {
+	var response struct {
+		ID     json.Number     `json:"id"`
+		Method string          `json:"method"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &response); err != nil || response.Method != "" {
+		return false
+	}
+	id, err := response.ID.Int64()
+	if err != nil || (response.Result == nil && response.Error == nil) {
+		return false
+	}
+
+	s.pendingMu.Lock()
+	responses, ok := s.pending[id]
+	s.pendingMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	var answer samplingResponse
+	if response.Error != nil {
+		answer.err = fmt.Errorf("sampling request failed: %s", response.Error.Message)
+	} else {
+		var result mcp.CreateMessageResult
+		if err := json.Unmarshal(response.Result, &result); err != nil {
+			answer.err = fmt.Errorf("failed to decode sampling response: %w", err)
+		} else {
+			answer.result = &result
+		}
+	}
+	select {
+	case responses <- answer:
+	default:
+	}
+	return true
+}
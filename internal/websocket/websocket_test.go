@@ -0,0 +1,306 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClient is a minimal WebSocket client speaking raw frames
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dial(t *testing.T, url, path string, header string) (*testClient, string) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	request := "GET " + path + " HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" + header + "\r\n"
+	_, err = conn.Write([]byte(request))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	return &testClient{t: t, conn: conn, reader: reader}, resp.Header.Get("Sec-WebSocket-Protocol")
+}
+
+func (c *testClient) writeFrame(opcode byte, payload []byte, masked bool) {
+	frame := []byte{0x80 | opcode}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	default:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	body := append([]byte(nil), payload...)
+	if masked {
+		mask := []byte{1, 2, 3, 4}
+		frame = append(frame, mask...)
+		for i := range body {
+			body[i] ^= mask[i%4]
+		}
+	}
+	_, err := c.conn.Write(append(frame, body...))
+	require.NoError(c.t, err)
+}
+
+func (c *testClient) send(message string) {
+	c.writeFrame(opText, []byte(message), true)
+}
+
+// read returns the next frame's opcode and payload
+func (c *testClient) read() (byte, string) {
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var header [2]byte
+	_, err := io.ReadFull(c.reader, header[:])
+	require.NoError(c.t, err)
+	length := int(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		_, err = io.ReadFull(c.reader, extended[:])
+		require.NoError(c.t, err)
+		length = int(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		_, err = io.ReadFull(c.reader, extended[:])
+		require.NoError(c.t, err)
+		length = int(binary.BigEndian.Uint64(extended[:]))
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+	require.NoError(c.t, err)
+	return header[0] & 0x0F, string(payload)
+}
+
+// readText skips pings and returns the next text message
+func (c *testClient) readText() string {
+	for {
+		opcode, payload := c.read()
+		if opcode == opText {
+			return payload
+		}
+		require.Equal(c.t, byte(opPing), opcode, "unexpected frame %q", payload)
+	}
+}
+
+func newTestServer(t *testing.T, opts ...Option) (*server.MCPServer, *Server, *httptest.Server) {
+	mcpServer := server.NewMCPServer("test", "1.0")
+	mcpServer.AddTool(mcp.NewTool("echo", mcp.WithString("text")), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		text, _ := request.GetArguments()["text"].(string)
+		return mcp.NewToolResultText("echo: " + text), nil
+	})
+	wsServer := NewServer(mcpServer, opts...)
+	httpServer := httptest.NewServer(wsServer)
+	t.Cleanup(httpServer.Close)
+	return mcpServer, wsServer, httpServer
+}
+
+const initialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455 section 1.3
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestServer_Session(t *testing.T) {
+	mcpServer, _, httpServer := newTestServer(t)
+	client, protocol := dial(t, httpServer.URL, Path, "Sec-WebSocket-Protocol: mcp\r\n")
+	assert.Equal(t, Subprotocol, protocol)
+
+	client.send(initialize)
+	assert.Contains(t, client.readText(), `"serverInfo":{"name":"test","version":"1.0"}`)
+	client.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	client.send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`)
+	assert.Contains(t, client.readText(), `"text":"echo: hi"`)
+
+	// Notifications are pushed without the client asking
+	mcpServer.SendNotificationToAllClients("notifications/resources/list_changed", nil)
+	assert.Contains(t, client.readText(), `"method":"notifications/resources/list_changed"`)
+
+	// Pings are answered with the same payload
+	client.writeFrame(opPing, []byte("still there?"), true)
+	opcode, payload := client.read()
+	assert.Equal(t, byte(opPong), opcode)
+	assert.Equal(t, "still there?", payload)
+
+	client.send(`not json`)
+	assert.Contains(t, client.readText(), `"message":"Parse error"`)
+}
+
+func TestServer_ContextFunc(t *testing.T) {
+	type authKey struct{}
+	var query string
+	mcpServer, _, httpServer := newTestServer(t, WithContextFunc(func(ctx context.Context, r *http.Request) context.Context {
+		query = r.URL.RawQuery + r.RequestURI
+		return context.WithValue(ctx, authKey{}, r.Header.Get("Authorization"))
+	}))
+	mcpServer.AddTool(mcp.NewTool("whoami"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		auth, _ := ctx.Value(authKey{}).(string)
+		return mcp.NewToolResultText("auth: " + auth), nil
+	})
+
+	// Browser clients pass the token as a query parameter
+	client, _ := dial(t, httpServer.URL, Path+"?access_token=s3cret", "")
+	client.send(initialize)
+	client.readText()
+	client.send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"whoami"}}`)
+	assert.Contains(t, client.readText(), `"text":"auth: Bearer s3cret"`)
+	assert.NotContains(t, query, "s3cret", "the token is taken off the URL")
+}
+
+func TestUpgrade_Origin(t *testing.T) {
+	_, _, httpServer := newTestServer(t, WithAllowedOrigins("https://app.example.com"))
+
+	tests := []struct {
+		origin string
+		want   int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{"http://localhost:3000", http.StatusSwitchingProtocols},
+		{"http://127.0.0.1:8787", http.StatusSwitchingProtocols},
+		{"http://[::1]", http.StatusSwitchingProtocols},
+		{"https://app.example.com", http.StatusSwitchingProtocols},
+		{"https://evil.example.com", http.StatusForbidden},
+		{"http://localhost.evil.example.com", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			conn, err := net.Dial("tcp", strings.TrimPrefix(httpServer.URL, "http://"))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			request := "GET " + Path + " HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+				"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+			if tt.origin != "" {
+				request += "Origin: " + tt.origin + "\r\n"
+			}
+			_, err = conn.Write([]byte(request + "\r\n"))
+			require.NoError(t, err)
+
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.want, resp.StatusCode)
+		})
+	}
+}
+
+func TestUpgrade_Rejects(t *testing.T) {
+	_, _, httpServer := newTestServer(t)
+
+	resp, err := http.Get(httpServer.URL + Path)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, httpServer.URL+Path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "8")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUpgradeRequired, resp.StatusCode)
+	assert.Equal(t, "13", resp.Header.Get("Sec-WebSocket-Version"))
+}
+
+func TestConn_UnmaskedFrame(t *testing.T) {
+	_, _, httpServer := newTestServer(t)
+	client, _ := dial(t, httpServer.URL, Path, "")
+
+	client.writeFrame(opText, []byte(initialize), false)
+	opcode, payload := client.read()
+	assert.Equal(t, byte(opClose), opcode)
+	assert.Equal(t, uint16(CloseProtocolError), binary.BigEndian.Uint16([]byte(payload)))
+	assert.Equal(t, "client frames must be masked", payload[2:])
+}
+
+func TestConn_CloseReservedCode(t *testing.T) {
+	tests := []struct {
+		code int
+		want uint16
+	}{
+		{CloseGoingAway, CloseGoingAway},
+		{4000, 4000},
+		{1005, CloseNormal},
+		{1006, CloseNormal},
+		{1015, CloseNormal},
+		{0, CloseNormal},
+	}
+	for _, tt := range tests {
+		serverSide, clientSide := net.Pipe()
+		conn := &Conn{conn: serverSide, reader: bufio.NewReader(serverSide)}
+		go conn.Close(tt.code, "bye")
+
+		var frame [6]byte
+		_, err := io.ReadFull(clientSide, frame[:])
+		require.NoError(t, err)
+		assert.Equal(t, byte(0x80|opClose), frame[0])
+		assert.Equal(t, tt.want, binary.BigEndian.Uint16(frame[2:4]), "code %d", tt.code)
+		clientSide.Close()
+	}
+}
+
+func TestConn_FragmentedMessage(t *testing.T) {
+	_, _, httpServer := newTestServer(t)
+	client, _ := dial(t, httpServer.URL, Path, "")
+
+	half := len(initialize) / 2
+	first := []byte{opText, 0x80 | byte(half), 0, 0, 0, 0}
+	_, err := client.conn.Write(append(first, initialize[:half]...))
+	require.NoError(t, err)
+	client.writeFrame(opContinuation, []byte(initialize[half:]), true)
+	assert.Contains(t, client.readText(), `"id":1`)
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	_, wsServer, httpServer := newTestServer(t)
+	client, _ := dial(t, httpServer.URL, Path, "")
+	client.send(initialize)
+	client.readText()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, wsServer.Shutdown(ctx))
+
+	opcode, payload := client.read()
+	assert.Equal(t, byte(opClose), opcode)
+	assert.Equal(t, uint16(CloseGoingAway), binary.BigEndian.Uint16([]byte(payload)))
+
+	// New connections are refused once shut down
+	resp, err := http.Get(httpServer.URL + Path)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.ErrorIs(t, wsServer.Start("127.0.0.1:0"), http.ErrServerClosed)
+}
@@ -0,0 +1,81 @@
+package workspace
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Sandbox confines the project files tools read and write to a set of
+// root directories, plus files and directories explicitly allowed outside
+// them. Paths are compared with their symlinks resolved, so a link inside
+// a root can't lead out of it. The nil Sandbox allows every path.
+type Sandbox struct {
+	roots []string // absolute, clean and resolved
+	allow []string
+}
+
+// NewSandbox returns a sandbox over the given absolute roots and allowed
+// paths
+func NewSandbox(roots, allow []string) *Sandbox {
+	s := &Sandbox{}
+	for _, root := range roots {
+		s.roots = append(s.roots, resolve(root))
+	}
+	for _, path := range allow {
+		s.allow = append(s.allow, resolve(path))
+	}
+	return s
+}
+
+// Roots returns the sandbox roots, resolved
+func (s *Sandbox) Roots() []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s.roots...)
+}
+
+// Check returns a *SandboxError when op, such as "backup" or "restore",
+// may not touch the absolute path
+func (s *Sandbox) Check(op, path string) error {
+	if s == nil {
+		return nil
+	}
+	real := resolve(path)
+	for _, dir := range append(s.roots, s.allow...) {
+		if _, ok := within(dir, real); ok {
+			return nil
+		}
+	}
+	return &SandboxError{Op: op, Path: path, Roots: s.Roots()}
+}
+
+// SandboxError reports a file operation the sandbox refused. Its fields
+// are reported to clients as JSON.
+type SandboxError struct {
+	Op    string   `json:"op"`
+	Path  string   `json:"path"`
+	Roots []string `json:"roots"`
+}
+
+func (e *SandboxError) Error() string {
+	return fmt.Sprintf("%s of %s refused: it is outside the sandbox roots (%s); add it to sandbox.allow to permit it", e.Op, e.Path, strings.Join(e.Roots, ", "))
+}
+
+// resolve cleans path and resolves the symlinks of its longest existing
+// prefix; the missing rest, such as a file about to be created, is kept
+// as written
+func resolve(path string) string {
+	path = filepath.Clean(path)
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(append([]string{real}, missing...)...)
+		}
+		if filepath.Dir(dir) == dir {
+			return path
+		}
+		missing = append([]string{filepath.Base(dir)}, missing...)
+	}
+}
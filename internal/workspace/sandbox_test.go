@@ -0,0 +1,48 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandbox_Check(t *testing.T) {
+	base := t.TempDir()
+	project := filepath.Join(base, "project")
+	shared := filepath.Join(base, "shared")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{project, shared, outside} {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("x"), 0644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(project, "escape")))
+
+	sandbox := NewSandbox([]string{project}, []string{filepath.Join(shared, "notes.md")})
+
+	assert.NoError(t, sandbox.Check("backup", filepath.Join(project, "main.go")))
+	assert.NoError(t, sandbox.Check("restore", filepath.Join(project, "new", "file.go")), "files not created yet")
+	assert.NoError(t, sandbox.Check("restore", filepath.Join(shared, "notes.md")), "allowed explicitly")
+
+	for _, path := range []string{
+		filepath.Join(outside, "secret.txt"),
+		filepath.Join(shared, "other.md"),
+		filepath.Join(project, "..", "outside", "secret.txt"),
+		filepath.Join(project, "escape", "secret.txt"),
+		project + "-sibling",
+	} {
+		err := sandbox.Check("restore", path)
+		var violation *SandboxError
+		require.True(t, errors.As(err, &violation), path)
+		assert.Equal(t, "restore", violation.Op)
+		assert.Equal(t, path, violation.Path)
+		assert.Equal(t, []string{resolve(project)}, violation.Roots)
+	}
+
+	// The nil sandbox allows everything
+	var off *Sandbox
+	assert.NoError(t, off.Check("restore", filepath.Join(outside, "secret.txt")))
+}
@@ -0,0 +1,83 @@
+// Package workspace translates between the absolute paths tools work with
+// and the workspace-relative paths buddy files store, so backups and
+// history keep working when the project is moved or mounted elsewhere.
+package workspace
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Root is the directory stored paths are relative to
+type Root struct {
+	dir string // absolute and clean
+}
+
+// NewRoot returns the root at dir, made absolute
+func NewRoot(dir string) (Root, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return Root{}, fmt.Errorf("failed to resolve workspace root: %w", err)
+	}
+	return Root{dir: abs}, nil
+}
+
+// Dir returns the root's absolute path
+func (r Root) Dir() string {
+	return r.dir
+}
+
+// Rel returns path in its stored form: slash-separated and relative to the
+// root when it lies inside it, or absolute when it doesn't. Relative paths
+// are taken as relative to the root already. The zero Root leaves paths
+// as they are.
+func (r Root) Rel(path string) string {
+	if r.dir == "" || path == "" {
+		return path
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(filepath.Clean(path))
+	}
+	rel, ok := within(r.dir, path)
+	if !ok {
+		return path
+	}
+	return rel
+}
+
+// Abs returns the absolute path of a stored path. Absolute paths, as
+// stored before paths became relative, are returned unchanged.
+func (r Root) Abs(path string) string {
+	if r.dir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(r.dir, filepath.FromSlash(path))
+}
+
+// Migrate returns a stored path relative to the root when it is an absolute
+// path inside the root or inside oldDir, where the workspace used to live,
+// and whether it changed
+func (r Root) Migrate(path, oldDir string) (string, bool) {
+	if r.dir == "" || !filepath.IsAbs(path) {
+		return path, false
+	}
+	if rel, ok := within(r.dir, path); ok {
+		return rel, true
+	}
+	if oldDir != "" {
+		if rel, ok := within(filepath.Clean(oldDir), path); ok {
+			return rel, true
+		}
+	}
+	return path, false
+}
+
+// within returns path relative to dir, slash-separated, if it lies inside
+func within(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,59 @@
+package workspace
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoot_RelAbs(t *testing.T) {
+	dir := t.TempDir()
+	root, err := NewRoot(dir)
+	require.NoError(t, err)
+
+	tests := []struct {
+		path   string
+		stored string
+	}{
+		{filepath.Join(dir, "src", "main.go"), "src/main.go"},
+		{filepath.Join(dir, ".buddy", "backups", "abc", "main.go"), ".buddy/backups/abc/main.go"},
+		{dir, "."},
+		{"/elsewhere/main.go", "/elsewhere/main.go"},
+		{filepath.Join(dir+"-other", "main.go"), filepath.Join(dir+"-other", "main.go")},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.stored, root.Rel(tt.path), tt.path)
+		assert.Equal(t, tt.path, root.Abs(tt.stored), tt.stored)
+	}
+
+	// Relative paths are already relative to the root
+	assert.Equal(t, "src/main.go", root.Rel("./src/../src/main.go"))
+	assert.Equal(t, "", root.Rel(""))
+	assert.Equal(t, "", root.Abs(""))
+
+	// The zero root leaves paths alone
+	assert.Equal(t, "src/main.go", Root{}.Abs("src/main.go"))
+	assert.Equal(t, "/abs/main.go", Root{}.Rel("/abs/main.go"))
+}
+
+func TestRoot_Migrate(t *testing.T) {
+	dir := t.TempDir()
+	root, err := NewRoot(dir)
+	require.NoError(t, err)
+
+	migrated, ok := root.Migrate(filepath.Join(dir, "src", "main.go"), "")
+	assert.True(t, ok)
+	assert.Equal(t, "src/main.go", migrated)
+
+	// Paths from where the workspace used to be are rebased
+	migrated, ok = root.Migrate("/old/checkout/src/main.go", "/old/checkout")
+	assert.True(t, ok)
+	assert.Equal(t, "src/main.go", migrated)
+
+	_, ok = root.Migrate("/unrelated/main.go", "/old/checkout")
+	assert.False(t, ok)
+	_, ok = root.Migrate("src/main.go", "/old/checkout")
+	assert.False(t, ok)
+}